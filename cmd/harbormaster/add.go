@@ -2,22 +2,30 @@ package main
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/tierone/harbormaster/pkg/config"
 	"github.com/tierone/harbormaster/pkg/downloader"
+	"github.com/tierone/harbormaster/pkg/importer"
 	"github.com/tierone/harbormaster/pkg/manager"
 )
 
 var (
-	addName   string
-	addType   string
-	addBranch string
-	addTag    string
-	addCommit string
-	addPath   string
-	addSync   bool
-	addTags   []string
+	addName    string
+	addType    string
+	addBranch  string
+	addTag     string
+	addCommit  string
+	addVersion string
+	addRef     string
+	addAsOf    string
+	addPath    string
+	addSubdir  string
+	addSync    bool
+	addSymlink bool
+	addTags    []string
+	addFile    string
 )
 
 var addCmd = &cobra.Command{
@@ -27,26 +35,62 @@ var addCmd = &cobra.Command{
 
 The repository type is auto-detected from the URL, but can be
 overridden with --type. Use --sync to immediately sync the
-repository after adding.`,
-	Args: cobra.ExactArgs(1),
+repository after adding.
+
+A path repository (--type path) points at an existing local directory,
+which is copied into the workspace on every sync, or linked to with
+--symlink. A relative path is resolved against the config directory.
+
+Use --from-file to add many repositories at once from a file listing one
+per line: a URL, optionally followed by a name and a branch, tag, or
+commit. A name of "-" stands for the one derived from the URL, and lines
+starting with # are ignored:
+
+  https://github.com/acme/api.git
+  git@github.com:acme/web.git   frontend
+  https://github.com/acme/lib.git -   v1.2.0
+
+Either every repository in the file is added or, if any can't be, none.
+--type and --tags apply to all of them.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if addFile != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
 	RunE: runAdd,
 }
 
 func init() {
 	addCmd.Flags().StringVarP(&addName, "name", "n", "", "repository name (required)")
-	addCmd.Flags().StringVarP(&addType, "type", "t", "", "repository type (git or http)")
+	addCmd.Flags().StringVarP(&addType, "type", "t", "", "repository type (git, http or path)")
 	addCmd.Flags().StringVarP(&addBranch, "branch", "b", "", "git branch")
 	addCmd.Flags().StringVar(&addTag, "tag", "", "git tag")
 	addCmd.Flags().StringVar(&addCommit, "commit", "", "git commit SHA")
+	addCmd.Flags().StringVar(&addVersion, "version", "", "semver constraint on git tags (e.g. ~>1.4)")
+	addCmd.Flags().StringVar(&addRef, "ref", "", "full ref such as refs/pull/123/head, or latest-release")
+	addCmd.Flags().StringVar(&addAsOf, "as-of", "", "check out the branch as it was at this date (YYYY-MM-DD or RFC 3339)")
 	addCmd.Flags().StringVarP(&addPath, "path", "p", "", "local path (relative to work_dir)")
+	addCmd.Flags().StringVar(&addSubdir, "subdir", "", "materialize only this directory of a git repository")
 	addCmd.Flags().BoolVar(&addSync, "sync", false, "sync immediately after adding")
+	addCmd.Flags().BoolVar(&addSymlink, "symlink", false, "link a path repository instead of copying it")
 	addCmd.Flags().StringSliceVar(&addTags, "tags", nil, "tags for filtering")
+	addCmd.Flags().StringVar(&addFile, "from-file", "", "add the repositories listed in a file, one URL per line")
 
-	_ = addCmd.MarkFlagRequired("name") // Safe to ignore - panics caught at startup
+	for _, flag := range []string{"name", "branch", "tag", "commit", "version", "ref", "as-of", "path", "subdir", "symlink"} {
+		addCmd.MarkFlagsMutuallyExclusive("from-file", flag)
+	}
+	_ = addCmd.RegisterFlagCompletionFunc("type", cobra.FixedCompletions([]string{"git", "http", "path"}, cobra.ShellCompDirectiveNoFileComp))
 	rootCmd.AddCommand(addCmd)
 }
 
 func runAdd(cmd *cobra.Command, args []string) error {
+	if addFile != "" {
+		return runAddFromFile()
+	}
+	if addName == "" {
+		return fmt.Errorf(`required flag(s) "name" not set`)
+	}
 	url := args[0]
 
 	// Determine type
@@ -57,14 +101,19 @@ func runAdd(cmd *cobra.Command, args []string) error {
 
 	// Create repository
 	repo := config.Repository{
-		Name:   addName,
-		URL:    url,
-		Type:   repoType,
-		Path:   addPath,
-		Branch: addBranch,
-		Tag:    addTag,
-		Commit: addCommit,
-		Tags:   addTags,
+		Name:    addName,
+		URL:     url,
+		Type:    repoType,
+		Path:    addPath,
+		Subdir:  addSubdir,
+		Branch:  addBranch,
+		Tag:     addTag,
+		Commit:  addCommit,
+		Version: addVersion,
+		Ref:     addRef,
+		AsOf:    addAsOf,
+		Symlink: addSymlink,
+		Tags:    addTags,
 	}
 
 	// Set default path if not specified
@@ -83,8 +132,21 @@ func runAdd(cmd *cobra.Command, args []string) error {
 	if addCommit != "" {
 		refCount++
 	}
+	if addVersion != "" {
+		refCount++
+	}
+	if addRef != "" {
+		refCount++
+	}
 	if refCount > 1 {
-		return fmt.Errorf("only one of --branch, --tag, or --commit can be specified")
+		return fmt.Errorf("only one of --branch, --tag, --commit, --version, or --ref can be specified")
+	}
+
+	if addSymlink && repoType != config.RepoTypePath {
+		return fmt.Errorf("--symlink requires --type path")
+	}
+	if addSubdir != "" && repoType != config.RepoTypeGit {
+		return fmt.Errorf("--subdir requires a git repository")
 	}
 
 	// Create manager and add repository
@@ -135,3 +197,68 @@ func runAdd(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// runAddFromFile adds every repository listed in addFile, saving the
+// config once.
+func runAddFromFile() error {
+	result, err := importer.ParseFile(addFile, importer.FormatList)
+	if err != nil {
+		return err
+	}
+	if len(result.Repositories) == 0 {
+		return fmt.Errorf("no repositories listed in %s", addFile)
+	}
+
+	repos := result.Repositories
+	for i := range repos {
+		repos[i].Type = config.RepositoryType(addType)
+		repos[i].Tags = addTags
+	}
+
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLockFile(lf),
+	)
+	if err := mgr.AddAll(repos); err != nil {
+		return fmt.Errorf("no repositories added:\n%w", err)
+	}
+
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	names := make([]string, len(repos))
+	for i, repo := range repos {
+		names[i] = repo.Name
+	}
+	if !quiet {
+		for _, name := range names {
+			repo, _ := cfg.GetRepository(name)
+			fmt.Printf("Added %s (%s, %s) -> %s\n", repo.Name, repo.Type, repoRef(*repo), repo.GetEffectivePath())
+		}
+		fmt.Printf("\nAdded %d repositories from %s\n", len(names), addFile)
+	}
+
+	if !addSync {
+		return nil
+	}
+	if !quiet {
+		fmt.Println("\nSyncing repositories...")
+	}
+	syncResult, err := mgr.Sync(manager.Filter{Names: names})
+	if err != nil {
+		return err
+	}
+	if err := saveLockFile(); err != nil {
+		return fmt.Errorf("failed to save lock file: %w", err)
+	}
+	if syncResult.HasFailures() {
+		for _, f := range syncResult.FailedResults() {
+			fmt.Fprintf(os.Stderr, "  %s: %v\n", f.RepoName, f.Error)
+		}
+		return syncFailedError(syncResult)
+	}
+	if !quiet {
+		fmt.Printf("Synced %d repositories\n", syncResult.SuccessCount)
+	}
+	return nil
+}
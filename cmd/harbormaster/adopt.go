@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/manager"
+)
+
+var (
+	adoptName string
+	adoptURL  string
+	adoptTags []string
+)
+
+var adoptCmd = &cobra.Command{
+	Use:   "adopt <path>",
+	Short: "Add an existing git clone as a repository",
+	Long: `Add the git clone at a path inside the work directory as a repository,
+with its origin URL and checked out branch, or the tag or commit a
+detached HEAD is at. The checked out commit is recorded in the lock file,
+so the clone is synced in place instead of being deleted and cloned again.
+
+The repository is named after the clone's directory unless --name is
+given. With --url, the clone's origin must be that URL.
+
+Example:
+  hm adopt libs/parser --name parser --url https://github.com/org/parser.git`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAdopt,
+}
+
+func init() {
+	adoptCmd.Flags().StringVarP(&adoptName, "name", "n", "", "repository name (default: the directory name)")
+	adoptCmd.Flags().StringVar(&adoptURL, "url", "", "URL the clone's origin must be")
+	adoptCmd.Flags().StringSliceVar(&adoptTags, "tags", nil, "tags to add to the repository")
+
+	rootCmd.AddCommand(adoptCmd)
+}
+
+func runAdopt(cmd *cobra.Command, args []string) error {
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLockFile(lf),
+	)
+
+	checkout, err := mgr.InspectUnconfigured(args[0], adoptURL)
+	if err != nil {
+		return err
+	}
+	name := adoptName
+	if name == "" {
+		name = mgr.CheckoutNames([]manager.Checkout{checkout})[0]
+	}
+
+	repos, err := mgr.Adopt([]manager.Checkout{checkout}, []string{name}, adoptTags)
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	if err := saveLockFile(); err != nil {
+		return fmt.Errorf("failed to save lock file: %w", err)
+	}
+
+	if !quiet {
+		fmt.Printf("Adopted %s (%s) at %s, locked at %s\n", repos[0].Name, repoRef(repos[0]), repos[0].Path, checkout.SHA[:8])
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/keychain"
+	"github.com/tierone/harbormaster/pkg/manager"
+	"github.com/tierone/harbormaster/pkg/ui"
+	"golang.org/x/term"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage tokens of git hosts in the system keychain",
+	Long: `Manage the tokens of git hosts kept in the operating system's credential
+store: the Keychain on macOS, the Credential Manager on Windows, and the
+Secret Service (GNOME Keyring, KWallet) through secret-tool elsewhere.
+
+A stored token authenticates git over HTTPS, HTTP downloads, and API calls
+to its host, unless the host has a token in the config.`,
+}
+
+var authLoginCmd = &cobra.Command{
+	Use:   "login <host>",
+	Short: "Store a token for a host",
+	Long: `Store a token for a host, such as github.com, in the system keychain,
+replacing any stored before. The token is prompted for without echo on a
+terminal, and otherwise read from standard input:
+
+  hm auth login github.com
+  echo "$GITHUB_TOKEN" | hm auth login github.com`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAuthLogin,
+}
+
+var authLogoutCmd = &cobra.Command{
+	Use:   "logout <host>",
+	Short: "Remove the token of a host",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runAuthLogout,
+}
+
+func init() {
+	authCmd.AddCommand(authLoginCmd)
+	authCmd.AddCommand(authLogoutCmd)
+	rootCmd.AddCommand(authCmd)
+}
+
+func runAuthLogin(cmd *cobra.Command, args []string) error {
+	host := args[0]
+	token, err := readToken(host)
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return fmt.Errorf("no token given for %s", host)
+	}
+	if err := keychain.System().Set(host, token); err != nil {
+		return fmt.Errorf("failed to store token for %s: %w", host, err)
+	}
+	if !quiet {
+		fmt.Printf("Stored token for %s\n", host)
+	}
+	return nil
+}
+
+func runAuthLogout(cmd *cobra.Command, args []string) error {
+	host := args[0]
+	err := keychain.System().Delete(host)
+	if errors.Is(err, keychain.ErrNotFound) {
+		return fmt.Errorf("no token stored for %s", host)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove token for %s: %w", host, err)
+	}
+	if !quiet {
+		fmt.Printf("Removed token for %s\n", host)
+	}
+	return nil
+}
+
+// readToken prompts for the token of host without echo on a terminal, or
+// reads it from standard input.
+func readToken(host string) (string, error) {
+	if !stdinIsTerminal() {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	fmt.Fprintf(os.Stderr, "Token or password for %s: ", host)
+	data, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// credentialPrompt returns a prompt for the credential of a host that
+// refused to authenticate a sync, suspending display while it asks.
+func credentialPrompt(display *ui.ProgressManager) manager.CredentialPrompt {
+	return func(host string) (manager.Credential, bool) {
+		display.Suspend()
+		defer display.Resume()
+
+		fmt.Fprintf(os.Stderr, "\nAuthentication to %s failed.\n", host)
+		fmt.Fprintf(os.Stderr, "Username for https://%s (empty for a token): ", host)
+		user, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return manager.Credential{}, false
+		}
+		token, err := readToken(host)
+		if err != nil || token == "" {
+			return manager.Credential{}, false
+		}
+		return manager.Credential{
+			User:  strings.TrimSpace(user),
+			Token: token,
+			Save:  confirm(fmt.Sprintf("Store the credential for %s in the keychain?", host)),
+		}, true
+	}
+}
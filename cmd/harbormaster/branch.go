@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/manager"
+)
+
+var (
+	branchCleanupProject string
+	branchCleanupTag     string
+	branchCleanupProtect []string
+	branchCleanupDryRun  bool
+)
+
+var branchCmd = &cobra.Command{
+	Use:   "branch",
+	Short: "Manage local branches across repositories",
+}
+
+var branchCleanupCmd = &cobra.Command{
+	Use:   "cleanup [repository...]",
+	Short: "Delete local branches that are already merged",
+	Long: `Delete the local branches that are already merged into each
+repository's base branch: its configured branch, or the remote's default
+branch. Merging is checked against the remote-tracking branch as of the
+last sync; run 'hm sync' first to include recently merged work.
+
+The base branch, the checked-out branch, and branches matching a
+--protect pattern are never deleted. Patterns are globs such as
+"release/*". Missing and non-git repositories are skipped.`,
+	ValidArgsFunction: completeRepositories,
+	RunE:              runBranchCleanup,
+}
+
+func init() {
+	branchCleanupCmd.Flags().StringVarP(&branchCleanupProject, "project", "p", "", "clean up repositories in project")
+	branchCleanupCmd.Flags().StringVarP(&branchCleanupTag, "tag", "t", "", "clean up repositories with tag")
+	branchCleanupCmd.Flags().StringSliceVar(&branchCleanupProtect, "protect",
+		[]string{"main", "master", "develop", "release/*"}, "branch patterns to keep")
+	branchCleanupCmd.Flags().BoolVar(&branchCleanupDryRun, "dry-run", false, "show what would be deleted")
+
+	_ = branchCleanupCmd.RegisterFlagCompletionFunc("project", completeProjects)
+	_ = branchCleanupCmd.RegisterFlagCompletionFunc("tag", completeTags)
+
+	branchCmd.AddCommand(branchCleanupCmd)
+	rootCmd.AddCommand(branchCmd)
+}
+
+func runBranchCleanup(cmd *cobra.Command, args []string) error {
+	filter := manager.Filter{}
+	if len(args) > 0 {
+		filter.Names = args
+	} else if branchCleanupProject != "" {
+		filter.Projects = []string{branchCleanupProject}
+	} else if branchCleanupTag != "" {
+		filter.Tags = []string{branchCleanupTag}
+	} else {
+		filter.All = true
+	}
+
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLogger(logger),
+		manager.WithVerbose(verboseOutput()),
+	)
+
+	results, err := mgr.CleanupBranches(context.Background(), filter, manager.BranchCleanupOptions{
+		Protected: branchCleanupProtect,
+		DryRun:    branchCleanupDryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	verb := "Deleted"
+	if branchCleanupDryRun {
+		verb = "Would delete"
+	}
+
+	var firstErr error
+	failed, deleted, repos := 0, 0, 0
+	for _, r := range results {
+		// Branches deleted before a failure are still reported
+		for _, b := range r.Deleted {
+			if !quiet {
+				fmt.Printf("  %s: %s (merged into %s)\n", r.Name, b, r.Base)
+			}
+		}
+		if len(r.Deleted) > 0 {
+			deleted += len(r.Deleted)
+			repos++
+		}
+		if r.Error != nil {
+			if firstErr == nil {
+				firstErr = r.Error
+			}
+			failed++
+			fmt.Fprintf(os.Stderr, "  %s: %v\n", r.Name, r.Error)
+		}
+	}
+
+	if !quiet {
+		if deleted == 0 {
+			fmt.Println("No merged branches to delete")
+		} else {
+			fmt.Printf("%s %d branches in %d repositories\n", verb, deleted, repos)
+		}
+	}
+
+	if firstErr != nil {
+		return errcode.Wrap(errcode.Of(firstErr), fmt.Errorf("could not clean up %d of %d repositories", failed, len(results)))
+	}
+	return nil
+}
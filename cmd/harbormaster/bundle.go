@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/export"
+	"github.com/tierone/harbormaster/pkg/lockfile"
+	"github.com/tierone/harbormaster/pkg/manager"
+	"github.com/tierone/harbormaster/pkg/ui"
+)
+
+var (
+	bundleProject string
+	bundleTag     string
+)
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Carry a locked workspace to an offline site",
+	Long: `Pack the locked state of a workspace into one archive and sync a
+workspace from it where the remotes cannot be reached:
+
+  hm bundle export workspace.tar.gz      # on a connected machine
+  hm bundle import workspace.tar.gz      # at the offline site
+
+The archive holds a git bundle of each git repository's locked commit,
+the downloaded file of each HTTP repository, the config file, and the
+lock entries.`,
+}
+
+var bundleExportCmd = &cobra.Command{
+	Use:   "export <file> [repository...]",
+	Short: "Pack locked repositories into an archive",
+	Long: `Write a gzipped tarball holding a git bundle of the locked commit of
+each selected git repository, the downloaded file of each HTTP
+repository, the config file, and their lock entries.
+
+Bundles are built from shared clones in the cache, which are fetched
+first unless they already have the locked commit, so checkouts may be
+shallow or have local changes. HTTP files must be synced and match the
+lock file. Path repositories are skipped.`,
+	Args: cobra.MinimumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		return completeRepositories(cmd, args[1:], toComplete)
+	},
+	RunE: runBundleExport,
+}
+
+var bundleImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Sync a workspace from an archive",
+	Long: `Sync the repositories in an archive written by 'hm bundle export' without
+network access, and record their commits in the lock file.
+
+Git repositories are synced in locked mode to the bundled commits,
+fetching from their bundle instead of their remote; HTTP files are copied
+into place. Every repository in the archive must be in the config. Run
+outside a workspace, the config and lock file of the archive are written
+to the current directory first.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBundleImport,
+}
+
+func init() {
+	bundleExportCmd.Flags().StringVarP(&bundleProject, "project", "p", "", "bundle repositories in project")
+	bundleExportCmd.Flags().StringVarP(&bundleTag, "tag", "t", "", "bundle repositories with tag")
+	_ = bundleExportCmd.RegisterFlagCompletionFunc("project", completeProjects)
+	_ = bundleExportCmd.RegisterFlagCompletionFunc("tag", completeTags)
+
+	bundleCmd.AddCommand(bundleExportCmd)
+	bundleCmd.AddCommand(bundleImportCmd)
+	rootCmd.AddCommand(bundleCmd)
+}
+
+func runBundleExport(cmd *cobra.Command, args []string) error {
+	output := args[0]
+	filter := manager.Filter{}
+	if len(args) > 1 {
+		filter.Names = args[1:]
+	} else if bundleProject != "" {
+		filter.Projects = []string{bundleProject}
+	} else if bundleTag != "" {
+		filter.Tags = []string{bundleTag}
+	} else {
+		filter.All = true
+	}
+
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLockFile(lf),
+		manager.WithLogger(logger),
+		manager.WithVerbose(verboseOutput()),
+	)
+
+	w, err := export.NewTarWriter(output)
+	if err != nil {
+		return err
+	}
+	manifest, err := mgr.ExportBundle(context.Background(), filter, w)
+	if err != nil {
+		_ = w.Close()
+		_ = os.Remove(output)
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	if !quiet {
+		fmt.Printf("Bundled %d repositories to %s\n", len(manifest.Repositories), output)
+	}
+	return nil
+}
+
+func runBundleImport(cmd *cobra.Command, args []string) error {
+	dir, err := os.MkdirTemp("", "hm-bundle-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	if err := export.ExtractTarball(args[0], dir); err != nil {
+		return err
+	}
+	if _, err := manager.ReadBundleManifest(dir); err != nil {
+		return err
+	}
+
+	// Start a workspace from the bundle where there is none
+	if cfgFile == "" && workspaceName == "" {
+		if _, err := config.FindConfigFile(); err != nil {
+			if err := newWorkspaceFromBundle(dir); err != nil {
+				return err
+			}
+		}
+	}
+	if err := loadWorkspace(); err != nil {
+		return err
+	}
+
+	uiMgr := ui.NewProgressManager(useInteractiveUI())
+	uiMgr.SetFolding(foldStyle)
+	if err := uiMgr.Start(); err != nil {
+		return fmt.Errorf("failed to start UI: %w", err)
+	}
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLockFile(lf),
+		manager.WithLogger(logger),
+		manager.WithVerbose(verboseOutput()),
+		manager.WithUI(uiMgr),
+	)
+
+	result, err := mgr.ImportBundle(context.Background(), dir)
+	if err != nil {
+		return err
+	}
+	if err := saveLockFile(); err != nil {
+		return fmt.Errorf("failed to save lock file: %w", err)
+	}
+
+	if result.HasFailures() {
+		for _, f := range result.FailedResults() {
+			if code := errcode.Of(f.Error); code != "" {
+				fmt.Printf("  %s: [%s] %v\n", f.RepoName, code, f.Error)
+			} else {
+				fmt.Printf("  %s: %v\n", f.RepoName, f.Error)
+			}
+		}
+		return syncFailedError(result)
+	}
+	return runPostSync(mgr)
+}
+
+// newWorkspaceFromBundle writes the config and lock file of the bundle
+// extracted to dir to the current directory.
+func newWorkspaceFromBundle(dir string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	src := filepath.Join(dir, config.ConfigFileName)
+	if _, err := os.Stat(src); err != nil {
+		return fmt.Errorf("no config file found, and the bundle has none")
+	}
+	if err := copyFile(src, filepath.Join(cwd, config.ConfigFileName)); err != nil {
+		return fmt.Errorf("failed to create config file: %w", err)
+	}
+	if err := copyFile(filepath.Join(dir, lockfile.LockFileName), filepath.Join(cwd, lockfile.LockFileName)); err != nil {
+		return fmt.Errorf("failed to create lock file: %w", err)
+	}
+	if !quiet {
+		fmt.Printf("Created workspace from bundle in %s\n", cwd)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/manager"
+	"github.com/tierone/harbormaster/pkg/ui"
+)
+
+var (
+	cacheJSON      bool
+	cacheOlderThan time.Duration
+	cacheDryRun    bool
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect and prune the cache",
+	Long: `Inspect and prune the cache under cache_dir, or .harbormaster/cache next
+to the config file when cache_dir is not set.
+
+The cache holds the shared clones of remotes that several repositories
+check out, which the checkouts borrow objects from. Entries are recreated
+by the next sync that needs them; remove them with 'hm cache clean', which
+gives the checkouts their own copies of the objects first.`,
+}
+
+var cacheListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List cache entries",
+	Args:    cobra.NoArgs,
+	RunE:    runCacheList,
+}
+
+var cacheSizeCmd = &cobra.Command{
+	Use:   "size",
+	Short: "Show the disk space used by the cache",
+	Args:  cobra.NoArgs,
+	RunE:  runCacheSize,
+}
+
+var cacheCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove cache entries",
+	Long: `Remove every cache entry, or with --older-than only those no sync has
+used for that long, for example --older-than 720h for 30 days.`,
+	Args: cobra.NoArgs,
+	RunE: runCacheClean,
+}
+
+func init() {
+	cacheListCmd.Flags().BoolVar(&cacheJSON, "json", false, "output as JSON")
+	cacheCleanCmd.Flags().DurationVar(&cacheOlderThan, "older-than", 0, "only remove entries unused for this long")
+	cacheCleanCmd.Flags().BoolVar(&cacheDryRun, "dry-run", false, "show what would be removed")
+
+	cacheCmd.AddCommand(cacheListCmd)
+	cacheCmd.AddCommand(cacheSizeCmd)
+	cacheCmd.AddCommand(cacheCleanCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
+
+func runCacheList(cmd *cobra.Command, args []string) error {
+	mgr := manager.NewRepositoryManager(cfg, manager.WithLogger(logger))
+	entries, err := mgr.CacheEntries()
+	if err != nil {
+		return err
+	}
+
+	if cacheJSON {
+		type jsonEntry struct {
+			Kind     string    `json:"kind"`
+			Path     string    `json:"path"`
+			URL      string    `json:"url,omitempty"`
+			Size     int64     `json:"size"`
+			LastUsed time.Time `json:"last_used"`
+		}
+		output := make([]jsonEntry, len(entries))
+		for i, e := range entries {
+			output[i] = jsonEntry{Kind: e.Kind, Path: e.Path, URL: e.URL, Size: e.Size, LastUsed: e.LastUsed}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(output)
+	}
+
+	if len(entries) == 0 {
+		fmt.Printf("Cache is empty (%s)\n", mgr.CacheDir())
+		return nil
+	}
+
+	columns := []tableColumn{{title: "KIND"}, {title: "SOURCE"}, {title: "SIZE"}, {title: "LAST USED"}}
+	rows := make([][]tableCell, len(entries))
+	for i, e := range entries {
+		source := e.URL
+		if source == "" {
+			source = e.Path
+		}
+		rows[i] = []tableCell{
+			plainCell(e.Kind),
+			plainCell(source),
+			plainCell(ui.FormatBytes(e.Size)),
+			plainCell(formatAge(e.LastUsed)),
+		}
+	}
+	printTable(columns, columnWidths(columns, rows), rows)
+	return nil
+}
+
+func runCacheSize(cmd *cobra.Command, args []string) error {
+	mgr := manager.NewRepositoryManager(cfg, manager.WithLogger(logger))
+	entries, err := mgr.CacheEntries()
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+	fmt.Printf("%s in %d entries (%s)\n", ui.FormatBytes(total), len(entries), mgr.CacheDir())
+	return nil
+}
+
+func runCacheClean(cmd *cobra.Command, args []string) error {
+	mgr := manager.NewRepositoryManager(cfg, manager.WithLogger(logger))
+	removed, err := mgr.CleanCache(cacheOlderThan, cacheDryRun)
+	if err != nil {
+		return err
+	}
+
+	if quiet {
+		return nil
+	}
+	verb := "Removed"
+	if cacheDryRun {
+		verb = "Would remove"
+	}
+	var total int64
+	for _, e := range removed {
+		total += e.Size
+		fmt.Printf("  %s\n", e.Path)
+	}
+	fmt.Printf("%s %d cache entries (%s)\n", verb, len(removed), ui.FormatBytes(total))
+	return nil
+}
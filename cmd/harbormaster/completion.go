@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/quarantine"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion <bash|zsh|fish|powershell>",
+	Short: "Generate shell completion scripts",
+	Long: `Generate a completion script for the given shell. Completions include
+repository, project, and tag names from the workspace configuration.
+
+Bash:
+  source <(hm completion bash)
+  # or permanently:
+  hm completion bash > /etc/bash_completion.d/hm
+
+Zsh:
+  hm completion zsh > "${fpath[1]}/_hm"
+
+Fish:
+  hm completion fish > ~/.config/fish/completions/hm.fish
+
+PowerShell:
+  hm completion powershell | Out-String | Invoke-Expression`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE:                  runCompletion,
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
+
+func runCompletion(cmd *cobra.Command, args []string) error {
+	switch args[0] {
+	case "bash":
+		return rootCmd.GenBashCompletionV2(os.Stdout, true)
+	case "zsh":
+		return rootCmd.GenZshCompletion(os.Stdout)
+	case "fish":
+		return rootCmd.GenFishCompletion(os.Stdout, true)
+	case "powershell":
+		return rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+	default:
+		return fmt.Errorf("unsupported shell: %s", args[0])
+	}
+}
+
+// completionConfig returns the workspace config for completion functions,
+// loading it on first use. It returns nil outside a workspace.
+func completionConfig() *config.Config {
+	if cfg == nil {
+		_ = loadWorkspace()
+	}
+	return cfg
+}
+
+// completeRepositories completes repository names not already given.
+func completeRepositories(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	c := completionConfig()
+	if c == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	given := make(map[string]bool)
+	for _, arg := range args {
+		given[arg] = true
+	}
+
+	var names []string
+	for _, repo := range c.Repositories {
+		if !given[repo.Name] {
+			names = append(names, repo.Name+"\t"+repo.URL)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeQuarantined completes quarantined repository names not already
+// given.
+func completeQuarantined(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if completionConfig() == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	q, err := quarantine.Load(quarantinePath())
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, name := range q.Quarantined() {
+		if !slices.Contains(args, name) {
+			names = append(names, name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeRepository completes a single repository name argument.
+func completeRepository(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeRepositories(cmd, args, toComplete)
+}
+
+// completeProjects completes project names.
+func completeProjects(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	c := completionConfig()
+	if c == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, proj := range c.Projects {
+		names = append(names, fmt.Sprintf("%s\t%d repositories", proj.Name, len(proj.Repositories)))
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProject completes a single project name argument.
+func completeProject(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeProjects(cmd, args, toComplete)
+}
+
+// completeProjectRepository completes a project name, then a repository.
+func completeProjectRepository(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		return completeProjects(cmd, args, toComplete)
+	case 1:
+		return completeRepositories(cmd, nil, toComplete)
+	default:
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeTags completes the tags used by repositories.
+func completeTags(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	c := completionConfig()
+	if c == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	tagSet := make(map[string]bool)
+	for _, repo := range c.Repositories {
+		for _, t := range repo.Tags {
+			tagSet[t] = true
+		}
+	}
+
+	tags := make([]string, 0, len(tagSet))
+	for t := range tagSet {
+		tags = append(tags, t)
+	}
+	sort.Strings(tags)
+	return tags, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/manager"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the workspace configuration",
+	Long:  `Manage the workspace configuration.`,
+}
+
+var configPullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Refresh the upstream shared config",
+	Long: `Download the upstream config named by [upstream] url and replace the
+cached copy (` + config.UpstreamFileName + `).
+
+Repositories and projects from the upstream config are merged into the
+workspace. Local definitions with the same name take precedence and are
+never modified.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigPull,
+}
+
+var configRootCmd = &cobra.Command{
+	Use:   "root",
+	Short: "Print the workspace root",
+	Long: `Print the workspace root: the directory of the config file in use.
+
+Commands find the config file in the current directory or the nearest
+parent directory that has one, so they work from inside synced
+repositories. The search stops below the directories listed in
+` + config.CeilingDirsEnv + `.
+
+  cd "$(hm config root)"`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println(cfg.Dir())
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configPullCmd)
+	configCmd.AddCommand(configRootCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigPull(cmd *cobra.Command, args []string) error {
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLockFile(lf),
+		manager.WithLogger(logger),
+		manager.WithVerbose(verboseOutput()),
+	)
+
+	changes, err := mgr.PullUpstream()
+	if err != nil {
+		return err
+	}
+
+	if !quiet {
+		printUpstreamChanges(cfg.Upstream.URL, changes)
+	}
+
+	return nil
+}
+
+// printUpstreamChanges summarizes the result of pulling an upstream config.
+func printUpstreamChanges(url string, changes *manager.UpstreamChanges) {
+	fmt.Printf("Pulled upstream config from %s\n", url)
+	if changes.Empty() {
+		fmt.Println("  No repository changes")
+		return
+	}
+	if len(changes.Added) > 0 {
+		fmt.Printf("  Added:   %s\n", strings.Join(changes.Added, ", "))
+	}
+	if len(changes.Changed) > 0 {
+		fmt.Printf("  Changed: %s\n", strings.Join(changes.Changed, ", "))
+	}
+	if len(changes.Removed) > 0 {
+		fmt.Printf("  Removed: %s\n", strings.Join(changes.Removed, ", "))
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/downloader"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/manager"
+	"github.com/tierone/harbormaster/pkg/ui"
+)
+
+var (
+	diffJSON       bool
+	diffProject    string
+	diffTag        string
+	diffFetch      bool
+	diffMaxCommits int
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [repository...]",
+	Short: "Explain how checkouts differ from the lock file and their refs",
+	Long: `Show, for each repository, the commit checked out (HEAD), the commit
+recorded in the lock file, and the commit its configured branch, tag, or
+pinned commit points to, with the subjects of the commits between them.
+
+Commits marked + are missing from HEAD and would be brought in by a sync;
+commits marked - are in HEAD only.
+
+The configured ref is resolved from the remote-tracking branch of the
+checkout, as of its last fetch. Use --fetch to resolve it on the remote
+and fetch it first. Working trees are never changed.`,
+	ValidArgsFunction: completeRepositories,
+	RunE:              runDiff,
+}
+
+func init() {
+	diffCmd.Flags().BoolVar(&diffJSON, "json", false, "output as JSON")
+	diffCmd.Flags().StringVarP(&diffProject, "project", "p", "", "diff repositories in project")
+	diffCmd.Flags().StringVarP(&diffTag, "tag", "t", "", "diff repositories with tag")
+	diffCmd.Flags().BoolVar(&diffFetch, "fetch", false, "resolve refs on the remote and fetch them")
+	diffCmd.Flags().IntVarP(&diffMaxCommits, "max-commits", "n", 10, "commits to list on each side")
+
+	_ = diffCmd.RegisterFlagCompletionFunc("project", completeProjects)
+	_ = diffCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	rootCmd.AddCommand(diffCmd)
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	filter := manager.Filter{}
+	if len(args) > 0 {
+		filter.Names = args
+	} else if diffProject != "" {
+		filter.Projects = []string{diffProject}
+	} else if diffTag != "" {
+		filter.Tags = []string{diffTag}
+	} else {
+		filter.All = true
+	}
+
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLockFile(lf),
+		manager.WithLogger(logger),
+		manager.WithVerbose(verboseOutput()),
+	)
+
+	diffs, err := mgr.Diff(context.Background(), filter, diffFetch, diffMaxCommits)
+	if err != nil {
+		return err
+	}
+
+	if len(diffs) == 0 {
+		fmt.Println("No repositories configured")
+		return nil
+	}
+
+	if diffJSON {
+		return outputDiffJSON(diffs)
+	}
+
+	for i, d := range diffs {
+		if i > 0 {
+			fmt.Println()
+		}
+		printRepoDiff(d)
+	}
+	return nil
+}
+
+// printRepoDiff prints the HEAD, locked, and ref commits of a repository
+// and the commits between them.
+func printRepoDiff(d manager.RepoDiff) {
+	ref := d.Ref
+	if ref == "HEAD" {
+		// No branch configured: the remote's default branch is followed
+		ref = "origin/HEAD"
+	}
+	fmt.Printf("%s %s\n", ui.TitleStyle.Render(d.Name), ui.MutedStyle.Render("("+ref+")"))
+
+	switch {
+	case !d.Exists:
+		fmt.Printf("  %s\n", ui.WarningStyle.Render("not present"))
+		return
+	case d.Error != nil:
+		fmt.Printf("  %s\n", ui.ErrorStyle.Render(d.Error.Error()))
+		return
+	case !d.IsGit:
+		fmt.Printf("  %-*s  %s\n", 6, "locked", shortCommit(d.LockedSHA))
+		fmt.Printf("  %s\n", ui.MutedStyle.Render("not a git checkout; commits cannot be compared"))
+		return
+	}
+
+	width := max(len("locked"), len(ref))
+	fmt.Printf("  %-*s  %s\n", width, "HEAD", shortCommit(d.HeadSHA))
+
+	if d.LockedSHA == "" {
+		fmt.Printf("  %-*s  %-8s  %s\n", width, "locked", shortCommit(""), ui.MutedStyle.Render("no lock entry"))
+	} else {
+		fmt.Printf("  %-*s  %-8s  %s\n", width, "locked", shortCommit(d.LockedSHA), describeRange(d.ToLocked))
+		printCommitRange(d.ToLocked, width)
+	}
+
+	if d.RefError != nil {
+		fmt.Printf("  %-*s  %-8s  %s\n", width, ref, shortCommit(""), ui.ErrorStyle.Render(d.RefError.Error()))
+		return
+	}
+	fmt.Printf("  %-*s  %-8s  %s\n", width, ref, shortCommit(d.RefSHA), describeRange(d.ToRef))
+	printCommitRange(d.ToRef, width)
+}
+
+// describeRange styles a commit range summary by whether HEAD differs.
+func describeRange(r manager.CommitRange) string {
+	switch {
+	case r.Unavailable:
+		return ui.MutedStyle.Render(r.String() + " (try --fetch)")
+	case r.Ahead > 0 || r.Behind > 0:
+		return ui.WarningStyle.Render(r.String())
+	default:
+		return ui.SuccessStyle.Render(r.String())
+	}
+}
+
+// printCommitRange lists the commits of a range under its summary line.
+func printCommitRange(r manager.CommitRange, width int) {
+	indent := width + 4
+	list := func(sign string, commits []downloader.Commit, total int) {
+		for _, c := range commits {
+			fmt.Printf("%*s%s %s %s\n", indent, "", sign, shortCommit(c.SHA), c.Subject)
+		}
+		if more := total - len(commits); more > 0 {
+			fmt.Printf("%*s%s\n", indent, "", ui.MutedStyle.Render(fmt.Sprintf("... and %d more", more)))
+		}
+	}
+	list(ui.SuccessStyle.Render("+"), r.BehindLog, r.Behind)
+	list(ui.WarningStyle.Render("-"), r.AheadLog, r.Ahead)
+}
+
+// shortCommit abbreviates a SHA for display, or returns "-" if empty.
+func shortCommit(sha string) string {
+	if sha == "" {
+		return "-"
+	}
+	return sha[:min(8, len(sha))]
+}
+
+func outputDiffJSON(diffs []manager.RepoDiff) error {
+	type jsonCommit struct {
+		SHA     string `json:"sha"`
+		Subject string `json:"subject"`
+	}
+	type jsonRange struct {
+		Ahead         int          `json:"ahead"`
+		Behind        int          `json:"behind"`
+		AheadCommits  []jsonCommit `json:"ahead_commits,omitempty"`
+		BehindCommits []jsonCommit `json:"behind_commits,omitempty"`
+		Unavailable   bool         `json:"unavailable,omitempty"`
+	}
+	type jsonDiff struct {
+		Name      string     `json:"name"`
+		Ref       string     `json:"ref"`
+		Exists    bool       `json:"exists"`
+		HeadSHA   string     `json:"head_sha,omitempty"`
+		LockedSHA string     `json:"locked_sha,omitempty"`
+		RefSHA    string     `json:"ref_sha,omitempty"`
+		ToLocked  *jsonRange `json:"to_locked,omitempty"`
+		ToRef     *jsonRange `json:"to_ref,omitempty"`
+		RefError  string     `json:"ref_error,omitempty"`
+		Error     string     `json:"error,omitempty"`
+		Code      string     `json:"code,omitempty"`
+	}
+
+	commits := func(cs []downloader.Commit) []jsonCommit {
+		var out []jsonCommit
+		for _, c := range cs {
+			out = append(out, jsonCommit{SHA: c.SHA, Subject: c.Subject})
+		}
+		return out
+	}
+	toRange := func(r manager.CommitRange) *jsonRange {
+		return &jsonRange{
+			Ahead:         r.Ahead,
+			Behind:        r.Behind,
+			AheadCommits:  commits(r.AheadLog),
+			BehindCommits: commits(r.BehindLog),
+			Unavailable:   r.Unavailable,
+		}
+	}
+
+	output := make([]jsonDiff, len(diffs))
+	for i, d := range diffs {
+		output[i] = jsonDiff{
+			Name:      d.Name,
+			Ref:       d.Ref,
+			Exists:    d.Exists,
+			HeadSHA:   d.HeadSHA,
+			LockedSHA: d.LockedSHA,
+			RefSHA:    d.RefSHA,
+		}
+		if d.IsGit && d.Error == nil {
+			if d.LockedSHA != "" {
+				output[i].ToLocked = toRange(d.ToLocked)
+			}
+			if d.RefError == nil {
+				output[i].ToRef = toRange(d.ToRef)
+			}
+		}
+		if d.RefError != nil {
+			output[i].RefError = d.RefError.Error()
+			output[i].Code = string(errcode.Of(d.RefError))
+		}
+		if d.Error != nil {
+			output[i].Error = d.Error.Error()
+			output[i].Code = string(errcode.Of(d.Error))
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
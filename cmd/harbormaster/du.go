@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/manager"
+	"github.com/tierone/harbormaster/pkg/ui"
+)
+
+var (
+	duProject    string
+	duTag        string
+	duSortBySize bool
+	duJSON       bool
+)
+
+var duCmd = &cobra.Command{
+	Use:   "du [repository...]",
+	Short: "Show disk usage of repositories",
+	Long: `Show the disk space used by each repository, split into the working
+tree, git metadata (.git), and Git LFS objects (.git/lfs), followed by the
+space used by the configured cache_dir and the total.
+
+Sizes are the apparent sizes of the files; symbolic links are not
+followed. Missing repositories are listed without sizes.`,
+	ValidArgsFunction: completeRepositories,
+	RunE:              runDu,
+}
+
+func init() {
+	duCmd.Flags().StringVarP(&duProject, "project", "p", "", "show repositories in project")
+	duCmd.Flags().StringVarP(&duTag, "tag", "t", "", "show repositories with tag")
+	duCmd.Flags().BoolVar(&duSortBySize, "sort-by-size", false, "list the largest repositories first")
+	duCmd.Flags().BoolVar(&duJSON, "json", false, "output as JSON")
+
+	_ = duCmd.RegisterFlagCompletionFunc("project", completeProjects)
+	_ = duCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	rootCmd.AddCommand(duCmd)
+}
+
+func runDu(cmd *cobra.Command, args []string) error {
+	filter := manager.Filter{}
+	if len(args) > 0 {
+		filter.Names = args
+	} else if duProject != "" {
+		filter.Projects = []string{duProject}
+	} else if duTag != "" {
+		filter.Tags = []string{duTag}
+	} else {
+		filter.All = true
+	}
+
+	mgr := manager.NewRepositoryManager(cfg, manager.WithLogger(logger))
+
+	usages, err := mgr.DiskUsage(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+	cache, err := mgr.CacheUsage()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to measure cache_dir: %v\n", err)
+	}
+
+	if duSortBySize {
+		sort.SliceStable(usages, func(i, j int) bool {
+			return usages[i].Total() > usages[j].Total()
+		})
+	}
+
+	var total manager.RepoUsage
+	for _, u := range usages {
+		total.WorkTree += u.WorkTree
+		total.Git += u.Git
+		total.LFS += u.LFS
+	}
+
+	if duJSON {
+		return outputDuJSON(usages, cache, total.Total()+cache)
+	}
+
+	columns := []tableColumn{
+		{title: "REPOSITORY"},
+		{title: "WORKTREE", right: true},
+		{title: "GIT", right: true},
+		{title: "LFS", right: true},
+		{title: "TOTAL", right: true},
+	}
+	sizes := func(name string, u manager.RepoUsage) []tableCell {
+		return []tableCell{
+			plainCell(name),
+			plainCell(ui.FormatBytes(u.WorkTree)),
+			plainCell(ui.FormatBytes(u.Git)),
+			plainCell(ui.FormatBytes(u.LFS)),
+			plainCell(ui.FormatBytes(u.Total())),
+		}
+	}
+
+	var rows [][]tableCell
+	for _, u := range usages {
+		if !u.Exists {
+			rows = append(rows, []tableCell{plainCell(u.Name), plainCell("-"), plainCell("-"), plainCell("-"), plainCell("missing")})
+			continue
+		}
+		rows = append(rows, sizes(u.Name, u))
+	}
+	rows = append(rows, sizes("Total", total))
+
+	widths := columnWidths(columns, rows)
+	printTable(columns, widths, rows[:len(rows)-1])
+	printTableRow(columns, widths, rows[len(rows)-1])
+
+	if cfg.General.CacheDir != "" {
+		fmt.Printf("\nCache:  %s (%s)\n", ui.FormatBytes(cache), cfg.General.CacheDir)
+		fmt.Printf("Total:  %s\n", ui.FormatBytes(total.Total()+cache))
+	}
+
+	for _, u := range usages {
+		if u.Error != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s: failed to measure: %v\n", u.Name, u.Error)
+		}
+	}
+	return nil
+}
+
+func outputDuJSON(usages []manager.RepoUsage, cache, total int64) error {
+	type jsonRepo struct {
+		Name     string `json:"name"`
+		Path     string `json:"path"`
+		Exists   bool   `json:"exists"`
+		WorkTree int64  `json:"worktree_bytes"`
+		Git      int64  `json:"git_bytes"`
+		LFS      int64  `json:"lfs_bytes"`
+		Total    int64  `json:"total_bytes"`
+		Error    string `json:"error,omitempty"`
+	}
+	type jsonUsage struct {
+		Repositories []jsonRepo `json:"repositories"`
+		CacheDir     string     `json:"cache_dir,omitempty"`
+		Cache        int64      `json:"cache_bytes"`
+		Total        int64      `json:"total_bytes"`
+	}
+
+	output := jsonUsage{
+		Repositories: make([]jsonRepo, len(usages)),
+		CacheDir:     cfg.General.CacheDir,
+		Cache:        cache,
+		Total:        total,
+	}
+	for i, u := range usages {
+		output.Repositories[i] = jsonRepo{
+			Name:     u.Name,
+			Path:     u.Path,
+			Exists:   u.Exists,
+			WorkTree: u.WorkTree,
+			Git:      u.Git,
+			LFS:      u.LFS,
+			Total:    u.Total(),
+		}
+		if u.Error != nil {
+			output.Repositories[i].Error = u.Error.Error()
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -146,6 +148,56 @@ func TestE2E_Init_WithExample(t *testing.T) {
 	}
 }
 
+func TestE2E_Init_WithTemplate(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	binary := buildBinary(t)
+	defer func() { _ = os.Remove(binary) }()
+
+	// Create a template repository with a config
+	templateDir := filepath.Join(t.TempDir(), "template")
+	setupTestGitRepo(t, templateDir)
+	tmplConfig := `# Team workspace
+[[repository]]
+name = "shared"
+url = "https://github.com/example/shared.git"
+type = "git"
+`
+	if err := os.WriteFile(filepath.Join(templateDir, ".harbormaster.toml"), []byte(tmplConfig), 0644); err != nil {
+		t.Fatalf("failed to write template config: %v", err)
+	}
+	for _, cmd := range [][]string{{"git", "add", "."}, {"git", "commit", "-m", "Add config"}} {
+		c := exec.Command(cmd[0], cmd[1:]...)
+		c.Dir = templateDir
+		if out, err := c.CombinedOutput(); err != nil {
+			t.Fatalf("failed to run %v: %v\n%s", cmd, err, out)
+		}
+	}
+
+	workDir := t.TempDir()
+	stdout, stderr, err := runCommand(t, binary, workDir, "init", "--template", "file://"+templateDir)
+	if err != nil {
+		t.Fatalf("init failed: %v\nstdout: %s\nstderr: %s", err, stdout, stderr)
+	}
+
+	// Config is copied verbatim, including comments
+	content, err := os.ReadFile(filepath.Join(workDir, ".harbormaster.toml"))
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	if string(content) != tmplConfig {
+		t.Errorf("expected template config to be copied verbatim, got:\n%s", content)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, ".harbormaster.lock")); err != nil {
+		t.Error("lock file not created")
+	}
+}
+
 func TestE2E_Init_AlreadyExists(t *testing.T) {
 	if _, err := exec.LookPath("go"); err != nil {
 		t.Skip("go not available")
@@ -250,6 +302,346 @@ func TestE2E_Status(t *testing.T) {
 	}
 }
 
+func TestE2E_ConfigDiscovery(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+
+	binary := buildBinary(t)
+	defer func() { _ = os.Remove(binary) }()
+
+	workDir := t.TempDir()
+	_, _, _ = runCommand(t, binary, workDir, "init", "--example")
+
+	subDir := filepath.Join(workDir, "example-repo", "src")
+	if err := os.MkdirAll(subDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, err := runCommand(t, binary, subDir, "status")
+	if err != nil {
+		t.Fatalf("status from a subdirectory failed: %v\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, "example-repo") {
+		t.Errorf("expected the workspace's repositories, got: %s", stdout)
+	}
+
+	stdout, _, err = runCommand(t, binary, subDir, "config", "root")
+	if err != nil {
+		t.Fatalf("config root failed: %v", err)
+	}
+	if root, _ := filepath.EvalSymlinks(workDir); strings.TrimSpace(stdout) != root && strings.TrimSpace(stdout) != workDir {
+		t.Errorf("expected workspace root %s, got %s", workDir, stdout)
+	}
+
+	cmd := exec.Command(binary, "status")
+	cmd.Dir = subDir
+	cmd.Env = append(os.Environ(), "HARBORMASTER_CEILING_DIRECTORIES="+filepath.Join(workDir, "example-repo"))
+	if out, err := cmd.CombinedOutput(); err == nil {
+		t.Errorf("expected status to stop at the ceiling, got: %s", out)
+	}
+}
+
+func TestE2E_Workspaces(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+
+	binary := buildBinary(t)
+	defer func() { _ = os.Remove(binary) }()
+
+	registryPath := filepath.Join(t.TempDir(), "workspaces.toml")
+	run := func(dir string, args ...string) (string, error) {
+		cmd := exec.Command(binary, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "HARBORMASTER_WORKSPACES="+registryPath)
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+
+	backend := t.TempDir()
+	_, _, _ = runCommand(t, binary, backend, "init", "--example")
+	frontend := t.TempDir()
+	_, _, _ = runCommand(t, binary, frontend, "init")
+	elsewhere := t.TempDir()
+
+	if out, err := run(backend, "workspaces", "add", "backend"); err != nil {
+		t.Fatalf("workspaces add failed: %v\n%s", err, out)
+	}
+	if out, err := run(elsewhere, "workspaces", "add", "frontend", frontend); err != nil {
+		t.Fatalf("workspaces add with a directory failed: %v\n%s", err, out)
+	}
+	if out, err := run(elsewhere, "workspaces", "add", "empty", elsewhere); err == nil {
+		t.Errorf("expected registering a directory without a config to fail, got: %s", out)
+	}
+
+	out, err := run(elsewhere, "-W", "backend", "status")
+	if err != nil || !strings.Contains(out, "example-repo") {
+		t.Errorf("expected -W backend to show its repositories, got %v: %s", err, out)
+	}
+	out, err = run(elsewhere, "-W", "mobile", "status")
+	if err == nil || !strings.Contains(out, "unknown workspace mobile") {
+		t.Errorf("expected an unknown workspace error, got %v: %s", err, out)
+	}
+
+	// Outside any workspace, the current one is used
+	out, err = run(elsewhere, "config", "root")
+	if err != nil || strings.TrimSpace(out) != backend {
+		t.Errorf("expected current workspace %s, got %v: %s", backend, err, out)
+	}
+	if out, err := run(elsewhere, "workspaces", "switch", "frontend"); err != nil {
+		t.Fatalf("workspaces switch failed: %v\n%s", err, out)
+	}
+	out, err = run(elsewhere, "config", "root")
+	if err != nil || strings.TrimSpace(out) != frontend {
+		t.Errorf("expected current workspace %s, got %v: %s", frontend, err, out)
+	}
+	// Inside a workspace, that one is used
+	out, err = run(backend, "config", "root")
+	if err != nil || strings.TrimSpace(out) != backend {
+		t.Errorf("expected enclosing workspace %s, got %v: %s", backend, err, out)
+	}
+
+	out, err = run(elsewhere, "workspaces", "list")
+	if err != nil {
+		t.Fatalf("workspaces list failed: %v", err)
+	}
+	for _, want := range []string{"backend", "frontend", "current"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in workspaces list, got: %s", want, out)
+		}
+	}
+}
+
+func TestE2E_Edit(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	binary := buildBinary(t)
+	defer func() { _ = os.Remove(binary) }()
+
+	workDir := t.TempDir()
+	_, _, _ = runCommand(t, binary, workDir, "init", "--example")
+	configPath := filepath.Join(workDir, ".harbormaster.toml")
+	original, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// edit runs hm edit with an editor appending line to the config
+	edit := func(line string) (string, error) {
+		script := filepath.Join(t.TempDir(), "editor.sh")
+		if err := os.WriteFile(script, []byte("#!/bin/sh\necho '"+line+"' >> \"$1\"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(binary, "edit")
+		cmd.Dir = workDir
+		cmd.Env = append(os.Environ(), "VISUAL=", "EDITOR="+script)
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+
+	// A misspelled key is refused, leaving the config alone
+	out, err := edit("brnach = \"develop\"")
+	if err == nil || !strings.Contains(out, "unknown key: project.brnach") {
+		t.Errorf("expected the unknown key to be refused, got %v: %s", err, out)
+	}
+	if data, _ := os.ReadFile(configPath); !bytes.Equal(data, original) {
+		t.Errorf("expected the config to be unchanged, got:\n%s", data)
+	}
+
+	// So is an invalid value
+	out, err = edit("[[repository]]\nname = \"broken\"")
+	if err == nil || !strings.Contains(out, "config not saved") {
+		t.Errorf("expected the invalid config to be refused, got %v: %s", err, out)
+	}
+
+	out, err = edit("# reviewed")
+	if err != nil || !strings.Contains(out, "Saved") {
+		t.Fatalf("expected a valid edit to be saved, got %v: %s", err, out)
+	}
+	if data, _ := os.ReadFile(configPath); !strings.HasSuffix(string(data), "# reviewed\n") {
+		t.Errorf("expected the edit in the config, got:\n%s", data)
+	}
+}
+
+func TestE2E_StatusPorcelain(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+
+	binary := buildBinary(t)
+	defer func() { _ = os.Remove(binary) }()
+
+	workDir := t.TempDir()
+	_, _, _ = runCommand(t, binary, workDir, "init", "--example")
+
+	stdout, _, err := runCommand(t, binary, workDir, "status", "--porcelain")
+	if err != nil {
+		t.Fatalf("status --porcelain failed: %v", err)
+	}
+	if want := "example-repo\tmissing\t\tmain\n"; stdout != want {
+		t.Errorf("expected v1 output %q, got %q", want, stdout)
+	}
+
+	stdout, _, err = runCommand(t, binary, workDir, "status", "--porcelain=v2", "-z")
+	if err != nil {
+		t.Fatalf("status --porcelain=v2 failed: %v", err)
+	}
+	records := strings.Split(strings.TrimSuffix(stdout, "\x00"), "\x00")
+	if len(records) != 2 || records[0] != "# porcelain v2" {
+		t.Fatalf("expected header and one record, got %q", stdout)
+	}
+	fields := strings.Split(records[1], "\t")
+	want := []string{"example-repo", "missing", "-", "-", "-", "main", "-", "-", "-", "no", "-"}
+	if len(fields) != 12 || strings.Join(fields[:11], "\t") != strings.Join(want, "\t") {
+		t.Errorf("expected fields %q and a path, got %q", want, fields)
+	}
+
+	// A literal "-" and separators inside values are quoted
+	configPath := filepath.Join(workDir, ".harbormaster.toml")
+	f, err := os.OpenFile(configPath, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("\n[[repository]]\n  name = \"odd-repo\"\n  url = \"https://github.com/user/odd.git\"\n  type = \"git\"\n  path = \"odd\\tdir\"\n  branch = \"-\"\n")
+	_ = f.Close()
+
+	stdout, _, err = runCommand(t, binary, workDir, "status", "--porcelain=v2", "odd-repo")
+	if err != nil {
+		t.Fatalf("status --porcelain=v2 failed: %v", err)
+	}
+	records = strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	if len(records) != 2 {
+		t.Fatalf("expected header and one record, got %q", stdout)
+	}
+	fields = strings.Split(records[1], "\t")
+	if len(fields) != 12 || fields[5] != `"-"` || !strings.HasSuffix(fields[11], `odd\tdir"`) {
+		t.Errorf("expected a quoted ref and path, got %q", fields)
+	}
+
+	if _, stderr, err := runCommand(t, binary, workDir, "status", "--porcelain=v3"); err == nil {
+		t.Errorf("expected --porcelain=v3 to fail")
+	} else if !strings.Contains(stderr, "unsupported porcelain format") {
+		t.Errorf("expected unsupported format error, got: %s", stderr)
+	}
+}
+
+func TestE2E_StatusByProject(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+
+	binary := buildBinary(t)
+	defer func() { _ = os.Remove(binary) }()
+
+	workDir := t.TempDir()
+	_, _, _ = runCommand(t, binary, workDir, "init", "--example")
+
+	configPath := filepath.Join(workDir, ".harbormaster.toml")
+	f, err := os.OpenFile(configPath, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("\n[[repository]]\n  name = \"loose-repo\"\n  url = \"https://github.com/user/loose.git\"\n  type = \"git\"\n  path = \"loose-repo\"\n")
+	_ = f.Close()
+
+	stdout, _, err := runCommand(t, binary, workDir, "status", "--by-project")
+	if err != nil {
+		t.Fatalf("status --by-project failed: %v", err)
+	}
+
+	project := strings.Index(stdout, "example-project")
+	unassigned := strings.Index(stdout, "unassigned")
+	if project < 0 || unassigned < 0 || project > unassigned {
+		t.Fatalf("expected example-project section before unassigned, got: %s", stdout)
+	}
+	if !strings.Contains(stdout[unassigned:], "loose-repo") {
+		t.Errorf("expected loose-repo under unassigned, got: %s", stdout)
+	}
+	if !strings.Contains(stdout, "Total  2 repositories: 2 missing") {
+		t.Errorf("expected workspace total, got: %s", stdout)
+	}
+}
+
+func TestE2E_SortAndColumns(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+
+	binary := buildBinary(t)
+	defer func() { _ = os.Remove(binary) }()
+
+	workDir := t.TempDir()
+	_, _, _ = runCommand(t, binary, workDir, "init", "--example")
+
+	configPath := filepath.Join(workDir, ".harbormaster.toml")
+	f, err := os.OpenFile(configPath, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("\n[[repository]]\n  name = \"zz-repo\"\n  url = \"https://github.com/user/zz.git\"\n  type = \"git\"\n  path = \"aa\"\n")
+	_ = f.Close()
+
+	stdout, _, err := runCommand(t, binary, workDir, "list", "repos", "--sort", "path", "--columns", "name,url")
+	if err != nil {
+		t.Fatalf("list repos failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and 2 rows, got: %s", stdout)
+	}
+	if fields := strings.Fields(lines[0]); strings.Join(fields, " ") != "NAME URL" {
+		t.Errorf("header = %q, want NAME URL", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "zz-repo") {
+		t.Errorf("expected zz-repo first when sorted by path, got: %s", stdout)
+	}
+
+	stdout, _, err = runCommand(t, binary, workDir, "status", "--columns", "name,type,age")
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	if !strings.Contains(stdout, "SYNCED") || strings.Contains(stdout, "BRANCH") {
+		t.Errorf("expected only the selected columns, got: %s", stdout)
+	}
+	if !strings.Contains(stdout, "never") {
+		t.Errorf("expected never-synced age, got: %s", stdout)
+	}
+
+	if _, stderr, err := runCommand(t, binary, workDir, "status", "--sort", "size"); err == nil {
+		t.Error("expected an error for an unknown sort key")
+	} else if !strings.Contains(stderr, "invalid sort key") {
+		t.Errorf("expected sort key error, got: %s", stderr)
+	}
+}
+
+func TestE2E_InteractiveRequiresTerminal(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+
+	binary := buildBinary(t)
+	defer func() { _ = os.Remove(binary) }()
+
+	workDir := t.TempDir()
+	_, _, _ = runCommand(t, binary, workDir, "init", "--example")
+
+	for _, args := range [][]string{{"status", "--tui"}, {"sync", "--interactive"}} {
+		_, stderr, err := runCommand(t, binary, workDir, args...)
+		if err == nil {
+			t.Errorf("%v: expected an error without a terminal", args)
+		}
+		if !strings.Contains(stderr, "requires a terminal") {
+			t.Errorf("%v: expected terminal error, got: %s", args, stderr)
+		}
+	}
+}
+
 func TestE2E_Add(t *testing.T) {
 	if _, err := exec.LookPath("go"); err != nil {
 		t.Skip("go not available")
@@ -283,6 +675,43 @@ func TestE2E_Add(t *testing.T) {
 	}
 }
 
+func TestE2E_Add_FromFile(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+
+	binary := buildBinary(t)
+	defer func() { _ = os.Remove(binary) }()
+
+	workDir := t.TempDir()
+	_, _, _ = runCommand(t, binary, workDir, "init")
+
+	list := filepath.Join(workDir, "repos.txt")
+	if err := os.WriteFile(list, []byte("https://github.com/test/api.git\nhttps://github.com/test/web.git frontend main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr, err := runCommand(t, binary, workDir, "add", "--from-file", list, "--tags", "team")
+	if err != nil {
+		t.Fatalf("add failed: %v\nstdout: %s\nstderr: %s", err, stdout, stderr)
+	}
+	if !strings.Contains(stdout, "Added 2 repositories") {
+		t.Errorf("expected a summary in output, got: %s", stdout)
+	}
+
+	// A list with a problem adds nothing
+	if err := os.WriteFile(list, []byte("https://github.com/test/lib.git\nhttps://github.com/test/api.git\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, stderr, err := runCommand(t, binary, workDir, "add", "--from-file", list); err == nil || !strings.Contains(stderr, "api: repository already exists") {
+		t.Errorf("expected the duplicate to fail the add, got %v: %s", err, stderr)
+	}
+
+	stdout, _, _ = runCommand(t, binary, workDir, "list", "repos")
+	if !strings.Contains(stdout, "api") || !strings.Contains(stdout, "frontend") || strings.Contains(stdout, "lib") {
+		t.Errorf("expected api and frontend only in list, got: %s", stdout)
+	}
+}
+
 func TestE2E_Remove(t *testing.T) {
 	if _, err := exec.LookPath("go"); err != nil {
 		t.Skip("go not available")
@@ -379,35 +808,134 @@ func TestE2E_Sync_RealRepo(t *testing.T) {
 	}
 }
 
-func TestE2E_Help(t *testing.T) {
+func TestE2E_Sync_Check(t *testing.T) {
 	if _, err := exec.LookPath("go"); err != nil {
 		t.Skip("go not available")
 	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
 
 	binary := buildBinary(t)
 	defer func() { _ = os.Remove(binary) }()
 
 	workDir := t.TempDir()
+	sourceDir := filepath.Join(workDir, "source")
+	setupTestGitRepo(t, sourceDir)
 
-	stdout, _, err := runCommand(t, binary, workDir, "--help")
-	if err != nil {
-		t.Fatalf("--help failed: %v", err)
+	_, _, _ = runCommand(t, binary, workDir, "init")
+	_, _, _ = runCommand(t, binary, workDir, "add", "file://"+sourceDir, "--name", "local-repo")
+
+	// Nothing synced yet: the clone is reported and nothing is created
+	stdout, stderr, err := runCommand(t, binary, workDir, "sync", "--check")
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Fatalf("expected exit status 4, got %v\nstdout: %s\nstderr: %s", err, stdout, stderr)
+	}
+	if !strings.Contains(stdout, "local-repo: clone") {
+		t.Errorf("expected clone to be reported, got: %s", stdout)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "local-repo")); !os.IsNotExist(err) {
+		t.Errorf("expected --check not to clone, stat error: %v", err)
 	}
 
-	expectedCommands := []string{"init", "sync", "status", "list", "add", "remove"}
-	for _, cmd := range expectedCommands {
-		if !strings.Contains(stdout, cmd) {
-			t.Errorf("expected '%s' in help output", cmd)
-		}
+	if _, stderr, err := runCommand(t, binary, workDir, "sync", "--quiet"); err != nil {
+		t.Fatalf("sync failed: %v\nstderr: %s", err, stderr)
+	}
+
+	stdout, stderr, err = runCommand(t, binary, workDir, "sync", "--check")
+	if err != nil {
+		t.Fatalf("expected nothing to change, got %v\nstdout: %s\nstderr: %s", err, stdout, stderr)
+	}
+	if !strings.Contains(stdout, "up to date") {
+		t.Errorf("expected up to date, got: %s", stdout)
 	}
 }
 
-func TestE2E_Version(t *testing.T) {
+func TestE2E_Status_ExitCode(t *testing.T) {
 	if _, err := exec.LookPath("go"); err != nil {
 		t.Skip("go not available")
 	}
-
-	binary := buildBinary(t)
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	binary := buildBinary(t)
+	defer func() { _ = os.Remove(binary) }()
+
+	exitCode := func(err error) int {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		return 0
+	}
+
+	workDir := t.TempDir()
+	sourceDir := filepath.Join(workDir, "source")
+	setupTestGitRepo(t, sourceDir)
+
+	_, _, _ = runCommand(t, binary, workDir, "init")
+	_, _, _ = runCommand(t, binary, workDir, "add", "file://"+sourceDir, "--name", "local-repo")
+
+	// Without the flag the state does not affect the exit status
+	if _, _, err := runCommand(t, binary, workDir, "status"); err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+
+	_, stderr, err := runCommand(t, binary, workDir, "status", "--exit-code")
+	if got := exitCode(err); got != 5 {
+		t.Errorf("missing repository: exit status = %d, want 5", got)
+	}
+	if stderr != "" {
+		t.Errorf("expected no error message, got: %s", stderr)
+	}
+
+	if _, stderr, err := runCommand(t, binary, workDir, "sync", "--quiet"); err != nil {
+		t.Fatalf("sync failed: %v\nstderr: %s", err, stderr)
+	}
+	if _, _, err := runCommand(t, binary, workDir, "status", "--exit-code", "--porcelain"); err != nil {
+		t.Errorf("clean workspace: expected exit status 0, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(workDir, "local-repo", "README.md"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = runCommand(t, binary, workDir, "status", "--exit-code", "--json")
+	if got := exitCode(err); got != 6 {
+		t.Errorf("dirty repository: exit status = %d, want 6", got)
+	}
+}
+
+func TestE2E_Help(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+
+	binary := buildBinary(t)
+	defer func() { _ = os.Remove(binary) }()
+
+	workDir := t.TempDir()
+
+	stdout, _, err := runCommand(t, binary, workDir, "--help")
+	if err != nil {
+		t.Fatalf("--help failed: %v", err)
+	}
+
+	expectedCommands := []string{"init", "sync", "status", "list", "add", "remove"}
+	for _, cmd := range expectedCommands {
+		if !strings.Contains(stdout, cmd) {
+			t.Errorf("expected '%s' in help output", cmd)
+		}
+	}
+}
+
+func TestE2E_Version(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+
+	binary := buildBinary(t)
 	defer func() { _ = os.Remove(binary) }()
 
 	workDir := t.TempDir()
@@ -418,3 +946,468 @@ func TestE2E_Version(t *testing.T) {
 		t.Error("expected help in output")
 	}
 }
+
+func TestE2E_Archive(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	binary := buildBinary(t)
+	defer func() { _ = os.Remove(binary) }()
+
+	workDir := t.TempDir()
+	sourceDir := filepath.Join(workDir, "source")
+	setupTestGitRepo(t, sourceDir)
+
+	_, _, _ = runCommand(t, binary, workDir, "init")
+	_, _, _ = runCommand(t, binary, workDir, "add", "file://"+sourceDir, "--name", "local-repo")
+	if _, stderr, err := runCommand(t, binary, workDir, "sync", "--quiet"); err != nil {
+		t.Fatalf("sync failed: %v\n%s", err, stderr)
+	}
+
+	// Local changes are ignored when archiving locked commits
+	if err := os.WriteFile(filepath.Join(workDir, "local-repo", "README.md"), []byte("changed"), 0644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+
+	for _, output := range []string{"out.tar.gz", "out.zip"} {
+		stdout, stderr, err := runCommand(t, binary, workDir, "archive", "--locked", output)
+		if err != nil {
+			t.Fatalf("archive %s failed: %v\nstdout: %s\nstderr: %s", output, err, stdout, stderr)
+		}
+		if !strings.Contains(stdout, "Archived 1 repositories") {
+			t.Errorf("expected archive summary, got: %s", stdout)
+		}
+		if info, err := os.Stat(filepath.Join(workDir, output)); err != nil || info.Size() == 0 {
+			t.Errorf("expected non-empty %s", output)
+		}
+	}
+}
+
+func TestE2E_Completion(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+
+	binary := buildBinary(t)
+	defer func() { _ = os.Remove(binary) }()
+
+	workDir := t.TempDir()
+	_, _, _ = runCommand(t, binary, workDir, "init")
+	_, _, _ = runCommand(t, binary, workDir, "add", "https://github.com/test/app.git", "--name", "app", "--tags", "frontend")
+	_, _, _ = runCommand(t, binary, workDir, "project", "add", "web", "--repos", "app")
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"__complete", "sync", ""}, "app"},
+		{[]string{"__complete", "sync", "app", ""}, ""},
+		{[]string{"__complete", "status", "-p", ""}, "web"},
+		{[]string{"__complete", "sync", "--tag", ""}, "frontend"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			stdout, stderr, err := runCommand(t, binary, workDir, tt.args...)
+			if err != nil {
+				t.Fatalf("completion failed: %v\nstderr: %s", err, stderr)
+			}
+			if tt.expected == "" {
+				if strings.Contains(stdout, "app") {
+					t.Errorf("expected already given repository to be omitted, got: %s", stdout)
+				}
+				return
+			}
+			if !strings.Contains(stdout, tt.expected) {
+				t.Errorf("expected %q in completions, got: %s", tt.expected, stdout)
+			}
+		})
+	}
+
+	// Completion scripts are generated outside a workspace
+	stdout, _, err := runCommand(t, binary, t.TempDir(), "completion", "bash")
+	if err != nil || !strings.Contains(stdout, "bash completion") {
+		t.Errorf("expected bash completion script, got err=%v", err)
+	}
+}
+
+func TestE2E_Docs(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+
+	binary := buildBinary(t)
+	defer func() { _ = os.Remove(binary) }()
+
+	// Docs are generated outside a workspace
+	workDir := t.TempDir()
+
+	if _, stderr, err := runCommand(t, binary, workDir, "docs", "man", "-o", "man"); err != nil {
+		t.Fatalf("docs man failed: %v\n%s", err, stderr)
+	}
+	content, err := os.ReadFile(filepath.Join(workDir, "man", "hm-sync.1"))
+	if err != nil {
+		t.Fatalf("expected hm-sync.1: %v", err)
+	}
+	if !strings.Contains(string(content), "Synchronize repositories") {
+		t.Error("expected sync description in man page")
+	}
+
+	if _, stderr, err := runCommand(t, binary, workDir, "docs", "markdown", "-o", "md"); err != nil {
+		t.Fatalf("docs markdown failed: %v\n%s", err, stderr)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "md", "hm_sync.md")); err != nil {
+		t.Errorf("expected hm_sync.md: %v", err)
+	}
+}
+
+func TestE2E_Watch_Once(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	binary := buildBinary(t)
+	defer func() { _ = os.Remove(binary) }()
+
+	workDir := t.TempDir()
+	sourceDir := filepath.Join(workDir, "source")
+	setupTestGitRepo(t, sourceDir)
+
+	git := func(args ...string) string {
+		c := exec.Command("git", args...)
+		c.Dir = sourceDir
+		out, err := c.CombinedOutput()
+		if err != nil {
+			t.Fatalf("failed to run git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("checkout", "-B", "main")
+
+	_, _, _ = runCommand(t, binary, workDir, "init")
+	_, _, _ = runCommand(t, binary, workDir, "add", "file://"+sourceDir, "--name", "local-repo", "--branch", "main")
+	if stdout, stderr, err := runCommand(t, binary, workDir, "sync", "--quiet"); err != nil {
+		t.Fatalf("sync failed: %v\nstdout: %s\nstderr: %s", err, stdout, stderr)
+	}
+
+	stdout, stderr, err := runCommand(t, binary, workDir, "watch", "--once")
+	if err != nil {
+		t.Fatalf("watch failed: %v\nstdout: %s\nstderr: %s", err, stdout, stderr)
+	}
+	if !strings.Contains(stdout, "No drift in 1 repositories") {
+		t.Errorf("expected no drift, got: %s", stdout)
+	}
+
+	// A new upstream commit is detected and synced
+	git("commit", "--allow-empty", "-m", "Second commit")
+	head := git("rev-parse", "HEAD")
+
+	stdout, stderr, err = runCommand(t, binary, workDir, "watch", "--once", "--policy", "sync")
+	if err != nil {
+		t.Fatalf("watch failed: %v\nstdout: %s\nstderr: %s", err, stdout, stderr)
+	}
+	if !strings.Contains(stdout, "local-repo: main moved") || !strings.Contains(stdout, "synced to "+head[:8]) {
+		t.Errorf("expected drift and sync, got: %s", stdout)
+	}
+
+	lockContent, _ := os.ReadFile(filepath.Join(workDir, ".harbormaster.lock"))
+	if !strings.Contains(string(lockContent), head) {
+		t.Error("expected lock file to record the new commit")
+	}
+}
+
+func TestE2E_LogLevel(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	binary := buildBinary(t)
+	defer func() { _ = os.Remove(binary) }()
+
+	workDir := t.TempDir()
+	sourceDir := filepath.Join(workDir, "source")
+	setupTestGitRepo(t, sourceDir)
+
+	_, _, _ = runCommand(t, binary, workDir, "init")
+	_, _, _ = runCommand(t, binary, workDir, "add", "file://"+sourceDir, "--name", "local-repo")
+
+	// Logs go to stderr as JSON, one record per line
+	stdout, stderr, err := runCommand(t, binary, workDir, "sync", "--quiet", "--log-level", "debug", "--log-format", "json")
+	if err != nil {
+		t.Fatalf("sync failed: %v\nstdout: %s\nstderr: %s", err, stdout, stderr)
+	}
+
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSpace(stderr), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("expected JSON log line, got %q", line)
+		}
+		messages = append(messages, record["msg"].(string))
+	}
+	joined := strings.Join(messages, ",")
+	for _, want := range []string{"sync started", "syncing repository", "git command", "repository synced", "sync finished"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected %q in log messages, got %v", want, messages)
+		}
+	}
+
+	// Nothing is logged by default
+	_, stderr, _ = runCommand(t, binary, workDir, "status")
+	if stderr != "" {
+		t.Errorf("expected no log output by default, got %q", stderr)
+	}
+
+	if _, _, err := runCommand(t, binary, workDir, "status", "--log-level", "verbose"); err == nil {
+		t.Error("expected error for invalid log level")
+	}
+}
+
+func TestE2E_Verbose(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	binary := buildBinary(t)
+	defer func() { _ = os.Remove(binary) }()
+
+	workDir := t.TempDir()
+	sourceDir := filepath.Join(workDir, "source")
+	setupTestGitRepo(t, sourceDir)
+
+	_, _, _ = runCommand(t, binary, workDir, "init")
+	_, _, _ = runCommand(t, binary, workDir, "add", "file://"+sourceDir, "--name", "local-repo")
+
+	stdout, stderr, err := runCommand(t, binary, workDir, "sync", "--verbose")
+	if err != nil {
+		t.Fatalf("sync failed: %v\nstdout: %s\nstderr: %s", err, stdout, stderr)
+	}
+
+	for _, want := range []string{"run:  git clone", "done: git clone", "(exit 0, "} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("expected %q in stderr, got:\n%s", want, stderr)
+		}
+	}
+	if strings.Contains(stdout, "run:  ") {
+		t.Errorf("expected commands on stderr only, got stdout:\n%s", stdout)
+	}
+}
+
+func TestE2E_History(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	binary := buildBinary(t)
+	defer func() { _ = os.Remove(binary) }()
+
+	workDir := t.TempDir()
+	sourceDir := filepath.Join(workDir, "source")
+	setupTestGitRepo(t, sourceDir)
+
+	_, _, _ = runCommand(t, binary, workDir, "init")
+	_, _, _ = runCommand(t, binary, workDir, "add", "file://"+sourceDir, "--name", "local-repo")
+
+	stdout, _, _ := runCommand(t, binary, workDir, "history")
+	if !strings.Contains(stdout, "No history recorded") {
+		t.Errorf("expected empty history, got: %s", stdout)
+	}
+
+	if stdout, stderr, err := runCommand(t, binary, workDir, "sync", "--quiet"); err != nil {
+		t.Fatalf("sync failed: %v\nstdout: %s\nstderr: %s", err, stdout, stderr)
+	}
+	if _, _, err := runCommand(t, binary, workDir, "remove", "local-repo", "--force"); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+
+	stdout, _, err := runCommand(t, binary, workDir, "history", "--json")
+	if err != nil {
+		t.Fatalf("history failed: %v", err)
+	}
+	var entries []struct {
+		ID          int    `json:"id"`
+		Command     string `json:"command"`
+		LockChanges []struct {
+			Name   string `json:"name"`
+			Before string `json:"before"`
+			After  string `json:"after"`
+		} `json:"lock_changes"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &entries); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout)
+	}
+	if len(entries) != 2 || entries[0].Command != "remove" || entries[1].Command != "sync" {
+		t.Fatalf("expected remove then sync, got %+v", entries)
+	}
+	if c := entries[1].LockChanges; len(c) != 1 || c[0].Name != "local-repo" || c[0].Before != "" || c[0].After == "" {
+		t.Errorf("expected sync to add lock entry, got %+v", c)
+	}
+	if c := entries[0].LockChanges; len(c) != 1 || c[0].After != "" {
+		t.Errorf("expected remove to delete lock entry, got %+v", c)
+	}
+
+	stdout, _, err = runCommand(t, binary, workDir, "history", "show", "1")
+	if err != nil {
+		t.Fatalf("history show failed: %v", err)
+	}
+	for _, want := range []string{"Operation 1: sync", "local-repo", "added at"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("expected %q in output, got: %s", want, stdout)
+		}
+	}
+
+	if _, _, err := runCommand(t, binary, workDir, "history", "show", "42"); err == nil {
+		t.Error("expected error for unknown history entry")
+	}
+}
+
+func TestE2E_ErrorCodes(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	binary := buildBinary(t)
+	defer func() { _ = os.Remove(binary) }()
+
+	exitCode := func(err error) int {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		return 0
+	}
+
+	// No workspace: configuration class
+	emptyDir := t.TempDir()
+	_, stderr, err := runCommand(t, binary, emptyDir, "status")
+	if exitCode(err) != 2 || !strings.Contains(stderr, "Error [HM002]") {
+		t.Errorf("expected HM002 with exit status 2, got %v: %s", err, stderr)
+	}
+
+	// Missing remote: repository class
+	workDir := t.TempDir()
+	_, _, _ = runCommand(t, binary, workDir, "init")
+	_, _, _ = runCommand(t, binary, workDir, "add", "file://"+filepath.Join(t.TempDir(), "missing"), "--name", "missing")
+
+	stdout, stderr, err := runCommand(t, binary, workDir, "sync", "--quiet")
+	if exitCode(err) != 3 || !strings.Contains(stderr, "Error [HM106]") {
+		t.Errorf("expected HM106 with exit status 3, got %v: %s", err, stderr)
+	}
+	if !strings.Contains(stdout, "missing: [HM106]") {
+		t.Errorf("expected coded failure in output, got: %s", stdout)
+	}
+
+	// Locked sync without a lock entry: lock class
+	_, stderr, err = runCommand(t, binary, workDir, "sync", "--quiet", "--locked")
+	if exitCode(err) != 4 || !strings.Contains(stderr, "Error [HM202]") {
+		t.Errorf("expected HM202 with exit status 4, got %v: %s", err, stderr)
+	}
+
+	stdout, _, _ = runCommand(t, binary, workDir, "history", "show", "1", "--json")
+	if !strings.Contains(stdout, `"code": "HM106"`) {
+		t.Errorf("expected code in history JSON, got: %s", stdout)
+	}
+}
+
+func TestE2E_Sync_NotTerminal(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	binary := buildBinary(t)
+	defer func() { _ = os.Remove(binary) }()
+
+	workDir := t.TempDir()
+	sourceDir := filepath.Join(workDir, "source")
+	setupTestGitRepo(t, sourceDir)
+
+	_, _, _ = runCommand(t, binary, workDir, "init")
+	_, _, _ = runCommand(t, binary, workDir, "add", "file://"+sourceDir, "--name", "local-repo")
+
+	// Output is captured, so sync falls back to plain lines
+	stdout, stderr, err := runCommand(t, binary, workDir, "sync")
+	if err != nil {
+		t.Fatalf("sync failed: %v\nstdout: %s\nstderr: %s", err, stdout, stderr)
+	}
+	if !strings.Contains(stdout, "local-repo: complete") {
+		t.Errorf("expected plain progress lines, got:\n%s", stdout)
+	}
+	if strings.Contains(stdout, "\x1b[") {
+		t.Errorf("expected no escape sequences, got:\n%q", stdout)
+	}
+
+	if _, stderr, err := runCommand(t, binary, workDir, "sync", "--progress=sometimes"); err == nil {
+		t.Error("expected an unknown --progress mode to fail")
+	} else if !strings.Contains(stderr, "unsupported progress mode") {
+		t.Errorf("expected unsupported progress mode error, got: %s", stderr)
+	}
+}
+
+func TestE2E_Env(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	binary := buildBinary(t)
+	defer func() { _ = os.Remove(binary) }()
+
+	workDir := t.TempDir()
+	sourceDir := filepath.Join(workDir, "source")
+	setupTestGitRepo(t, sourceDir)
+
+	_, _, _ = runCommand(t, binary, workDir, "init")
+	_, _, _ = runCommand(t, binary, workDir, "add", "file://"+sourceDir, "--name", "local-repo", "--path", "it's here")
+	if _, stderr, err := runCommand(t, binary, workDir, "sync", "--quiet"); err != nil {
+		t.Fatalf("sync failed: %v\nstderr: %s", err, stderr)
+	}
+
+	stdout, stderr, err := runCommand(t, binary, workDir, "env")
+	if err != nil {
+		t.Fatalf("env failed: %v\nstderr: %s", err, stderr)
+	}
+
+	// The output must survive eval, including the quote in the path
+	script := stdout + `printf '%s\n' "$HM_REPOSITORIES" "$HM_LOCAL_REPO_PATH" "$HM_LOCAL_REPO_SHA"`
+	out, err := exec.Command("sh", "-c", script).Output()
+	if err != nil {
+		t.Fatalf("eval failed: %v\nscript:\n%s", err, script)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("unexpected output: %q", out)
+	}
+	if lines[0] != "local-repo" {
+		t.Errorf("HM_REPOSITORIES = %q", lines[0])
+	}
+	if want := filepath.Join(workDir, "it's here"); lines[1] != want {
+		t.Errorf("HM_LOCAL_REPO_PATH = %q, want %q", lines[1], want)
+	}
+	if len(lines[2]) != 40 {
+		t.Errorf("HM_LOCAL_REPO_SHA = %q, want a full SHA", lines[2])
+	}
+}
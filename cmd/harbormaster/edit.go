@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/config"
+)
+
+const editLong = `Open the workspace config file in $VISUAL or $EDITOR (default: vi) and
+save the changes only if the result is valid.
+
+The file is edited as a copy next to it. When the editor exits, the copy
+is checked as every command would load it, and for keys that match no
+setting, such as misspelled ones, which are otherwise ignored. A broken
+copy is never saved over the config: edit it again, or give up and keep
+it aside for reference.`
+
+var editCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit the config file, rejecting invalid changes",
+	Long:  editLong,
+	Args:  cobra.NoArgs,
+	RunE:  runEdit,
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: editCmd.Short,
+	Long:  editLong,
+	Args:  cobra.NoArgs,
+	RunE:  runEdit,
+}
+
+func init() {
+	configCmd.AddCommand(configEditCmd)
+	rootCmd.AddCommand(editCmd)
+}
+
+func runEdit(cmd *cobra.Command, args []string) error {
+	// The config is not loaded beforehand, so that a broken one can be fixed
+	path, err := findConfig()
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	// Relative paths in the copy resolve as they do in the config
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".harbormaster-edit-*.toml")
+	if err != nil {
+		return fmt.Errorf("failed to create copy of config: %w", err)
+	}
+	keep := false
+	defer func() {
+		if !keep {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if _, err := tmp.Write(original); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to create copy of config: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to create copy of config: %w", err)
+	}
+
+	for {
+		if err := runEditor(tmp.Name()); err != nil {
+			return err
+		}
+		edited, err := os.ReadFile(tmp.Name())
+		if err != nil {
+			return fmt.Errorf("failed to read edited config: %w", err)
+		}
+		if bytes.Equal(edited, original) {
+			if !quiet {
+				fmt.Println("No changes")
+			}
+			return nil
+		}
+
+		problems := configProblems(tmp.Name())
+		if len(problems) == 0 {
+			break
+		}
+		fmt.Fprintln(os.Stderr, "The edited config is invalid:")
+		for _, p := range problems {
+			fmt.Fprintf(os.Stderr, "  %s\n", p)
+		}
+		if !confirm("Edit again?") {
+			keep = true
+			return fmt.Errorf("config not saved; your changes are in %s", tmp.Name())
+		}
+	}
+
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	keep = true
+
+	if !quiet {
+		fmt.Printf("Saved %s\n", path)
+	}
+	return nil
+}
+
+// configProblems returns why the config file at path would be rejected:
+// the error loading it, or the keys that match no setting.
+func configProblems(path string) []string {
+	if _, err := config.Load(path); err != nil {
+		return []string{err.Error()}
+	}
+	keys, err := config.UnknownKeys(path)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	problems := make([]string, len(keys))
+	for i, key := range keys {
+		problems[i] = "unknown key: " + key
+	}
+	return problems
+}
+
+// runEditor edits path in the user's editor: $VISUAL, $EDITOR, or vi
+// (notepad on Windows). The variables may include arguments, such as
+// "code --wait".
+func runEditor(path string) error {
+	var editor []string
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if editor = strings.Fields(os.Getenv(env)); len(editor) > 0 {
+			break
+		}
+	}
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+		if runtime.GOOS == "windows" {
+			editor = []string{"notepad"}
+		}
+	}
+
+	c := exec.Command(editor[0], append(editor[1:], path)...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("editor %s failed: %w", editor[0], err)
+	}
+	return nil
+}
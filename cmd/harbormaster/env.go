@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/manager"
+)
+
+var (
+	envShell   string
+	envProject string
+	envTag     string
+)
+
+var envCmd = &cobra.Command{
+	Use:   "env [repository...]",
+	Short: "Print workspace variables for the shell",
+	Long: `Print shell commands that export the workspace layout, so that
+build scripts can use it without parsing the config:
+
+  HM_WORKSPACE        directory containing the config file
+  HM_CONFIG           path of the config file
+  HM_WORK_DIR         directory repositories are synced into
+  HM_REPOSITORIES     selected repository names, space-separated
+  HM_<NAME>_PATH      checkout path of each repository
+  HM_<NAME>_REF       branch, tag, or commit it tracks
+  HM_<NAME>_SHA       commit recorded in the lock file, if any
+
+<NAME> is the repository name in upper case with other characters
+replaced by underscores, e.g. HM_MY_APP_PATH for my-app.
+
+  eval "$(hm env)"             # sh, bash, zsh
+  hm env --shell fish | source # fish
+
+For direnv, add to the workspace's .envrc:
+
+  watch_file .harbormaster.toml .harbormaster.lock
+  eval "$(hm env)"`,
+	ValidArgsFunction: completeRepositories,
+	RunE:              runEnv,
+}
+
+func init() {
+	envCmd.Flags().StringVar(&envShell, "shell", "sh", "output syntax: sh, fish, or json")
+	envCmd.Flags().StringVarP(&envProject, "project", "p", "", "include repositories in project")
+	envCmd.Flags().StringVarP(&envTag, "tag", "t", "", "include repositories with tag")
+
+	_ = envCmd.RegisterFlagCompletionFunc("shell", cobra.FixedCompletions([]string{"sh", "fish", "json"}, cobra.ShellCompDirectiveNoFileComp))
+	_ = envCmd.RegisterFlagCompletionFunc("project", completeProjects)
+	_ = envCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	rootCmd.AddCommand(envCmd)
+}
+
+func runEnv(cmd *cobra.Command, args []string) error {
+	filter := manager.Filter{}
+	if len(args) > 0 {
+		filter.Names = args
+	} else if envProject != "" {
+		filter.Projects = []string{envProject}
+	} else if envTag != "" {
+		filter.Tags = []string{envTag}
+	} else {
+		filter.All = true
+	}
+
+	mgr := manager.NewRepositoryManager(cfg, manager.WithLockFile(lf))
+	vars, err := mgr.Environment(filter)
+	if err != nil {
+		return err
+	}
+
+	switch envShell {
+	case "sh":
+		for _, v := range vars {
+			fmt.Printf("export %s=%s\n", v.Name, shellQuote(v.Value))
+		}
+	case "fish":
+		for _, v := range vars {
+			fmt.Printf("set -gx %s %s\n", v.Name, fishQuote(v.Value))
+		}
+	case "json":
+		output := make(map[string]string, len(vars))
+		for _, v := range vars {
+			output[v.Name] = v.Value
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(output)
+	default:
+		return fmt.Errorf("invalid shell: %s (must be sh, fish, or json)", envShell)
+	}
+	return nil
+}
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote quotes s for fish, where backslashes and single quotes are
+// escaped inside single quotes.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/export"
+	"github.com/tierone/harbormaster/pkg/manager"
+)
+
+var (
+	exportVendor  string
+	exportProject string
+	exportTag     string
+	exportOutput  string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [repository...]",
+	Short: "Export workspace content",
+	Long: `Export the content of a locked workspace.
+
+Use --vendor to copy all synced content, without VCS metadata, into a
+directory or a .tar.gz/.tgz tarball together with a provenance manifest
+(harbormaster-manifest.json) recording each repository's URL and SHA.
+
+Every exported repository must be clean and at its locked commit.`,
+	ValidArgsFunction: completeRepositories,
+	RunE:              runExport,
+}
+
+var exportRepoManifestCmd = &cobra.Command{
+	Use:   "repo-manifest [repository...]",
+	Short: "Export a repo tool manifest.xml",
+	Long: `Export the workspace definition as a manifest.xml for the 'repo' tool,
+with every project pinned to the SHA recorded in the lock file.
+
+Only git repositories are included. Remotes are derived from the
+repository URLs.`,
+	ValidArgsFunction: completeRepositories,
+	RunE:              runExportRepoManifest,
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportVendor, "vendor", "", "destination directory or tarball for vendored content")
+	exportCmd.PersistentFlags().StringVarP(&exportProject, "project", "p", "", "export repositories in project")
+	exportCmd.PersistentFlags().StringVarP(&exportTag, "tag", "t", "", "export repositories with tag")
+
+	exportRepoManifestCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "output file (default: stdout)")
+
+	_ = exportCmd.RegisterFlagCompletionFunc("project", completeProjects)
+	_ = exportCmd.RegisterFlagCompletionFunc("tag", completeTags)
+
+	exportCmd.AddCommand(exportRepoManifestCmd)
+	rootCmd.AddCommand(exportCmd)
+}
+
+func exportFilter(args []string) manager.Filter {
+	filter := manager.Filter{}
+	if len(args) > 0 {
+		filter.Names = args
+	} else if exportProject != "" {
+		filter.Projects = []string{exportProject}
+	} else if exportTag != "" {
+		filter.Tags = []string{exportTag}
+	} else {
+		filter.All = true
+	}
+	return filter
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	if exportVendor == "" {
+		return cmd.Help()
+	}
+
+	filter := exportFilter(args)
+
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLockFile(lf),
+		manager.WithLogger(logger),
+		manager.WithVerbose(verboseOutput()),
+	)
+
+	sources, err := mgr.ExportSources(filter)
+	if err != nil {
+		return err
+	}
+
+	w, err := export.NewWriter(exportVendor)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := export.Vendor(w, sources)
+	if err != nil {
+		_ = w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	if !quiet {
+		fmt.Printf("Exported %d repositories to %s\n", len(manifest.Repositories), exportVendor)
+	}
+
+	return nil
+}
+
+func runExportRepoManifest(cmd *cobra.Command, args []string) error {
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLockFile(lf),
+		manager.WithLogger(logger),
+		manager.WithVerbose(verboseOutput()),
+	)
+
+	sources, err := mgr.LockedSources(exportFilter(args))
+	if err != nil {
+		return err
+	}
+
+	data, err := export.RepoManifest(sources)
+	if err != nil {
+		return err
+	}
+
+	if exportOutput == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	if err := os.WriteFile(exportOutput, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if !quiet {
+		fmt.Printf("Wrote %s\n", exportOutput)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/manager"
+)
+
+var (
+	findProject  string
+	findTag      string
+	findAbsolute bool
+	findJSON     bool
+)
+
+var findCmd = &cobra.Command{
+	Use:   "find <glob>",
+	Short: "Find files across repositories",
+	Long: `Find files matching a glob across the repositories of the workspace.
+
+Only files tracked by git are searched in git checkouts; other repositories
+are searched in full. A pattern without a slash matches file names in any
+directory, such as '*.proto'. A pattern with a slash matches the path
+within the repository, and ** matches any number of directories, such as
+'config/**/*.yaml'. Quote patterns so that the shell does not expand them.
+
+Matches are printed as repository:path, or as absolute paths with
+--absolute.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runFind,
+}
+
+func init() {
+	findCmd.Flags().StringVarP(&findProject, "project", "p", "", "search repositories in project")
+	findCmd.Flags().StringVarP(&findTag, "tag", "t", "", "search repositories with tag")
+	findCmd.Flags().BoolVar(&findAbsolute, "absolute", false, "print absolute paths")
+	findCmd.Flags().BoolVar(&findJSON, "json", false, "output as JSON")
+
+	_ = findCmd.RegisterFlagCompletionFunc("project", completeProjects)
+	_ = findCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	rootCmd.AddCommand(findCmd)
+}
+
+func runFind(cmd *cobra.Command, args []string) error {
+	filter := manager.Filter{}
+	if findProject != "" {
+		filter.Projects = []string{findProject}
+	} else if findTag != "" {
+		filter.Tags = []string{findTag}
+	} else {
+		filter.All = true
+	}
+
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLogger(logger),
+		manager.WithVerbose(verboseOutput()),
+	)
+
+	matches, failures, err := mgr.Find(context.Background(), filter, args[0])
+	if err != nil {
+		return err
+	}
+
+	if findJSON {
+		type jsonMatch struct {
+			Repo string `json:"repo"`
+			Path string `json:"path"`
+			Abs  string `json:"abs"`
+		}
+		output := make([]jsonMatch, len(matches))
+		for i, m := range matches {
+			output[i] = jsonMatch{Repo: m.Repo, Path: m.Path, Abs: m.Abs}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(output); err != nil {
+			return err
+		}
+	} else {
+		for _, m := range matches {
+			if findAbsolute {
+				fmt.Println(m.Abs)
+			} else {
+				fmt.Printf("%s:%s\n", m.Repo, m.Path)
+			}
+		}
+	}
+
+	for _, f := range failures {
+		fmt.Fprintf(os.Stderr, "Warning: %s: failed to list files: %v\n", f.Repo, f.Error)
+	}
+	if len(failures) > 0 {
+		return errcode.Wrap(errcode.Of(failures[0].Error), fmt.Errorf("%d repositories could not be searched", len(failures)))
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/manager"
+	"github.com/tierone/harbormaster/pkg/ui"
+)
+
+var (
+	fsckProject string
+	fsckTag     string
+	fsckRepair  bool
+	fsckJSON    bool
+)
+
+// fsckMaxProblems is how many of a repository's problems are printed.
+const fsckMaxProblems = 5
+
+var fsckCmd = &cobra.Command{
+	Use:   "fsck [repository...]",
+	Short: "Check repositories for corruption",
+	Long: `Check each git checkout with git fsck for missing or corrupt objects
+and broken refs.
+
+With --repair, or after confirming when run in a terminal, a corrupt
+checkout is repaired by fetching every object from origin again. If
+problems remain, the checkout is moved aside and cloned again from the
+URL recorded in the lock file, and the lock file is updated; the old
+checkout is deleted once the clone succeeds and put back if it fails.
+Checkouts with uncommitted changes or unpushed branches, or whose remote
+is unreachable, are left alone. Missing, vendored, and non-git
+repositories are skipped.
+
+Exits with status 3 (HM112) if any repository is left corrupt.`,
+	ValidArgsFunction: completeRepositories,
+	RunE:              runFsck,
+}
+
+func init() {
+	fsckCmd.Flags().StringVarP(&fsckProject, "project", "p", "", "check repositories in project")
+	fsckCmd.Flags().StringVarP(&fsckTag, "tag", "t", "", "check repositories with tag")
+	fsckCmd.Flags().BoolVar(&fsckRepair, "repair", false, "repair corrupt repositories without asking")
+	fsckCmd.Flags().BoolVar(&fsckJSON, "json", false, "output as JSON")
+
+	_ = fsckCmd.RegisterFlagCompletionFunc("project", completeProjects)
+	_ = fsckCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	rootCmd.AddCommand(fsckCmd)
+}
+
+func runFsck(cmd *cobra.Command, args []string) error {
+	filter := manager.Filter{}
+	if len(args) > 0 {
+		filter.Names = args
+	} else if fsckProject != "" {
+		filter.Projects = []string{fsckProject}
+	} else if fsckTag != "" {
+		filter.Tags = []string{fsckTag}
+	} else {
+		filter.All = true
+	}
+
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLockFile(lf),
+		manager.WithLogger(logger),
+		manager.WithVerbose(verboseOutput()),
+	)
+
+	ctx := context.Background()
+	results, err := mgr.Fsck(ctx, filter, fsckRepair)
+	if err != nil {
+		return err
+	}
+
+	// Offer to repair what was found
+	var corrupt []string
+	for _, r := range results {
+		if errcode.Of(r.Error) == errcode.Corrupt && r.Repaired == "" {
+			corrupt = append(corrupt, r.Name)
+		}
+	}
+	offered := len(corrupt) > 0 && !fsckRepair && !fsckJSON && stdinIsTerminal()
+	if offered {
+		printFsckProblems(results)
+		if confirm(fmt.Sprintf("Repair %d corrupt repositories?", len(corrupt))) {
+			repaired, err := mgr.Fsck(ctx, manager.Filter{Names: corrupt}, true)
+			if err != nil {
+				return err
+			}
+			byName := make(map[string]manager.RepoFsck, len(repaired))
+			for _, r := range repaired {
+				byName[r.Name] = r
+			}
+			for i, r := range results {
+				if rr, ok := byName[r.Name]; ok {
+					results[i] = rr
+				}
+			}
+		}
+	}
+
+	for _, r := range results {
+		if r.Repaired == manager.RepairRecloned {
+			if err := saveLockFile(); err != nil {
+				return fmt.Errorf("failed to save lock file: %w", err)
+			}
+			break
+		}
+	}
+
+	if fsckJSON {
+		if err := outputFsckJSON(results); err != nil {
+			return err
+		}
+	}
+
+	var firstErr error
+	failed, left := 0, 0
+	for _, r := range results {
+		switch {
+		case r.Repaired != "":
+			if !fsckJSON && !quiet {
+				fmt.Printf("%s  repaired (%s)\n", ui.TitleStyle.Render(r.Name), r.Repaired)
+			}
+		case errcode.Of(r.Error) == errcode.Corrupt:
+			left++
+			if !fsckJSON && !quiet {
+				fmt.Printf("%s  %v\n", ui.TitleStyle.Render(r.Name), r.Error)
+				if !offered {
+					printProblems(r.Problems)
+				}
+			}
+		case r.Error != nil:
+			if firstErr == nil {
+				firstErr = r.Error
+			}
+			failed++
+			fmt.Fprintf(os.Stderr, "  %s: %v\n", r.Name, r.Error)
+		}
+	}
+
+	if left > 0 {
+		return errcode.Wrap(errcode.Corrupt, fmt.Errorf("%d of %d repositories are corrupt", left, len(results)))
+	}
+	if firstErr != nil {
+		return errcode.Wrap(errcode.Of(firstErr), fmt.Errorf("could not check %d of %d repositories", failed, len(results)))
+	}
+	if !fsckJSON && !quiet {
+		fmt.Printf("All %d repositories are intact\n", len(results))
+	}
+	return nil
+}
+
+// printFsckProblems prints the problems found in each corrupt repository.
+func printFsckProblems(results []manager.RepoFsck) {
+	for _, r := range results {
+		if len(r.Problems) > 0 && r.Repaired == "" {
+			fmt.Println(ui.TitleStyle.Render(r.Name))
+			printProblems(r.Problems)
+		}
+	}
+}
+
+func printProblems(problems []string) {
+	for i, p := range problems {
+		if i == fsckMaxProblems {
+			fmt.Printf("  ... and %d more\n", len(problems)-i)
+			break
+		}
+		fmt.Printf("  %s\n", p)
+	}
+}
+
+func outputFsckJSON(results []manager.RepoFsck) error {
+	type jsonRepo struct {
+		Name     string   `json:"name"`
+		Problems []string `json:"problems"`
+		Repaired string   `json:"repaired,omitempty"`
+		Error    string   `json:"error,omitempty"`
+		Code     string   `json:"code,omitempty"`
+	}
+
+	output := make([]jsonRepo, len(results))
+	for i, r := range results {
+		output[i] = jsonRepo{Name: r.Name, Problems: r.Problems, Repaired: r.Repaired}
+		if output[i].Problems == nil {
+			output[i].Problems = []string{}
+		}
+		if r.Error != nil {
+			output[i].Error = r.Error.Error()
+			output[i].Code = string(errcode.Of(r.Error))
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/graph"
+	"github.com/tierone/harbormaster/pkg/manager"
+)
+
+var (
+	graphFormat  string
+	graphProject string
+	graphTag     string
+	graphNoTags  bool
+	graphOutput  string
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph [repository...]",
+	Short: "Render the workspace as a graph",
+	Long: `Render projects, repositories, tags, and the dependencies declared
+with depends_on as a Graphviz DOT or Mermaid diagram, for documentation
+and review:
+
+  hm graph | dot -Tsvg > workspace.svg
+  hm graph --format mermaid -o docs/workspace.mmd
+
+Repositories that the selected ones depend on are always included.`,
+	ValidArgsFunction: completeRepositories,
+	RunE:              runGraph,
+}
+
+func init() {
+	graphCmd.Flags().StringVar(&graphFormat, "format", "dot", "output format: dot or mermaid")
+	graphCmd.Flags().StringVarP(&graphProject, "project", "p", "", "graph repositories in project")
+	graphCmd.Flags().StringVarP(&graphTag, "tag", "t", "", "graph repositories with tag")
+	graphCmd.Flags().BoolVar(&graphNoTags, "no-tags", false, "leave out tags")
+	graphCmd.Flags().StringVarP(&graphOutput, "output", "o", "", "output file (default: stdout)")
+
+	_ = graphCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(graph.Formats, cobra.ShellCompDirectiveNoFileComp))
+	_ = graphCmd.RegisterFlagCompletionFunc("project", completeProjects)
+	_ = graphCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	rootCmd.AddCommand(graphCmd)
+}
+
+func runGraph(cmd *cobra.Command, args []string) error {
+	filter := manager.Filter{}
+	if len(args) > 0 {
+		filter.Names = args
+	} else if graphProject != "" {
+		filter.Projects = []string{graphProject}
+	} else if graphTag != "" {
+		filter.Tags = []string{graphTag}
+	} else {
+		filter.All = true
+	}
+
+	mgr := manager.NewRepositoryManager(cfg, manager.WithLogger(logger))
+	repos, err := mgr.Repositories(filter)
+	if err != nil {
+		return err
+	}
+
+	g := graph.New(cfg, repos, graph.Options{Tags: !graphNoTags})
+	data, err := graph.Render(g, graphFormat)
+	if err != nil {
+		return err
+	}
+
+	if graphOutput == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	if err := os.WriteFile(graphOutput, data, 0644); err != nil {
+		return fmt.Errorf("failed to write graph: %w", err)
+	}
+	if !quiet {
+		fmt.Printf("Wrote %s\n", graphOutput)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/manager"
+	"github.com/tierone/harbormaster/pkg/ui"
+)
+
+var (
+	healthProject string
+	healthTag     string
+	healthJSON    bool
+)
+
+var healthCmd = &cobra.Command{
+	Use:   "health [repository...]",
+	Short: "Audit the state of repositories",
+	Long: `Check each repository's checkout for states that need attention:
+
+  detached  HEAD is detached away from the tracked branch
+  branch    a different branch than the tracked one is checked out
+  diverged  the branch and its remote-tracking branch have diverged
+  remote    the origin remote is missing
+  shallow   a shallow clone where a full one is configured
+  lfs       Git LFS pointer files whose content was never fetched
+
+Repositories pinned to a tag or commit are expected to be detached, and
+those with checkout_mode = "branch" to be on their branch.
+Divergence is checked as of the last fetch; the remote is not contacted.
+Missing and non-git repositories are skipped.
+
+Exits with status 4 (HM204) if any repository has an issue.`,
+	ValidArgsFunction: completeRepositories,
+	RunE:              runHealth,
+}
+
+func init() {
+	healthCmd.Flags().StringVarP(&healthProject, "project", "p", "", "check repositories in project")
+	healthCmd.Flags().StringVarP(&healthTag, "tag", "t", "", "check repositories with tag")
+	healthCmd.Flags().BoolVar(&healthJSON, "json", false, "output as JSON")
+
+	_ = healthCmd.RegisterFlagCompletionFunc("project", completeProjects)
+	_ = healthCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	rootCmd.AddCommand(healthCmd)
+}
+
+func runHealth(cmd *cobra.Command, args []string) error {
+	filter := manager.Filter{}
+	if len(args) > 0 {
+		filter.Names = args
+	} else if healthProject != "" {
+		filter.Projects = []string{healthProject}
+	} else if healthTag != "" {
+		filter.Tags = []string{healthTag}
+	} else {
+		filter.All = true
+	}
+
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLogger(logger),
+		manager.WithVerbose(verboseOutput()),
+	)
+
+	results, err := mgr.Health(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+
+	if healthJSON {
+		if err := outputHealthJSON(results); err != nil {
+			return err
+		}
+	}
+
+	var firstErr error
+	failed, unhealthy := 0, 0
+	for _, h := range results {
+		if h.Error != nil {
+			if firstErr == nil {
+				firstErr = h.Error
+			}
+			failed++
+			fmt.Fprintf(os.Stderr, "  %s: %v\n", h.Name, h.Error)
+			continue
+		}
+		if len(h.Issues) == 0 {
+			continue
+		}
+		unhealthy++
+		if healthJSON || quiet {
+			continue
+		}
+		fmt.Println(ui.TitleStyle.Render(h.Name))
+		for _, issue := range h.Issues {
+			fmt.Printf("  %-9s %s\n", issue.Check, issue.Message)
+		}
+	}
+
+	if firstErr != nil {
+		return errcode.Wrap(errcode.Of(firstErr), fmt.Errorf("could not check %d of %d repositories", failed, len(results)))
+	}
+	if unhealthy > 0 {
+		if !healthJSON && !quiet {
+			fmt.Println()
+		}
+		return errcode.Wrap(errcode.Unhealthy, fmt.Errorf("%d of %d repositories have issues", unhealthy, len(results)))
+	}
+	if !healthJSON && !quiet {
+		fmt.Printf("All %d repositories are healthy\n", len(results))
+	}
+	return nil
+}
+
+func outputHealthJSON(results []manager.RepoHealth) error {
+	type jsonIssue struct {
+		Check   string `json:"check"`
+		Message string `json:"message"`
+	}
+	type jsonRepo struct {
+		Name   string      `json:"name"`
+		Issues []jsonIssue `json:"issues"`
+		Error  string      `json:"error,omitempty"`
+	}
+
+	output := make([]jsonRepo, len(results))
+	for i, h := range results {
+		output[i] = jsonRepo{Name: h.Name, Issues: []jsonIssue{}}
+		for _, issue := range h.Issues {
+			output[i].Issues = append(output[i].Issues, jsonIssue{Check: issue.Check, Message: issue.Message})
+		}
+		if h.Error != nil {
+			output[i].Error = h.Error.Error()
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/history"
+)
+
+var (
+	historyJSON  bool
+	historyLimit int
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show past operations",
+	Long: `Show past sync and remove operations, newest first.
+
+Each operation records when it ran, which repositories it targeted, the
+result for each repository, and how the lock file changed. Use
+'hm history show <id>' for the details of one operation.`,
+	Args: cobra.NoArgs,
+	RunE: runHistory,
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show the details of an operation",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runHistoryShow,
+}
+
+func init() {
+	historyCmd.PersistentFlags().BoolVar(&historyJSON, "json", false, "output as JSON")
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "number of operations to show (0 for all)")
+
+	historyCmd.AddCommand(historyShowCmd)
+	rootCmd.AddCommand(historyCmd)
+}
+
+// historyStore returns the history of the current workspace.
+func historyStore() *history.Store {
+	return history.NewStore(history.DefaultPath(getConfigDir()))
+}
+
+// recordHistory appends e to the workspace history. Failing to record is
+// reported but never fails the operation itself.
+func recordHistory(e *history.Entry) {
+	if err := historyStore().Append(e); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record history: %v\n", err)
+	}
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	entries, err := historyStore().List()
+	if err != nil {
+		return err
+	}
+
+	// Newest first
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	if historyLimit > 0 && len(entries) > historyLimit {
+		entries = entries[:historyLimit]
+	}
+
+	if historyJSON {
+		if entries == nil {
+			entries = []history.Entry{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No history recorded")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tTIME\tCOMMAND\tFILTER\tRESULT\tLOCK CHANGES")
+
+	for _, e := range entries {
+		result := "-"
+		if len(e.Results) > 0 {
+			result = fmt.Sprintf("%d ok", e.Succeeded())
+			if failed := e.Failed(); failed > 0 {
+				result += fmt.Sprintf(", %d failed", failed)
+			}
+		}
+
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%d\n",
+			e.ID,
+			e.Time.Local().Format("2006-01-02 15:04:05"),
+			e.Command,
+			e.Filter,
+			result,
+			len(e.LockChanges),
+		)
+	}
+
+	return w.Flush()
+}
+
+func runHistoryShow(cmd *cobra.Command, args []string) error {
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid history ID: %s", args[0])
+	}
+
+	e, err := historyStore().Get(id)
+	if err != nil {
+		return err
+	}
+
+	if historyJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(e)
+	}
+
+	fmt.Printf("Operation %d: %s\n", e.ID, e.Command)
+	fmt.Printf("  Time:     %s\n", e.Time.Local().Format(time.RFC3339))
+	if e.Filter != "" {
+		fmt.Printf("  Filter:   %s\n", e.Filter)
+	}
+	fmt.Printf("  Duration: %s\n", (time.Duration(e.DurationMs) * time.Millisecond).String())
+
+	if len(e.Results) > 0 {
+		fmt.Println()
+		fmt.Printf("Repositories (%d ok, %d failed):\n", e.Succeeded(), e.Failed())
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, r := range e.Results {
+			duration := (time.Duration(r.DurationMs) * time.Millisecond).String()
+			if r.Success {
+				_, _ = fmt.Fprintf(w, "  %s\tok\t%s\t%s\n", r.Name, shortSHA(r.SHA), duration)
+			} else {
+				_, _ = fmt.Fprintf(w, "  %s\tfailed\t%s\t%s\n", r.Name, r.Error, duration)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(e.LockChanges) > 0 {
+		fmt.Println()
+		fmt.Println("Lock file changes:")
+		for _, c := range e.LockChanges {
+			switch {
+			case c.Added():
+				fmt.Printf("  %s: added at %s\n", c.Name, shortSHA(c.After))
+			case c.Removed():
+				fmt.Printf("  %s: removed (was %s)\n", c.Name, shortSHA(c.Before))
+			default:
+				fmt.Printf("  %s: %s -> %s\n", c.Name, shortSHA(c.Before), shortSHA(c.After))
+			}
+		}
+	}
+
+	return nil
+}
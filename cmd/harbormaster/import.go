@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/importer"
+	"github.com/tierone/harbormaster/pkg/manager"
+)
+
+var (
+	importFormat  string
+	importProject string
+	importTags    []string
+	importDryRun  bool
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <manifest>",
+	Short: "Import repositories from another tool's manifest",
+	Long: `Import repositories from a multi-repo manifest into the configuration.
+
+Supported formats:
+  vcstool   .repos files
+  west      Zephyr west.yml manifests
+  gitman    gitman.yml files
+  list      one URL per line, as read by hm add --from-file
+
+The format is detected from the file name, or set with --format.
+Revisions are mapped to a commit if they look like a SHA, to a tag if
+they look like a version number, and to a branch otherwise.
+
+Repositories whose names already exist in the configuration are skipped.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImport,
+}
+
+func init() {
+	importCmd.Flags().StringVar(&importFormat, "format", "", "manifest format (vcstool, west, gitman, or list)")
+	importCmd.Flags().StringVarP(&importProject, "project", "p", "", "create a project containing the imported repositories")
+	importCmd.Flags().StringSliceVar(&importTags, "tags", nil, "tags to add to imported repositories")
+	importCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "show what would be imported without changing the configuration")
+
+	_ = importCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{"vcstool", "west", "gitman", "list"}, cobra.ShellCompDirectiveNoFileComp))
+
+	rootCmd.AddCommand(importCmd)
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	file := args[0]
+
+	format := importer.Format(importFormat)
+	if format == "" {
+		var err error
+		if format, err = importer.DetectFormat(file); err != nil {
+			return err
+		}
+	}
+
+	result, err := importer.ParseFile(file, format)
+	if err != nil {
+		return err
+	}
+
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLockFile(lf),
+	)
+
+	var added []string
+	for _, repo := range result.Repositories {
+		if _, exists := cfg.GetRepository(repo.Name); exists {
+			if !quiet {
+				fmt.Printf("Skipping %s: already configured\n", repo.Name)
+			}
+			continue
+		}
+
+		repo.Tags = append(repo.Tags, importTags...)
+		if !importDryRun {
+			if err := mgr.Add(repo); err != nil {
+				return fmt.Errorf("failed to add %s: %w", repo.Name, err)
+			}
+		}
+		added = append(added, repo.Name)
+
+		if !quiet {
+			fmt.Printf("Imported %s (%s) -> %s\n", repo.Name, repoRef(repo), repo.Path)
+		}
+	}
+
+	if !quiet {
+		for _, reason := range result.Skipped {
+			fmt.Printf("Skipping %s\n", reason)
+		}
+	}
+
+	if importDryRun {
+		return nil
+	}
+
+	if importProject != "" && len(added) > 0 {
+		proj := config.Project{
+			Name:         importProject,
+			Repositories: added,
+		}
+		if err := mgr.AddProject(proj); err != nil {
+			return err
+		}
+	}
+
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	if !quiet {
+		fmt.Printf("\nImported %d repositories from %s\n", len(added), format)
+	}
+
+	return nil
+}
+
+// repoRef returns a short description of the ref a repository tracks.
+func repoRef(repo config.Repository) string {
+	switch {
+	case repo.Commit != "":
+		return "commit " + repo.Commit
+	case repo.Tag != "":
+		return "tag " + repo.Tag
+	case repo.Branch != "":
+		return "branch " + repo.Branch
+	default:
+		return "default branch"
+	}
+}
+
+var (
+	scanProject string
+	scanTags    []string
+	scanDryRun  bool
+)
+
+var importScanCmd = &cobra.Command{
+	Use:   "scan [dir]",
+	Short: "Adopt existing git clones found in a directory",
+	Long: `Walk a directory inside the work directory (the work directory itself by
+default) for existing git clones and add a repository for each, with its
+origin URL and checked out branch, or the tag or commit a detached HEAD is
+at. The checked out commits are recorded in the lock file, so the clones
+are synced in place instead of being cloned again.
+
+Clones are named after their directory, or after their whole path when
+that name is taken. Hidden directories, the insides of clones, and clones
+already configured are skipped, as are clones without an origin remote.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runImportScan,
+}
+
+func init() {
+	importScanCmd.Flags().StringVarP(&scanProject, "project", "p", "", "create a project containing the adopted repositories")
+	importScanCmd.Flags().StringSliceVar(&scanTags, "tags", nil, "tags to add to adopted repositories")
+	importScanCmd.Flags().BoolVar(&scanDryRun, "dry-run", false, "show what would be adopted without changing the configuration")
+
+	importCmd.AddCommand(importScanCmd)
+}
+
+func runImportScan(cmd *cobra.Command, args []string) error {
+	dir := cfg.General.WorkDir
+	if len(args) > 0 {
+		dir = args[0]
+	}
+
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLockFile(lf),
+	)
+
+	checkouts, skipped, err := mgr.ScanCheckouts(dir)
+	if err != nil {
+		return err
+	}
+	if !quiet {
+		for _, reason := range skipped {
+			fmt.Printf("Skipping %s\n", reason)
+		}
+	}
+	if len(checkouts) == 0 {
+		if !quiet {
+			fmt.Printf("No git clones to adopt in %s\n", dir)
+		}
+		return nil
+	}
+
+	names := mgr.CheckoutNames(checkouts)
+	if scanDryRun {
+		if !quiet {
+			for i, c := range checkouts {
+				fmt.Printf("Would adopt %s (%s) at %s\n", names[i], repoRef(c.Repository(names[i])), c.Path)
+			}
+		}
+		return nil
+	}
+
+	repos, err := mgr.Adopt(checkouts, names, scanTags)
+	if err != nil {
+		return fmt.Errorf("no repositories adopted:\n%w", err)
+	}
+	if scanProject != "" {
+		if err := mgr.AddProject(config.Project{Name: scanProject, Repositories: names}); err != nil {
+			return err
+		}
+	}
+
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	if err := saveLockFile(); err != nil {
+		return fmt.Errorf("failed to save lock file: %w", err)
+	}
+
+	if !quiet {
+		for i, repo := range repos {
+			fmt.Printf("Adopted %s (%s) at %s, locked at %s\n", repo.Name, repoRef(repo), repo.Path, checkouts[i].SHA[:8])
+		}
+		fmt.Printf("\nAdopted %d repositories\n", len(repos))
+	}
+	return nil
+}
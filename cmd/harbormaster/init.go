@@ -4,15 +4,20 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/downloader"
 	"github.com/tierone/harbormaster/pkg/lockfile"
+	"github.com/tierone/harbormaster/pkg/manager"
 )
 
 var (
-	initForce   bool
-	initExample bool
+	initForce    bool
+	initExample  bool
+	initTemplate string
+	initUpstream string
 )
 
 var initCmd = &cobra.Command{
@@ -22,13 +27,25 @@ var initCmd = &cobra.Command{
 file (.harbormaster.toml) and lock file (.harbormaster.lock) in the
 current directory.
 
-Use --example to include example repository entries in the configuration.`,
+Use --example to include example repository entries in the configuration.
+
+Use --template to bootstrap from a shared workspace definition. The
+template may be a git repository containing a .harbormaster.toml at its
+root, an HTTP(S) URL of a config file, or a local file or directory. A
+.harbormaster.lock next to the template config is copied as well.
+
+Use --from-config to link the workspace to a centrally published config
+instead. Its repositories and projects are merged into the workspace, and
+'hm config pull' refreshes them; local definitions take precedence.`,
 	RunE: runInit,
 }
 
 func init() {
 	initCmd.Flags().BoolVarP(&initForce, "force", "f", false, "overwrite existing configuration")
 	initCmd.Flags().BoolVar(&initExample, "example", false, "include example repository entries")
+	initCmd.Flags().StringVar(&initTemplate, "template", "", "initialize from a template repository, URL, or path")
+	initCmd.Flags().StringVar(&initUpstream, "from-config", "", "link the workspace to an upstream config URL")
+	initCmd.MarkFlagsMutuallyExclusive("example", "template", "from-config")
 	rootCmd.AddCommand(initCmd)
 }
 
@@ -46,8 +63,16 @@ func runInit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("config file already exists: %s\nUse --force to overwrite", configPath)
 	}
 
+	if initTemplate != "" {
+		return initFromTemplate(initTemplate, configPath, lockPath)
+	}
+
 	// Create default config
 	cfg := config.NewDefaultConfig()
+	cfg.Upstream.URL = initUpstream
+	if err := config.ValidateConfig(cfg); err != nil {
+		return err
+	}
 
 	// Add example repositories if requested
 	if initExample {
@@ -81,11 +106,23 @@ func runInit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create lock file: %w", err)
 	}
 
+	// Fetch the initial copy of the upstream config
+	var changes *manager.UpstreamChanges
+	if cfg.Upstream.Enabled() {
+		if changes, err = manager.NewRepositoryManager(cfg).PullUpstream(); err != nil {
+			return err
+		}
+	}
+
 	if !quiet {
 		fmt.Println("Initialized Harbormaster workspace:")
 		fmt.Printf("  Config: %s\n", configPath)
 		fmt.Printf("  Lock:   %s\n", lockPath)
-		if initExample {
+		if changes != nil {
+			fmt.Println()
+			printUpstreamChanges(cfg.Upstream.URL, changes)
+			fmt.Println("\nRun 'hm sync' to fetch the upstream repositories.")
+		} else if initExample {
 			fmt.Println("\nExample configuration created. Edit the config file to add your repositories.")
 		} else {
 			fmt.Println("\nEdit the config file to add your repositories, then run 'hm sync'.")
@@ -94,3 +131,119 @@ func runInit(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// initFromTemplate copies the config, and lock file if present, from a
+// template into the workspace. The template config is validated before
+// anything is written and copied verbatim to preserve its comments.
+func initFromTemplate(src, configPath, lockPath string) error {
+	tmpDir, err := os.MkdirTemp("", "hm-template-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	tmplConfig, tmplLock, err := fetchTemplate(src, tmpDir)
+	if err != nil {
+		return err
+	}
+
+	if _, err := config.Load(tmplConfig); err != nil {
+		return fmt.Errorf("invalid template config: %w", err)
+	}
+
+	if err := copyFile(tmplConfig, configPath); err != nil {
+		return fmt.Errorf("failed to create config file: %w", err)
+	}
+
+	if tmplLock != "" {
+		if _, err := lockfile.Load(tmplLock); err != nil {
+			return fmt.Errorf("invalid template lock file: %w", err)
+		}
+		if err := copyFile(tmplLock, lockPath); err != nil {
+			return fmt.Errorf("failed to create lock file: %w", err)
+		}
+	} else if err := lockfile.New().Save(lockPath); err != nil {
+		return fmt.Errorf("failed to create lock file: %w", err)
+	}
+
+	if !quiet {
+		fmt.Printf("Initialized Harbormaster workspace from template %s:\n", src)
+		fmt.Printf("  Config: %s\n", configPath)
+		fmt.Printf("  Lock:   %s\n", lockPath)
+		if tmplLock != "" {
+			fmt.Println("\nRun 'hm sync --locked' to reproduce the template workspace.")
+		} else {
+			fmt.Println("\nRun 'hm sync' to fetch the template repositories.")
+		}
+	}
+
+	return nil
+}
+
+// fetchTemplate retrieves a template into tmpDir and returns the paths of
+// its config file and lock file. The lock path is empty if the template
+// has none.
+func fetchTemplate(src, tmpDir string) (string, string, error) {
+	// Local file or directory
+	if info, err := os.Stat(src); err == nil {
+		configPath := src
+		if info.IsDir() {
+			configPath = filepath.Join(src, config.ConfigFileName)
+		}
+		if _, err := os.Stat(configPath); err != nil {
+			return "", "", fmt.Errorf("template has no %s: %s", config.ConfigFileName, src)
+		}
+		return configPath, optionalFile(filepath.Join(filepath.Dir(configPath), lockfile.LockFileName)), nil
+	}
+
+	// Single config file over HTTP(S)
+	if downloader.DetectType(src) == config.RepoTypeHTTP {
+		opts := downloader.DefaultOptions()
+		configPath := filepath.Join(tmpDir, config.ConfigFileName)
+		if _, err := downloader.NewHTTPDownloader(opts).Download(src, configPath); err != nil {
+			return "", "", fmt.Errorf("failed to download template: %w", err)
+		}
+
+		// Look for a lock file next to the config without retrying
+		var lockPath string
+		if strings.HasSuffix(src, "/"+config.ConfigFileName) {
+			lockURL := strings.TrimSuffix(src, config.ConfigFileName) + lockfile.LockFileName
+			opts.RetryAttempts = 0
+			lockPath = filepath.Join(tmpDir, lockfile.LockFileName)
+			if _, err := downloader.NewHTTPDownloader(opts).Download(lockURL, lockPath); err != nil {
+				lockPath = ""
+			}
+		}
+		return configPath, lockPath, nil
+	}
+
+	// Git repository
+	repoDir := filepath.Join(tmpDir, "repo")
+	opts := downloader.DefaultOptions()
+	opts.Submodules = false
+	if _, err := downloader.NewGitDownloader(opts).Download(src, repoDir); err != nil {
+		return "", "", fmt.Errorf("failed to clone template: %w", err)
+	}
+	configPath := filepath.Join(repoDir, config.ConfigFileName)
+	if _, err := os.Stat(configPath); err != nil {
+		return "", "", fmt.Errorf("template has no %s: %s", config.ConfigFileName, src)
+	}
+	return configPath, optionalFile(filepath.Join(repoDir, lockfile.LockFileName)), nil
+}
+
+// optionalFile returns path if it exists, or an empty string.
+func optionalFile(path string) string {
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// copyFile copies the content of src to dest.
+func copyFile(src, dest string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dest, data, 0644)
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/manager"
+)
+
+var (
+	licensesProject string
+	licensesTag     string
+	licensesFormat  string
+)
+
+// licensesFormats are the output formats of hm licenses.
+var licensesFormats = []string{"table", "json", "csv"}
+
+var licensesCmd = &cobra.Command{
+	Use:   "licenses [repository...]",
+	Short: "Report the licenses of repositories",
+	Long: `Find the license files of each synced repository and report their
+licenses as SPDX identifiers, with a count of repositories per license,
+for third-party software compliance reviews.
+
+License files are LICENSE, LICENCE, COPYING, UNLICENSE, and their variants
+(LICENSE.md, LICENSE-MIT, COPYING.LESSER, ...) at the top of a repository,
+and the files of a REUSE LICENSES directory. A file is identified by its
+SPDX-License-Identifier line, by its name under LICENSES, or by the wording
+of common licenses; others are reported as unknown. Repositories without
+license files are reported as none, and missing ones as missing.
+
+--format csv prints one row per license file, and a row without a file
+for repositories that have none.
+
+Examples:
+  hm licenses
+  hm licenses --format csv > licenses.csv
+  hm licenses -p backend --format json`,
+	ValidArgsFunction: completeRepositories,
+	RunE:              runLicenses,
+}
+
+func init() {
+	licensesCmd.Flags().StringVarP(&licensesProject, "project", "p", "", "report repositories in project")
+	licensesCmd.Flags().StringVarP(&licensesTag, "tag", "t", "", "report repositories with tag")
+	licensesCmd.Flags().StringVar(&licensesFormat, "format", "table", "output format: table, json, or csv")
+
+	_ = licensesCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(licensesFormats, cobra.ShellCompDirectiveNoFileComp))
+	_ = licensesCmd.RegisterFlagCompletionFunc("project", completeProjects)
+	_ = licensesCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	rootCmd.AddCommand(licensesCmd)
+}
+
+func runLicenses(cmd *cobra.Command, args []string) error {
+	format := strings.ToLower(licensesFormat)
+	if !slices.Contains(licensesFormats, format) {
+		return fmt.Errorf("unsupported format: %s (must be %s)", licensesFormat, strings.Join(licensesFormats, ", "))
+	}
+
+	filter := manager.Filter{}
+	if len(args) > 0 {
+		filter.Names = args
+	} else if licensesProject != "" {
+		filter.Projects = []string{licensesProject}
+	} else if licensesTag != "" {
+		filter.Tags = []string{licensesTag}
+	} else {
+		filter.All = true
+	}
+
+	mgr := manager.NewRepositoryManager(cfg, manager.WithLogger(logger))
+
+	results, err := mgr.Licenses(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		err = outputLicensesJSON(results)
+	case "csv":
+		err = outputLicensesCSV(results)
+	default:
+		outputLicensesTable(results)
+	}
+
+	for _, r := range results {
+		if r.Error != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s: failed to read license files: %v\n", r.Name, r.Error)
+		}
+	}
+	return err
+}
+
+// licenseSummary returns how a repository is licensed, as its SPDX
+// identifiers, or "unknown", "none", or "missing".
+func licenseSummary(r manager.RepoLicenses) []string {
+	switch ids := r.Licenses(); {
+	case !r.Exists:
+		return []string{"missing"}
+	case len(ids) > 0:
+		return ids
+	case len(r.Files) > 0:
+		return []string{"unknown"}
+	default:
+		return []string{"none"}
+	}
+}
+
+// licenseCounts returns the number of repositories under each license,
+// most common first.
+func licenseCounts(results []manager.RepoLicenses) ([]string, map[string]int) {
+	counts := make(map[string]int)
+	for _, r := range results {
+		for _, id := range licenseSummary(r) {
+			counts[id]++
+		}
+	}
+	ids := make([]string, 0, len(counts))
+	for id := range counts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if counts[ids[i]] != counts[ids[j]] {
+			return counts[ids[i]] > counts[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids, counts
+}
+
+func outputLicensesTable(results []manager.RepoLicenses) {
+	columns := []tableColumn{
+		{title: "REPOSITORY"},
+		{title: "LICENSE"},
+		{title: "FILES"},
+	}
+	var rows [][]tableCell
+	for _, r := range results {
+		files := make([]string, len(r.Files))
+		for i, f := range r.Files {
+			files[i] = f.Path
+		}
+		rows = append(rows, []tableCell{
+			plainCell(r.Name),
+			plainCell(strings.Join(licenseSummary(r), ", ")),
+			plainCell(strings.Join(files, ", ")),
+		})
+	}
+	printTable(columns, columnWidths(columns, rows), rows)
+
+	ids, counts := licenseCounts(results)
+	if len(ids) == 0 {
+		return
+	}
+	columns = []tableColumn{
+		{title: "LICENSE"},
+		{title: "REPOSITORIES", right: true},
+	}
+	rows = nil
+	for _, id := range ids {
+		rows = append(rows, []tableCell{plainCell(id), plainCell(strconv.Itoa(counts[id]))})
+	}
+	fmt.Println()
+	printTable(columns, columnWidths(columns, rows), rows)
+}
+
+func outputLicensesJSON(results []manager.RepoLicenses) error {
+	type jsonFile struct {
+		Path string `json:"path"`
+		SPDX string `json:"spdx,omitempty"`
+	}
+	type jsonRepo struct {
+		Name     string     `json:"name"`
+		URL      string     `json:"url"`
+		Path     string     `json:"path"`
+		Exists   bool       `json:"exists"`
+		Licenses []string   `json:"licenses"`
+		Files    []jsonFile `json:"files"`
+		Error    string     `json:"error,omitempty"`
+	}
+	type jsonReport struct {
+		Repositories []jsonRepo     `json:"repositories"`
+		Summary      map[string]int `json:"summary"`
+	}
+
+	_, counts := licenseCounts(results)
+	output := jsonReport{Repositories: make([]jsonRepo, len(results)), Summary: counts}
+	for i, r := range results {
+		repo := jsonRepo{
+			Name:     r.Name,
+			URL:      r.URL,
+			Path:     r.Path,
+			Exists:   r.Exists,
+			Licenses: licenseSummary(r),
+			Files:    []jsonFile{},
+		}
+		for _, f := range r.Files {
+			repo.Files = append(repo.Files, jsonFile{Path: f.Path, SPDX: f.SPDX})
+		}
+		if r.Error != nil {
+			repo.Error = r.Error.Error()
+		}
+		output.Repositories[i] = repo
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
+
+func outputLicensesCSV(results []manager.RepoLicenses) error {
+	w := csv.NewWriter(os.Stdout)
+	_ = w.Write([]string{"repository", "url", "license", "file"})
+	for _, r := range results {
+		if len(r.Files) == 0 {
+			_ = w.Write([]string{r.Name, r.URL, licenseSummary(r)[0], ""})
+			continue
+		}
+		for _, f := range r.Files {
+			license := f.SPDX
+			if license == "" {
+				license = "unknown"
+			}
+			_ = w.Write([]string{r.Name, r.URL, license, f.Path})
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -4,16 +4,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/config"
 )
 
 var (
 	listJSON    bool
 	listProject string
 	listTag     string
+	listSort    string
+	listColumns string
 )
 
 var listCmd = &cobra.Command{
@@ -48,18 +52,54 @@ func init() {
 	listCmd.PersistentFlags().StringVarP(&listProject, "project", "p", "", "filter by project")
 	listCmd.PersistentFlags().StringVarP(&listTag, "tag", "t", "", "filter by tag")
 
+	listReposCmd.Flags().StringVar(&listSort, "sort", "", "sort by "+strings.Join(listSortKeys, ", "))
+	listReposCmd.Flags().StringVar(&listColumns, "columns", "", "comma-separated table columns: "+columnNames(listTableColumns))
+
+	_ = listCmd.RegisterFlagCompletionFunc("project", completeProjects)
+	_ = listCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	_ = listReposCmd.RegisterFlagCompletionFunc("sort", cobra.FixedCompletions(listSortKeys, cobra.ShellCompDirectiveNoFileComp))
+
 	listCmd.AddCommand(listReposCmd)
 	listCmd.AddCommand(listProjectsCmd)
 	listCmd.AddCommand(listTagsCmd)
 	rootCmd.AddCommand(listCmd)
 }
 
+// listTableColumns are the columns available to list repos.
+var listTableColumns = []tableColumn{
+	{name: "name", title: "NAME"},
+	{name: "type", title: "TYPE"},
+	{name: "ref", title: "REF"},
+	{name: "path", title: "PATH"},
+	{name: "tags", title: "TAGS"},
+	{name: "url", title: "URL"},
+	{name: "age", title: "SYNCED"},
+	{name: "source", title: "SOURCE"},
+}
+
+// listSortKeys are the values accepted by list repos --sort.
+var listSortKeys = []string{"name", "type", "path", "age"}
+
 func runListRepos(cmd *cobra.Command, args []string) error {
+	if listSort != "" {
+		if err := checkSortKey(listSort, listSortKeys); err != nil {
+			return err
+		}
+	}
+	defaults := []string{"name", "type", "ref", "path", "tags"}
+	if len(cfg.Sources) > 0 {
+		// Mark the repositories that sources add
+		defaults = append(defaults, "source")
+	}
+	columns, err := selectColumns(listColumns, listTableColumns, defaults)
+	if err != nil {
+		return err
+	}
+
 	repos := cfg.Repositories
 
 	// Filter by project
 	if listProject != "" {
-		var err error
 		repos, err = cfg.GetRepositoriesForProject(listProject)
 		if err != nil {
 			return err
@@ -76,29 +116,41 @@ func runListRepos(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	if listSort != "" {
+		repos = sortRepositories(repos, listSort)
+	}
+
 	if listJSON {
 		type jsonRepo struct {
-			Name   string   `json:"name"`
-			URL    string   `json:"url"`
-			Type   string   `json:"type"`
-			Path   string   `json:"path"`
-			Branch string   `json:"branch,omitempty"`
-			Tag    string   `json:"tag,omitempty"`
-			Commit string   `json:"commit,omitempty"`
-			Tags   []string `json:"tags,omitempty"`
+			Name    string   `json:"name"`
+			URL     string   `json:"url"`
+			Type    string   `json:"type"`
+			Path    string   `json:"path"`
+			Branch  string   `json:"branch,omitempty"`
+			Tag     string   `json:"tag,omitempty"`
+			Commit  string   `json:"commit,omitempty"`
+			Version string   `json:"version,omitempty"`
+			Ref     string   `json:"ref,omitempty"`
+			AsOf    string   `json:"as_of,omitempty"`
+			Tags    []string `json:"tags,omitempty"`
+			Source  string   `json:"source,omitempty"`
 		}
 
 		output := make([]jsonRepo, len(repos))
 		for i, r := range repos {
 			output[i] = jsonRepo{
-				Name:   r.Name,
-				URL:    r.URL,
-				Type:   string(r.Type),
-				Path:   r.GetEffectivePath(),
-				Branch: r.Branch,
-				Tag:    r.Tag,
-				Commit: r.Commit,
-				Tags:   r.Tags,
+				Name:    r.Name,
+				URL:     r.URL,
+				Type:    string(r.Type),
+				Path:    r.GetEffectivePath(),
+				Branch:  r.Branch,
+				Tag:     r.Tag,
+				Commit:  r.Commit,
+				Version: r.Version,
+				Ref:     r.Ref,
+				AsOf:    r.AsOf,
+				Tags:    r.Tags,
+				Source:  cfg.SourceOf(r.Name),
 			}
 		}
 
@@ -107,13 +159,34 @@ func runListRepos(cmd *cobra.Command, args []string) error {
 		return enc.Encode(output)
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	_, _ = fmt.Fprintln(w, "NAME\tTYPE\tREF\tPATH\tTAGS")
+	rows := make([][]tableCell, len(repos))
+	for i, r := range repos {
+		row := make([]tableCell, len(columns))
+		for c, col := range columns {
+			row[c] = plainCell(repositoryCell(r, col.name))
+		}
+		rows[i] = row
+	}
+	printTable(columns, columnWidths(columns, rows), rows)
+	return nil
+}
 
-	for _, r := range repos {
+// repositoryCell returns the value of the named list column for a
+// repository.
+func repositoryCell(r config.Repository, column string) string {
+	switch column {
+	case "name":
+		return r.Name
+	case "type":
+		return string(r.Type)
+	case "ref":
 		ref := r.Branch
 		if r.Tag != "" {
 			ref = "tag:" + r.Tag
+		} else if r.Version != "" {
+			ref = "version:" + r.Version
+		} else if r.Ref != "" {
+			ref = r.Ref
 		} else if r.Commit != "" {
 			if len(r.Commit) > 8 {
 				ref = r.Commit[:8]
@@ -124,22 +197,53 @@ func runListRepos(cmd *cobra.Command, args []string) error {
 		if ref == "" {
 			ref = cfg.General.DefaultBranch
 		}
-
-		tags := "-"
-		if len(r.Tags) > 0 {
-			tags = strings.Join(r.Tags, ", ")
+		if r.AsOf != "" {
+			ref += "@" + r.AsOf
 		}
-
-		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
-			r.Name,
-			r.Type,
-			ref,
-			r.GetEffectivePath(),
-			tags,
-		)
+		return ref
+	case "path":
+		return r.GetEffectivePath()
+	case "tags":
+		if len(r.Tags) == 0 {
+			return "-"
+		}
+		return strings.Join(r.Tags, ", ")
+	case "url":
+		return r.URL
+	case "age":
+		return formatAge(lastSynced(r.Name))
+	case "source":
+		if src := cfg.SourceOf(r.Name); src != "" {
+			return src
+		}
+		return "-"
 	}
+	return ""
+}
 
-	return w.Flush()
+// sortRepositories returns a copy of repos ordered by the --sort key.
+// Sorting by age puts repositories synced longest ago first.
+func sortRepositories(repos []config.Repository, key string) []config.Repository {
+	sorted := append([]config.Repository(nil), repos...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		switch key {
+		case "type":
+			if a.Type != b.Type {
+				return a.Type < b.Type
+			}
+		case "path":
+			if a.GetEffectivePath() != b.GetEffectivePath() {
+				return a.GetEffectivePath() < b.GetEffectivePath()
+			}
+		case "age":
+			if at, bt := lastSynced(a.Name), lastSynced(b.Name); !at.Equal(bt) {
+				return at.Before(bt)
+			}
+		}
+		return a.Name < b.Name
+	})
+	return sorted
 }
 
 func runListProjects(cmd *cobra.Command, args []string) error {
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/manager"
+)
+
+var (
+	logSince   string
+	logProject string
+	logTag     string
+	logFetch   bool
+	logLimit   int
+	logJSON    bool
+)
+
+var logCmd = &cobra.Command{
+	Use:   "log [repository...]",
+	Short: "Show commits across repositories",
+	Long: `Show the commits of the selected repositories in one chronological
+view, newest first, with the repository, author, and subject of each.
+
+--since accepts any date git does, such as "2024-05-01" or "1 week ago",
+or "last-sync" to show, for each repository, the commits made since it
+was last synced according to the lock file.
+
+By default the checked-out HEAD of each repository is logged. Use
+--fetch to log each repository's configured ref on the remote instead,
+which with --since last-sync shows what a sync would bring in.`,
+	ValidArgsFunction: completeRepositories,
+	RunE:              runLog,
+}
+
+func init() {
+	logCmd.Flags().StringVar(&logSince, "since", "", `show commits after a date, or "last-sync"`)
+	logCmd.Flags().StringVarP(&logProject, "project", "p", "", "log repositories in project")
+	logCmd.Flags().StringVarP(&logTag, "tag", "t", "", "log repositories with tag")
+	logCmd.Flags().BoolVar(&logFetch, "fetch", false, "log the configured refs fetched from the remote")
+	logCmd.Flags().IntVarP(&logLimit, "limit", "n", 50, "number of commits to show (0 for all)")
+	logCmd.Flags().BoolVar(&logJSON, "json", false, "output as JSON")
+
+	_ = logCmd.RegisterFlagCompletionFunc("project", completeProjects)
+	_ = logCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	rootCmd.AddCommand(logCmd)
+}
+
+func runLog(cmd *cobra.Command, args []string) error {
+	filter := manager.Filter{}
+	if len(args) > 0 {
+		filter.Names = args
+	} else if logProject != "" {
+		filter.Projects = []string{logProject}
+	} else if logTag != "" {
+		filter.Tags = []string{logTag}
+	} else {
+		filter.All = true
+	}
+
+	opts := manager.LogOptions{
+		Since: logSince,
+		Fetch: logFetch,
+		Limit: logLimit,
+	}
+	if logSince == "last-sync" {
+		opts.Since = ""
+		opts.SinceLastSync = true
+	}
+
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLockFile(lf),
+		manager.WithLogger(logger),
+		manager.WithVerbose(verboseOutput()),
+	)
+
+	entries, failures, err := mgr.Log(context.Background(), filter, opts)
+	if err != nil {
+		return err
+	}
+
+	if logJSON {
+		if err := outputLogJSON(entries); err != nil {
+			return err
+		}
+	} else if len(entries) == 0 {
+		fmt.Println("No commits found")
+	} else {
+		columns := []tableColumn{
+			{title: "DATE"},
+			{title: "REPOSITORY"},
+			{title: "COMMIT"},
+			{title: "AUTHOR"},
+			{title: "SUBJECT"},
+		}
+		rows := make([][]tableCell, len(entries))
+		for i, e := range entries {
+			rows[i] = []tableCell{
+				plainCell(e.Time.Local().Format("2006-01-02 15:04")),
+				plainCell(e.Repo),
+				plainCell(shortCommit(e.SHA)),
+				plainCell(e.Author),
+				plainCell(e.Subject),
+			}
+		}
+		printTable(columns, columnWidths(columns, rows), rows)
+	}
+
+	for _, f := range failures {
+		fmt.Fprintf(os.Stderr, "Warning: %s: failed to list commits: %v\n", f.Repo, f.Error)
+	}
+	if len(failures) > 0 {
+		return errcode.Wrap(errcode.Of(failures[0].Error), fmt.Errorf("%d repositories could not be logged", len(failures)))
+	}
+	return nil
+}
+
+func outputLogJSON(entries []manager.LogEntry) error {
+	type jsonCommit struct {
+		Repo    string    `json:"repo"`
+		SHA     string    `json:"sha"`
+		Author  string    `json:"author"`
+		Time    time.Time `json:"time"`
+		Subject string    `json:"subject"`
+	}
+
+	output := make([]jsonCommit, len(entries))
+	for i, e := range entries {
+		output[i] = jsonCommit{
+			Repo:    e.Repo,
+			SHA:     e.SHA,
+			Author:  e.Author,
+			Time:    e.Time,
+			Subject: e.Subject,
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+
+	"github.com/tierone/harbormaster/pkg/errcode"
 )
 
 var version = "dev"
@@ -10,7 +13,26 @@ var version = "dev"
 func main() {
 	rootCmd.Version = version
 	if err := Execute(); err != nil {
-		_, _ = fmt.Fprintln(os.Stderr, "Error:", err)
-		os.Exit(1)
+		var exit *exitStatusError
+		if errors.As(err, &exit) {
+			os.Exit(exit.status)
+		}
+		code := errcode.Of(err)
+		if code != "" {
+			_, _ = fmt.Fprintf(os.Stderr, "Error [%s]: %v\n", code, err)
+		} else {
+			_, _ = fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+		os.Exit(code.ExitStatus())
 	}
 }
+
+// exitStatusError ends a command with a specific exit status and no
+// message, for commands that report a state through their exit status.
+type exitStatusError struct {
+	status int
+}
+
+func (e *exitStatusError) Error() string {
+	return fmt.Sprintf("exit status %d", e.status)
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/errcode"
 	"github.com/tierone/harbormaster/pkg/manager"
 )
 
@@ -42,29 +43,33 @@ var projectRemoveCmd = &cobra.Command{
 	Long: `Remove a project from the configuration.
 
 This only removes the project grouping; repositories are not affected.`,
-	Args: cobra.ExactArgs(1),
-	RunE: runProjectRemove,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProject,
+	RunE:              runProjectRemove,
 }
 
 var projectAddRepoCmd = &cobra.Command{
-	Use:   "add-repo <project> <repository>",
-	Short: "Add a repository to a project",
-	Args:  cobra.ExactArgs(2),
-	RunE:  runProjectAddRepo,
+	Use:               "add-repo <project> <repository>",
+	Short:             "Add a repository to a project",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeProjectRepository,
+	RunE:              runProjectAddRepo,
 }
 
 var projectRemoveRepoCmd = &cobra.Command{
-	Use:     "remove-repo <project> <repository>",
-	Aliases: []string{"rm-repo"},
-	Short:   "Remove a repository from a project",
-	Args:    cobra.ExactArgs(2),
-	RunE:    runProjectRemoveRepo,
+	Use:               "remove-repo <project> <repository>",
+	Aliases:           []string{"rm-repo"},
+	Short:             "Remove a repository from a project",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeProjectRepository,
+	RunE:              runProjectRemoveRepo,
 }
 
 func init() {
 	// project add flags
 	projectAddCmd.Flags().StringSliceVarP(&projectRepos, "repos", "r", nil,
 		"initial repositories (comma-separated)")
+	_ = projectAddCmd.RegisterFlagCompletionFunc("repos", completeRepositories)
 	projectAddCmd.Flags().StringSliceVarP(&projectTags, "tags", "t", nil,
 		"project tags")
 
@@ -123,7 +128,7 @@ func runProjectRemove(cmd *cobra.Command, args []string) error {
 	// Check if project exists
 	proj, ok := cfg.GetProject(name)
 	if !ok {
-		return fmt.Errorf("project not found: %s", name)
+		return errcode.Wrap(errcode.UnknownProject, fmt.Errorf("project not found: %s", name))
 	}
 
 	// Confirm if not forced
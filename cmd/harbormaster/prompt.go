@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/manager"
+	"github.com/tierone/harbormaster/pkg/statuscache"
+)
+
+var (
+	promptMaxAge    time.Duration
+	promptRefresh   bool
+	promptNoRefresh bool
+)
+
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Print a one-line workspace summary for shell prompts",
+	Long: `Print a one-line summary of the workspace, such as
+"12 ok · 2 drift · 1 dirty", from the status cached by the last 'hm status'
+of the whole workspace. Nothing is printed outside a workspace or before
+the first status.
+
+When the cache is older than --max-age, or than the config or lock file,
+the cached summary is printed and a refresh is started in the background,
+so the command always returns in milliseconds. For example, in bash:
+
+  PS1='\w $(hm prompt 2>/dev/null)\$ '`,
+	Args: cobra.NoArgs,
+	RunE: runPrompt,
+}
+
+func init() {
+	promptCmd.Flags().DurationVar(&promptMaxAge, "max-age", time.Minute, "refresh the cache when it is older than this")
+	promptCmd.Flags().BoolVar(&promptRefresh, "refresh", false, "update the cache now instead of printing it")
+	promptCmd.Flags().BoolVar(&promptNoRefresh, "no-refresh", false, "never start a background refresh")
+	rootCmd.AddCommand(promptCmd)
+}
+
+func runPrompt(cmd *cobra.Command, args []string) error {
+	// Outside a workspace the prompt stays empty rather than failing
+	if err := loadWorkspace(); err != nil {
+		return nil
+	}
+	path := statusCachePath()
+
+	if promptRefresh {
+		defer statuscache.ReleaseRefresh(path)
+		mgr := manager.NewRepositoryManager(cfg, manager.WithLockFile(lf), manager.WithLogger(logger))
+		statuses, err := mgr.Status(manager.Filter{All: true})
+		if err != nil {
+			return err
+		}
+		return statuscache.Save(path, statuscache.New(statuses))
+	}
+
+	summary, err := statuscache.Load(path)
+	if err != nil || summary.Stale(promptMaxAge, cfg.Path(), getLockFilePath()) {
+		if !promptNoRefresh && statuscache.ClaimRefresh(path) {
+			startPromptRefresh(path)
+		}
+	}
+	if summary != nil {
+		if s := summary.String(); s != "" {
+			fmt.Println(s)
+		}
+	}
+	return nil
+}
+
+// startPromptRefresh runs 'hm prompt --refresh' for this workspace in the
+// background without waiting for it.
+func startPromptRefresh(path string) {
+	exe, err := os.Executable()
+	if err != nil {
+		statuscache.ReleaseRefresh(path)
+		return
+	}
+	refresh := exec.Command(exe, "--config", cfg.Path(), "prompt", "--refresh")
+	if err := refresh.Start(); err != nil {
+		statuscache.ReleaseRefresh(path)
+		return
+	}
+	_ = refresh.Process.Release()
+}
+
+// statusCachePath returns the status cache of the workspace.
+func statusCachePath() string {
+	return statuscache.DefaultPath(getConfigDir())
+}
+
+// saveStatusCache updates the status cache from a status of the whole
+// workspace. The cache only serves prompts, so failures are ignored.
+func saveStatusCache(statuses []manager.RepoStatus) {
+	if cfg.Path() == "" {
+		return
+	}
+	if err := statuscache.Save(statusCachePath(), statuscache.New(statuses)); err != nil {
+		logger.Debug("failed to save status cache", "error", err)
+	}
+}
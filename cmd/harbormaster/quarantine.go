@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/manager"
+	"github.com/tierone/harbormaster/pkg/quarantine"
+	"github.com/tierone/harbormaster/pkg/types"
+)
+
+var unquarantineAll bool
+
+var unquarantineCmd = &cobra.Command{
+	Use:   "unquarantine [repository...]",
+	Short: "Resume syncing quarantined repositories",
+	Long: `Release repositories from quarantine so that the next sync includes
+them again.
+
+A repository is quarantined after general.quarantine_after consecutive
+failed syncs (default: 5). Quarantined repositories are skipped with a
+warning and listed by 'hm status' until they are released.`,
+	ValidArgsFunction: completeQuarantined,
+	RunE:              runUnquarantine,
+}
+
+func init() {
+	unquarantineCmd.Flags().BoolVar(&unquarantineAll, "all", false, "release every quarantined repository")
+	rootCmd.AddCommand(unquarantineCmd)
+}
+
+func runUnquarantine(cmd *cobra.Command, args []string) error {
+	if unquarantineAll == (len(args) > 0) {
+		return fmt.Errorf("specify repositories or --all")
+	}
+
+	q, err := quarantine.Load(quarantinePath())
+	if err != nil {
+		return err
+	}
+	if unquarantineAll {
+		args = q.Quarantined()
+	}
+
+	for _, name := range args {
+		if !q.Release(name) {
+			return fmt.Errorf("repository %s is not quarantined", name)
+		}
+	}
+	if err := q.Save(); err != nil {
+		return err
+	}
+
+	if !quiet {
+		for _, name := range args {
+			fmt.Printf("Released %s from quarantine\n", name)
+		}
+	}
+	return nil
+}
+
+// quarantinePath returns the quarantine file of the workspace.
+func quarantinePath() string {
+	return quarantine.DefaultPath(getConfigDir())
+}
+
+// loadQuarantine returns the workspace quarantine, or nil if it cannot be
+// read, in which case syncing goes ahead without it.
+func loadQuarantine() *quarantine.Store {
+	q, err := quarantine.Load(quarantinePath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return nil
+	}
+	return q
+}
+
+// warnQuarantined reports the repositories a sync skipped and those it
+// quarantined.
+func warnQuarantined(q *quarantine.Store, result *types.SyncResult) {
+	if q == nil {
+		return
+	}
+	for _, name := range result.Quarantined {
+		fmt.Fprintf(os.Stderr, "Warning: skipped quarantined repository %s; run 'hm unquarantine %s' to retry it\n", name, name)
+	}
+	for _, r := range result.FailedResults() {
+		// Quarantined repositories are not synced, so any that failed
+		// was quarantined by this sync
+		if e, ok := q.Get(r.RepoName); ok && e.Quarantined() {
+			fmt.Fprintf(os.Stderr, "Warning: quarantined %s after %d consecutive failures\n", r.RepoName, e.Failures)
+		}
+	}
+}
+
+// warnQuarantinedStatus reports which of the repositories in statuses are
+// quarantined.
+func warnQuarantinedStatus(q *quarantine.Store, statuses []manager.RepoStatus) {
+	if q == nil {
+		return
+	}
+	for _, s := range statuses {
+		if e, ok := q.Get(s.Name); ok && e.Quarantined() {
+			fmt.Fprintf(os.Stderr, "Warning: %s is quarantined after %d consecutive failures; run 'hm unquarantine %s' to retry it\n", s.Name, e.Failures, s.Name)
+		}
+	}
+}
@@ -6,8 +6,12 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/history"
+	"github.com/tierone/harbormaster/pkg/lockfile"
 	"github.com/tierone/harbormaster/pkg/manager"
 )
 
@@ -24,8 +28,9 @@ var removeCmd = &cobra.Command{
 
 By default, only removes the repository from the configuration file.
 Use --delete-files to also delete the local repository files.`,
-	Args: cobra.ExactArgs(1),
-	RunE: runRemove,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeRepository,
+	RunE:              runRemove,
 }
 
 func init() {
@@ -40,7 +45,7 @@ func runRemove(cmd *cobra.Command, args []string) error {
 	// Check if repository exists
 	repo, ok := cfg.GetRepository(name)
 	if !ok {
-		return fmt.Errorf("repository not found: %s", name)
+		return errcode.Wrap(errcode.UnknownRepository, fmt.Errorf("repository not found: %s", name))
 	}
 
 	repoPath := filepath.Join(cfg.General.WorkDir, repo.GetEffectivePath())
@@ -63,6 +68,7 @@ func runRemove(cmd *cobra.Command, args []string) error {
 		manager.WithLockFile(lf),
 	)
 
+	before := lf.Clone()
 	if err := mgr.Remove(name); err != nil {
 		return err
 	}
@@ -77,6 +83,13 @@ func runRemove(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to save lock file: %w", err)
 	}
 
+	recordHistory(&history.Entry{
+		Time:        time.Now(),
+		Command:     history.CommandRemove,
+		Filter:      name,
+		LockChanges: lockfile.Diff(before, lf),
+	})
+
 	if !quiet {
 		fmt.Printf("Removed repository: %s\n", name)
 	}
@@ -96,9 +109,11 @@ func runRemove(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// confirm asks a yes or no question on standard error, like the other
+// prompts, so that it stays out of output that is piped or captured.
 func confirm(prompt string) bool {
 	reader := bufio.NewReader(os.Stdin)
-	fmt.Printf("%s [y/N]: ", prompt)
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", prompt)
 
 	response, err := reader.ReadString('\n')
 	if err != nil {
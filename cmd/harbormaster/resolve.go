@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/manager"
+)
+
+var (
+	resolveProject string
+	resolveTag     string
+	resolveJSON    bool
+)
+
+var resolveCmd = &cobra.Command{
+	Use:   "resolve [repository...]",
+	Short: "Show what each configured ref resolves to on the remote",
+	Long: `Look up the commit each repository's configured branch or tag points
+at right now, using git ls-remote. Nothing is cloned or fetched and no
+working tree is touched, so this previews what 'hm sync' would check out.
+
+Repositories without a branch or tag resolve the remote's HEAD, and those
+pinned to a commit resolve to it without contacting the remote. Only git
+repositories are resolved.`,
+	ValidArgsFunction: completeRepositories,
+	RunE:              runResolve,
+}
+
+func init() {
+	resolveCmd.Flags().StringVarP(&resolveProject, "project", "p", "", "resolve repositories in project")
+	resolveCmd.Flags().StringVarP(&resolveTag, "tag", "t", "", "resolve repositories with tag")
+	resolveCmd.Flags().BoolVar(&resolveJSON, "json", false, "output as JSON")
+
+	_ = resolveCmd.RegisterFlagCompletionFunc("project", completeProjects)
+	_ = resolveCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	rootCmd.AddCommand(resolveCmd)
+}
+
+func runResolve(cmd *cobra.Command, args []string) error {
+	filter := manager.Filter{}
+	if len(args) > 0 {
+		filter.Names = args
+	} else if resolveProject != "" {
+		filter.Projects = []string{resolveProject}
+	} else if resolveTag != "" {
+		filter.Tags = []string{resolveTag}
+	} else {
+		filter.All = true
+	}
+
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLockFile(lf),
+		manager.WithLogger(logger),
+		manager.WithVerbose(verboseOutput()),
+	)
+
+	results, err := mgr.Resolve(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+
+	if resolveJSON {
+		if err := outputResolveJSON(results); err != nil {
+			return err
+		}
+	} else if !quiet {
+		if err := printResolved(results); err != nil {
+			return err
+		}
+	}
+
+	var firstErr error
+	failed := 0
+	for _, r := range results {
+		if r.Error != nil {
+			if firstErr == nil {
+				firstErr = r.Error
+			}
+			failed++
+			fmt.Fprintf(os.Stderr, "  %s: %v\n", r.Name, r.Error)
+		}
+	}
+	if firstErr != nil {
+		return errcode.Wrap(errcode.Of(firstErr), fmt.Errorf("could not resolve %d of %d repositories", failed, len(results)))
+	}
+	return nil
+}
+
+func printResolved(results []manager.ResolvedRef) error {
+	if len(results) == 0 {
+		fmt.Println("No git repositories to resolve")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "REPOSITORY\tREF\tRESOLVED\tCURRENT\tSYNC")
+	for _, r := range results {
+		if r.Error != nil {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t-\t%s\terror\n", r.Name, r.Ref, shortSHA(r.CurrentSHA))
+			continue
+		}
+
+		ref := r.Ref
+		if r.Pinned {
+			ref = "commit " + shortSHA(r.Ref)
+		} else if r.Target != "" {
+			ref = r.Ref + " (" + r.Target + ")"
+		}
+		sync := "up to date"
+		switch {
+		case r.CurrentSHA == "":
+			sync = "clone"
+		case r.Changed():
+			sync = "update"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Name, ref, shortSHA(r.SHA), shortSHA(r.CurrentSHA), sync)
+	}
+	return w.Flush()
+}
+
+func outputResolveJSON(results []manager.ResolvedRef) error {
+	type jsonRef struct {
+		Name       string `json:"name"`
+		Ref        string `json:"ref"`
+		Target     string `json:"target,omitempty"`
+		SHA        string `json:"sha,omitempty"`
+		Pinned     bool   `json:"pinned,omitempty"`
+		CurrentSHA string `json:"current_sha,omitempty"`
+		LockedSHA  string `json:"locked_sha,omitempty"`
+		Changed    bool   `json:"changed"`
+		Error      string `json:"error,omitempty"`
+	}
+
+	output := make([]jsonRef, len(results))
+	for i, r := range results {
+		output[i] = jsonRef{
+			Name:       r.Name,
+			Ref:        r.Ref,
+			Target:     r.Target,
+			SHA:        r.SHA,
+			Pinned:     r.Pinned,
+			CurrentSHA: r.CurrentSHA,
+			LockedSHA:  r.LockedSHA,
+			Changed:    r.Changed(),
+		}
+		if r.Error != nil {
+			output[i].Error = r.Error.Error()
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/manager"
+)
+
+var reviewCmd = &cobra.Command{
+	Use:   "review <change>",
+	Short: "Check out a Gerrit change in its repository",
+	Long: `Look up a Gerrit change by its number or Change-Id and check out its
+current patch set in the repository of its project, on the branch
+review/<number>. Hosts whose [[host]] entry has provider = "gerrit" are
+searched in config order. The checkout must not have uncommitted changes.
+
+Clones of Gerrit projects get the server's commit-msg hook, so commits
+amended on the review branch keep their Change-Id.
+
+Examples:
+  hm review 1234
+  hm review I8473b95934b5732ac55d26311a706c9c2bde9940
+  hm review platform/api~1234`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReview,
+}
+
+func init() {
+	rootCmd.AddCommand(reviewCmd)
+}
+
+func runReview(cmd *cobra.Command, args []string) error {
+	mgr := manager.NewRepositoryManager(cfg, manager.WithLogger(logger))
+
+	review, err := mgr.Review(context.Background(), args[0])
+	if err != nil {
+		return err
+	}
+
+	if !quiet {
+		c := review.Change
+		fmt.Printf("Checked out change %d patch set %d of %s on %s in %s\n", c.Number, c.Patchset, review.Repository, review.Branch, review.Path)
+		fmt.Printf("  %s (%s, for %s)\n", c.Subject, c.Status, c.Branch)
+	}
+	return nil
+}
@@ -2,19 +2,41 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
 	"github.com/tierone/harbormaster/pkg/config"
 	"github.com/tierone/harbormaster/pkg/lockfile"
+	"github.com/tierone/harbormaster/pkg/logging"
+	"github.com/tierone/harbormaster/pkg/ui"
+	"golang.org/x/term"
 )
 
 var (
 	// Global flags
-	cfgFile string
-	workDir string
-	quiet   bool
-	noColor bool
+	cfgFile       string
+	workspaceName string
+	workDir       string
+	quiet         bool
+	verbose       bool
+	noColor       bool
+
+	// When to use the interactive progress display: one of progressModes
+	progressMode string
+
+	// CI log sections around each repository's non-interactive output
+	ciGroups  string
+	foldStyle = ui.FoldOff
+
+	// Diagnostic logging, separate from command output
+	logLevel  string
+	logFormat string
+	logger    = logging.Discard()
 
 	// Loaded config and lockfile
 	cfg *config.Config
@@ -33,65 +55,213 @@ synchronize your repositories.`,
 	SilenceUsage:  true,
 	SilenceErrors: true,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		// Skip config loading for init command
-		if cmd.Name() == "init" || cmd.Name() == "help" || cmd.Name() == "version" {
-			return nil
-		}
-
-		// Load configuration
 		var err error
-		if cfgFile != "" {
-			cfg, err = config.Load(cfgFile)
-		} else {
-			cfgPath, findErr := config.FindConfigFile()
-			if findErr != nil {
-				return fmt.Errorf("no config file found: %w\nRun 'hm init' to create one", findErr)
-			}
-			cfg, err = config.Load(cfgPath)
+		if logger, err = logging.New(os.Stderr, logLevel, logFormat); err != nil {
+			return err
 		}
-		if err != nil {
-			return fmt.Errorf("failed to load config: %w", err)
+		if noColor || os.Getenv("NO_COLOR") != "" {
+			ui.DisableColor()
+		}
+		if foldStyle, err = ui.ParseFoldStyle(ciGroups, os.Getenv); err != nil {
+			return err
+		}
+		if !slices.Contains(progressModes, progressMode) {
+			return fmt.Errorf("unsupported progress mode: %s (must be %s)", progressMode, strings.Join(progressModes, ", "))
+		}
+		if cfgFile != "" && workspaceName != "" {
+			return fmt.Errorf("--config and --workspace cannot be used together")
 		}
 
-		// Override work directory if specified
-		if workDir != "" {
-			expandedPath, err := config.ExpandPath(workDir)
-			if err != nil {
-				return fmt.Errorf("invalid work directory: %w", err)
-			}
-			cfg.General.WorkDir = expandedPath
+		// Skip config loading for commands that don't need a workspace.
+		// Completion requests load the config lazily and tolerate its absence.
+		// Those that print styled output still use the workspace's theme.
+		switch cmd.Name() {
+		case "init", "edit":
+			applyWorkspaceTheme()
+			return nil
+		case "help", "version", "completion", "prompt",
+			cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+			return nil
+		}
+		if cmd.HasParent() && (cmd.Parent() == authCmd || cmd.Parent() == workspacesCmd) {
+			applyWorkspaceTheme()
+			return nil
+		}
+		if cmd.HasParent() && cmd.Parent().Name() == "docs" {
+			return nil
+		}
+		// Importing a bundle loads the workspace itself, creating it if needed
+		if cmd == bundleImportCmd {
+			return nil
 		}
 
-		// Load lock file
-		lockPath := getLockFilePath()
-		lf, err = lockfile.Load(lockPath)
+		return loadWorkspace()
+	},
+}
+
+// findConfig returns the config file of the workspace: the one given by
+// --config, that of the registered workspace given by --workspace, the
+// nearest one found from the current directory, or else that of the
+// current registered workspace.
+func findConfig() (string, error) {
+	if cfgFile != "" {
+		return cfgFile, nil
+	}
+	if workspaceName != "" {
+		path, _, err := registeredConfigFile(workspaceName)
+		return path, err
+	}
+
+	path, findErr := config.FindConfigFile()
+	if findErr == nil {
+		return path, nil
+	}
+	path, ok, err := registeredConfigFile("")
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("no config file found: %w\nRun 'hm init' to create one", findErr)
+	}
+	return path, nil
+}
+
+// loadWorkspace loads the configuration and lock file into cfg and lf,
+// and applies the configured theme.
+func loadWorkspace() error {
+	cfgPath, err := findConfig()
+	if err != nil {
+		return err
+	}
+	cfg, err = config.Load(cfgPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	applyTheme(cfg.UI)
+
+	// Override work directory if specified
+	if workDir != "" {
+		expandedPath, err := config.ExpandPath(workDir)
 		if err != nil {
-			return fmt.Errorf("failed to load lock file: %w", err)
+			return fmt.Errorf("invalid work directory: %w", err)
 		}
+		cfg.General.WorkDir = expandedPath
+	}
 
-		return nil
-	},
+	// Load lock file
+	lockPath := getLockFilePath()
+	lf, err = lockfile.Load(lockPath)
+	if err != nil {
+		return fmt.Errorf("failed to load lock file: %w", err)
+	}
+
+	return nil
+}
+
+// applyTheme draws styled output in the configured theme and colors.
+// The settings are expected to have passed config validation.
+func applyTheme(settings config.UIConfig) {
+	p := ui.ThemePalette(settings.Theme)
+	for name, value := range settings.Colors {
+		color := lipgloss.Color(value)
+		switch name {
+		case "primary":
+			p.Primary = color
+		case "success":
+			p.Success = color
+		case "warning":
+			p.Warning = color
+		case "error":
+			p.Error = color
+		case "muted":
+			p.Muted = color
+		case "highlight":
+			p.Highlight = color
+		}
+	}
+	ui.SetPalette(p)
+}
+
+// applyWorkspaceTheme applies the theme of the workspace, if one is found
+// and loads, for commands that run without a workspace. Otherwise the
+// default theme stays.
+func applyWorkspaceTheme() {
+	cfgPath, err := findConfig()
+	if err != nil {
+		return
+	}
+	if c, err := config.Load(cfgPath); err == nil {
+		applyTheme(c.UI)
+	}
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file path")
+	rootCmd.PersistentFlags().StringVarP(&workspaceName, "workspace", "W", "", "run against the registered workspace of this name")
 	rootCmd.PersistentFlags().StringVarP(&workDir, "work-dir", "w", "", "override work directory")
 	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "minimal output")
-	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "disable colored output")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "print every git command and HTTP request on stderr")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "disable colored output (also set by NO_COLOR)")
+	rootCmd.PersistentFlags().StringVar(&progressMode, "progress", progressAuto, "when to use the interactive progress display (auto, always, never)")
+	rootCmd.PersistentFlags().StringVar(&ciGroups, "ci-groups", string(ui.FoldOff), "group each repository's output into collapsible CI log sections (auto, github, gitlab, off)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", logging.LevelOff, "diagnostic log level on stderr (debug, info, warn, error, off)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logging.FormatText, "diagnostic log format (text or json)")
+
+	_ = rootCmd.RegisterFlagCompletionFunc("workspace", completeWorkspaces)
+	_ = rootCmd.RegisterFlagCompletionFunc("progress", cobra.FixedCompletions(progressModes, cobra.ShellCompDirectiveNoFileComp))
+	_ = rootCmd.RegisterFlagCompletionFunc("ci-groups", cobra.FixedCompletions(ui.FoldStyles, cobra.ShellCompDirectiveNoFileComp))
+	_ = rootCmd.RegisterFlagCompletionFunc("log-level", cobra.FixedCompletions([]string{"debug", "info", "warn", "error", "off"}, cobra.ShellCompDirectiveNoFileComp))
+	_ = rootCmd.RegisterFlagCompletionFunc("log-format", cobra.FixedCompletions([]string{logging.FormatText, logging.FormatJSON}, cobra.ShellCompDirectiveNoFileComp))
 }
 
-func getLockFilePath() string {
-	if cfg != nil && cfg.Path() != "" {
-		dir := getConfigDir()
-		return dir + "/" + lockfile.LockFileName
+// verboseOutput returns the writer for --verbose output, or nil.
+func verboseOutput() io.Writer {
+	if !verbose {
+		return nil
 	}
-	cwd, _ := os.Getwd()
-	return cwd + "/" + lockfile.LockFileName
+	return os.Stderr
+}
+
+// Values of --progress.
+const (
+	progressAuto   = "auto"
+	progressAlways = "always"
+	progressNever  = "never"
+)
+
+// progressModes are the accepted values of --progress.
+var progressModes = []string{progressAuto, progressAlways, progressNever}
+
+// useInteractiveUI reports whether to show the interactive progress
+// display. --progress=always and --progress=never take precedence;
+// otherwise it is used unless output is quiet, verbose, or not a terminal.
+func useInteractiveUI() bool {
+	switch {
+	case progressMode == progressNever:
+		return false
+	case progressMode == progressAlways:
+		return true
+	case quiet, verbose:
+		return false
+	}
+	return term.IsTerminal(int(os.Stdout.Fd())) && term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+// stdinIsTerminal reports whether standard input is a terminal, as
+// required by the interactive commands.
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+func getLockFilePath() string {
+	return filepath.Join(getConfigDir(), lockfile.LockFileName)
 }
 
+// getConfigDir returns the workspace root, where the config file was
+// found, or the current directory if there is none.
 func getConfigDir() string {
-	if cfg != nil && cfg.Path() != "" {
-		return cfg.Path()[:len(cfg.Path())-len(config.ConfigFileName)-1]
+	if cfg != nil && cfg.Dir() != "" {
+		return cfg.Dir()
 	}
 	cwd, _ := os.Getwd()
 	return cwd
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/server"
+)
+
+var (
+	serveAddr     string
+	serveSocket   string
+	serveParallel int
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a local HTTP API for the workspace",
+	Long: `Serve a JSON API for the workspace so that editors and dashboards can
+drive Harbormaster without running the CLI.
+
+Endpoints:
+  GET  /api/repositories  configured repositories
+  GET  /api/projects      configured projects
+  GET  /api/status        repository status (?name=, ?project=, ?tag=)
+  POST /api/sync          start a sync; body {"names", "projects", "tags", "locked"}
+  GET  /api/events        progress, complete, and error server-sent events
+
+The API has no authentication. It listens on localhost by default; use
+--socket to serve on a unix socket readable only by the current user.
+Requests from web pages of other origins, requests naming the server by
+a host name other than localhost, and posts that are not JSON are
+rejected.`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:7420", "TCP address to listen on")
+	serveCmd.Flags().StringVar(&serveSocket, "socket", "", "unix socket path to listen on instead of TCP")
+	serveCmd.Flags().IntVar(&serveParallel, "parallel", 0, "number of concurrent sync operations (default: general.concurrency or 4)")
+
+	rootCmd.AddCommand(serveCmd)
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	ln, location, err := serveListener()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if !quiet {
+		fmt.Printf("Serving %s on %s\n", getConfigDir(), location)
+	}
+
+	srv := server.New(cfg, lf,
+		server.WithConcurrency(serveParallel),
+		server.WithLogger(logger),
+		server.WithVerbose(verboseOutput()),
+	)
+	return srv.Serve(ctx, ln)
+}
+
+// serveListener opens the TCP or unix socket listener.
+func serveListener() (net.Listener, string, error) {
+	if serveSocket == "" {
+		ln, err := net.Listen("tcp", serveAddr)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to listen: %w", err)
+		}
+		return ln, "http://" + ln.Addr().String(), nil
+	}
+
+	// Remove a stale socket left by a previous run
+	if info, err := os.Stat(serveSocket); err == nil && info.Mode()&os.ModeSocket != 0 {
+		_ = os.Remove(serveSocket)
+	}
+
+	ln, err := net.Listen("unix", serveSocket)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to listen: %w", err)
+	}
+	if err := os.Chmod(serveSocket, 0600); err != nil {
+		_ = ln.Close()
+		return nil, "", fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	return ln, "unix:" + serveSocket, nil
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/manager"
+)
+
+var (
+	sourceName     string
+	sourceHost     string
+	sourceInclude  []string
+	sourceExclude  []string
+	sourceArchived bool
+	sourcePath     string
+	sourceTags     []string
+	sourceRefresh  string
+)
+
+var sourceCmd = &cobra.Command{
+	Use:   "source",
+	Short: "Manage sources that populate repositories",
+	Long: `Manage sources: listings on a hosting service, such as every repository in
+a GitHub organization, GitLab group, or Gitea/Forgejo organization, that
+repositories are added from automatically.
+
+The repositories of a source are listed when it is added and again by
+'hm source refresh', and hm sync refreshes listings older than the
+source's refresh interval (24h by default). Listings are cached in
+` + config.SourcesName + `; repositories from sources are not written to the
+config file, and repositories defined there take precedence.`,
+}
+
+var sourceAddCmd = &cobra.Command{
+	Use:   "add <type> <org>",
+	Short: "Add a source and list its repositories",
+	Long: `Add a source of the given type and list its repositories. Types:
+
+  github-org    every repository of a GitHub organization (or user)
+  gitlab-group  every project of a GitLab group (or user) and its
+                subgroups, named by their path below the group
+  gitea-org     every repository of a Gitea or Forgejo organization (or
+                user) on --host
+
+Examples:
+  hm source add github-org acme
+  hm source add github-org acme --include 'api-*' --exclude '*-legacy' --path acme
+  hm source add gitlab-group acme/platform --host gitlab.example.com
+  hm source add gitea-org acme --host forge.example.com`,
+	Args:      cobra.ExactArgs(2),
+	ValidArgs: config.SourceTypes,
+	RunE:      runSourceAdd,
+}
+
+var sourceListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List sources",
+	Args:    cobra.NoArgs,
+	RunE:    runSourceList,
+}
+
+var sourceRefreshCmd = &cobra.Command{
+	Use:               "refresh [source...]",
+	Aliases:           []string{"update"},
+	Short:             "List the repositories of sources again",
+	ValidArgsFunction: completeSources,
+	RunE:              runSourceRefresh,
+}
+
+var sourceRemoveCmd = &cobra.Command{
+	Use:     "remove <source>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a source and its repositories from the configuration",
+	Long: `Remove a source and the repositories it added from the configuration.
+Their checkouts are left on disk.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeSources,
+	RunE:              runSourceRemove,
+}
+
+func init() {
+	sourceAddCmd.Flags().StringVarP(&sourceName, "name", "n", "", "source name (default: the last element of the org)")
+	sourceAddCmd.Flags().StringVar(&sourceHost, "host", "", "host of the organization (default: github.com or gitlab.com)")
+	sourceAddCmd.Flags().StringSliceVar(&sourceInclude, "include", nil, "only add repositories whose names match these patterns")
+	sourceAddCmd.Flags().StringSliceVar(&sourceExclude, "exclude", nil, "leave out repositories whose names match these patterns")
+	sourceAddCmd.Flags().BoolVar(&sourceArchived, "archived", false, "also add archived repositories")
+	sourceAddCmd.Flags().StringVar(&sourcePath, "path", "", "directory the repositories are cloned into")
+	sourceAddCmd.Flags().StringSliceVar(&sourceTags, "tags", nil, "tags to give the repositories")
+	sourceAddCmd.Flags().StringVar(&sourceRefresh, "refresh", "", "how often sync refreshes the listing, such as 12h, or never (default: 24h)")
+
+	sourceCmd.AddCommand(sourceAddCmd)
+	sourceCmd.AddCommand(sourceListCmd)
+	sourceCmd.AddCommand(sourceRefreshCmd)
+	sourceCmd.AddCommand(sourceRemoveCmd)
+	rootCmd.AddCommand(sourceCmd)
+}
+
+func runSourceAdd(cmd *cobra.Command, args []string) error {
+	src, err := config.ParseSource(config.SourceConfigFile{
+		Name:     sourceName,
+		Type:     args[0],
+		Host:     sourceHost,
+		Org:      args[1],
+		Include:  sourceInclude,
+		Exclude:  sourceExclude,
+		Archived: sourceArchived,
+		Path:     sourcePath,
+		Tags:     sourceTags,
+		Refresh:  sourceRefresh,
+	})
+	if err != nil {
+		return err
+	}
+	if err := cfg.AddSource(src); err != nil {
+		return err
+	}
+	if err := config.ValidateConfig(cfg); err != nil {
+		return err
+	}
+
+	mgr := manager.NewRepositoryManager(cfg, manager.WithLogger(logger))
+	refreshed, err := mgr.RefreshSources(context.Background(), []string{src.Name}, true)
+	if err != nil {
+		return err
+	}
+	if len(refreshed) > 0 && refreshed[0].Error != nil {
+		return fmt.Errorf("source %s not added: %w", src.Name, refreshed[0].Error)
+	}
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	if !quiet {
+		fmt.Printf("Added source %s with %d repositories\n", src.Name, len(refreshed[0].Added))
+		for _, name := range refreshed[0].Added {
+			fmt.Printf("  + %s\n", name)
+		}
+		fmt.Println("\nRun 'hm sync' to clone them.")
+	}
+	return nil
+}
+
+func runSourceList(cmd *cobra.Command, args []string) error {
+	if len(cfg.Sources) == 0 {
+		fmt.Println("No sources configured")
+		return nil
+	}
+	listings, err := config.LoadSourceListings(cfg.SourcesPath())
+	if err != nil {
+		return err
+	}
+	counts := make(map[string]int)
+	for _, repo := range cfg.Repositories {
+		if src := cfg.SourceOf(repo.Name); src != "" {
+			counts[src]++
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tTYPE\tORG\tREPOSITORIES\tREFRESHED")
+	for _, src := range cfg.Sources {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s/%s\t%d\t%s\n", src.Name, src.Type, src.Host, src.Org, counts[src.Name], formatAge(listings[src.Name].Refreshed))
+	}
+	return w.Flush()
+}
+
+func runSourceRefresh(cmd *cobra.Command, args []string) error {
+	mgr := manager.NewRepositoryManager(cfg, manager.WithLogger(logger))
+	refreshed, err := mgr.RefreshSources(context.Background(), args, true)
+	if err != nil {
+		return err
+	}
+	if len(refreshed) == 0 {
+		fmt.Println("No sources configured")
+		return nil
+	}
+	failed := printSourceRefreshes(refreshed, true)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d sources failed to refresh", failed, len(refreshed))
+	}
+	return nil
+}
+
+func runSourceRemove(cmd *cobra.Command, args []string) error {
+	if err := cfg.RemoveSource(args[0]); err != nil {
+		return err
+	}
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	if !quiet {
+		fmt.Printf("Removed source %s\n", args[0])
+	}
+	return nil
+}
+
+// refreshStaleSources refreshes the sources whose listings are due before
+// a sync, warning about those that fail rather than failing the sync.
+func refreshStaleSources(mgr *manager.RepositoryManager) error {
+	refreshed, err := mgr.RefreshSources(context.Background(), nil, false)
+	if err != nil {
+		return err
+	}
+	printSourceRefreshes(refreshed, false)
+	return nil
+}
+
+// printSourceRefreshes reports the repositories each source added and
+// removed, and its failure, returning the number of failures. Sources
+// without changes are only reported if all is set.
+func printSourceRefreshes(refreshed []manager.SourceRefresh, all bool) int {
+	failed := 0
+	for _, r := range refreshed {
+		if r.Error != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "Warning: failed to refresh source %s: %v\n", r.Source, r.Error)
+			continue
+		}
+		if quiet || (!all && len(r.Added) == 0 && len(r.Removed) == 0) {
+			continue
+		}
+		fmt.Printf("Source %s: %d added, %d removed\n", r.Source, len(r.Added), len(r.Removed))
+		for _, name := range r.Added {
+			fmt.Printf("  + %s\n", name)
+		}
+		for _, name := range r.Removed {
+			fmt.Printf("  - %s\n", name)
+		}
+	}
+	return failed
+}
+
+// completeSources completes source names.
+func completeSources(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	c := completionConfig()
+	if c == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, src := range c.Sources {
+		if strings.HasPrefix(src.Name, toComplete) {
+			names = append(names, src.Name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
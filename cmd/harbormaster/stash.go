@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/manager"
+)
+
+var (
+	stashName    string
+	stashProject string
+	stashTag     string
+	stashJSON    bool
+)
+
+var stashCmd = &cobra.Command{
+	Use:   "stash",
+	Short: "Stash changes across repositories",
+	Long: `Stash and restore uncommitted changes across repositories as one
+named set, for example to run a sync that would discard them:
+
+  hm stash push -n before-sync
+  hm sync
+  hm stash pop -n before-sync
+
+Sets are ordinary git stash entries, so 'git stash list' in a repository
+shows them too.`,
+}
+
+var stashPushCmd = &cobra.Command{
+	Use:   "push [repository...]",
+	Short: "Stash uncommitted changes as a named set",
+	Long: `Stash the uncommitted changes, including untracked files, of every
+selected repository that has any. Without --name the set is named after
+the current time. Clean, missing, and non-git repositories are skipped.`,
+	ValidArgsFunction: completeRepositories,
+	RunE:              runStashPush,
+}
+
+var stashPopCmd = &cobra.Command{
+	Use:   "pop [repository...]",
+	Short: "Restore a stashed set",
+	Long: `Restore a stashed set in the selected repositories that have it and
+drop it. Without --name the most recent set is restored. If restoring
+conflicts in a repository, its entry is kept so nothing is lost.`,
+	ValidArgsFunction: completeRepositories,
+	RunE:              runStashPop,
+}
+
+var stashListCmd = &cobra.Command{
+	Use:               "list [repository...]",
+	Aliases:           []string{"ls"},
+	Short:             "List stashed sets",
+	ValidArgsFunction: completeRepositories,
+	RunE:              runStashList,
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{stashPushCmd, stashPopCmd, stashListCmd} {
+		cmd.Flags().StringVarP(&stashProject, "project", "p", "", "select repositories in project")
+		cmd.Flags().StringVarP(&stashTag, "tag", "t", "", "select repositories with tag")
+		_ = cmd.RegisterFlagCompletionFunc("project", completeProjects)
+		_ = cmd.RegisterFlagCompletionFunc("tag", completeTags)
+		stashCmd.AddCommand(cmd)
+	}
+	stashPushCmd.Flags().StringVarP(&stashName, "name", "n", "", "name of the set")
+	stashPopCmd.Flags().StringVarP(&stashName, "name", "n", "", "name of the set to restore")
+	stashListCmd.Flags().BoolVar(&stashJSON, "json", false, "output as JSON")
+
+	rootCmd.AddCommand(stashCmd)
+}
+
+// stashFilter selects repositories from arguments, -p, or -t.
+func stashFilter(args []string) manager.Filter {
+	filter := manager.Filter{}
+	if len(args) > 0 {
+		filter.Names = args
+	} else if stashProject != "" {
+		filter.Projects = []string{stashProject}
+	} else if stashTag != "" {
+		filter.Tags = []string{stashTag}
+	} else {
+		filter.All = true
+	}
+	return filter
+}
+
+func runStashPush(cmd *cobra.Command, args []string) error {
+	name := stashName
+	if name == "" {
+		name = time.Now().Format("2006-01-02-150405")
+	}
+
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLogger(logger),
+		manager.WithVerbose(verboseOutput()),
+	)
+	results, err := mgr.StashPush(context.Background(), stashFilter(args), name)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		if !quiet {
+			fmt.Println("No uncommitted changes to stash")
+		}
+		return nil
+	}
+	return reportStash(results, "stashed", "Stashed changes in %d repositories as %s\n")
+}
+
+func runStashPop(cmd *cobra.Command, args []string) error {
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLogger(logger),
+		manager.WithVerbose(verboseOutput()),
+	)
+	results, err := mgr.StashPop(context.Background(), stashFilter(args), stashName)
+	if err != nil {
+		return err
+	}
+	return reportStash(results, "restored", "Restored changes in %d repositories from %s\n")
+}
+
+// reportStash prints the outcome of a push or pop and returns an error if
+// it failed in any repository.
+func reportStash(results []manager.StashResult, verb, summary string) error {
+	var firstErr error
+	failed := 0
+	for _, r := range results {
+		if r.Error != nil {
+			if firstErr == nil {
+				firstErr = r.Error
+			}
+			failed++
+			fmt.Fprintf(os.Stderr, "  %s: %v\n", r.Name, r.Error)
+			continue
+		}
+		if !quiet {
+			fmt.Printf("  %s: %s\n", r.Name, verb)
+		}
+	}
+
+	if !quiet && failed < len(results) {
+		fmt.Printf(summary, len(results)-failed, results[0].Set)
+	}
+	if firstErr != nil {
+		return errcode.Wrap(errcode.Of(firstErr), fmt.Errorf("failed in %d of %d repositories", failed, len(results)))
+	}
+	return nil
+}
+
+func runStashList(cmd *cobra.Command, args []string) error {
+	mgr := manager.NewRepositoryManager(cfg, manager.WithLogger(logger))
+	sets, err := mgr.StashSets(context.Background(), stashFilter(args))
+	if err != nil {
+		return err
+	}
+
+	if stashJSON {
+		type jsonSet struct {
+			Name         string    `json:"name"`
+			Created      time.Time `json:"created"`
+			Repositories []string  `json:"repositories"`
+		}
+		output := make([]jsonSet, len(sets))
+		for i, s := range sets {
+			output[i] = jsonSet{Name: s.Name, Created: s.Created, Repositories: s.Repos}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(output)
+	}
+
+	if len(sets) == 0 {
+		fmt.Println("No stashed sets")
+		return nil
+	}
+
+	columns := []tableColumn{{title: "NAME"}, {title: "CREATED"}, {title: "REPOSITORIES"}}
+	rows := make([][]tableCell, len(sets))
+	for i, s := range sets {
+		rows[i] = []tableCell{
+			plainCell(s.Name),
+			plainCell(formatAge(s.Created)),
+			plainCell(strings.Join(s.Repos, ", ")),
+		}
+	}
+	printTable(columns, columnWidths(columns, rows), rows)
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/manager"
+)
+
+var (
+	statsSince   string
+	statsProject string
+	statsTag     string
+	statsJSON    bool
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats [repository...]",
+	Short: "Summarize activity across repositories",
+	Long: `Summarize each repository and the workspace as a whole: the commits
+and contributors on the checked-out HEAD within a time window, the lines
+in tracked text files, and the date of the latest commit.
+
+--since accepts any date git does, such as "2024-05-01" or "1 week ago".
+Contributors are counted once across the workspace in the total.
+Missing and non-git repositories are skipped.`,
+	ValidArgsFunction: completeRepositories,
+	RunE:              runStats,
+}
+
+func init() {
+	statsCmd.Flags().StringVar(&statsSince, "since", "30 days ago", "start of the time window")
+	statsCmd.Flags().StringVarP(&statsProject, "project", "p", "", "summarize repositories in project")
+	statsCmd.Flags().StringVarP(&statsTag, "tag", "t", "", "summarize repositories with tag")
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "output as JSON")
+
+	_ = statsCmd.RegisterFlagCompletionFunc("project", completeProjects)
+	_ = statsCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	rootCmd.AddCommand(statsCmd)
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	filter := manager.Filter{}
+	if len(args) > 0 {
+		filter.Names = args
+	} else if statsProject != "" {
+		filter.Projects = []string{statsProject}
+	} else if statsTag != "" {
+		filter.Tags = []string{statsTag}
+	} else {
+		filter.All = true
+	}
+
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLogger(logger),
+		manager.WithVerbose(verboseOutput()),
+	)
+
+	stats, err := mgr.Stats(context.Background(), filter, statsSince)
+	if err != nil {
+		return err
+	}
+
+	// Workspace totals, counting each contributor once
+	total := manager.RepoStats{Name: "Total"}
+	contributors := make(map[string]bool)
+	for _, s := range stats {
+		total.Commits += s.Commits
+		total.Lines += s.Lines
+		if s.LastActivity.After(total.LastActivity) {
+			total.LastActivity = s.LastActivity
+		}
+		for _, c := range s.Contributors {
+			if !contributors[c] {
+				contributors[c] = true
+				total.Contributors = append(total.Contributors, c)
+			}
+		}
+	}
+	sort.Strings(total.Contributors)
+
+	if statsJSON {
+		return outputStatsJSON(stats, total)
+	}
+
+	if len(stats) == 0 {
+		fmt.Println("No repositories to summarize")
+		return nil
+	}
+
+	fmt.Printf("Since %s\n\n", statsSince)
+	columns := []tableColumn{
+		{title: "REPOSITORY"},
+		{title: "COMMITS", right: true},
+		{title: "CONTRIBUTORS", right: true},
+		{title: "LINES", right: true},
+		{title: "LAST ACTIVITY"},
+	}
+	row := func(s manager.RepoStats) []tableCell {
+		last := "-"
+		if !s.LastActivity.IsZero() {
+			last = s.LastActivity.Local().Format("2006-01-02")
+		}
+		return []tableCell{
+			plainCell(s.Name),
+			plainCell(strconv.Itoa(s.Commits)),
+			plainCell(strconv.Itoa(len(s.Contributors))),
+			plainCell(strconv.Itoa(s.Lines)),
+			plainCell(last),
+		}
+	}
+
+	rows := make([][]tableCell, 0, len(stats)+1)
+	for _, s := range stats {
+		rows = append(rows, row(s))
+	}
+	rows = append(rows, row(total))
+
+	widths := columnWidths(columns, rows)
+	printTable(columns, widths, rows[:len(rows)-1])
+	printTableRow(columns, widths, rows[len(rows)-1])
+
+	for _, s := range stats {
+		if s.Error != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s: failed to summarize: %v\n", s.Name, s.Error)
+		}
+	}
+	return nil
+}
+
+func outputStatsJSON(stats []manager.RepoStats, total manager.RepoStats) error {
+	type jsonRepo struct {
+		Name         string     `json:"name"`
+		Commits      int        `json:"commits"`
+		Contributors []string   `json:"contributors"`
+		Lines        int        `json:"lines"`
+		LastActivity *time.Time `json:"last_activity,omitempty"`
+		Error        string     `json:"error,omitempty"`
+	}
+	type jsonStats struct {
+		Since        string     `json:"since"`
+		Repositories []jsonRepo `json:"repositories"`
+		Total        jsonRepo   `json:"total"`
+	}
+
+	toJSON := func(s manager.RepoStats) jsonRepo {
+		r := jsonRepo{
+			Name:         s.Name,
+			Commits:      s.Commits,
+			Contributors: s.Contributors,
+			Lines:        s.Lines,
+		}
+		if r.Contributors == nil {
+			r.Contributors = []string{}
+		}
+		if !s.LastActivity.IsZero() {
+			r.LastActivity = &s.LastActivity
+		}
+		if s.Error != nil {
+			r.Error = s.Error.Error()
+		}
+		return r
+	}
+
+	output := jsonStats{
+		Since:        statsSince,
+		Repositories: make([]jsonRepo, len(stats)),
+		Total:        toJSON(total),
+	}
+	for i, s := range stats {
+		output.Repositories[i] = toJSON(s)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
@@ -1,19 +1,43 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/history"
+	"github.com/tierone/harbormaster/pkg/lockfile"
 	"github.com/tierone/harbormaster/pkg/manager"
+	"github.com/tierone/harbormaster/pkg/quarantine"
 	"github.com/tierone/harbormaster/pkg/ui"
 )
 
 var (
 	statusJSON      bool
 	statusProject   string
-	statusPorcelain bool
+	statusPorcelain string
+	statusNul       bool
+	statusTUI       bool
+	statusFetch     bool
+	statusByProject bool
+	statusSort      string
+	statusColumns   string
+	statusExitCode  bool
+)
+
+// Exit statuses of status --exit-code. The most severe state of any
+// repository wins.
+const (
+	statusExitClean       = 0
+	statusExitNeedsUpdate = 5
+	statusExitDirty       = 6
+	statusExitError       = 7
 )
 
 var statusCmd = &cobra.Command{
@@ -22,14 +46,49 @@ var statusCmd = &cobra.Command{
 	Long: `Show the status of repositories in the workspace.
 
 Displays whether each repository exists, its current commit, lock status,
-and whether it needs updating.`,
-	RunE: runStatus,
+and whether it needs updating.
+
+Use --fetch to compare each repository with its remote ref, so that
+"outdated" means behind the upstream rather than different from the lock
+file. The remote ref is fetched if it is not present locally.
+
+Use --exit-code to report the state of the workspace through the exit
+status: 0 if every repository is clean and up to date, 5 if any needs
+updating (including missing ones), 6 if any has uncommitted changes, and
+7 if any could not be inspected. The most severe state wins.
+
+Use --porcelain=v2 for output that scripts can rely on across releases:
+a "# porcelain v2" header, then one record per repository of tab-separated
+fields in a fixed order, "-" for empty values. Fields that are "-" or
+contain tabs, newlines, quotes, or backslashes are double-quoted with
+C-style escapes. -z ends records with NUL instead of newline. Plain
+--porcelain keeps the original v1 format.
+
+Use --tui for a live dashboard that can be sorted, filtered, refreshed,
+and used to sync selected repositories.`,
+	ValidArgsFunction: completeRepositories,
+	RunE:              runStatus,
 }
 
 func init() {
 	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "output as JSON")
 	statusCmd.Flags().StringVarP(&statusProject, "project", "p", "", "show status for project only")
-	statusCmd.Flags().BoolVar(&statusPorcelain, "porcelain", false, "machine-readable output")
+	statusCmd.Flags().StringVar(&statusPorcelain, "porcelain", "", "machine-readable output: v1 (default) or v2")
+	statusCmd.Flags().Lookup("porcelain").NoOptDefVal = porcelainV1
+	statusCmd.Flags().BoolVarP(&statusNul, "null", "z", false, "terminate porcelain records with NUL instead of newline")
+	statusCmd.Flags().BoolVar(&statusTUI, "tui", false, "open an interactive dashboard")
+	statusCmd.Flags().BoolVar(&statusFetch, "fetch", false, "compare with the remote and report ahead/behind")
+	statusCmd.Flags().BoolVar(&statusByProject, "by-project", false, "group the table by project")
+	statusCmd.Flags().StringVar(&statusSort, "sort", "", "sort by "+strings.Join(statusSortKeys, ", "))
+	statusCmd.Flags().BoolVar(&statusExitCode, "exit-code", false, "exit 5 if outdated, 6 if dirty, 7 on errors")
+	statusCmd.Flags().StringVar(&statusColumns, "columns", "", "comma-separated table columns: "+columnNames(statusTableColumns))
+
+	statusCmd.MarkFlagsMutuallyExclusive("by-project", "json")
+	statusCmd.MarkFlagsMutuallyExclusive("by-project", "porcelain")
+
+	_ = statusCmd.RegisterFlagCompletionFunc("project", completeProjects)
+	_ = statusCmd.RegisterFlagCompletionFunc("porcelain", cobra.FixedCompletions([]string{porcelainV1, porcelainV2}, cobra.ShellCompDirectiveNoFileComp))
+	_ = statusCmd.RegisterFlagCompletionFunc("sort", cobra.FixedCompletions(statusSortKeys, cobra.ShellCompDirectiveNoFileComp))
 	rootCmd.AddCommand(statusCmd)
 }
 
@@ -44,35 +103,111 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		filter.All = true
 	}
 
+	if statusTUI {
+		return runStatusTUI(filter)
+	}
+
+	if statusSort != "" {
+		if err := checkSortKey(statusSort, statusSortKeys); err != nil {
+			return err
+		}
+	}
+	switch statusPorcelain {
+	case "", porcelainV1, porcelainV2:
+	default:
+		return fmt.Errorf("unsupported porcelain format: %s (must be %s or %s)", statusPorcelain, porcelainV1, porcelainV2)
+	}
+	if statusNul && statusPorcelain == "" {
+		return fmt.Errorf("-z requires --porcelain")
+	}
+
+	defaultColumns := []string{"name", "status", "branch", "commit", "lock"}
+	if statusFetch {
+		defaultColumns = append(defaultColumns, "remote")
+	}
+	columns, err := selectColumns(statusColumns, statusTableColumns, defaultColumns)
+	if err != nil {
+		return err
+	}
+
 	// Create manager
 	mgr := manager.NewRepositoryManager(cfg,
 		manager.WithLockFile(lf),
+		manager.WithLogger(logger),
+		manager.WithVerbose(verboseOutput()),
 	)
 
 	// Get status
-	statuses, err := mgr.Status(filter)
+	var statuses []manager.RepoStatus
+	if statusFetch {
+		statuses, err = mgr.StatusUpstream(context.Background(), filter)
+	} else {
+		statuses, err = mgr.Status(filter)
+	}
 	if err != nil {
 		return err
 	}
 
-	if len(statuses) == 0 {
+	if len(statuses) == 0 && statusPorcelain != porcelainV2 {
 		fmt.Println("No repositories configured")
 		return nil
 	}
+	if filter.All {
+		saveStatusCache(statuses)
+	}
+
+	if statusSort != "" {
+		sortStatuses(statuses, statusSort)
+	}
+
+	q := loadQuarantine()
 
 	// Output based on format
-	if statusJSON {
-		return outputStatusJSON(statuses)
+	switch {
+	case statusJSON:
+		err = outputStatusJSON(statuses, q)
+	case statusPorcelain == porcelainV2:
+		err = outputStatusPorcelainV2(statuses, q)
+	case statusPorcelain != "":
+		err = outputStatusPorcelain(statuses)
+	case statusByProject:
+		err = outputStatusByProject(statuses, columns)
+		warnQuarantinedStatus(q, statuses)
+	default:
+		err = outputStatusTable(statuses, columns)
+		warnQuarantinedStatus(q, statuses)
+	}
+	if err != nil {
+		return err
 	}
 
-	if statusPorcelain {
-		return outputStatusPorcelain(statuses)
+	if statusExitCode {
+		if code := workspaceExitStatus(statuses); code != statusExitClean {
+			return &exitStatusError{status: code}
+		}
 	}
+	return nil
+}
 
-	return outputStatusTable(statuses)
+// workspaceExitStatus returns the --exit-code status for the most severe
+// state of any repository.
+func workspaceExitStatus(statuses []manager.RepoStatus) int {
+	code := statusExitClean
+	for _, s := range statuses {
+		_, plain := getStatusString(s)
+		switch {
+		case plain == "error" || s.RemoteError != nil:
+			return statusExitError
+		case plain == "dirty":
+			code = max(code, statusExitDirty)
+		case plain != "ok" || s.NeedsUpdate:
+			code = max(code, statusExitNeedsUpdate)
+		}
+	}
+	return code
 }
 
-func outputStatusJSON(statuses []manager.RepoStatus) error {
+func outputStatusJSON(statuses []manager.RepoStatus, q *quarantine.Store) error {
 	type jsonStatus struct {
 		Name         string `json:"name"`
 		Path         string `json:"path"`
@@ -84,6 +219,12 @@ func outputStatusJSON(statuses []manager.RepoStatus) error {
 		IsDirty      bool   `json:"is_dirty"`
 		NeedsUpdate  bool   `json:"needs_update"`
 		Error        string `json:"error,omitempty"`
+		Code         string `json:"code,omitempty"`
+		RemoteSHA    string `json:"remote_sha,omitempty"`
+		Ahead        *int   `json:"ahead,omitempty"`
+		Behind       *int   `json:"behind,omitempty"`
+		RemoteError  string `json:"remote_error,omitempty"`
+		Quarantined  bool   `json:"quarantined,omitempty"`
 	}
 
 	output := make([]jsonStatus, len(statuses))
@@ -98,9 +239,22 @@ func outputStatusJSON(statuses []manager.RepoStatus) error {
 			Branch:       s.Branch,
 			IsDirty:      s.IsDirty,
 			NeedsUpdate:  s.NeedsUpdate,
+			Quarantined:  q != nil && q.IsQuarantined(s.Name),
 		}
 		if s.Error != nil {
 			output[i].Error = s.Error.Error()
+			output[i].Code = string(errcode.Of(s.Error))
+		}
+		if s.RemoteChecked {
+			output[i].RemoteSHA = s.RemoteSHA
+			output[i].Ahead = &s.Ahead
+			output[i].Behind = &s.Behind
+		}
+		if s.RemoteError != nil {
+			output[i].RemoteError = s.RemoteError.Error()
+			if output[i].Code == "" {
+				output[i].Code = string(errcode.Of(s.RemoteError))
+			}
 		}
 	}
 
@@ -128,26 +282,205 @@ func outputStatusPorcelain(statuses []manager.RepoStatus) error {
 			sha = sha[:8]
 		}
 
-		fmt.Printf("%s\t%s\t%s\t%s\n", s.Name, status, sha, s.RequestedRef)
+		fmt.Printf("%s\t%s\t%s\t%s%s", s.Name, status, sha, s.RequestedRef, porcelainTerminator())
 	}
 	return nil
 }
 
-func outputStatusTable(statuses []manager.RepoStatus) error {
-	// Calculate max repo name width
-	maxNameWidth := 10
+// Versions of status --porcelain. v1 is the original ad-hoc format, kept
+// for existing scripts.
+const (
+	porcelainV1 = "v1"
+	porcelainV2 = "v2"
+)
+
+// porcelainV2Header is the first record of --porcelain=v2 output.
+const porcelainV2Header = "# porcelain v2"
+
+// porcelainTerminator returns the end of a porcelain record: NUL with -z,
+// otherwise a newline.
+func porcelainTerminator() string {
+	if statusNul {
+		return "\x00"
+	}
+	return "\n"
+}
+
+// outputStatusPorcelainV2 prints the --porcelain=v2 format: a header,
+// then one record per repository with the tab-separated fields
+//
+//	name status lock commit locked ref branch ahead behind quarantined code path
+//
+// with "-" for empty values, quoted by porcelainField. status is ok,
+// dirty, outdated, missing, or error; lock is locked, drift, or -; commit
+// and locked are full SHAs; ahead and behind are counts with --fetch;
+// quarantined is yes or no; and code is the error code of a failed
+// inspection. Later releases may add fields at the end of a record, but
+// never change or reorder these.
+func outputStatusPorcelainV2(statuses []manager.RepoStatus, q *quarantine.Store) error {
+
+	end := porcelainTerminator()
+	fmt.Print(porcelainV2Header + end)
 	for _, s := range statuses {
-		if len(s.Name) > maxNameWidth {
-			maxNameWidth = len(s.Name)
+		_, status := getStatusString(s)
+		var ahead, behind string
+		if s.RemoteChecked {
+			ahead, behind = strconv.Itoa(s.Ahead), strconv.Itoa(s.Behind)
+		}
+		quarantined := "no"
+		if q != nil && q.IsQuarantined(s.Name) {
+			quarantined = "yes"
+		}
+		code := errcode.Of(s.Error)
+		if code == "" {
+			code = errcode.Of(s.RemoteError)
+		}
+
+		fields := []string{
+			s.Name,
+			status,
+			getLockString(s),
+			s.CurrentSHA,
+			s.LockedSHA,
+			s.RequestedRef,
+			s.Branch,
+			ahead,
+			behind,
+			quarantined,
+			string(code),
+			s.Path,
+		}
+		for i, f := range fields {
+			fields[i] = porcelainField(f)
+		}
+		fmt.Print(strings.Join(fields, "\t") + end)
+	}
+	return nil
+}
+
+// porcelainField formats a --porcelain=v2 field. An empty value is "-".
+// A value that is "-" itself, or contains a tab, newline, double quote,
+// backslash, or other unprintable character, is double-quoted with
+// C-style escapes, as git quotes unusual paths, so that every field is
+// unambiguous and free of separators.
+func porcelainField(v string) string {
+	if v == "" {
+		return "-"
+	}
+	if quoted := strconv.Quote(v); v == "-" || quoted != `"`+v+`"` {
+		return quoted
+	}
+	return v
+}
+
+func outputStatusTable(statuses []manager.RepoStatus, columns []tableColumn) error {
+	rows := statusRows(statuses, columns)
+	printTable(columns, columnWidths(columns, rows), rows)
+	warnRemoteErrors(statuses)
+	return nil
+}
+
+// outputStatusByProject prints one table per project with a summary of
+// its repositories, then one for repositories in no project.
+func outputStatusByProject(statuses []manager.RepoStatus, columns []tableColumn) error {
+	// Sections share column widths so that they line up
+	widths := columnWidths(columns, statusRows(statuses, columns))
+	assigned := make(map[string]bool)
+
+	section := func(title string, members []manager.RepoStatus) {
+		if len(members) == 0 {
+			return
 		}
+		fmt.Printf("%s  %s\n", ui.TitleStyle.Render(title), ui.MutedStyle.Render(statusSummary(members)))
+		printTable(columns, widths, statusRows(members, columns))
+		fmt.Println()
 	}
 
-	// Print header
-	fmt.Printf("%-*s  %-8s  %-15s  %-8s  %s\n",
-		maxNameWidth, "REPOSITORY", "STATUS", "BRANCH", "COMMIT", "LOCK")
+	for _, proj := range cfg.Projects {
+		var members []manager.RepoStatus
+		for _, s := range statuses {
+			if proj.HasRepository(s.Name) {
+				members = append(members, s)
+				assigned[s.Name] = true
+			}
+		}
+		section(proj.Name, members)
+	}
 
+	var unassigned []manager.RepoStatus
 	for _, s := range statuses {
-		status, statusPlain := getStatusString(s)
+		if !assigned[s.Name] {
+			unassigned = append(unassigned, s)
+		}
+	}
+	section("unassigned", unassigned)
+
+	fmt.Printf("Total  %s\n", statusSummary(statuses))
+	warnRemoteErrors(statuses)
+	return nil
+}
+
+// statusSummary counts repositories by status, e.g.
+// "3 repositories: 2 ok, 1 outdated".
+func statusSummary(statuses []manager.RepoStatus) string {
+	counts := make(map[string]int)
+	for _, s := range statuses {
+		_, plain := getStatusString(s)
+		counts[plain]++
+	}
+
+	var parts []string
+	for _, status := range []string{"ok", "dirty", "outdated", "missing", "error"} {
+		if n := counts[status]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, status))
+		}
+	}
+
+	noun := "repositories"
+	if len(statuses) == 1 {
+		noun = "repository"
+	}
+	return fmt.Sprintf("%d %s: %s", len(statuses), noun, strings.Join(parts, ", "))
+}
+
+// statusTableColumns are the columns available to the status table.
+var statusTableColumns = []tableColumn{
+	{name: "name", title: "REPOSITORY", width: 10},
+	{name: "status", title: "STATUS", width: 8},
+	{name: "branch", title: "BRANCH", width: 15},
+	{name: "commit", title: "COMMIT", width: 8},
+	{name: "lock", title: "LOCK", width: 6},
+	{name: "remote", title: "REMOTE"},
+	{name: "type", title: "TYPE"},
+	{name: "path", title: "PATH"},
+	{name: "age", title: "SYNCED"},
+}
+
+// statusSortKeys are the values accepted by status --sort.
+var statusSortKeys = []string{"name", "status", "type", "path", "age"}
+
+// statusRows returns the cells of the status table.
+func statusRows(statuses []manager.RepoStatus, columns []tableColumn) [][]tableCell {
+	rows := make([][]tableCell, len(statuses))
+	for i, s := range statuses {
+		row := make([]tableCell, len(columns))
+		for c, col := range columns {
+			row[c] = statusCell(s, col.name)
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+// statusCell returns the value of the named column for a repository.
+func statusCell(s manager.RepoStatus, column string) tableCell {
+	switch column {
+	case "name":
+		return plainCell(s.Name)
+	case "status":
+		text, plain := getStatusString(s)
+		return tableCell{plain: plain, text: text}
+	case "branch":
 		branch := s.Branch
 		if branch == "" {
 			branch = s.RequestedRef
@@ -155,34 +488,94 @@ func outputStatusTable(statuses []manager.RepoStatus) error {
 		if len(branch) > 15 {
 			branch = branch[:15]
 		}
+		return plainCell(branch)
+	case "commit":
+		if s.CurrentSHA == "" {
+			return plainCell("-")
+		}
+		return plainCell(s.CurrentSHA[:min(8, len(s.CurrentSHA))])
+	case "lock":
+		switch lock := getLockString(s); lock {
+		case "locked":
+			return tableCell{plain: lock, text: ui.SuccessStyle.Render(lock)}
+		case "drift":
+			return tableCell{plain: lock, text: ui.WarningStyle.Render(lock)}
+		}
+		return plainCell("-")
+	case "remote":
+		text, plain := getRemoteString(s)
+		return tableCell{plain: plain, text: text}
+	case "type":
+		if repo, ok := cfg.GetRepository(s.Name); ok {
+			return plainCell(string(repo.Type))
+		}
+		return plainCell("-")
+	case "path":
+		return plainCell(s.Path)
+	case "age":
+		return plainCell(formatAge(lastSynced(s.Name)))
+	}
+	return plainCell("")
+}
 
-		commit := "-"
-		if s.CurrentSHA != "" {
-			commit = s.CurrentSHA[:min(8, len(s.CurrentSHA))]
+// sortStatuses orders statuses by the --sort key. Sorting by status puts
+// repositories that need attention first; sorting by age puts those
+// synced longest ago first.
+func sortStatuses(statuses []manager.RepoStatus, key string) {
+	rank := func(s manager.RepoStatus) int {
+		_, plain := getStatusString(s)
+		switch plain {
+		case "error":
+			return 0
+		case "missing":
+			return 1
+		case "outdated":
+			return 2
+		case "dirty":
+			return 3
+		default:
+			return 4
 		}
+	}
+	repoType := func(s manager.RepoStatus) string {
+		if repo, ok := cfg.GetRepository(s.Name); ok {
+			return string(repo.Type)
+		}
+		return ""
+	}
 
-		lockStatus := "-"
-		if s.LockedSHA != "" {
-			if s.CurrentSHA == s.LockedSHA {
-				lockStatus = ui.SuccessStyle.Render("locked")
-			} else {
-				lockStatus = ui.WarningStyle.Render("drift")
+	sort.SliceStable(statuses, func(i, j int) bool {
+		a, b := statuses[i], statuses[j]
+		switch key {
+		case "status":
+			if rank(a) != rank(b) {
+				return rank(a) < rank(b)
+			}
+		case "type":
+			if repoType(a) != repoType(b) {
+				return repoType(a) < repoType(b)
+			}
+		case "path":
+			if a.Path != b.Path {
+				return a.Path < b.Path
+			}
+		case "age":
+			if at, bt := lastSynced(a.Name), lastSynced(b.Name); !at.Equal(bt) {
+				return at.Before(bt)
 			}
 		}
+		return a.Name < b.Name
+	})
+}
 
-		// Print with fixed widths, accounting for ANSI codes in status
-		// Status field: print colored text then pad with spaces
-		statusPadding := 8 - len(statusPlain)
-		fmt.Printf("%-*s  %s%*s  %-15s  %-8s  %s\n",
-			maxNameWidth, s.Name,
-			status, statusPadding, "",
-			branch,
-			commit,
-			lockStatus,
-		)
+// warnRemoteErrors reports repositories that could not be compared with
+// their remote.
+func warnRemoteErrors(statuses []manager.RepoStatus) {
+	for _, s := range statuses {
+		if s.RemoteError != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s: failed to compare with remote: %v\n", s.Name, s.RemoteError)
+		}
 	}
-
-	return nil
 }
 
 func getStatusString(s manager.RepoStatus) (styled string, plain string) {
@@ -201,6 +594,111 @@ func getStatusString(s manager.RepoStatus) (styled string, plain string) {
 	return ui.SuccessStyle.Render("ok"), "ok"
 }
 
+// getLockString returns whether the repository is at its locked commit:
+// "locked", "drift", or "" if it is not locked.
+func getLockString(s manager.RepoStatus) string {
+	switch {
+	case s.LockedSHA == "":
+		return ""
+	case s.CurrentSHA == s.LockedSHA:
+		return "locked"
+	default:
+		return "drift"
+	}
+}
+
+// getRemoteString describes how a repository compares with its remote
+// ref, or returns "-" if it was not compared.
+func getRemoteString(s manager.RepoStatus) (styled string, plain string) {
+	switch {
+	case s.RemoteError != nil:
+		return ui.ErrorStyle.Render("error"), "error"
+	case !s.RemoteChecked:
+		return "-", "-"
+	case s.Ahead > 0 && s.Behind > 0:
+		plain = fmt.Sprintf("ahead %d, behind %d", s.Ahead, s.Behind)
+		return ui.WarningStyle.Render(plain), plain
+	case s.Behind > 0:
+		plain = fmt.Sprintf("behind %d", s.Behind)
+		return ui.WarningStyle.Render(plain), plain
+	case s.Ahead > 0:
+		plain = fmt.Sprintf("ahead %d", s.Ahead)
+		return plain, plain
+	default:
+		return ui.SuccessStyle.Render("up to date"), "up to date"
+	}
+}
+
+// runStatusTUI runs the interactive status dashboard.
+func runStatusTUI(filter manager.Filter) error {
+	if !stdinIsTerminal() {
+		return fmt.Errorf("--tui requires a terminal")
+	}
+
+	load := func() ([]ui.StatusRow, error) {
+		mgr := manager.NewRepositoryManager(cfg,
+			manager.WithLockFile(lf),
+			manager.WithLogger(logger),
+		)
+		statuses, err := mgr.Status(filter)
+		if err != nil {
+			return nil, err
+		}
+
+		rows := make([]ui.StatusRow, len(statuses))
+		for i, s := range statuses {
+			_, status := getStatusString(s)
+			branch := s.Branch
+			if branch == "" {
+				branch = s.RequestedRef
+			}
+			commit := "-"
+			if s.CurrentSHA != "" {
+				commit = shortSHA(s.CurrentSHA)
+			}
+			rows[i] = ui.StatusRow{
+				Name:    s.Name,
+				Project: cfg.ProjectOf(s.Name),
+				Status:  status,
+				Branch:  branch,
+				Commit:  commit,
+				Lock:    getLockString(s),
+			}
+			if s.Error != nil {
+				rows[i].Error = s.Error.Error()
+			}
+		}
+		return rows, nil
+	}
+
+	// Syncing runs without a progress display or prompts, which would
+	// disturb the dashboard.
+	sync := func(names []string) error {
+		mgr := manager.NewRepositoryManager(cfg,
+			manager.WithLockFile(lf),
+			manager.WithLogger(logger),
+		)
+		filter := manager.Filter{Names: names}
+		before := lf.Clone()
+		result, err := mgr.Sync(filter)
+		if err != nil {
+			return err
+		}
+		if err := saveLockFile(); err != nil {
+			return fmt.Errorf("failed to save lock file: %w", err)
+		}
+		if err := historyStore().Append(history.NewSyncEntry(filter.String(), result, lockfile.Diff(before, lf))); err != nil {
+			return fmt.Errorf("failed to record history: %w", err)
+		}
+		if result.HasFailures() {
+			return syncFailedError(result)
+		}
+		return nil
+	}
+
+	return ui.RunDashboard(load, sync)
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/history"
+	"github.com/tierone/harbormaster/pkg/lockfile"
 	"github.com/tierone/harbormaster/pkg/manager"
+	"github.com/tierone/harbormaster/pkg/types"
 	"github.com/tierone/harbormaster/pkg/ui"
 )
 
@@ -14,6 +21,11 @@ var (
 	syncTag      string
 	syncParallel int
 	syncDryRun   bool
+	syncCheck    bool
+	syncInteract bool
+	syncLowPrio  bool
+	syncFsck     bool
+	syncForce    bool
 )
 
 var syncCmd = &cobra.Command{
@@ -25,16 +37,45 @@ Without arguments, syncs all repositories. Specify repository names
 to sync specific ones, or use --project to sync a project's repositories.
 
 Use --locked to sync to the exact commits recorded in the lock file
-for reproducible builds.`,
-	RunE: runSync,
+for reproducible builds.
+
+Use --check to resolve what a sync would do without touching any working
+tree. It lists the repositories that would be cloned or updated and why,
+and exits with status 4 if anything would change.
+
+Use --interactive to pick the repositories to sync from a checklist.
+Repositories matching the arguments or filters are preselected.
+
+Use --fsck to check existing checkouts with git fsck first and repair
+corrupt ones, as hm fsck --repair does.
+
+Repositories synced more recently than their min_sync_interval are
+skipped, unless they are named on the command line or --force is given.
+
+When run in a terminal, a sync asks for the username and token or
+password of each HTTPS host that refused to authenticate repositories,
+once all have been tried, and syncs them again. A credential that works
+is used for the rest of the sync, and can be stored in the keychain.`,
+	ValidArgsFunction: completeRepositories,
+	RunE:              runSync,
 }
 
 func init() {
 	syncCmd.Flags().BoolVar(&syncLocked, "locked", false, "sync to locked SHAs only")
 	syncCmd.Flags().StringVarP(&syncProject, "project", "p", "", "sync repositories in project")
 	syncCmd.Flags().StringVarP(&syncTag, "tag", "t", "", "sync repositories with tag")
-	syncCmd.Flags().IntVar(&syncParallel, "parallel", 4, "number of concurrent operations")
+	syncCmd.Flags().IntVar(&syncParallel, "parallel", 0, "number of concurrent operations (default: general.concurrency or 4)")
 	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "show what would be synced")
+	syncCmd.Flags().BoolVar(&syncCheck, "check", false, "resolve refs and fail if anything would change")
+	syncCmd.Flags().BoolVarP(&syncInteract, "interactive", "i", false, "choose repositories to sync from a list")
+	syncCmd.Flags().BoolVar(&syncLowPrio, "low-priority", false, "run git with reduced CPU and I/O priority (default: general.low_priority)")
+	syncCmd.Flags().BoolVar(&syncFsck, "fsck", false, "check checkouts for corruption and repair them before syncing")
+	syncCmd.Flags().BoolVar(&syncForce, "force", false, "sync repositories synced within their min_sync_interval too")
+
+	syncCmd.MarkFlagsMutuallyExclusive("check", "dry-run")
+
+	_ = syncCmd.RegisterFlagCompletionFunc("project", completeProjects)
+	_ = syncCmd.RegisterFlagCompletionFunc("tag", completeTags)
 	rootCmd.AddCommand(syncCmd)
 }
 
@@ -51,34 +92,79 @@ func runSync(cmd *cobra.Command, args []string) error {
 		filter.All = true
 	}
 
+	if syncLowPrio {
+		cfg.General.LowPriority = true
+	}
+
 	// Create manager
 	mgr := manager.NewRepositoryManager(cfg,
 		manager.WithLockFile(lf),
+		manager.WithLogger(logger),
+		manager.WithVerbose(verboseOutput()),
 		manager.WithConcurrency(syncParallel),
 		manager.WithLocked(syncLocked),
-		manager.WithInteractive(!quiet),
 	)
 
+	// Follow the sources before deciding what to sync, unless the sync
+	// only looks or must reproduce the lock file
+	if !syncLocked && !syncCheck && !syncDryRun {
+		if err := refreshStaleSources(mgr); err != nil {
+			return err
+		}
+	}
+
+	if syncInteract {
+		selected, err := selectRepositories(mgr, filter)
+		if errors.Is(err, ui.ErrSelectionCanceled) || (err == nil && len(selected) == 0) {
+			fmt.Println("Cancelled")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		filter = manager.Filter{Names: selected}
+	}
+
+	if syncCheck {
+		return runSyncCheck(mgr, filter)
+	}
+
 	// Dry run - just show what would be synced
 	if syncDryRun {
 		return runSyncDryRun(mgr, filter)
 	}
 
 	// Create and start UI
-	uiMgr := ui.NewProgressManager(!quiet)
+	uiMgr := ui.NewProgressManager(useInteractiveUI())
+	groups := make(map[string]string, len(cfg.Repositories))
+	for _, repo := range cfg.Repositories {
+		groups[repo.Name] = cfg.ProjectOf(repo.Name)
+	}
+	uiMgr.SetGroups(groups)
+	uiMgr.SetFolding(foldStyle)
 	if err := uiMgr.Start(); err != nil {
 		return fmt.Errorf("failed to start UI: %w", err)
 	}
 
-	mgr = manager.NewRepositoryManager(cfg,
+	q := loadQuarantine()
+	opts := []manager.ManagerOption{
 		manager.WithLockFile(lf),
+		manager.WithLogger(logger),
+		manager.WithVerbose(verboseOutput()),
 		manager.WithConcurrency(syncParallel),
 		manager.WithLocked(syncLocked),
-		manager.WithInteractive(!quiet),
 		manager.WithUI(uiMgr),
-	)
+		manager.WithQuarantine(q),
+		manager.WithFsck(syncFsck),
+		manager.WithForce(syncForce),
+	}
+	if stdinIsTerminal() && !quiet {
+		opts = append(opts, manager.WithCredentialPrompt(credentialPrompt(uiMgr)))
+	}
+	mgr = manager.NewRepositoryManager(cfg, opts...)
 
 	// Run sync
+	before := lf.Clone()
 	result, err := mgr.Sync(filter)
 	if err != nil {
 		return err
@@ -91,17 +177,151 @@ func runSync(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	recordHistory(history.NewSyncEntry(filter.String(), result, lockfile.Diff(before, lf)))
+	warnQuarantined(q, result)
+	warnRecovered(result)
+	warnRewritten(result)
+	if len(result.Fresh) > 0 && !quiet {
+		fmt.Printf("Skipped %d recently synced repositories (min_sync_interval; use --force to sync them)\n", len(result.Fresh))
+	}
+
+	// Notify regardless of outcome; delivery failures don't fail the sync
+	if err := mgr.NotifySync(context.Background(), result); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to send notifications: %v\n", err)
+	}
+
 	// Return error if any operations failed
 	if result.HasFailures() {
 		// Print details for each failure
 		for _, f := range result.FailedResults() {
-			if f.Error != nil {
+			if code := errcode.Of(f.Error); code != "" {
+				fmt.Printf("  %s: [%s] %v\n", f.RepoName, code, f.Error)
+			} else if f.Error != nil {
 				fmt.Printf("  %s: %v\n", f.RepoName, f.Error)
 			} else {
 				fmt.Printf("  %s: unknown error\n", f.RepoName)
 			}
 		}
-		return fmt.Errorf("%d of %d repositories failed to sync", result.FailureCount, result.TotalRepos)
+		return syncFailedError(result)
+	}
+
+	return runPostSync(mgr)
+}
+
+// warnRecovered reports the repositories whose clone, interrupted by an
+// earlier sync, was removed and cloned again, and those whose corrupt
+// checkout was repaired.
+func warnRecovered(result *types.SyncResult) {
+	for _, r := range result.RecoveredResults() {
+		fmt.Fprintf(os.Stderr, "Warning: removed an interrupted clone of %s and cloned it again\n", r.RepoName)
+	}
+	for _, r := range result.Results {
+		switch r.Repaired {
+		case manager.RepairRefetched:
+			fmt.Fprintf(os.Stderr, "Warning: repaired the corrupt checkout of %s by fetching its objects again\n", r.RepoName)
+		case manager.RepairRecloned:
+			fmt.Fprintf(os.Stderr, "Warning: removed the corrupt checkout of %s and cloned it again\n", r.RepoName)
+		}
+	}
+}
+
+// warnRewritten reports the repositories whose branch was rewritten
+// upstream since the previous sync, dropping the commit locked then.
+func warnRewritten(result *types.SyncResult) {
+	rewritten := result.RewrittenResults()
+	if len(rewritten) == 0 {
+		return
+	}
+	fmt.Fprintln(os.Stderr, ui.SummaryErrorStyle.Render(fmt.Sprintf("! History rewritten upstream in %d repositories", len(rewritten))))
+	for _, r := range rewritten {
+		branch := "its branch"
+		if r.Branch != "" {
+			branch = r.Branch
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s: %s was rewritten (force-pushed): %s is no longer in its history, now at %s\n",
+			r.RepoName, branch, shortSHA(r.PreviousSHA), shortSHA(r.CommitSHA))
+	}
+}
+
+// selectRepositories lets the user pick repositories to sync, listed under
+// their projects with those matching filter preselected.
+func selectRepositories(mgr *manager.RepositoryManager, filter manager.Filter) ([]string, error) {
+	if !stdinIsTerminal() {
+		return nil, fmt.Errorf("--interactive requires a terminal")
+	}
+
+	matched, err := mgr.Repositories(filter)
+	if err != nil {
+		return nil, err
+	}
+	preselected := make(map[string]bool, len(matched))
+	for _, repo := range matched {
+		preselected[repo.Name] = true
+	}
+
+	// List each repository under the first project that contains it,
+	// followed by those in no project.
+	var items []ui.SelectItem
+	listed := make(map[string]bool)
+	for _, proj := range cfg.Projects {
+		for _, name := range proj.Repositories {
+			if listed[name] {
+				continue
+			}
+			if _, ok := cfg.GetRepository(name); !ok {
+				continue
+			}
+			listed[name] = true
+			items = append(items, ui.SelectItem{Name: name, Group: proj.Name, Selected: preselected[name]})
+		}
+	}
+	for _, repo := range cfg.Repositories {
+		if !listed[repo.Name] {
+			items = append(items, ui.SelectItem{Name: repo.Name, Selected: preselected[repo.Name]})
+		}
+	}
+
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no repositories configured")
+	}
+	return ui.RunSelector("Select repositories to sync", items)
+}
+
+// syncFailedError summarizes a sync with failures. It carries the code of
+// the first failure so that the exit status reflects its class.
+func syncFailedError(result *types.SyncResult) error {
+	code := errcode.SyncFailed
+	for _, f := range result.FailedResults() {
+		if c := errcode.Of(f.Error); c != "" {
+			code = c
+			break
+		}
+	}
+	return errcode.Wrap(code, fmt.Errorf("%d of %d repositories failed to sync", result.FailureCount, result.TotalRepos))
+}
+
+// runPostSync runs workspace steps that depend on a fully synced workspace.
+func runPostSync(mgr *manager.RepositoryManager) error {
+	if cfg.Overlay.Enabled() {
+		res, err := mgr.BuildOverlay()
+		if err != nil {
+			return fmt.Errorf("failed to build overlay: %w", err)
+		}
+		if !quiet {
+			fmt.Printf("Overlay: %d links in %s\n", res.Links, res.Target)
+		}
+	}
+
+	if len(cfg.Generate) > 0 {
+		written, err := mgr.GenerateFiles()
+		if err != nil {
+			return fmt.Errorf("failed to generate files: %w", err)
+		}
+		if !quiet {
+			for _, path := range written {
+				fmt.Printf("Generated: %s\n", path)
+			}
+		}
 	}
 
 	return nil
@@ -138,3 +358,43 @@ func runSyncDryRun(mgr *manager.RepositoryManager, filter manager.Filter) error
 
 	return nil
 }
+
+// runSyncCheck reports what a sync would change without changing it and
+// fails if anything would.
+func runSyncCheck(mgr *manager.RepositoryManager, filter manager.Filter) error {
+	plans, err := mgr.Plan(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	failed, changes := 0, 0
+	for _, p := range plans {
+		if p.Error != nil {
+			if firstErr == nil {
+				firstErr = p.Error
+			}
+			failed++
+			fmt.Fprintf(os.Stderr, "  %s: %v\n", p.Name, p.Error)
+			continue
+		}
+		if !p.Changes() {
+			continue
+		}
+		changes++
+		if !quiet {
+			fmt.Printf("  %s: %s (%s) - %s\n", p.Name, p.Action, p.Ref, p.Reason)
+		}
+	}
+
+	if firstErr != nil {
+		return errcode.Wrap(errcode.Of(firstErr), fmt.Errorf("could not check %d of %d repositories", failed, len(plans)))
+	}
+	if changes > 0 {
+		return errcode.Wrap(errcode.SyncPending, fmt.Errorf("%d of %d repositories would change", changes, len(plans)))
+	}
+	if !quiet {
+		fmt.Printf("All %d repositories are up to date\n", len(plans))
+	}
+	return nil
+}
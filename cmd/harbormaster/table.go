@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// tableColumn is a column of a listing that can be chosen with --columns.
+type tableColumn struct {
+	name  string // Name used with --columns
+	title string
+	width int  // Minimum width
+	right bool // Align to the right, for numbers
+}
+
+// tableCell is one cell of a table. plain is used for alignment and text
+// is printed, which may include ANSI styling.
+type tableCell struct {
+	plain string
+	text  string
+}
+
+// plainCell returns a cell without styling.
+func plainCell(s string) tableCell {
+	return tableCell{plain: s, text: s}
+}
+
+// columnNames returns the names of the columns, for help and errors.
+func columnNames(columns []tableColumn) string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// selectColumns resolves a comma-separated --columns value against the
+// available columns. An empty value selects the defaults.
+func selectColumns(spec string, available []tableColumn, defaults []string) ([]tableColumn, error) {
+	names := defaults
+	if spec != "" {
+		names = strings.Split(spec, ",")
+	}
+
+	var selected []tableColumn
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, c := range available {
+			if c.name == name {
+				selected = append(selected, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid column: %s (must be one of %s)", name, columnNames(available))
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no columns selected")
+	}
+	return selected, nil
+}
+
+// columnWidths returns the width of each column: the widest of its
+// minimum, its title, and its cells.
+func columnWidths(columns []tableColumn, rows [][]tableCell) []int {
+	widths := make([]int, len(columns))
+	for i, c := range columns {
+		widths[i] = max(c.width, len(c.title))
+		for _, row := range rows {
+			widths[i] = max(widths[i], len(row[i].plain))
+		}
+	}
+	return widths
+}
+
+// printTable prints a header and the rows with columns two spaces apart.
+func printTable(columns []tableColumn, widths []int, rows [][]tableCell) {
+	header := make([]tableCell, len(columns))
+	for i, c := range columns {
+		header[i] = plainCell(c.title)
+	}
+	printTableRow(columns, widths, header)
+	for _, row := range rows {
+		printTableRow(columns, widths, row)
+	}
+}
+
+func printTableRow(columns []tableColumn, widths []int, row []tableCell) {
+	var b strings.Builder
+	for i, cell := range row {
+		if i > 0 {
+			b.WriteString("  ")
+		}
+		padding := strings.Repeat(" ", max(widths[i]-len(cell.plain), 0))
+		switch {
+		case columns[i].right:
+			b.WriteString(padding + cell.text)
+		case i < len(row)-1:
+			b.WriteString(cell.text + padding)
+		default:
+			b.WriteString(cell.text)
+		}
+	}
+	fmt.Println(strings.TrimRight(b.String(), " "))
+}
+
+// checkSortKey returns an error unless key is one of keys.
+func checkSortKey(key string, keys []string) error {
+	for _, k := range keys {
+		if k == key {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid sort key: %s (must be one of %s)", key, strings.Join(keys, ", "))
+}
+
+// lastSynced returns when the repository was last synced according to
+// the lock file, or the zero time if it never was.
+func lastSynced(name string) time.Time {
+	if lf == nil {
+		return time.Time{}
+	}
+	entry, ok := lf.Get(name)
+	if !ok {
+		return time.Time{}
+	}
+	return entry.LastSyncedAt
+}
+
+// formatAge describes how long ago t was, e.g. "3h ago", or "never" for
+// the zero time.
+func formatAge(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
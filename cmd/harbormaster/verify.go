@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/manager"
+	"github.com/tierone/harbormaster/pkg/ui"
+)
+
+var (
+	verifyProject string
+	verifyTag     string
+	verifyJSON    bool
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [repository...]",
+	Short: "Check that locked commits are signed",
+	Long: `Check the signature of the commit locked for each repository in a project
+with require_signed = true, using git verify-commit and the GPG or SSH
+trust configured for git. A repository pinned to a tag, or resolving to
+one, also passes if the tag is signed and points at the locked commit.
+
+Locked commits missing from a checkout are fetched. Missing, vendored,
+and non-git repositories are skipped.
+
+Repositories whose branch the last sync found rewritten upstream, such as
+by a force-push that dropped the commit locked before, are reported as
+warnings for every selected repository, signed or not.
+
+Configured notifications receive a summary of the check with the event
+"verify".
+
+Exits with status 3 (HM109) if any locked commit has no valid signature.`,
+	ValidArgsFunction: completeRepositories,
+	RunE:              runVerify,
+}
+
+func init() {
+	verifyCmd.Flags().StringVarP(&verifyProject, "project", "p", "", "verify repositories in project")
+	verifyCmd.Flags().StringVarP(&verifyTag, "tag", "t", "", "verify repositories with tag")
+	verifyCmd.Flags().BoolVar(&verifyJSON, "json", false, "output as JSON")
+
+	_ = verifyCmd.RegisterFlagCompletionFunc("project", completeProjects)
+	_ = verifyCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	rootCmd.AddCommand(verifyCmd)
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	filter := manager.Filter{}
+	if len(args) > 0 {
+		filter.Names = args
+	} else if verifyProject != "" {
+		filter.Projects = []string{verifyProject}
+	} else if verifyTag != "" {
+		filter.Tags = []string{verifyTag}
+	} else {
+		filter.All = true
+	}
+
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLockFile(lf),
+		manager.WithLogger(logger),
+		manager.WithVerbose(verboseOutput()),
+	)
+
+	start := time.Now()
+	results, err := mgr.Verify(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+
+	// Delivery failures don't fail the check
+	if err := mgr.NotifyVerify(context.Background(), results, time.Since(start)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to send notifications: %v\n", err)
+	}
+	rewrites, err := mgr.Rewrites(filter)
+	if err != nil {
+		return err
+	}
+	for _, r := range rewrites {
+		fmt.Fprintf(os.Stderr, "Warning: %s: %s was rewritten upstream: locked %s replaced %s, which is no longer in its history\n",
+			r.Name, r.Branch, shortSHA(r.To), shortSHA(r.From))
+	}
+
+	if verifyJSON {
+		if err := outputVerifyJSON(results); err != nil {
+			return err
+		}
+	}
+
+	var firstErr error
+	failed, unsigned := 0, 0
+	for _, r := range results {
+		switch {
+		case r.Error == nil:
+			continue
+		case errcode.Of(r.Error) == errcode.Unsigned:
+			unsigned++
+			if !verifyJSON && !quiet {
+				fmt.Printf("%s  %v\n", ui.TitleStyle.Render(r.Name), r.Error)
+			}
+		default:
+			if firstErr == nil {
+				firstErr = r.Error
+			}
+			failed++
+			fmt.Fprintf(os.Stderr, "  %s: %v\n", r.Name, r.Error)
+		}
+	}
+
+	if unsigned > 0 {
+		return errcode.Wrap(errcode.Unsigned, fmt.Errorf("%d of %d repositories have unsigned commits", unsigned, len(results)))
+	}
+	if firstErr != nil {
+		return errcode.Wrap(errcode.Of(firstErr), fmt.Errorf("could not verify %d of %d repositories", failed, len(results)))
+	}
+	if !verifyJSON && !quiet {
+		if len(results) == 0 {
+			fmt.Println("No repositories require signed commits")
+		} else {
+			fmt.Printf("All %d locked commits are signed\n", len(results))
+		}
+	}
+	return nil
+}
+
+func outputVerifyJSON(results []manager.RepoVerification) error {
+	type jsonRepo struct {
+		Name   string `json:"name"`
+		SHA    string `json:"sha,omitempty"`
+		Signed bool   `json:"signed"`
+		Error  string `json:"error,omitempty"`
+		Code   string `json:"code,omitempty"`
+	}
+
+	output := make([]jsonRepo, len(results))
+	for i, r := range results {
+		output[i] = jsonRepo{Name: r.Name, SHA: r.SHA, Signed: r.Error == nil}
+		if r.Error != nil {
+			output[i].Error = r.Error.Error()
+			output[i].Code = string(errcode.Of(r.Error))
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/history"
+	"github.com/tierone/harbormaster/pkg/lockfile"
+	"github.com/tierone/harbormaster/pkg/manager"
+	"github.com/tierone/harbormaster/pkg/notify"
+)
+
+var (
+	watchInterval time.Duration
+	watchJitter   float64
+	watchPolicy   string
+	watchAddr     string
+	watchProject  string
+	watchTag      string
+	watchParallel int
+	watchOnce     bool
+	watchLowPrio  bool
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [repository...]",
+	Short: "Watch remotes for new commits",
+	Long: `Periodically check each repository's remote (git ls-remote) for the
+configured branch or tag and compare it with the lock file.
+
+Policies:
+  report  print repositories whose remote ref has moved (default)
+  sync    also sync drifted repositories and update the lock file
+
+Intervals are randomized by --jitter so that many workspaces polling the
+same server spread their load. Use --addr to serve the latest results as
+JSON on /status. Repositories pinned to a commit are not watched.
+
+Use --low-priority, or general.low_priority in the config, to run git with
+reduced CPU and I/O priority so that background syncs don't slow down
+builds.`,
+	ValidArgsFunction: completeRepositories,
+	RunE:              runWatch,
+}
+
+func init() {
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 5*time.Minute, "time between checks")
+	watchCmd.Flags().Float64Var(&watchJitter, "jitter", 0.1, "randomize the interval by up to this fraction")
+	watchCmd.Flags().StringVar(&watchPolicy, "policy", "report", "action on drift (report or sync)")
+	watchCmd.Flags().StringVar(&watchAddr, "addr", "", "serve status JSON on this address")
+	watchCmd.Flags().StringVarP(&watchProject, "project", "p", "", "watch repositories in project")
+	watchCmd.Flags().StringVarP(&watchTag, "tag", "t", "", "watch repositories with tag")
+	watchCmd.Flags().IntVar(&watchParallel, "parallel", 0, "number of concurrent operations (default: general.concurrency or 4)")
+	watchCmd.Flags().BoolVar(&watchOnce, "once", false, "check once and exit")
+	watchCmd.Flags().BoolVar(&watchLowPrio, "low-priority", false, "run git with reduced CPU and I/O priority (default: general.low_priority)")
+
+	_ = watchCmd.RegisterFlagCompletionFunc("policy", cobra.FixedCompletions([]string{"report", "sync"}, cobra.ShellCompDirectiveNoFileComp))
+	_ = watchCmd.RegisterFlagCompletionFunc("project", completeProjects)
+	_ = watchCmd.RegisterFlagCompletionFunc("tag", completeTags)
+	rootCmd.AddCommand(watchCmd)
+}
+
+// watchState holds the latest results for the status endpoint.
+type watchState struct {
+	mu        sync.Mutex
+	lastCheck time.Time
+	nextCheck time.Time
+	checks    []manager.RemoteCheck
+	lastSync  *notify.Summary
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	if watchPolicy != "report" && watchPolicy != "sync" {
+		return fmt.Errorf("invalid policy: %s (must be report or sync)", watchPolicy)
+	}
+	if watchInterval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	if watchJitter < 0 || watchJitter >= 1 {
+		return fmt.Errorf("jitter must be between 0 and 1")
+	}
+
+	filter := manager.Filter{}
+	if len(args) > 0 {
+		filter.Names = args
+	} else if watchProject != "" {
+		filter.Projects = []string{watchProject}
+	} else if watchTag != "" {
+		filter.Tags = []string{watchTag}
+	} else {
+		filter.All = true
+	}
+
+	if watchLowPrio {
+		cfg.General.LowPriority = true
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	state := &watchState{}
+	if watchAddr != "" {
+		if err := serveWatchStatus(ctx, state); err != nil {
+			return err
+		}
+	}
+
+	for {
+		if err := watchCheck(ctx, filter, state); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if watchOnce {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Warning: check failed: %v\n", err)
+		}
+		if watchOnce {
+			return nil
+		}
+
+		wait := jitter(watchInterval, watchJitter)
+		state.mu.Lock()
+		state.nextCheck = time.Now().Add(wait)
+		state.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// watchCheck checks the remotes once and applies the policy.
+func watchCheck(ctx context.Context, filter manager.Filter, state *watchState) error {
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLockFile(lf),
+		manager.WithLogger(logger),
+		manager.WithVerbose(verboseOutput()),
+		manager.WithConcurrency(watchParallel),
+	)
+
+	checks, err := mgr.CheckRemotes(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	state.mu.Lock()
+	state.lastCheck = time.Now()
+	state.checks = checks
+	state.mu.Unlock()
+
+	stamp := time.Now().Format(time.RFC3339)
+	var drifted []string
+	for _, c := range checks {
+		switch {
+		case c.Error != nil:
+			fmt.Fprintf(os.Stderr, "%s %s: %v\n", stamp, c.Name, c.Error)
+		case c.Drifted:
+			drifted = append(drifted, c.Name)
+			if !quiet {
+				fmt.Printf("%s %s: %s moved %s -> %s\n", stamp, c.Name, c.Ref, shortSHA(c.LockedSHA), shortSHA(c.RemoteSHA))
+			}
+		}
+	}
+
+	if len(drifted) == 0 {
+		if !quiet {
+			fmt.Printf("%s No drift in %d repositories\n", stamp, len(checks))
+		}
+		return nil
+	}
+
+	if watchPolicy != "sync" {
+		return nil
+	}
+	return watchSync(ctx, drifted, state)
+}
+
+// watchSync syncs the drifted repositories and records the result.
+func watchSync(ctx context.Context, names []string, state *watchState) error {
+	q := loadQuarantine()
+	mgr := manager.NewRepositoryManager(cfg,
+		manager.WithLockFile(lf),
+		manager.WithLogger(logger),
+		manager.WithVerbose(verboseOutput()),
+		manager.WithConcurrency(watchParallel),
+		manager.WithQuarantine(q),
+	)
+
+	filter := manager.Filter{Names: names}
+	before := lf.Clone()
+	result, err := mgr.SyncContext(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if err := saveLockFile(); err != nil {
+		return fmt.Errorf("failed to save lock file: %w", err)
+	}
+
+	recordHistory(history.NewSyncEntry(filter.String(), result, lockfile.Diff(before, lf)))
+	warnQuarantined(q, result)
+	warnRecovered(result)
+	warnRewritten(result)
+
+	if err := mgr.NotifySync(ctx, result); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to send notifications: %v\n", err)
+	}
+
+	state.mu.Lock()
+	state.lastSync = notify.NewSyncSummary(mgr.WorkspaceName(), result)
+	state.mu.Unlock()
+
+	stamp := time.Now().Format(time.RFC3339)
+	for _, r := range result.Results {
+		switch {
+		case !r.Success:
+			fmt.Fprintf(os.Stderr, "%s %s: sync failed: %v\n", stamp, r.RepoName, r.Error)
+		case !quiet:
+			fmt.Printf("%s %s: synced to %s\n", stamp, r.RepoName, shortSHA(r.CommitSHA))
+		}
+	}
+
+	if result.HasFailures() {
+		return syncFailedError(result)
+	}
+	return runPostSync(mgr)
+}
+
+// serveWatchStatus serves the latest watch results as JSON on /status.
+func serveWatchStatus(ctx context.Context, state *watchState) error {
+	ln, err := net.Listen("tcp", watchAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", state.handleStatus)
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "Warning: status server stopped: %v\n", err)
+		}
+	}()
+
+	if !quiet {
+		fmt.Printf("Serving watch status on http://%s/status\n", ln.Addr())
+	}
+	return nil
+}
+
+func (s *watchState) handleStatus(w http.ResponseWriter, r *http.Request) {
+	type jsonCheck struct {
+		Name      string `json:"name"`
+		Ref       string `json:"ref"`
+		LockedSHA string `json:"locked_sha,omitempty"`
+		RemoteSHA string `json:"remote_sha,omitempty"`
+		Drifted   bool   `json:"drifted"`
+		Error     string `json:"error,omitempty"`
+		Code      string `json:"code,omitempty"`
+	}
+	type jsonStatus struct {
+		Policy       string          `json:"policy"`
+		Interval     string          `json:"interval"`
+		LastCheck    *time.Time      `json:"last_check,omitempty"`
+		NextCheck    *time.Time      `json:"next_check,omitempty"`
+		Drifted      int             `json:"drifted"`
+		Repositories []jsonCheck     `json:"repositories"`
+		LastSync     *notify.Summary `json:"last_sync,omitempty"`
+	}
+
+	s.mu.Lock()
+	output := jsonStatus{
+		Policy:       watchPolicy,
+		Interval:     watchInterval.String(),
+		Repositories: make([]jsonCheck, len(s.checks)),
+		LastSync:     s.lastSync,
+	}
+	if !s.lastCheck.IsZero() {
+		last := s.lastCheck
+		output.LastCheck = &last
+	}
+	if !s.nextCheck.IsZero() {
+		next := s.nextCheck
+		output.NextCheck = &next
+	}
+	for i, c := range s.checks {
+		output.Repositories[i] = jsonCheck{
+			Name:      c.Name,
+			Ref:       c.Ref,
+			LockedSHA: c.LockedSHA,
+			RemoteSHA: c.RemoteSHA,
+			Drifted:   c.Drifted,
+		}
+		if c.Error != nil {
+			output.Repositories[i].Error = c.Error.Error()
+			output.Repositories[i].Code = string(errcode.Of(c.Error))
+		}
+		if c.Drifted {
+			output.Drifted++
+		}
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(output)
+}
+
+// jitter randomizes d by up to the given fraction in either direction.
+func jitter(d time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 {
+		return d
+	}
+	spread := int64(float64(d) * fraction)
+	if spread <= 0 {
+		return d
+	}
+	return d + time.Duration(rand.Int64N(2*spread+1)-spread)
+}
+
+// shortSHA abbreviates a SHA for display, or shows "none".
+func shortSHA(sha string) string {
+	switch {
+	case sha == "":
+		return "none"
+	case len(sha) > 8:
+		return sha[:8]
+	default:
+		return sha
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/registry"
+	"github.com/tierone/harbormaster/pkg/ui"
+)
+
+var workspacesJSON bool
+
+var workspacesCmd = &cobra.Command{
+	Use:     "workspaces",
+	Aliases: []string{"ws"},
+	Short:   "Manage the registry of known workspaces",
+	Long: `Keep a per-user registry of workspaces, so that commands can be run
+against any of them by name from anywhere:
+
+  hm workspaces add backend ~/work/backend
+  hm -W backend status
+  hm -W frontend sync
+
+Commands use the workspace given by -W/--workspace, otherwise the one
+containing the current directory, otherwise the current registered
+workspace, which 'hm workspaces switch' selects.
+
+The registry is kept in harbormaster/` + registry.FileName + ` in the user's
+config directory, or in the file named by ` + registry.PathEnv + `.`,
+}
+
+var workspacesListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List registered workspaces",
+	Args:    cobra.NoArgs,
+	RunE:    runWorkspacesList,
+}
+
+var workspacesAddCmd = &cobra.Command{
+	Use:   "add <name> [directory]",
+	Short: "Register a workspace",
+	Long: `Register the workspace rooted at directory under name. Without a
+directory, the workspace containing the current directory is registered.
+The first workspace registered becomes the current one.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runWorkspacesAdd,
+}
+
+var workspacesRemoveCmd = &cobra.Command{
+	Use:               "remove <name>",
+	Aliases:           []string{"rm"},
+	Short:             "Unregister a workspace",
+	Long:              `Remove a workspace from the registry. Its files are not affected.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeWorkspace,
+	RunE:              runWorkspacesRemove,
+}
+
+var workspacesSwitchCmd = &cobra.Command{
+	Use:   "switch <name>",
+	Short: "Select the current workspace",
+	Long: `Make a registered workspace the current one, used by commands run
+outside any workspace.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeWorkspace,
+	RunE:              runWorkspacesSwitch,
+}
+
+func init() {
+	workspacesListCmd.Flags().BoolVar(&workspacesJSON, "json", false, "output as JSON")
+
+	workspacesCmd.AddCommand(workspacesListCmd)
+	workspacesCmd.AddCommand(workspacesAddCmd)
+	workspacesCmd.AddCommand(workspacesRemoveCmd)
+	workspacesCmd.AddCommand(workspacesSwitchCmd)
+	rootCmd.AddCommand(workspacesCmd)
+}
+
+// loadRegistry loads the user's workspace registry.
+func loadRegistry() (*registry.Registry, error) {
+	path, err := registry.DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return registry.Load(path)
+}
+
+// registeredConfigFile returns the config file of the registered
+// workspace name, or of the current one if name is empty. ok is false if
+// name is empty and no workspace is current.
+func registeredConfigFile(name string) (path string, ok bool, err error) {
+	reg, err := loadRegistry()
+	if err != nil {
+		return "", false, err
+	}
+	w, ok, err := reg.Resolve(name)
+	if err != nil || !ok {
+		return "", ok, err
+	}
+	return filepath.Join(w.Path, config.ConfigFileName), true, nil
+}
+
+func runWorkspacesList(cmd *cobra.Command, args []string) error {
+	reg, err := loadRegistry()
+	if err != nil {
+		return err
+	}
+
+	if workspacesJSON {
+		type jsonWorkspace struct {
+			Name    string `json:"name"`
+			Path    string `json:"path"`
+			Current bool   `json:"current"`
+			Exists  bool   `json:"exists"`
+		}
+		output := make([]jsonWorkspace, len(reg.Workspaces))
+		for i, w := range reg.Workspaces {
+			output[i] = jsonWorkspace{Name: w.Name, Path: w.Path, Current: w.Name == reg.Current, Exists: workspaceExists(w)}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(output)
+	}
+
+	if len(reg.Workspaces) == 0 {
+		fmt.Println("No workspaces registered")
+		return nil
+	}
+
+	columns := []tableColumn{
+		{title: "WORKSPACE"},
+		{title: "PATH"},
+		{title: "STATE"},
+	}
+	var rows [][]tableCell
+	for _, w := range reg.Workspaces {
+		var state []string
+		styled := ""
+		if w.Name == reg.Current {
+			state = append(state, "current")
+			styled = ui.SuccessStyle.Render("current")
+		}
+		if !workspaceExists(w) {
+			state = append(state, "missing")
+			if styled != "" {
+				styled += ", "
+			}
+			styled += ui.WarningStyle.Render("missing")
+		}
+		if len(state) == 0 {
+			state, styled = []string{"-"}, "-"
+		}
+		rows = append(rows, []tableCell{
+			plainCell(w.Name),
+			plainCell(w.Path),
+			{plain: strings.Join(state, ", "), text: styled},
+		})
+	}
+	printTable(columns, columnWidths(columns, rows), rows)
+	return nil
+}
+
+// workspaceExists reports whether a registered workspace still has its
+// config file.
+func workspaceExists(w registry.Workspace) bool {
+	_, err := os.Stat(filepath.Join(w.Path, config.ConfigFileName))
+	return err == nil
+}
+
+func runWorkspacesAdd(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	var dir string
+	if len(args) > 1 {
+		expanded, err := config.ExpandPath(args[1])
+		if err != nil {
+			return err
+		}
+		if dir, err = filepath.Abs(expanded); err != nil {
+			return err
+		}
+		if _, err := os.Stat(filepath.Join(dir, config.ConfigFileName)); err != nil {
+			return fmt.Errorf("no workspace in %s: %s not found\nRun 'hm init' there to create one", dir, config.ConfigFileName)
+		}
+	} else {
+		path, err := config.FindConfigFile()
+		if err != nil {
+			return fmt.Errorf("not in a workspace: %w", err)
+		}
+		dir = filepath.Dir(path)
+	}
+
+	reg, err := loadRegistry()
+	if err != nil {
+		return err
+	}
+	if err := reg.Add(name, dir); err != nil {
+		return err
+	}
+	if err := reg.Save(); err != nil {
+		return err
+	}
+
+	if !quiet {
+		fmt.Printf("Registered workspace %s (%s)\n", name, dir)
+	}
+	return nil
+}
+
+func runWorkspacesRemove(cmd *cobra.Command, args []string) error {
+	reg, err := loadRegistry()
+	if err != nil {
+		return err
+	}
+	if !reg.Remove(args[0]) {
+		return fmt.Errorf("workspace %s is not registered", args[0])
+	}
+	if err := reg.Save(); err != nil {
+		return err
+	}
+
+	if !quiet {
+		fmt.Printf("Unregistered workspace %s\n", args[0])
+	}
+	return nil
+}
+
+func runWorkspacesSwitch(cmd *cobra.Command, args []string) error {
+	reg, err := loadRegistry()
+	if err != nil {
+		return err
+	}
+	if err := reg.Switch(args[0]); err != nil {
+		return err
+	}
+	if err := reg.Save(); err != nil {
+		return err
+	}
+
+	if !quiet {
+		w, _ := reg.Get(args[0])
+		fmt.Printf("Switched to workspace %s (%s)\n", w.Name, w.Path)
+	}
+	return nil
+}
+
+// completeWorkspaces completes registered workspace names.
+func completeWorkspaces(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	reg, err := loadRegistry()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names := make([]string, len(reg.Workspaces))
+	for i, w := range reg.Workspaces {
+		names[i] = w.Name + "\t" + w.Path
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeWorkspace completes a single workspace name argument.
+func completeWorkspace(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeWorkspaces(cmd, args, toComplete)
+}
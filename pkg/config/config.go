@@ -4,15 +4,21 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/tierone/harbormaster/pkg/errcode"
 )
 
 const (
 	// ConfigFileName is the name of the configuration file.
 	ConfigFileName = ".harbormaster.toml"
 
+	// StateDirName is the directory, next to the configuration file, that
+	// holds local state such as logs and history.
+	StateDirName = ".harbormaster"
+
 	// DefaultTimeout is the default operation timeout.
 	DefaultTimeout = 10 * time.Minute
 
@@ -22,11 +28,16 @@ const (
 	// DefaultCloneDepth is the default shallow clone depth.
 	DefaultCloneDepth = 1
 
-	// DefaultRetryAttempts is the default number of HTTP retry attempts.
+	// DefaultRetryAttempts is the default number of HTTP and git retry
+	// attempts.
 	DefaultRetryAttempts = 3
 
 	// DefaultRetryDelay is the default delay between retries.
 	DefaultRetryDelay = 2 * time.Second
+
+	// DefaultQuarantineAfter is the default number of consecutive sync
+	// failures after which a repository is quarantined.
+	DefaultQuarantineAfter = 5
 )
 
 // Config represents the parsed and validated configuration.
@@ -34,20 +45,43 @@ type Config struct {
 	General      GeneralConfig
 	HTTP         HTTPConfig
 	Git          GitConfig
+	Upstream     UpstreamConfig
+	Notify       NotifyConfig
+	Overlay      OverlayConfig
+	UI           UIConfig
+	Generate     []GenerateConfig
+	Hosts        []HostConfig
+	Sources      []SourceConfig
+	MirrorMap    map[string]string // Upstream URL prefix to mirror root, expanded at sync time
+	RepoDefaults RepoDefaults
 	Repositories []Repository
 	Projects     []Project
 	configPath   string // Path to the config file
+
+	// Names of entries merged from the upstream config, which are not
+	// saved back to the local file
+	upstreamRepos    map[string]bool
+	upstreamProjects map[string]bool
+
+	// Source of each repository merged from the source listings, which
+	// are not saved to the local file either
+	sourceRepos map[string]string
 }
 
 // GeneralConfig holds general settings.
 type GeneralConfig struct {
-	WorkDir          string // Expanded absolute path for use at runtime
-	WorkDirOriginal  string // Original value from config (for saving back)
-	CacheDir         string
-	CacheDirOriginal string // Original value from config (for saving back)
-	Timeout          time.Duration
-	DefaultBranch    string
-	RecurseSubmodule bool
+	WorkDir           string // Expanded absolute path for use at runtime
+	WorkDirOriginal   string // Original value from config (for saving back)
+	CacheDir          string
+	CacheDirOriginal  string // Original value from config (for saving back)
+	Timeout           time.Duration
+	DefaultBranch     string
+	RecurseSubmodule  bool
+	RecurseWorkspaces bool          // Sync nested workspaces found in repositories
+	Concurrency       int           // Max concurrent operations; 0 uses the default
+	QuarantineAfter   int           // Consecutive sync failures before a repository is skipped; 0 disables
+	LowPriority       bool          // Run git with reduced CPU and I/O priority
+	MinSyncInterval   time.Duration // Repositories synced more recently are skipped; 0 disables
 }
 
 // HTTPConfig holds HTTP-specific settings.
@@ -59,26 +93,42 @@ type HTTPConfig struct {
 
 // GitConfig holds Git-specific settings.
 type GitConfig struct {
-	ShallowClone bool
-	CloneDepth   int
+	ShallowClone  bool
+	CloneDepth    int
+	RetryAttempts int           // Retries of clones and fetches that fail transiently
+	RetryDelay    time.Duration // Delay before the first retry; doubles on each one
 }
 
 // ConfigFile represents the raw TOML structure for file I/O.
 type ConfigFile struct {
-	General      GeneralConfigFile `toml:"general"`
-	HTTP         HTTPConfigFile    `toml:"http"`
-	Git          GitConfigFile     `toml:"git"`
-	Repositories []RepositoryFile  `toml:"repository"`
-	Projects     []ProjectFile     `toml:"project"`
+	General      GeneralConfigFile  `toml:"general"`
+	HTTP         HTTPConfigFile     `toml:"http"`
+	Git          GitConfigFile      `toml:"git"`
+	Upstream     UpstreamConfigFile `toml:"upstream,omitempty"`
+	Notify       NotifyConfigFile   `toml:"notify,omitempty"`
+	Overlay      OverlayConfigFile  `toml:"overlay,omitempty"`
+	UI           UIConfigFile       `toml:"ui,omitempty"`
+	Generate     []GenerateFile     `toml:"generate,omitempty"`
+	Hosts        []HostConfigFile   `toml:"host,omitempty"`
+	Sources      []SourceConfigFile `toml:"source,omitempty"`
+	MirrorMap    map[string]string  `toml:"mirror_map,omitempty"`
+	RepoDefaults RepoDefaultsFile   `toml:"repo_defaults,omitempty"`
+	Repositories []RepositoryFile   `toml:"repository"`
+	Projects     []ProjectFile      `toml:"project"`
 }
 
 // GeneralConfigFile is the raw TOML structure for general settings.
 type GeneralConfigFile struct {
-	WorkDir          string `toml:"work_dir"`
-	CacheDir         string `toml:"cache_dir"`
-	Timeout          string `toml:"timeout"`
-	DefaultBranch    string `toml:"default_branch"`
-	RecurseSubmodule *bool  `toml:"recurse_submodule"`
+	WorkDir           string `toml:"work_dir"`
+	CacheDir          string `toml:"cache_dir"`
+	Timeout           string `toml:"timeout"`
+	DefaultBranch     string `toml:"default_branch"`
+	RecurseSubmodule  *bool  `toml:"recurse_submodule"`
+	RecurseWorkspaces bool   `toml:"recurse_workspaces,omitempty"`
+	Concurrency       int    `toml:"concurrency,omitempty"`
+	QuarantineAfter   *int   `toml:"quarantine_after,omitempty"`
+	LowPriority       bool   `toml:"low_priority,omitempty"`
+	MinSyncInterval   string `toml:"min_sync_interval,omitempty"`
 }
 
 // HTTPConfigFile is the raw TOML structure for HTTP settings.
@@ -90,46 +140,106 @@ type HTTPConfigFile struct {
 
 // GitConfigFile is the raw TOML structure for Git settings.
 type GitConfigFile struct {
-	ShallowClone *bool `toml:"shallow_clone"`
-	CloneDepth   *int  `toml:"clone_depth"`
+	ShallowClone  *bool  `toml:"shallow_clone"`
+	CloneDepth    *int   `toml:"clone_depth"`
+	RetryAttempts *int   `toml:"retry_attempts,omitempty"`
+	RetryDelay    string `toml:"retry_delay,omitempty"`
 }
 
 // Load reads and parses the configuration file.
 func Load(path string) (*Config, error) {
 	var cf ConfigFile
 	if _, err := toml.DecodeFile(path, &cf); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+		return nil, errcode.Wrap(errcode.ConfigInvalid, fmt.Errorf("failed to parse config file: %w", err))
 	}
 
 	cfg, err := parseConfigFile(&cf, path)
 	if err != nil {
-		return nil, err
+		return nil, errcode.Wrap(errcode.ConfigInvalid, err)
 	}
 	cfg.configPath = path
 
+	if cfg.Upstream.Enabled() {
+		if err := cfg.mergeUpstream(); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.mergeSources(); err != nil {
+		return nil, err
+	}
+
 	if err := ValidateConfig(cfg); err != nil {
-		return nil, fmt.Errorf("config validation failed: %w", err)
+		return nil, errcode.Wrap(errcode.ConfigInvalid, fmt.Errorf("config validation failed: %w", err))
 	}
 
 	return cfg, nil
 }
 
-// FindConfigFile searches for the configuration file in the workspace root.
+// UnknownKeys returns the keys of the config file at path that match no
+// setting, such as misspelled ones, in the order they appear. Load
+// ignores them.
+func UnknownKeys(path string) ([]string, error) {
+	var cf ConfigFile
+	md, err := toml.DecodeFile(path, &cf)
+	if err != nil {
+		return nil, errcode.Wrap(errcode.ConfigInvalid, fmt.Errorf("failed to parse config file: %w", err))
+	}
+	// Keys of an unknown table are not listed on their own
+	var keys []string
+	var unknown []toml.Key
+	for _, key := range md.Undecoded() {
+		if !slices.ContainsFunc(unknown, func(table toml.Key) bool {
+			return len(table) < len(key) && slices.Equal(table, key[:len(table)])
+		}) {
+			unknown = append(unknown, key)
+			keys = append(keys, key.String())
+		}
+	}
+	return keys, nil
+}
+
+// CeilingDirsEnv names the environment variable listing directories,
+// separated like PATH, that FindConfigFile does not search in or above,
+// like GIT_CEILING_DIRECTORIES.
+const CeilingDirsEnv = "HARBORMASTER_CEILING_DIRECTORIES"
+
+// FindConfigFile searches for the configuration file in the current
+// directory and then its parents, so that commands work from anywhere in
+// a workspace. The nearest config file wins. The search stops below the
+// directories listed in CeilingDirsEnv, and at the root of the file
+// system.
 func FindConfigFile() (string, error) {
 	cwd, err := os.Getwd()
 	if err != nil {
 		return "", err
 	}
+	return findConfigFile(cwd, filepath.SplitList(os.Getenv(CeilingDirsEnv)))
+}
 
-	configPath := filepath.Join(cwd, ConfigFileName)
-	if _, err := os.Stat(configPath); err != nil {
-		if os.IsNotExist(err) {
-			return "", fmt.Errorf("config file not found: %s", configPath)
+// findConfigFile searches dir and its parents below ceilings for the
+// configuration file.
+func findConfigFile(dir string, ceilings []string) (string, error) {
+	stop := make(map[string]bool, len(ceilings))
+	for _, c := range ceilings {
+		if abs, err := filepath.Abs(c); err == nil && c != "" {
+			stop[abs] = true
 		}
-		return "", err
 	}
 
-	return configPath, nil
+	for start := dir; ; {
+		configPath := filepath.Join(dir, ConfigFileName)
+		if _, err := os.Stat(configPath); err == nil {
+			return configPath, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir || stop[parent] {
+			return "", errcode.Wrap(errcode.ConfigNotFound, fmt.Errorf("config file %s not found in %s or its parents", ConfigFileName, start))
+		}
+		dir = parent
+	}
 }
 
 // Save writes the configuration to the config file.
@@ -168,6 +278,18 @@ func (c *Config) Path() string {
 	return c.configPath
 }
 
+// Dir returns the workspace root: the absolute directory of the config
+// file, or "" if the config was not loaded from a file.
+func (c *Config) Dir() string {
+	if c.configPath == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(c.configPath); err == nil {
+		return filepath.Dir(abs)
+	}
+	return filepath.Dir(c.configPath)
+}
+
 // GetRepository returns a repository by name.
 func (c *Config) GetRepository(name string) (*Repository, bool) {
 	for i := range c.Repositories {
@@ -188,12 +310,23 @@ func (c *Config) GetProject(name string) (*Project, bool) {
 	return nil, false
 }
 
-// AddRepository adds a repository to the configuration.
+// AddRepository adds a repository to the configuration, expanding its URL
+// shorthand and filling in the settings it inherits from the repository
+// defaults.
 func (c *Config) AddRepository(repo Repository) error {
 	if _, exists := c.GetRepository(repo.Name); exists {
 		return fmt.Errorf("repository already exists: %s", repo.Name)
 	}
+	for _, other := range c.Repositories {
+		if filepath.Clean(other.GetEffectivePath()) == filepath.Clean(repo.GetEffectivePath()) {
+			return fmt.Errorf("path %s is already used by repository %s", repo.GetEffectivePath(), other.Name)
+		}
+	}
+	c.expandURL(&repo)
+	c.applyRepoDefaults(&repo)
 	c.Repositories = append(c.Repositories, repo)
+	delete(c.upstreamRepos, repo.Name)
+	delete(c.sourceRepos, repo.Name)
 	return nil
 }
 
@@ -212,7 +345,7 @@ func (c *Config) RemoveRepository(name string) error {
 func (c *Config) GetRepositoriesForProject(projectName string) ([]Repository, error) {
 	project, ok := c.GetProject(projectName)
 	if !ok {
-		return nil, fmt.Errorf("project not found: %s", projectName)
+		return nil, errcode.Wrap(errcode.UnknownProject, fmt.Errorf("project not found: %s", projectName))
 	}
 
 	var repos []Repository
@@ -240,12 +373,74 @@ func (c *Config) GetRepositoriesByTag(tag string) []Repository {
 	return repos
 }
 
+// ProjectOf returns the name of the first project that contains the
+// repository, or "" if it belongs to none.
+func (c *Config) ProjectOf(repoName string) string {
+	for _, proj := range c.Projects {
+		if proj.HasRepository(repoName) {
+			return proj.Name
+		}
+	}
+	return ""
+}
+
+// RequiresSigned reports whether a git repository belongs to a project
+// that requires signed commits.
+func (c *Config) RequiresSigned(repo *Repository) bool {
+	if repo.Type != RepoTypeGit {
+		return false
+	}
+	for _, proj := range c.Projects {
+		if proj.RequireSigned && proj.HasRepository(repo.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsVendored returns true if a git repository should be vendored, either
+// directly or through a project that enables vendoring. A repository-level
+// setting takes precedence over its projects. Repositories with a subdir
+// are always vendored.
+func (c *Config) IsVendored(repo *Repository) bool {
+	if repo.Type != RepoTypeGit {
+		return false
+	}
+	if repo.Subdir != "" {
+		return true
+	}
+	if repo.Vendor != nil {
+		return *repo.Vendor
+	}
+	for _, proj := range c.Projects {
+		if proj.Vendor && proj.HasRepository(repo.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalSource returns the directory a path repository points at. A
+// relative url is resolved against the directory of the config file.
+func (c *Config) LocalSource(repo *Repository) (string, error) {
+	base := c.General.WorkDir
+	if c.configPath != "" {
+		dir, err := filepath.Abs(filepath.Dir(c.configPath))
+		if err != nil {
+			return "", err
+		}
+		base = dir
+	}
+	return resolvePath(repo.URL, base)
+}
+
 // AddProject adds a new project to the configuration.
 func (c *Config) AddProject(proj Project) error {
 	if _, exists := c.GetProject(proj.Name); exists {
 		return fmt.Errorf("project already exists: %s", proj.Name)
 	}
 	c.Projects = append(c.Projects, proj)
+	delete(c.upstreamProjects, proj.Name)
 	return nil
 }
 
@@ -368,6 +563,25 @@ func parseConfigFile(cf *ConfigFile, configPath string) (*Config, error) {
 		cfg.General.RecurseSubmodule = true
 	}
 
+	cfg.General.RecurseWorkspaces = cf.General.RecurseWorkspaces
+	cfg.General.Concurrency = cf.General.Concurrency
+
+	cfg.General.LowPriority = cf.General.LowPriority
+
+	if cf.General.MinSyncInterval != "" {
+		interval, err := time.ParseDuration(cf.General.MinSyncInterval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse min_sync_interval: %w", err)
+		}
+		cfg.General.MinSyncInterval = interval
+	}
+
+	if cf.General.QuarantineAfter != nil {
+		cfg.General.QuarantineAfter = *cf.General.QuarantineAfter
+	} else {
+		cfg.General.QuarantineAfter = DefaultQuarantineAfter
+	}
+
 	// Parse HTTP config
 	if cf.HTTP.UserAgent != "" {
 		cfg.HTTP.UserAgent = cf.HTTP.UserAgent
@@ -404,20 +618,114 @@ func parseConfigFile(cf *ConfigFile, configPath string) (*Config, error) {
 		cfg.Git.CloneDepth = DefaultCloneDepth
 	}
 
+	if cf.Git.RetryAttempts != nil {
+		cfg.Git.RetryAttempts = *cf.Git.RetryAttempts
+	} else {
+		cfg.Git.RetryAttempts = DefaultRetryAttempts
+	}
+
+	if cf.Git.RetryDelay != "" {
+		delay, err := time.ParseDuration(cf.Git.RetryDelay)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse git retry_delay: %w", err)
+		}
+		cfg.Git.RetryDelay = delay
+	} else {
+		cfg.Git.RetryDelay = DefaultRetryDelay
+	}
+
+	cfg.Upstream.URL = cf.Upstream.URL
+
+	// Parse notification config
+	webhook, err := parseWebhook(cf.Notify.Webhook)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse notify.webhook: %w", err)
+	}
+	cfg.Notify.Webhook = webhook
+	cfg.Notify.Slack = parseChat(cf.Notify.Slack)
+	cfg.Notify.Teams = parseChat(cf.Notify.Teams)
+
+	// Parse overlay config
+	cfg.Overlay.TargetOriginal = cf.Overlay.Target
+	cfg.Overlay.Repositories = cf.Overlay.Repositories
+	overlayTarget, err := resolvePath(cf.Overlay.Target, configDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand overlay target: %w", err)
+	}
+	cfg.Overlay.Target = overlayTarget
+
+	// Parse UI config
+	cfg.UI.Theme = cf.UI.Theme
+	cfg.UI.Colors = cf.UI.Colors
+
+	// Parse generate entries
+	for i, gf := range cf.Generate {
+		gen := GenerateConfig{
+			TemplateOriginal: gf.Template,
+			OutputOriginal:   gf.Output,
+		}
+		if gen.Template, err = resolvePath(gf.Template, configDir); err != nil {
+			return nil, fmt.Errorf("failed to expand generate[%d].template: %w", i, err)
+		}
+		if gen.Output, err = resolvePath(gf.Output, configDir); err != nil {
+			return nil, fmt.Errorf("failed to expand generate[%d].output: %w", i, err)
+		}
+		cfg.Generate = append(cfg.Generate, gen)
+	}
+
+	// Parse hosts
+	for _, hf := range cf.Hosts {
+		cfg.Hosts = append(cfg.Hosts, parseHost(hf))
+	}
+	// Parse sources
+	for _, sf := range cf.Sources {
+		src, err := ParseSource(sf)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Sources = append(cfg.Sources, src)
+	}
+	cfg.MirrorMap = cf.MirrorMap
+	cfg.RepoDefaults = parseRepoDefaults(cf.RepoDefaults)
+
 	// Parse repositories
 	for _, rf := range cf.Repositories {
 		repo := Repository{
-			Name:       rf.Name,
-			URL:        rf.URL,
-			Type:       RepositoryType(rf.Type),
-			Path:       rf.Path,
-			Branch:     rf.Branch,
-			Tag:        rf.Tag,
-			Commit:     rf.Commit,
-			Shallow:    rf.Shallow,
-			Depth:      rf.Depth,
-			Submodules: rf.Submodules,
-			Tags:       rf.Tags,
+			Name:              rf.Name,
+			URL:               rf.URL,
+			Type:              RepositoryType(rf.Type),
+			Path:              rf.Path,
+			Subdir:            rf.Subdir,
+			Branch:            rf.Branch,
+			BranchSort:        rf.BranchSort,
+			Tag:               rf.Tag,
+			Commit:            rf.Commit,
+			Version:           rf.Version,
+			Ref:               rf.Ref,
+			Prereleases:       rf.Prereleases,
+			AsOf:              rf.AsOf,
+			Refspecs:          rf.Refspecs,
+			CheckoutMode:      rf.CheckoutMode,
+			BlockedCommits:    rf.BlockedCommits,
+			AllowedCommitters: rf.AllowedCommitters,
+			Shallow:           rf.Shallow,
+			Depth:             rf.Depth,
+			Submodules:        rf.Submodules,
+			SubmoduleDepth:    rf.SubmoduleDepth,
+			IncludeSubmodules: rf.IncludeSubmodules,
+			ExcludeSubmodules: rf.ExcludeSubmodules,
+			Vendor:            rf.Vendor,
+			Symlink:           rf.Symlink,
+			Tags:              rf.Tags,
+			DependsOn:         rf.DependsOn,
+			Priority:          rf.Priority,
+		}
+		if rf.MinSyncInterval != "" {
+			interval, err := time.ParseDuration(rf.MinSyncInterval)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse min_sync_interval of repository %s: %w", rf.Name, err)
+			}
+			repo.MinSyncInterval = &interval
 		}
 		cfg.Repositories = append(cfg.Repositories, repo)
 	}
@@ -427,6 +735,13 @@ func parseConfigFile(cf *ConfigFile, configPath string) (*Config, error) {
 		cfg.Projects = append(cfg.Projects, Project(pf))
 	}
 
+	// Expand URL shorthands and fill in repository defaults once hosts
+	// and projects are known
+	for i := range cfg.Repositories {
+		cfg.expandURL(&cfg.Repositories[i])
+		cfg.applyRepoDefaults(&cfg.Repositories[i])
+	}
+
 	return cfg, nil
 }
 
@@ -439,6 +754,13 @@ func toConfigFile(c *Config) *ConfigFile {
 	cf.General.Timeout = c.General.Timeout.String()
 	cf.General.DefaultBranch = c.General.DefaultBranch
 	cf.General.RecurseSubmodule = &c.General.RecurseSubmodule
+	cf.General.RecurseWorkspaces = c.General.RecurseWorkspaces
+	cf.General.Concurrency = c.General.Concurrency
+	cf.General.QuarantineAfter = &c.General.QuarantineAfter
+	cf.General.LowPriority = c.General.LowPriority
+	if c.General.MinSyncInterval != 0 {
+		cf.General.MinSyncInterval = c.General.MinSyncInterval.String()
+	}
 
 	// HTTP config
 	cf.HTTP.UserAgent = c.HTTP.UserAgent
@@ -448,27 +770,93 @@ func toConfigFile(c *Config) *ConfigFile {
 	// Git config
 	cf.Git.ShallowClone = &c.Git.ShallowClone
 	cf.Git.CloneDepth = &c.Git.CloneDepth
+	cf.Git.RetryAttempts = &c.Git.RetryAttempts
+	cf.Git.RetryDelay = c.Git.RetryDelay.String()
+
+	cf.Upstream.URL = c.Upstream.URL
+	cf.Notify.Webhook = toWebhookFile(c.Notify.Webhook)
+	cf.Notify.Slack = toChatFile(c.Notify.Slack)
+	cf.Notify.Teams = toChatFile(c.Notify.Teams)
 
-	// Repositories
+	// Overlay config
+	cf.Overlay.Target = c.Overlay.TargetOriginal
+	cf.Overlay.Repositories = c.Overlay.Repositories
+
+	// UI config
+	cf.UI.Theme = c.UI.Theme
+	cf.UI.Colors = c.UI.Colors
+
+	// Generate entries
+	for _, gen := range c.Generate {
+		cf.Generate = append(cf.Generate, GenerateFile{
+			Template: gen.TemplateOriginal,
+			Output:   gen.OutputOriginal,
+		})
+	}
+
+	// Hosts
+	for _, h := range c.Hosts {
+		cf.Hosts = append(cf.Hosts, toHostFile(h))
+	}
+	for _, src := range c.Sources {
+		cf.Sources = append(cf.Sources, toSourceFile(src))
+	}
+	cf.MirrorMap = c.MirrorMap
+	cf.RepoDefaults = toRepoDefaultsFile(c.RepoDefaults)
+
+	// Repositories (upstream entries live in the upstream config, and
+	// source entries in the source listings), without the settings they
+	// inherit
 	for _, repo := range c.Repositories {
+		if c.upstreamRepos[repo.Name] || c.sourceRepos[repo.Name] != "" {
+			continue
+		}
+		shorthand := repo.URLOriginal != "" && c.ExpandURL(repo.URLOriginal) == repo.URL
+		repo = c.withoutRepoDefaults(repo)
+		if shorthand {
+			repo.URL = repo.URLOriginal
+		}
 		rf := RepositoryFile{
-			Name:       repo.Name,
-			URL:        repo.URL,
-			Type:       string(repo.Type),
-			Path:       repo.Path,
-			Branch:     repo.Branch,
-			Tag:        repo.Tag,
-			Commit:     repo.Commit,
-			Shallow:    repo.Shallow,
-			Depth:      repo.Depth,
-			Submodules: repo.Submodules,
-			Tags:       repo.Tags,
+			Name:              repo.Name,
+			URL:               repo.URL,
+			Type:              string(repo.Type),
+			Path:              repo.Path,
+			Subdir:            repo.Subdir,
+			Branch:            repo.Branch,
+			BranchSort:        repo.BranchSort,
+			Tag:               repo.Tag,
+			Commit:            repo.Commit,
+			Version:           repo.Version,
+			Ref:               repo.Ref,
+			Prereleases:       repo.Prereleases,
+			AsOf:              repo.AsOf,
+			Refspecs:          repo.Refspecs,
+			CheckoutMode:      repo.CheckoutMode,
+			BlockedCommits:    repo.BlockedCommits,
+			AllowedCommitters: repo.AllowedCommitters,
+			Shallow:           repo.Shallow,
+			Depth:             repo.Depth,
+			Submodules:        repo.Submodules,
+			SubmoduleDepth:    repo.SubmoduleDepth,
+			IncludeSubmodules: repo.IncludeSubmodules,
+			ExcludeSubmodules: repo.ExcludeSubmodules,
+			Vendor:            repo.Vendor,
+			Symlink:           repo.Symlink,
+			Tags:              repo.Tags,
+			DependsOn:         repo.DependsOn,
+			Priority:          repo.Priority,
+		}
+		if repo.MinSyncInterval != nil {
+			rf.MinSyncInterval = repo.MinSyncInterval.String()
 		}
 		cf.Repositories = append(cf.Repositories, rf)
 	}
 
 	// Projects
 	for _, proj := range c.Projects {
+		if c.upstreamProjects[proj.Name] {
+			continue
+		}
 		cf.Projects = append(cf.Projects, ProjectFile(proj))
 	}
 
@@ -485,6 +873,7 @@ func NewDefaultConfig() *Config {
 			Timeout:          DefaultTimeout,
 			DefaultBranch:    DefaultBranch,
 			RecurseSubmodule: true,
+			QuarantineAfter:  DefaultQuarantineAfter,
 		},
 		HTTP: HTTPConfig{
 			UserAgent:     "Harbormaster/1.0",
@@ -492,8 +881,10 @@ func NewDefaultConfig() *Config {
 			RetryDelay:    DefaultRetryDelay,
 		},
 		Git: GitConfig{
-			ShallowClone: true,
-			CloneDepth:   DefaultCloneDepth,
+			ShallowClone:  true,
+			CloneDepth:    DefaultCloneDepth,
+			RetryAttempts: DefaultRetryAttempts,
+			RetryDelay:    DefaultRetryDelay,
 		},
 	}
 }
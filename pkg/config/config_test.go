@@ -3,8 +3,13 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/tierone/harbormaster/pkg/errcode"
 )
 
 func TestLoad_ValidConfig(t *testing.T) {
@@ -98,6 +103,101 @@ type = "git"
 	if cfg.HTTP.RetryAttempts != DefaultRetryAttempts {
 		t.Errorf("expected default RetryAttempts %d, got %d", DefaultRetryAttempts, cfg.HTTP.RetryAttempts)
 	}
+	if cfg.Git.RetryAttempts != DefaultRetryAttempts || cfg.Git.RetryDelay != DefaultRetryDelay {
+		t.Errorf("expected default git retries %d/%v, got %d/%v", DefaultRetryAttempts, DefaultRetryDelay, cfg.Git.RetryAttempts, cfg.Git.RetryDelay)
+	}
+	if cfg.General.Concurrency != 0 {
+		t.Errorf("expected unset Concurrency, got %d", cfg.General.Concurrency)
+	}
+}
+
+func TestFindConfigFile_Parents(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "repos", "app", "src")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(root, ConfigFileName)
+	if err := os.WriteFile(configPath, []byte("[general]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := findConfigFile(nested, nil)
+	if err != nil || got != configPath {
+		t.Errorf("findConfigFile() = %q, %v; want %q", got, err, configPath)
+	}
+
+	// A nearer config file wins
+	inner := filepath.Join(root, "repos", "app", ConfigFileName)
+	if err := os.WriteFile(inner, []byte("[general]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := findConfigFile(nested, nil); got != inner {
+		t.Errorf("findConfigFile() = %q, want nearest %q", got, inner)
+	}
+	if err := os.Remove(inner); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ceilings are not searched, nor is anything above them
+	if _, err := findConfigFile(nested, []string{"", root}); errcode.Of(err) != errcode.ConfigNotFound {
+		t.Errorf("expected config not found below a ceiling, got %v", err)
+	}
+	if got, err := findConfigFile(nested, []string{filepath.Join(root, "other")}); err != nil || got != configPath {
+		t.Errorf("findConfigFile() with unrelated ceiling = %q, %v; want %q", got, err, configPath)
+	}
+}
+
+func TestConfig_Dir(t *testing.T) {
+	if dir := NewDefaultConfig().Dir(); dir != "" {
+		t.Errorf("expected no workspace root without a config file, got %q", dir)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, ConfigFileName)
+	if err := NewDefaultConfig().SaveTo(path); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Dir() != dir {
+		t.Errorf("Dir() = %q, want %q", cfg.Dir(), dir)
+	}
+}
+
+func TestUnknownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	content := `
+[general]
+concurency = 4
+default_branch = "main"
+
+[[repository]]
+name = "app"
+url = "https://github.com/org/app.git"
+brnach = "develop"
+
+[hosts."github.com"]
+api = "https://api.github.com"
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := UnknownKeys(path)
+	if err != nil {
+		t.Fatalf("UnknownKeys failed: %v", err)
+	}
+	want := []string{"general.concurency", "repository.brnach", `hosts."github.com"`}
+	if !slices.Equal(keys, want) {
+		t.Errorf("UnknownKeys() = %v, want %v", keys, want)
+	}
+
+	if keys, err := UnknownKeys("testdata/valid.toml"); err != nil || len(keys) != 0 {
+		t.Errorf("expected no unknown keys in a valid config, got %v, %v", keys, err)
+	}
 }
 
 func TestConfig_GetRepository(t *testing.T) {
@@ -222,6 +322,24 @@ func TestConfig_GetRepositoriesForProject(t *testing.T) {
 	}
 }
 
+func TestConfig_ProjectOf(t *testing.T) {
+	cfg, err := Load("testdata/valid.toml")
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	tests := map[string]string{
+		"repo1":       "core",
+		"config-file": "all",
+		"nonexistent": "",
+	}
+	for repo, want := range tests {
+		if got := cfg.ProjectOf(repo); got != want {
+			t.Errorf("ProjectOf(%q) = %q, want %q", repo, got, want)
+		}
+	}
+}
+
 func TestConfig_GetRepositoriesByTag(t *testing.T) {
 	cfg, err := Load("testdata/valid.toml")
 	if err != nil {
@@ -249,8 +367,9 @@ func TestConfig_SaveAndLoad(t *testing.T) {
 
 	// Create config
 	cfg := NewDefaultConfig()
+	cfg.General.Concurrency = 8
 	cfg.Repositories = []Repository{
-		{Name: "test-repo", URL: "https://github.com/test/repo.git", Type: RepoTypeGit, Branch: "main"},
+		{Name: "test-repo", URL: "https://github.com/test/repo.git", Type: RepoTypeGit, Branch: "main", Priority: 3},
 	}
 	cfg.Projects = []Project{
 		{Name: "test-project", Repositories: []string{"test-repo"}},
@@ -273,6 +392,12 @@ func TestConfig_SaveAndLoad(t *testing.T) {
 	if loaded.Repositories[0].Name != "test-repo" {
 		t.Errorf("expected 'test-repo', got '%s'", loaded.Repositories[0].Name)
 	}
+	if loaded.Repositories[0].Priority != 3 {
+		t.Errorf("expected priority 3, got %d", loaded.Repositories[0].Priority)
+	}
+	if loaded.General.Concurrency != 8 {
+		t.Errorf("expected concurrency 8, got %d", loaded.General.Concurrency)
+	}
 }
 
 func TestNewDefaultConfig(t *testing.T) {
@@ -419,3 +544,649 @@ func TestConfig_RemoveRepoFromProject(t *testing.T) {
 		t.Error("expected error when removing from nonexistent project")
 	}
 }
+
+func TestLoad_Overlay(t *testing.T) {
+	content := `
+[overlay]
+target = "merged"
+repositories = ["test"]
+
+[[repository]]
+name = "test"
+url = "https://github.com/test/test.git"
+type = "git"
+`
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, ".harbormaster.toml")
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+
+	cfg, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	if !cfg.Overlay.Enabled() {
+		t.Fatal("expected overlay to be enabled")
+	}
+	if cfg.Overlay.Target != filepath.Join(tmpDir, "merged") {
+		t.Errorf("expected target resolved against config dir, got '%s'", cfg.Overlay.Target)
+	}
+	if !cfg.Overlay.Includes("test") || cfg.Overlay.Includes("other") {
+		t.Error("unexpected Includes result")
+	}
+
+	// Unknown overlay repositories are rejected
+	bad := `
+[overlay]
+target = "merged"
+repositories = ["missing"]
+`
+	if err := os.WriteFile(tmpFile, []byte(bad), 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	if _, err := Load(tmpFile); err == nil {
+		t.Error("expected validation error for unknown overlay repository")
+	}
+}
+
+func TestLoad_PathRepository(t *testing.T) {
+	content := `
+[[repository]]
+name = "local"
+url = "../components/local"
+type = "path"
+symlink = true
+`
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "ws", ".harbormaster.toml")
+	if err := os.MkdirAll(filepath.Dir(tmpFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+
+	cfg, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	repo := &cfg.Repositories[0]
+	if repo.Type != RepoTypePath || !repo.Symlink {
+		t.Fatalf("unexpected repository: %+v", repo)
+	}
+	source, err := cfg.LocalSource(repo)
+	if err != nil {
+		t.Fatalf("LocalSource failed: %v", err)
+	}
+	if want := filepath.Join(tmpDir, "components", "local"); source != want {
+		t.Errorf("expected source resolved against config dir %s, got %s", want, source)
+	}
+}
+
+func TestLoad_Hosts(t *testing.T) {
+	t.Setenv("HM_TEST_TOKEN", "secret")
+	content := `
+[[host]]
+name = "github.com"
+token = "${HM_TEST_TOKEN}"
+
+[[host]]
+name = "git.example.com"
+provider = "gitlab"
+api_url = "https://git.example.com/api/v4/"
+`
+	tmpFile := filepath.Join(t.TempDir(), ".harbormaster.toml")
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+
+	cfg, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	gh, ok := cfg.Host("GitHub.com")
+	if !ok || gh.Provider != ProviderGitHub || gh.Token != "secret" {
+		t.Errorf("unexpected github.com host: %+v", gh)
+	}
+	gl, ok := cfg.Host("git.example.com")
+	if !ok || gl.Provider != ProviderGitLab || gl.APIURL != "https://git.example.com/api/v4" {
+		t.Errorf("unexpected git.example.com host: %+v", gl)
+	}
+	if h, ok := cfg.Host("gitlab.com"); !ok || h.Provider != ProviderGitLab || h.Token != "" {
+		t.Errorf("expected gitlab.com to be known without a token, got %+v", h)
+	}
+	if _, ok := cfg.Host("unknown.example.com"); ok {
+		t.Error("expected unknown host not to be found")
+	}
+
+	// The token is saved as written, not expanded
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+	data, _ := os.ReadFile(tmpFile)
+	if !strings.Contains(string(data), "${HM_TEST_TOKEN}") || strings.Contains(string(data), "secret") {
+		t.Errorf("expected the unexpanded token to be saved:\n%s", data)
+	}
+}
+
+func TestLoad_RepoDefaults(t *testing.T) {
+	content := `
+[repo_defaults]
+branch = "develop"
+shallow = false
+url_prefix = "https://github.com/acme/"
+
+[repo_defaults.tag.firmware]
+branch = "release"
+submodules = false
+
+[repo_defaults.project.tools]
+url_prefix = "https://gitlab.com/acme/"
+
+[[repository]]
+name = "app"
+url = "app.git"
+type = "git"
+
+[[repository]]
+name = "boot"
+url = "boot.git"
+type = "git"
+tags = ["firmware"]
+
+[[repository]]
+name = "cli"
+url = "cli.git"
+type = "git"
+tag = "v1.0.0"
+shallow = true
+
+[[repository]]
+name = "other"
+url = "https://example.com/other.git"
+type = "git"
+
+[[project]]
+name = "tools"
+repositories = ["cli"]
+`
+	tmpFile := filepath.Join(t.TempDir(), ".harbormaster.toml")
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+
+	cfg, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	tests := []struct {
+		name, url, branch string
+		shallow           bool
+		noSubmodules      bool
+	}{
+		{"app", "https://github.com/acme/app.git", "develop", false, false},
+		{"boot", "https://github.com/acme/boot.git", "release", false, true},
+		{"cli", "https://gitlab.com/acme/cli.git", "", true, false},
+		{"other", "https://example.com/other.git", "develop", false, false},
+	}
+	for _, tt := range tests {
+		repo, _ := cfg.GetRepository(tt.name)
+		if repo.URL != tt.url || repo.Branch != tt.branch || repo.Shallow == nil || *repo.Shallow != tt.shallow {
+			t.Errorf("%s: got url %q, branch %q, shallow %v", tt.name, repo.URL, repo.Branch, repo.Shallow)
+		}
+		if noSubmodules := repo.Submodules != nil && !*repo.Submodules; noSubmodules != tt.noSubmodules {
+			t.Errorf("%s: got submodules %v", tt.name, repo.Submodules)
+		}
+	}
+
+	// Inherited settings are not written to each repository
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+	data, _ := os.ReadFile(tmpFile)
+	if strings.Count(string(data), `"develop"`) != 1 || strings.Count(string(data), "https://github.com/acme/") != 1 {
+		t.Errorf("expected inherited settings to be left out:\n%s", data)
+	}
+	reloaded, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to reload config: %v", err)
+	}
+	for i, repo := range reloaded.Repositories {
+		if !reflect.DeepEqual(repo, cfg.Repositories[i]) {
+			t.Errorf("%s changed on reload: %+v, was %+v", repo.Name, repo, cfg.Repositories[i])
+		}
+	}
+}
+
+func TestLoad_URLShorthands(t *testing.T) {
+	content := `
+[[host]]
+name = "github.com"
+protocol = "ssh"
+
+[[host]]
+name = "git.corp.example.com"
+shorthand = "corp"
+
+[[repository]]
+name = "app"
+url = "github:acme/app"
+type = "git"
+
+[[repository]]
+name = "lib"
+url = "gitlab:acme/lib.git"
+type = "git"
+
+[[repository]]
+name = "tool"
+url = "corp:team/tool"
+type = "git"
+`
+	tmpFile := filepath.Join(t.TempDir(), ".harbormaster.toml")
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+
+	cfg, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	tests := map[string]string{
+		"app":  "git@github.com:acme/app.git",
+		"lib":  "https://gitlab.com/acme/lib.git",
+		"tool": "https://git.corp.example.com/team/tool.git",
+	}
+	for name, want := range tests {
+		if repo, _ := cfg.GetRepository(name); repo.URL != want {
+			t.Errorf("%s: got URL %q, want %q", name, repo.URL, want)
+		}
+	}
+	if got := cfg.ExpandURL("git@github.com:acme/app.git"); got != "git@github.com:acme/app.git" {
+		t.Errorf("expected a full URL to be left alone, got %q", got)
+	}
+
+	// Shorthands are saved as written
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+	data, _ := os.ReadFile(tmpFile)
+	if !strings.Contains(string(data), `"github:acme/app"`) || strings.Contains(string(data), "git@github.com") {
+		t.Errorf("expected the shorthand to be saved:\n%s", data)
+	}
+}
+
+func TestLoad_MirrorMap(t *testing.T) {
+	t.Setenv("HM_TEST_MIRROR", "https://git.internal.example.com")
+	t.Setenv("HM_TEST_UNSET", "")
+	content := `
+[mirror_map]
+"https://github.com/" = "${HM_TEST_MIRROR}/github/"
+"https://github.com/org/" = "${HM_TEST_MIRROR}/org/"
+"git@gitlab.com:" = "${HM_TEST_UNSET}"
+`
+	tmpFile := filepath.Join(t.TempDir(), ".harbormaster.toml")
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+
+	cfg, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	tests := map[string]string{
+		"https://github.com/user/app.git": "https://git.internal.example.com/github/user/app.git",
+		"https://github.com/org/lib.git":  "https://git.internal.example.com/org/lib.git",
+		"git@gitlab.com:team/tool.git":    "git@gitlab.com:team/tool.git",
+		"https://example.com/file.tar.gz": "https://example.com/file.tar.gz",
+	}
+	for url, want := range tests {
+		if got := cfg.MirrorURL(url); got != want {
+			t.Errorf("MirrorURL(%q) = %q, want %q", url, got, want)
+		}
+	}
+
+	want := []string{
+		"url.https://git.internal.example.com/github/.insteadOf=https://github.com/",
+		"url.https://git.internal.example.com/org/.insteadOf=https://github.com/org/",
+	}
+	if got := cfg.MirrorRewrites(); !slices.Equal(got, want) {
+		t.Errorf("MirrorRewrites() = %v, want %v", got, want)
+	}
+
+	// Mirror roots are saved as written, not expanded
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+	data, _ := os.ReadFile(tmpFile)
+	if !strings.Contains(string(data), "${HM_TEST_MIRROR}/github/") {
+		t.Errorf("expected the unexpanded mirror root to be saved:\n%s", data)
+	}
+}
+
+func TestLoad_Upstream(t *testing.T) {
+	local := `
+[upstream]
+url = "https://config.example.com/workspace.toml"
+
+[[repository]]
+name = "shared"
+url = "https://github.com/me/shared-fork.git"
+type = "git"
+
+[[project]]
+name = "mine"
+repositories = ["shared", "tools"]
+`
+	upstream := `
+[general]
+work_dir = "/ignored"
+
+[[repository]]
+name = "shared"
+url = "https://github.com/team/shared.git"
+type = "git"
+
+[[repository]]
+name = "tools"
+url = "https://github.com/team/tools.git"
+type = "git"
+
+[[project]]
+name = "team"
+repositories = ["shared", "tools"]
+`
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, ConfigFileName)
+	if err := os.WriteFile(tmpFile, []byte(local), 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, UpstreamFileName), []byte(upstream), 0644); err != nil {
+		t.Fatalf("failed to write upstream file: %v", err)
+	}
+
+	cfg, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	if len(cfg.Repositories) != 2 || len(cfg.Projects) != 2 {
+		t.Fatalf("expected 2 repositories and 2 projects, got %d and %d", len(cfg.Repositories), len(cfg.Projects))
+	}
+
+	// Local definitions take precedence
+	shared, _ := cfg.GetRepository("shared")
+	if shared.URL != "https://github.com/me/shared-fork.git" {
+		t.Errorf("expected local override, got '%s'", shared.URL)
+	}
+	if cfg.IsUpstreamRepository("shared") || !cfg.IsUpstreamRepository("tools") {
+		t.Error("unexpected upstream repository markers")
+	}
+	if !cfg.IsUpstreamProject("team") {
+		t.Error("expected team project from upstream")
+	}
+
+	// Only upstream settings for repositories and projects are merged
+	if cfg.General.WorkDir != tmpDir {
+		t.Errorf("expected local work_dir, got '%s'", cfg.General.WorkDir)
+	}
+
+	// Upstream entries are not written to the local file
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+	saved, err := os.ReadFile(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to read saved config: %v", err)
+	}
+	if strings.Contains(string(saved), "team/tools") || strings.Contains(string(saved), `"team"`) {
+		t.Errorf("upstream entries leaked into local config:\n%s", saved)
+	}
+	if !strings.Contains(string(saved), "config.example.com") {
+		t.Error("expected upstream url to be saved")
+	}
+}
+
+func TestLoad_UI(t *testing.T) {
+	content := `
+[ui]
+theme = "colorblind-safe"
+
+[ui.colors]
+warning = "#e69f00"
+`
+	tmpFile := filepath.Join(t.TempDir(), ConfigFileName)
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+
+	cfg, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg.UI.Theme != ThemeColorblindSafe || cfg.UI.Colors["warning"] != "#e69f00" {
+		t.Errorf("unexpected ui config: %+v", cfg.UI)
+	}
+
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+	reloaded, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to reload config: %v", err)
+	}
+	if !reflect.DeepEqual(reloaded.UI, cfg.UI) {
+		t.Errorf("ui config not saved back: %+v", reloaded.UI)
+	}
+
+	if err := os.WriteFile(tmpFile, []byte("[ui]\ntheme = \"neon\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(tmpFile); err == nil {
+		t.Error("expected an unknown theme to fail")
+	}
+}
+
+func TestLoad_MinSyncInterval(t *testing.T) {
+	content := `
+[general]
+min_sync_interval = "1h"
+
+[[repository]]
+name = "app"
+url = "https://github.com/acme/app.git"
+type = "git"
+
+[[repository]]
+name = "hot"
+url = "https://github.com/acme/hot.git"
+type = "git"
+min_sync_interval = "0s"
+`
+	tmpFile := filepath.Join(t.TempDir(), ConfigFileName)
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+
+	cfg, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg.General.MinSyncInterval != time.Hour {
+		t.Errorf("expected min_sync_interval 1h, got %v", cfg.General.MinSyncInterval)
+	}
+	app, _ := cfg.GetRepository("app")
+	hot, _ := cfg.GetRepository("hot")
+	if app.GetMinSyncInterval(cfg.General.MinSyncInterval) != time.Hour || hot.GetMinSyncInterval(cfg.General.MinSyncInterval) != 0 {
+		t.Errorf("unexpected intervals: app %v, hot %v", app.MinSyncInterval, hot.MinSyncInterval)
+	}
+
+	// Saved back as set
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+	reloaded, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to reload config: %v", err)
+	}
+	app, _ = reloaded.GetRepository("app")
+	hot, _ = reloaded.GetRepository("hot")
+	if reloaded.General.MinSyncInterval != time.Hour || app.MinSyncInterval != nil || hot.MinSyncInterval == nil || *hot.MinSyncInterval != 0 {
+		t.Errorf("intervals not saved back: general %v, app %v, hot %v", reloaded.General.MinSyncInterval, app.MinSyncInterval, hot.MinSyncInterval)
+	}
+
+	// Invalid durations fail to load
+	if err := os.WriteFile(tmpFile, []byte("[general]\nmin_sync_interval = \"hourly\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(tmpFile); err == nil {
+		t.Error("expected an invalid min_sync_interval to fail")
+	}
+}
+
+func TestLoad_Sources(t *testing.T) {
+	local := `
+[[source]]
+type = "github-org"
+org = "acme"
+exclude = ["*-legacy"]
+path = "acme"
+tags = ["acme"]
+refresh = "12h"
+
+[[repository]]
+name = "api"
+url = "https://github.com/me/api-fork.git"
+type = "git"
+`
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, ConfigFileName)
+	if err := os.WriteFile(tmpFile, []byte(local), 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	listings := map[string]SourceListing{
+		"acme": {Source: "acme", Refreshed: time.Now().UTC(), Repositories: []SourceRepository{
+			{Name: "api", URL: "https://github.com/acme/api.git"},
+			{Name: "web", URL: "https://github.com/acme/web.git"},
+			{Name: "web-legacy", URL: "https://github.com/acme/web-legacy.git"},
+			{Name: "old", URL: "https://github.com/acme/old.git", Archived: true},
+		}},
+	}
+	if err := SaveSourceListings(filepath.Join(tmpDir, SourcesName), listings); err != nil {
+		t.Fatalf("failed to save listings: %v", err)
+	}
+
+	cfg, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	src, ok := cfg.GetSource("acme")
+	if !ok || src.Host != "github.com" || src.Refresh != 12*time.Hour {
+		t.Fatalf("unexpected source %+v", src)
+	}
+	if len(cfg.Repositories) != 2 {
+		t.Fatalf("expected the config's api and the source's web, got %d repositories", len(cfg.Repositories))
+	}
+
+	// Repositories in the config take precedence
+	api, _ := cfg.GetRepository("api")
+	if api.URL != "https://github.com/me/api-fork.git" || cfg.SourceOf("api") != "" {
+		t.Errorf("expected the config's api, got %s", api.URL)
+	}
+	web, _ := cfg.GetRepository("web")
+	if cfg.SourceOf("web") != "acme" || web.Path != "acme/web" || len(web.Tags) != 1 || web.Tags[0] != "acme" {
+		t.Errorf("unexpected source repository %+v", web)
+	}
+
+	// Source repositories are not written to the config
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+	saved, err := os.ReadFile(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to read saved config: %v", err)
+	}
+	if strings.Contains(string(saved), "acme/web") {
+		t.Errorf("source repositories leaked into config:\n%s", saved)
+	}
+	if !strings.Contains(string(saved), `org = "acme"`) || !strings.Contains(string(saved), `refresh = "12h"`) {
+		t.Errorf("expected the source to be saved:\n%s", saved)
+	}
+
+	if err := cfg.RemoveSource("acme"); err != nil {
+		t.Fatalf("RemoveSource failed: %v", err)
+	}
+	if _, ok := cfg.GetRepository("web"); ok || len(cfg.Repositories) != 1 {
+		t.Error("expected the source's repositories to be removed with it")
+	}
+}
+
+func TestLoad_Notify(t *testing.T) {
+	t.Setenv("HM_TEST_WEBHOOK_SECRET", "s3cret")
+
+	content := `
+[notify.webhook]
+urls = ["https://hooks.example.com/hm"]
+secret = "${HM_TEST_WEBHOOK_SECRET}"
+retry_delay = "500ms"
+`
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, ConfigFileName)
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+
+	cfg, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	hook := cfg.Notify.Webhook
+	if !hook.Enabled() {
+		t.Fatal("expected webhook to be enabled")
+	}
+	if hook.Secret != "s3cret" {
+		t.Errorf("expected expanded secret, got '%s'", hook.Secret)
+	}
+	if hook.RetryAttempts != DefaultRetryAttempts {
+		t.Errorf("expected default retry attempts, got %d", hook.RetryAttempts)
+	}
+	if hook.RetryDelay != 500*time.Millisecond {
+		t.Errorf("expected retry delay 500ms, got %v", hook.RetryDelay)
+	}
+	if hook.Timeout != DefaultNotifyTimeout {
+		t.Errorf("expected default timeout, got %v", hook.Timeout)
+	}
+
+	// The unexpanded secret is written back
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+	saved, err := os.ReadFile(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to read saved config: %v", err)
+	}
+	if !strings.Contains(string(saved), "${HM_TEST_WEBHOOK_SECRET}") {
+		t.Errorf("expected original secret to be saved:\n%s", saved)
+	}
+
+	// Non-HTTP URLs are rejected
+	bad := `
+[notify.webhook]
+urls = ["ftp://hooks.example.com/hm"]
+`
+	if err := os.WriteFile(tmpFile, []byte(bad), 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	if _, err := Load(tmpFile); err == nil {
+		t.Error("expected validation error for non-http webhook URL")
+	}
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// RepoDefaults holds settings inherited by repositories that don't set
+// them. Defaults for a project take precedence over those for a tag,
+// which take precedence over the top-level ones.
+type RepoDefaults struct {
+	Branch     string // Branch of git repositories with no branch, tag, commit, version, or ref
+	Shallow    *bool
+	Depth      *int
+	Submodules *bool
+	URLPrefix  string // Prepended to relative repository URLs, such as "app.git"
+
+	Tags     map[string]RepoDefaults // Defaults for repositories with a tag
+	Projects map[string]RepoDefaults // Defaults for repositories in a project
+}
+
+// RepoDefaultsFile is the raw TOML structure for repository defaults.
+type RepoDefaultsFile struct {
+	Branch     string `toml:"branch,omitempty"`
+	Shallow    *bool  `toml:"shallow,omitempty"`
+	Depth      *int   `toml:"depth,omitempty"`
+	Submodules *bool  `toml:"submodules,omitempty"`
+	URLPrefix  string `toml:"url_prefix,omitempty"`
+
+	Tags     map[string]RepoDefaultsFile `toml:"tag,omitempty"`
+	Projects map[string]RepoDefaultsFile `toml:"project,omitempty"`
+}
+
+// DefaultsFor returns the defaults that apply to repo, merged in order of
+// precedence. Of several matching tags, the first one the repository
+// lists wins; of several projects, the first one that lists it.
+func (c *Config) DefaultsFor(repo *Repository) RepoDefaults {
+	var layers []RepoDefaults
+	if project := c.ProjectOf(repo.Name); project != "" {
+		if d, ok := c.RepoDefaults.Projects[project]; ok {
+			layers = append(layers, d)
+		}
+	}
+	for _, tag := range repo.Tags {
+		if d, ok := c.RepoDefaults.Tags[tag]; ok {
+			layers = append(layers, d)
+		}
+	}
+	layers = append(layers, c.RepoDefaults)
+
+	var merged RepoDefaults
+	for _, d := range layers {
+		if merged.Branch == "" {
+			merged.Branch = d.Branch
+		}
+		if merged.Shallow == nil {
+			merged.Shallow = d.Shallow
+		}
+		if merged.Depth == nil {
+			merged.Depth = d.Depth
+		}
+		if merged.Submodules == nil {
+			merged.Submodules = d.Submodules
+		}
+		if merged.URLPrefix == "" {
+			merged.URLPrefix = d.URLPrefix
+		}
+	}
+	return merged
+}
+
+// applyRepoDefaults fills in the settings repo inherits from the
+// repository defaults.
+func (c *Config) applyRepoDefaults(repo *Repository) {
+	d := c.DefaultsFor(repo)
+	if d.URLPrefix != "" && repo.Type != RepoTypePath && isRelativeURL(repo.URL) {
+		repo.URL = d.URLPrefix + repo.URL
+	}
+	if repo.Type != RepoTypeGit {
+		return
+	}
+	if d.Branch != "" && repo.GetEffectiveRef("") == "" {
+		repo.Branch = d.Branch
+	}
+	if repo.Shallow == nil {
+		repo.Shallow = d.Shallow
+	}
+	if repo.Depth == nil {
+		repo.Depth = d.Depth
+	}
+	if repo.Submodules == nil {
+		repo.Submodules = d.Submodules
+	}
+}
+
+// withoutRepoDefaults returns repo with the settings it inherits from the
+// repository defaults removed, as it is written to the config file.
+func (c *Config) withoutRepoDefaults(repo Repository) Repository {
+	d := c.DefaultsFor(&repo)
+	if d.URLPrefix != "" && repo.Type != RepoTypePath && strings.HasPrefix(repo.URL, d.URLPrefix) {
+		if rest := strings.TrimPrefix(repo.URL, d.URLPrefix); isRelativeURL(rest) {
+			repo.URL = rest
+		}
+	}
+	if repo.Type != RepoTypeGit {
+		return repo
+	}
+	if d.Branch != "" && repo.Branch == d.Branch {
+		repo.Branch = ""
+	}
+	if sameBool(repo.Shallow, d.Shallow) {
+		repo.Shallow = nil
+	}
+	if repo.Depth != nil && d.Depth != nil && *repo.Depth == *d.Depth {
+		repo.Depth = nil
+	}
+	if sameBool(repo.Submodules, d.Submodules) {
+		repo.Submodules = nil
+	}
+	return repo
+}
+
+// isRelativeURL returns true if url is neither a URL with a scheme, an
+// scp-style git address, nor an absolute path.
+func isRelativeURL(url string) bool {
+	return url != "" && !strings.Contains(url, ":") && !filepath.IsAbs(url) && !strings.HasPrefix(url, "/")
+}
+
+func sameBool(a, b *bool) bool {
+	return a != nil && b != nil && *a == *b
+}
+
+// parseRepoDefaults converts the raw repository defaults.
+func parseRepoDefaults(df RepoDefaultsFile) RepoDefaults {
+	d := RepoDefaults{
+		Branch:     df.Branch,
+		Shallow:    df.Shallow,
+		Depth:      df.Depth,
+		Submodules: df.Submodules,
+		URLPrefix:  df.URLPrefix,
+	}
+	if len(df.Tags) > 0 {
+		d.Tags = make(map[string]RepoDefaults, len(df.Tags))
+		for tag, tf := range df.Tags {
+			d.Tags[tag] = parseRepoDefaults(tf)
+		}
+	}
+	if len(df.Projects) > 0 {
+		d.Projects = make(map[string]RepoDefaults, len(df.Projects))
+		for project, pf := range df.Projects {
+			d.Projects[project] = parseRepoDefaults(pf)
+		}
+	}
+	return d
+}
+
+// toRepoDefaultsFile converts repository defaults back to their raw form.
+func toRepoDefaultsFile(d RepoDefaults) RepoDefaultsFile {
+	df := RepoDefaultsFile{
+		Branch:     d.Branch,
+		Shallow:    d.Shallow,
+		Depth:      d.Depth,
+		Submodules: d.Submodules,
+		URLPrefix:  d.URLPrefix,
+	}
+	if len(d.Tags) > 0 {
+		df.Tags = make(map[string]RepoDefaultsFile, len(d.Tags))
+		for tag, t := range d.Tags {
+			df.Tags[tag] = toRepoDefaultsFile(t)
+		}
+	}
+	if len(d.Projects) > 0 {
+		df.Projects = make(map[string]RepoDefaultsFile, len(d.Projects))
+		for project, p := range d.Projects {
+			df.Projects[project] = toRepoDefaultsFile(p)
+		}
+	}
+	return df
+}
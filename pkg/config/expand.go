@@ -37,3 +37,74 @@ func ExpandPath(path string) (string, error) {
 func ExpandEnv(s string) string {
 	return os.ExpandEnv(s)
 }
+
+// resolvePath expands a path and resolves it against base if relative.
+func resolvePath(path, base string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	expanded, err := ExpandPath(path)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(expanded) {
+		expanded = filepath.Join(base, expanded)
+	}
+	return filepath.Clean(expanded), nil
+}
+
+// PathsOverlap reports whether two directories are the same or one
+// contains the other, once made absolute and with symlinks resolved as
+// far as the paths exist. A link at b itself is not followed, since it
+// may be the link a path repository keeps to its source.
+func PathsOverlap(a, b string) (bool, error) {
+	ra, err := resolveExisting(a)
+	if err != nil {
+		return false, err
+	}
+	parent, err := resolveExisting(filepath.Dir(b))
+	if err != nil {
+		return false, err
+	}
+	rb := filepath.Join(parent, filepath.Base(b))
+	return within(ra, rb) || within(rb, ra), nil
+}
+
+// PathWithin reports whether path is dir or lies inside it, once both
+// are made absolute and with symlinks resolved as far as they exist.
+func PathWithin(path, dir string) (bool, error) {
+	rp, err := resolveExisting(path)
+	if err != nil {
+		return false, err
+	}
+	rd, err := resolveExisting(dir)
+	if err != nil {
+		return false, err
+	}
+	return within(rp, rd), nil
+}
+
+// resolveExisting makes path absolute and resolves the symlinks in the
+// longest part of it that exists.
+func resolveExisting(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	var rest []string
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...), nil
+		}
+		if dir == filepath.Dir(dir) {
+			return abs, nil
+		}
+		rest = append([]string{filepath.Base(dir)}, rest...)
+	}
+}
+
+// within reports whether path is dir or lies inside it.
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,15 @@
+package config
+
+// GenerateConfig describes a file rendered from a template after sync.
+type GenerateConfig struct {
+	Template         string // Expanded absolute template path
+	TemplateOriginal string // Original value from config (for saving back)
+	Output           string // Expanded absolute output path
+	OutputOriginal   string // Original value from config (for saving back)
+}
+
+// GenerateFile is the raw TOML structure for a generate entry.
+type GenerateFile struct {
+	Template string `toml:"template"`
+	Output   string `toml:"output"`
+}
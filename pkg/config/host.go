@@ -0,0 +1,195 @@
+package config
+
+import "strings"
+
+// Provider kinds of a host.
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+	ProviderGerrit = "gerrit"
+	ProviderGitea  = "gitea"
+	// ProviderForgejo is Forgejo, a fork of Gitea with the same API.
+	ProviderForgejo = "forgejo"
+)
+
+// providers lists the valid provider kinds.
+var providers = []string{ProviderGitHub, ProviderGitLab, ProviderGitea, ProviderForgejo, ProviderGerrit}
+
+// Protocols that repository URL shorthands expand to.
+const (
+	ProtocolHTTPS = "https" // https://host/path.git (default)
+	ProtocolSSH   = "ssh"   // git@host:path.git
+)
+
+// HostConfig holds settings for a git hosting service, shared by every
+// repository on that host.
+type HostConfig struct {
+	Name          string // Host name as it appears in repository URLs
+	Provider      string // "github", "gitlab", "gitea", "forgejo", or "gerrit"
+	APIURL        string // Base URL of the API; empty uses the provider's default
+	Token         string // Expanded API token; "user:password" on Gerrit
+	TokenOriginal string // Original value from config (for saving back)
+
+	// SSHFingerprints pins the SSH host keys accepted for the host, as
+	// SHA256 fingerprints ("SHA256:..."). Empty leaves host key checking
+	// to the user's SSH configuration.
+	SSHFingerprints []string
+
+	// Shorthand names the host in repository URLs such as
+	// "corp:team/app"; github.com and gitlab.com are also known as
+	// "github" and "gitlab". Protocol is what such URLs expand to.
+	Shorthand string
+	Protocol  string // ProtocolHTTPS (default) or ProtocolSSH
+}
+
+// HostConfigFile is the raw TOML structure for a host.
+type HostConfigFile struct {
+	Name     string `toml:"name"`
+	Provider string `toml:"provider,omitempty"`
+	APIURL   string `toml:"api_url,omitempty"`
+	Token    string `toml:"token,omitempty"`
+
+	SSHFingerprints []string `toml:"ssh_fingerprints,omitempty"`
+
+	Shorthand string `toml:"shorthand,omitempty"`
+	Protocol  string `toml:"protocol,omitempty"`
+}
+
+// Host returns the settings of the named host. Hosts that are not
+// configured but are known, such as github.com and gitlab.com, are
+// returned with defaults and no token.
+func (c *Config) Host(name string) (HostConfig, bool) {
+	name = strings.ToLower(name)
+	for _, h := range c.Hosts {
+		if strings.EqualFold(h.Name, name) {
+			return h, true
+		}
+	}
+	if provider := defaultProvider(name); provider != "" {
+		return HostConfig{Name: name, Provider: provider}, true
+	}
+	return HostConfig{}, false
+}
+
+// ExpandURL expands a repository URL shorthand such as "github:org/repo"
+// to the URL of the repository on the host with that shorthand, over the
+// host's protocol. Other URLs are returned unchanged.
+func (c *Config) ExpandURL(url string) string {
+	shorthand, path, ok := strings.Cut(url, ":")
+	if !ok || path == "" || strings.HasPrefix(path, "/") {
+		return url
+	}
+	h, ok := c.shorthandHost(shorthand)
+	if !ok {
+		return url
+	}
+	if !strings.HasSuffix(path, ".git") {
+		path += ".git"
+	}
+	if h.Protocol == ProtocolSSH {
+		return "git@" + h.Name + ":" + path
+	}
+	return "https://" + h.Name + "/" + path
+}
+
+// expandURL expands the URL shorthand of a git repository, keeping the
+// shorthand for saving back.
+func (c *Config) expandURL(repo *Repository) {
+	if repo.Type != RepoTypeGit {
+		return
+	}
+	if url := c.ExpandURL(repo.URL); url != repo.URL {
+		repo.URLOriginal = repo.URL
+		repo.URL = url
+	}
+}
+
+// shorthandHost returns the host a URL shorthand names.
+func (c *Config) shorthandHost(shorthand string) (HostConfig, bool) {
+	for _, h := range c.Hosts {
+		if h.Shorthand == shorthand {
+			return h, true
+		}
+	}
+	switch shorthand {
+	case ProviderGitHub:
+		return c.Host("github.com")
+	case ProviderGitLab:
+		return c.Host("gitlab.com")
+	default:
+		return HostConfig{}, false
+	}
+}
+
+// defaultProvider infers the provider of well-known hosts.
+func defaultProvider(name string) string {
+	switch {
+	case name == "github.com" || strings.HasPrefix(name, "github."):
+		return ProviderGitHub
+	case name == "gitlab.com" || strings.HasPrefix(name, "gitlab."):
+		return ProviderGitLab
+	case name == "codeberg.org":
+		return ProviderForgejo
+	default:
+		return ""
+	}
+}
+
+// parseHost converts the raw host settings, inferring the provider of
+// well-known hosts.
+func parseHost(hf HostConfigFile) HostConfig {
+	h := HostConfig{
+		Name:          hf.Name,
+		Provider:      hf.Provider,
+		APIURL:        strings.TrimSuffix(hf.APIURL, "/"),
+		Token:         ExpandEnv(hf.Token),
+		TokenOriginal: hf.Token,
+
+		SSHFingerprints: hf.SSHFingerprints,
+
+		Shorthand: hf.Shorthand,
+		Protocol:  hf.Protocol,
+	}
+	if h.Provider == "" {
+		h.Provider = defaultProvider(strings.ToLower(h.Name))
+	}
+	return h
+}
+
+// toHostFile converts host settings back to their raw form.
+func toHostFile(h HostConfig) HostConfigFile {
+	return HostConfigFile{
+		Name:     h.Name,
+		Provider: h.Provider,
+		APIURL:   h.APIURL,
+		Token:    h.TokenOriginal,
+
+		SSHFingerprints: h.SSHFingerprints,
+
+		Shorthand: h.Shorthand,
+		Protocol:  h.Protocol,
+	}
+}
+
+// IsSCPLikeURL reports whether url uses git's scp-like syntax for SSH,
+// user@host:path, as in git@github.com:org/app.git or Azure DevOps'
+// org@vs-ssh.visualstudio.com:v3/org/project/app.
+func IsSCPLikeURL(url string) bool {
+	if strings.Contains(url, "://") {
+		return false
+	}
+	user, rest, ok := strings.Cut(url, "@")
+	if !ok || user == "" || strings.ContainsAny(user, "/:") {
+		return false
+	}
+	host, path, ok := strings.Cut(rest, ":")
+	return ok && host != "" && path != "" && !strings.Contains(host, "/")
+}
+
+// BasicAuthHost reports whether a host takes tokens only as the password
+// of HTTP Basic authentication, not as bearer tokens: Bitbucket Cloud's
+// app passwords and Azure DevOps' personal access tokens.
+func BasicAuthHost(name string) bool {
+	name = strings.ToLower(name)
+	return name == "bitbucket.org" || name == "dev.azure.com" || strings.HasSuffix(name, ".visualstudio.com")
+}
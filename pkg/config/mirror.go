@@ -0,0 +1,42 @@
+package config
+
+import (
+	"sort"
+	"strings"
+)
+
+// MirrorURL returns url with its longest prefix in mirror_map replaced by
+// the mirror root it maps to, or url unchanged if no prefix matches.
+// Mirror roots may refer to environment variables; one that expands to
+// nothing is ignored, so the same config works where no mirror is set.
+func (c *Config) MirrorURL(url string) string {
+	best := ""
+	for prefix, root := range c.MirrorMap {
+		if strings.HasPrefix(url, prefix) && len(prefix) > len(best) && ExpandEnv(root) != "" {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return url
+	}
+	return ExpandEnv(c.MirrorMap[best]) + strings.TrimPrefix(url, best)
+}
+
+// MirrorRewrites returns the git url.<root>.insteadOf settings that make
+// git fetch from the mirror roots in mirror_map, in order of prefix. Git
+// picks the longest matching prefix, like MirrorURL.
+func (c *Config) MirrorRewrites() []string {
+	prefixes := make([]string, 0, len(c.MirrorMap))
+	for prefix := range c.MirrorMap {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	var settings []string
+	for _, prefix := range prefixes {
+		if root := ExpandEnv(c.MirrorMap[prefix]); root != "" {
+			settings = append(settings, "url."+root+".insteadOf="+prefix)
+		}
+	}
+	return settings
+}
@@ -0,0 +1,130 @@
+package config
+
+import "time"
+
+const (
+	// DefaultNotifyTimeout is the default timeout for a notification request.
+	DefaultNotifyTimeout = 10 * time.Second
+)
+
+// NotifyConfig holds settings for post-operation notifications.
+type NotifyConfig struct {
+	Webhook WebhookConfig
+	Slack   ChatConfig
+	Teams   ChatConfig
+}
+
+// WebhookConfig holds settings for JSON webhook notifications.
+type WebhookConfig struct {
+	URLs           []string
+	Secret         string // Expanded HMAC signing secret
+	SecretOriginal string // Original value from config (for saving back)
+	RetryAttempts  int
+	RetryDelay     time.Duration
+	Timeout        time.Duration
+}
+
+// NotifyConfigFile is the raw TOML structure for notification settings.
+type NotifyConfigFile struct {
+	Webhook WebhookConfigFile `toml:"webhook,omitempty"`
+	Slack   ChatConfigFile    `toml:"slack,omitempty"`
+	Teams   ChatConfigFile    `toml:"teams,omitempty"`
+}
+
+// WebhookConfigFile is the raw TOML structure for webhook settings.
+type WebhookConfigFile struct {
+	URLs          []string `toml:"urls,omitempty"`
+	Secret        string   `toml:"secret,omitempty"`
+	RetryAttempts *int     `toml:"retry_attempts,omitempty"`
+	RetryDelay    string   `toml:"retry_delay,omitempty"`
+	Timeout       string   `toml:"timeout,omitempty"`
+}
+
+// ChatConfig holds settings for a Slack or Microsoft Teams incoming webhook.
+type ChatConfig struct {
+	URL         string // Expanded webhook URL
+	URLOriginal string // Original value from config (for saving back)
+	On          string // "always", "failure", or "change" (default)
+	Template    string // text/template message; empty uses the default
+}
+
+// ChatConfigFile is the raw TOML structure for chat notifier settings.
+type ChatConfigFile struct {
+	URL      string `toml:"url,omitempty"`
+	On       string `toml:"on,omitempty"`
+	Template string `toml:"template,omitempty"`
+}
+
+// Enabled returns true if a webhook URL is configured.
+func (c *ChatConfig) Enabled() bool {
+	return c.URL != ""
+}
+
+// Enabled returns true if any webhook URL is configured.
+func (w *WebhookConfig) Enabled() bool {
+	return len(w.URLs) > 0
+}
+
+// parseWebhook converts the raw webhook settings, applying defaults.
+func parseWebhook(wf WebhookConfigFile) (WebhookConfig, error) {
+	w := WebhookConfig{
+		URLs:           wf.URLs,
+		Secret:         ExpandEnv(wf.Secret),
+		SecretOriginal: wf.Secret,
+		RetryAttempts:  DefaultRetryAttempts,
+		RetryDelay:     DefaultRetryDelay,
+		Timeout:        DefaultNotifyTimeout,
+	}
+
+	if wf.RetryAttempts != nil {
+		w.RetryAttempts = *wf.RetryAttempts
+	}
+
+	var err error
+	if wf.RetryDelay != "" {
+		if w.RetryDelay, err = time.ParseDuration(wf.RetryDelay); err != nil {
+			return w, err
+		}
+	}
+	if wf.Timeout != "" {
+		if w.Timeout, err = time.ParseDuration(wf.Timeout); err != nil {
+			return w, err
+		}
+	}
+
+	return w, nil
+}
+
+// toWebhookFile converts webhook settings back to their raw form. Nothing
+// is written when no URLs are configured.
+func toWebhookFile(w WebhookConfig) WebhookConfigFile {
+	if !w.Enabled() {
+		return WebhookConfigFile{}
+	}
+	return WebhookConfigFile{
+		URLs:          w.URLs,
+		Secret:        w.SecretOriginal,
+		RetryAttempts: &w.RetryAttempts,
+		RetryDelay:    w.RetryDelay.String(),
+		Timeout:       w.Timeout.String(),
+	}
+}
+
+// parseChat converts the raw chat notifier settings.
+func parseChat(cf ChatConfigFile) ChatConfig {
+	return ChatConfig{
+		URL:         ExpandEnv(cf.URL),
+		URLOriginal: cf.URL,
+		On:          cf.On,
+		Template:    cf.Template,
+	}
+}
+
+// toChatFile converts chat notifier settings back to their raw form.
+func toChatFile(c ChatConfig) ChatConfigFile {
+	return ChatConfigFile{
+		URL:      c.URLOriginal,
+		On:       c.On,
+		Template: c.Template,
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+// OverlayConfig holds settings for the symlink overlay workspace.
+type OverlayConfig struct {
+	Target         string   // Expanded absolute path for use at runtime
+	TargetOriginal string   // Original value from config (for saving back)
+	Repositories   []string // Repositories to include (empty means all)
+}
+
+// OverlayConfigFile is the raw TOML structure for overlay settings.
+type OverlayConfigFile struct {
+	Target       string   `toml:"target,omitempty"`
+	Repositories []string `toml:"repositories,omitempty"`
+}
+
+// Enabled returns true if an overlay target is configured.
+func (o *OverlayConfig) Enabled() bool {
+	return o.Target != ""
+}
+
+// Includes returns true if the named repository contributes to the overlay.
+func (o *OverlayConfig) Includes(name string) bool {
+	if len(o.Repositories) == 0 {
+		return true
+	}
+	for _, r := range o.Repositories {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
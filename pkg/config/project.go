@@ -2,16 +2,20 @@ package config
 
 // Project represents a collection of repositories (a "fleet").
 type Project struct {
-	Name         string
-	Repositories []string // Repository names
-	Tags         []string // User-defined tags
+	Name          string
+	Repositories  []string // Repository names
+	Tags          []string // User-defined tags
+	Vendor        bool     // Vendor all repositories in the project
+	RequireSigned bool     // Require signed commits in all git repositories of the project
 }
 
 // ProjectFile is the raw TOML structure for a project.
 type ProjectFile struct {
-	Name         string   `toml:"name"`
-	Repositories []string `toml:"repositories"`
-	Tags         []string `toml:"tags,omitempty"`
+	Name          string   `toml:"name"`
+	Repositories  []string `toml:"repositories"`
+	Tags          []string `toml:"tags,omitempty"`
+	Vendor        bool     `toml:"vendor,omitempty"`
+	RequireSigned bool     `toml:"require_signed,omitempty"`
 }
 
 // HasRepository returns true if the project contains the named repository.
@@ -1,58 +1,186 @@
 package config
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 // RepositoryType defines the type of repository.
 type RepositoryType string
 
 const (
 	RepoTypeGit  RepositoryType = "git"
 	RepoTypeHTTP RepositoryType = "http"
+	RepoTypePath RepositoryType = "path" // Existing local directory
+)
+
+// RefLatestRelease is the ref that resolves to the tag of the latest
+// release on the repository's hosting service.
+const RefLatestRelease = "latest-release"
+
+// Orders in which branches matching a branch pattern are compared to pick
+// the newest.
+const (
+	BranchSortVersion = "version" // By the version numbers in their names
+	BranchSortDate    = "date"    // By the committer date of their tips
+)
+
+// How a sync checks out a branch.
+const (
+	CheckoutDetached = "detached" // Detach HEAD at the remote-tracking branch (default)
+	CheckoutBranch   = "branch"   // Create or fast-forward a local branch tracking it
 )
 
 // Repository represents a single repository definition.
 type Repository struct {
-	Name       string
-	URL        string
-	Type       RepositoryType
-	Path       string   // Local path relative to work_dir
-	Branch     string   // Git branch (optional)
-	Tag        string   // Git tag (optional)
-	Commit     string   // Git commit SHA (optional)
-	Shallow    *bool    // Override global shallow clone setting
-	Depth      *int     // Override global clone depth
-	Submodules *bool    // Override global submodule setting
-	Tags       []string // User-defined tags for filtering
+	Name              string
+	URL               string // Expanded URL for use at runtime
+	URLOriginal       string // Shorthand the URL was expanded from, if any (for saving back)
+	Type              RepositoryType
+	Path              string         // Local path relative to work_dir
+	Subdir            string         // Directory of the repository to materialize at Path, alone (optional)
+	Branch            string         // Git branch or glob pattern (optional)
+	BranchSort        string         // BranchSortVersion (default) or BranchSortDate, for a branch pattern
+	Tag               string         // Git tag (optional)
+	Commit            string         // Git commit SHA (optional)
+	Version           string         // Semver constraint on the remote's tags (optional)
+	Ref               string         // RefLatestRelease or a full ref such as refs/pull/123/head (optional)
+	Prereleases       bool           // Let Ref resolve to a prerelease
+	AsOf              string         // Check out the branch as it was at this date or time (optional)
+	Refspecs          []string       // Extra refspecs fetched on every sync, such as refs/notes/*:refs/notes/*
+	CheckoutMode      string         // CheckoutDetached (default) or CheckoutBranch
+	BlockedCommits    []string       // Commits that may not be checked out or be in its history
+	AllowedCommitters []string       // Committer emails or glob patterns allowed on new commits
+	Shallow           *bool          // Override global shallow clone setting
+	Depth             *int           // Override global clone depth
+	Submodules        *bool          // Override global submodule setting
+	SubmoduleDepth    int            // Levels of nested submodules to check out; 0 is unlimited
+	IncludeSubmodules []string       // Submodule paths or glob patterns to check out; empty is all
+	ExcludeSubmodules []string       // Submodule paths or glob patterns never to check out
+	Vendor            *bool          // Strip VCS metadata after checkout
+	Symlink           bool           // Link a path repository instead of copying it
+	Tags              []string       // User-defined tags for filtering
+	DependsOn         []string       // Names of repositories this one depends on
+	Priority          int            // Higher priorities are synced first; default 0
+	MinSyncInterval   *time.Duration // Override general.min_sync_interval
 }
 
 // RepositoryFile is the raw TOML structure for a repository.
 type RepositoryFile struct {
-	Name       string   `toml:"name"`
-	URL        string   `toml:"url"`
-	Type       string   `toml:"type"`
-	Path       string   `toml:"path,omitempty"`
-	Branch     string   `toml:"branch,omitempty"`
-	Tag        string   `toml:"tag,omitempty"`
-	Commit     string   `toml:"commit,omitempty"`
-	Shallow    *bool    `toml:"shallow,omitempty"`
-	Depth      *int     `toml:"depth,omitempty"`
-	Submodules *bool    `toml:"submodules,omitempty"`
-	Tags       []string `toml:"tags,omitempty"`
+	Name              string   `toml:"name"`
+	URL               string   `toml:"url"`
+	Type              string   `toml:"type"`
+	Path              string   `toml:"path,omitempty"`
+	Subdir            string   `toml:"subdir,omitempty"`
+	Branch            string   `toml:"branch,omitempty"`
+	BranchSort        string   `toml:"branch_sort,omitempty"`
+	Tag               string   `toml:"tag,omitempty"`
+	Commit            string   `toml:"commit,omitempty"`
+	Version           string   `toml:"version,omitempty"`
+	Ref               string   `toml:"ref,omitempty"`
+	Prereleases       bool     `toml:"prereleases,omitempty"`
+	AsOf              string   `toml:"as_of,omitempty"`
+	Refspecs          []string `toml:"refspecs,omitempty"`
+	CheckoutMode      string   `toml:"checkout_mode,omitempty"`
+	BlockedCommits    []string `toml:"blocked_commits,omitempty"`
+	AllowedCommitters []string `toml:"allowed_committers,omitempty"`
+	Shallow           *bool    `toml:"shallow,omitempty"`
+	Depth             *int     `toml:"depth,omitempty"`
+	Submodules        *bool    `toml:"submodules,omitempty"`
+	SubmoduleDepth    int      `toml:"submodule_depth,omitempty"`
+	IncludeSubmodules []string `toml:"include_submodules,omitempty"`
+	ExcludeSubmodules []string `toml:"exclude_submodules,omitempty"`
+	Vendor            *bool    `toml:"vendor,omitempty"`
+	Symlink           bool     `toml:"symlink,omitempty"`
+	Tags              []string `toml:"tags,omitempty"`
+	DependsOn         []string `toml:"depends_on,omitempty"`
+	Priority          int      `toml:"priority,omitempty"`
+	MinSyncInterval   string   `toml:"min_sync_interval,omitempty"`
 }
 
-// GetEffectiveRef returns the reference (branch, tag, or commit) to checkout.
-// Priority: commit > tag > branch > default.
+// GetEffectiveRef returns the reference (branch, tag, version constraint,
+// symbolic ref, or commit) to checkout. Priority: commit > tag > version >
+// ref > branch > default. Path repositories have no reference.
 func (r *Repository) GetEffectiveRef(defaultBranch string) string {
+	if r.Type == RepoTypePath {
+		return ""
+	}
 	if r.Commit != "" {
 		return r.Commit
 	}
 	if r.Tag != "" {
 		return r.Tag
 	}
+	if r.Version != "" {
+		return r.Version
+	}
+	if r.Ref != "" {
+		return r.Ref
+	}
 	if r.Branch != "" {
 		return r.Branch
 	}
 	return defaultBranch
 }
 
+// ResolvesTag reports whether the tag to check out is resolved at sync
+// time, from a version constraint or the latest release.
+func (r *Repository) ResolvesTag() bool {
+	return r.Version != "" || r.Ref == RefLatestRelease
+}
+
+// BranchPattern reports whether Branch is a glob pattern, such as
+// "release/2024.*", that resolves to the newest matching remote branch.
+func (r *Repository) BranchPattern() bool {
+	return strings.ContainsAny(r.Branch, "*?[")
+}
+
+// ResolvesRef reports whether the ref to check out is resolved at sync
+// time: a resolved tag or a branch pattern.
+func (r *Repository) ResolvesRef() bool {
+	return r.ResolvesTag() || r.BranchPattern()
+}
+
+// asOfLayouts are the accepted formats of as_of. A date without a time
+// means midnight UTC.
+var asOfLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// AsOfTime returns the time in as_of, or the zero time if it is unset.
+func (r *Repository) AsOfTime() (time.Time, error) {
+	if r.AsOf == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range asOfLayouts {
+		if t, err := time.Parse(layout, r.AsOf); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (expected YYYY-MM-DD or RFC 3339)", r.AsOf)
+}
+
+// CustomRef reports whether Ref is a full ref name, such as a pull
+// request (refs/pull/123/head) or a Gerrit change (refs/changes/...),
+// that is fetched explicitly since clones and fetches don't include it.
+func (r *Repository) CustomRef() bool {
+	return strings.HasPrefix(r.Ref, "refs/")
+}
+
+// TracksBranch reports whether a sync follows the tip of a branch, named
+// or matched by a pattern, rather than a tag, commit, date, or custom ref.
+func (r *Repository) TracksBranch() bool {
+	return r.Type == RepoTypeGit && r.Commit == "" && r.Tag == "" && r.AsOf == "" && !r.ResolvesTag() && !r.CustomRef()
+}
+
+// GetMinSyncInterval returns how recently the repository may have been
+// synced for a sync to skip it; 0 never skips it.
+func (r *Repository) GetMinSyncInterval(defaultInterval time.Duration) time.Duration {
+	if r.MinSyncInterval != nil {
+		return *r.MinSyncInterval
+	}
+	return defaultInterval
+}
+
 // GetEffectivePath returns the local path for the repository.
 // Defaults to the repository name if not specified.
 func (r *Repository) GetEffectivePath() string {
@@ -62,8 +190,19 @@ func (r *Repository) GetEffectivePath() string {
 	return r.Name
 }
 
+// LocalBranch reports whether a sync checks out a local branch tracking
+// the remote one, rather than detaching HEAD.
+func (r *Repository) LocalBranch() bool {
+	return r.CheckoutMode == CheckoutBranch
+}
+
 // IsShallow returns whether to use shallow clone for this repository.
+// A repository with as_of is never shallow: the commit is found in its
+// history.
 func (r *Repository) IsShallow(defaultShallow bool) bool {
+	if r.AsOf != "" {
+		return false
+	}
 	if r.Shallow != nil {
 		return *r.Shallow
 	}
@@ -1,6 +1,9 @@
 package config
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestRepositoryType_Constants(t *testing.T) {
 	if RepoTypeGit != "git" {
@@ -30,6 +33,12 @@ func TestRepository_GetEffectiveRef(t *testing.T) {
 			defaultBranch: "develop",
 			expected:      "v1.0",
 		},
+		{
+			name:          "version over branch",
+			repo:          Repository{Branch: "main", Version: "~>1.4"},
+			defaultBranch: "develop",
+			expected:      "~>1.4",
+		},
 		{
 			name:          "branch specified",
 			repo:          Repository{Branch: "feature"},
@@ -54,6 +63,33 @@ func TestRepository_GetEffectiveRef(t *testing.T) {
 	}
 }
 
+func TestRepository_TracksBranch(t *testing.T) {
+	tests := []struct {
+		name     string
+		repo     Repository
+		expected bool
+	}{
+		{"default branch", Repository{Type: RepoTypeGit}, true},
+		{"branch", Repository{Type: RepoTypeGit, Branch: "main"}, true},
+		{"branch pattern", Repository{Type: RepoTypeGit, Branch: "release/*"}, true},
+		{"tag", Repository{Type: RepoTypeGit, Tag: "v1.0.0"}, false},
+		{"commit", Repository{Type: RepoTypeGit, Commit: "abc123"}, false},
+		{"version", Repository{Type: RepoTypeGit, Version: "^1.2"}, false},
+		{"latest release", Repository{Type: RepoTypeGit, Ref: RefLatestRelease}, false},
+		{"custom ref", Repository{Type: RepoTypeGit, Ref: "refs/pull/1/head"}, false},
+		{"as of", Repository{Type: RepoTypeGit, Branch: "main", AsOf: "2024-01-01"}, false},
+		{"http", Repository{Type: RepoTypeHTTP}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.repo.TracksBranch(); result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestRepository_GetEffectivePath(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -116,6 +152,12 @@ func TestRepository_IsShallow(t *testing.T) {
 			defaultShallow: false,
 			expected:       false,
 		},
+		{
+			name:           "as_of needs history",
+			repo:           Repository{Shallow: &trueBool, AsOf: "2024-06-01"},
+			defaultShallow: true,
+			expected:       false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -128,6 +170,27 @@ func TestRepository_IsShallow(t *testing.T) {
 	}
 }
 
+func TestRepository_AsOfTime(t *testing.T) {
+	tests := []struct {
+		asOf string
+		want time.Time
+	}{
+		{"", time.Time{}},
+		{"2024-06-01", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"2024-06-01T12:30:00+02:00", time.Date(2024, 6, 1, 10, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := (&Repository{AsOf: tt.asOf}).AsOfTime()
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("AsOfTime(%q) = %v, %v, want %v", tt.asOf, got, err, tt.want)
+		}
+	}
+
+	if _, err := (&Repository{AsOf: "June 2024"}).AsOfTime(); err == nil {
+		t.Error("expected error for an unknown format")
+	}
+}
+
 func TestRepository_GetDepth(t *testing.T) {
 	depth5 := 5
 
@@ -0,0 +1,339 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Source types.
+const (
+	SourceGitHubOrg   = "github-org"   // Repositories of a GitHub organization
+	SourceGitLabGroup = "gitlab-group" // Projects of a GitLab group and its subgroups
+	SourceGiteaOrg    = "gitea-org"    // Repositories of a Gitea or Forgejo organization
+)
+
+// SourceTypes lists the valid source types.
+var SourceTypes = []string{SourceGitHubOrg, SourceGitLabGroup, SourceGiteaOrg}
+
+// DefaultSourceRefresh is how old the listing of a source may get before
+// a sync refreshes it, if the source doesn't say.
+const DefaultSourceRefresh = 24 * time.Hour
+
+// SourcesName is the cached listing of the sources, relative to the
+// workspace root.
+var SourcesName = filepath.Join(StateDirName, "sources.toml")
+
+// SourceConfig populates repositories from a listing on a hosting
+// service, such as every repository in a GitHub organization or GitLab
+// group, so that the config follows the organization as it grows.
+type SourceConfig struct {
+	Name     string   // Names the source; defaults to the last element of Org
+	Type     string   // SourceGitHubOrg, SourceGitLabGroup, or SourceGiteaOrg
+	Host     string   // Host of the organization; defaults to github.com or gitlab.com
+	Org      string   // Organization or group path whose repositories are listed
+	Include  []string // Glob patterns of repository names to include; none means all
+	Exclude  []string // Glob patterns of repository names to leave out
+	Archived bool     // Also include archived repositories
+	Path     string   // Directory the repositories are cloned into, relative to the work directory
+	Tags     []string // Tags given to the repositories
+
+	// Refresh is how old the listing may get before a sync refreshes it;
+	// 0 uses DefaultSourceRefresh and a negative value never refreshes it.
+	Refresh         time.Duration
+	RefreshOriginal string // Original value from config (for saving back)
+}
+
+// SourceConfigFile is the raw TOML structure for a source.
+type SourceConfigFile struct {
+	Name     string   `toml:"name,omitempty"`
+	Type     string   `toml:"type"`
+	Host     string   `toml:"host,omitempty"`
+	Org      string   `toml:"org,omitempty"`
+	Group    string   `toml:"group,omitempty"` // Org of a GitLab group
+	Include  []string `toml:"include,omitempty"`
+	Exclude  []string `toml:"exclude,omitempty"`
+	Archived bool     `toml:"archived,omitempty"`
+	Path     string   `toml:"path,omitempty"`
+	Tags     []string `toml:"tags,omitempty"`
+	Refresh  string   `toml:"refresh,omitempty"`
+}
+
+// Provider returns the provider whose API lists the source.
+func (s *SourceConfig) Provider() string {
+	switch s.Type {
+	case SourceGitLabGroup:
+		return ProviderGitLab
+	case SourceGiteaOrg:
+		return ProviderGitea
+	default:
+		return ProviderGitHub
+	}
+}
+
+// RefreshInterval returns how old the listing may get before a sync
+// refreshes it, or 0 if it never does.
+func (s *SourceConfig) RefreshInterval() time.Duration {
+	switch {
+	case s.Refresh < 0:
+		return 0
+	case s.Refresh == 0:
+		return DefaultSourceRefresh
+	default:
+		return s.Refresh
+	}
+}
+
+// Matches returns true if a listed repository belongs to the source: its
+// name matches an include pattern, if any, and no exclude pattern, and it
+// is not archived unless archived repositories are included.
+func (s *SourceConfig) Matches(repo SourceRepository) bool {
+	if repo.Archived && !s.Archived {
+		return false
+	}
+	matches := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, repo.Name); ok {
+				return true
+			}
+		}
+		return false
+	}
+	if len(s.Include) > 0 && !matches(s.Include) {
+		return false
+	}
+	return !matches(s.Exclude)
+}
+
+// SourceListing is the cached list of the repositories of a source.
+type SourceListing struct {
+	Source       string             `toml:"source"`
+	Refreshed    time.Time          `toml:"refreshed"`
+	Repositories []SourceRepository `toml:"repository"`
+}
+
+// SourceRepository is a repository as listed by a source.
+type SourceRepository struct {
+	Name     string `toml:"name"`
+	URL      string `toml:"url"`
+	Archived bool   `toml:"archived,omitempty"`
+}
+
+// sourcesFile is the raw TOML structure of the cached listings.
+type sourcesFile struct {
+	Sources []SourceListing `toml:"source"`
+}
+
+// SourcesPath returns the path of the cached source listings.
+func (c *Config) SourcesPath() string {
+	return filepath.Join(filepath.Dir(c.configPath), SourcesName)
+}
+
+// GetSource returns the named source.
+func (c *Config) GetSource(name string) (*SourceConfig, bool) {
+	for i := range c.Sources {
+		if c.Sources[i].Name == name {
+			return &c.Sources[i], true
+		}
+	}
+	return nil, false
+}
+
+// AddSource adds a source to the configuration. Its repositories are
+// merged once it has been refreshed.
+func (c *Config) AddSource(src SourceConfig) error {
+	if _, exists := c.GetSource(src.Name); exists {
+		return fmt.Errorf("source already exists: %s", src.Name)
+	}
+	c.Sources = append(c.Sources, src)
+	return nil
+}
+
+// RemoveSource removes a source and the repositories merged from it.
+func (c *Config) RemoveSource(name string) error {
+	for i, src := range c.Sources {
+		if src.Name == name {
+			c.Sources = append(c.Sources[:i], c.Sources[i+1:]...)
+			return c.ReloadSources()
+		}
+	}
+	return fmt.Errorf("source not found: %s", name)
+}
+
+// SourceOf returns the name of the source the named repository comes
+// from, or "" if it is defined in the config.
+func (c *Config) SourceOf(name string) string {
+	return c.sourceRepos[name]
+}
+
+// LoadSourceListings reads the cached source listings at path, by source
+// name. A missing file has none.
+func LoadSourceListings(path string) (map[string]SourceListing, error) {
+	listings := make(map[string]SourceListing)
+	var sf sourcesFile
+	if _, err := toml.DecodeFile(path, &sf); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return listings, nil
+		}
+		return nil, fmt.Errorf("failed to parse source listings: %w", err)
+	}
+	for _, l := range sf.Sources {
+		listings[l.Source] = l
+	}
+	return listings, nil
+}
+
+// SaveSourceListings writes the source listings to path.
+func SaveSourceListings(path string, listings map[string]SourceListing) error {
+	var sf sourcesFile
+	for _, l := range listings {
+		sf.Sources = append(sf.Sources, l)
+	}
+	sort.Slice(sf.Sources, func(i, j int) bool { return sf.Sources[i].Source < sf.Sources[j].Source })
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to save source listings: %w", err)
+	}
+	if err := toml.NewEncoder(f).Encode(sf); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to encode source listings: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to save source listings: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// ReloadSources replaces the repositories from sources with those in
+// the cached listings, as after a refresh.
+func (c *Config) ReloadSources() error {
+	repos := c.Repositories[:0]
+	for _, repo := range c.Repositories {
+		if c.sourceRepos[repo.Name] == "" {
+			repos = append(repos, repo)
+		}
+	}
+	c.Repositories = repos
+	return c.mergeSources()
+}
+
+// mergeSources adds the repositories in the cached listings of the
+// sources to c. Repositories defined in the config, and those listed by
+// an earlier source, take precedence.
+func (c *Config) mergeSources() error {
+	c.sourceRepos = make(map[string]string)
+	if len(c.Sources) == 0 || c.configPath == "" {
+		return nil
+	}
+	listings, err := LoadSourceListings(c.SourcesPath())
+	if err != nil {
+		return err
+	}
+
+	for _, src := range c.Sources {
+		for _, listed := range listings[src.Name].Repositories {
+			if !src.Matches(listed) {
+				continue
+			}
+			if _, exists := c.GetRepository(listed.Name); exists {
+				continue
+			}
+			repo := Repository{
+				Name: listed.Name,
+				URL:  listed.URL,
+				Type: RepoTypeGit,
+				Tags: src.Tags,
+			}
+			if src.Path != "" {
+				repo.Path = path.Join(src.Path, listed.Name)
+			}
+			c.applyRepoDefaults(&repo)
+			c.Repositories = append(c.Repositories, repo)
+			c.sourceRepos[repo.Name] = src.Name
+		}
+	}
+	return nil
+}
+
+// ParseSource converts the raw source settings, filling in defaults.
+func ParseSource(sf SourceConfigFile) (SourceConfig, error) {
+	s := SourceConfig{
+		Name:            sf.Name,
+		Type:            sf.Type,
+		Host:            sf.Host,
+		Org:             sf.Org,
+		Include:         sf.Include,
+		Exclude:         sf.Exclude,
+		Archived:        sf.Archived,
+		Path:            sf.Path,
+		Tags:            sf.Tags,
+		RefreshOriginal: sf.Refresh,
+	}
+	if s.Org == "" {
+		s.Org = sf.Group
+	}
+	s.Org = strings.Trim(s.Org, "/")
+	if s.Name == "" && s.Org != "" {
+		s.Name = path.Base(s.Org)
+	}
+	if s.Host == "" {
+		switch s.Type {
+		case SourceGitHubOrg:
+			s.Host = "github.com"
+		case SourceGitLabGroup:
+			s.Host = "gitlab.com"
+		}
+	}
+	switch sf.Refresh {
+	case "":
+	case "never":
+		s.Refresh = -1
+	default:
+		d, err := time.ParseDuration(sf.Refresh)
+		if err != nil || d <= 0 {
+			return s, fmt.Errorf("invalid refresh %q for source %s (expected a duration such as 12h, or never)", sf.Refresh, s.Name)
+		}
+		s.Refresh = d
+	}
+	return s, nil
+}
+
+// toSourceFile converts source settings back to their raw form.
+func toSourceFile(s SourceConfig) SourceConfigFile {
+	sf := SourceConfigFile{
+		Type:     s.Type,
+		Host:     s.Host,
+		Include:  s.Include,
+		Exclude:  s.Exclude,
+		Archived: s.Archived,
+		Path:     s.Path,
+		Tags:     s.Tags,
+		Refresh:  s.RefreshOriginal,
+	}
+	if s.Type == SourceGitLabGroup {
+		sf.Group = s.Org
+	} else {
+		sf.Org = s.Org
+	}
+	if s.Name != path.Base(s.Org) {
+		sf.Name = s.Name
+	}
+	if (s.Type == SourceGitHubOrg && s.Host == "github.com") || (s.Type == SourceGitLabGroup && s.Host == "gitlab.com") {
+		sf.Host = ""
+	}
+	return sf
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UI themes.
+const (
+	ThemeDefault        = "default"
+	ThemeColorblindSafe = "colorblind-safe"
+	ThemeMonochrome     = "monochrome"
+)
+
+// UIThemes are the accepted values of ui.theme.
+var UIThemes = []string{ThemeDefault, ThemeColorblindSafe, ThemeMonochrome}
+
+// UIColorNames are the colors ui.colors can override.
+var UIColorNames = []string{"primary", "success", "warning", "error", "muted", "highlight"}
+
+// UIConfig holds settings for styled terminal output.
+type UIConfig struct {
+	Theme  string            // One of UIThemes; "" is the default theme
+	Colors map[string]string // Color overrides by name in UIColorNames
+}
+
+// UIConfigFile is the raw TOML structure for UI settings.
+type UIConfigFile struct {
+	Theme  string            `toml:"theme,omitempty"`
+	Colors map[string]string `toml:"colors,omitempty"`
+}
+
+// hexColorPattern matches colors given as #rgb or #rrggbb.
+var hexColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// validColor reports whether s is an ANSI 256-color number or a hex
+// color.
+func validColor(s string) bool {
+	if hexColorPattern.MatchString(s) {
+		return true
+	}
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 0 && n <= 255
+}
+
+func validateUI(ui *UIConfig) error {
+	if ui.Theme != "" && !slices.Contains(UIThemes, ui.Theme) {
+		return &ValidationError{
+			Field:   "ui.theme",
+			Message: fmt.Sprintf("invalid value %q (must be %s)", ui.Theme, strings.Join(UIThemes, ", ")),
+		}
+	}
+
+	names := make([]string, 0, len(ui.Colors))
+	for name := range ui.Colors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !slices.Contains(UIColorNames, name) {
+			return &ValidationError{
+				Field:   "ui.colors." + name,
+				Message: fmt.Sprintf("unknown color (must be one of %s)", strings.Join(UIColorNames, ", ")),
+			}
+		}
+		if !validColor(ui.Colors[name]) {
+			return &ValidationError{
+				Field:   "ui.colors." + name,
+				Message: fmt.Sprintf("invalid color %q (must be an ANSI color number 0-255 or #rrggbb)", ui.Colors[name]),
+			}
+		}
+	}
+	return nil
+}
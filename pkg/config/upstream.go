@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+)
+
+// UpstreamFileName is the name of the cached upstream config, stored next
+// to the local config file.
+const UpstreamFileName = ".harbormaster.upstream.toml"
+
+// UpstreamConfig holds the location of a centrally published config.
+type UpstreamConfig struct {
+	URL string
+}
+
+// UpstreamConfigFile is the raw TOML structure for upstream settings.
+type UpstreamConfigFile struct {
+	URL string `toml:"url,omitempty"`
+}
+
+// Enabled returns true if an upstream config is configured.
+func (u *UpstreamConfig) Enabled() bool {
+	return u.URL != ""
+}
+
+// UpstreamPath returns the path of the cached upstream config.
+func (c *Config) UpstreamPath() string {
+	return filepath.Join(filepath.Dir(c.configPath), UpstreamFileName)
+}
+
+// IsUpstreamRepository returns true if the named repository comes from the
+// upstream config rather than the local file.
+func (c *Config) IsUpstreamRepository(name string) bool {
+	return c.upstreamRepos[name]
+}
+
+// IsUpstreamProject returns true if the named project comes from the
+// upstream config rather than the local file.
+func (c *Config) IsUpstreamProject(name string) bool {
+	return c.upstreamProjects[name]
+}
+
+// LoadUpstream reads the repositories and projects from an upstream config
+// file. Only repositories and projects are shared; other settings in the
+// file are ignored.
+func LoadUpstream(path string) ([]Repository, []Project, error) {
+	var cf ConfigFile
+	if _, err := toml.DecodeFile(path, &cf); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse upstream config: %w", err)
+	}
+
+	ucfg, err := parseConfigFile(&cf, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateConfig(ucfg); err != nil {
+		return nil, nil, fmt.Errorf("upstream config validation failed: %w", err)
+	}
+
+	return ucfg.Repositories, ucfg.Projects, nil
+}
+
+// mergeUpstream adds the cached upstream repositories and projects to c.
+// Local definitions with the same name take precedence.
+func (c *Config) mergeUpstream() error {
+	path := c.UpstreamPath()
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	repos, projects, err := LoadUpstream(path)
+	if err != nil {
+		return err
+	}
+
+	c.upstreamRepos = make(map[string]bool)
+	for _, repo := range repos {
+		if _, exists := c.GetRepository(repo.Name); exists {
+			continue
+		}
+		c.Repositories = append(c.Repositories, repo)
+		c.upstreamRepos[repo.Name] = true
+	}
+
+	c.upstreamProjects = make(map[string]bool)
+	for _, proj := range projects {
+		if _, exists := c.GetProject(proj.Name); exists {
+			continue
+		}
+		c.Projects = append(c.Projects, proj)
+		c.upstreamProjects[proj.Name] = true
+	}
+
+	return nil
+}
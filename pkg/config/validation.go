@@ -1,9 +1,16 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
 	"net/url"
+	"path"
+	"path/filepath"
+	"slices"
 	"strings"
+
+	"github.com/tierone/harbormaster/pkg/semver"
 )
 
 // ValidationError represents a configuration validation error.
@@ -18,8 +25,37 @@ func (e *ValidationError) Error() string {
 
 // ValidateConfig validates the entire configuration.
 func ValidateConfig(cfg *Config) error {
-	// Validate repositories
+	// Validate general settings
+	if cfg.General.Concurrency < 0 {
+		return &ValidationError{
+			Field:   "general.concurrency",
+			Message: "must not be negative",
+		}
+	}
+
+	if cfg.General.QuarantineAfter < 0 {
+		return &ValidationError{
+			Field:   "general.quarantine_after",
+			Message: "must not be negative",
+		}
+	}
+	if cfg.General.MinSyncInterval < 0 {
+		return &ValidationError{
+			Field:   "general.min_sync_interval",
+			Message: "must not be negative",
+		}
+	}
+	if cfg.Git.RetryAttempts < 0 {
+		return &ValidationError{
+			Field:   "git.retry_attempts",
+			Message: "must not be negative",
+		}
+	}
+
+	// Validate repositories. Several may check out the same URL, but
+	// each needs a path of its own.
 	repoNames := make(map[string]bool)
+	repoPaths := make(map[string]string)
 	for i, repo := range cfg.Repositories {
 		if err := validateRepository(&repo, i); err != nil {
 			return err
@@ -31,6 +67,34 @@ func ValidateConfig(cfg *Config) error {
 			}
 		}
 		repoNames[repo.Name] = true
+		p := path.Clean(filepath.ToSlash(repo.GetEffectivePath()))
+		if other, ok := repoPaths[p]; ok {
+			return &ValidationError{
+				Field:   fmt.Sprintf("repository[%d].path", i),
+				Message: fmt.Sprintf("path %s is also used by repository %s", p, other),
+			}
+		}
+		repoPaths[p] = repo.Name
+
+		// Copying or linking a directory into itself would destroy it
+		if repo.Type == RepoTypePath {
+			if err := validatePathSource(cfg, &repo, i); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Validate dependencies
+	for i, repo := range cfg.Repositories {
+		for j, dep := range repo.DependsOn {
+			field := fmt.Sprintf("repository[%d].depends_on[%d]", i, j)
+			if dep == repo.Name {
+				return &ValidationError{Field: field, Message: "repository cannot depend on itself"}
+			}
+			if !repoNames[dep] {
+				return &ValidationError{Field: field, Message: fmt.Sprintf("unknown repository: %s", dep)}
+			}
+		}
 	}
 
 	// Validate projects
@@ -48,6 +112,224 @@ func ValidateConfig(cfg *Config) error {
 		projectNames[proj.Name] = true
 	}
 
+	// Validate generate entries
+	for i, gen := range cfg.Generate {
+		if gen.Template == "" {
+			return &ValidationError{
+				Field:   fmt.Sprintf("generate[%d].template", i),
+				Message: "template is required",
+			}
+		}
+		if gen.Output == "" {
+			return &ValidationError{
+				Field:   fmt.Sprintf("generate[%d].output", i),
+				Message: "output is required",
+			}
+		}
+	}
+
+	// Validate hosts
+	hostNames := make(map[string]bool)
+	for i, host := range cfg.Hosts {
+		prefix := fmt.Sprintf("host[%d]", i)
+		if host.Name == "" {
+			return &ValidationError{Field: prefix + ".name", Message: "name is required"}
+		}
+		if hostNames[strings.ToLower(host.Name)] {
+			return &ValidationError{
+				Field:   prefix + ".name",
+				Message: fmt.Sprintf("duplicate host name: %s", host.Name),
+			}
+		}
+		hostNames[strings.ToLower(host.Name)] = true
+		// A host may only pin SSH host keys or name a URL shorthand,
+		// without an API
+		apiless := host.Provider == "" && host.APIURL == "" && host.Token == "" &&
+			(len(host.SSHFingerprints) > 0 || host.Shorthand != "" || host.Protocol != "")
+		if !apiless && !slices.Contains(providers, host.Provider) {
+			return &ValidationError{
+				Field:   prefix + ".provider",
+				Message: fmt.Sprintf("invalid provider %q (must be %s)", host.Provider, strings.Join(providers, ", ")),
+			}
+		}
+		if host.APIURL != "" {
+			if u, err := url.Parse(host.APIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return &ValidationError{
+					Field:   prefix + ".api_url",
+					Message: "must be an http or https URL",
+				}
+			}
+		}
+		if host.Protocol != "" && host.Protocol != ProtocolHTTPS && host.Protocol != ProtocolSSH {
+			return &ValidationError{
+				Field:   prefix + ".protocol",
+				Message: fmt.Sprintf("invalid protocol %q (must be https or ssh)", host.Protocol),
+			}
+		}
+		if strings.ContainsAny(host.Shorthand, ":/@ ") {
+			return &ValidationError{
+				Field:   prefix + ".shorthand",
+				Message: fmt.Sprintf("invalid shorthand %q", host.Shorthand),
+			}
+		}
+		for j, fp := range host.SSHFingerprints {
+			if !isSSHFingerprint(fp) {
+				return &ValidationError{
+					Field:   fmt.Sprintf("%s.ssh_fingerprints[%d]", prefix, j),
+					Message: fmt.Sprintf("invalid fingerprint %q (expected SHA256:<base64>, as printed by ssh-keygen -l)", fp),
+				}
+			}
+		}
+	}
+
+	// Validate sources
+	sourceNames := make(map[string]bool)
+	for i, src := range cfg.Sources {
+		prefix := fmt.Sprintf("source[%d]", i)
+		if !slices.Contains(SourceTypes, src.Type) {
+			return &ValidationError{
+				Field:   prefix + ".type",
+				Message: fmt.Sprintf("invalid source type %q (must be %s)", src.Type, strings.Join(SourceTypes, " or ")),
+			}
+		}
+		if src.Org == "" {
+			field := "org"
+			if src.Type == SourceGitLabGroup {
+				field = "group"
+			}
+			return &ValidationError{Field: prefix + "." + field, Message: field + " is required"}
+		}
+		if src.Type == SourceGiteaOrg && src.Host == "" {
+			return &ValidationError{Field: prefix + ".host", Message: "host is required for " + SourceGiteaOrg}
+		}
+		if src.Type != SourceGitLabGroup && strings.Contains(src.Org, "/") {
+			return &ValidationError{Field: prefix + ".org", Message: fmt.Sprintf("invalid org %q", src.Org)}
+		}
+		if sourceNames[src.Name] {
+			return &ValidationError{
+				Field:   prefix + ".name",
+				Message: fmt.Sprintf("duplicate source name: %s", src.Name),
+			}
+		}
+		sourceNames[src.Name] = true
+		for field, patterns := range map[string][]string{"include": src.Include, "exclude": src.Exclude} {
+			for _, pattern := range patterns {
+				if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+					return &ValidationError{
+						Field:   prefix + "." + field,
+						Message: fmt.Sprintf("invalid pattern %q", pattern),
+					}
+				}
+			}
+		}
+		if clean := path.Clean(src.Path); src.Path != "" && (path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../")) {
+			return &ValidationError{
+				Field:   prefix + ".path",
+				Message: "must be relative to the work directory",
+			}
+		}
+	}
+
+	// Validate mirror map
+	for prefix, root := range cfg.MirrorMap {
+		if prefix == "" {
+			return &ValidationError{Field: "mirror_map", Message: "upstream URL prefix is required"}
+		}
+		if strings.TrimSpace(root) == "" {
+			return &ValidationError{
+				Field:   fmt.Sprintf("mirror_map.%q", prefix),
+				Message: "mirror root is required",
+			}
+		}
+	}
+
+	// Validate repository defaults
+	for _, scope := range []struct {
+		kind     string
+		defaults map[string]RepoDefaults
+	}{{"tag", cfg.RepoDefaults.Tags}, {"project", cfg.RepoDefaults.Projects}} {
+		for name, d := range scope.defaults {
+			field := fmt.Sprintf("repo_defaults.%s.%q", scope.kind, name)
+			if scope.kind == "project" && !projectNames[name] {
+				return &ValidationError{Field: field, Message: fmt.Sprintf("unknown project: %s", name)}
+			}
+			if len(d.Tags) > 0 || len(d.Projects) > 0 {
+				return &ValidationError{Field: field, Message: "defaults for a tag or project cannot be nested"}
+			}
+		}
+	}
+
+	// Validate upstream
+	if cfg.Upstream.Enabled() {
+		if u, err := url.Parse(cfg.Upstream.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return &ValidationError{
+				Field:   "upstream.url",
+				Message: "must be an http or https URL",
+			}
+		}
+	}
+
+	// Validate notifications
+	for i, hook := range cfg.Notify.Webhook.URLs {
+		if u, err := url.Parse(hook); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return &ValidationError{
+				Field:   fmt.Sprintf("notify.webhook.urls[%d]", i),
+				Message: "must be an http or https URL",
+			}
+		}
+	}
+	if cfg.Notify.Webhook.RetryAttempts < 0 {
+		return &ValidationError{
+			Field:   "notify.webhook.retry_attempts",
+			Message: "must not be negative",
+		}
+	}
+
+	for _, chat := range []struct {
+		name string
+		on   string
+	}{{"slack", cfg.Notify.Slack.On}, {"teams", cfg.Notify.Teams.On}} {
+		switch chat.on {
+		case "", "always", "failure", "change":
+		default:
+			return &ValidationError{
+				Field:   fmt.Sprintf("notify.%s.on", chat.name),
+				Message: fmt.Sprintf("invalid value %q (must be always, failure, or change)", chat.on),
+			}
+		}
+	}
+
+	// Validate overlay
+	for i, repoName := range cfg.Overlay.Repositories {
+		if !repoNames[repoName] {
+			return &ValidationError{
+				Field:   fmt.Sprintf("overlay.repositories[%d]", i),
+				Message: fmt.Sprintf("unknown repository: %s", repoName),
+			}
+		}
+	}
+
+	return validateUI(&cfg.UI)
+}
+
+// validatePathSource rejects a path repository whose source directory
+// is its destination, contains it, or lies inside it.
+func validatePathSource(cfg *Config, repo *Repository, index int) error {
+	source, err := cfg.LocalSource(repo)
+	if err != nil {
+		return &ValidationError{Field: fmt.Sprintf("repository[%d].url", index), Message: err.Error()}
+	}
+	destination := filepath.Join(cfg.General.WorkDir, repo.GetEffectivePath())
+	overlap, err := PathsOverlap(source, destination)
+	if err != nil {
+		return &ValidationError{Field: fmt.Sprintf("repository[%d].url", index), Message: err.Error()}
+	}
+	if overlap {
+		return &ValidationError{
+			Field:   fmt.Sprintf("repository[%d].url", index),
+			Message: fmt.Sprintf("source directory %s overlaps the repository path %s", source, destination),
+		}
+	}
 	return nil
 }
 
@@ -62,18 +344,237 @@ func validateRepository(repo *Repository, index int) error {
 		return &ValidationError{Field: prefix + ".url", Message: "url is required"}
 	}
 
-	if err := validateURL(repo.URL); err != nil {
-		return &ValidationError{Field: prefix + ".url", Message: err.Error()}
+	// The url of a path repository is a local directory
+	if repo.Type != RepoTypePath {
+		if err := validateURL(repo.URL); err != nil {
+			return &ValidationError{Field: prefix + ".url", Message: err.Error()}
+		}
 	}
 
 	if repo.Type == "" {
 		return &ValidationError{Field: prefix + ".type", Message: "type is required"}
 	}
 
-	if repo.Type != RepoTypeGit && repo.Type != RepoTypeHTTP {
+	if repo.Type != RepoTypeGit && repo.Type != RepoTypeHTTP && repo.Type != RepoTypePath {
 		return &ValidationError{
 			Field:   prefix + ".type",
-			Message: fmt.Sprintf("invalid type: %s (must be 'git' or 'http')", repo.Type),
+			Message: fmt.Sprintf("invalid type: %s (must be 'git', 'http' or 'path')", repo.Type),
+		}
+	}
+
+	if repo.Symlink && repo.Type != RepoTypePath {
+		return &ValidationError{
+			Field:   prefix + ".symlink",
+			Message: "symlinks are only supported for path repositories",
+		}
+	}
+
+	if repo.Type == RepoTypePath && (repo.Branch != "" || repo.Tag != "" || repo.Commit != "" || repo.Version != "" || repo.Ref != "") {
+		return &ValidationError{
+			Field:   prefix,
+			Message: "path repositories do not support branch, tag, commit, version, or ref",
+		}
+	}
+
+	if repo.Version != "" {
+		if repo.Type != RepoTypeGit {
+			return &ValidationError{
+				Field:   prefix + ".version",
+				Message: "version constraints are only supported for git repositories",
+			}
+		}
+		if _, err := semver.ParseConstraint(repo.Version); err != nil {
+			return &ValidationError{Field: prefix + ".version", Message: err.Error()}
+		}
+	}
+
+	if repo.Ref != "" {
+		if repo.Ref != RefLatestRelease && !repo.CustomRef() {
+			return &ValidationError{
+				Field:   prefix + ".ref",
+				Message: fmt.Sprintf("must be '%s' or a full ref starting with 'refs/'", RefLatestRelease),
+			}
+		}
+		if repo.CustomRef() && strings.ContainsAny(repo.Ref, " ~^:?*[\\") {
+			return &ValidationError{
+				Field:   prefix + ".ref",
+				Message: "invalid ref name",
+			}
+		}
+		if repo.Type != RepoTypeGit {
+			return &ValidationError{
+				Field:   prefix + ".ref",
+				Message: "refs are only supported for git repositories",
+			}
+		}
+	}
+
+	if repo.BranchPattern() {
+		if _, err := path.Match(repo.Branch, ""); err != nil {
+			return &ValidationError{
+				Field:   prefix + ".branch",
+				Message: fmt.Sprintf("invalid branch pattern: %v", err),
+			}
+		}
+	}
+
+	if repo.BranchSort != "" {
+		if !repo.BranchPattern() {
+			return &ValidationError{
+				Field:   prefix + ".branch_sort",
+				Message: "branch_sort requires a branch pattern",
+			}
+		}
+		if repo.BranchSort != BranchSortVersion && repo.BranchSort != BranchSortDate {
+			return &ValidationError{
+				Field:   prefix + ".branch_sort",
+				Message: fmt.Sprintf("must be '%s' or '%s'", BranchSortVersion, BranchSortDate),
+			}
+		}
+	}
+
+	if repo.AsOf != "" {
+		if _, err := repo.AsOfTime(); err != nil {
+			return &ValidationError{Field: prefix + ".as_of", Message: err.Error()}
+		}
+		if repo.Type != RepoTypeGit {
+			return &ValidationError{
+				Field:   prefix + ".as_of",
+				Message: "as_of is only supported for git repositories",
+			}
+		}
+		if repo.Tag != "" || repo.Commit != "" || repo.ResolvesRef() {
+			return &ValidationError{
+				Field:   prefix + ".as_of",
+				Message: "as_of requires a plain branch or the default branch",
+			}
+		}
+	}
+
+	if len(repo.Refspecs) > 0 && repo.Type != RepoTypeGit {
+		return &ValidationError{
+			Field:   prefix + ".refspecs",
+			Message: "refspecs are only supported for git repositories",
+		}
+	}
+	for i, spec := range repo.Refspecs {
+		src, dst, ok := strings.Cut(strings.TrimPrefix(spec, "+"), ":")
+		if !ok || src == "" || !strings.HasPrefix(dst, "refs/") || strings.ContainsAny(spec, " \t") {
+			return &ValidationError{
+				Field:   fmt.Sprintf("%s.refspecs[%d]", prefix, i),
+				Message: fmt.Sprintf("invalid refspec %q (expected [+]<src>:refs/<dst>)", spec),
+			}
+		}
+	}
+
+	if repo.Subdir != "" {
+		if repo.Type != RepoTypeGit {
+			return &ValidationError{
+				Field:   prefix + ".subdir",
+				Message: "subdir is only supported for git repositories",
+			}
+		}
+		if clean := path.Clean(repo.Subdir); path.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+			return &ValidationError{
+				Field:   prefix + ".subdir",
+				Message: fmt.Sprintf("invalid subdir %q (must be a directory inside the repository)", repo.Subdir),
+			}
+		}
+		if repo.Vendor != nil && !*repo.Vendor {
+			return &ValidationError{
+				Field:   prefix + ".subdir",
+				Message: "a subdir is always vendored and cannot be combined with vendor = false",
+			}
+		}
+	}
+
+	if repo.MinSyncInterval != nil && *repo.MinSyncInterval < 0 {
+		return &ValidationError{
+			Field:   prefix + ".min_sync_interval",
+			Message: "must not be negative",
+		}
+	}
+
+	if repo.SubmoduleDepth != 0 || len(repo.IncludeSubmodules) > 0 || len(repo.ExcludeSubmodules) > 0 {
+		if repo.Type != RepoTypeGit {
+			return &ValidationError{
+				Field:   prefix,
+				Message: "submodule_depth, include_submodules, and exclude_submodules are only supported for git repositories",
+			}
+		}
+		if repo.SubmoduleDepth < 0 {
+			return &ValidationError{
+				Field:   prefix + ".submodule_depth",
+				Message: "must not be negative",
+			}
+		}
+	}
+	for field, patterns := range map[string][]string{"include_submodules": repo.IncludeSubmodules, "exclude_submodules": repo.ExcludeSubmodules} {
+		for i, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil || pattern == "" || path.IsAbs(pattern) {
+				return &ValidationError{
+					Field:   fmt.Sprintf("%s.%s[%d]", prefix, field, i),
+					Message: fmt.Sprintf("invalid submodule pattern %q", pattern),
+				}
+			}
+		}
+	}
+
+	if repo.CheckoutMode != "" {
+		if repo.CheckoutMode != CheckoutDetached && repo.CheckoutMode != CheckoutBranch {
+			return &ValidationError{
+				Field:   prefix + ".checkout_mode",
+				Message: fmt.Sprintf("must be '%s' or '%s'", CheckoutDetached, CheckoutBranch),
+			}
+		}
+		if repo.Type != RepoTypeGit {
+			return &ValidationError{
+				Field:   prefix + ".checkout_mode",
+				Message: "checkout_mode is only supported for git repositories",
+			}
+		}
+		if repo.CheckoutMode == CheckoutBranch && (repo.Tag != "" || repo.Commit != "" || repo.Ref != "" || repo.Version != "" || repo.AsOf != "") {
+			return &ValidationError{
+				Field:   prefix + ".checkout_mode",
+				Message: fmt.Sprintf("checkout_mode = '%s' requires a branch, a branch pattern, or the default branch", CheckoutBranch),
+			}
+		}
+	}
+
+	if (len(repo.BlockedCommits) > 0 || len(repo.AllowedCommitters) > 0) && repo.Type != RepoTypeGit {
+		return &ValidationError{
+			Field:   prefix,
+			Message: "blocked_commits and allowed_committers are only supported for git repositories",
+		}
+	}
+	for i, sha := range repo.BlockedCommits {
+		if !isCommitPrefix(sha) {
+			return &ValidationError{
+				Field:   fmt.Sprintf("%s.blocked_commits[%d]", prefix, i),
+				Message: fmt.Sprintf("invalid commit %q (expected 7 to 40 hex digits)", sha),
+			}
+		}
+	}
+	for i, pattern := range repo.AllowedCommitters {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return &ValidationError{
+				Field:   fmt.Sprintf("%s.allowed_committers[%d]", prefix, i),
+				Message: fmt.Sprintf("invalid committer pattern %q", pattern),
+			}
+		}
+	}
+
+	if repo.Prereleases && repo.Ref != RefLatestRelease {
+		return &ValidationError{
+			Field:   prefix + ".prereleases",
+			Message: fmt.Sprintf("prereleases requires ref = '%s'", RefLatestRelease),
+		}
+	}
+
+	if repo.Vendor != nil && *repo.Vendor && repo.Type != RepoTypeGit {
+		return &ValidationError{
+			Field:   prefix + ".vendor",
+			Message: "vendoring is only supported for git repositories",
 		}
 	}
 
@@ -88,16 +589,45 @@ func validateRepository(repo *Repository, index int) error {
 	if repo.Commit != "" {
 		refCount++
 	}
+	if repo.Version != "" {
+		refCount++
+	}
+	if repo.Ref != "" {
+		refCount++
+	}
 	if refCount > 1 {
 		return &ValidationError{
 			Field:   prefix,
-			Message: "only one of branch, tag, or commit can be specified",
+			Message: "only one of branch, tag, commit, version, or ref can be specified",
 		}
 	}
 
 	return nil
 }
 
+// isSSHFingerprint reports whether s is a SHA256 host key fingerprint.
+func isSSHFingerprint(s string) bool {
+	b64, ok := strings.CutPrefix(s, "SHA256:")
+	if !ok {
+		return false
+	}
+	sum, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(b64, "="))
+	return err == nil && len(sum) == sha256.Size
+}
+
+// isCommitPrefix reports whether s is a full or abbreviated commit SHA.
+func isCommitPrefix(s string) bool {
+	if len(s) < 7 || len(s) > 40 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
 func validateProject(proj *Project, index int, repoNames map[string]bool) error {
 	prefix := fmt.Sprintf("project[%d]", index)
 
@@ -120,8 +650,8 @@ func validateProject(proj *Project, index int, repoNames map[string]bool) error
 }
 
 func validateURL(rawURL string) error {
-	// Handle git@ SSH URLs
-	if strings.HasPrefix(rawURL, "git@") {
+	// scp-like SSH URLs, such as git@github.com:org/app.git
+	if IsSCPLikeURL(rawURL) {
 		return nil
 	}
 
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestValidateConfig_Valid(t *testing.T) {
@@ -39,6 +41,23 @@ func TestValidateConfig_DuplicateRepoName(t *testing.T) {
 	}
 }
 
+func TestValidateConfig_SameURL(t *testing.T) {
+	cfg := &Config{
+		Repositories: []Repository{
+			{Name: "linux-v6.6", URL: "https://github.com/torvalds/linux.git", Type: RepoTypeGit, Tag: "v6.6"},
+			{Name: "linux-master", URL: "https://github.com/torvalds/linux.git", Type: RepoTypeGit, Branch: "master"},
+		},
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("repositories sharing a URL should be valid: %v", err)
+	}
+
+	cfg.Repositories[1].Path = "./linux-v6.6"
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("expected error for repositories sharing a path")
+	}
+}
+
 func TestValidateConfig_DuplicateProjectName(t *testing.T) {
 	cfg := &Config{
 		Repositories: []Repository{
@@ -144,12 +163,451 @@ func TestValidateConfig_GitSSHURL(t *testing.T) {
 	cfg := &Config{
 		Repositories: []Repository{
 			{Name: "repo1", URL: "git@github.com:test/repo.git", Type: RepoTypeGit},
+			{Name: "repo2", URL: "org@vs-ssh.visualstudio.com:v3/org/project/repo", Type: RepoTypeGit},
+			{Name: "repo3", URL: "git@ssh.dev.azure.com:v3/org/project/repo", Type: RepoTypeGit},
 		},
 	}
 
 	err := ValidateConfig(cfg)
 	if err != nil {
-		t.Errorf("git@ SSH URLs should be valid: %v", err)
+		t.Errorf("scp-like SSH URLs should be valid: %v", err)
+	}
+}
+
+func TestValidateConfig_PathRepository(t *testing.T) {
+	cfg := &Config{
+		Repositories: []Repository{
+			{Name: "local", URL: "../components/local", Type: RepoTypePath, Symlink: true},
+		},
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("path repositories should accept local directories: %v", err)
+	}
+
+	cfg.Repositories[0].Branch = "main"
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("expected error for a branch on a path repository")
+	}
+}
+
+func TestValidateConfig_PathRepositoryOverlap(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		workDir string
+		repo    Repository
+	}{
+		{"same directory", dir, Repository{Name: "libfoo", URL: "./libfoo", Type: RepoTypePath, Symlink: true}},
+		{"destination inside source", filepath.Join(dir, "src", "deps"), Repository{Name: "src", URL: "./src", Type: RepoTypePath}},
+		{"source inside destination", dir, Repository{Name: "vendor", URL: "./vendor/lib", Type: RepoTypePath}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				General:      GeneralConfig{WorkDir: tt.workDir},
+				Repositories: []Repository{tt.repo},
+				configPath:   filepath.Join(dir, ConfigFileName),
+			}
+			if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "overlaps") {
+				t.Errorf("expected overlap error, got %v", err)
+			}
+		})
+	}
+
+	cfg := &Config{
+		General:      GeneralConfig{WorkDir: filepath.Join(dir, "deps")},
+		Repositories: []Repository{{Name: "libfoo", URL: "./libfoo", Type: RepoTypePath, Symlink: true}},
+		configPath:   filepath.Join(dir, ConfigFileName),
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("expected separate directories to be valid: %v", err)
+	}
+}
+
+func TestValidateConfig_SymlinkRequiresPath(t *testing.T) {
+	cfg := &Config{
+		Repositories: []Repository{
+			{Name: "repo1", URL: "https://github.com/test/repo.git", Type: RepoTypeGit, Symlink: true},
+		},
+	}
+
+	err := ValidateConfig(cfg)
+	if err == nil {
+		t.Error("expected error for symlink on a git repository")
+	}
+}
+
+func TestValidateConfig_Version(t *testing.T) {
+	cfg := &Config{
+		Repositories: []Repository{
+			{Name: "lib", URL: "https://github.com/test/lib.git", Type: RepoTypeGit, Version: "~>1.4"},
+		},
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("version constraints should be valid on git repositories: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		repo Repository
+	}{
+		{"invalid constraint", Repository{Version: "latest"}},
+		{"with a tag", Repository{Version: "~>1.4", Tag: "v1.4.0"}},
+		{"on an http repository", Repository{Version: "~>1.4", Type: RepoTypeHTTP}},
+	}
+	for _, tt := range tests {
+		repo := cfg.Repositories[0]
+		repo.Version, repo.Tag = tt.repo.Version, tt.repo.Tag
+		if tt.repo.Type != "" {
+			repo.Type = tt.repo.Type
+		}
+		if err := ValidateConfig(&Config{Repositories: []Repository{repo}}); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestValidateConfig_LatestRelease(t *testing.T) {
+	cfg := &Config{
+		Repositories: []Repository{
+			{Name: "app", URL: "https://github.com/test/app.git", Type: RepoTypeGit, Ref: RefLatestRelease, Prereleases: true},
+		},
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("latest-release should be valid on git repositories: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		repo Repository
+	}{
+		{"unknown ref", Repository{Name: "app", URL: "https://github.com/test/app.git", Type: RepoTypeGit, Ref: "newest"}},
+		{"with a branch", Repository{Name: "app", URL: "https://github.com/test/app.git", Type: RepoTypeGit, Ref: RefLatestRelease, Branch: "main"}},
+		{"prereleases without ref", Repository{Name: "app", URL: "https://github.com/test/app.git", Type: RepoTypeGit, Prereleases: true}},
+	}
+	for _, tt := range tests {
+		if err := ValidateConfig(&Config{Repositories: []Repository{tt.repo}}); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestValidateConfig_BranchPattern(t *testing.T) {
+	repo := Repository{Name: "product", URL: "https://github.com/test/product.git", Type: RepoTypeGit, Branch: "release/2024.*", BranchSort: BranchSortDate}
+	if err := ValidateConfig(&Config{Repositories: []Repository{repo}}); err != nil {
+		t.Errorf("branch patterns should be valid: %v", err)
+	}
+
+	bad := repo
+	bad.Branch = "release/[2024"
+	if err := ValidateConfig(&Config{Repositories: []Repository{bad}}); err == nil {
+		t.Error("expected error for a malformed pattern")
+	}
+	bad = repo
+	bad.BranchSort = "name"
+	if err := ValidateConfig(&Config{Repositories: []Repository{bad}}); err == nil {
+		t.Error("expected error for an unknown branch_sort")
+	}
+	bad = repo
+	bad.Branch = "main"
+	if err := ValidateConfig(&Config{Repositories: []Repository{bad}}); err == nil {
+		t.Error("expected error for branch_sort without a pattern")
+	}
+}
+
+func TestValidateConfig_AsOf(t *testing.T) {
+	repo := Repository{Name: "app", URL: "https://github.com/test/app.git", Type: RepoTypeGit, Branch: "main", AsOf: "2024-06-01"}
+	if err := ValidateConfig(&Config{Repositories: []Repository{repo}}); err != nil {
+		t.Errorf("as_of should be valid on a branch: %v", err)
+	}
+
+	bad := repo
+	bad.AsOf = "yesterday"
+	if err := ValidateConfig(&Config{Repositories: []Repository{bad}}); err == nil {
+		t.Error("expected error for an invalid date")
+	}
+	bad = repo
+	bad.Branch, bad.Tag = "", "v1.0"
+	if err := ValidateConfig(&Config{Repositories: []Repository{bad}}); err == nil {
+		t.Error("expected error for as_of with a tag")
+	}
+}
+
+func TestValidateConfig_CustomRef(t *testing.T) {
+	for _, ref := range []string{"refs/pull/123/head", "refs/changes/34/1234/2"} {
+		repo := Repository{Name: "app", URL: "https://github.com/test/app.git", Type: RepoTypeGit, Ref: ref}
+		if err := ValidateConfig(&Config{Repositories: []Repository{repo}}); err != nil {
+			t.Errorf("%s should be valid: %v", ref, err)
+		}
+	}
+
+	for _, ref := range []string{"pull/123/head", "refs/pull/123/head:local", "refs/pull/*"} {
+		repo := Repository{Name: "app", URL: "https://github.com/test/app.git", Type: RepoTypeGit, Ref: ref}
+		if err := ValidateConfig(&Config{Repositories: []Repository{repo}}); err == nil {
+			t.Errorf("%s: expected error", ref)
+		}
+	}
+}
+
+func TestValidateConfig_Refspecs(t *testing.T) {
+	repo := Repository{Name: "app", URL: "https://github.com/test/app.git", Type: RepoTypeGit, Refspecs: []string{"+refs/notes/*:refs/notes/*", "refs/meta/config:refs/remotes/origin/meta/config"}}
+	if err := ValidateConfig(&Config{Repositories: []Repository{repo}}); err != nil {
+		t.Errorf("refspecs should be valid: %v", err)
+	}
+
+	for _, spec := range []string{"refs/notes/*", ":refs/notes/commits", "refs/notes/*:notes/*"} {
+		repo.Refspecs = []string{spec}
+		if err := ValidateConfig(&Config{Repositories: []Repository{repo}}); err == nil {
+			t.Errorf("%s: expected error", spec)
+		}
+	}
+}
+
+func TestValidateConfig_Subdir(t *testing.T) {
+	repo := Repository{Name: "app", URL: "https://github.com/test/app.git", Type: RepoTypeGit, Subdir: "tools/scripts"}
+	if err := ValidateConfig(&Config{Repositories: []Repository{repo}}); err != nil {
+		t.Errorf("subdir should be valid: %v", err)
+	}
+
+	for _, subdir := range []string{"/tools", ".", "..", "../other", "tools/../.."} {
+		bad := repo
+		bad.Subdir = subdir
+		if err := ValidateConfig(&Config{Repositories: []Repository{bad}}); err == nil {
+			t.Errorf("%s: expected error", subdir)
+		}
+	}
+
+	noVendor := false
+	bad := repo
+	bad.Vendor = &noVendor
+	if err := ValidateConfig(&Config{Repositories: []Repository{bad}}); err == nil {
+		t.Error("expected error for a subdir with vendor = false")
+	}
+}
+
+func TestValidateConfig_SubmoduleFilters(t *testing.T) {
+	repo := Repository{
+		Name: "app", URL: "https://github.com/test/app.git", Type: RepoTypeGit,
+		SubmoduleDepth:    1,
+		IncludeSubmodules: []string{"third_party/*"},
+		ExcludeSubmodules: []string{"third_party/testdata"},
+	}
+	if err := ValidateConfig(&Config{Repositories: []Repository{repo}}); err != nil {
+		t.Errorf("submodule filters should be valid: %v", err)
+	}
+
+	bad := repo
+	bad.SubmoduleDepth = -1
+	if err := ValidateConfig(&Config{Repositories: []Repository{bad}}); err == nil {
+		t.Error("expected error for a negative submodule_depth")
+	}
+
+	bad = repo
+	bad.ExcludeSubmodules = []string{"[third_party"}
+	if err := ValidateConfig(&Config{Repositories: []Repository{bad}}); err == nil {
+		t.Error("expected error for an invalid submodule pattern")
+	}
+
+	bad = repo
+	bad.Type, bad.URL = RepoTypeHTTP, "https://example.com/app.tar.gz"
+	if err := ValidateConfig(&Config{Repositories: []Repository{bad}}); err == nil {
+		t.Error("expected error for submodule filters on an HTTP repository")
+	}
+}
+
+func TestValidateConfig_CheckoutMode(t *testing.T) {
+	repo := Repository{Name: "app", URL: "https://github.com/test/app.git", Type: RepoTypeGit, Branch: "main", CheckoutMode: CheckoutBranch}
+	if err := ValidateConfig(&Config{Repositories: []Repository{repo}}); err != nil {
+		t.Errorf("branch mode should be valid: %v", err)
+	}
+
+	bad := repo
+	bad.CheckoutMode = "attached"
+	if err := ValidateConfig(&Config{Repositories: []Repository{bad}}); err == nil {
+		t.Error("expected error for an unknown checkout_mode")
+	}
+
+	bad = repo
+	bad.Branch, bad.Tag = "", "v1.0.0"
+	if err := ValidateConfig(&Config{Repositories: []Repository{bad}}); err == nil {
+		t.Error("expected error for branch mode with a tag")
+	}
+}
+
+func TestValidateConfig_CommitPolicy(t *testing.T) {
+	repo := Repository{
+		Name: "app", URL: "https://github.com/test/app.git", Type: RepoTypeGit,
+		BlockedCommits:    []string{"a1b2c3d", "0123456789abcdef0123456789abcdef01234567"},
+		AllowedCommitters: []string{"release-bot@example.com", "*@example.com"},
+	}
+	if err := ValidateConfig(&Config{Repositories: []Repository{repo}}); err != nil {
+		t.Errorf("commit policy should be valid: %v", err)
+	}
+
+	bad := repo
+	bad.BlockedCommits = []string{"a1b2"}
+	if err := ValidateConfig(&Config{Repositories: []Repository{bad}}); err == nil {
+		t.Error("expected error for a short blocked commit")
+	}
+
+	bad = repo
+	bad.AllowedCommitters = []string{"[*@example.com"}
+	if err := ValidateConfig(&Config{Repositories: []Repository{bad}}); err == nil {
+		t.Error("expected error for an invalid committer pattern")
+	}
+}
+
+func TestValidateConfig_Sources(t *testing.T) {
+	src := SourceConfig{Name: "acme", Type: SourceGitHubOrg, Host: "github.com", Org: "acme", Include: []string{"api-*"}, Path: "acme"}
+	if err := ValidateConfig(&Config{Sources: []SourceConfig{src}}); err != nil {
+		t.Errorf("source should be valid: %v", err)
+	}
+
+	if err := ValidateConfig(&Config{Sources: []SourceConfig{src, src}}); err == nil {
+		t.Error("expected error for a duplicate source name")
+	}
+
+	bad := src
+	bad.Type = "bitbucket-team"
+	if err := ValidateConfig(&Config{Sources: []SourceConfig{bad}}); err == nil {
+		t.Error("expected error for an unknown source type")
+	}
+
+	bad = src
+	bad.Org = ""
+	if err := ValidateConfig(&Config{Sources: []SourceConfig{bad}}); err == nil {
+		t.Error("expected error for a missing org")
+	}
+
+	bad = src
+	bad.Exclude = []string{"[api"}
+	if err := ValidateConfig(&Config{Sources: []SourceConfig{bad}}); err == nil {
+		t.Error("expected error for an invalid pattern")
+	}
+
+	bad = src
+	bad.Path = "../elsewhere"
+	if err := ValidateConfig(&Config{Sources: []SourceConfig{bad}}); err == nil {
+		t.Error("expected error for a path outside the work directory")
+	}
+
+	bad = src
+	bad.Org = "acme/platform"
+	if err := ValidateConfig(&Config{Sources: []SourceConfig{bad}}); err == nil {
+		t.Error("expected error for a GitHub org with a path")
+	}
+
+	group, err := ParseSource(SourceConfigFile{Type: SourceGitLabGroup, Group: "acme/platform/"})
+	if err != nil {
+		t.Fatalf("ParseSource failed: %v", err)
+	}
+	if group.Name != "platform" || group.Org != "acme/platform" || group.Host != "gitlab.com" {
+		t.Errorf("unexpected defaults %+v", group)
+	}
+	if err := ValidateConfig(&Config{Sources: []SourceConfig{src, group}}); err != nil {
+		t.Errorf("GitLab group should be valid: %v", err)
+	}
+	if sf := toSourceFile(group); sf.Group != "acme/platform" || sf.Org != "" || sf.Name != "" || sf.Host != "" {
+		t.Errorf("unexpected saved source %+v", sf)
+	}
+
+	group.Org = ""
+	if err := ValidateConfig(&Config{Sources: []SourceConfig{group}}); err == nil {
+		t.Error("expected error for a missing group")
+	}
+
+	gitea := SourceConfig{Name: "acme", Type: SourceGiteaOrg, Org: "acme"}
+	if err := ValidateConfig(&Config{Sources: []SourceConfig{gitea}}); err == nil {
+		t.Error("expected error for a Gitea source without a host")
+	}
+	gitea.Host = "forge.example.com"
+	if err := ValidateConfig(&Config{Sources: []SourceConfig{gitea}}); err != nil {
+		t.Errorf("Gitea source should be valid: %v", err)
+	}
+
+	if _, err := ParseSource(SourceConfigFile{Type: SourceGitHubOrg, Org: "acme", Refresh: "daily"}); err == nil {
+		t.Error("expected error for an invalid refresh interval")
+	}
+}
+
+func TestValidateConfig_MirrorMap(t *testing.T) {
+	cfg := &Config{MirrorMap: map[string]string{"https://github.com/": "${MIRROR}/github/"}}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("mirror map should be valid: %v", err)
+	}
+
+	cfg.MirrorMap = map[string]string{"https://github.com/": " "}
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("expected error for an empty mirror root")
+	}
+
+	cfg.MirrorMap = map[string]string{"": "https://mirror.example.com/"}
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("expected error for an empty prefix")
+	}
+}
+
+func TestValidateConfig_RepoDefaults(t *testing.T) {
+	cfg := &Config{
+		Projects: []Project{{Name: "tools"}},
+		RepoDefaults: RepoDefaults{
+			Tags:     map[string]RepoDefaults{"firmware": {Branch: "release"}},
+			Projects: map[string]RepoDefaults{"tools": {URLPrefix: "https://gitlab.com/acme/"}},
+		},
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("repository defaults should be valid: %v", err)
+	}
+
+	cfg.RepoDefaults.Projects = map[string]RepoDefaults{"missing": {Branch: "main"}}
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("expected error for defaults of an unknown project")
+	}
+
+	cfg.RepoDefaults.Projects = nil
+	cfg.RepoDefaults.Tags = map[string]RepoDefaults{"firmware": {Tags: map[string]RepoDefaults{"boot": {}}}}
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("expected error for nested defaults")
+	}
+}
+
+func TestValidateConfig_Hosts(t *testing.T) {
+	tests := []struct {
+		name string
+		host HostConfig
+	}{
+		{"missing name", HostConfig{Provider: ProviderGitHub}},
+		{"unknown provider", HostConfig{Name: "git.example.com"}},
+		{"invalid api_url", HostConfig{Name: "git.example.com", Provider: ProviderGitLab, APIURL: "git.example.com/api"}},
+		{"invalid fingerprint", HostConfig{Name: "github.com", Provider: ProviderGitHub, SSHFingerprints: []string{"MD5:16:27:ac:a5"}}},
+		{"token without provider", HostConfig{Name: "git.example.com", Token: "secret", SSHFingerprints: []string{"SHA256:+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU"}}},
+		{"unknown protocol", HostConfig{Name: "git.example.com", Shorthand: "corp", Protocol: "ftp"}},
+		{"invalid shorthand", HostConfig{Name: "git.example.com", Shorthand: "corp:"}},
+	}
+	for _, tt := range tests {
+		cfg := &Config{Hosts: []HostConfig{tt.host}}
+		if err := ValidateConfig(cfg); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+
+	// Hosts without an API may only pin SSH host keys or name a shorthand
+	cfg := &Config{Hosts: []HostConfig{
+		{Name: "git.example.com", SSHFingerprints: []string{"SHA256:+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU"}},
+		{Name: "git.corp.example.com", Shorthand: "corp", Protocol: ProtocolSSH},
+		{Name: "review.example.com", Provider: ProviderGerrit, Token: "jane:secret"},
+		{Name: "forge.example.com", Provider: ProviderForgejo, APIURL: "https://forge.example.com/api/v1"},
+	}}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("expected hosts without an API to be valid: %v", err)
+	}
+
+	cfg = &Config{Hosts: []HostConfig{
+		{Name: "github.com", Provider: ProviderGitHub},
+		{Name: "GitHub.com", Provider: ProviderGitHub},
+	}}
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("expected error for duplicate hosts")
 	}
 }
 
@@ -169,6 +627,91 @@ func TestValidateConfig_ProjectUnknownRepo(t *testing.T) {
 	}
 }
 
+func TestValidateConfig_DependsOn(t *testing.T) {
+	tests := []struct {
+		name      string
+		dependsOn []string
+		wantErr   string
+	}{
+		{"known repository", []string{"repo2"}, ""},
+		{"unknown repository", []string{"nonexistent"}, "unknown repository"},
+		{"itself", []string{"repo1"}, "cannot depend on itself"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Repositories: []Repository{
+					{Name: "repo1", URL: "https://github.com/test/repo1.git", Type: RepoTypeGit, DependsOn: tt.dependsOn},
+					{Name: "repo2", URL: "https://github.com/test/repo2.git", Type: RepoTypeGit},
+				},
+			}
+
+			err := ValidateConfig(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected valid config, got error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_NegativeConcurrency(t *testing.T) {
+	cfg := &Config{General: GeneralConfig{Concurrency: -1}}
+
+	err := ValidateConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "general.concurrency") {
+		t.Errorf("expected general.concurrency error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_NegativeMinSyncInterval(t *testing.T) {
+	cfg := &Config{General: GeneralConfig{MinSyncInterval: -time.Minute}}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "general.min_sync_interval") {
+		t.Errorf("expected general.min_sync_interval error, got: %v", err)
+	}
+
+	interval := -time.Hour
+	cfg = &Config{Repositories: []Repository{
+		{Name: "repo1", URL: "https://github.com/test/repo.git", Type: RepoTypeGit, MinSyncInterval: &interval},
+	}}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "min_sync_interval") {
+		t.Errorf("expected min_sync_interval error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_UI(t *testing.T) {
+	tests := []struct {
+		name    string
+		ui      UIConfig
+		wantErr string
+	}{
+		{name: "default", ui: UIConfig{}},
+		{name: "colorblind-safe", ui: UIConfig{Theme: ThemeColorblindSafe, Colors: map[string]string{"success": "33", "error": "#d55e00"}}},
+		{name: "unknown theme", ui: UIConfig{Theme: "neon"}, wantErr: "ui.theme"},
+		{name: "unknown color", ui: UIConfig{Colors: map[string]string{"ok": "33"}}, wantErr: "ui.colors.ok"},
+		{name: "out of range", ui: UIConfig{Colors: map[string]string{"error": "256"}}, wantErr: "ui.colors.error"},
+		{name: "named color", ui: UIConfig{Colors: map[string]string{"error": "red"}}, wantErr: "ui.colors.error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig(&Config{UI: tt.ui})
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("expected %s error, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
 func TestValidateConfig_EmptyProject(t *testing.T) {
 	cfg := &Config{
 		Repositories: []Repository{
@@ -0,0 +1,124 @@
+package downloader
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/tierone/harbormaster/pkg/errcode"
+)
+
+// gitFailures maps fragments of git's error output to error codes. Checked
+// in order; matching is case-insensitive.
+var gitFailures = []struct {
+	code      errcode.Code
+	fragments []string
+}{
+	{errcode.AuthFailed, []string{
+		"authentication failed",
+		"could not read username",
+		"could not read password",
+		"permission denied (publickey",
+		"terminal prompts disabled",
+		"http basic: access denied",
+		"returned error: 401",
+		"returned error: 403",
+	}},
+	{errcode.RemoteNotFound, []string{
+		"repository not found",
+		"does not appear to be a git repository",
+		"' does not exist",
+		"returned error: 404",
+	}},
+	{errcode.RefNotFound, []string{
+		"not found in upstream",
+		"couldn't find remote ref",
+		"did not match any file(s) known to git",
+		"unknown revision",
+		"reference is not a tree",
+		"ref not found on remote",
+	}},
+	{errcode.NetworkError, []string{
+		"could not resolve host",
+		"connection refused",
+		"connection timed out",
+		"operation timed out",
+		"network is unreachable",
+		"failed to connect",
+		"ssl certificate problem",
+		"connection reset",
+	}},
+}
+
+// gitError attaches the code matching git's output to err, or fallback if
+// nothing matches.
+func gitError(fallback errcode.Code, output string, err error) error {
+	lower := strings.ToLower(output)
+	for _, f := range gitFailures {
+		for _, fragment := range f.fragments {
+			if strings.Contains(lower, fragment) {
+				return errcode.Wrap(f.code, err)
+			}
+		}
+	}
+	return errcode.Wrap(fallback, err)
+}
+
+// httpStatusCode returns the error code for an unsuccessful HTTP status.
+func httpStatusCode(status int) errcode.Code {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return errcode.AuthFailed
+	case http.StatusNotFound, http.StatusGone:
+		return errcode.RemoteNotFound
+	default:
+		return errcode.DownloadFailed
+	}
+}
+
+// lastLines keeps the last few lines of a command's output so that a
+// failure can be explained and classified.
+type lastLines struct {
+	lines []string
+	max   int
+}
+
+func newLastLines(max int) *lastLines {
+	return &lastLines{max: max}
+}
+
+func (l *lastLines) add(line string) {
+	l.lines = append(l.lines, line)
+	if len(l.lines) > l.max {
+		l.lines = l.lines[1:]
+	}
+}
+
+// String returns the kept lines joined by newlines.
+func (l *lastLines) String() string {
+	return strings.Join(l.lines, "\n")
+}
+
+// last returns the final kept line, which is where git reports the
+// reason for a failure.
+func (l *lastLines) last() string {
+	if len(l.lines) == 0 {
+		return ""
+	}
+	return l.lines[len(l.lines)-1]
+}
+
+// withDetail wraps err with msg and, if present, the line git printed to
+// explain the failure.
+func withDetail(msg string, err error, detail string) error {
+	if detail == "" {
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+	return fmt.Errorf("%s: %w: %s", msg, err, detail)
+}
+
+// lastLine returns the last non-empty line of s.
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
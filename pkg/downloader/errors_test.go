@@ -0,0 +1,105 @@
+package downloader
+
+import (
+	"errors"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/errcode"
+)
+
+func TestGitError(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected errcode.Code
+	}{
+		{"fatal: Authentication failed for 'https://example.com/repo.git/'", errcode.AuthFailed},
+		{"fatal: could not read Username for 'https://github.com': terminal prompts disabled", errcode.AuthFailed},
+		{"git@github.com: Permission denied (publickey).", errcode.AuthFailed},
+		{"remote: Repository not found.\nfatal: repository 'https://github.com/x/y.git/' not found", errcode.RemoteNotFound},
+		{"fatal: '/tmp/missing' does not appear to be a git repository", errcode.RemoteNotFound},
+		{"warning: Could not find remote branch nope to clone.\nfatal: Remote branch nope not found in upstream origin", errcode.RefNotFound},
+		{"error: pathspec 'v9' did not match any file(s) known to git", errcode.RefNotFound},
+		{"fatal: unable to access 'https://example.invalid/': Could not resolve host: example.invalid", errcode.NetworkError},
+		{"fatal: something unexpected", errcode.CloneFailed},
+		{"", errcode.CloneFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.expected), func(t *testing.T) {
+			base := errors.New("exit status 128")
+			err := gitError(errcode.CloneFailed, tt.output, base)
+			if got := errcode.Of(err); got != tt.expected {
+				t.Errorf("expected %s for %q, got %s", tt.expected, tt.output, got)
+			}
+			if !errors.Is(err, base) {
+				t.Error("expected original error to be wrapped")
+			}
+		})
+	}
+}
+
+func TestHTTPStatusCode(t *testing.T) {
+	tests := []struct {
+		status   int
+		expected errcode.Code
+	}{
+		{http.StatusUnauthorized, errcode.AuthFailed},
+		{http.StatusForbidden, errcode.AuthFailed},
+		{http.StatusNotFound, errcode.RemoteNotFound},
+		{http.StatusInternalServerError, errcode.DownloadFailed},
+	}
+
+	for _, tt := range tests {
+		if got := httpStatusCode(tt.status); got != tt.expected {
+			t.Errorf("%d: expected %s, got %s", tt.status, tt.expected, got)
+		}
+	}
+}
+
+func TestGitDownloader_ErrorCodes(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	sourceRepo := setupTestGitRepo(t)
+
+	tests := []struct {
+		name     string
+		source   string
+		opts     Options
+		expected errcode.Code
+	}{
+		{"missing repository", filepath.Join(t.TempDir(), "missing"), Options{}, errcode.RemoteNotFound},
+		{"missing branch", sourceRepo, Options{Branch: "no-such-branch"}, errcode.RefNotFound},
+		{"missing tag", sourceRepo, Options{Tag: "v9.9.9"}, errcode.RefNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Timeout = 30 * time.Second
+			dl := NewGitDownloader(tt.opts)
+
+			_, progress, err := dl.DownloadWithProgress(tt.source, filepath.Join(t.TempDir(), "dest"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var failure error
+			for update := range progress {
+				if update.Error != nil {
+					failure = update.Error
+				}
+			}
+
+			if failure == nil {
+				t.Fatal("expected download to fail")
+			}
+			if got := errcode.Of(failure); got != tt.expected {
+				t.Errorf("expected %s, got %s (%v)", tt.expected, got, failure)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package downloader
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -14,6 +15,8 @@ func New(repoType config.RepositoryType, opts Options) (Downloader, error) {
 		return NewGitDownloader(opts), nil
 	case config.RepoTypeHTTP:
 		return NewHTTPDownloader(opts), nil
+	case config.RepoTypePath:
+		return NewPathDownloader(opts), nil
 	default:
 		return nil, fmt.Errorf("unknown repository type: %s", repoType)
 	}
@@ -21,31 +24,70 @@ func New(repoType config.RepositoryType, opts Options) (Downloader, error) {
 
 // NewFromRepository creates a Downloader from a repository configuration.
 func NewFromRepository(repo *config.Repository, cfg *config.Config) (Downloader, error) {
-	opts := Options{
-		Branch:        repo.Branch,
-		Tag:           repo.Tag,
-		Commit:        repo.Commit,
-		Depth:         repo.GetDepth(cfg.Git.CloneDepth),
-		Shallow:       repo.IsShallow(cfg.Git.ShallowClone),
-		Submodules:    repo.HasSubmodules(cfg.General.RecurseSubmodule),
-		UserAgent:     cfg.HTTP.UserAgent,
-		RetryAttempts: cfg.HTTP.RetryAttempts,
-		RetryDelay:    cfg.HTTP.RetryDelay,
-		Timeout:       cfg.General.Timeout,
-	}
+	return NewFromRepositoryContext(context.Background(), repo, cfg)
+}
 
+// NewFromRepositoryContext is like NewFromRepository, but operations of the
+// returned Downloader are canceled when ctx is done.
+func NewFromRepositoryContext(ctx context.Context, repo *config.Repository, cfg *config.Config) (Downloader, error) {
+	opts := OptionsFromRepository(repo, cfg)
+	opts.Context = ctx
 	return New(repo.Type, opts)
 }
 
+// OptionsFromRepository returns the downloader options for a repository.
+func OptionsFromRepository(repo *config.Repository, cfg *config.Config) Options {
+	asOf, _ := repo.AsOfTime() // Validated with the config
+	opts := Options{
+		Branch:            repo.Branch,
+		Tag:               repo.Tag,
+		Commit:            repo.Commit,
+		AsOf:              asOf,
+		Refspecs:          repo.Refspecs,
+		Subdir:            repo.Subdir,
+		LocalBranch:       repo.LocalBranch(),
+		Depth:             repo.GetDepth(cfg.Git.CloneDepth),
+		Shallow:           repo.IsShallow(cfg.Git.ShallowClone),
+		Submodules:        repo.HasSubmodules(cfg.General.RecurseSubmodule),
+		SubmoduleDepth:    repo.SubmoduleDepth,
+		IncludeSubmodules: repo.IncludeSubmodules,
+		ExcludeSubmodules: repo.ExcludeSubmodules,
+		Symlink:           repo.Symlink,
+		GitRetryAttempts:  cfg.Git.RetryAttempts,
+		GitRetryDelay:     cfg.Git.RetryDelay,
+		UserAgent:         cfg.HTTP.UserAgent,
+		RetryAttempts:     cfg.HTTP.RetryAttempts,
+		RetryDelay:        cfg.HTTP.RetryDelay,
+		Timeout:           cfg.General.Timeout,
+		LowPriority:       cfg.General.LowPriority,
+	}
+	if repo.CustomRef() {
+		opts.Ref = repo.Ref
+	}
+	if repo.Type == config.RepoTypeGit {
+		// Fetch from mirrors, keeping the canonical URL as origin, also
+		// when git is run in the checkout or its submodules by hand
+		opts.GitConfig = cfg.MirrorRewrites()
+		opts.CloneConfig = opts.GitConfig
+	}
+	return opts
+}
+
 // DetectType attempts to detect the repository type from the URL.
 func DetectType(url string) config.RepositoryType {
-	// Git URLs
-	if strings.HasPrefix(url, "git@") ||
+	// Git URLs, including Azure DevOps' (dev.azure.com/org/project/_git/app)
+	// and Bitbucket Server's (/scm/project/app.git), which may lack .git
+	if config.IsSCPLikeURL(url) ||
 		strings.HasPrefix(url, "git://") ||
+		strings.HasPrefix(url, "ssh://") ||
 		strings.HasSuffix(url, ".git") ||
+		strings.Contains(url, "/_git/") ||
+		strings.Contains(url, "/scm/") ||
 		strings.Contains(url, "github.com") ||
 		strings.Contains(url, "gitlab.com") ||
-		strings.Contains(url, "bitbucket.org") {
+		strings.Contains(url, "bitbucket.org") ||
+		strings.Contains(url, "dev.azure.com") ||
+		strings.Contains(url, ".visualstudio.com") {
 		return config.RepoTypeGit
 	}
 
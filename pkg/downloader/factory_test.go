@@ -105,6 +105,14 @@ func TestDetectType(t *testing.T) {
 		{"https://github.com/user/repo", config.RepoTypeGit},
 		{"https://gitlab.com/user/repo.git", config.RepoTypeGit},
 		{"https://bitbucket.org/user/repo.git", config.RepoTypeGit},
+		{"https://bob@bitbucket.org/team/repo.git", config.RepoTypeGit},
+		{"https://git.example.com/scm/proj/repo", config.RepoTypeGit},
+		{"https://dev.azure.com/org/project/_git/repo", config.RepoTypeGit},
+		{"https://org.visualstudio.com/project/_git/repo", config.RepoTypeGit},
+		{"https://tfs.example.com/tfs/DefaultCollection/project/_git/repo", config.RepoTypeGit},
+		{"git@ssh.dev.azure.com:v3/org/project/repo", config.RepoTypeGit},
+		{"org@vs-ssh.visualstudio.com:v3/org/project/repo", config.RepoTypeGit},
+		{"ssh://git@bitbucket.example.com:7999/proj/repo.git", config.RepoTypeGit},
 
 		// HTTP URLs (non-git hosts)
 		{"https://example.com/file.tar.gz", config.RepoTypeHTTP},
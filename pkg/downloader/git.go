@@ -3,13 +3,21 @@ package downloader
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/tierone/harbormaster/pkg/errcode"
 	"github.com/tierone/harbormaster/pkg/types"
 )
 
@@ -47,20 +55,46 @@ func (g *GitDownloader) Download(source, destination string) (string, error) {
 		args = append(args, "--single-branch")
 	}
 
-	if g.options.Submodules {
+	if g.options.Subdir != "" {
+		args = append(args, "--filter=blob:none", "--sparse")
+	}
+
+	if g.options.Reference != "" {
+		args = append(args, "--reference-if-able", g.options.Reference)
+	}
+
+	if g.options.Submodules && !g.filtersSubmodules() {
 		args = append(args, "--recurse-submodules")
 	}
 
 	args = append(args, source, destination)
 
-	cmd := exec.Command("git", args...)
-	output, err := cmd.CombinedOutput()
+	output, err := g.retry("clone", source, nil, func() (string, error) {
+		output, err := g.combinedOutput(g.command("", args...))
+		return string(output), err
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to clone: %w\n%s", err, string(output))
+		return "", gitError(errcode.CloneFailed, output, fmt.Errorf("failed to clone: %w\n%s", err, output))
+	}
+
+	if err := g.sparseCheckout(destination); err != nil {
+		return "", err
+	}
+
+	if err := g.fetchRefspecs(destination); err != nil {
+		return "", err
 	}
 
 	// Checkout specific ref if needed
-	if err := g.checkoutRef(destination); err != nil {
+	if err := g.checkoutRef(destination, true); err != nil {
+		return "", err
+	}
+
+	if err := g.updateSubmodules(destination, "", 1, nil); err != nil {
+		return "", err
+	}
+
+	if err := g.writeCloneConfig(destination); err != nil {
 		return "", err
 	}
 
@@ -92,7 +126,15 @@ func (g *GitDownloader) DownloadWithProgress(source, destination string) (string
 			args = append(args, "--single-branch")
 		}
 
-		if g.options.Submodules {
+		if g.options.Subdir != "" {
+			args = append(args, "--filter=blob:none", "--sparse")
+		}
+
+		if g.options.Reference != "" {
+			args = append(args, "--reference-if-able", g.options.Reference)
+		}
+
+		if g.options.Submodules && !g.filtersSubmodules() {
 			args = append(args, "--recurse-submodules")
 		}
 
@@ -103,66 +145,43 @@ func (g *GitDownloader) DownloadWithProgress(source, destination string) (string
 			Message: "Cloning repository...",
 		}
 
-		cmd := exec.Command("git", args...)
-
-		// Git outputs progress to stderr
-		stderr, err := cmd.StderrPipe()
+		tail, err := g.retryWithProgress("clone", source, progress, func() *exec.Cmd {
+			return g.command("", args...)
+		})
 		if err != nil {
+			if tail != nil {
+				err = gitError(errcode.CloneFailed, tail.String(), withDetail("clone failed", err, tail.last()))
+			}
 			progress <- types.ProgressUpdate{
 				Phase: types.PhaseFailed,
-				Error: fmt.Errorf("failed to create pipe: %w", err),
+				Error: err,
 			}
 			return
 		}
 
-		if err := cmd.Start(); err != nil {
+		if err := g.sparseCheckout(destination); err != nil {
 			progress <- types.ProgressUpdate{
 				Phase: types.PhaseFailed,
-				Error: fmt.Errorf("failed to start git: %w", err),
+				Error: err,
 			}
 			return
 		}
 
-		// Parse progress from stderr
-		scanner := bufio.NewScanner(stderr)
-		scanner.Split(scanGitProgress)
-		for scanner.Scan() {
-			line := scanner.Text()
-			if line == "" {
-				continue
-			}
-
-			update := types.ProgressUpdate{
-				Phase:   types.PhaseFetching,
-				Message: line,
-			}
-
-			if pct := extractPercentage(line); pct >= 0 {
-				update.BytesDone = int64(pct)
-				update.BytesTotal = 100
-			}
-
-			select {
-			case progress <- update:
-			default:
-			}
-		}
-
-		if err := cmd.Wait(); err != nil {
+		if err := g.fetchRefspecs(destination); err != nil {
 			progress <- types.ProgressUpdate{
 				Phase: types.PhaseFailed,
-				Error: fmt.Errorf("clone failed: %w", err),
+				Error: err,
 			}
 			return
 		}
 
 		// Checkout specific ref if needed
-		if g.options.Commit != "" || g.options.Tag != "" {
+		if g.options.Commit != "" || g.options.Tag != "" || g.options.Ref != "" || !g.options.AsOf.IsZero() || g.localBranch(destination, true) {
 			progress <- types.ProgressUpdate{
 				Phase:   types.PhaseCheckout,
 				Message: "Checking out ref...",
 			}
-			if err := g.checkoutRef(destination); err != nil {
+			if err := g.checkoutRef(destination, true); err != nil {
 				progress <- types.ProgressUpdate{
 					Phase: types.PhaseFailed,
 					Error: err,
@@ -171,6 +190,22 @@ func (g *GitDownloader) DownloadWithProgress(source, destination string) (string
 			}
 		}
 
+		if err := g.updateSubmodules(destination, "", 1, progress); err != nil {
+			progress <- types.ProgressUpdate{
+				Phase: types.PhaseFailed,
+				Error: err,
+			}
+			return
+		}
+
+		if err := g.writeCloneConfig(destination); err != nil {
+			progress <- types.ProgressUpdate{
+				Phase: types.PhaseFailed,
+				Error: err,
+			}
+			return
+		}
+
 		sha, err := g.getHeadSHA(destination)
 		if err != nil {
 			progress <- types.ProgressUpdate{
@@ -192,14 +227,28 @@ func (g *GitDownloader) DownloadWithProgress(source, destination string) (string
 // Update fetches and checks out the latest changes.
 func (g *GitDownloader) Update(destination string) (string, error) {
 	// Fetch from origin
-	cmd := exec.Command("git", "fetch", "--all", "--force")
-	cmd.Dir = destination
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return "", fmt.Errorf("failed to fetch: %w\n%s", err, string(output))
+	output, err := g.retry("fetch", destination, nil, func() (string, error) {
+		output, err := g.combinedOutput(g.command(destination, "fetch", "--all", "--force"))
+		return string(output), err
+	})
+	if err != nil {
+		return "", gitError(errcode.FetchFailed, output, fmt.Errorf("failed to fetch: %w\n%s", err, output))
+	}
+
+	if err := g.fetchRefspecs(destination); err != nil {
+		return "", err
 	}
 
 	// Checkout the requested ref
-	if err := g.checkoutRef(destination); err != nil {
+	if err := g.checkoutRef(destination, false); err != nil {
+		return "", err
+	}
+
+	if err := g.updateSubmodules(destination, "", 1, nil); err != nil {
+		return "", err
+	}
+
+	if err := g.writeCloneConfig(destination); err != nil {
 		return "", err
 	}
 
@@ -218,64 +267,50 @@ func (g *GitDownloader) UpdateWithProgress(destination string) (string, <-chan t
 			Message: "Fetching updates...",
 		}
 
-		cmd := exec.Command("git", "fetch", "--all", "--force", "--progress")
-		cmd.Dir = destination
-
-		stderr, err := cmd.StderrPipe()
+		tail, err := g.retryWithProgress("fetch", destination, progress, func() *exec.Cmd {
+			return g.command(destination, "fetch", "--all", "--force", "--progress")
+		})
 		if err != nil {
+			if tail != nil {
+				err = gitError(errcode.FetchFailed, tail.String(), withDetail("fetch failed", err, tail.last()))
+			}
 			progress <- types.ProgressUpdate{
 				Phase: types.PhaseFailed,
-				Error: fmt.Errorf("failed to create pipe: %w", err),
+				Error: err,
 			}
 			return
 		}
 
-		if err := cmd.Start(); err != nil {
+		if err := g.fetchRefspecs(destination); err != nil {
 			progress <- types.ProgressUpdate{
 				Phase: types.PhaseFailed,
-				Error: fmt.Errorf("failed to start git: %w", err),
+				Error: err,
 			}
 			return
 		}
 
-		scanner := bufio.NewScanner(stderr)
-		scanner.Split(scanGitProgress)
-		for scanner.Scan() {
-			line := scanner.Text()
-			if line == "" {
-				continue
-			}
-
-			update := types.ProgressUpdate{
-				Phase:   types.PhaseFetching,
-				Message: line,
-			}
-
-			if pct := extractPercentage(line); pct >= 0 {
-				update.BytesDone = int64(pct)
-				update.BytesTotal = 100
-			}
-
-			select {
-			case progress <- update:
-			default:
-			}
+		progress <- types.ProgressUpdate{
+			Phase:   types.PhaseCheckout,
+			Message: "Checking out...",
 		}
 
-		if err := cmd.Wait(); err != nil {
+		if err := g.checkoutRef(destination, false); err != nil {
 			progress <- types.ProgressUpdate{
 				Phase: types.PhaseFailed,
-				Error: fmt.Errorf("fetch failed: %w", err),
+				Error: err,
 			}
 			return
 		}
 
-		progress <- types.ProgressUpdate{
-			Phase:   types.PhaseCheckout,
-			Message: "Checking out...",
+		if err := g.updateSubmodules(destination, "", 1, progress); err != nil {
+			progress <- types.ProgressUpdate{
+				Phase: types.PhaseFailed,
+				Error: err,
+			}
+			return
 		}
 
-		if err := g.checkoutRef(destination); err != nil {
+		if err := g.writeCloneConfig(destination); err != nil {
 			progress <- types.ProgressUpdate{
 				Phase: types.PhaseFailed,
 				Error: err,
@@ -306,89 +341,1429 @@ func (g *GitDownloader) GetCurrentRef(destination string) (string, error) {
 	return g.getHeadSHA(destination)
 }
 
-func (g *GitDownloader) checkoutRef(destination string) error {
+// checkoutRef checks out the requested ref in the repository at
+// destination, which was just cloned if cloned is set.
+func (g *GitDownloader) checkoutRef(destination string, cloned bool) error {
 	var ref string
 
+	if g.localBranch(destination, cloned) {
+		return g.checkoutLocalBranch(destination)
+	}
+
 	if g.options.Commit != "" {
 		ref = g.options.Commit
+	} else if g.options.Ref != "" {
+		ref = g.options.Ref
 	} else if g.options.Tag != "" {
 		ref = g.options.Tag
 	} else if g.options.Branch != "" {
 		ref = "origin/" + g.options.Branch
+	} else if !g.options.AsOf.IsZero() {
+		ref = "origin/HEAD"
 	} else {
 		return nil
 	}
 
-	cmd := exec.Command("git", "checkout", "--force", ref)
-	cmd.Dir = destination
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to checkout %s: %w\n%s", ref, err, string(output))
+	// A shallow or single-branch clone may not have the tag or branch yet,
+	// and no clone has other refs; those may also have been force-pushed
+	if g.options.Commit == "" && g.options.Ref != "" {
+		if err := g.fetchRef(destination, g.options.Ref); err != nil {
+			return err
+		}
+	} else if g.options.Commit == "" && g.options.Tag != "" {
+		if err := g.fetchTag(destination, g.options.Tag); err != nil {
+			return err
+		}
+	} else if g.options.Commit == "" && g.options.Branch != "" {
+		if err := g.fetchBranch(destination, g.options.Branch); err != nil {
+			return err
+		}
+	}
+
+	if g.options.Commit == "" && g.options.Tag == "" && !g.options.AsOf.IsZero() {
+		sha, err := g.commitBefore(destination, ref, g.options.AsOf)
+		if err != nil {
+			return err
+		}
+		ref = sha
+	}
+
+	g.options.log().Debug("checking out ref", "path", destination, "ref", ref)
+	if output, err := g.combinedOutput(g.command(destination, "checkout", "--force", ref)); err != nil {
+		return gitError(errcode.CheckoutFailed, string(output), fmt.Errorf("failed to checkout %s: %w\n%s", ref, err, string(output)))
 	}
 
 	return nil
 }
 
-func (g *GitDownloader) getHeadSHA(destination string) (string, error) {
-	cmd := exec.Command("git", "rev-parse", "HEAD")
-	cmd.Dir = destination
-	output, err := cmd.Output()
+// localBranch reports whether checkoutRef checks out a local branch in
+// the repository at destination rather than detaching HEAD: always with
+// LocalBranch, and otherwise when a branch is configured and either the
+// repository was just cloned or HEAD is still on that branch and it can
+// be fast-forwarded, so that a new checkout can be pulled and pushed from
+// for as long as it stays on the branch it was cloned on. A branch that
+// cannot, such as after a force-push upstream, is left as it is.
+func (g *GitDownloader) localBranch(destination string, cloned bool) bool {
+	if g.options.Commit != "" || g.options.Ref != "" || g.options.Tag != "" || !g.options.AsOf.IsZero() {
+		return false
+	}
+	switch {
+	case g.options.LocalBranch:
+		return true
+	case g.options.Branch == "":
+		return false
+	case cloned:
+		return true
+	}
+	head, err := g.HeadBranch(destination)
+	if err != nil || head != g.options.Branch {
+		return false
+	}
+	return g.IsAncestor(destination, "refs/heads/"+head, "refs/remotes/origin/"+head)
+}
+
+// checkoutLocalBranch checks out Branch, or the default branch, as a
+// local branch tracking its remote-tracking branch. The local branch is
+// created or fast-forwarded; one with commits the remote lacks is left
+// alone and reported.
+func (g *GitDownloader) checkoutLocalBranch(destination string) error {
+	branch := g.options.Branch
+	if branch == "" {
+		var err error
+		if branch, err = g.DefaultBranch(destination); err != nil {
+			return err
+		}
+	} else if err := g.fetchBranch(destination, branch); err != nil {
+		return err
+	}
+
+	local, upstream := "refs/heads/"+branch, "refs/remotes/origin/"+branch
+	if _, _, err := g.output(g.command(destination, "rev-parse", "--verify", "--quiet", local)); err == nil {
+		if !g.IsAncestor(destination, local, upstream) {
+			return errcode.Wrap(errcode.CheckoutFailed, fmt.Errorf("local branch %s has commits that origin/%s lacks; not fast-forwarding", branch, branch))
+		}
+	}
+
+	g.options.log().Debug("checking out local branch", "path", destination, "branch", branch)
+	if output, err := g.combinedOutput(g.command(destination, "checkout", "--force", "-B", branch, "--track", "origin/"+branch)); err != nil {
+		return gitError(errcode.CheckoutFailed, string(output), fmt.Errorf("failed to checkout %s: %w\n%s", branch, err, string(output)))
+	}
+	return nil
+}
+
+// fetchTag fetches tag from origin unless the repository at destination
+// already has it.
+func (g *GitDownloader) fetchTag(destination, tag string) error {
+	if _, _, err := g.output(g.command(destination, "rev-parse", "--verify", "--quiet", "refs/tags/"+tag)); err == nil {
+		return nil
+	}
+
+	args := []string{"fetch", "--quiet", "--no-tags"}
+	if g.options.Shallow && g.options.Depth > 0 {
+		args = append(args, "--depth", fmt.Sprintf("%d", g.options.Depth))
+	}
+	args = append(args, "origin", "+refs/tags/"+tag+":refs/tags/"+tag)
+
+	stderr, err := g.retry("fetch", destination, nil, func() (string, error) {
+		_, stderr, err := g.output(g.command(destination, args...))
+		return string(stderr), err
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to get HEAD SHA: %w", err)
+		return gitError(errcode.FetchFailed, stderr, withDetail("failed to fetch tag "+tag, err, lastLine(stderr)))
 	}
-	return strings.TrimSpace(string(output)), nil
+	return nil
 }
 
-// scanGitProgress is a split function for bufio.Scanner that handles git's progress output.
-// Git uses \r to update progress lines, so we split on \r and \n.
-func scanGitProgress(data []byte, atEOF bool) (advance int, token []byte, err error) {
-	if atEOF && len(data) == 0 {
-		return 0, nil, nil
+// sparseCheckout limits the working tree of the repository just cloned at
+// destination to the configured subdirectory.
+func (g *GitDownloader) sparseCheckout(destination string) error {
+	if g.options.Subdir == "" {
+		return nil
+	}
+	if output, err := g.combinedOutput(g.command(destination, "sparse-checkout", "set", "--", g.options.Subdir)); err != nil {
+		return withDetail("failed to set up sparse checkout", err, lastLine(string(output)))
 	}
+	return nil
+}
 
-	// Look for \r or \n
-	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
-		return i + 1, data[0:i], nil
+// filtersSubmodules reports whether submodules are limited in depth or
+// by path, in which case they are checked out one level at a time after
+// the clone instead of by git clone itself.
+func (g *GitDownloader) filtersSubmodules() bool {
+	return g.options.SubmoduleDepth > 0 || len(g.options.IncludeSubmodules) > 0 || len(g.options.ExcludeSubmodules) > 0
+}
+
+// updateSubmodules checks out the commits the repository at prefix,
+// relative to the clone at root, records for the submodules the
+// submodule filters select, cloning new ones and fetching moved ones, and
+// theirs in turn down to Options.SubmoduleDepth levels. Level is the
+// nesting level of the submodules at prefix, starting at 1. Progress, if
+// not nil, receives each submodule as a sub-operation.
+func (g *GitDownloader) updateSubmodules(root, prefix string, level int, progress chan<- types.ProgressUpdate) error {
+	if !g.options.Submodules {
+		return nil
 	}
 
-	if atEOF {
-		return len(data), data, nil
+	dir := filepath.Join(root, filepath.FromSlash(prefix))
+	output, _, err := g.output(g.command(dir, "ls-files", "--stage"))
+	if err != nil {
+		return fmt.Errorf("failed to list submodules in %s: %w", dir, err)
+	}
+	var paths []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if mode, p, ok := strings.Cut(line, "\t"); ok && strings.HasPrefix(mode, "160000 ") && g.wantSubmodule(path.Join(prefix, p)) {
+			paths = append(paths, p)
+		}
 	}
 
-	return 0, nil, nil
+	for _, p := range paths {
+		name := path.Join(prefix, p)
+		args := []string{"submodule", "update", "--init"}
+		if g.options.Shallow && g.options.Depth > 0 {
+			args = append(args, "--depth", fmt.Sprintf("%d", g.options.Depth))
+		}
+		if !g.filtersSubmodules() {
+			args = append(args, "--recursive")
+		}
+
+		if progress == nil {
+			args = append(args, "--", p)
+			out, err := g.retry("fetch", dir, nil, func() (string, error) {
+				output, err := g.combinedOutput(g.command(dir, args...))
+				return string(output), err
+			})
+			if err != nil {
+				return gitError(errcode.FetchFailed, out, withDetail(fmt.Sprintf("failed to update submodule %s", name), err, lastLine(out)))
+			}
+		} else {
+			progress <- types.ProgressUpdate{
+				Phase:   types.PhaseFetching,
+				Message: fmt.Sprintf("Updating submodule %s...", name),
+			}
+			args = append(args, "--progress", "--", p)
+			tail, err := g.retryWithProgress("fetch", dir, progress, func() *exec.Cmd {
+				return g.command(dir, args...)
+			})
+			if err != nil {
+				if tail != nil {
+					err = gitError(errcode.FetchFailed, tail.String(), withDetail(fmt.Sprintf("failed to update submodule %s", name), err, tail.last()))
+				}
+				return err
+			}
+		}
+	}
+
+	if !g.filtersSubmodules() || (g.options.SubmoduleDepth > 0 && level >= g.options.SubmoduleDepth) {
+		return nil
+	}
+	for _, p := range paths {
+		if err := g.updateSubmodules(root, path.Join(prefix, p), level+1, progress); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// extractPercentage tries to extract a percentage from git output.
-var percentRegex = regexp.MustCompile(`(\d+)%`)
+// wantSubmodule reports whether the submodule at p, relative to the top
+// of the clone, is inside Options.Subdir, if any, and passes
+// Options.IncludeSubmodules and ExcludeSubmodules.
+func (g *GitDownloader) wantSubmodule(p string) bool {
+	if g.options.Subdir != "" && !matchSubmodule([]string{path.Clean(g.options.Subdir)}, p) {
+		return false // Outside the sparse checkout
+	}
+	if matchSubmodule(g.options.ExcludeSubmodules, p) {
+		return false
+	}
+	return len(g.options.IncludeSubmodules) == 0 || matchSubmodule(g.options.IncludeSubmodules, p)
+}
 
-func extractPercentage(s string) int {
-	matches := percentRegex.FindStringSubmatch(s)
-	if len(matches) >= 2 {
-		var pct int
-		_, _ = fmt.Sscanf(matches[1], "%d", &pct)
-		return pct
+// matchSubmodule reports whether any of patterns matches the submodule
+// path p or a directory containing it.
+func matchSubmodule(patterns []string, p string) bool {
+	for ; p != "." && p != "/"; p = path.Dir(p) {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(strings.TrimSuffix(pattern, "/"), p); ok {
+				return true
+			}
+		}
 	}
-	return -1
+	return false
 }
 
-// IsGitRepository returns true if the path is a git repository.
-func IsGitRepository(path string) bool {
-	gitDir := filepath.Join(path, ".git")
-	info, err := os.Stat(gitDir)
+// CloneConfigFile is the file, in the git directory of a clone and of
+// each of its submodules, holding Options.CloneConfig. The config of the
+// git directory includes it.
+const CloneConfigFile = "harbormaster.config"
+
+// writeCloneConfig writes Options.CloneConfig to the git directories of
+// the repository at destination and its submodules, replacing what was
+// written before.
+func (g *GitDownloader) writeCloneConfig(destination string) error {
+	gitDir := filepath.Join(destination, ".git")
+	for _, gitDir := range append([]string{gitDir}, submoduleGitDirs(filepath.Join(gitDir, "modules"))...) {
+		file := filepath.Join(gitDir, CloneConfigFile)
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to replace clone config: %w", err)
+		}
+		if len(g.options.CloneConfig) == 0 {
+			continue
+		}
+		for _, setting := range g.options.CloneConfig {
+			key, value, _ := strings.Cut(setting, "=")
+			if output, err := g.combinedOutput(g.command("", "config", "--file", file, "--add", key, value)); err != nil {
+				return withDetail("failed to write clone config", err, lastLine(string(output)))
+			}
+		}
+		config := filepath.Join(gitDir, "config")
+		if output, _, _ := g.output(g.command("", "config", "--file", config, "--get-all", "include.path")); slices.Contains(strings.Fields(string(output)), CloneConfigFile) {
+			continue
+		}
+		if output, err := g.combinedOutput(g.command("", "config", "--file", config, "--add", "include.path", CloneConfigFile)); err != nil {
+			return withDetail("failed to write clone config", err, lastLine(string(output)))
+		}
+	}
+	return nil
+}
+
+// submoduleGitDirs returns the git directories of submodules kept under
+// the modules directory of a git directory, including nested ones.
+// Submodule names may contain slashes, nesting their git directories.
+func submoduleGitDirs(modules string) []string {
+	entries, err := os.ReadDir(modules)
 	if err != nil {
-		return false
+		return nil
 	}
-	return info.IsDir()
+	var dirs []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		p := filepath.Join(modules, e.Name())
+		if Exists(filepath.Join(p, "HEAD")) && Exists(filepath.Join(p, "objects")) {
+			dirs = append(dirs, p)
+			dirs = append(dirs, submoduleGitDirs(filepath.Join(p, "modules"))...)
+		} else {
+			dirs = append(dirs, submoduleGitDirs(p)...)
+		}
+	}
+	return dirs
 }
 
-// GetRemoteURL returns the origin remote URL of a git repository.
-func GetRemoteURL(path string) (string, error) {
-	cmd := exec.Command("git", "remote", "get-url", "origin")
-	cmd.Dir = path
-	output, err := cmd.Output()
+// fetchRefspecs fetches the extra refspecs configured for the
+// repository from origin, which neither clones nor fetches of the
+// remote's branches include.
+func (g *GitDownloader) fetchRefspecs(destination string) error {
+	if len(g.options.Refspecs) == 0 {
+		return nil
+	}
+
+	args := []string{"fetch", "--quiet", "--no-tags"}
+	if g.options.Shallow && g.options.Depth > 0 {
+		args = append(args, "--depth", fmt.Sprintf("%d", g.options.Depth))
+	}
+	args = append(args, "origin")
+	args = append(args, g.options.Refspecs...)
+
+	stderr, err := g.retry("fetch", destination, nil, func() (string, error) {
+		_, stderr, err := g.output(g.command(destination, args...))
+		return string(stderr), err
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to get remote URL: %w", err)
+		return gitError(errcode.FetchFailed, stderr, withDetail("failed to fetch refspecs", err, lastLine(stderr)))
 	}
-	return strings.TrimSpace(string(output)), nil
+	return nil
+}
+
+// fetchRef fetches ref from origin into the same ref name in the
+// repository at destination, replacing what it pointed to before.
+func (g *GitDownloader) fetchRef(destination, ref string) error {
+	args := []string{"fetch", "--quiet", "--no-tags"}
+	if g.options.Shallow && g.options.Depth > 0 {
+		args = append(args, "--depth", fmt.Sprintf("%d", g.options.Depth))
+	}
+	args = append(args, "origin", "+"+ref+":"+ref)
+
+	stderr, err := g.retry("fetch", destination, nil, func() (string, error) {
+		_, stderr, err := g.output(g.command(destination, args...))
+		return string(stderr), err
+	})
+	if err != nil {
+		return gitError(errcode.FetchFailed, stderr, withDetail("failed to fetch "+ref, err, lastLine(stderr)))
+	}
+	return nil
+}
+
+// fetchBranch fetches branch from origin unless the repository at
+// destination already has its remote-tracking branch. The branch is added
+// to the branches origin fetches, so later updates include it even in a
+// single-branch clone.
+func (g *GitDownloader) fetchBranch(destination, branch string) error {
+	if _, _, err := g.output(g.command(destination, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+branch)); err == nil {
+		return nil
+	}
+
+	args := []string{"fetch", "--quiet"}
+	if g.options.Shallow && g.options.Depth > 0 {
+		args = append(args, "--depth", fmt.Sprintf("%d", g.options.Depth))
+	}
+	args = append(args, "origin", "+refs/heads/"+branch+":refs/remotes/origin/"+branch)
+
+	stderr, err := g.retry("fetch", destination, nil, func() (string, error) {
+		_, stderr, err := g.output(g.command(destination, args...))
+		return string(stderr), err
+	})
+	if err != nil {
+		return gitError(errcode.FetchFailed, stderr, withDetail("failed to fetch branch "+branch, err, lastLine(stderr)))
+	}
+
+	// Only track branches that exist, or every later fetch would fail
+	if output, err := g.combinedOutput(g.command(destination, "remote", "set-branches", "--add", "origin", branch)); err != nil {
+		return withDetail("failed to track branch "+branch, err, lastLine(string(output)))
+	}
+	return nil
+}
+
+// UpdateMirror clones url into a bare repository at path, or fetches its
+// branches and tags again if path already holds one, pruning those removed
+// from the remote. Extra refs, such as refs/pull/123/head, are fetched
+// under the same name. Checkouts of the same remote can then fetch from
+// the mirror instead of the network.
+func (g *GitDownloader) UpdateMirror(url, path string, refs ...string) error {
+	dir, args := "", []string{"clone", "--bare", "--quiet", url, path}
+	if Exists(path) {
+		dir, args = path, []string{"fetch", "--prune", "--quiet", url, "+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*"}
+	}
+
+	stderr, err := g.retry(args[0], url, nil, func() (string, error) {
+		_, stderr, err := g.output(g.command(dir, args...))
+		return string(stderr), err
+	})
+	if err != nil {
+		code := errcode.FetchFailed
+		if dir == "" {
+			code = errcode.CloneFailed
+		}
+		return gitError(code, stderr, withDetail("failed to update shared clone of "+url, err, lastLine(stderr)))
+	}
+
+	if len(refs) == 0 {
+		return nil
+	}
+	args = []string{"fetch", "--quiet", "--no-tags", url}
+	for _, ref := range refs {
+		args = append(args, "+"+ref+":"+ref)
+	}
+	stderr, err = g.retry("fetch", url, nil, func() (string, error) {
+		_, stderr, err := g.output(g.command(path, args...))
+		return string(stderr), err
+	})
+	if err != nil {
+		return gitError(errcode.FetchFailed, stderr, withDetail("failed to fetch "+strings.Join(refs, ", "), err, lastLine(stderr)))
+	}
+	return nil
+}
+
+// CreateBundle writes a git bundle to file holding the history of sha in
+// the repository at source, with HEAD detached at sha and each of refs
+// pointing at the object its value names in source, such as sha or an
+// annotated tag of it. The bundle is built in a scratch repository
+// borrowing the objects of source, whose own refs are left alone.
+func (g *GitDownloader) CreateBundle(source, sha string, refs map[string]string, file string) error {
+	scratch, err := os.MkdirTemp("", "hm-bundle-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(scratch) }()
+
+	run := func(args ...string) error {
+		output, err := g.combinedOutput(g.command(scratch, args...))
+		if err != nil {
+			return withDetail("failed to create bundle", err, lastLine(string(output)))
+		}
+		return nil
+	}
+
+	if err := run("init", "--bare", "--quiet"); err != nil {
+		return err
+	}
+	objects := filepath.Join(source, "objects")
+	if Exists(filepath.Join(source, ".git")) {
+		objects = filepath.Join(source, ".git", "objects")
+	}
+	if err := os.WriteFile(filepath.Join(scratch, "objects", "info", "alternates"), []byte(objects+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+
+	if err := run("update-ref", "--no-deref", "HEAD", sha); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(refs))
+	for name, rev := range refs {
+		output, _, err := g.output(g.command(source, "rev-parse", "--verify", "--quiet", rev))
+		if err != nil {
+			return errcode.Wrap(errcode.RefNotFound, fmt.Errorf("failed to create bundle: %s not found", rev))
+		}
+		if err := run("update-ref", name, strings.TrimSpace(string(output))); err != nil {
+			return err
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	return run(append([]string{"bundle", "create", "--quiet", abs, "HEAD"}, names...)...)
+}
+
+// commitBefore returns the last commit on ref committed before t,
+// following first parents so that commits merged later don't count.
+// A shallow repository is unshallowed first.
+func (g *GitDownloader) commitBefore(destination, ref string, t time.Time) (string, error) {
+	if shallow, err := g.IsShallow(destination); err != nil {
+		return "", err
+	} else if shallow {
+		stderr, err := g.retry("fetch", destination, nil, func() (string, error) {
+			_, stderr, err := g.output(g.command(destination, "fetch", "--quiet", "--unshallow", "origin"))
+			return string(stderr), err
+		})
+		if err != nil {
+			return "", gitError(errcode.FetchFailed, stderr, withDetail("failed to fetch history", err, lastLine(stderr)))
+		}
+	}
+
+	output, stderr, err := g.output(g.command(destination, "rev-list", "-1", "--first-parent", fmt.Sprintf("--before=%d", t.Unix()), ref))
+	if err != nil {
+		return "", withDetail("failed to find commit before "+t.Format(time.RFC3339), err, lastLine(string(stderr)))
+	}
+	sha := strings.TrimSpace(string(output))
+	if sha == "" {
+		return "", errcode.Wrap(errcode.RefNotFound, fmt.Errorf("no commit on %s before %s", strings.TrimPrefix(ref, "origin/"), t.Format(time.RFC3339)))
+	}
+	return sha, nil
+}
+
+func (g *GitDownloader) getHeadSHA(destination string) (string, error) {
+	output, _, err := g.output(g.command(destination, "rev-parse", "HEAD"))
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD SHA: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// retryWithProgress runs the command made by newCmd with retries,
+// forwarding git's progress and announcing each retry on progress. It
+// returns the last lines git printed besides progress, or nil if the
+// command could not be started.
+func (g *GitDownloader) retryWithProgress(op, target string, progress chan<- types.ProgressUpdate, newCmd func() *exec.Cmd) (*lastLines, error) {
+	var tail *lastLines
+	notify := func(attempt int, delay time.Duration) {
+		progress <- types.ProgressUpdate{
+			Phase:   types.PhaseConnecting,
+			Message: fmt.Sprintf("Retrying %s (%d/%d) in %s...", op, attempt, g.options.GitRetryAttempts, delay.Round(100*time.Millisecond)),
+		}
+	}
+	_, err := g.retry(op, target, notify, func() (string, error) {
+		var err error
+		tail, err = g.runWithProgress(newCmd(), progress)
+		if tail == nil {
+			return "", err
+		}
+		return tail.String(), err
+	})
+	return tail, err
+}
+
+// runWithProgress runs cmd, forwarding the progress git reports on
+// stderr. It returns the last lines git printed besides progress, or nil
+// if the command could not be started.
+func (g *GitDownloader) runWithProgress(cmd *exec.Cmd, progress chan<- types.ProgressUpdate) (*lastLines, error) {
+	// Git outputs progress to stderr
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pipe: %w", err)
+	}
+
+	start := g.begin(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start git: %w", err)
+	}
+
+	scanner := bufio.NewScanner(g.tee(stderr))
+	scanner.Split(scanGitProgress)
+	tail := newLastLines(10)
+	sender := newProgressSender(progress)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		update := types.ProgressUpdate{
+			Phase:   types.PhaseFetching,
+			Message: line,
+		}
+
+		if !parseGitProgress(line, &update) {
+			tail.add(line)
+		}
+		sender.send(update)
+	}
+	sender.flush()
+
+	err = cmd.Wait()
+	g.end(cmd, start, err)
+	return tail, err
+}
+
+// command creates a git command that runs in dir and is canceled with the
+// downloader's context. With LowPriority, it runs with reduced priority.
+func (g *GitDownloader) command(dir string, args ...string) *exec.Cmd {
+	if len(g.options.GitConfig) > 0 {
+		config := make([]string, 0, 2*len(g.options.GitConfig)+len(args))
+		for _, kv := range g.options.GitConfig {
+			config = append(config, "-c", kv)
+		}
+		args = append(config, args...)
+	}
+	var env []string
+	if g.options.Token != "" && g.options.TokenHost != "" {
+		// Only the helper replacing the user's ones is on the command
+		// line; it reads the token from the environment
+		helper := "credential.https://" + g.options.TokenHost + ".helper"
+		args = append([]string{"-c", helper + "=", "-c", helper + "=" + tokenHelper}, args...)
+		user := g.options.TokenUser
+		if user == "" {
+			user = "x-access-token"
+		}
+		env = append(env, tokenEnv+"="+g.options.Token, tokenUserEnv+"="+user)
+	}
+	cmd := exec.CommandContext(g.options.ctx(), "git", args...)
+	cmd.Dir = dir
+	if g.options.KnownHostsFile != "" {
+		env = append(env, "GIT_SSH_COMMAND="+sshCommand(g.options.KnownHostsFile))
+	}
+	if g.options.NoTerminalPrompt {
+		env = append(env, "GIT_TERMINAL_PROMPT=0")
+	}
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	if g.options.LowPriority {
+		lowerPriority(cmd)
+	}
+	return cmd
+}
+
+// Environment variables tokenHelper reads the token and its user from,
+// which may come from URLs and must not be interpreted by the shell.
+const (
+	tokenEnv     = "HARBORMASTER_GIT_TOKEN"
+	tokenUserEnv = "HARBORMASTER_GIT_USER"
+)
+
+// tokenHelper is a git credential helper answering every request with
+// the user and token in the environment.
+const tokenHelper = `!f() { test "$1" = get && echo "username=$` + tokenUserEnv + `" && echo "password=$` + tokenEnv + `"; }; f`
+
+// combinedOutput runs cmd and logs it, returning stdout and stderr.
+func (g *GitDownloader) combinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	start := g.begin(cmd)
+	output, err := cmd.CombinedOutput()
+	g.capture(output)
+	g.end(cmd, start, err)
+	return output, err
+}
+
+// output runs cmd and logs it, returning stdout and stderr separately.
+// Stderr is also copied to the output writer, if any.
+func (g *GitDownloader) output(cmd *exec.Cmd) ([]byte, []byte, error) {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if g.options.Output != nil {
+		cmd.Stderr = io.MultiWriter(&stderr, g.options.Output)
+	}
+	start := g.begin(cmd)
+	output, err := cmd.Output()
+	g.capture(output)
+	g.end(cmd, start, err)
+	return output, stderr.Bytes(), err
+}
+
+// capture copies command output to the output writer, if any.
+func (g *GitDownloader) capture(output []byte) {
+	if g.options.Output != nil && len(output) > 0 {
+		_, _ = g.options.Output.Write(output)
+	}
+}
+
+// tee copies everything read from r to the output writer, if any.
+func (g *GitDownloader) tee(r io.Reader) io.Reader {
+	if g.options.Output == nil {
+		return r
+	}
+	return io.TeeReader(r, g.options.Output)
+}
+
+// begin reports that cmd is about to run and returns its start time.
+func (g *GitDownloader) begin(cmd *exec.Cmd) time.Time {
+	g.options.traceStart(cmd)
+	return time.Now()
+}
+
+// end records a finished git command in the diagnostic log and the
+// verbose output.
+func (g *GitDownloader) end(cmd *exec.Cmd, start time.Time, err error) {
+	g.options.traceEnd(cmd, start, err)
+
+	attrs := []any{
+		"args", formatCommand(gitArgs(cmd)),
+		"dir", cmd.Dir,
+		"duration", time.Since(start),
+	}
+	if err != nil {
+		attrs = append(attrs, "error", err)
+	}
+	g.options.log().Debug("git command", attrs...)
+}
+
+// gitArgs returns the arguments passed to git by cmd, skipping any
+// command that lowers its priority.
+func gitArgs(cmd *exec.Cmd) []string {
+	if i := slices.Index(cmd.Args, "git"); i >= 0 {
+		return cmd.Args[i+1:]
+	}
+	return cmd.Args[1:]
+}
+
+// scanGitProgress is a split function for bufio.Scanner that handles git's progress output.
+// Git uses \r to update progress lines, so we split on \r and \n.
+func scanGitProgress(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	// Look for \r or \n
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[0:i], nil
+	}
+
+	if atEOF {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
+
+// extractPercentage tries to extract a percentage from git output.
+var percentRegex = regexp.MustCompile(`(\d+)%`)
+
+func extractPercentage(s string) int {
+	matches := percentRegex.FindStringSubmatch(s)
+	if len(matches) >= 2 {
+		var pct int
+		_, _ = fmt.Sscanf(matches[1], "%d", &pct)
+		return pct
+	}
+	return -1
+}
+
+// transferredRegex matches the amount received in git's progress output,
+// e.g. "Receiving objects:  45% (450/1000), 1.20 MiB | 3.40 MiB/s".
+var transferredRegex = regexp.MustCompile(`, (\d+(?:\.\d+)?) (bytes|KiB|MiB|GiB)`)
+
+// extractTransferred returns the number of bytes received according to a
+// git progress line, or 0 if it does not say.
+func extractTransferred(s string) int64 {
+	matches := transferredRegex.FindStringSubmatch(s)
+	if len(matches) < 3 {
+		return 0
+	}
+	n, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0
+	}
+	switch matches[2] {
+	case "KiB":
+		n *= 1 << 10
+	case "MiB":
+		n *= 1 << 20
+	case "GiB":
+		n *= 1 << 30
+	}
+	return int64(n)
+}
+
+// gitProgressRegex matches a counted stage in git's progress output, e.g.
+// "Receiving objects:  45% (450/1000), 1.20 MiB | 3.40 MiB/s".
+var gitProgressRegex = regexp.MustCompile(`^(?:remote: )?([A-Z][a-z]+ [a-z ]+?):\s+(\d+)% \((\d+)/(\d+)\)`)
+
+// gitStages maps git's progress labels to stages.
+var gitStages = map[string]types.GitStage{
+	"Enumerating objects": types.StageEnumerating,
+	"Counting objects":    types.StageCounting,
+	"Compressing objects": types.StageCompressing,
+	"Receiving objects":   types.StageReceiving,
+	"Resolving deltas":    types.StageResolving,
+	"Updating files":      types.StageUpdating,
+}
+
+// parseGitProgress fills update from a git progress line: the stage, its
+// percentage and counters, and the bytes received. It reports whether the
+// line was progress rather than other output.
+func parseGitProgress(line string, update *types.ProgressUpdate) bool {
+	matches := gitProgressRegex.FindStringSubmatch(line)
+	if matches == nil {
+		// Progress in a form without counters
+		pct := extractPercentage(line)
+		if pct < 0 {
+			return false
+		}
+		update.Percent = float64(pct)
+		return true
+	}
+
+	update.Stage = gitStages[matches[1]]
+	update.Percent, _ = strconv.ParseFloat(matches[2], 64)
+	update.ObjectsDone, _ = strconv.Atoi(matches[3])
+	update.ObjectsTotal, _ = strconv.Atoi(matches[4])
+	update.BytesDone = extractTransferred(line)
+	return true
+}
+
+// IsGitRepository returns true if the path is a git repository.
+func IsGitRepository(path string) bool {
+	gitDir := filepath.Join(path, ".git")
+	info, err := os.Stat(gitDir)
+	if err != nil {
+		return false
+	}
+	return info.IsDir()
+}
+
+// GetRemoteURL returns the origin remote URL of a git repository.
+func GetRemoteURL(path string) (string, error) {
+	cmd := exec.Command("git", "remote", "get-url", "origin")
+	cmd.Dir = path
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get remote URL: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// HasHead returns true if HEAD of the git repository at path resolves to
+// a commit, which it doesn't in a clone interrupted before checking out.
+func HasHead(path string) bool {
+	cmd := exec.Command("git", "--git-dir", filepath.Join(path, ".git"), "rev-parse", "--verify", "--quiet", "HEAD^{commit}")
+	return cmd.Run() == nil
+}
+
+// ConfiguredRemoteURL returns the origin URL as configured in the git
+// repository at path, without the rewrites git applies to it, reading
+// the config even of an incomplete repository.
+func ConfiguredRemoteURL(path string) (string, error) {
+	cmd := exec.Command("git", "config", "--file", filepath.Join(path, ".git", "config"), "--get", "remote.origin.url")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get remote URL: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Alternates returns the object directories the repository at
+// destination borrows objects from, such as those of a clone made with
+// Options.Reference.
+func Alternates(destination string) []string {
+	data, err := os.ReadFile(filepath.Join(destination, ".git", "objects", "info", "alternates"))
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			dirs = append(dirs, line)
+		}
+	}
+	return dirs
+}
+
+// Dissociate copies the objects the repository at destination borrows
+// into it and stops borrowing them, so that the repositories they came
+// from can be removed.
+func (g *GitDownloader) Dissociate(destination string) error {
+	if output, err := g.combinedOutput(g.command(destination, "repack", "-a", "-d", "--quiet")); err != nil {
+		return withDetail("failed to copy borrowed objects", err, lastLine(string(output)))
+	}
+	if err := os.Remove(filepath.Join(destination, ".git", "objects", "info", "alternates")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to stop borrowing objects: %w", err)
+	}
+	return nil
+}
+
+// SetRemoteURL points the origin remote of the repository at destination
+// at url, if it points elsewhere.
+func (g *GitDownloader) SetRemoteURL(destination, url string) error {
+	if current, err := GetRemoteURL(destination); err == nil && current == url {
+		return nil
+	}
+	if output, err := g.combinedOutput(g.command(destination, "remote", "set-url", "origin", url)); err != nil {
+		return withDetail("failed to set remote URL", err, lastLine(string(output)))
+	}
+	return nil
+}
+
+// LsRemote returns the SHA that ref points to on the remote without
+// fetching. An empty ref resolves HEAD. Annotated tags are peeled to the
+// commit they point to.
+func LsRemote(ctx context.Context, url, ref string) (string, error) {
+	return NewGitDownloader(Options{Context: ctx}).LsRemote(url, ref)
+}
+
+// LsRemote is like the package-level LsRemote but runs with the
+// downloader's context, logger, and verbose output.
+func (g *GitDownloader) LsRemote(url, ref string) (string, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	output, stderr, err := g.output(g.command("", "ls-remote", url, ref, ref+"^{}"))
+	if err != nil {
+		return "", gitError(errcode.FetchFailed, string(stderr), withDetail("failed to list remote refs", err, lastLine(string(stderr))))
+	}
+
+	// Prefer a branch, then a peeled tag, then any other match
+	refs := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 {
+			refs[fields[1]] = fields[0]
+		}
+	}
+	for _, name := range []string{"refs/heads/" + ref, "refs/tags/" + ref + "^{}", "refs/tags/" + ref, ref} {
+		if sha, ok := refs[name]; ok {
+			return sha, nil
+		}
+	}
+
+	return "", errcode.Wrap(errcode.RefNotFound, fmt.Errorf("ref not found on remote: %s", ref))
+}
+
+// RemoteTags returns the tags on the remote and the commits they point to,
+// without fetching. Annotated tags are peeled to the commit they point to.
+func (g *GitDownloader) RemoteTags(url string) (map[string]string, error) {
+	output, stderr, err := g.output(g.command("", "ls-remote", "--tags", url))
+	if err != nil {
+		return nil, gitError(errcode.FetchFailed, string(stderr), withDetail("failed to list remote tags", err, lastLine(string(stderr))))
+	}
+
+	tags := make(map[string]string)
+	peeled := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || !strings.HasPrefix(fields[1], "refs/tags/") {
+			continue
+		}
+		name := strings.TrimPrefix(fields[1], "refs/tags/")
+		if tag, ok := strings.CutSuffix(name, "^{}"); ok {
+			tags[tag] = fields[0]
+			peeled[tag] = true
+		} else if !peeled[name] {
+			tags[name] = fields[0]
+		}
+	}
+	return tags, nil
+}
+
+// RemoteBranches returns the branches on the remote and the commits they
+// point to, without fetching.
+func (g *GitDownloader) RemoteBranches(url string) (map[string]string, error) {
+	output, stderr, err := g.output(g.command("", "ls-remote", "--heads", url))
+	if err != nil {
+		return nil, gitError(errcode.FetchFailed, string(stderr), withDetail("failed to list remote branches", err, lastLine(string(stderr))))
+	}
+
+	branches := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.HasPrefix(fields[1], "refs/heads/") {
+			branches[strings.TrimPrefix(fields[1], "refs/heads/")] = fields[0]
+		}
+	}
+	return branches, nil
+}
+
+// BranchDates returns the committer dates of the tips of branches on the
+// remote. Only the tip commits are fetched, into a temporary repository.
+func (g *GitDownloader) BranchDates(url string, branches []string) (map[string]time.Time, error) {
+	tmp, err := os.MkdirTemp("", "hm-branches-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	if output, err := g.combinedOutput(g.command(tmp, "init", "--bare", "--quiet")); err != nil {
+		return nil, withDetail("failed to create temporary repository", err, lastLine(string(output)))
+	}
+
+	args := []string{"fetch", "--quiet", "--no-tags", "--depth", "1", url}
+	for _, b := range branches {
+		args = append(args, "+refs/heads/"+b+":refs/heads/"+b)
+	}
+	stderr, err := g.retry("fetch", url, nil, func() (string, error) {
+		_, stderr, err := g.output(g.command(tmp, args...))
+		return string(stderr), err
+	})
+	if err != nil {
+		return nil, gitError(errcode.FetchFailed, stderr, withDetail("failed to fetch branch tips", err, lastLine(stderr)))
+	}
+
+	output, errOutput, err := g.output(g.command(tmp, "for-each-ref", "--format=%(committerdate:unix) %(refname:strip=2)", "refs/heads"))
+	if err != nil {
+		return nil, withDetail("failed to read branch dates", err, lastLine(string(errOutput)))
+	}
+	dates := make(map[string]time.Time)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		ts, name, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		if secs, err := strconv.ParseInt(ts, 10, 64); err == nil {
+			dates[name] = time.Unix(secs, 0)
+		}
+	}
+	return dates, nil
+}
+
+// AheadBehind counts the commits that HEAD of the repository at
+// destination has and sha lacks (ahead) and the reverse (behind). If sha
+// is not present locally, ref is fetched from origin first; the working
+// tree is not touched.
+func (g *GitDownloader) AheadBehind(destination, ref, sha string) (ahead, behind int, err error) {
+	if err := g.EnsureCommit(destination, ref, sha); err != nil {
+		return 0, 0, err
+	}
+
+	output, stderr, err := g.output(g.command(destination, "rev-list", "--left-right", "--count", "HEAD..."+sha))
+	if err != nil {
+		return 0, 0, withDetail("failed to compare with remote", err, lastLine(string(stderr)))
+	}
+	if _, err := fmt.Sscanf(string(output), "%d %d", &ahead, &behind); err != nil {
+		return 0, 0, fmt.Errorf("failed to parse commit counts: %w", err)
+	}
+	return ahead, behind, nil
+}
+
+// EnsureCommit fetches ref from origin into the repository at
+// destination unless sha is already present. The working tree is not
+// touched.
+func (g *GitDownloader) EnsureCommit(destination, ref, sha string) error {
+	if g.HasCommit(destination, sha) {
+		return nil
+	}
+	stderr, err := g.retry("fetch", destination, nil, func() (string, error) {
+		_, stderr, err := g.output(g.command(destination, "fetch", "--quiet", "--no-tags", "origin", ref))
+		return string(stderr), err
+	})
+	if err != nil {
+		return gitError(errcode.FetchFailed, stderr, withDetail("fetch failed", err, lastLine(stderr)))
+	}
+	return nil
+}
+
+// VerifySignature checks that commit sha in the repository at destination
+// has a valid signature, using git's configured GPG or SSH trust. A tag
+// pointing at sha, if given, may carry the signature instead. The tag is
+// looked up under refs/tags only, so that a branch of the same name
+// cannot stand in for it.
+func (g *GitDownloader) VerifySignature(destination, sha, tag string) error {
+	if tag != "" {
+		ref := "refs/tags/" + tag
+		if _, _, err := g.output(g.command(destination, "verify-tag", ref)); err == nil {
+			output, _, err := g.output(g.command(destination, "rev-parse", "--verify", "--quiet", ref+"^{commit}"))
+			if err == nil && strings.TrimSpace(string(output)) == sha {
+				return nil
+			}
+		}
+	}
+	if _, stderr, err := g.output(g.command(destination, "verify-commit", sha)); err != nil {
+		return errcode.Wrap(errcode.Unsigned, withDetail("commit "+sha[:min(8, len(sha))]+" has no valid signature", err, lastLine(string(stderr))))
+	}
+	return nil
+}
+
+// HasCommit reports whether the repository at destination has sha.
+func (g *GitDownloader) HasCommit(destination, sha string) bool {
+	_, _, err := g.output(g.command(destination, "cat-file", "-e", sha+"^{commit}"))
+	return err == nil
+}
+
+// ResolveLocal returns the commit that ref names in the repository at
+// destination without contacting the remote. A branch resolves to its
+// remote-tracking branch; tags and commits resolve as themselves.
+func (g *GitDownloader) ResolveLocal(destination, ref string) (string, error) {
+	for _, name := range []string{"refs/remotes/origin/" + ref, "refs/tags/" + ref, ref} {
+		output, _, err := g.output(g.command(destination, "rev-parse", "--verify", "--quiet", name+"^{commit}"))
+		if err == nil {
+			return strings.TrimSpace(string(output)), nil
+		}
+	}
+	return "", errcode.Wrap(errcode.RefNotFound, fmt.Errorf("ref not found locally: %s", ref))
+}
+
+// Commit is a commit as listed by Log and LogSince.
+type Commit struct {
+	SHA     string
+	Author  string
+	Time    time.Time // Committer date
+	Subject string
+}
+
+// commitFormat is the git log format parsed by parseCommits.
+const commitFormat = "--format=%H%x00%an%x00%ct%x00%s"
+
+// parseCommits parses git log output in commitFormat.
+func parseCommits(output []byte) []Commit {
+	var commits []Commit
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.SplitN(line, "\x00", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		c := Commit{SHA: fields[0], Author: fields[1], Subject: fields[3]}
+		if secs, err := strconv.ParseInt(fields[2], 10, 64); err == nil {
+			c.Time = time.Unix(secs, 0)
+		}
+		commits = append(commits, c)
+	}
+	return commits
+}
+
+// Log returns up to limit commits reachable from to but not from, newest
+// first, and how many there are in total.
+func (g *GitDownloader) Log(destination, from, to string, limit int) ([]Commit, int, error) {
+	output, stderr, err := g.output(g.command(destination, "rev-list", "--count", from+".."+to))
+	if err != nil {
+		return nil, 0, withDetail("failed to list commits", err, lastLine(string(stderr)))
+	}
+	total, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse commit count: %w", err)
+	}
+	if total == 0 || limit <= 0 {
+		return nil, total, nil
+	}
+
+	output, stderr, err = g.output(g.command(destination, "log", commitFormat, "-n", strconv.Itoa(limit), from+".."+to))
+	if err != nil {
+		return nil, 0, withDetail("failed to list commits", err, lastLine(string(stderr)))
+	}
+	return parseCommits(output), total, nil
+}
+
+// LogSince returns up to limit commits reachable from rev, newest first.
+// since is passed to git log --since and may be any date git accepts,
+// such as "2024-05-01" or "1 week ago"; empty lists all commits.
+func (g *GitDownloader) LogSince(destination, rev, since string, limit int) ([]Commit, error) {
+	args := []string{"log", commitFormat}
+	if since != "" {
+		args = append(args, "--since="+since)
+	}
+	if limit > 0 {
+		args = append(args, "-n", strconv.Itoa(limit))
+	}
+	args = append(args, rev, "--")
+
+	output, stderr, err := g.output(g.command(destination, args...))
+	if err != nil {
+		return nil, withDetail("failed to list commits", err, lastLine(string(stderr)))
+	}
+	return parseCommits(output), nil
+}
+
+// Committer is the committer of a commit, as listed by Committers.
+type Committer struct {
+	SHA   string
+	Name  string
+	Email string
+}
+
+// Committers returns the committers of the commits reachable from to but
+// not from, newest first. With from empty, only to's committer is
+// returned.
+func (g *GitDownloader) Committers(destination, from, to string) ([]Committer, error) {
+	args := []string{"log", "--format=%H%x00%cn%x00%ce"}
+	if from == "" {
+		args = append(args, "-n", "1", to)
+	} else {
+		args = append(args, from+".."+to)
+	}
+	output, stderr, err := g.output(g.command(destination, append(args, "--")...))
+	if err != nil {
+		return nil, withDetail("failed to list commits", err, lastLine(string(stderr)))
+	}
+
+	var committers []Committer
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if fields := strings.SplitN(line, "\x00", 3); len(fields) == 3 {
+			committers = append(committers, Committer{SHA: fields[0], Name: fields[1], Email: fields[2]})
+		}
+	}
+	return committers, nil
+}
+
+// IsAncestor reports whether commit ancestor is reachable from sha in
+// the repository at destination, or is sha itself.
+func (g *GitDownloader) IsAncestor(destination, ancestor, sha string) bool {
+	_, _, err := g.output(g.command(destination, "merge-base", "--is-ancestor", ancestor, sha))
+	return err == nil
+}
+
+// TrackedFiles returns the paths of the files tracked in the repository
+// at destination, relative to it and slash-separated.
+func (g *GitDownloader) TrackedFiles(destination string) ([]string, error) {
+	output, stderr, err := g.output(g.command(destination, "ls-files", "-z"))
+	if err != nil {
+		return nil, withDetail("failed to list files", err, lastLine(string(stderr)))
+	}
+	var files []string
+	for _, f := range strings.Split(string(output), "\x00") {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// DefaultBranch returns the branch that origin/HEAD points to in the
+// repository at destination, as recorded when it was cloned.
+func (g *GitDownloader) DefaultBranch(destination string) (string, error) {
+	output, _, err := g.output(g.command(destination, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"))
+	if err != nil {
+		return "", errcode.Wrap(errcode.RefNotFound, fmt.Errorf("default branch unknown: origin/HEAD is not set"))
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(output)), "origin/"), nil
+}
+
+// HeadBranch returns the branch HEAD points to in the repository at
+// destination, which for a bare clone is the default branch of its remote.
+func (g *GitDownloader) HeadBranch(destination string) (string, error) {
+	output, _, err := g.output(g.command(destination, "symbolic-ref", "--quiet", "--short", "HEAD"))
+	if err != nil {
+		return "", errcode.Wrap(errcode.RefNotFound, fmt.Errorf("HEAD is detached"))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Restore checks out sha again in the repository at destination, undoing
+// an update that moved the checkout. With a branch, the branch is reset
+// to sha and checked out; otherwise HEAD is detached at sha.
+func (g *GitDownloader) Restore(destination, branch, sha string) error {
+	args := []string{"checkout", "--force", "--quiet", "--detach", sha}
+	if branch != "" {
+		args = []string{"checkout", "--force", "--quiet", "-B", branch, sha}
+	}
+	if _, stderr, err := g.output(g.command(destination, args...)); err != nil {
+		return gitError(errcode.CheckoutFailed, string(stderr), withDetail("failed to restore "+sha[:min(8, len(sha))], err, lastLine(string(stderr))))
+	}
+	return nil
+}
+
+// TagsAt returns the tags pointing at the commit checked out in the
+// repository at destination, in git's order.
+func (g *GitDownloader) TagsAt(destination string) ([]string, error) {
+	output, stderr, err := g.output(g.command(destination, "tag", "--points-at", "HEAD"))
+	if err != nil {
+		return nil, withDetail("failed to list tags", err, lastLine(string(stderr)))
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// MergedBranches returns the local branches of the repository at
+// destination whose tips are reachable from sha.
+func (g *GitDownloader) MergedBranches(destination, sha string) ([]string, error) {
+	output, stderr, err := g.output(g.command(destination, "for-each-ref", "--merged="+sha, "--format=%(refname:short)", "refs/heads/"))
+	if err != nil {
+		return nil, withDetail("failed to list branches", err, lastLine(string(stderr)))
+	}
+	var branches []string
+	for _, b := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if b != "" {
+			branches = append(branches, b)
+		}
+	}
+	return branches, nil
+}
+
+// DeleteBranch deletes a local branch of the repository at destination
+// without checking whether it is merged; callers check that first.
+func (g *GitDownloader) DeleteBranch(destination, branch string) error {
+	if _, stderr, err := g.output(g.command(destination, "branch", "--delete", "--force", "--", branch)); err != nil {
+		return withDetail("failed to delete branch "+branch, err, lastLine(string(stderr)))
+	}
+	return nil
+}
+
+// Fsck checks the objects and refs of the repository at destination
+// with git fsck and returns the problems it reports, such as missing or
+// corrupt objects and broken refs; none if the repository is intact.
+func (g *GitDownloader) Fsck(destination string) ([]string, error) {
+	output, err := g.combinedOutput(g.command(destination, "fsck", "--no-progress", "--no-dangling"))
+	if err == nil {
+		return nil, nil
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return nil, fmt.Errorf("failed to run git fsck: %w", err)
+	}
+
+	var problems []string
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "Checking ") || strings.HasPrefix(line, "notice:") {
+			continue
+		}
+		problems = append(problems, line)
+	}
+	if len(problems) == 0 {
+		problems = []string{fmt.Sprintf("git fsck failed: %v", err)}
+	}
+	return problems, nil
+}
+
+// LocalOnlyBranches returns the local branches of the repository at
+// destination with commits that no remote-tracking branch has. Branches
+// whose commit is missing are left out, since nothing of them is left to
+// lose.
+func (g *GitDownloader) LocalOnlyBranches(destination string) ([]string, error) {
+	output, stderr, err := g.output(g.command(destination, "for-each-ref", "--format=%(objectname) %(refname:short)", "refs/heads"))
+	if err != nil {
+		return nil, withDetail("failed to list branches", err, lastLine(string(stderr)))
+	}
+
+	var branches []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		sha, name, ok := strings.Cut(line, " ")
+		if !ok || !g.HasCommit(destination, sha) {
+			continue
+		}
+		unpushed, stderr, err := g.output(g.command(destination, "rev-list", "-n", "1", sha, "--not", "--remotes"))
+		if err != nil {
+			return nil, withDetail("failed to compare branch "+name+" with origin", err, lastLine(string(stderr)))
+		}
+		if strings.TrimSpace(string(unpushed)) != "" {
+			branches = append(branches, name)
+		}
+	}
+	return branches, nil
+}
+
+// Refetch fetches every object of the repository at destination from
+// origin again, as if it were cloned, replacing missing ones.
+func (g *GitDownloader) Refetch(destination string) error {
+	args := []string{"fetch", "--refetch", "--force", "--tags"}
+	if g.options.Shallow && g.options.Depth > 0 {
+		args = append(args, "--depth", fmt.Sprintf("%d", g.options.Depth))
+	}
+	args = append(args, "origin")
+
+	output, err := g.retry("fetch", destination, nil, func() (string, error) {
+		output, err := g.combinedOutput(g.command(destination, args...))
+		return string(output), err
+	})
+	if err != nil {
+		return gitError(errcode.FetchFailed, output, withDetail("failed to fetch objects again", err, lastLine(output)))
+	}
+	return nil
+}
+
+// IsShallow reports whether the repository at destination is a shallow
+// clone.
+func (g *GitDownloader) IsShallow(destination string) (bool, error) {
+	output, stderr, err := g.output(g.command(destination, "rev-parse", "--is-shallow-repository"))
+	if err != nil {
+		return false, withDetail("failed to inspect repository", err, lastLine(string(stderr)))
+	}
+	return strings.TrimSpace(string(output)) == "true", nil
+}
+
+// Stash is an entry in a repository's stash list.
+type Stash struct {
+	Ref     string // e.g. stash@{0}
+	Message string
+	Time    time.Time
+}
+
+// Stashes returns the stash list of the repository at destination,
+// newest first.
+func (g *GitDownloader) Stashes(destination string) ([]Stash, error) {
+	output, stderr, err := g.output(g.command(destination, "stash", "list", "--format=%gd%x00%ct%x00%gs"))
+	if err != nil {
+		return nil, withDetail("failed to list stashes", err, lastLine(string(stderr)))
+	}
+	var stashes []Stash
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.SplitN(line, "\x00", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		s := Stash{Ref: fields[0], Message: fields[2]}
+		// The subject is "On <branch>: <message>" or "WIP on <branch>: ..."
+		if _, msg, ok := strings.Cut(fields[2], ": "); ok {
+			s.Message = msg
+		}
+		if secs, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			s.Time = time.Unix(secs, 0)
+		}
+		stashes = append(stashes, s)
+	}
+	return stashes, nil
+}
+
+// StashPush stashes the changes in the repository at destination,
+// including untracked files, under message.
+func (g *GitDownloader) StashPush(destination, message string) error {
+	if _, stderr, err := g.output(g.command(destination, "stash", "push", "--include-untracked", "--message", message)); err != nil {
+		return withDetail("failed to stash changes", err, lastLine(string(stderr)))
+	}
+	return nil
+}
+
+// StashPop applies the stash ref in the repository at destination and
+// drops it. If applying it conflicts, the stash is kept.
+func (g *GitDownloader) StashPop(destination, ref string) error {
+	if _, stderr, err := g.output(g.command(destination, "stash", "pop", ref)); err != nil {
+		return withDetail("failed to restore stash", err, lastLine(string(stderr)))
+	}
+	return nil
+}
+
+// HooksDir returns the directory git runs hooks from in the repository
+// at destination, honoring core.hooksPath.
+func (g *GitDownloader) HooksDir(destination string) (string, error) {
+	output, stderr, err := g.output(g.command(destination, "rev-parse", "--git-path", "hooks"))
+	if err != nil {
+		return "", withDetail("failed to find hooks directory", err, lastLine(string(stderr)))
+	}
+	dir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(destination, dir)
+	}
+	return dir, nil
+}
+
+// CheckoutChange fetches ref, such as the refs/changes/... ref of a
+// Gerrit patch set, into the repository at destination and checks it out
+// on branch, resetting the branch if it exists.
+func (g *GitDownloader) CheckoutChange(destination, ref, branch string) error {
+	if err := g.fetchRef(destination, ref); err != nil {
+		return err
+	}
+	if _, stderr, err := g.output(g.command(destination, "checkout", "--quiet", "-B", branch, ref)); err != nil {
+		return gitError(errcode.CheckoutFailed, string(stderr), withDetail("failed to check out "+ref, err, lastLine(string(stderr))))
+	}
+	return nil
 }
 
 // IsDirty returns true if the repository has uncommitted changes.
@@ -0,0 +1,182 @@
+package downloader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/tierone/harbormaster/pkg/errcode"
+)
+
+// SSHHost returns the host and port of a repository URL reached over SSH,
+// for ssh:// and scp-like (git@host:owner/repo) URLs. The port is empty
+// unless the URL names one. ok is false for other URLs.
+func SSHHost(repoURL string) (host, port string, ok bool) {
+	if !strings.Contains(repoURL, "://") {
+		// scp-like syntax: [user@]host:path, but not a local path
+		rest := repoURL[strings.Index(repoURL, "@")+1:]
+		host, _, found := strings.Cut(rest, ":")
+		if !found || host == "" || strings.ContainsAny(host, "/\\") {
+			return "", "", false
+		}
+		return strings.ToLower(host), "", true
+	}
+	u, err := url.Parse(repoURL)
+	if err != nil || (u.Scheme != "ssh" && u.Scheme != "git+ssh" && u.Scheme != "ssh+git") || u.Hostname() == "" {
+		return "", "", false
+	}
+	return strings.ToLower(u.Hostname()), u.Port(), true
+}
+
+// knownHostsName returns how a host is written in known_hosts.
+func knownHostsName(host, port string) string {
+	if port == "" || port == "22" {
+		return host
+	}
+	return "[" + host + "]:" + port
+}
+
+// HostKeyFingerprint returns the SHA256 fingerprint of the public key in
+// a known_hosts line ("host ssh-ed25519 AAAA..."), in the form ssh-keygen
+// -l prints it ("SHA256:..."), along with the host names of the line.
+func HostKeyFingerprint(line string) (hosts, fingerprint string, err error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return "", "", fmt.Errorf("invalid known_hosts line: %q", line)
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[2])
+	if err != nil {
+		return "", "", fmt.Errorf("invalid host key: %w", err)
+	}
+	sum := sha256.Sum256(blob)
+	return fields[0], "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:]), nil
+}
+
+// PinHostKeys makes sure the known_hosts file at path holds only keys of
+// the SSH host matching one of fingerprints, and at least one. Entries
+// for the host whose keys are no longer pinned are removed, so that a
+// key dropped from the config is no longer accepted. If no entry is
+// left, the host's keys are scanned with ssh-keyscan and those matching
+// a fingerprint are kept. A host offering no matching key is not
+// trusted.
+func PinHostKeys(ctx context.Context, path, host, port string, fingerprints []string) error {
+	name := knownHostsName(host, port)
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read known_hosts: %w", err)
+	}
+
+	// Keep the lines of other hosts, and this host's that match
+	var kept []string
+	matched, stale := false, false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		hosts, fp, err := HostKeyFingerprint(line)
+		if err != nil || !slices.Contains(strings.Split(hosts, ","), name) {
+			kept = append(kept, line)
+			continue
+		}
+		if slices.Contains(fingerprints, fp) {
+			kept = append(kept, line)
+			matched = true
+		} else {
+			stale = true
+		}
+	}
+	if matched {
+		if !stale {
+			return nil
+		}
+		return writeKnownHosts(path, kept)
+	}
+
+	args := []string{"-T", "10"}
+	if port != "" {
+		args = append(args, "-p", port)
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ssh-keyscan", append(args, host)...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return errcode.Wrap(errcode.NetworkError, withDetail("failed to scan SSH host keys of "+host, err, lastLine(stderr.String())))
+	}
+
+	var offered []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		_, fp, err := HostKeyFingerprint(line)
+		if err != nil {
+			continue
+		}
+		offered = append(offered, fp)
+		if slices.Contains(fingerprints, fp) {
+			kept = append(kept, line)
+			matched = true
+		}
+	}
+	if !matched {
+		if len(offered) == 0 {
+			return errcode.Wrap(errcode.NetworkError, fmt.Errorf("SSH host %s offered no host keys", host))
+		}
+		return errcode.Wrap(errcode.HostKeyMismatch, fmt.Errorf("no SSH host key of %s matches its pinned fingerprints (offered %s)", host, strings.Join(offered, ", ")))
+	}
+
+	return writeKnownHosts(path, kept)
+}
+
+// writeKnownHosts replaces the known_hosts file at path with lines. The
+// file is written beside it and renamed into place, so that ssh checking
+// an already pinned host never reads it half written.
+func writeKnownHosts(path string, lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create known_hosts directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	if _, err := tmp.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	return nil
+}
+
+// sshCommand returns the SSH command git runs to check host keys strictly
+// against knownHosts alone, extending GIT_SSH_COMMAND if it is set.
+func sshCommand(knownHosts string) string {
+	base := os.Getenv("GIT_SSH_COMMAND")
+	if base == "" {
+		base = "ssh"
+	}
+	quoted := "'" + strings.ReplaceAll(knownHosts, "'", `'\''`) + "'"
+	return base + " -o StrictHostKeyChecking=yes -o GlobalKnownHostsFile=/dev/null -o UserKnownHostsFile=" + quoted
+}
@@ -0,0 +1,145 @@
+package downloader
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/tierone/harbormaster/pkg/errcode"
+)
+
+func TestSSHHost(t *testing.T) {
+	tests := []struct {
+		url  string
+		host string
+		port string
+		ok   bool
+	}{
+		{"git@github.com:user/repo.git", "github.com", "", true},
+		{"GitHub.com:user/repo", "github.com", "", true},
+		{"ssh://git@git.example.com:2222/team/repo.git", "git.example.com", "2222", true},
+		{"git+ssh://git.example.com/repo", "git.example.com", "", true},
+		{"https://github.com/user/repo.git", "", "", false},
+		{"file:///srv/repo.git", "", "", false},
+		{"./local/repo", "", "", false},
+	}
+	for _, tt := range tests {
+		host, port, ok := SSHHost(tt.url)
+		if host != tt.host || port != tt.port || ok != tt.ok {
+			t.Errorf("SSHHost(%q) = %q, %q, %v; want %q, %q, %v", tt.url, host, port, ok, tt.host, tt.port, tt.ok)
+		}
+	}
+}
+
+// testHostKey generates a host key and returns its known_hosts line for
+// host and its fingerprint as ssh-keygen prints it.
+func testHostKey(t *testing.T, host string) (line, fingerprint string) {
+	t.Helper()
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not available")
+	}
+	key := filepath.Join(t.TempDir(), "key")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key).CombinedOutput(); err != nil {
+		t.Fatalf("failed to generate key: %v\n%s", err, out)
+	}
+	out, err := exec.Command("ssh-keygen", "-l", "-f", key+".pub").Output()
+	if err != nil {
+		t.Fatalf("failed to fingerprint key: %v", err)
+	}
+	pub, err := os.ReadFile(key + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := strings.Fields(string(pub))
+	return host + " " + fields[0] + " " + fields[1], strings.Fields(string(out))[1]
+}
+
+func TestHostKeyFingerprint(t *testing.T) {
+	line, want := testHostKey(t, "git.example.com")
+	hosts, got, err := HostKeyFingerprint(line)
+	if err != nil {
+		t.Fatalf("HostKeyFingerprint failed: %v", err)
+	}
+	if hosts != "git.example.com" || got != want {
+		t.Errorf("got %s %s, want git.example.com %s", hosts, got, want)
+	}
+}
+
+func TestPinHostKeys(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ssh-keyscan is a shell script")
+	}
+	line, fingerprint := testHostKey(t, "git.example.com")
+	other, otherFingerprint := testHostKey(t, "git.example.com")
+
+	// Stand in for ssh-keyscan, offering the first key
+	bin := t.TempDir()
+	script := "#!/bin/sh\necho '# git.example.com:22 SSH-2.0-OpenSSH'\necho '" + line + "'\n"
+	if err := os.WriteFile(filepath.Join(bin, "ssh-keyscan"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(path, []byte("other.example.com "+strings.SplitN(other, " ", 2)[1]+"\n"+other+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := PinHostKeys(ctx, path, "git.example.com", "", []string{fingerprint}); err != nil {
+		t.Fatalf("PinHostKeys failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), line) || strings.Contains(string(data), other+"\n") {
+		t.Errorf("expected the stale key to be replaced by the pinned one, got:\n%s", data)
+	}
+	if !strings.Contains(string(data), "other.example.com ") {
+		t.Errorf("expected other hosts to be kept, got:\n%s", data)
+	}
+
+	// Unpinning a key removes it without scanning again
+	if err := os.WriteFile(filepath.Join(bin, "ssh-keyscan"), []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(line+"\n"+other+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := PinHostKeys(ctx, path, "git.example.com", "", []string{fingerprint}); err != nil {
+		t.Fatalf("PinHostKeys failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != line+"\n" {
+		t.Errorf("expected only the pinned key to be left, got:\n%s", data)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("expected the rewrite to leave no temporary files, got %v", entries)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0644 {
+		t.Errorf("expected known_hosts to stay readable, got %v", info.Mode())
+	}
+	if err := os.WriteFile(filepath.Join(bin, "ssh-keyscan"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// A host offering no pinned key is refused
+	err := PinHostKeys(ctx, path, "git.example.com", "2222", []string{otherFingerprint})
+	if errcode.Of(err) != errcode.HostKeyMismatch {
+		t.Errorf("expected %s, got %v", errcode.HostKeyMismatch, err)
+	}
+}
+
+func TestGitDownloader_KnownHostsFile(t *testing.T) {
+	t.Setenv("GIT_SSH_COMMAND", "ssh -i /keys/deploy")
+	dl := NewGitDownloader(Options{KnownHostsFile: "/work/.harbormaster/known_hosts"})
+	cmd := dl.command("", "version")
+
+	want := "GIT_SSH_COMMAND=ssh -i /keys/deploy -o StrictHostKeyChecking=yes -o GlobalKnownHostsFile=/dev/null -o UserKnownHostsFile='/work/.harbormaster/known_hosts'"
+	if len(cmd.Env) == 0 || cmd.Env[len(cmd.Env)-1] != want {
+		t.Errorf("expected %s to be set last, got %v", want, cmd.Env)
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/tierone/harbormaster/pkg/errcode"
 	"github.com/tierone/harbormaster/pkg/types"
 )
 
@@ -22,14 +24,18 @@ type HTTPDownloader struct {
 
 // NewHTTPDownloader creates a new HTTPDownloader with the given options.
 func NewHTTPDownloader(opts Options) *HTTPDownloader {
-	client := &http.Client{
-		Timeout: opts.Timeout,
+	h := &HTTPDownloader{
+		options: opts,
+		client: &http.Client{
+			Timeout: opts.Timeout,
+		},
 	}
 
-	return &HTTPDownloader{
-		options: opts,
-		client:  client,
+	if h.options.tracing() {
+		h.client.Transport = &traceTransport{base: http.DefaultTransport, options: &h.options}
 	}
+
+	return h
 }
 
 // Type returns the downloader type.
@@ -49,17 +55,21 @@ func (h *HTTPDownloader) Download(source, destination string) (string, error) {
 	var lastErr error
 	for attempt := 0; attempt <= h.options.RetryAttempts; attempt++ {
 		if attempt > 0 {
-			time.Sleep(h.options.RetryDelay)
+			if err := h.waitRetry(source, attempt, lastErr); err != nil {
+				return "", err
+			}
 		}
 
+		start := time.Now()
 		hash, err := h.downloadFile(source, destination)
+		h.logDownload(source, start, err)
 		if err == nil {
 			return hash, nil
 		}
 		lastErr = err
 	}
 
-	return "", fmt.Errorf("download failed after %d attempts: %w", h.options.RetryAttempts+1, lastErr)
+	return "", downloadError(fmt.Errorf("download failed after %d attempts: %w", h.options.RetryAttempts+1, lastErr))
 }
 
 // DownloadWithProgress downloads with progress reporting.
@@ -91,10 +101,15 @@ func (h *HTTPDownloader) DownloadWithProgress(source, destination string) (strin
 					Phase:   types.PhaseConnecting,
 					Message: fmt.Sprintf("Retrying (%d/%d)...", attempt, h.options.RetryAttempts),
 				}
-				time.Sleep(h.options.RetryDelay)
+				if err := h.waitRetry(source, attempt, lastErr); err != nil {
+					lastErr = err
+					break
+				}
 			}
 
+			start := time.Now()
 			hash, err := h.downloadFileWithProgress(source, destination, progress)
+			h.logDownload(source, start, err)
 			if err == nil {
 				progress <- types.ProgressUpdate{
 					Phase:   types.PhaseComplete,
@@ -107,7 +122,7 @@ func (h *HTTPDownloader) DownloadWithProgress(source, destination string) (strin
 
 		progress <- types.ProgressUpdate{
 			Phase: types.PhaseFailed,
-			Error: fmt.Errorf("download failed: %w", lastErr),
+			Error: downloadError(fmt.Errorf("download failed: %w", lastErr)),
 		}
 	}()
 
@@ -135,8 +150,57 @@ func (h *HTTPDownloader) GetCurrentRef(destination string) (string, error) {
 	return hashFile(destination)
 }
 
+// downloadError codes err as a failed download unless it already has a
+// more specific code.
+func downloadError(err error) error {
+	if errcode.Of(err) != "" {
+		return err
+	}
+	return errcode.Wrap(errcode.DownloadFailed, err)
+}
+
+// waitRetry sleeps for the retry delay, returning early with the context's
+// error if it is canceled.
+func (h *HTTPDownloader) waitRetry(source string, attempt int, lastErr error) error {
+	h.options.log().Warn("retrying download",
+		"url", source,
+		"attempt", attempt,
+		"of", h.options.RetryAttempts,
+		"delay", h.options.RetryDelay,
+		"error", lastErr,
+	)
+	select {
+	case <-time.After(h.options.RetryDelay):
+		return nil
+	case <-h.options.ctx().Done():
+		return h.options.ctx().Err()
+	}
+}
+
+// logDownload records a finished download attempt in the diagnostic log.
+func (h *HTTPDownloader) logDownload(source string, start time.Time, err error) {
+	attrs := []any{"url", source, "duration", time.Since(start)}
+	if err != nil {
+		attrs = append(attrs, "error", err)
+	}
+	h.options.log().Debug("http download", attrs...)
+}
+
+// authorize adds the token to req if it goes to the token's host. The
+// header is dropped when a redirect leaves the host.
+func (h *HTTPDownloader) authorize(req *http.Request) {
+	if h.options.Token == "" || !strings.EqualFold(req.URL.Host, h.options.TokenHost) {
+		return
+	}
+	if h.options.TokenBasic {
+		req.SetBasicAuth(h.options.TokenUser, h.options.Token)
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+h.options.Token)
+}
+
 func (h *HTTPDownloader) downloadFile(source, destination string) (string, error) {
-	req, err := http.NewRequest("GET", source, nil)
+	req, err := http.NewRequestWithContext(h.options.ctx(), "GET", source, nil)
 	if err != nil {
 		return "", err
 	}
@@ -144,15 +208,16 @@ func (h *HTTPDownloader) downloadFile(source, destination string) (string, error
 	if h.options.UserAgent != "" {
 		req.Header.Set("User-Agent", h.options.UserAgent)
 	}
+	h.authorize(req)
 
 	resp, err := h.client.Do(req)
 	if err != nil {
-		return "", err
+		return "", errcode.Wrap(errcode.NetworkError, err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+		return "", errcode.Wrap(httpStatusCode(resp.StatusCode), fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status))
 	}
 
 	f, err := os.Create(destination)
@@ -178,7 +243,7 @@ func (h *HTTPDownloader) downloadFile(source, destination string) (string, error
 }
 
 func (h *HTTPDownloader) downloadFileWithProgress(source, destination string, progress chan<- types.ProgressUpdate) (string, error) {
-	req, err := http.NewRequest("GET", source, nil)
+	req, err := http.NewRequestWithContext(h.options.ctx(), "GET", source, nil)
 	if err != nil {
 		return "", err
 	}
@@ -186,15 +251,16 @@ func (h *HTTPDownloader) downloadFileWithProgress(source, destination string, pr
 	if h.options.UserAgent != "" {
 		req.Header.Set("User-Agent", h.options.UserAgent)
 	}
+	h.authorize(req)
 
 	resp, err := h.client.Do(req)
 	if err != nil {
-		return "", err
+		return "", errcode.Wrap(errcode.NetworkError, err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+		return "", errcode.Wrap(httpStatusCode(resp.StatusCode), fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status))
 	}
 
 	progress <- types.ProgressUpdate{
@@ -213,6 +279,9 @@ func (h *HTTPDownloader) downloadFileWithProgress(source, destination string, pr
 	total := resp.ContentLength
 	var done int64
 
+	sender := newProgressSender(progress)
+	defer sender.flush()
+
 	buf := make([]byte, 32*1024)
 	for {
 		n, err := resp.Body.Read(buf)
@@ -225,14 +294,11 @@ func (h *HTTPDownloader) downloadFileWithProgress(source, destination string, pr
 			done += int64(n)
 
 			if total > 0 {
-				select {
-				case progress <- types.ProgressUpdate{
+				sender.send(types.ProgressUpdate{
 					Phase:      types.PhaseFetching,
 					BytesDone:  done,
 					BytesTotal: total,
-				}:
-				default:
-				}
+				})
 			}
 		}
 		if err == io.EOF {
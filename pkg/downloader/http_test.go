@@ -88,6 +88,55 @@ func TestHTTPDownloader_Download_UserAgent(t *testing.T) {
 	}
 }
 
+func TestHTTPDownloader_Download_Token(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	destPath := filepath.Join(t.TempDir(), "test.txt")
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	dl := NewHTTPDownloader(Options{Token: "secret", TokenHost: host, Timeout: 30 * time.Second})
+	if _, err := dl.Download(server.URL, destPath); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if received != "Bearer secret" {
+		t.Errorf("expected the token to be sent, got %q", received)
+	}
+
+	// Other hosts don't get the token
+	dl = NewHTTPDownloader(Options{Token: "secret", TokenHost: "example.com", Timeout: 30 * time.Second})
+	if _, err := dl.Download(server.URL, destPath); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if received != "" {
+		t.Errorf("expected no token for another host, got %q", received)
+	}
+}
+
+func TestHTTPDownloader_Download_BasicToken(t *testing.T) {
+	var user, password string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ = r.BasicAuth()
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	destPath := filepath.Join(t.TempDir(), "test.txt")
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	dl := NewHTTPDownloader(Options{Token: "app-password", TokenUser: "bob", TokenBasic: true, TokenHost: host, Timeout: 30 * time.Second})
+	if _, err := dl.Download(server.URL, destPath); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if user != "bob" || password != "app-password" {
+		t.Errorf("expected basic authentication as bob, got %q:%q", user, password)
+	}
+}
+
 func TestHTTPDownloader_Download_404(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
@@ -249,3 +298,28 @@ func TestHTTPDownloader_DownloadWithProgress(t *testing.T) {
 		t.Fatalf("file not created: %v", err)
 	}
 }
+
+func TestHTTPDownloader_Verbose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("data"))
+	}))
+	defer server.Close()
+
+	var out strings.Builder
+	dl := NewHTTPDownloader(Options{
+		Timeout: 30 * time.Second,
+		Verbose: &out,
+	})
+
+	if _, err := dl.Download(server.URL+"/file.txt", filepath.Join(t.TempDir(), "file.txt")); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+
+	trace := out.String()
+	if !strings.Contains(trace, "run:  GET "+server.URL+"/file.txt") {
+		t.Errorf("expected request in trace, got:\n%s", trace)
+	}
+	if !strings.Contains(trace, "(HTTP 200, ") {
+		t.Errorf("expected response status in trace, got:\n%s", trace)
+	}
+}
@@ -1,8 +1,12 @@
 package downloader
 
 import (
+	"context"
+	"io"
+	"log/slog"
 	"time"
 
+	"github.com/tierone/harbormaster/pkg/logging"
 	"github.com/tierone/harbormaster/pkg/types"
 )
 
@@ -28,7 +32,7 @@ type Downloader interface {
 	// For git: HEAD commit SHA. For HTTP: content hash.
 	GetCurrentRef(destination string) (string, error)
 
-	// Type returns the downloader type (git, http).
+	// Type returns the downloader type (git, http, path).
 	Type() string
 }
 
@@ -38,9 +42,66 @@ type Options struct {
 	Branch     string
 	Tag        string
 	Commit     string
+	Ref        string   // Full ref to fetch and check out, such as refs/pull/123/head
+	Refspecs   []string // Extra refspecs fetched from origin after every clone and fetch
 	Depth      int
 	Shallow    bool
 	Submodules bool
+	AsOf       time.Time // Check out the last commit on the branch before this time; zero disables
+	Subdir     string    // Check out only this directory, with a partial clone and sparse checkout
+
+	// SubmoduleDepth limits how many levels of nested submodules are
+	// checked out; 0 is unlimited. IncludeSubmodules and ExcludeSubmodules
+	// hold submodule paths or glob patterns, relative to the top of the
+	// clone, that are checked out or skipped along with everything below
+	// them; no includes means all submodules.
+	SubmoduleDepth    int
+	IncludeSubmodules []string
+	ExcludeSubmodules []string
+
+	// LocalBranch checks out Branch, or the default branch without one,
+	// as a local branch tracking origin, creating or fast-forwarding it,
+	// instead of detaching HEAD at the remote-tracking branch.
+	LocalBranch bool
+
+	// KnownHostsFile, if set, is the only known_hosts file SSH remotes are
+	// checked against, with strict host key checking.
+	KnownHostsFile string
+
+	// GitConfig holds "key=value" settings passed to every git command
+	// with -c, such as url.<base>.insteadOf rewrites.
+	GitConfig []string
+
+	// CloneConfig holds "key=value" settings written to the config of a
+	// clone and of its submodules, in a file replaced on every clone and
+	// update, so that git run in the checkout by hand uses them too.
+	CloneConfig []string
+
+	// Token, if set, authenticates HTTPS requests to TokenHost: git gets
+	// it as the password of TokenUser from a credential helper for that
+	// host, and HTTP downloads from it send it as a bearer token, or with
+	// Basic authentication as TokenUser's password if TokenBasic is set.
+	Token      string
+	TokenUser  string
+	TokenHost  string
+	TokenBasic bool
+
+	// NoTerminalPrompt keeps git from asking for credentials on the
+	// terminal, so that a remote refusing the ones it has fails instead.
+	NoTerminalPrompt bool
+
+	// Reference, if set, is a local repository whose objects a clone
+	// borrows instead of copying them, through git alternates.
+	Reference string
+
+	// GitRetryAttempts is how many times a clone or fetch that failed
+	// transiently is retried, waiting GitRetryDelay before the first retry
+	// and twice as long before each following one, with jitter.
+	GitRetryAttempts int
+	GitRetryDelay    time.Duration
+
+	// Path-specific options
+	Symlink bool // Link to the source directory instead of copying it
 
 	// HTTP-specific options
 	UserAgent     string
@@ -48,7 +109,12 @@ type Options struct {
 	RetryDelay    time.Duration
 
 	// Common options
-	Timeout time.Duration
+	Timeout     time.Duration
+	LowPriority bool            // Run git with reduced CPU and I/O priority
+	Context     context.Context // Cancels in-flight operations; nil means background
+	Logger      *slog.Logger    // Diagnostic log; nil discards
+	Verbose     io.Writer       // Receives every command and request as it runs; nil disables
+	Output      io.Writer       // Receives every command and request with its full output; nil discards
 }
 
 // DefaultOptions returns options with default values.
@@ -64,6 +130,19 @@ func DefaultOptions() Options {
 	}
 }
 
+// ctx returns the context for operations.
+func (o *Options) ctx() context.Context {
+	if o.Context == nil {
+		return context.Background()
+	}
+	return o.Context
+}
+
+// log returns the diagnostic logger.
+func (o *Options) log() *slog.Logger {
+	return logging.OrDiscard(o.Logger)
+}
+
 // GetEffectiveRef returns the ref to checkout (commit > tag > branch).
 func (o *Options) GetEffectiveRef() string {
 	if o.Commit != "" {
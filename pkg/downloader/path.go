@@ -0,0 +1,223 @@
+package downloader
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/types"
+)
+
+// pathStagingSuffix is appended to the destination of a copy while it is
+// being written, so that a failed copy leaves the previous one in place.
+const pathStagingSuffix = ".hm-copy"
+
+// PathDownloader implements Downloader for existing local directories,
+// which are copied into the workspace or linked to from it. The resolved
+// reference is the git tree hash of the content.
+type PathDownloader struct {
+	options Options
+	source  string
+}
+
+// NewPathDownloader creates a new PathDownloader with the given options.
+func NewPathDownloader(opts Options) *PathDownloader {
+	return &PathDownloader{options: opts}
+}
+
+// Type returns the downloader type.
+func (p *PathDownloader) Type() string {
+	return "path"
+}
+
+// Download copies or links the directory source to destination. A copy
+// replaces whatever is there; see link for links.
+func (p *PathDownloader) Download(source, destination string) (string, error) {
+	p.source = source
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return "", errcode.Wrap(errcode.RemoteNotFound, fmt.Errorf("source directory: %w", err))
+	}
+	if !info.IsDir() {
+		return "", errcode.Wrap(errcode.RemoteNotFound, fmt.Errorf("source %s is not a directory", source))
+	}
+
+	// A copy would recurse into itself and a link would replace the source
+	overlap, err := config.PathsOverlap(source, destination)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", destination, err)
+	}
+	if overlap {
+		return "", fmt.Errorf("source directory %s overlaps the destination %s", source, destination)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if p.options.Symlink {
+		if err := p.link(source, destination); err != nil {
+			return "", err
+		}
+	} else if err := p.copy(source, destination); err != nil {
+		return "", err
+	}
+	return TreeHash(destination)
+}
+
+// DownloadWithProgress copies or links with progress reporting.
+func (p *PathDownloader) DownloadWithProgress(source, destination string) (string, <-chan types.ProgressUpdate, error) {
+	p.source = source
+	progress := make(chan types.ProgressUpdate, 2)
+
+	go func() {
+		defer close(progress)
+
+		message := "Copying..."
+		if p.options.Symlink {
+			message = "Linking..."
+		}
+		progress <- types.ProgressUpdate{Phase: types.PhaseCheckout, Message: message}
+
+		hash, err := p.Download(source, destination)
+		if err != nil {
+			progress <- types.ProgressUpdate{Phase: types.PhaseFailed, Error: err}
+			return
+		}
+		progress <- types.ProgressUpdate{Phase: types.PhaseComplete, Message: hash}
+	}()
+
+	return "", progress, nil
+}
+
+// Update copies the source directory again, or checks the link to it.
+func (p *PathDownloader) Update(destination string) (string, error) {
+	if p.source == "" {
+		return "", fmt.Errorf("source directory not set")
+	}
+	return p.Download(p.source, destination)
+}
+
+// UpdateWithProgress updates with progress reporting.
+func (p *PathDownloader) UpdateWithProgress(destination string) (string, <-chan types.ProgressUpdate, error) {
+	if p.source == "" {
+		return "", nil, fmt.Errorf("source directory not set")
+	}
+	return p.DownloadWithProgress(p.source, destination)
+}
+
+// GetCurrentRef returns the git tree hash of the content at destination.
+func (p *PathDownloader) GetCurrentRef(destination string) (string, error) {
+	return TreeHash(destination)
+}
+
+// link makes destination a symlink to source. A link that already points
+// there is kept and any other link is replaced, but a file or directory
+// at destination is never removed: it may hold the only copy of its
+// content.
+func (p *PathDownloader) link(source, destination string) error {
+	info, err := os.Lstat(destination)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return fmt.Errorf("failed to read %s: %w", destination, err)
+	case info.Mode()&os.ModeSymlink == 0:
+		return fmt.Errorf("refusing to replace %s with a link to %s: it is not a link; move it away first", destination, source)
+	default:
+		if target, err := os.Readlink(destination); err == nil && target == source {
+			return nil
+		}
+		if err := os.Remove(destination); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", destination, err)
+		}
+	}
+	if err := os.Symlink(source, destination); err != nil {
+		return fmt.Errorf("failed to link %s: %w", source, err)
+	}
+	return nil
+}
+
+// copy copies source to a staging directory and swaps it in for
+// destination. The .git directory of the source is left out.
+func (p *PathDownloader) copy(source, destination string) error {
+	staging := destination + pathStagingSuffix
+	_ = os.RemoveAll(staging)
+
+	if err := copyDir(source, staging); err != nil {
+		_ = os.RemoveAll(staging)
+		return fmt.Errorf("failed to copy %s: %w", source, err)
+	}
+	if err := os.RemoveAll(destination); err != nil {
+		_ = os.RemoveAll(staging)
+		return fmt.Errorf("failed to remove %s: %w", destination, err)
+	}
+	if err := os.Rename(staging, destination); err != nil {
+		_ = os.RemoveAll(staging)
+		return fmt.Errorf("failed to move copy into place: %w", err)
+	}
+	return nil
+}
+
+// copyDir copies the tree at src to dst, preserving file modes and
+// symlinks, except for the top-level .git directory.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel == ".git" {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			return copyRegularFile(path, target, info.Mode().Perm())
+		default:
+			// Sockets, devices and pipes have no content to copy
+			return nil
+		}
+	})
+}
+
+func copyRegularFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,162 @@
+package downloader
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func setupTestDir(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := filepath.Join(t.TempDir(), "component")
+	for name, content := range map[string]string{
+		"README.md":   "# component\n",
+		"src/main.go": "package main\n",
+		".git/HEAD":   "ref: refs/heads/main\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestPathDownloader_Copy(t *testing.T) {
+	source := setupTestDir(t)
+	dest := filepath.Join(t.TempDir(), "repos", "component")
+
+	dl := NewPathDownloader(Options{})
+	hash, err := dl.Download(source, dest)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	if fi, err := os.Lstat(dest); err != nil || !fi.IsDir() {
+		t.Fatalf("expected a directory at destination, got %v, %v", fi, err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "src", "main.go")); err != nil {
+		t.Errorf("expected nested file to be copied: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, ".git")); !os.IsNotExist(err) {
+		t.Errorf("expected .git not to be copied, got %v", err)
+	}
+
+	sourceHash, err := TreeHash(source)
+	if err != nil {
+		t.Fatalf("TreeHash failed: %v", err)
+	}
+	if hash != sourceHash {
+		t.Errorf("expected hash of the copy %s to match the source %s", hash, sourceHash)
+	}
+
+	// Changes to the source are picked up by the next update
+	if err := os.WriteFile(filepath.Join(source, "README.md"), []byte("# changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dest, "stale.txt"), []byte("stale\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := dl.Update(dest)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if updated == hash {
+		t.Error("expected hash to change with the source")
+	}
+	if _, err := os.Stat(filepath.Join(dest, "stale.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected update to replace the copy, got %v", err)
+	}
+	if _, err := os.Stat(dest + pathStagingSuffix); !os.IsNotExist(err) {
+		t.Errorf("expected staging directory to be removed, got %v", err)
+	}
+}
+
+func TestPathDownloader_Symlink(t *testing.T) {
+	source := setupTestDir(t)
+	dest := filepath.Join(t.TempDir(), "component")
+
+	// A directory at the destination is left alone
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dest, "local.c"), []byte("int x;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dl := NewPathDownloader(Options{Symlink: true})
+	if _, err := dl.Download(source, dest); err == nil {
+		t.Fatal("expected a directory at the destination to be refused")
+	}
+	if _, err := os.Stat(filepath.Join(dest, "local.c")); err != nil {
+		t.Fatalf("expected the directory to be kept: %v", err)
+	}
+
+	// A stale link is replaced
+	if err := os.RemoveAll(dest); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(t.TempDir(), dest); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := dl.Download(source, dest)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	target, err := os.Readlink(dest)
+	if err != nil || target != source {
+		t.Fatalf("expected a link to %s, got %q, %v", source, target, err)
+	}
+
+	sourceHash, _ := TreeHash(source)
+	if hash != sourceHash {
+		t.Errorf("expected hash %s, got %s", sourceHash, hash)
+	}
+
+	if _, err := dl.Update(dest); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(source, "README.md")); err != nil {
+		t.Errorf("expected source to be left alone: %v", err)
+	}
+}
+
+func TestPathDownloader_MissingSource(t *testing.T) {
+	dl := NewPathDownloader(Options{})
+	if _, err := dl.Download(filepath.Join(t.TempDir(), "missing"), filepath.Join(t.TempDir(), "dest")); err == nil {
+		t.Fatal("expected missing source to fail")
+	}
+}
+
+func TestPathDownloader_Overlap(t *testing.T) {
+	source := setupTestDir(t)
+
+	tests := []struct {
+		name    string
+		dest    string
+		symlink bool
+	}{
+		{"link to itself", source, true},
+		{"copy into itself", filepath.Join(source, "src", "deps", "component"), false},
+		{"copy over its parent", filepath.Dir(source), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dl := NewPathDownloader(Options{Symlink: tt.symlink})
+			if _, err := dl.Download(source, tt.dest); err == nil {
+				t.Fatal("expected overlapping source and destination to fail")
+			}
+			if _, err := os.Stat(filepath.Join(source, "src", "main.go")); err != nil {
+				t.Errorf("expected the source to be left alone: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+//go:build !windows
+
+package downloader
+
+import (
+	"os/exec"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// niceCommand is the command line prefix that lowers the priority of a
+// command: nice, and on Linux also ionice with the idle I/O class. It is
+// empty if neither tool is installed.
+var niceCommand = sync.OnceValue(func() []string {
+	var prefix []string
+	if _, err := exec.LookPath("nice"); err == nil {
+		prefix = append(prefix, "nice", "-n", "10")
+	}
+	if runtime.GOOS == "linux" {
+		if _, err := exec.LookPath("ionice"); err == nil {
+			prefix = append(prefix, "ionice", "-c", "3")
+		}
+	}
+	return prefix
+})
+
+// lowerPriority makes cmd run through nice and ionice, so that its CPU
+// and disk use yield to other work. Children of git inherit the priority.
+func lowerPriority(cmd *exec.Cmd) {
+	prefix := niceCommand()
+	if len(prefix) == 0 || cmd.Err != nil {
+		return
+	}
+	path, err := exec.LookPath(prefix[0])
+	if err != nil {
+		return
+	}
+	cmd.Path = path
+	cmd.Args = append(slices.Clone(prefix), cmd.Args...)
+}
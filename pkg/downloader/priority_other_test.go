@@ -0,0 +1,32 @@
+//go:build !windows
+
+package downloader
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestGitDownloader_LowPriority(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dl := NewGitDownloader(Options{LowPriority: true})
+	cmd := dl.command("", "version")
+	if got := gitArgs(cmd); len(got) != 1 || got[0] != "version" {
+		t.Errorf("expected git args [version], got %v", got)
+	}
+	if prefix := niceCommand(); len(prefix) > 0 && cmd.Args[0] != prefix[0] {
+		t.Errorf("expected command to run through %s, got %v", prefix[0], cmd.Args)
+	}
+
+	output, err := dl.combinedOutput(cmd)
+	if err != nil {
+		t.Fatalf("git failed: %v\n%s", err, output)
+	}
+	if !strings.HasPrefix(string(output), "git version") {
+		t.Errorf("unexpected output: %s", output)
+	}
+}
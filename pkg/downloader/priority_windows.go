@@ -0,0 +1,19 @@
+package downloader
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// belowNormalPriorityClass is the Windows process creation flag for
+// below-normal scheduling priority.
+const belowNormalPriorityClass = 0x00004000
+
+// lowerPriority makes cmd run in the below-normal priority class, so that
+// its CPU use yields to other work. Children of git inherit the class.
+func lowerPriority(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= belowNormalPriorityClass
+}
@@ -0,0 +1,34 @@
+package downloader
+
+import "github.com/tierone/harbormaster/pkg/types"
+
+// progressSender delivers frequent progress updates without blocking the
+// operation on a slow reader. When the channel is full the update is held
+// back, and a newer one replaces it, so the reader always ends up with the
+// latest state rather than a stale one.
+type progressSender struct {
+	ch      chan<- types.ProgressUpdate
+	pending *types.ProgressUpdate
+}
+
+func newProgressSender(ch chan<- types.ProgressUpdate) *progressSender {
+	return &progressSender{ch: ch}
+}
+
+// send delivers u if the channel has room, or holds it back otherwise.
+func (s *progressSender) send(u types.ProgressUpdate) {
+	select {
+	case s.ch <- u:
+		s.pending = nil
+	default:
+		s.pending = &u
+	}
+}
+
+// flush delivers the update held back, if any, waiting for room.
+func (s *progressSender) flush() {
+	if s.pending != nil {
+		s.ch <- *s.pending
+		s.pending = nil
+	}
+}
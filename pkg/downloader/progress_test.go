@@ -0,0 +1,29 @@
+package downloader
+
+import (
+	"testing"
+
+	"github.com/tierone/harbormaster/pkg/types"
+)
+
+func TestProgressSender_KeepsLatest(t *testing.T) {
+	ch := make(chan types.ProgressUpdate, 1)
+	s := newProgressSender(ch)
+
+	s.send(types.ProgressUpdate{Percent: 10})
+	s.send(types.ProgressUpdate{Percent: 50}) // Channel full: held back
+	s.send(types.ProgressUpdate{Percent: 90}) // Replaces 50
+
+	if u := <-ch; u.Percent != 10 {
+		t.Errorf("expected first update 10, got %v", u.Percent)
+	}
+	s.flush()
+	if u := <-ch; u.Percent != 90 {
+		t.Errorf("expected latest update 90, got %v", u.Percent)
+	}
+	select {
+	case u := <-ch:
+		t.Errorf("unexpected update %v", u.Percent)
+	default:
+	}
+}
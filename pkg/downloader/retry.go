@@ -0,0 +1,117 @@
+package downloader
+
+import (
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// maxGitRetryDelay caps the exponential backoff between git retries.
+const maxGitRetryDelay = time.Minute
+
+// transientGitFailures are fragments of git's error output, matched
+// case-insensitively, that indicate a failure worth retrying: dropped
+// connections and servers that are overloaded or rate limiting.
+var transientGitFailures = []string{
+	"connection reset",
+	"connection timed out",
+	"operation timed out",
+	"could not resolve host",
+	"temporary failure in name resolution",
+	"failed to connect",
+	"the remote end hung up unexpectedly",
+	"early eof",
+	"unexpected disconnect",
+	"rpc failed",
+	"gnutls recv error",
+	"returned error: 429",
+	"returned error: 500",
+	"returned error: 502",
+	"returned error: 503",
+	"returned error: 504",
+}
+
+// isTransientGitFailure reports whether git's output describes a failure
+// that may succeed when retried.
+func isTransientGitFailure(output string) bool {
+	lower := strings.ToLower(output)
+	for _, fragment := range transientGitFailures {
+		if strings.Contains(lower, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// jitter returns a random duration in [d/2, d]. It is a variable so that
+// tests can make delays deterministic.
+var jitter = func(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// backoff returns the delay before retry attempt (1-based): base doubled
+// for every earlier retry, capped at maxGitRetryDelay, with jitter.
+func backoff(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < maxGitRetryDelay; i++ {
+		delay *= 2
+	}
+	return jitter(min(delay, maxGitRetryDelay))
+}
+
+// shouldRetry reports whether a git operation that failed with output
+// on the given attempt (0 for the first) should be retried, and after
+// which delay.
+func (g *GitDownloader) shouldRetry(attempt int, output string) (time.Duration, bool) {
+	if attempt >= g.options.GitRetryAttempts || g.options.ctx().Err() != nil {
+		return 0, false
+	}
+	if !isTransientGitFailure(output) {
+		return 0, false
+	}
+	return backoff(g.options.GitRetryDelay, attempt+1), true
+}
+
+// waitRetry logs that op is retried after delay and sleeps, returning
+// early with the context's error if it is canceled.
+func (g *GitDownloader) waitRetry(op, target string, attempt int, delay time.Duration, lastErr error) error {
+	g.options.log().Warn("retrying git "+op,
+		"target", target,
+		"attempt", attempt,
+		"of", g.options.GitRetryAttempts,
+		"delay", delay,
+		"error", lastErr,
+	)
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-g.options.ctx().Done():
+		return g.options.ctx().Err()
+	}
+}
+
+// retry runs a git operation until it succeeds, fails for a reason that
+// is not transient, or runs out of attempts, and returns the output and
+// error of the last run. run returns git's error output so that failures
+// can be classified. notify, if not nil, is called before each retry.
+func (g *GitDownloader) retry(op, target string, notify func(attempt int, delay time.Duration), run func() (string, error)) (string, error) {
+	for attempt := 0; ; attempt++ {
+		output, err := run()
+		if err == nil {
+			return output, nil
+		}
+		delay, ok := g.shouldRetry(attempt, output)
+		if !ok {
+			return output, err
+		}
+		if notify != nil {
+			notify(attempt+1, delay)
+		}
+		if g.waitRetry(op, target, attempt+1, delay, err) != nil {
+			return output, err
+		}
+	}
+}
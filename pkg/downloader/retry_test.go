@@ -0,0 +1,177 @@
+package downloader
+
+import (
+	"net/http"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/types"
+)
+
+func TestIsTransientGitFailure(t *testing.T) {
+	tests := []struct {
+		output string
+		want   bool
+	}{
+		{"fatal: unable to access 'https://x/': The requested URL returned error: 503", true},
+		{"fatal: unable to access 'https://x/': The requested URL returned error: 429", true},
+		{"error: RPC failed; curl 56 Recv failure: Connection reset by peer", true},
+		{"fatal: the remote end hung up unexpectedly", true},
+		{"fatal: Could not resolve host: example.invalid", true},
+		{"fatal: Authentication failed for 'https://x/'", false},
+		{"remote: Repository not found.", false},
+		{"fatal: The requested URL returned error: 404", false},
+	}
+
+	for _, tt := range tests {
+		if got := isTransientGitFailure(tt.output); got != tt.want {
+			t.Errorf("isTransientGitFailure(%q) = %v, want %v", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	saved := jitter
+	jitter = func(d time.Duration) time.Duration { return d }
+	defer func() { jitter = saved }()
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{10, maxGitRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := backoff(time.Second, tt.attempt); got != tt.want {
+			t.Errorf("backoff(1s, %d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		if d := jitter(time.Second); d < 500*time.Millisecond || d > time.Second {
+			t.Fatalf("jitter(1s) = %v, want within [500ms, 1s]", d)
+		}
+	}
+}
+
+// flakyGitServer serves the repositories under root over smart HTTP,
+// answering the first failures requests with 503.
+func flakyGitServer(t *testing.T, root string, failures int32) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	git, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git not available")
+	}
+	backend := &cgi.Handler{
+		Path: git,
+		Args: []string{"http-backend"},
+		Env:  []string{"GIT_PROJECT_ROOT=" + root, "GIT_HTTP_EXPORT_ALL=1"},
+	}
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		backend.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestGitDownloader_DownloadRetriesTransientFailures(t *testing.T) {
+	sourceRepo := setupTestGitRepo(t)
+	srv, _ := flakyGitServer(t, filepath.Dir(sourceRepo), 2)
+
+	dl := NewGitDownloader(Options{
+		GitRetryAttempts: 3,
+		GitRetryDelay:    time.Millisecond,
+	})
+	sha, err := dl.Download(srv.URL+"/test-repo", filepath.Join(t.TempDir(), "cloned"))
+	if err != nil {
+		t.Fatalf("expected clone to succeed after retries, got: %v", err)
+	}
+	if sha == "" {
+		t.Error("expected SHA to be returned")
+	}
+}
+
+func TestGitDownloader_DownloadWithProgressReportsRetries(t *testing.T) {
+	sourceRepo := setupTestGitRepo(t)
+	srv, _ := flakyGitServer(t, filepath.Dir(sourceRepo), 1)
+
+	dl := NewGitDownloader(Options{
+		GitRetryAttempts: 2,
+		GitRetryDelay:    time.Millisecond,
+	})
+	_, progressCh, err := dl.DownloadWithProgress(srv.URL+"/test-repo", filepath.Join(t.TempDir(), "cloned"))
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+
+	var retried bool
+	var last types.ProgressUpdate
+	for update := range progressCh {
+		if strings.HasPrefix(update.Message, "Retrying clone (1/2)") {
+			retried = true
+		}
+		last = update
+	}
+	if last.Error != nil {
+		t.Fatalf("expected clone to succeed after retry, got: %v", last.Error)
+	}
+	if !retried {
+		t.Error("expected a retry progress update")
+	}
+}
+
+func TestGitDownloader_DownloadGivesUp(t *testing.T) {
+	sourceRepo := setupTestGitRepo(t)
+	srv, requests := flakyGitServer(t, filepath.Dir(sourceRepo), 100)
+
+	dl := NewGitDownloader(Options{
+		GitRetryAttempts: 2,
+		GitRetryDelay:    time.Millisecond,
+	})
+	if _, err := dl.Download(srv.URL+"/test-repo", filepath.Join(t.TempDir(), "cloned")); err == nil {
+		t.Fatal("expected clone to fail")
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("expected 3 attempts, got %d", n)
+	}
+}
+
+func TestGitDownloader_DownloadDoesNotRetryPermanentFailures(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	var retries int
+	dl := NewGitDownloader(Options{
+		GitRetryAttempts: 3,
+		GitRetryDelay:    time.Hour,
+	})
+	_, err := dl.retry("clone", "missing", func(int, time.Duration) { retries++ }, func() (string, error) {
+		output, err := dl.combinedOutput(dl.command("", "clone", filepath.Join(t.TempDir(), "missing"), filepath.Join(t.TempDir(), "cloned")))
+		return string(output), err
+	})
+	if err == nil {
+		t.Fatal("expected clone to fail")
+	}
+	if retries != 0 {
+		t.Errorf("expected no retries, got %d", retries)
+	}
+}
@@ -0,0 +1,67 @@
+package downloader
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// StripGitMetadata removes all .git directories and files (including
+// those of submodules) below path, leaving only the checked-out content.
+func StripGitMetadata(path string) error {
+	var gitPaths []string
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Name() == ".git" {
+			gitPaths = append(gitPaths, p)
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan for git metadata: %w", err)
+	}
+
+	for _, p := range gitPaths {
+		if err := os.RemoveAll(p); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// TreeHash computes the git tree hash of the content at path without
+// requiring (or touching) a repository there. Files matched by .gitignore
+// rules in the content are excluded, as they would be from a commit.
+func TreeHash(path string) (string, error) {
+	gitDir, err := os.MkdirTemp("", "hm-tree-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(gitDir) }()
+
+	run := func(args ...string) (string, error) {
+		full := append([]string{"--git-dir", gitDir, "--work-tree", path}, args...)
+		cmd := exec.Command("git", full...)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return "", fmt.Errorf("git %s failed: %w\n%s", args[0], err, string(output))
+		}
+		return strings.TrimSpace(string(output)), nil
+	}
+
+	if _, err := run("init", "--quiet"); err != nil {
+		return "", err
+	}
+	if _, err := run("add", "--all", "."); err != nil {
+		return "", err
+	}
+	return run("write-tree")
+}
@@ -0,0 +1,113 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// tracing reports whether commands and requests are traced.
+func (o *Options) tracing() bool {
+	return o.Verbose != nil || o.Output != nil
+}
+
+// trace writes a formatted line to the verbose and output writers.
+func (o *Options) trace(format string, args ...any) {
+	for _, w := range []io.Writer{o.Verbose, o.Output} {
+		if w != nil {
+			fmt.Fprintf(w, format, args...)
+		}
+	}
+}
+
+// traceStart writes the command line of cmd to the verbose and output
+// writers.
+func (o *Options) traceStart(cmd *exec.Cmd) {
+	if !o.tracing() {
+		return
+	}
+	dir := cmd.Dir
+	if dir == "" {
+		dir = "."
+	}
+	o.trace("run:  %s (dir: %s)\n", formatCommand(cmd.Args), dir)
+}
+
+// traceEnd writes the exit status and duration of cmd to the verbose and
+// output writers.
+func (o *Options) traceEnd(cmd *exec.Cmd, start time.Time, err error) {
+	if !o.tracing() {
+		return
+	}
+	o.trace("done: %s (%s, %s)\n", formatCommand(cmd.Args), exitStatus(err), formatDuration(time.Since(start)))
+}
+
+// exitStatus describes how a command finished.
+func exitStatus(err error) string {
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return "exit 0"
+	case errors.As(err, &exitErr):
+		return fmt.Sprintf("exit %d", exitErr.ExitCode())
+	default:
+		return "error: " + err.Error()
+	}
+}
+
+// formatCommand renders args as a shell-like command line, quoting
+// arguments that contain spaces and hiding passwords in URLs.
+func formatCommand(args []string) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		arg = redactURL(arg)
+		if arg == "" || strings.ContainsAny(arg, " \t\"'") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		parts[i] = arg
+	}
+	return strings.Join(parts, " ")
+}
+
+// redactURL replaces the password of a URL with "xxxxx". Other strings are
+// returned unchanged.
+func redactURL(s string) string {
+	if !strings.Contains(s, "://") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	return u.Redacted()
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+// traceTransport writes each HTTP request and its response status to the
+// verbose and output writers.
+type traceTransport struct {
+	base    http.RoundTripper
+	options *Options
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	target := req.Method + " " + redactURL(req.URL.String())
+	t.options.trace("run:  %s\n", target)
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		t.options.trace("done: %s (error: %v, %s)\n", target, err, formatDuration(time.Since(start)))
+		return nil, err
+	}
+	t.options.trace("done: %s (HTTP %d, %s)\n", target, resp.StatusCode, formatDuration(time.Since(start)))
+	return resp, nil
+}
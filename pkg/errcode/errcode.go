@@ -0,0 +1,139 @@
+// Package errcode defines stable codes that classify Harbormaster errors,
+// so that scripts and CI can branch on the kind of failure instead of
+// matching messages.
+//
+// Codes are grouped by class: HM0xx for workspace and configuration
+// problems, HM1xx for repository operations, and HM2xx for lock file
+// mismatches. Codes are never reused or renumbered.
+package errcode
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Code identifies a class of failure.
+type Code string
+
+// Workspace and configuration errors.
+const (
+	ConfigInvalid     Code = "HM001" // Config file cannot be parsed or fails validation
+	ConfigNotFound    Code = "HM002" // No config file in the workspace
+	LockFileInvalid   Code = "HM003" // Lock file cannot be parsed
+	UnknownRepository Code = "HM004" // Named repository is not configured
+	UnknownProject    Code = "HM005" // Named project is not configured
+)
+
+// Repository operation errors.
+const (
+	SyncFailed      Code = "HM100" // Sync failed for an unclassified reason
+	CloneFailed     Code = "HM101" // git clone failed
+	FetchFailed     Code = "HM102" // git fetch failed
+	CheckoutFailed  Code = "HM103" // Requested ref could not be checked out
+	AuthFailed      Code = "HM104" // Remote rejected the credentials or none were available
+	NetworkError    Code = "HM105" // Remote host unreachable, DNS or TLS failure
+	RemoteNotFound  Code = "HM106" // Remote repository or URL does not exist
+	RefNotFound     Code = "HM107" // Branch, tag, or commit does not exist on the remote
+	DownloadFailed  Code = "HM108" // HTTP download failed
+	Unsigned        Code = "HM109" // Commit has no valid signature where one is required
+	PolicyViolated  Code = "HM110" // Commit is blocked or has a committer that is not allowed
+	HostKeyMismatch Code = "HM111" // SSH host key does not match the pinned fingerprints
+	Corrupt         Code = "HM112" // Repository has missing or corrupt objects or broken refs
+)
+
+// Lock file errors.
+const (
+	LockDrift   Code = "HM201" // Checked-out SHA differs from the locked SHA
+	LockMissing Code = "HM202" // Repository has no lock entry
+	SyncPending Code = "HM203" // Sync would change repositories or the lock file
+	Unhealthy   Code = "HM204" // A checkout is in an unexpected state
+)
+
+var descriptions = map[Code]string{
+	ConfigInvalid:     "configuration invalid",
+	ConfigNotFound:    "configuration not found",
+	LockFileInvalid:   "lock file invalid",
+	UnknownRepository: "unknown repository",
+	UnknownProject:    "unknown project",
+	SyncFailed:        "sync failed",
+	CloneFailed:       "clone failed",
+	FetchFailed:       "fetch failed",
+	CheckoutFailed:    "checkout failed",
+	AuthFailed:        "authentication failed",
+	NetworkError:      "network error",
+	RemoteNotFound:    "remote not found",
+	RefNotFound:       "ref not found",
+	DownloadFailed:    "download failed",
+	Unsigned:          "signature missing",
+	PolicyViolated:    "commit policy violated",
+	HostKeyMismatch:   "host key mismatch",
+	Corrupt:           "repository corrupt",
+	LockDrift:         "lock drift",
+	LockMissing:       "lock entry missing",
+	SyncPending:       "sync pending",
+	Unhealthy:         "repository unhealthy",
+}
+
+// Description returns a short description of the code.
+func (c Code) Description() string {
+	return descriptions[c]
+}
+
+// ExitStatus returns the process exit status for the code's class: 2 for
+// workspace and configuration errors, 3 for repository operations, 4 for
+// lock file mismatches, and 1 otherwise.
+func (c Code) ExitStatus() int {
+	switch {
+	case strings.HasPrefix(string(c), "HM0"):
+		return 2
+	case strings.HasPrefix(string(c), "HM1"):
+		return 3
+	case strings.HasPrefix(string(c), "HM2"):
+		return 4
+	default:
+		return 1
+	}
+}
+
+// All returns every defined code in order.
+func All() []Code {
+	codes := make([]Code, 0, len(descriptions))
+	for c := range descriptions {
+		codes = append(codes, c)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
+// Error attaches a code to an error. Its message is the wrapped error's.
+type Error struct {
+	Code Code
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap attaches code to err. A nil err stays nil.
+func Wrap(code Code, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// Of returns the code of the outermost coded error in err's chain, or ""
+// if there is none.
+func Of(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
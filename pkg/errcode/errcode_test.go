@@ -0,0 +1,71 @@
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestWrapAndOf(t *testing.T) {
+	base := errors.New("fatal: Authentication failed")
+	err := fmt.Errorf("sync: %w", Wrap(AuthFailed, base))
+
+	if got := Of(err); got != AuthFailed {
+		t.Errorf("expected %s, got %q", AuthFailed, got)
+	}
+	if !errors.Is(err, base) {
+		t.Error("expected wrapped error to be preserved")
+	}
+	if err.Error() != "sync: fatal: Authentication failed" {
+		t.Errorf("expected message unchanged, got %q", err.Error())
+	}
+
+	if Wrap(AuthFailed, nil) != nil {
+		t.Error("expected nil for nil error")
+	}
+	if got := Of(base); got != "" {
+		t.Errorf("expected no code, got %q", got)
+	}
+	if got := Of(nil); got != "" {
+		t.Errorf("expected no code for nil, got %q", got)
+	}
+}
+
+func TestOf_Outermost(t *testing.T) {
+	err := Wrap(RefNotFound, Wrap(CheckoutFailed, errors.New("no such ref")))
+	if got := Of(err); got != RefNotFound {
+		t.Errorf("expected outermost code %s, got %s", RefNotFound, got)
+	}
+}
+
+func TestCode_ExitStatus(t *testing.T) {
+	tests := []struct {
+		code     Code
+		expected int
+	}{
+		{ConfigInvalid, 2},
+		{UnknownProject, 2},
+		{CloneFailed, 3},
+		{AuthFailed, 3},
+		{LockDrift, 4},
+		{"", 1},
+	}
+
+	for _, tt := range tests {
+		if got := tt.code.ExitStatus(); got != tt.expected {
+			t.Errorf("%q: expected %d, got %d", tt.code, tt.expected, got)
+		}
+	}
+}
+
+func TestAll(t *testing.T) {
+	codes := All()
+	if len(codes) == 0 || codes[0] != ConfigInvalid {
+		t.Fatalf("expected codes sorted from %s, got %v", ConfigInvalid, codes)
+	}
+	for _, c := range codes {
+		if c.Description() == "" {
+			t.Errorf("%s has no description", c)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package export
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// copyCommit exports src.SHA with git archive into a temporary directory
+// and copies the result to w.
+func copyCommit(w Writer, src Source) error {
+	if src.SHA == "" {
+		return fmt.Errorf("no SHA to archive")
+	}
+
+	tmpDir, err := os.MkdirTemp("", "hm-archive-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	cmd := exec.Command("git", "archive", "--format=tar", src.SHA)
+	cmd.Dir = src.Path
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create pipe: %w", err)
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start git archive: %w", err)
+	}
+
+	extractErr := extractTar(stdout, tmpDir)
+	// Drain remaining output so git can exit if extraction stopped early
+	_, _ = io.Copy(io.Discard, stdout)
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("git archive failed: %w\n%s", err, stderr.String())
+	}
+	if extractErr != nil {
+		return fmt.Errorf("failed to extract archive: %w", extractErr)
+	}
+
+	return CopyTree(w, tmpDir, src.RelPath)
+}
+
+// copyTracked copies the files git tracks in the checkout at src.Path,
+// including those of its submodules, as they are in the working tree.
+func copyTracked(w Writer, src Source) error {
+	cmd := exec.Command("git", "ls-files", "-z", "--cached", "--recurse-submodules")
+	cmd.Dir = src.Path
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("git ls-files failed: %w\n%s", err, stderr.String())
+	}
+
+	names := strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+	sort.Strings(names)
+	prefix := filepath.ToSlash(src.RelPath)
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		p := filepath.Join(src.Path, filepath.FromSlash(name))
+		info, err := os.Lstat(p)
+		if os.IsNotExist(err) {
+			// Deleted in the working tree
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// A submodule that is not checked out
+			continue
+		}
+		if err := w.WriteFile(path.Join(prefix, name), p, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExtractTarball unpacks the gzipped tarball at src into dir.
+func ExtractTarball(src, dir string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	if err := extractTar(gz, dir); err != nil {
+		return fmt.Errorf("failed to extract %s: %w", src, err)
+	}
+	return nil
+}
+
+// extractTar unpacks a tar stream into dir.
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		dest := filepath.Join(dir, filepath.FromSlash(hdr.Name))
+		if !strings.HasPrefix(dest, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid path in archive: %s", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(dest, 0755); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(hdr.Linkname, dest); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode).Perm())
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				_ = f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+)
+
+// ManifestFileName is the name of the provenance manifest in an export.
+const ManifestFileName = "harbormaster-manifest.json"
+
+// Source is a synced repository to include in an export.
+type Source struct {
+	Name    string
+	URL     string
+	Type    string
+	Ref     string // Requested ref
+	Branch  string // Tracked branch, if the ref is a branch
+	SHA     string // Locked SHA or content hash
+	Path    string // Absolute path to the checkout
+	RelPath string // Path within the export
+}
+
+// Manifest records the provenance of exported content.
+type Manifest struct {
+	GeneratedAt  time.Time       `json:"generated_at"`
+	Repositories []ManifestEntry `json:"repositories"`
+}
+
+// ManifestEntry records the provenance of a single repository.
+type ManifestEntry struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	Type string `json:"type"`
+	Ref  string `json:"ref"`
+	SHA  string `json:"sha"`
+	Path string `json:"path"`
+}
+
+// NewManifest builds a manifest for the given sources.
+func NewManifest(sources []Source) *Manifest {
+	m := &Manifest{GeneratedAt: time.Now().UTC()}
+	for _, s := range sources {
+		m.Repositories = append(m.Repositories, ManifestEntry{
+			Name: s.Name,
+			URL:  s.URL,
+			Type: s.Type,
+			Ref:  s.Ref,
+			SHA:  s.SHA,
+			Path: s.RelPath,
+		})
+	}
+	return m
+}
+
+// Vendor copies the working tree content of all sources, without VCS
+// metadata, to w and adds a provenance manifest at the root. Of git
+// checkouts only the files git tracks are copied, leaving out ignored
+// and untracked files such as build output.
+func Vendor(w Writer, sources []Source) (*Manifest, error) {
+	return write(w, sources, func(src Source) error {
+		if !isGitCheckout(src) {
+			return CopyTree(w, src.Path, src.RelPath)
+		}
+		return copyTracked(w, src)
+	})
+}
+
+// ArchiveCommits is like Vendor but exports git sources from their SHA
+// using git archive, ignoring any working tree changes. Sources without
+// git metadata (HTTP files, vendored checkouts) are copied as-is.
+func ArchiveCommits(w Writer, sources []Source) (*Manifest, error) {
+	return write(w, sources, func(src Source) error {
+		if !isGitCheckout(src) {
+			return CopyTree(w, src.Path, src.RelPath)
+		}
+		return copyCommit(w, src)
+	})
+}
+
+// isGitCheckout reports whether src is a git repository with its git
+// metadata.
+func isGitCheckout(src Source) bool {
+	_, err := os.Stat(filepath.Join(src.Path, ".git"))
+	return src.Type == "git" && err == nil
+}
+
+// write exports each source with copyFn, then adds the manifest. Sources
+// are written in order of their path, so that the same workspace always
+// gives the same export.
+func write(w Writer, sources []Source, copyFn func(Source) error) (*Manifest, error) {
+	sources = slices.Clone(sources)
+	sort.SliceStable(sources, func(i, j int) bool { return sources[i].RelPath < sources[j].RelPath })
+
+	for _, src := range sources {
+		if err := copyFn(src); err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", src.Name, err)
+		}
+	}
+
+	manifest := NewManifest(sources)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := w.WriteData(ManifestFileName, append(data, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// CopyTree writes every file below root (or root itself if it is a file)
+// to w under prefix, skipping .git directories and files.
+func CopyTree(w Writer, root, prefix string) error {
+	prefix = filepath.ToSlash(prefix)
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Name() == ".git" {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		name := prefix
+		if rel != "." {
+			name = path.Join(prefix, filepath.ToSlash(rel))
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return w.WriteFile(name, p, info)
+	})
+}
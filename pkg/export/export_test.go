@@ -0,0 +1,247 @@
+package export
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func setupSource(t *testing.T) string {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "repo")
+	files := map[string]string{
+		"README.md":   "# repo",
+		"src/main.go": "package main",
+		".git/HEAD":   "ref: refs/heads/main",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestVendor_Directory(t *testing.T) {
+	src := setupSource(t)
+	dest := filepath.Join(t.TempDir(), "drop")
+
+	w, err := NewWriter(dest)
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	sources := []Source{{Name: "repo", URL: "https://example.com/repo.git", SHA: "abc123", Path: src, RelPath: "libs/repo"}}
+	if _, err := Vendor(w, sources); err != nil {
+		t.Fatalf("Vendor failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dest, "libs", "repo", "src", "main.go")); err != nil {
+		t.Error("expected src/main.go to be exported")
+	}
+	if _, err := os.Stat(filepath.Join(dest, "libs", "repo", ".git")); err == nil {
+		t.Error("expected .git to be excluded")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dest, ManifestFileName))
+	if err != nil {
+		t.Fatalf("expected manifest: %v", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("invalid manifest: %v", err)
+	}
+	if len(manifest.Repositories) != 1 || manifest.Repositories[0].SHA != "abc123" {
+		t.Errorf("unexpected manifest: %+v", manifest)
+	}
+}
+
+func TestVendor_GitTrackedFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	src := filepath.Join(t.TempDir(), "repo")
+	for name, content := range map[string]string{
+		".gitignore":   "build/\n.env\n",
+		"main.go":      "package main",
+		"build/app":    "binary",
+		".env":         "SECRET=1",
+		"untracked.go": "package main",
+	} {
+		path := filepath.Join(src, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", ".gitignore", "main.go"},
+		{"-c", "user.email=test@test.com", "-c", "user.name=Test User", "commit", "--quiet", "-m", "Initial commit"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = src
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	dest := filepath.Join(t.TempDir(), "drop")
+	w, err := NewWriter(dest)
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	sources := []Source{
+		{Name: "b", Type: "git", Path: src, RelPath: "b"},
+		{Name: "a", Type: "git", Path: src, RelPath: "a"},
+	}
+	manifest, err := Vendor(w, sources)
+	if err != nil {
+		t.Fatalf("Vendor failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	var got []string
+	_ = filepath.WalkDir(filepath.Join(dest, "a"), func(p string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(filepath.Join(dest, "a"), p)
+			got = append(got, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if want := []string{".gitignore", "main.go"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected only tracked files %v, got %v", want, got)
+	}
+	if manifest.Repositories[0].Name != "a" || manifest.Repositories[1].Name != "b" {
+		t.Errorf("expected sources in order of path, got %+v", manifest.Repositories)
+	}
+}
+
+func TestVendor_Tarball(t *testing.T) {
+	src := setupSource(t)
+	dest := filepath.Join(t.TempDir(), "drop.tar.gz")
+
+	w, err := NewWriter(dest)
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if _, err := Vendor(w, []Source{{Name: "repo", Path: src, RelPath: "repo"}}); err != nil {
+		t.Fatalf("Vendor failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	f, err := os.Open(dest)
+	if err != nil {
+		t.Fatalf("failed to open tarball: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("invalid gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid tar: %v", err)
+		}
+		names = append(names, hdr.Name)
+	}
+	sort.Strings(names)
+
+	expected := []string{ManifestFileName, "repo/README.md", "repo/src/main.go"}
+	if len(names) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], names[i])
+		}
+	}
+}
+
+func TestNewDirWriter_NonEmpty(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("x"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := NewDirWriter(dir); err == nil {
+		t.Error("expected error for non-empty destination")
+	}
+}
+
+func TestSplitRepoURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		base string
+		name string
+	}{
+		{"https://github.com/org/repo.git", "https://github.com/org", "repo.git"},
+		{"git@github.com:org/repo.git", "git@github.com:org", "repo.git"},
+		{"git@host:repo.git", "git@host:", "repo.git"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			base, name := splitRepoURL(tt.url)
+			if base != tt.base || name != tt.name {
+				t.Errorf("expected (%s, %s), got (%s, %s)", tt.base, tt.name, base, name)
+			}
+		})
+	}
+}
+
+func TestRepoManifest(t *testing.T) {
+	sources := []Source{
+		{Name: "app", URL: "https://github.com/org/app.git", Type: "git", SHA: "aaa111", Branch: "main", RelPath: "app"},
+		{Name: "lib", URL: "https://gitlab.com/group/lib.git", Type: "git", SHA: "bbb222", RelPath: "libs/lib"},
+		{Name: "blob", URL: "https://example.com/file.tar.gz", Type: "http", SHA: "ccc333", RelPath: "blob"},
+	}
+
+	data, err := RepoManifest(sources)
+	if err != nil {
+		t.Fatalf("RepoManifest failed: %v", err)
+	}
+
+	out := string(data)
+	expected := []string{
+		`<remote name="github" fetch="https://github.com/org"></remote>`,
+		`<remote name="gitlab" fetch="https://gitlab.com/group"></remote>`,
+		`<project name="app.git" path="app" remote="github" revision="aaa111" upstream="main"></project>`,
+		`<project name="lib.git" path="libs/lib" remote="gitlab" revision="bbb222"></project>`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Errorf("expected %q in manifest:\n%s", e, out)
+		}
+	}
+	if strings.Contains(out, "blob") {
+		t.Error("expected HTTP sources to be excluded")
+	}
+}
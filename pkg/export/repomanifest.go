@@ -0,0 +1,108 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// repoManifest is the XML structure of a Google repo manifest.
+type repoManifest struct {
+	XMLName  xml.Name      `xml:"manifest"`
+	Remotes  []repoRemote  `xml:"remote"`
+	Projects []repoProject `xml:"project"`
+}
+
+type repoRemote struct {
+	Name  string `xml:"name,attr"`
+	Fetch string `xml:"fetch,attr"`
+}
+
+type repoProject struct {
+	Name     string `xml:"name,attr"`
+	Path     string `xml:"path,attr"`
+	Remote   string `xml:"remote,attr"`
+	Revision string `xml:"revision,attr"`
+	Upstream string `xml:"upstream,attr,omitempty"`
+}
+
+// RepoManifest renders git sources as a repo tool manifest.xml with each
+// project pinned to its SHA. Sources are grouped into remotes by the URL
+// prefix preceding the repository name.
+func RepoManifest(sources []Source) ([]byte, error) {
+	m := repoManifest{}
+	remotes := make(map[string]string) // fetch base -> remote name
+	usedNames := make(map[string]bool)
+
+	for _, src := range sources {
+		if src.Type != "git" {
+			continue
+		}
+		if src.SHA == "" {
+			return nil, fmt.Errorf("%s: no locked SHA", src.Name)
+		}
+
+		base, name := splitRepoURL(src.URL)
+		remote, ok := remotes[base]
+		if !ok {
+			remote = remoteName(base, usedNames)
+			remotes[base] = remote
+			m.Remotes = append(m.Remotes, repoRemote{Name: remote, Fetch: base})
+		}
+
+		m.Projects = append(m.Projects, repoProject{
+			Name:     name,
+			Path:     src.RelPath,
+			Remote:   remote,
+			Revision: src.SHA,
+			Upstream: src.Branch,
+		})
+	}
+
+	sort.Slice(m.Projects, func(i, j int) bool {
+		return m.Projects[i].Path < m.Projects[j].Path
+	})
+
+	data, err := xml.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// splitRepoURL splits a repository URL into a fetch base and a project
+// name, e.g. "https://github.com/org/repo.git" becomes
+// ("https://github.com/org", "repo.git"). SCP-style SSH URLs without a
+// path separator split at the colon.
+func splitRepoURL(rawURL string) (string, string) {
+	if i := strings.LastIndex(rawURL, "/"); i >= 0 && !strings.HasSuffix(rawURL[:i], ":/") {
+		return rawURL[:i], rawURL[i+1:]
+	}
+	if i := strings.LastIndex(rawURL, ":"); i >= 0 {
+		return rawURL[:i+1], rawURL[i+1:]
+	}
+	return rawURL, rawURL
+}
+
+// remoteName derives a unique remote name from a fetch base.
+func remoteName(base string, used map[string]bool) string {
+	name := "origin"
+	if u, err := url.Parse(base); err == nil && u.Host != "" {
+		name = u.Hostname()
+	} else if at := strings.Index(base, "@"); at >= 0 {
+		name = strings.TrimSuffix(strings.SplitN(base[at+1:], ":", 2)[0], "/")
+	}
+	name = strings.Split(name, ".")[0]
+	if name == "" {
+		name = "origin"
+	}
+
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+	used[unique] = true
+	return unique
+}
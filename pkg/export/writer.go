@@ -0,0 +1,263 @@
+package export
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Writer receives files for an export destination.
+type Writer interface {
+	// WriteFile adds the file at src under the slash-separated name.
+	WriteFile(name, src string, info fs.FileInfo) error
+	// WriteData adds in-memory content under name.
+	WriteData(name string, data []byte) error
+	// Close finalizes the destination.
+	Close() error
+}
+
+// IsTarball returns true if dest names a gzipped tarball.
+func IsTarball(dest string) bool {
+	return strings.HasSuffix(dest, ".tar.gz") || strings.HasSuffix(dest, ".tgz")
+}
+
+// IsZip returns true if dest names a zip archive.
+func IsZip(dest string) bool {
+	return strings.HasSuffix(dest, ".zip")
+}
+
+// NewWriter returns a tarball writer if dest ends in .tar.gz or .tgz, a
+// zip writer if it ends in .zip, and a directory writer otherwise.
+func NewWriter(dest string) (Writer, error) {
+	switch {
+	case IsTarball(dest):
+		return NewTarWriter(dest)
+	case IsZip(dest):
+		return NewZipWriter(dest)
+	default:
+		return NewDirWriter(dest)
+	}
+}
+
+// DirWriter copies files into a directory.
+type DirWriter struct {
+	root string
+}
+
+// NewDirWriter creates a writer rooted at dir, which must be empty or absent.
+func NewDirWriter(dir string) (*DirWriter, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read destination: %w", err)
+	}
+	if len(entries) > 0 {
+		return nil, fmt.Errorf("destination is not empty: %s", dir)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create destination: %w", err)
+	}
+	return &DirWriter{root: dir}, nil
+}
+
+// WriteFile copies a file or symlink into the directory.
+func (w *DirWriter) WriteFile(name, src string, info fs.FileInfo) error {
+	dest := filepath.Join(w.root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		link, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(link, dest)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// WriteData writes content to a file in the directory.
+func (w *DirWriter) WriteData(name string, data []byte) error {
+	dest := filepath.Join(w.root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dest, data, 0644)
+}
+
+// Close is a no-op for directories.
+func (w *DirWriter) Close() error {
+	return nil
+}
+
+// TarWriter writes files into a gzipped tarball.
+type TarWriter struct {
+	file *os.File
+	gz   *gzip.Writer
+	tw   *tar.Writer
+}
+
+// NewTarWriter creates a gzipped tarball at path.
+func NewTarWriter(path string) (*TarWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tarball: %w", err)
+	}
+	gz := gzip.NewWriter(f)
+	return &TarWriter{file: f, gz: gz, tw: tar.NewWriter(gz)}, nil
+}
+
+// WriteFile adds a file or symlink to the tarball.
+func (w *TarWriter) WriteFile(name, src string, info fs.FileInfo) error {
+	var link string
+	if info.Mode()&os.ModeSymlink != 0 {
+		var err error
+		if link, err = os.Readlink(src); err != nil {
+			return err
+		}
+	}
+
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := w.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if link != "" {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	_, err = io.Copy(w.tw, in)
+	return err
+}
+
+// WriteData adds in-memory content to the tarball.
+func (w *TarWriter) WriteData(name string, data []byte) error {
+	hdr := &tar.Header{
+		Name: name,
+		Mode: 0644,
+		Size: int64(len(data)),
+	}
+	if err := w.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := w.tw.Write(data)
+	return err
+}
+
+// Close flushes and closes the tarball.
+func (w *TarWriter) Close() error {
+	if err := w.tw.Close(); err != nil {
+		_ = w.gz.Close()
+		_ = w.file.Close()
+		return fmt.Errorf("failed to finalize tarball: %w", err)
+	}
+	if err := w.gz.Close(); err != nil {
+		_ = w.file.Close()
+		return fmt.Errorf("failed to finalize tarball: %w", err)
+	}
+	return w.file.Close()
+}
+
+// ZipWriter writes files into a zip archive.
+type ZipWriter struct {
+	file *os.File
+	zw   *zip.Writer
+}
+
+// NewZipWriter creates a zip archive at path.
+func NewZipWriter(path string) (*ZipWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zip archive: %w", err)
+	}
+	return &ZipWriter{file: f, zw: zip.NewWriter(f)}, nil
+}
+
+// WriteFile adds a file or symlink to the zip archive.
+func (w *ZipWriter) WriteFile(name, src string, info fs.FileInfo) error {
+	hdr, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	hdr.Method = zip.Deflate
+
+	out, err := w.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+
+	// Symlinks are stored with their target as content
+	if info.Mode()&os.ModeSymlink != 0 {
+		link, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(out, link)
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// WriteData adds in-memory content to the zip archive.
+func (w *ZipWriter) WriteData(name string, data []byte) error {
+	out, err := w.zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+// Close flushes and closes the zip archive.
+func (w *ZipWriter) Close() error {
+	if err := w.zw.Close(); err != nil {
+		_ = w.file.Close()
+		return fmt.Errorf("failed to finalize zip archive: %w", err)
+	}
+	return w.file.Close()
+}
@@ -0,0 +1,102 @@
+package generate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// Repository is the per-repository data available to templates.
+type Repository struct {
+	Name string
+	URL  string
+	Type string
+	Path string
+	Ref  string // Requested ref (branch, tag, or commit)
+	SHA  string // Resolved SHA or content hash
+}
+
+// Data is the root object passed to templates.
+type Data struct {
+	GeneratedAt  time.Time
+	Repositories []Repository
+}
+
+// Repo returns the named repository, or nil if absent.
+func (d Data) Repo(name string) *Repository {
+	for i := range d.Repositories {
+		if d.Repositories[i].Name == name {
+			return &d.Repositories[i]
+		}
+	}
+	return nil
+}
+
+// Funcs are the helper functions available to templates.
+var Funcs = template.FuncMap{
+	"short": func(sha string) string {
+		if len(sha) > 8 {
+			return sha[:8]
+		}
+		return sha
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"ident": Ident,
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Ident converts a name into an upper-case C-style identifier,
+// e.g. "my-app" becomes "MY_APP".
+func Ident(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r) || (unicode.IsDigit(r) && i > 0):
+			b.WriteRune(unicode.ToUpper(r))
+		case unicode.IsDigit(r):
+			b.WriteRune('_')
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+// Render executes the template at templatePath with data and writes the
+// result to outputPath. The output is only rewritten when its content
+// changes, so build systems don't see spurious modifications.
+func Render(templatePath, outputPath string, data Data) (bool, error) {
+	tmpl, err := template.New(filepath.Base(templatePath)).Funcs(Funcs).ParseFiles(templatePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return false, fmt.Errorf("failed to render template: %w", err)
+	}
+
+	if existing, err := os.ReadFile(outputPath); err == nil && bytes.Equal(existing, buf.Bytes()) {
+		return false, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return false, fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		return false, fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return true, nil
+}
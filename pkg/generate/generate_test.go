@@ -0,0 +1,78 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIdent(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"my-app", "MY_APP"},
+		{"api.v2", "API_V2"},
+		{"3rdparty", "_3RDPARTY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := Ident(tt.input); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	tmpDir := t.TempDir()
+	tmplPath := filepath.Join(tmpDir, "versions.h.tmpl")
+	outPath := filepath.Join(tmpDir, "out", "versions.h")
+
+	tmpl := `{{range .Repositories}}#define {{ident .Name}}_SHA "{{short .SHA}}"
+{{end}}`
+	if err := os.WriteFile(tmplPath, []byte(tmpl), 0644); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+
+	data := Data{Repositories: []Repository{
+		{Name: "my-app", SHA: "abc123def4567890"},
+	}}
+
+	changed, err := Render(tmplPath, outPath, data)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !changed {
+		t.Error("expected first render to write output")
+	}
+
+	content, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if string(content) != "#define MY_APP_SHA \"abc123de\"\n" {
+		t.Errorf("unexpected output: %q", content)
+	}
+
+	// Identical content is not rewritten
+	changed, err = Render(tmplPath, outPath, data)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if changed {
+		t.Error("expected unchanged output not to be rewritten")
+	}
+}
+
+func TestData_Repo(t *testing.T) {
+	data := Data{Repositories: []Repository{{Name: "a"}, {Name: "b"}}}
+
+	if r := data.Repo("b"); r == nil || r.Name != "b" {
+		t.Error("expected to find repo b")
+	}
+	if r := data.Repo("missing"); r != nil {
+		t.Error("expected nil for missing repo")
+	}
+}
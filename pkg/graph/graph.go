@@ -0,0 +1,213 @@
+// Package graph renders the structure of a workspace as Graphviz DOT or
+// Mermaid diagrams: its projects, repositories, tags, and the dependencies
+// declared between repositories.
+package graph
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/tierone/harbormaster/pkg/config"
+)
+
+// Formats lists the supported output formats.
+var Formats = []string{"dot", "mermaid"}
+
+// Node kinds.
+const (
+	KindProject    = "project"
+	KindRepository = "repository"
+	KindTag        = "tag"
+)
+
+// Edge kinds.
+const (
+	EdgeContains  = "contains"   // Project to repository
+	EdgeTagged    = "tagged"     // Repository or project to tag
+	EdgeDependsOn = "depends on" // Repository to repository
+)
+
+// Node is a vertex of the graph.
+type Node struct {
+	Kind string
+	Name string
+}
+
+// ID returns an identifier for the node that is unique within a graph
+// and safe to use in any supported format.
+func (n Node) ID() string {
+	var b strings.Builder
+	b.WriteString(n.Kind[:1] + "_")
+	for _, r := range n.Name {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		} else {
+			// Keep distinct names distinct
+			fmt.Fprintf(&b, "_%x_", r)
+		}
+	}
+	return b.String()
+}
+
+// Edge connects two nodes.
+type Edge struct {
+	From Node
+	To   Node
+	Kind string
+}
+
+// Graph is the structure of a workspace.
+type Graph struct {
+	Nodes []Node
+	Edges []Edge
+}
+
+// Options controls what New includes.
+type Options struct {
+	Tags bool // Include tag nodes
+}
+
+// New builds the graph of repos: the projects that contain them, their
+// tags, and their declared dependencies. Repositories that repos depend
+// on are included even if not selected, so that no edge is lost.
+func New(cfg *config.Config, repos []config.Repository, opts Options) *Graph {
+	g := &Graph{}
+	seen := make(map[string]bool)
+	node := func(kind, name string) Node {
+		n := Node{Kind: kind, Name: name}
+		if !seen[n.ID()] {
+			seen[n.ID()] = true
+			g.Nodes = append(g.Nodes, n)
+		}
+		return n
+	}
+
+	selected := make(map[string]bool)
+	for _, repo := range repos {
+		selected[repo.Name] = true
+	}
+
+	for _, proj := range cfg.Projects {
+		var members []string
+		for _, name := range proj.Repositories {
+			if selected[name] {
+				members = append(members, name)
+			}
+		}
+		if len(members) == 0 {
+			continue
+		}
+		p := node(KindProject, proj.Name)
+		for _, name := range members {
+			g.Edges = append(g.Edges, Edge{From: p, To: node(KindRepository, name), Kind: EdgeContains})
+		}
+		if opts.Tags {
+			for _, tag := range proj.Tags {
+				g.Edges = append(g.Edges, Edge{From: p, To: node(KindTag, tag), Kind: EdgeTagged})
+			}
+		}
+	}
+
+	for i := range repos {
+		r := node(KindRepository, repos[i].Name)
+		for _, dep := range repos[i].DependsOn {
+			g.Edges = append(g.Edges, Edge{From: r, To: node(KindRepository, dep), Kind: EdgeDependsOn})
+		}
+		if opts.Tags {
+			for _, tag := range repos[i].Tags {
+				g.Edges = append(g.Edges, Edge{From: r, To: node(KindTag, tag), Kind: EdgeTagged})
+			}
+		}
+	}
+
+	kindOrder := []string{KindProject, KindRepository, KindTag}
+	sort.SliceStable(g.Nodes, func(i, j int) bool {
+		a, b := g.Nodes[i], g.Nodes[j]
+		if a.Kind != b.Kind {
+			return slices.Index(kindOrder, a.Kind) < slices.Index(kindOrder, b.Kind)
+		}
+		return a.Name < b.Name
+	})
+	return g
+}
+
+// Render renders g in format, one of Formats.
+func Render(g *Graph, format string) ([]byte, error) {
+	switch format {
+	case "dot":
+		return DOT(g), nil
+	case "mermaid":
+		return Mermaid(g), nil
+	default:
+		return nil, fmt.Errorf("invalid format: %s (must be one of %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// DOT renders g as a Graphviz digraph.
+func DOT(g *Graph) []byte {
+	var b strings.Builder
+	b.WriteString("digraph workspace {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [fontname=\"Helvetica\"];\n\n")
+
+	shapes := map[string]string{
+		KindProject:    "shape=folder, style=filled, fillcolor=lightblue",
+		KindRepository: "shape=box",
+		KindTag:        "shape=ellipse, style=dashed",
+	}
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&b, "  %s [label=%s, %s];\n", n.ID(), dotQuote(n.Name), shapes[n.Kind])
+	}
+	if len(g.Edges) > 0 {
+		b.WriteString("\n")
+	}
+
+	styles := map[string]string{
+		EdgeContains:  "",
+		EdgeTagged:    " [style=dashed, arrowhead=none]",
+		EdgeDependsOn: " [label=\"depends on\", color=firebrick]",
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s%s;\n", e.From.ID(), e.To.ID(), styles[e.Kind])
+	}
+	b.WriteString("}\n")
+	return []byte(b.String())
+}
+
+// dotQuote quotes s as a DOT string.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// Mermaid renders g as a Mermaid flowchart.
+func Mermaid(g *Graph) []byte {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	shapes := map[string][2]string{
+		KindProject:    {"[[", "]]"},
+		KindRepository: {"[", "]"},
+		KindTag:        {"([", "])"},
+	}
+	for _, n := range g.Nodes {
+		shape := shapes[n.Kind]
+		fmt.Fprintf(&b, "  %s%s%s%s\n", n.ID(), shape[0], mermaidQuote(n.Name), shape[1])
+	}
+
+	arrows := map[string]string{
+		EdgeContains:  "-->",
+		EdgeTagged:    "-.-",
+		EdgeDependsOn: "-->|depends on|",
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s %s %s\n", e.From.ID(), arrows[e.Kind], e.To.ID())
+	}
+	return []byte(b.String())
+}
+
+// mermaidQuote quotes s as a Mermaid node label.
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
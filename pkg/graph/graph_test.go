@@ -0,0 +1,122 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tierone/harbormaster/pkg/config"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{
+		Repositories: []config.Repository{
+			{Name: "api", Tags: []string{"backend"}, DependsOn: []string{"lib-core"}},
+			{Name: "lib-core", Tags: []string{"backend"}},
+			{Name: "web", DependsOn: []string{"api"}},
+		},
+		Projects: []config.Project{
+			{Name: "platform", Repositories: []string{"api", "web"}, Tags: []string{"team-a"}},
+			{Name: "unrelated", Repositories: []string{"lib-core"}},
+		},
+	}
+}
+
+func TestNew(t *testing.T) {
+	cfg := testConfig()
+
+	tests := []struct {
+		name  string
+		repos []string
+		opts  Options
+		nodes string
+		edges int
+	}{
+		{
+			name:  "all with tags",
+			repos: []string{"api", "lib-core", "web"},
+			opts:  Options{Tags: true},
+			nodes: "p_platform p_unrelated r_api r_lib_2d_core r_web t_backend t_team_2d_a",
+			edges: 8,
+		},
+		{
+			name:  "dependencies pulled in",
+			repos: []string{"web"},
+			nodes: "p_platform r_api r_web",
+			edges: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var repos []config.Repository
+			for _, name := range tt.repos {
+				repo, _ := cfg.GetRepository(name)
+				repos = append(repos, *repo)
+			}
+
+			g := New(cfg, repos, tt.opts)
+
+			var ids []string
+			for _, n := range g.Nodes {
+				ids = append(ids, n.ID())
+			}
+			if got := strings.Join(ids, " "); got != tt.nodes {
+				t.Errorf("nodes = %q, want %q", got, tt.nodes)
+			}
+			if len(g.Edges) != tt.edges {
+				t.Errorf("got %d edges, want %d: %+v", len(g.Edges), tt.edges, g.Edges)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	cfg := testConfig()
+	repo, _ := cfg.GetRepository("web")
+	g := New(cfg, []config.Repository{*repo}, Options{})
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"dot", []string{
+			"digraph workspace {",
+			`p_platform [label="platform", shape=folder`,
+			"p_platform -> r_web;",
+			`r_web -> r_api [label="depends on"`,
+		}},
+		{"mermaid", []string{
+			"flowchart LR",
+			`p_platform[["platform"]]`,
+			`r_web["web"]`,
+			"r_web -->|depends on| r_api",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			data, err := Render(g, tt.format)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("output missing %q:\n%s", want, data)
+				}
+			}
+		})
+	}
+
+	if _, err := Render(g, "svg"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestQuoting(t *testing.T) {
+	if got := dotQuote(`a "b" \c`); got != `"a \"b\" \\c"` {
+		t.Errorf("dotQuote = %s", got)
+	}
+	if got := mermaidQuote(`a "b"`); got != `"a #quot;b#quot;"` {
+		t.Errorf("mermaidQuote = %s", got)
+	}
+}
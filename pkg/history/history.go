@@ -0,0 +1,188 @@
+// Package history records sync and remove operations in a local,
+// append-only store so that past runs can be inspected.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/lockfile"
+	"github.com/tierone/harbormaster/pkg/types"
+)
+
+// FileName is the name of the history file inside the state directory.
+const FileName = "history.jsonl"
+
+// Commands recorded in the history.
+const (
+	CommandSync   = "sync"
+	CommandRemove = "remove"
+)
+
+// Entry is one recorded operation.
+type Entry struct {
+	ID          int               `json:"id"`
+	Time        time.Time         `json:"time"`
+	Command     string            `json:"command"`
+	Filter      string            `json:"filter,omitempty"`
+	DurationMs  int64             `json:"duration_ms"`
+	Results     []Result          `json:"results,omitempty"`
+	LockChanges []lockfile.Change `json:"lock_changes,omitempty"`
+}
+
+// Result is the outcome for one repository.
+type Result struct {
+	Name       string `json:"name"`
+	Success    bool   `json:"success"`
+	SHA        string `json:"sha,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+	Code       string `json:"code,omitempty"`
+	LogPath    string `json:"log_path,omitempty"`
+}
+
+// NewSyncEntry builds an entry for a sync. changes is the lock file delta
+// the sync produced.
+func NewSyncEntry(filter string, result *types.SyncResult, changes []lockfile.Change) *Entry {
+	e := &Entry{
+		Time:        time.Now(),
+		Command:     CommandSync,
+		Filter:      filter,
+		DurationMs:  result.Duration.Milliseconds(),
+		Results:     make([]Result, len(result.Results)),
+		LockChanges: changes,
+	}
+	for i, r := range result.Results {
+		e.Results[i] = Result{
+			Name:       r.RepoName,
+			Success:    r.Success,
+			SHA:        r.CommitSHA,
+			DurationMs: r.Duration.Milliseconds(),
+			LogPath:    r.LogPath,
+		}
+		if r.Error != nil {
+			e.Results[i].Error = r.Error.Error()
+			e.Results[i].Code = string(errcode.Of(r.Error))
+		}
+	}
+	return e
+}
+
+// Succeeded returns the number of repositories that succeeded.
+func (e *Entry) Succeeded() int {
+	n := 0
+	for _, r := range e.Results {
+		if r.Success {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed returns the number of repositories that failed.
+func (e *Entry) Failed() int {
+	return len(e.Results) - e.Succeeded()
+}
+
+// Store is a history file of JSON entries, one per line.
+type Store struct {
+	path string
+}
+
+// NewStore returns the store at path. The file is created on the first
+// Append.
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// DefaultPath returns the history file of the workspace whose config is
+// in dir.
+func DefaultPath(dir string) string {
+	return filepath.Join(dir, config.StateDirName, FileName)
+}
+
+// Path returns the path of the history file.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Append assigns the next ID to e and writes it to the store.
+func (s *Store) Append(e *Entry) error {
+	entries, err := s.List()
+	if err != nil {
+		return err
+	}
+	e.ID = 1
+	if len(entries) > 0 {
+		e.ID = entries[len(entries)-1].ID + 1
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode history entry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return f.Close()
+}
+
+// List returns all entries, oldest first. A missing file has no entries.
+func (s *Store) List() ([]Entry, error) {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse history line %d: %w", line, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return entries, nil
+}
+
+// Get returns the entry with the given ID.
+func (s *Store) Get(id int) (*Entry, error) {
+	entries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].ID == id {
+			return &entries[i], nil
+		}
+	}
+	return nil, fmt.Errorf("history entry not found: %d", id)
+}
@@ -0,0 +1,99 @@
+package history
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/lockfile"
+	"github.com/tierone/harbormaster/pkg/types"
+)
+
+func TestStore_AppendAndList(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "state", FileName))
+
+	entries, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected empty history, got %d entries", len(entries))
+	}
+
+	for _, cmd := range []string{CommandSync, CommandRemove, CommandSync} {
+		if err := store.Append(&Entry{Time: time.Now(), Command: cmd}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	entries, err = store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	for i, e := range entries {
+		if e.ID != i+1 {
+			t.Errorf("expected ID %d, got %d", i+1, e.ID)
+		}
+	}
+
+	e, err := store.Get(2)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if e.Command != CommandRemove {
+		t.Errorf("expected remove, got %s", e.Command)
+	}
+
+	if _, err := store.Get(9); err == nil {
+		t.Error("expected error for unknown ID")
+	}
+}
+
+func TestStore_List_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte("{\"id\":1}\nnot json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewStore(path).List()
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected parse error on line 2, got %v", err)
+	}
+}
+
+func TestNewSyncEntry(t *testing.T) {
+	result := &types.SyncResult{
+		Duration: 1500 * time.Millisecond,
+		Results: []types.OperationResult{
+			{RepoName: "api", Success: true, CommitSHA: "abc", Duration: time.Second},
+			{RepoName: "web", Error: errors.New("clone failed"), LogPath: "/logs/web.log"},
+		},
+	}
+	changes := []lockfile.Change{{Name: "api", Before: "old", After: "abc"}}
+
+	e := NewSyncEntry("all", result, changes)
+	if e.Command != CommandSync || e.Filter != "all" || e.DurationMs != 1500 {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+	if e.Succeeded() != 1 || e.Failed() != 1 {
+		t.Errorf("expected 1 succeeded and 1 failed, got %d and %d", e.Succeeded(), e.Failed())
+	}
+	if e.Results[1].Error != "clone failed" || e.Results[1].LogPath != "/logs/web.log" {
+		t.Errorf("unexpected failed result: %+v", e.Results[1])
+	}
+	if len(e.LockChanges) != 1 {
+		t.Errorf("expected lock changes to be kept, got %+v", e.LockChanges)
+	}
+}
+
+func TestDefaultPath(t *testing.T) {
+	if got := DefaultPath("/ws"); got != filepath.Join("/ws", ".harbormaster", FileName) {
+		t.Errorf("unexpected path: %s", got)
+	}
+}
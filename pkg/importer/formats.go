@@ -0,0 +1,220 @@
+package importer
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// vcstoolFile is the structure of a vcstool .repos file.
+type vcstoolFile struct {
+	Repositories map[string]struct {
+		Type    string `yaml:"type"`
+		URL     string `yaml:"url"`
+		Version string `yaml:"version"`
+	} `yaml:"repositories"`
+}
+
+func parseVcstool(data []byte) (*Result, error) {
+	var f vcstoolFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse vcstool file: %w", err)
+	}
+
+	// Map iteration order is random; sort for stable output
+	paths := make([]string, 0, len(f.Repositories))
+	for p := range f.Repositories {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	result := &Result{}
+	names := make(map[string]bool)
+	for _, p := range paths {
+		entry := f.Repositories[p]
+		if entry.Type != "" && entry.Type != "git" {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: unsupported type %s", p, entry.Type))
+			continue
+		}
+
+		// Fall back to the full path when base names collide
+		name := nameFromPath(p)
+		if names[name] {
+			name = strings.ReplaceAll(strings.Trim(p, "/"), "/", "-")
+		}
+		names[name] = true
+
+		repo := config.Repository{
+			Name: name,
+			URL:  entry.URL,
+			Type: config.RepoTypeGit,
+			Path: p,
+		}
+		applyRef(&repo, entry.Version)
+		result.Repositories = append(result.Repositories, repo)
+	}
+
+	return result, nil
+}
+
+// westFile is the structure of a Zephyr west.yml manifest.
+type westFile struct {
+	Manifest struct {
+		Defaults struct {
+			Remote   string `yaml:"remote"`
+			Revision string `yaml:"revision"`
+		} `yaml:"defaults"`
+		Remotes []struct {
+			Name    string `yaml:"name"`
+			URLBase string `yaml:"url-base"`
+		} `yaml:"remotes"`
+		Projects []struct {
+			Name     string `yaml:"name"`
+			Remote   string `yaml:"remote"`
+			RepoPath string `yaml:"repo-path"`
+			URL      string `yaml:"url"`
+			Revision string `yaml:"revision"`
+			Path     string `yaml:"path"`
+		} `yaml:"projects"`
+	} `yaml:"manifest"`
+}
+
+// westDefaultRevision is the revision west uses when none is specified.
+const westDefaultRevision = "master"
+
+func parseWest(data []byte) (*Result, error) {
+	var f westFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse west manifest: %w", err)
+	}
+
+	remotes := make(map[string]string)
+	for _, r := range f.Manifest.Remotes {
+		remotes[r.Name] = strings.TrimSuffix(r.URLBase, "/")
+	}
+
+	result := &Result{}
+	for _, p := range f.Manifest.Projects {
+		url := p.URL
+		if url == "" {
+			remote := p.Remote
+			if remote == "" {
+				remote = f.Manifest.Defaults.Remote
+			}
+			base, ok := remotes[remote]
+			if !ok {
+				result.Skipped = append(result.Skipped, fmt.Sprintf("%s: unknown remote %q", p.Name, remote))
+				continue
+			}
+			repoPath := p.RepoPath
+			if repoPath == "" {
+				repoPath = p.Name
+			}
+			url = base + "/" + repoPath
+		}
+
+		revision := p.Revision
+		if revision == "" {
+			revision = f.Manifest.Defaults.Revision
+		}
+		if revision == "" {
+			revision = westDefaultRevision
+		}
+
+		repo := config.Repository{
+			Name: p.Name,
+			URL:  url,
+			Type: config.RepoTypeGit,
+			Path: p.Path,
+		}
+		if repo.Path == "" {
+			repo.Path = p.Name
+		}
+		applyRef(&repo, revision)
+		result.Repositories = append(result.Repositories, repo)
+	}
+
+	return result, nil
+}
+
+// gitmanFile is the structure of a gitman.yml file.
+type gitmanFile struct {
+	Location string `yaml:"location"`
+	Sources  []struct {
+		Name string `yaml:"name"`
+		Type string `yaml:"type"`
+		Repo string `yaml:"repo"`
+		Rev  string `yaml:"rev"`
+	} `yaml:"sources"`
+}
+
+func parseGitman(data []byte) (*Result, error) {
+	var f gitmanFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse gitman file: %w", err)
+	}
+
+	result := &Result{}
+	for _, s := range f.Sources {
+		if s.Type != "" && s.Type != "git" {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: unsupported type %s", s.Name, s.Type))
+			continue
+		}
+
+		name := s.Name
+		if name == "" {
+			name = strings.TrimSuffix(nameFromPath(s.Repo), ".git")
+		}
+
+		repo := config.Repository{
+			Name: name,
+			URL:  s.Repo,
+			Type: config.RepoTypeGit,
+			Path: name,
+		}
+		if f.Location != "" {
+			repo.Path = path.Join(f.Location, name)
+		}
+		applyRef(&repo, s.Rev)
+		result.Repositories = append(result.Repositories, repo)
+	}
+
+	return result, nil
+}
+
+// parseList parses a plain list of repositories, one per line: a URL,
+// optionally followed by a name and a ref, separated by whitespace. A
+// name of "-" stands for the one derived from the URL. Blank lines and
+// lines starting with # are ignored. The repository type is left to be
+// detected from the URL.
+func parseList(data []byte) (*Result, error) {
+	result := &Result{}
+	for i, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) > 3 {
+			return nil, fmt.Errorf("line %d: expected a URL, a name, and a ref at most", i+1)
+		}
+
+		name := strings.TrimSuffix(nameFromPath(fields[0]), ".git")
+		if len(fields) > 1 && fields[1] != "-" {
+			name = fields[1]
+		}
+		if name == "" || name == "." {
+			return nil, fmt.Errorf("line %d: cannot derive a name from %s", i+1, fields[0])
+		}
+
+		repo := config.Repository{Name: name, URL: fields[0]}
+		if len(fields) > 2 {
+			applyRef(&repo, fields[2])
+		}
+		result.Repositories = append(result.Repositories, repo)
+	}
+	return result, nil
+}
@@ -0,0 +1,97 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/tierone/harbormaster/pkg/config"
+)
+
+// Format identifies a foreign manifest format.
+type Format string
+
+const (
+	FormatVcstool Format = "vcstool"
+	FormatWest    Format = "west"
+	FormatGitman  Format = "gitman"
+	FormatList    Format = "list" // One URL per line, with an optional name and ref
+)
+
+// Result holds repositories converted from a manifest.
+type Result struct {
+	Repositories []config.Repository
+	Skipped      []string // Human-readable reasons for skipped entries
+}
+
+// DetectFormat guesses the format of a manifest from its file name.
+func DetectFormat(file string) (Format, error) {
+	base := strings.ToLower(filepath.Base(file))
+	switch {
+	case strings.HasSuffix(base, ".repos"):
+		return FormatVcstool, nil
+	case base == "west.yml" || base == "west.yaml":
+		return FormatWest, nil
+	case base == "gitman.yml" || base == "gitman.yaml" || base == ".gitman.yml":
+		return FormatGitman, nil
+	default:
+		return "", fmt.Errorf("cannot detect manifest format of %s, use --format", filepath.Base(file))
+	}
+}
+
+// ParseFile reads and converts the manifest at path.
+func ParseFile(path string, format Format) (*Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return Parse(data, format)
+}
+
+// Parse converts manifest data in the given format.
+func Parse(data []byte, format Format) (*Result, error) {
+	switch format {
+	case FormatVcstool:
+		return parseVcstool(data)
+	case FormatWest:
+		return parseWest(data)
+	case FormatGitman:
+		return parseGitman(data)
+	case FormatList:
+		return parseList(data)
+	default:
+		return nil, fmt.Errorf("unknown manifest format: %s", format)
+	}
+}
+
+var (
+	shaRegex     = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+	versionRegex = regexp.MustCompile(`^v?\d+(\.\d+)*([-+.].*)?$`)
+)
+
+// applyRef sets the branch, tag, or commit of repo from a foreign
+// revision string. Manifests don't distinguish ref kinds, so hex strings
+// are treated as commits, version-like strings as tags, and anything
+// else as a branch.
+func applyRef(repo *config.Repository, ref string) {
+	ref = strings.TrimPrefix(ref, "refs/heads/")
+	switch {
+	case ref == "":
+	case strings.HasPrefix(ref, "refs/tags/"):
+		repo.Tag = strings.TrimPrefix(ref, "refs/tags/")
+	case shaRegex.MatchString(ref):
+		repo.Commit = ref
+	case versionRegex.MatchString(ref):
+		repo.Tag = ref
+	default:
+		repo.Branch = ref
+	}
+}
+
+// nameFromPath derives a repository name from a checkout path.
+func nameFromPath(p string) string {
+	return path.Base(strings.TrimSuffix(p, "/"))
+}
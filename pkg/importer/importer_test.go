@@ -0,0 +1,210 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/tierone/harbormaster/pkg/config"
+)
+
+func TestApplyRef(t *testing.T) {
+	tests := []struct {
+		ref      string
+		expected config.Repository
+	}{
+		{"main", config.Repository{Branch: "main"}},
+		{"refs/heads/develop", config.Repository{Branch: "develop"}},
+		{"v3.5.0", config.Repository{Tag: "v3.5.0"}},
+		{"2.1", config.Repository{Tag: "2.1"}},
+		{"refs/tags/release", config.Repository{Tag: "release"}},
+		{"4c5e1b7a9f", config.Repository{Commit: "4c5e1b7a9f"}},
+		{"", config.Repository{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			var repo config.Repository
+			applyRef(&repo, tt.ref)
+			if repo.Branch != tt.expected.Branch || repo.Tag != tt.expected.Tag || repo.Commit != tt.expected.Commit {
+				t.Errorf("expected branch=%q tag=%q commit=%q, got branch=%q tag=%q commit=%q",
+					tt.expected.Branch, tt.expected.Tag, tt.expected.Commit,
+					repo.Branch, repo.Tag, repo.Commit)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		file     string
+		expected Format
+		wantErr  bool
+	}{
+		{"ros2.repos", FormatVcstool, false},
+		{"/work/west.yml", FormatWest, false},
+		{"gitman.yml", FormatGitman, false},
+		{"manifest.xml", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got, err := DetectFormat(tt.file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DetectFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParse_Vcstool(t *testing.T) {
+	data := `repositories:
+  src/ros2/rclcpp:
+    type: git
+    url: https://github.com/ros2/rclcpp.git
+    version: rolling
+  src/vendor/rclcpp:
+    type: git
+    url: https://github.com/example/rclcpp.git
+    version: 1.2.0
+  src/legacy:
+    type: svn
+    url: https://svn.example.com/legacy
+`
+	result, err := Parse([]byte(data), FormatVcstool)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(result.Repositories) != 2 {
+		t.Fatalf("expected 2 repositories, got %d", len(result.Repositories))
+	}
+	if len(result.Skipped) != 1 {
+		t.Errorf("expected 1 skipped entry, got %v", result.Skipped)
+	}
+
+	first := result.Repositories[0]
+	if first.Name != "rclcpp" || first.Path != "src/ros2/rclcpp" || first.Branch != "rolling" {
+		t.Errorf("unexpected first repository: %+v", first)
+	}
+
+	// Colliding base names fall back to the full path
+	second := result.Repositories[1]
+	if second.Name != "src-vendor-rclcpp" || second.Tag != "1.2.0" {
+		t.Errorf("unexpected second repository: %+v", second)
+	}
+}
+
+func TestParse_West(t *testing.T) {
+	data := `manifest:
+  defaults:
+    remote: upstream
+    revision: main
+  remotes:
+    - name: upstream
+      url-base: https://github.com/zephyrproject-rtos
+  projects:
+    - name: zephyr
+      revision: v3.5.0
+      import: true
+    - name: hal_nordic
+      repo-path: hal-nordic
+      path: modules/hal/nordic
+    - name: custom
+      url: https://git.example.com/custom.git
+      revision: 0123456789abcdef0123456789abcdef01234567
+    - name: orphan
+      remote: missing
+`
+	result, err := Parse([]byte(data), FormatWest)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(result.Repositories) != 3 {
+		t.Fatalf("expected 3 repositories, got %d", len(result.Repositories))
+	}
+	if len(result.Skipped) != 1 {
+		t.Errorf("expected 1 skipped entry, got %v", result.Skipped)
+	}
+
+	zephyr := result.Repositories[0]
+	if zephyr.URL != "https://github.com/zephyrproject-rtos/zephyr" || zephyr.Tag != "v3.5.0" || zephyr.Path != "zephyr" {
+		t.Errorf("unexpected zephyr repository: %+v", zephyr)
+	}
+
+	hal := result.Repositories[1]
+	if hal.URL != "https://github.com/zephyrproject-rtos/hal-nordic" || hal.Branch != "main" || hal.Path != "modules/hal/nordic" {
+		t.Errorf("unexpected hal repository: %+v", hal)
+	}
+
+	custom := result.Repositories[2]
+	if custom.URL != "https://git.example.com/custom.git" || custom.Commit == "" {
+		t.Errorf("unexpected custom repository: %+v", custom)
+	}
+}
+
+func TestParse_Gitman(t *testing.T) {
+	data := `location: vendor/gitman
+sources:
+  - name: demo
+    type: git
+    repo: https://github.com/jacebrowning/gitman-demo
+    rev: example-branch
+  - repo: https://github.com/example/lib.git
+    rev: v1.0
+`
+	result, err := Parse([]byte(data), FormatGitman)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(result.Repositories) != 2 {
+		t.Fatalf("expected 2 repositories, got %d", len(result.Repositories))
+	}
+
+	demo := result.Repositories[0]
+	if demo.Name != "demo" || demo.Path != "vendor/gitman/demo" || demo.Branch != "example-branch" {
+		t.Errorf("unexpected demo repository: %+v", demo)
+	}
+
+	lib := result.Repositories[1]
+	if lib.Name != "lib" || lib.Tag != "v1.0" {
+		t.Errorf("unexpected lib repository: %+v", lib)
+	}
+}
+
+func TestParse_List(t *testing.T) {
+	data := `# Team repositories
+https://github.com/example/api.git
+git@github.com:example/web.git   frontend
+https://github.com/example/lib.git -   v1.2.0
+
+https://example.com/assets.tar.gz  assets
+`
+	result, err := Parse([]byte(data), FormatList)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(result.Repositories) != 4 {
+		t.Fatalf("expected 4 repositories, got %d", len(result.Repositories))
+	}
+	want := []struct{ name, url, tag string }{
+		{"api", "https://github.com/example/api.git", ""},
+		{"frontend", "git@github.com:example/web.git", ""},
+		{"lib", "https://github.com/example/lib.git", "v1.2.0"},
+		{"assets", "https://example.com/assets.tar.gz", ""},
+	}
+	for i, w := range want {
+		r := result.Repositories[i]
+		if r.Name != w.name || r.URL != w.url || r.Tag != w.tag {
+			t.Errorf("repository %d: expected %s %s %q, got %+v", i, w.name, w.url, w.tag, r)
+		}
+	}
+
+	if _, err := Parse([]byte("https://github.com/example/api.git api main extra\n"), FormatList); err == nil {
+		t.Error("expected error for a line with too many columns")
+	}
+}
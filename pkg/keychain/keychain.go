@@ -0,0 +1,86 @@
+// Package keychain stores tokens for git hosts in the operating system's
+// credential store: the Keychain on macOS, the Credential Manager on
+// Windows, and the Secret Service (through libsecret's secret-tool)
+// elsewhere, so that they need not be kept in environment variables or
+// the config.
+package keychain
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Service is the name tokens are stored under, with the host as the
+// account.
+const Service = "harbormaster"
+
+// ErrNotFound is returned when no token is stored for a host.
+var ErrNotFound = errors.New("no token stored")
+
+// ErrUnavailable is returned when the system has no credential store
+// harbormaster can use.
+var ErrUnavailable = errors.New("no keychain available")
+
+// Store holds a token per host.
+type Store interface {
+	// Get returns the token stored for host, or ErrNotFound.
+	Get(host string) (string, error)
+
+	// Set stores token for host, replacing any stored before.
+	Set(host, token string) error
+
+	// Delete removes the token stored for host, or returns ErrNotFound.
+	Delete(host string) error
+}
+
+// System returns the credential store of the operating system.
+func System() Store {
+	return systemStore{}
+}
+
+// Cache wraps a store, remembering the tokens it returned so that each
+// host is looked up once, as a credential store may be slow or prompt
+// the user.
+type Cache struct {
+	store Store
+
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+// NewCache returns a cache of store.
+func NewCache(store Store) *Cache {
+	return &Cache{store: store, tokens: make(map[string]string)}
+}
+
+// Token returns the token stored for host, or "" if there is none or it
+// can't be read.
+func (c *Cache) Token(host string) string {
+	host = normalize(host)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	token, ok := c.tokens[host]
+	if !ok {
+		token, _ = c.store.Get(host)
+		c.tokens[host] = token
+	}
+	return token
+}
+
+// Save stores token for host in the store and remembers it.
+func (c *Cache) Save(host, token string) error {
+	host = normalize(host)
+	if err := c.store.Set(host, token); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokens[host] = token
+	return nil
+}
+
+// normalize returns the account name of host.
+func normalize(host string) string {
+	return strings.ToLower(strings.TrimSpace(host))
+}
@@ -0,0 +1,62 @@
+package keychain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// notFoundStatus is the exit status of security(1) when no item matches.
+const notFoundStatus = 44
+
+// systemStore keeps tokens as generic passwords in the login keychain,
+// through security(1).
+type systemStore struct{}
+
+func (systemStore) Get(host string) (string, error) {
+	out, err := security(nil, "find-generic-password", "-s", Service, "-a", normalize(host), "-w")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+func (systemStore) Set(host, token string) error {
+	// Commands read from stdin keep the token off the command line
+	cmd := fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n",
+		quote(Service), quote(normalize(host)), hex.EncodeToString([]byte(token)))
+	_, err := security(strings.NewReader(cmd), "-i")
+	return err
+}
+
+func (systemStore) Delete(host string) error {
+	_, err := security(nil, "delete-generic-password", "-s", Service, "-a", normalize(host))
+	return err
+}
+
+// security runs security(1) with stdin, returning its output.
+func security(stdin *strings.Reader, args ...string) ([]byte, error) {
+	cmd := exec.Command("/usr/bin/security", args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if exitErr.ExitCode() == notFoundStatus {
+				return nil, ErrNotFound
+			}
+			return nil, fmt.Errorf("security %s failed: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return out, nil
+}
+
+// quote quotes s for security's interactive mode.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
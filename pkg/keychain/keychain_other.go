@@ -0,0 +1,66 @@
+//go:build !darwin && !windows
+
+package keychain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// systemStore keeps tokens in the Secret Service, such as GNOME Keyring
+// or KWallet, through secret-tool.
+type systemStore struct{}
+
+func (systemStore) Get(host string) (string, error) {
+	out, err := secretTool(nil, "lookup", "service", Service, "host", normalize(host))
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// secret-tool fails silently when nothing matches
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	token := strings.TrimRight(string(out), "\n")
+	if token == "" {
+		return "", ErrNotFound
+	}
+	return token, nil
+}
+
+func (systemStore) Set(host, token string) error {
+	host = normalize(host)
+	_, err := secretTool(strings.NewReader(token), "store", "--label", "Harbormaster token for "+host, "service", Service, "host", host)
+	return err
+}
+
+func (s systemStore) Delete(host string) error {
+	if _, err := s.Get(host); err != nil {
+		return err
+	}
+	_, err := secretTool(nil, "clear", "service", Service, "host", normalize(host))
+	return err
+}
+
+// secretTool runs secret-tool with stdin, returning its output.
+func secretTool(stdin *strings.Reader, args ...string) ([]byte, error) {
+	path, err := exec.LookPath("secret-tool")
+	if err != nil {
+		return nil, fmt.Errorf("%w: secret-tool (libsecret) is not installed", ErrUnavailable)
+	}
+	cmd := exec.Command(path, args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(bytes.TrimSpace(exitErr.Stderr)) > 0 {
+			return nil, fmt.Errorf("secret-tool %s failed: %s", args[0], bytes.TrimSpace(exitErr.Stderr))
+		}
+	}
+	return out, err
+}
@@ -0,0 +1,59 @@
+//go:build !darwin && !windows
+
+package keychain
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeSecretTool installs a secret-tool that keeps secrets in files in
+// a temporary directory, named after the host attribute.
+func fakeSecretTool(t *testing.T) {
+	t.Helper()
+	bin, store := t.TempDir(), t.TempDir()
+	script := `#!/bin/sh
+op=$1
+[ "$op" = store ] && shift 2
+host=$5
+case $op in
+store) cat > "` + store + `/$host" ;;
+lookup) cat "` + store + `/$host" 2>/dev/null || exit 1 ;;
+clear) rm -f "` + store + `/$host" ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(bin, "secret-tool"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestSystemStore_SecretTool(t *testing.T) {
+	fakeSecretTool(t)
+	store := System()
+
+	if _, err := store.Get("github.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound before login, got %v", err)
+	}
+	if err := store.Set("GitHub.com", "ghp_secret"); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+	if token, err := store.Get("github.com"); err != nil || token != "ghp_secret" {
+		t.Errorf("expected the stored token, got %q (%v)", token, err)
+	}
+	if err := store.Delete("github.com"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if err := store.Delete("github.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound deleting again, got %v", err)
+	}
+}
+
+func TestSystemStore_Unavailable(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	if _, err := System().Get("github.com"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable without secret-tool, got %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package keychain
+
+import "testing"
+
+// mapStore is a Store in memory that counts lookups.
+type mapStore struct {
+	tokens  map[string]string
+	lookups int
+}
+
+func (s *mapStore) Get(host string) (string, error) {
+	s.lookups++
+	if token, ok := s.tokens[host]; ok {
+		return token, nil
+	}
+	return "", ErrNotFound
+}
+
+func (s *mapStore) Set(host, token string) error {
+	s.tokens[host] = token
+	return nil
+}
+
+func (s *mapStore) Delete(host string) error {
+	delete(s.tokens, host)
+	return nil
+}
+
+func TestCache_Token(t *testing.T) {
+	store := &mapStore{tokens: map[string]string{"github.com": "secret"}}
+	cache := NewCache(store)
+
+	for i := 0; i < 2; i++ {
+		if token := cache.Token("GitHub.com"); token != "secret" {
+			t.Errorf("expected the stored token, got %q", token)
+		}
+		if token := cache.Token("gitlab.com"); token != "" {
+			t.Errorf("expected no token, got %q", token)
+		}
+	}
+	if store.lookups != 2 {
+		t.Errorf("expected each host to be looked up once, got %d lookups", store.lookups)
+	}
+}
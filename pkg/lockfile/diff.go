@@ -0,0 +1,51 @@
+package lockfile
+
+import "sort"
+
+// Change describes how an entry's resolved SHA differs between two lock
+// files.
+type Change struct {
+	Name   string `json:"name"`
+	Before string `json:"before,omitempty"` // Empty if the entry was added
+	After  string `json:"after,omitempty"`  // Empty if the entry was removed
+}
+
+// Added reports whether the entry is new.
+func (c Change) Added() bool {
+	return c.Before == ""
+}
+
+// Removed reports whether the entry was deleted.
+func (c Change) Removed() bool {
+	return c.After == ""
+}
+
+// Diff returns the entries whose resolved SHA differs between before and
+// after, sorted by name. A nil lock file is treated as empty.
+func Diff(before, after *LockFile) []Change {
+	shas := func(lf *LockFile) map[string]string {
+		m := make(map[string]string)
+		if lf != nil {
+			for name, e := range lf.Entries {
+				m[name] = e.ResolvedSHA
+			}
+		}
+		return m
+	}
+	old, cur := shas(before), shas(after)
+
+	var changes []Change
+	for name, sha := range cur {
+		if old[name] != sha {
+			changes = append(changes, Change{Name: name, Before: old[name], After: sha})
+		}
+	}
+	for name, sha := range old {
+		if _, ok := cur[name]; !ok {
+			changes = append(changes, Change{Name: name, Before: sha})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
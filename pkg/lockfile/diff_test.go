@@ -0,0 +1,41 @@
+package lockfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	before := New()
+	before.Update("same", LockEntry{ResolvedSHA: "aaa"})
+	before.Update("moved", LockEntry{ResolvedSHA: "bbb"})
+	before.Update("gone", LockEntry{ResolvedSHA: "ccc"})
+
+	after := before.Clone()
+	after.Update("moved", LockEntry{ResolvedSHA: "ddd"})
+	after.Update("new", LockEntry{ResolvedSHA: "eee"})
+	after.Remove("gone")
+
+	want := []Change{
+		{Name: "gone", Before: "ccc"},
+		{Name: "moved", Before: "bbb", After: "ddd"},
+		{Name: "new", After: "eee"},
+	}
+	got := Diff(before, after)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if !got[0].Removed() || got[0].Added() {
+		t.Error("expected gone to be removed")
+	}
+	if !got[2].Added() || got[2].Removed() {
+		t.Error("expected new to be added")
+	}
+
+	if changes := Diff(before, before.Clone()); len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+	if changes := Diff(nil, after); len(changes) != after.Len() {
+		t.Errorf("expected every entry added, got %+v", changes)
+	}
+}
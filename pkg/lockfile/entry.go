@@ -4,12 +4,16 @@ import "time"
 
 // LockEntry represents a locked repository state.
 type LockEntry struct {
-	URL          string          `toml:"url"`
-	Type         string          `toml:"type"`
-	RequestedRef string          `toml:"requested_ref"`
-	ResolvedSHA  string          `toml:"resolved_sha"`
-	LastSyncedAt time.Time       `toml:"last_synced_at"`
-	Submodules   []SubmoduleLock `toml:"submodule,omitempty"`
+	URL           string          `toml:"url"`
+	Type          string          `toml:"type"`
+	RequestedRef  string          `toml:"requested_ref"`
+	ResolvedSHA   string          `toml:"resolved_sha"`
+	ResolvedRef   string          `toml:"resolved_ref,omitempty"` // Tag chosen for a version constraint
+	LastSyncedAt  time.Time       `toml:"last_synced_at"`
+	Vendored      bool            `toml:"vendored,omitempty"`
+	TreeHash      string          `toml:"tree_hash,omitempty"`
+	RewrittenFrom string          `toml:"rewritten_from,omitempty"` // Previous commit, if the sync found the branch rewritten
+	Submodules    []SubmoduleLock `toml:"submodule,omitempty"`
 }
 
 // SubmoduleLock represents a locked submodule state.
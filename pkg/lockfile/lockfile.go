@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/tierone/harbormaster/pkg/errcode"
 )
 
 const (
@@ -48,7 +49,7 @@ func Load(path string) (*LockFile, error) {
 	}
 
 	if _, err := toml.DecodeFile(path, lf); err != nil {
-		return nil, fmt.Errorf("failed to parse lock file: %w", err)
+		return nil, errcode.Wrap(errcode.LockFileInvalid, fmt.Errorf("failed to parse lock file: %w", err))
 	}
 
 	lf.path = path
@@ -166,6 +167,16 @@ func (lf *LockFile) Clear() {
 	lf.Entries = make(map[string]LockEntry)
 }
 
+// Clone returns a copy of the lock file that can be updated independently.
+func (lf *LockFile) Clone() *LockFile {
+	c := *lf
+	c.Entries = make(map[string]LockEntry, len(lf.Entries))
+	for name, entry := range lf.Entries {
+		c.Entries[name] = entry
+	}
+	return &c
+}
+
 // NewEntry creates a new LockEntry with the given parameters.
 func NewEntry(url, repoType, requestedRef, resolvedSHA string) LockEntry {
 	return LockEntry{
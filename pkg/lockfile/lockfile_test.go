@@ -258,6 +258,22 @@ func TestLockFile_Clear(t *testing.T) {
 	}
 }
 
+func TestLockFile_Clone(t *testing.T) {
+	lf := New()
+	lf.Update("repo1", LockEntry{ResolvedSHA: "abc"})
+
+	c := lf.Clone()
+	c.Update("repo1", LockEntry{ResolvedSHA: "def"})
+	c.Update("repo2", LockEntry{})
+
+	if sha, _ := lf.GetResolvedSHA("repo1"); sha != "abc" {
+		t.Errorf("expected original entry unchanged, got '%s'", sha)
+	}
+	if lf.Len() != 1 || c.Len() != 2 {
+		t.Errorf("expected lengths 1 and 2, got %d and %d", lf.Len(), c.Len())
+	}
+}
+
 func TestNewEntry(t *testing.T) {
 	entry := NewEntry(
 		"https://github.com/test/repo.git",
@@ -0,0 +1,80 @@
+// Package logging configures the structured diagnostic log. The log is
+// separate from user-facing output: it records decisions, commands, retries,
+// and timings for debugging and is disabled unless a level is set.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Log formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// LevelOff disables logging.
+const LevelOff = "off"
+
+// New creates a logger writing to w at the given level and format. A level
+// of "off" or "" returns a logger that discards everything.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	if level == "" || level == LevelOff {
+		return Discard(), nil
+	}
+
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case "", FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format: %s (must be text or json)", format)
+	}
+}
+
+// ParseLevel parses debug, info, warn, or error.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("invalid log level: %s (must be debug, info, warn, error, or off)", s)
+	}
+}
+
+// Discard returns a logger that drops all records.
+func Discard() *slog.Logger {
+	return slog.New(discardHandler{})
+}
+
+// OrDiscard returns l, or a discarding logger if l is nil.
+func OrDiscard(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return Discard()
+	}
+	return l
+}
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		level   string
+		format  string
+		wantErr bool
+		wantOut bool
+	}{
+		{"", "", false, false},
+		{"off", "json", false, false},
+		{"debug", "text", false, true},
+		{"info", "json", false, true},
+		{"error", "", false, false},
+		{"verbose", "text", true, false},
+		{"info", "xml", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level+"/"+tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := New(&buf, tt.level, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil {
+				return
+			}
+
+			logger.Info("synced", "repo", "core")
+			if got := buf.Len() > 0; got != tt.wantOut {
+				t.Errorf("expected output=%v, got %q", tt.wantOut, buf.String())
+			}
+		})
+	}
+}
+
+func TestNew_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "debug", FormatJSON)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	logger.Debug("git command", "args", "fetch --all")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected JSON record, got %q", buf.String())
+	}
+	if record["msg"] != "git command" || record["level"] != "DEBUG" {
+		t.Errorf("unexpected record: %v", record)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, s := range []string{"debug", "INFO", "warn", "warning", "error"} {
+		if _, err := ParseLevel(s); err != nil {
+			t.Errorf("ParseLevel(%q) failed: %v", s, err)
+		}
+	}
+	if _, err := ParseLevel("trace"); err == nil || !strings.Contains(err.Error(), "invalid log level") {
+		t.Errorf("expected invalid level error, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sync"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/downloader"
+)
+
+// BranchCleanupOptions controls CleanupBranches.
+type BranchCleanupOptions struct {
+	// Protected are glob patterns (as in path.Match) of branches that are
+	// never deleted, in addition to the base and checked-out branches.
+	Protected []string
+	// DryRun reports the branches that would be deleted without deleting
+	// them.
+	DryRun bool
+}
+
+// BranchCleanup is the result of cleaning up the branches of one
+// repository.
+type BranchCleanup struct {
+	Name    string
+	Base    string   // Branch the deleted branches were merged into
+	Deleted []string // Deleted branches, or those that would be in a dry run
+	Error   error
+}
+
+// CleanupBranches deletes the local branches of the selected git
+// repositories that are already merged into their base branch: the
+// configured branch, or the remote's default branch. Merging is checked
+// against the remote-tracking branch as of the last fetch. The base
+// branch, the checked-out branch, and protected branches are kept.
+// Missing and non-git repositories are skipped.
+func (m *RepositoryManager) CleanupBranches(ctx context.Context, filter Filter, opts BranchCleanupOptions) ([]BranchCleanup, error) {
+	for _, pattern := range opts.Protected {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid branch pattern %q: %w", pattern, err)
+		}
+	}
+
+	candidates, err := m.gitRepositories(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	sem := newSemaphore(m.concurrent)
+	var wg sync.WaitGroup
+	results := make([]BranchCleanup, len(candidates))
+
+	for i, repo := range candidates {
+		wg.Add(1)
+		go func(idx int, r config.Repository) {
+			defer wg.Done()
+			results[idx] = BranchCleanup{Name: r.Name}
+			if err := sem.acquire(ctx); err != nil {
+				results[idx].Error = err
+				return
+			}
+			defer sem.release()
+
+			results[idx].Error = m.cleanupBranches(ctx, &r, opts, &results[idx])
+		}(i, repo)
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (m *RepositoryManager) cleanupBranches(ctx context.Context, repo *config.Repository, opts BranchCleanupOptions, result *BranchCleanup) error {
+	repoPath := m.getRepoPath(repo)
+	dl := m.gitDownloader(ctx, repo)
+
+	base := repo.Branch
+	if repo.BranchPattern() {
+		base = m.lockedRef(repo)
+	}
+	if base == "" {
+		var err error
+		if base, err = dl.DefaultBranch(repoPath); err != nil {
+			return err
+		}
+	}
+	result.Base = base
+
+	baseSHA, err := dl.ResolveLocal(repoPath, base)
+	if err != nil {
+		return err
+	}
+	merged, err := dl.MergedBranches(repoPath, baseSHA)
+	if err != nil {
+		return err
+	}
+	current, err := downloader.GetCurrentBranch(repoPath)
+	if err != nil {
+		return err
+	}
+
+	for _, branch := range merged {
+		if branch == base || branch == current || isProtectedBranch(branch, opts.Protected) {
+			continue
+		}
+		if !opts.DryRun {
+			if err := dl.DeleteBranch(repoPath, branch); err != nil {
+				return err
+			}
+			m.logger.Debug("deleted merged branch", "repo", repo.Name, "branch", branch, "base", base)
+		}
+		result.Deleted = append(result.Deleted, branch)
+	}
+	return nil
+}
+
+// isProtectedBranch reports whether branch matches one of the patterns.
+func isProtectedBranch(branch string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return true
+		}
+	}
+	return false
+}
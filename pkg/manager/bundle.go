@@ -0,0 +1,382 @@
+package manager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/downloader"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/export"
+	"github.com/tierone/harbormaster/pkg/lockfile"
+	"github.com/tierone/harbormaster/pkg/types"
+)
+
+// BundleManifestName is the manifest at the root of a bundle archive.
+const BundleManifestName = "harbormaster-bundle.json"
+
+// BundleManifest lists the repositories a bundle archive carries.
+type BundleManifest struct {
+	GeneratedAt  time.Time     `json:"generated_at"`
+	Repositories []BundleEntry `json:"repositories"`
+}
+
+// BundleEntry is a repository carried by a bundle archive.
+type BundleEntry struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	Type string `json:"type"`
+	SHA  string `json:"sha"`  // Locked commit or content hash
+	File string `json:"file"` // Git bundle or downloaded file, relative to the archive root
+}
+
+// ExportBundle writes what is needed to reproduce the locked state of the
+// selected repositories offline to w: a git bundle of the locked commit of
+// each git repository, the downloaded file of each HTTP repository, the
+// config file, and their lock entries. See ImportBundle.
+//
+// Bundles are built from the shared clone of each remote in the cache,
+// which is fetched first unless it already has the locked commit. HTTP
+// files must be synced and match the lock file. Path repositories are
+// skipped.
+func (m *RepositoryManager) ExportBundle(ctx context.Context, filter Filter, w export.Writer) (*BundleManifest, error) {
+	repos, err := m.getRepositories(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "hm-bundle-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	sem := newSemaphore(m.concurrent)
+	var wg sync.WaitGroup
+	entries := make([]BundleEntry, len(repos))
+	sources := make([]string, len(repos))
+	errs := make([]error, len(repos))
+
+	for i, repo := range repos {
+		wg.Add(1)
+		go func(idx int, r config.Repository) {
+			defer wg.Done()
+			if err := sem.acquire(ctx); err != nil {
+				errs[idx] = err
+				return
+			}
+			defer sem.release()
+
+			entries[idx], sources[idx], errs[idx] = m.bundleRepository(ctx, &r, tmpDir)
+		}(i, repo)
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var problems []string
+	for i, err := range errs {
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", repos[i].Name, err))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("cannot bundle workspace:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	manifest := &BundleManifest{GeneratedAt: time.Now().UTC()}
+	lock := lockfile.New()
+	for i, e := range entries {
+		if e.File == "" {
+			continue
+		}
+		if err := writeFile(w, e.File, sources[i]); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", e.Name, err)
+		}
+		entry, _ := m.lockFile.Get(e.Name)
+		lock.Update(e.Name, entry)
+		manifest.Repositories = append(manifest.Repositories, e)
+	}
+
+	if m.config.Path() != "" {
+		if err := writeFile(w, config.ConfigFileName, m.config.Path()); err != nil {
+			return nil, fmt.Errorf("failed to write config: %w", err)
+		}
+	}
+	lockPath := filepath.Join(tmpDir, lockfile.LockFileName)
+	if err := lock.Save(lockPath); err != nil {
+		return nil, err
+	}
+	if err := writeFile(w, lockfile.LockFileName, lockPath); err != nil {
+		return nil, fmt.Errorf("failed to write lock file: %w", err)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := w.WriteData(BundleManifestName, append(data, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// writeFile adds the file at src to w under name.
+func writeFile(w export.Writer, name, src string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	return w.WriteFile(name, src, info)
+}
+
+// bundleRepository prepares the bundle entry of repo and returns it with
+// the file to add to the archive, which git bundles are written to under
+// tmpDir. Path repositories return an entry without a file.
+func (m *RepositoryManager) bundleRepository(ctx context.Context, repo *config.Repository, tmpDir string) (BundleEntry, string, error) {
+	entry := BundleEntry{Name: repo.Name, URL: repo.URL, Type: string(repo.Type)}
+	if repo.Type == config.RepoTypePath {
+		return entry, "", nil
+	}
+	if m.lockFile != nil {
+		entry.SHA, _ = m.lockFile.GetResolvedSHA(repo.Name)
+	}
+	if entry.SHA == "" {
+		return entry, "", errcode.Wrap(errcode.LockMissing, fmt.Errorf("no lock entry (run sync first)"))
+	}
+	sum := sha256.Sum256([]byte(repo.Name))
+	id := hex.EncodeToString(sum[:8])
+
+	if repo.Type == config.RepoTypeHTTP {
+		entry.File = "files/" + id
+		repoPath := m.getRepoPath(repo)
+		hash, err := downloader.NewHTTPDownloader(downloader.Options{}).GetCurrentRef(repoPath)
+		switch {
+		case err != nil:
+			return entry, "", fmt.Errorf("not synced: %w", err)
+		case hash != entry.SHA:
+			return entry, "", errcode.Wrap(errcode.LockDrift, fmt.Errorf("differs from lock file"))
+		}
+		return entry, repoPath, nil
+	}
+
+	entry.File = "git/" + id + ".bundle"
+	mirror := mirrorPath(defaultCacheDir(m.config), repo.URL)
+	dl := m.gitDownloader(ctx, repo)
+	if !dl.HasCommit(mirror, entry.SHA) {
+		var refs []string
+		if repo.CustomRef() {
+			refs = []string{repo.Ref}
+		}
+		if err := m.updateSharedClone(ctx, repo, mirror, refs...); err != nil {
+			return entry, "", err
+		}
+		if !dl.HasCommit(mirror, entry.SHA) {
+			return entry, "", errcode.Wrap(errcode.RefNotFound, fmt.Errorf("locked commit %s is not on any branch or tag of the remote", entry.SHA))
+		}
+	}
+
+	file := filepath.Join(tmpDir, filepath.FromSlash(entry.File))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return entry, "", err
+	}
+	if err := dl.CreateBundle(mirror, entry.SHA, m.bundleRefs(dl, repo, mirror, entry.SHA), file); err != nil {
+		return entry, "", err
+	}
+	return entry, file, nil
+}
+
+// bundleRefs returns the refs, besides HEAD, that a bundle of repo must
+// carry for a locked sync to find sha: the ref the sync checks out, set to
+// sha, or to the remote's annotated tag if it points at sha. The default
+// branch of the remote is set to sha too unless it is that ref, since a
+// shallow checkout of a tag or commit fetches it on update.
+func (m *RepositoryManager) bundleRefs(dl *downloader.GitDownloader, repo *config.Repository, mirror, sha string) map[string]string {
+	refs := make(map[string]string)
+	if branch, err := dl.HeadBranch(mirror); err == nil {
+		refs["refs/heads/"+branch] = sha
+	}
+
+	ref := m.remoteRef(repo)
+	switch {
+	case repo.CustomRef():
+		refs[repo.Ref] = sha
+	case ref == "HEAD":
+		if branch := m.trackedBranch(repo); branch != "" {
+			refs["refs/heads/"+branch] = sha
+		}
+	case repo.Tag != "" || repo.ResolvesTag():
+		name := "refs/tags/" + ref
+		if peeled, err := dl.ResolveLocal(mirror, ref); err == nil && peeled == sha {
+			refs[name] = name
+		} else {
+			refs[name] = sha
+		}
+	default:
+		refs["refs/heads/"+ref] = sha
+	}
+	return refs
+}
+
+// ReadBundleManifest reads the manifest of a bundle archive extracted to
+// dir.
+func ReadBundleManifest(dir string) (*BundleManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, BundleManifestName))
+	if err != nil {
+		return nil, fmt.Errorf("not a bundle: %w", err)
+	}
+	var manifest BundleManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// ImportBundle syncs the repositories carried by the bundle archive
+// extracted to dir without network access and records their commits in
+// the lock file. Git repositories are synced in locked mode to the
+// commits of the bundle, fetching from their git bundle instead of their
+// remote; HTTP files are copied into place. Every repository in the
+// bundle must be in the config.
+func (m *RepositoryManager) ImportBundle(ctx context.Context, dir string) (*types.SyncResult, error) {
+	startTime := time.Now()
+	manifest, err := ReadBundleManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	bundleLock, err := lockfile.Load(filepath.Join(dir, lockfile.LockFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []config.Repository
+	var results []types.OperationResult
+	bundles := make(map[string]string)
+	var missing []string
+	for _, e := range manifest.Repositories {
+		repo, ok := m.config.GetRepository(e.Name)
+		if !ok {
+			missing = append(missing, e.Name)
+			continue
+		}
+		file, err := filepath.Abs(filepath.Join(dir, filepath.FromSlash(e.File)))
+		if err != nil {
+			return nil, err
+		}
+		switch repo.Type {
+		case config.RepoTypeGit:
+			repos = append(repos, *repo)
+			bundles[repo.Name] = file
+		case config.RepoTypeHTTP:
+			results = append(results, m.importFile(repo, file, e.SHA))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, errcode.Wrap(errcode.ConfigInvalid, fmt.Errorf("repositories not in the config: %s", strings.Join(missing, ", ")))
+	}
+	if err := m.ensureWorkDir(); err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+
+	m.logger.Info("bundle import started", "repositories", len(manifest.Repositories), "bundle", dir)
+	if m.ui != nil {
+		m.ui.SetTotal(len(repos))
+	}
+
+	// Sync against the bundle's lock entries, from the bundles alone
+	importer := *m
+	importer.lockFile = bundleLock
+	importer.locked = true
+	importer.bundles = bundles
+	results = append(importer.syncRepositories(ctx, repos), results...)
+
+	for _, r := range results {
+		if !r.Success || m.lockFile == nil {
+			continue
+		}
+		entry, _ := bundleLock.Get(r.RepoName)
+		entry.LastSyncedAt = time.Now()
+		m.lockFile.Update(r.RepoName, entry)
+	}
+
+	duration := time.Since(startTime)
+	if m.ui != nil {
+		m.ui.Complete(duration)
+	}
+	result := types.NewSyncResult(results, duration)
+	m.logger.Info("bundle import finished", "succeeded", result.SuccessCount, "failed", result.FailureCount, "duration", duration)
+	return result, nil
+}
+
+// importFile copies the downloaded file of an HTTP repository from a
+// bundle into place, after checking it against the hash in the bundle.
+func (m *RepositoryManager) importFile(repo *config.Repository, src, sha string) types.OperationResult {
+	start := time.Now()
+	result := types.OperationResult{RepoName: repo.Name, RepoURL: repo.URL}
+	defer func() {
+		result.Duration = time.Since(start)
+		m.logResult(result)
+	}()
+
+	hash, err := downloader.NewHTTPDownloader(downloader.Options{}).GetCurrentRef(src)
+	if err != nil {
+		result.Error = errcode.Wrap(errcode.SyncFailed, fmt.Errorf("failed to read bundled file: %w", err))
+		return result
+	}
+	if hash != sha {
+		result.Error = errcode.Wrap(errcode.LockDrift, fmt.Errorf("bundled file does not match its hash"))
+		return result
+	}
+
+	dest := m.getRepoPath(repo)
+	if err := copyFileAtomic(src, dest); err != nil {
+		result.Error = errcode.Wrap(errcode.SyncFailed, err)
+		return result
+	}
+	result.Success = true
+	result.CommitSHA = sha
+	return result
+}
+
+// copyFileAtomic copies src to dest through a temporary file, so that a
+// failed copy leaves dest as it was.
+func copyFileAtomic(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), filepath.Base(dest)+".hm-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, in); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
@@ -0,0 +1,127 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/downloader"
+)
+
+// CacheEntry is one entry in the workspace cache, such as the shared
+// clone of a remote.
+type CacheEntry struct {
+	Kind     string // Cache subdirectory holding the entry, such as "git"
+	Path     string
+	URL      string // Remote of a shared clone, if known
+	Size     int64
+	LastUsed time.Time // Last sync that used the entry
+}
+
+// CacheDir returns the cache directory of the workspace: cache_dir if set,
+// or else .harbormaster/cache next to the config file.
+func (m *RepositoryManager) CacheDir() string {
+	return defaultCacheDir(m.config)
+}
+
+// CacheEntries lists the entries in the workspace cache, least recently
+// used first. A missing cache directory has no entries.
+func (m *RepositoryManager) CacheEntries() ([]CacheEntry, error) {
+	dir := m.CacheDir()
+	kinds, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	var entries []CacheEntry
+	for _, kind := range kinds {
+		if !kind.IsDir() {
+			continue
+		}
+		children, err := os.ReadDir(filepath.Join(dir, kind.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cache directory: %w", err)
+		}
+		for _, child := range children {
+			info, err := child.Info()
+			if err != nil {
+				return nil, err
+			}
+			e := CacheEntry{
+				Kind:     kind.Name(),
+				Path:     filepath.Join(dir, kind.Name(), child.Name()),
+				LastUsed: info.ModTime(),
+			}
+			if e.Size, err = dirSize(e.Path); err != nil {
+				return nil, fmt.Errorf("failed to measure %s: %w", e.Path, err)
+			}
+			if child.IsDir() && kind.Name() == "git" {
+				e.URL, _ = downloader.GetRemoteURL(e.Path)
+			}
+			entries = append(entries, e)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].LastUsed.Before(entries[j].LastUsed)
+	})
+	return entries, nil
+}
+
+// CleanCache removes the cache entries not used by a sync within
+// olderThan, or every entry if olderThan is 0, and returns them. With
+// dryRun nothing is removed. Checkouts borrowing objects from a shared
+// clone get their own copies first. An entry removed while a sync uses it
+// is fetched again by the next one.
+func (m *RepositoryManager) CleanCache(olderThan time.Duration, dryRun bool) ([]CacheEntry, error) {
+	entries, err := m.CacheEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	var removed []CacheEntry
+	for _, e := range entries {
+		if olderThan > 0 && e.LastUsed.After(cutoff) {
+			continue
+		}
+		if !dryRun {
+			if err := m.dissociateFrom(e.Path); err != nil {
+				return removed, err
+			}
+			m.logger.Debug("removing cache entry", "path", e.Path)
+			if err := os.RemoveAll(e.Path); err != nil {
+				return removed, fmt.Errorf("failed to remove %s: %w", e.Path, err)
+			}
+		}
+		removed = append(removed, e)
+	}
+	return removed, nil
+}
+
+// dissociateFrom makes the checkouts in the workspace that borrow objects
+// from the shared clone at path copy them, so that it can be removed.
+func (m *RepositoryManager) dissociateFrom(path string) error {
+	objects := filepath.Join(path, "objects")
+	for _, repo := range m.config.Repositories {
+		if repo.Type != config.RepoTypeGit {
+			continue
+		}
+		repoPath := m.getRepoPath(&repo)
+		if !slices.Contains(downloader.Alternates(repoPath), objects) {
+			continue
+		}
+		m.logger.Debug("copying borrowed objects", "repo", repo.Name, "from", path)
+		if err := downloader.NewGitDownloader(downloader.Options{LowPriority: m.config.General.LowPriority}).Dissociate(repoPath); err != nil {
+			return fmt.Errorf("%s: %w", repo.Name, err)
+		}
+	}
+	return nil
+}
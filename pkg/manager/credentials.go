@@ -0,0 +1,186 @@
+package manager
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/downloader"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/types"
+)
+
+// Credential is a user name and password or token for an HTTPS host.
+type Credential struct {
+	User  string // Empty uses the host's default user for tokens
+	Token string
+	Save  bool // Store the credential in the keychain once it has worked
+}
+
+// CredentialPrompt asks for the credential of an HTTPS host that refused
+// to authenticate a sync. It returns false if the user gives none.
+type CredentialPrompt func(host string) (Credential, bool)
+
+// credentialSession holds the credentials entered while the process
+// runs, by host name, shared with nested workspaces.
+type credentialSession struct {
+	mu    sync.Mutex
+	creds map[string]Credential
+}
+
+func newCredentialSession() *credentialSession {
+	return &credentialSession{creds: make(map[string]Credential)}
+}
+
+func (s *credentialSession) get(host string) (Credential, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cred, ok := s.creds[host]
+	return cred, ok
+}
+
+func (s *credentialSession) set(host string, cred Credential) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.creds[host] = cred
+}
+
+func (s *credentialSession) forget(host string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.creds, host)
+}
+
+// httpsRemote returns the HTTPS URL repo is fetched from, directly or
+// through its mirror in mirror_map, or nil if it is fetched from a
+// bundle, over another protocol, or from a URL with a password of its
+// own. URLs with only a user name, as Bitbucket and Azure DevOps show
+// them, are kept.
+func (m *RepositoryManager) httpsRemote(repo *config.Repository) *url.URL {
+	if _, bundled := m.bundles[repo.Name]; bundled || repo.Type == config.RepoTypePath {
+		return nil
+	}
+	u, err := url.Parse(m.config.MirrorURL(repo.URL))
+	if err != nil || u.Scheme != "https" {
+		return nil
+	}
+	if _, hasPassword := u.User.Password(); hasPassword {
+		return nil
+	}
+	return u
+}
+
+// withCredentials sets the credential of the HTTPS host repo is fetched
+// from in opts: the one entered during this process, or else the token
+// stored in the keychain. When the manager prompts for credentials
+// itself, git is kept from prompting in the middle of the sync.
+func (m *RepositoryManager) withCredentials(opts *downloader.Options, repo *config.Repository) {
+	opts.NoTerminalPrompt = m.prompt != nil
+	u := m.httpsRemote(repo)
+	if u == nil {
+		return
+	}
+	cred, ok := m.session.get(u.Hostname())
+	if !ok && m.credentials != nil {
+		cred.Token = m.credentials.Token(u.Hostname())
+	}
+	if cred.Token == "" {
+		return
+	}
+	opts.Token = cred.Token
+	opts.TokenHost = u.Host
+	opts.TokenUser = cred.User
+	if opts.TokenUser == "" {
+		// A token of the form user:password is that user's password, such
+		// as a Bitbucket app password or a Gerrit HTTP password
+		if user, password, ok := strings.Cut(cred.Token, ":"); ok {
+			opts.TokenUser, opts.Token = user, password
+		} else if u.User != nil {
+			opts.TokenUser = u.User.Username()
+		}
+	}
+	// Passwords of a user go in Basic authentication, as do all tokens of
+	// hosts that don't take bearer tokens
+	opts.TokenBasic = opts.TokenUser != "" || config.BasicAuthHost(u.Hostname())
+	if opts.TokenUser == "" {
+		opts.TokenUser = m.tokenUser(u.Hostname())
+	}
+}
+
+// tokenUser returns the user name a host expects with a bare token, or ""
+// for git's default.
+func (m *RepositoryManager) tokenUser(host string) string {
+	if strings.EqualFold(host, "bitbucket.org") {
+		// Repository and workspace access tokens
+		return "x-token-auth"
+	}
+	if hc, ok := m.config.Host(host); ok && hc.Provider == config.ProviderGitLab {
+		return "oauth2"
+	}
+	return ""
+}
+
+// retryUnauthorized asks for the credential of each HTTPS host that
+// refused to authenticate the sync of repos, once per host, and syncs the
+// repositories on it again, updating results. A credential that works
+// for any of them is kept for the rest of the process, and stored in the
+// keychain if asked to.
+func (m *RepositoryManager) retryUnauthorized(ctx context.Context, repos []config.Repository, results []types.OperationResult) {
+	if m.prompt == nil {
+		return
+	}
+	var hosts []string
+	failed := make(map[string][]int)
+	for i, r := range results {
+		if r.Success || errcode.Of(r.Error) != errcode.AuthFailed {
+			continue
+		}
+		u := m.httpsRemote(&repos[i])
+		if u == nil {
+			continue
+		}
+		host := u.Hostname()
+		if _, ok := failed[host]; !ok {
+			hosts = append(hosts, host)
+		}
+		failed[host] = append(failed[host], i)
+	}
+
+	for _, host := range hosts {
+		if ctx.Err() != nil {
+			return
+		}
+		cred, ok := m.prompt(host)
+		if !ok || cred.Token == "" {
+			continue
+		}
+		m.session.set(host, cred)
+
+		retry := make([]config.Repository, len(failed[host]))
+		for j, i := range failed[host] {
+			retry[j] = repos[i]
+		}
+		worked := false
+		for j, r := range m.syncRepositories(ctx, retry) {
+			results[failed[host][j]] = r
+			worked = worked || r.Success
+		}
+		if !worked {
+			m.session.forget(host)
+			continue
+		}
+		if cred.Save && m.credentials != nil {
+			// A password is stored with its user, in the user:password
+			// form withCredentials reads
+			secret := cred.Token
+			if cred.User != "" {
+				secret = cred.User + ":" + cred.Token
+			}
+			if err := m.credentials.Save(host, secret); err != nil {
+				m.logger.Warn("failed to store token in the keychain", "host", host, "error", err)
+			}
+		}
+	}
+}
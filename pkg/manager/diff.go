@@ -0,0 +1,159 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/downloader"
+)
+
+// CommitRange compares the checkout's HEAD with another commit.
+type CommitRange struct {
+	Ahead       int                 // Commits in HEAD missing from the other commit
+	Behind      int                 // Commits in the other commit missing from HEAD
+	AheadLog    []downloader.Commit // Newest first, up to the diff limit
+	BehindLog   []downloader.Commit
+	Unavailable bool // The other commit is not present locally
+}
+
+// RepoDiff relates a repository's checked-out HEAD, its locked SHA, and
+// the commit its configured ref points to.
+type RepoDiff struct {
+	Name      string
+	Ref       string // Configured branch, tag, or commit; HEAD follows the remote default
+	Exists    bool
+	IsGit     bool
+	HeadSHA   string
+	LockedSHA string
+	RefSHA    string
+	RefError  error // Set if the ref could not be resolved
+	ToLocked  CommitRange
+	ToRef     CommitRange
+	Error     error
+}
+
+// Diff compares each repository's HEAD with its locked SHA and with the
+// commit its configured ref points to, listing up to limit commits on
+// each side. The ref is resolved from the local remote-tracking branch
+// unless fetch is set, in which case it is resolved on the remote and
+// fetched if needed. Working trees are never touched.
+func (m *RepositoryManager) Diff(ctx context.Context, filter Filter, fetch bool, limit int) ([]RepoDiff, error) {
+	repos, err := m.getRepositories(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	sem := newSemaphore(m.concurrent)
+	var wg sync.WaitGroup
+	diffs := make([]RepoDiff, len(repos))
+
+	for i, repo := range repos {
+		wg.Add(1)
+		go func(idx int, r config.Repository) {
+			defer wg.Done()
+			if err := sem.acquire(ctx); err != nil {
+				diffs[idx] = RepoDiff{Name: r.Name, Error: err}
+				return
+			}
+			defer sem.release()
+
+			diffs[idx] = m.diffRepository(ctx, &r, fetch, limit)
+		}(i, repo)
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return diffs, nil
+}
+
+func (m *RepositoryManager) diffRepository(ctx context.Context, repo *config.Repository, fetch bool, limit int) RepoDiff {
+	repoPath := m.getRepoPath(repo)
+	diff := RepoDiff{
+		Name:   repo.Name,
+		Ref:    repo.Commit,
+		Exists: downloader.Exists(repoPath),
+	}
+	if diff.Ref == "" {
+		diff.Ref = m.remoteRef(repo)
+	}
+	if m.lockFile != nil {
+		diff.LockedSHA, _ = m.lockFile.GetResolvedSHA(repo.Name)
+	}
+	if !diff.Exists || repo.Type != config.RepoTypeGit || !downloader.IsGitRepository(repoPath) {
+		return diff
+	}
+	diff.IsGit = true
+
+	dl := m.gitDownloader(ctx, repo)
+
+	if diff.HeadSHA, diff.Error = dl.GetCurrentRef(repoPath); diff.Error != nil {
+		return diff
+	}
+
+	if fetch && repo.Commit == "" {
+		diff.RefSHA, diff.RefError = dl.LsRemote(repo.URL, diff.Ref)
+		if diff.RefError == nil {
+			diff.RefError = dl.EnsureCommit(repoPath, diff.Ref, diff.RefSHA)
+		}
+	} else {
+		diff.RefSHA, diff.RefError = dl.ResolveLocal(repoPath, diff.Ref)
+	}
+
+	diff.ToLocked = compareCommits(dl, repoPath, diff.HeadSHA, diff.LockedSHA, limit)
+	if diff.RefError == nil {
+		diff.ToRef = compareCommits(dl, repoPath, diff.HeadSHA, diff.RefSHA, limit)
+	}
+
+	m.logger.Debug("repository diffed",
+		"repo", repo.Name,
+		"head", diff.HeadSHA,
+		"locked_sha", diff.LockedSHA,
+		"ref_sha", diff.RefSHA,
+		"error", diff.RefError,
+	)
+	return diff
+}
+
+// compareCommits lists the commits between head and other. The range is
+// marked unavailable if other is not present locally.
+func compareCommits(dl *downloader.GitDownloader, repoPath, head, other string, limit int) CommitRange {
+	var r CommitRange
+	if other == "" || other == head {
+		return r
+	}
+	if !dl.HasCommit(repoPath, other) {
+		r.Unavailable = true
+		return r
+	}
+
+	var err error
+	if r.BehindLog, r.Behind, err = dl.Log(repoPath, head, other, limit); err != nil {
+		r.Unavailable = true
+		return r
+	}
+	if r.AheadLog, r.Ahead, err = dl.Log(repoPath, other, head, limit); err != nil {
+		r.Unavailable = true
+	}
+	return r
+}
+
+// String describes the range from HEAD's point of view.
+func (r CommitRange) String() string {
+	switch {
+	case r.Unavailable:
+		return "commits not available locally"
+	case r.Ahead > 0 && r.Behind > 0:
+		return fmt.Sprintf("HEAD is %d ahead, %d behind", r.Ahead, r.Behind)
+	case r.Behind > 0:
+		return fmt.Sprintf("HEAD is %d behind", r.Behind)
+	case r.Ahead > 0:
+		return fmt.Sprintf("HEAD is %d ahead", r.Ahead)
+	default:
+		return "same as HEAD"
+	}
+}
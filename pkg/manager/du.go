@@ -0,0 +1,129 @@
+package manager
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/tierone/harbormaster/pkg/downloader"
+)
+
+// RepoUsage is the disk space used by a repository, in bytes.
+type RepoUsage struct {
+	Name     string
+	Path     string
+	Exists   bool
+	WorkTree int64 // Everything outside .git
+	Git      int64 // .git, excluding LFS objects
+	LFS      int64 // .git/lfs
+	Error    error
+}
+
+// Total returns the space used by the whole repository.
+func (u RepoUsage) Total() int64 {
+	return u.WorkTree + u.Git + u.LFS
+}
+
+// DiskUsage measures the disk space used by the selected repositories,
+// split into the working tree, git metadata, and LFS objects. Sizes are
+// apparent file sizes; symbolic links are not followed.
+func (m *RepositoryManager) DiskUsage(ctx context.Context, filter Filter) ([]RepoUsage, error) {
+	repos, err := m.getRepositories(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	sem := newSemaphore(m.concurrent)
+	var wg sync.WaitGroup
+	usages := make([]RepoUsage, len(repos))
+
+	for i, repo := range repos {
+		usages[i] = RepoUsage{Name: repo.Name, Path: m.getRepoPath(&repo)}
+		if !downloader.Exists(usages[i].Path) {
+			continue
+		}
+		usages[i].Exists = true
+
+		wg.Add(1)
+		go func(u *RepoUsage) {
+			defer wg.Done()
+			if err := sem.acquire(ctx); err != nil {
+				u.Error = err
+				return
+			}
+			defer sem.release()
+
+			u.Error = measureRepository(u)
+		}(&usages[i])
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return usages, nil
+}
+
+// measureRepository fills in the sizes of u by walking its path.
+func measureRepository(u *RepoUsage) error {
+	gitDir := filepath.Join(u.Path, ".git") + string(filepath.Separator)
+	lfsDir := filepath.Join(gitDir, "lfs") + string(filepath.Separator)
+
+	return filepath.WalkDir(u.Path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case strings.HasPrefix(p, lfsDir):
+			u.LFS += info.Size()
+		case strings.HasPrefix(p, gitDir):
+			u.Git += info.Size()
+		default:
+			u.WorkTree += info.Size()
+		}
+		return nil
+	})
+}
+
+// CacheUsage returns the disk space used by the configured cache_dir, or
+// 0 if none is configured or it does not exist.
+func (m *RepositoryManager) CacheUsage() (int64, error) {
+	dir := m.config.General.CacheDir
+	if dir == "" {
+		return 0, nil
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return 0, nil
+	}
+	return dirSize(dir)
+}
+
+// dirSize returns the apparent size of the regular files under dir.
+func dirSize(dir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
@@ -0,0 +1,61 @@
+package manager
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/tierone/harbormaster/pkg/generate"
+)
+
+// EnvVar is an environment variable describing the workspace.
+type EnvVar struct {
+	Name  string
+	Value string
+}
+
+// Environment returns variables describing the workspace layout for use
+// by scripts: HM_WORKSPACE (the directory of the config file), HM_CONFIG,
+// HM_WORK_DIR, and HM_REPOSITORIES (the selected names, space-separated),
+// followed by HM_<NAME>_PATH, HM_<NAME>_REF, and HM_<NAME>_SHA for each
+// selected repository, where NAME is the repository name as an upper-case
+// identifier. The SHA comes from the lock file and is empty if the
+// repository is not locked.
+func (m *RepositoryManager) Environment(filter Filter) ([]EnvVar, error) {
+	repos, err := m.getRepositories(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var configPath string
+	workspace := m.config.Dir()
+	if workspace != "" {
+		configPath = filepath.Join(workspace, filepath.Base(m.config.Path()))
+	}
+
+	names := make([]string, len(repos))
+	for i, repo := range repos {
+		names[i] = repo.Name
+	}
+
+	vars := []EnvVar{
+		{"HM_WORKSPACE", workspace},
+		{"HM_CONFIG", configPath},
+		{"HM_WORK_DIR", m.workDir},
+		{"HM_REPOSITORIES", strings.Join(names, " ")},
+	}
+	for _, repo := range repos {
+		prefix := "HM_" + generate.Ident(repo.Name)
+		var sha string
+		if m.lockFile != nil {
+			if entry, ok := m.lockFile.Get(repo.Name); ok {
+				sha = entry.ResolvedSHA
+			}
+		}
+		vars = append(vars,
+			EnvVar{prefix + "_PATH", m.getRepoPath(&repo)},
+			EnvVar{prefix + "_REF", repo.GetEffectiveRef(m.config.General.DefaultBranch)},
+			EnvVar{prefix + "_SHA", sha},
+		)
+	}
+	return vars, nil
+}
@@ -0,0 +1,165 @@
+package manager
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/export"
+)
+
+// ExportSources returns export sources for the repositories matching the
+// filter. Every repository must be synced, clean, and at its locked SHA so
+// the export exactly reproduces the locked workspace.
+func (m *RepositoryManager) ExportSources(filter Filter) ([]export.Source, error) {
+	repos, err := m.getRepositories(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []export.Source
+	var problems []string
+	for i := range repos {
+		repo := &repos[i]
+		status := m.getRepoStatus(repo)
+
+		switch {
+		case status.Error != nil:
+			problems = append(problems, fmt.Sprintf("%s: %v", repo.Name, status.Error))
+			continue
+		case !status.Exists:
+			problems = append(problems, fmt.Sprintf("%s: not synced", repo.Name))
+			continue
+		case status.LockedSHA == "":
+			problems = append(problems, fmt.Sprintf("%s: no lock entry", repo.Name))
+			continue
+		case status.CurrentSHA != status.LockedSHA:
+			problems = append(problems, fmt.Sprintf("%s: differs from lock file", repo.Name))
+			continue
+		case status.IsDirty:
+			problems = append(problems, fmt.Sprintf("%s: has local changes", repo.Name))
+			continue
+		}
+
+		sources = append(sources, export.Source{
+			Name:    repo.Name,
+			URL:     repo.URL,
+			Type:    string(repo.Type),
+			Ref:     status.RequestedRef,
+			Branch:  m.trackedBranch(repo),
+			SHA:     status.LockedSHA,
+			Path:    status.Path,
+			RelPath: repo.GetEffectivePath(),
+		})
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("workspace does not match lock file:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	return sources, nil
+}
+
+// ArchiveSources returns export sources for the repositories matching the
+// filter. With locked set, each source carries its locked SHA for
+// archiving from history; otherwise the current working tree is used.
+func (m *RepositoryManager) ArchiveSources(filter Filter, locked bool) ([]export.Source, error) {
+	repos, err := m.getRepositories(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []export.Source
+	var problems []string
+	for i := range repos {
+		repo := &repos[i]
+		status := m.getRepoStatus(repo)
+
+		sha := status.CurrentSHA
+		if locked {
+			sha = status.LockedSHA
+		}
+
+		switch {
+		case !status.Exists:
+			problems = append(problems, fmt.Sprintf("%s: not synced", repo.Name))
+			continue
+		case locked && sha == "":
+			problems = append(problems, fmt.Sprintf("%s: no lock entry", repo.Name))
+			continue
+		}
+
+		sources = append(sources, export.Source{
+			Name:    repo.Name,
+			URL:     repo.URL,
+			Type:    string(repo.Type),
+			Ref:     status.RequestedRef,
+			Branch:  m.trackedBranch(repo),
+			SHA:     sha,
+			Path:    status.Path,
+			RelPath: repo.GetEffectivePath(),
+		})
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("cannot archive workspace:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	return sources, nil
+}
+
+// LockedSources returns export sources for the repositories matching the
+// filter using only the lock file, without requiring local checkouts.
+func (m *RepositoryManager) LockedSources(filter Filter) ([]export.Source, error) {
+	repos, err := m.getRepositories(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []export.Source
+	var missing []string
+	for i := range repos {
+		repo := &repos[i]
+		sha := ""
+		if m.lockFile != nil {
+			sha, _ = m.lockFile.GetResolvedSHA(repo.Name)
+		}
+		if sha == "" {
+			missing = append(missing, repo.Name)
+			continue
+		}
+
+		sources = append(sources, export.Source{
+			Name:    repo.Name,
+			URL:     repo.URL,
+			Type:    string(repo.Type),
+			Ref:     repo.GetEffectiveRef(m.config.General.DefaultBranch),
+			Branch:  m.trackedBranch(repo),
+			SHA:     sha,
+			Path:    m.getRepoPath(repo),
+			RelPath: repo.GetEffectivePath(),
+		})
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("no lock entry for: %s (run 'hm sync' first)", strings.Join(missing, ", "))
+	}
+
+	return sources, nil
+}
+
+// trackedBranch returns the branch a repository follows, or "" if it is
+// pinned to a tag, commit, or date, follows another ref, or its tag is
+// resolved at sync time.
+func (m *RepositoryManager) trackedBranch(repo *config.Repository) string {
+	if repo.Commit != "" || repo.Tag != "" || repo.AsOf != "" || repo.Ref != "" || repo.ResolvesTag() {
+		return ""
+	}
+	if repo.BranchPattern() {
+		return m.lockedRef(repo)
+	}
+	if repo.Branch != "" {
+		return repo.Branch
+	}
+	return m.config.General.DefaultBranch
+}
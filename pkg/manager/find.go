@@ -0,0 +1,181 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/downloader"
+)
+
+// FileMatch is a file found by Find.
+type FileMatch struct {
+	Repo string
+	Path string // Relative to the repository, slash-separated
+	Abs  string
+}
+
+// FindError records a repository whose files could not be listed.
+type FindError struct {
+	Repo  string
+	Error error
+}
+
+// Find searches the files of the selected repositories for pattern. In
+// git checkouts only tracked files are searched; other repositories are
+// walked. A pattern without a slash matches file names in any directory;
+// one with a slash matches the whole path, where ** matches any number of
+// directories. Matches are sorted by repository and path.
+func (m *RepositoryManager) Find(ctx context.Context, filter Filter, pattern string) ([]FileMatch, []FindError, error) {
+	if err := validateGlob(pattern); err != nil {
+		return nil, nil, err
+	}
+
+	repos, err := m.getRepositories(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sem := newSemaphore(m.concurrent)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var matches []FileMatch
+	var failures []FindError
+
+	for _, repo := range repos {
+		repoPath := m.getRepoPath(&repo)
+		if !downloader.Exists(repoPath) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(r config.Repository, repoPath string) {
+			defer wg.Done()
+			if err := sem.acquire(ctx); err != nil {
+				return
+			}
+			defer sem.release()
+
+			files, err := m.listFiles(ctx, &r, repoPath)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failures = append(failures, FindError{Repo: r.Name, Error: err})
+				return
+			}
+			for _, f := range files {
+				if matchGlob(pattern, f) {
+					matches = append(matches, FileMatch{
+						Repo: r.Name,
+						Path: f,
+						Abs:  filepath.Join(repoPath, filepath.FromSlash(f)),
+					})
+				}
+			}
+		}(repo, repoPath)
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Repo != matches[j].Repo {
+			return matches[i].Repo < matches[j].Repo
+		}
+		return matches[i].Path < matches[j].Path
+	})
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Repo < failures[j].Repo })
+	return matches, failures, nil
+}
+
+// listFiles returns the files of a repository relative to repoPath.
+func (m *RepositoryManager) listFiles(ctx context.Context, repo *config.Repository, repoPath string) ([]string, error) {
+	if repo.Type == config.RepoTypeGit && downloader.IsGitRepository(repoPath) {
+		return m.gitDownloader(ctx, repo).TrackedFiles(repoPath)
+	}
+
+	info, err := os.Stat(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		// A single downloaded file
+		return []string{filepath.Base(repoPath)}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(repoPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(repoPath, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	return files, err
+}
+
+// validateGlob returns an error if pattern is malformed.
+func validateGlob(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("empty pattern")
+	}
+	for _, seg := range strings.Split(pattern, "/") {
+		if _, err := path.Match(seg, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchGlob reports whether the slash-separated name matches pattern. A
+// pattern without a slash matches the last element of name; otherwise the
+// whole name must match, with ** matching zero or more elements.
+func matchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(strings.TrimPrefix(pattern, "/"), "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], name[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
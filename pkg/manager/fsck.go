@@ -0,0 +1,214 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/downloader"
+	"github.com/tierone/harbormaster/pkg/errcode"
+)
+
+// Repairs reported in RepoFsck.Repaired and types.OperationResult.Repaired.
+const (
+	RepairRefetched = "refetched" // Every object was fetched from origin again
+	RepairRecloned  = "recloned"  // The checkout was replaced by a new clone
+)
+
+// corruptSuffix is appended to the path of a corrupt checkout while it is
+// cloned again, so that it can be put back if cloning fails.
+const corruptSuffix = ".hm-corrupt"
+
+// RepoFsck is the result of checking one repository for corruption.
+type RepoFsck struct {
+	Name     string
+	Problems []string // Problems git fsck reported; none if the repository is intact
+	Repaired string   // RepairRefetched or RepairRecloned, if it was repaired
+	Error    error    // errcode.Corrupt if it has problems that were not repaired
+
+	path  string // Checkout moved aside to be cloned again
+	aside string // Where it was moved
+}
+
+// Fsck checks the objects and refs of the selected git checkouts with
+// git fsck. With repair, a corrupt checkout is fetched from origin again
+// and, if that doesn't repair it, moved aside and cloned again from the
+// URL recorded in the lock file, unless that URL is unreachable or the
+// checkout has uncommitted changes or branches with commits that are not
+// on origin.
+// The old checkout is removed once the clone succeeds and put back if it
+// fails; clones are recorded in the lock file like a sync. Missing,
+// vendored, and non-git repositories are skipped.
+func (m *RepositoryManager) Fsck(ctx context.Context, filter Filter, repair bool) ([]RepoFsck, error) {
+	repos, err := m.gitRepositories(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	sem := newSemaphore(m.concurrent)
+	var wg sync.WaitGroup
+	results := make([]RepoFsck, len(repos))
+
+	for i, repo := range repos {
+		wg.Add(1)
+		go func(idx int, r config.Repository) {
+			defer wg.Done()
+			results[idx] = RepoFsck{Name: r.Name}
+			if err := sem.acquire(ctx); err != nil {
+				results[idx].Error = err
+				return
+			}
+			defer sem.release()
+
+			results[idx] = m.fsckRepository(ctx, &r, repair)
+		}(i, repo)
+	}
+
+	wg.Wait()
+
+	// Clone the checkouts moved aside again, all in one sync
+	var reclone []string
+	for _, r := range results {
+		if r.aside != "" {
+			reclone = append(reclone, r.Name)
+		}
+	}
+	if len(reclone) == 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return results, nil
+	}
+
+	failed := make(map[string]error)
+	synced, err := m.withRecordedURLs().SyncContext(ctx, Filter{Names: reclone})
+	if err == nil {
+		for _, r := range synced.FailedResults() {
+			failed[r.RepoName] = r.Error
+		}
+	}
+	for i, r := range results {
+		if r.aside == "" {
+			continue
+		}
+		cloneErr, ok := failed[r.Name]
+		if err != nil {
+			cloneErr, ok = err, true
+		}
+		if ok {
+			results[i].Repaired = ""
+			if err := restoreCheckout(r.path, r.aside); err != nil {
+				results[i].Error = fmt.Errorf("failed to clone the corrupt checkout again (%v), and failed to put it back from %s: %w", cloneErr, r.aside, err)
+			} else {
+				results[i].Error = errcode.Wrap(errcode.Corrupt, fmt.Errorf("failed to clone the corrupt checkout again, so it was put back: %w", cloneErr))
+			}
+		} else if err := os.RemoveAll(r.aside); err != nil {
+			m.logger.Warn("failed to remove corrupt checkout", "repo", r.Name, "path", r.aside, "error", err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// withRecordedURLs returns a manager like m that clones repositories from
+// the URL recorded in the lock file, where it differs from the configured
+// one, so that a corrupt checkout is replaced by the same repository.
+func (m *RepositoryManager) withRecordedURLs() *RepositoryManager {
+	cfg := *m.config
+	cfg.Repositories = slices.Clone(m.config.Repositories)
+	for i := range cfg.Repositories {
+		cfg.Repositories[i].URL = m.recordedURL(&cfg.Repositories[i])
+	}
+	recorded := *m
+	recorded.config = &cfg
+	return &recorded
+}
+
+// recordedURL returns the URL repo was last synced from, as recorded in
+// the lock file, or its configured URL if none is recorded.
+func (m *RepositoryManager) recordedURL(repo *config.Repository) string {
+	if m.lockFile != nil {
+		if entry, ok := m.lockFile.Get(repo.Name); ok && entry.URL != "" {
+			return entry.URL
+		}
+	}
+	return repo.URL
+}
+
+// restoreCheckout puts the corrupt checkout moved aside to aside back at
+// path, in place of whatever a failed clone left there.
+func restoreCheckout(path, aside string) error {
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+	return os.Rename(aside, path)
+}
+
+// fsckRepository checks the checkout of repo and, with repair, repairs
+// it up to moving it aside to be cloned again.
+func (m *RepositoryManager) fsckRepository(ctx context.Context, repo *config.Repository, repair bool) RepoFsck {
+	result := RepoFsck{Name: repo.Name}
+	dl := m.gitDownloader(ctx, repo)
+	result.Problems, result.Error = dl.Fsck(m.getRepoPath(repo))
+	if result.Error != nil || len(result.Problems) == 0 {
+		return result
+	}
+	if !repair {
+		result.Error = errcode.Wrap(errcode.Corrupt, fmt.Errorf("%d problems found by git fsck", len(result.Problems)))
+		return result
+	}
+	result.Repaired, result.Error = m.repairCorruption(repo, dl)
+	if result.Repaired == RepairRecloned {
+		result.path = m.getRepoPath(repo)
+		result.aside = result.path + corruptSuffix
+	}
+	return result
+}
+
+// repairCorruption repairs the checkout of repo, in which git fsck found
+// problems, by fetching every object from origin again. If problems
+// remain, it moves the checkout aside, unless it has uncommitted changes
+// or unpushed branches, and returns RepairRecloned for the caller to
+// clone it again. A checkout whose remote is unreachable is left alone,
+// since cloning it again would fail too.
+func (m *RepositoryManager) repairCorruption(repo *config.Repository, dl *downloader.GitDownloader) (string, error) {
+	repoPath := m.getRepoPath(repo)
+	if err := dl.Refetch(repoPath); err != nil {
+		// Corruption can break the fetch too, but an unreachable remote
+		// would break the clone as well
+		m.logger.Warn("failed to fetch objects again", "repo", repo.Name, "error", err)
+		if _, err := dl.LsRemote(m.recordedURL(repo), ""); err != nil {
+			return "", errcode.Wrap(errcode.Corrupt, fmt.Errorf("corrupt, and the remote is unreachable, so it was left in place: %w", err))
+		}
+	} else if problems, err := dl.Fsck(repoPath); err == nil && len(problems) == 0 {
+		return RepairRefetched, nil
+	}
+
+	dirty, err := downloader.IsDirty(repoPath)
+	if err != nil {
+		return "", errcode.Wrap(errcode.Corrupt, fmt.Errorf("corrupt, and could not check for uncommitted changes (%v); move the checkout aside and sync to clone it again", err))
+	}
+	if dirty {
+		return "", errcode.Wrap(errcode.Corrupt, fmt.Errorf("corrupt, and has uncommitted changes; save them and remove the checkout to clone it again"))
+	}
+	branches, err := dl.LocalOnlyBranches(repoPath)
+	if err != nil {
+		return "", errcode.Wrap(errcode.Corrupt, fmt.Errorf("corrupt, and could not check for unpushed commits (%v); move the checkout aside and sync to clone it again", err))
+	}
+	if len(branches) > 0 {
+		return "", errcode.Wrap(errcode.Corrupt, fmt.Errorf("corrupt, and branches %s have commits that are not on origin; push them and remove the checkout to clone it again", strings.Join(branches, ", ")))
+	}
+
+	aside := repoPath + corruptSuffix
+	m.logger.Warn("moving corrupt checkout aside", "repo", repo.Name, "path", aside)
+	if err := os.Rename(repoPath, aside); err != nil {
+		return "", fmt.Errorf("failed to move corrupt checkout aside: %w", err)
+	}
+	return RepairRecloned, nil
+}
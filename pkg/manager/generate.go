@@ -0,0 +1,50 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/generate"
+)
+
+// GenerateFiles renders all configured generate templates from the lock
+// file state. Returns the output paths whose content changed.
+func (m *RepositoryManager) GenerateFiles() ([]string, error) {
+	data := m.generateData()
+
+	var written []string
+	for _, gen := range m.config.Generate {
+		changed, err := generate.Render(gen.Template, gen.Output, data)
+		if err != nil {
+			return written, fmt.Errorf("%s: %w", gen.OutputOriginal, err)
+		}
+		if changed {
+			written = append(written, gen.Output)
+		}
+	}
+
+	return written, nil
+}
+
+// generateData collects template data for all locked repositories.
+func (m *RepositoryManager) generateData() generate.Data {
+	data := generate.Data{GeneratedAt: time.Now()}
+
+	for _, repo := range m.config.Repositories {
+		r := generate.Repository{
+			Name: repo.Name,
+			URL:  repo.URL,
+			Type: string(repo.Type),
+			Path: repo.GetEffectivePath(),
+			Ref:  repo.GetEffectiveRef(m.config.General.DefaultBranch),
+		}
+		if m.lockFile != nil {
+			if entry, ok := m.lockFile.Get(repo.Name); ok {
+				r.SHA = entry.ResolvedSHA
+			}
+		}
+		data.Repositories = append(data.Repositories, r)
+	}
+
+	return data
+}
@@ -0,0 +1,174 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/downloader"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/provider"
+)
+
+// Review is a change under review checked out by Review.
+type Review struct {
+	Repository string
+	Path       string
+	Branch     string // Local branch the change is checked out on
+	Change     provider.Change
+}
+
+// gerritHost returns the host of repo if it is a Gerrit server, with the
+// project's name on it.
+func (m *RepositoryManager) gerritHost(repo *config.Repository) (host, project string, ok bool) {
+	if repo.Type != config.RepoTypeGit {
+		return "", "", false
+	}
+	host, project, err := provider.ParseRepoURL(repo.URL)
+	if err != nil {
+		return "", "", false
+	}
+	if hc, found := m.config.Host(host); !found || hc.Provider != config.ProviderGerrit {
+		return "", "", false
+	}
+	// Authenticated HTTPS URLs carry an /a/ prefix that is not part of
+	// the project's name
+	return host, strings.TrimPrefix(project, "a/"), true
+}
+
+// reviewer returns the API of a Gerrit host.
+func (m *RepositoryManager) reviewer(host string) (provider.Reviewer, error) {
+	api, err := m.providers.ForHost(host)
+	if err != nil {
+		return nil, err
+	}
+	r, ok := api.(provider.Reviewer)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a code review host", host)
+	}
+	return r, nil
+}
+
+// installCommitMsgHook installs the commit-msg hook of the Gerrit server
+// hosting repo in its checkout at path, so that commits get the Change-Id
+// Gerrit requires. Repositories on other hosts, and checkouts that have a
+// commit-msg hook of their own, are left alone.
+func (m *RepositoryManager) installCommitMsgHook(ctx context.Context, repo *config.Repository, path string) error {
+	host, _, ok := m.gerritHost(repo)
+	if !ok {
+		return nil
+	}
+	hooks, err := m.gitDownloader(ctx, repo).HooksDir(path)
+	if err != nil {
+		return err
+	}
+	hookPath := filepath.Join(hooks, "commit-msg")
+	if _, err := os.Stat(hookPath); err == nil {
+		return nil
+	}
+
+	r, err := m.reviewer(host)
+	if err != nil {
+		return err
+	}
+	hook, err := r.CommitMsgHook(ctx)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(hooks, 0755); err != nil {
+		return fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+	if err := os.WriteFile(hookPath, hook, 0755); err != nil {
+		return fmt.Errorf("failed to install commit-msg hook: %w", err)
+	}
+	m.logger.Debug("installed commit-msg hook", "repo", repo.Name, "host", host)
+	return nil
+}
+
+// Review looks up a change by its number or Change-Id on the Gerrit
+// hosts of the configured repositories, and checks out its current patch
+// set in the repository of its project on the branch review/<number>.
+// The checkout must not have uncommitted changes.
+func (m *RepositoryManager) Review(ctx context.Context, id string) (*Review, error) {
+	// Gerrit hosts in config order, with the repositories of each project
+	var hosts []string
+	projects := make(map[string][]*config.Repository)
+	for i := range m.config.Repositories {
+		repo := &m.config.Repositories[i]
+		host, project, ok := m.gerritHost(repo)
+		if !ok {
+			continue
+		}
+		if !slices.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+		projects[host+"/"+project] = append(projects[host+"/"+project], repo)
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no repository is on a Gerrit host (set provider = %q on its [[host]])", config.ProviderGerrit)
+	}
+
+	var change *provider.Change
+	var host string
+	for _, h := range hosts {
+		r, err := m.reviewer(h)
+		if err != nil {
+			return nil, err
+		}
+		c, err := r.Change(ctx, id)
+		if errcode.Of(err) == errcode.RefNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up change %s on %s: %w", id, h, err)
+		}
+		change, host = c, h
+		break
+	}
+	if change == nil {
+		return nil, errcode.Wrap(errcode.RefNotFound, fmt.Errorf("change %s not found on %s", id, strings.Join(hosts, ", ")))
+	}
+
+	// A project synced on several branches prefers the change's one
+	candidates := projects[host+"/"+change.Project]
+	if len(candidates) == 0 {
+		return nil, errcode.Wrap(errcode.UnknownRepository, fmt.Errorf("change %d is for %s, which no repository is configured for", change.Number, change.Project))
+	}
+	repo := candidates[0]
+	for _, c := range candidates {
+		if c.Branch == change.Branch {
+			repo = c
+			break
+		}
+	}
+
+	path := m.getRepoPath(repo)
+	if !downloader.IsGitRepository(path) {
+		return nil, fmt.Errorf("%s is not cloned (run hm sync %s first)", repo.Name, repo.Name)
+	}
+	if dirty, err := downloader.IsDirty(path); err != nil {
+		return nil, err
+	} else if dirty {
+		return nil, fmt.Errorf("%s has uncommitted changes", repo.Name)
+	}
+
+	review := &Review{
+		Repository: repo.Name,
+		Path:       path,
+		Branch:     "review/" + strconv.Itoa(change.Number),
+		Change:     *change,
+	}
+	gd := m.gitDownloader(ctx, repo)
+	if err := gd.CheckoutChange(path, change.Ref, review.Branch); err != nil {
+		return nil, err
+	}
+	if err := m.installCommitMsgHook(ctx, repo, path); err != nil {
+		m.logger.Warn("failed to install commit-msg hook", "repo", repo.Name, "error", err)
+	}
+	return review, nil
+}
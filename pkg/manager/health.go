@@ -0,0 +1,182 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/downloader"
+)
+
+// Health checks reported in HealthIssue.Check.
+const (
+	CheckDetached = "detached" // HEAD is detached away from the tracked branch
+	CheckBranch   = "branch"   // A different branch than the tracked one is checked out
+	CheckDiverged = "diverged" // The branch and its remote-tracking branch have diverged
+	CheckRemote   = "remote"   // The origin remote is missing
+	CheckShallow  = "shallow"  // A shallow clone where a full one is configured
+	CheckLFS      = "lfs"      // Git LFS pointer files whose content was not fetched
+)
+
+// HealthIssue is a problem found in a checkout.
+type HealthIssue struct {
+	Check   string
+	Message string
+}
+
+// RepoHealth is the result of checking one repository.
+type RepoHealth struct {
+	Name   string
+	Issues []HealthIssue
+	Error  error
+}
+
+// Healthy reports whether the repository was checked without finding
+// any issue.
+func (h RepoHealth) Healthy() bool {
+	return h.Error == nil && len(h.Issues) == 0
+}
+
+// Health audits the state of the selected git checkouts for problems
+// that status does not show: a detached HEAD or unexpected branch, a
+// branch diverged from its remote-tracking branch (as of the last fetch),
+// a missing origin remote, a shallow clone where a full one is
+// configured, and Git LFS pointer files without content. Missing and
+// non-git repositories are skipped.
+func (m *RepositoryManager) Health(ctx context.Context, filter Filter) ([]RepoHealth, error) {
+	candidates, err := m.gitRepositories(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	sem := newSemaphore(m.concurrent)
+	var wg sync.WaitGroup
+	results := make([]RepoHealth, len(candidates))
+
+	for i, repo := range candidates {
+		wg.Add(1)
+		go func(idx int, r config.Repository) {
+			defer wg.Done()
+			results[idx] = RepoHealth{Name: r.Name}
+			if err := sem.acquire(ctx); err != nil {
+				results[idx].Error = err
+				return
+			}
+			defer sem.release()
+
+			results[idx].Issues, results[idx].Error = m.checkHealth(ctx, &r)
+		}(i, repo)
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (m *RepositoryManager) checkHealth(ctx context.Context, repo *config.Repository) ([]HealthIssue, error) {
+	repoPath := m.getRepoPath(repo)
+	dl := m.gitDownloader(ctx, repo)
+	var issues []HealthIssue
+	add := func(check, format string, args ...any) {
+		issues = append(issues, HealthIssue{Check: check, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if _, err := downloader.GetRemoteURL(repoPath); err != nil {
+		add(CheckRemote, "no origin remote")
+	}
+
+	head, err := dl.GetCurrentRef(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	current, err := downloader.GetCurrentBranch(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pinned repositories are expected to be detached
+	if repo.Tag == "" && repo.Commit == "" && repo.AsOf == "" && repo.Ref == "" && !repo.ResolvesTag() {
+		expected := repo.Branch
+		if repo.BranchPattern() {
+			expected = m.lockedRef(repo)
+		} else if expected == "" {
+			expected, _ = dl.DefaultBranch(repoPath)
+		}
+		switch {
+		case current == "" && expected != "":
+			// Sync leaves a configured branch detached at its
+			// remote-tracking branch, unless it checks out local branches
+			if tip, err := dl.ResolveLocal(repoPath, expected); repo.LocalBranch() || err != nil || tip != head {
+				add(CheckDetached, "HEAD is detached at %s, expected branch %s", shortSHA(head), expected)
+			}
+		case current == "":
+			add(CheckDetached, "HEAD is detached at %s", shortSHA(head))
+		case expected != "" && current != expected:
+			add(CheckBranch, "on branch %s, expected %s", current, expected)
+		}
+	}
+
+	if current != "" {
+		if upstream, err := dl.ResolveLocal(repoPath, current); err == nil && upstream != head {
+			ahead, behind, err := dl.AheadBehind(repoPath, current, upstream)
+			if err != nil {
+				return nil, err
+			}
+			if ahead > 0 && behind > 0 {
+				add(CheckDiverged, "branch %s has diverged from origin/%s (%d ahead, %d behind)", current, current, ahead, behind)
+			}
+		}
+	}
+
+	shallow, err := dl.IsShallow(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if shallow && !(repo.IsShallow(m.config.Git.ShallowClone) && repo.GetDepth(m.config.Git.CloneDepth) > 0) {
+		add(CheckShallow, "shallow clone, but a full clone is configured")
+	}
+
+	files, err := dl.TrackedFiles(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	var pointers []string
+	for _, f := range files {
+		if isLFSPointer(filepath.Join(repoPath, filepath.FromSlash(f))) {
+			pointers = append(pointers, f)
+		}
+	}
+	switch len(pointers) {
+	case 0:
+	case 1:
+		add(CheckLFS, "LFS content missing for %s", pointers[0])
+	default:
+		add(CheckLFS, "LFS content missing for %d files, e.g. %s", len(pointers), pointers[0])
+	}
+
+	return issues, nil
+}
+
+// lfsPointerPrefix starts every Git LFS pointer file.
+var lfsPointerPrefix = []byte("version https://git-lfs.github.com/spec/v1\n")
+
+// lfsPointerMaxSize is the largest size of a Git LFS pointer file.
+const lfsPointerMaxSize = 1024
+
+// isLFSPointer reports whether the file at path is a Git LFS pointer, i.e.
+// its content was never downloaded.
+func isLFSPointer(path string) bool {
+	info, err := os.Lstat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > lfsPointerMaxSize {
+		return false
+	}
+	data, err := os.ReadFile(path)
+	return err == nil && bytes.HasPrefix(data, lfsPointerPrefix)
+}
@@ -0,0 +1,64 @@
+package manager
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/downloader"
+)
+
+// KnownHostsName is the managed known_hosts file, relative to the
+// workspace root, holding the SSH host keys pinned in the config.
+var KnownHostsName = filepath.Join(config.StateDirName, "known_hosts")
+
+// hostKeyPins records the hosts whose keys a manager has pinned, so that
+// each host is checked once however many repositories it serves.
+type hostKeyPins struct {
+	path string
+	mu   sync.Mutex
+	errs map[string]error // By host and port
+}
+
+// defaultKnownHosts returns the managed known_hosts file of the workspace
+// described by cfg: next to the config file, or in the work directory if
+// the config was not loaded from a file.
+func defaultKnownHosts(cfg *config.Config) string {
+	if cfg.Path() == "" {
+		return filepath.Join(cfg.General.WorkDir, KnownHostsName)
+	}
+	return filepath.Join(filepath.Dir(cfg.Path()), KnownHostsName)
+}
+
+// knownHosts returns the known_hosts file that git must check the SSH
+// host key of repo's remote, or of its mirror in mirror_map, against,
+// after pinning the host's keys in it, or "" if the remote is not reached
+// over SSH or its host pins no keys.
+// The file is returned with any error, so that git still refuses a host
+// whose keys could not be pinned.
+func (m *RepositoryManager) knownHosts(ctx context.Context, repo *config.Repository) (string, error) {
+	if _, bundled := m.bundles[repo.Name]; repo.Type != config.RepoTypeGit || bundled {
+		return "", nil
+	}
+	host, port, ok := downloader.SSHHost(m.config.MirrorURL(repo.URL))
+	if !ok {
+		return "", nil
+	}
+	hc, ok := m.config.Host(host)
+	if !ok || len(hc.SSHFingerprints) == 0 {
+		return "", nil
+	}
+
+	p := m.hostKeys
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := host + ":" + port
+	err, done := p.errs[key]
+	if !done {
+		m.logger.Debug("pinning SSH host keys", "host", host, "path", p.path)
+		err = downloader.PinHostKeys(ctx, p.path, host, port, hc.SSHFingerprints)
+		p.errs[key] = err
+	}
+	return p.path, err
+}
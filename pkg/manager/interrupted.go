@@ -0,0 +1,67 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/downloader"
+)
+
+// cloneMarkerSuffix is appended to a repository path for a file that
+// exists while the repository is being cloned, so that the next sync
+// recognizes a clone that was interrupted, such as by a killed sync.
+const cloneMarkerSuffix = ".hm-cloning"
+
+// interruptedClone reports whether the checkout of repo at repoPath was
+// left incomplete by an interrupted clone: its clone marker is still
+// there, or its HEAD doesn't resolve although origin is repo's URL, as
+// in a clone killed before checking out.
+func interruptedClone(repo *config.Repository, repoPath string) bool {
+	if !downloader.Exists(repoPath) {
+		return false
+	}
+	if _, err := os.Stat(repoPath + cloneMarkerSuffix); err == nil {
+		return true
+	}
+	if !downloader.IsGitRepository(repoPath) || downloader.HasHead(repoPath) {
+		return false
+	}
+	origin, err := downloader.ConfiguredRemoteURL(repoPath)
+	return err == nil && origin == repo.URL
+}
+
+// recoverInterruptedClone removes what an interrupted clone of repo left
+// at repoPath, so that it is cloned again. It returns whether there was
+// one.
+func (m *RepositoryManager) recoverInterruptedClone(repo *config.Repository, repoPath string) (bool, error) {
+	if !interruptedClone(repo, repoPath) {
+		_ = os.Remove(repoPath + cloneMarkerSuffix)
+		return false, nil
+	}
+	m.logger.Warn("removing interrupted clone", "repo", repo.Name, "path", repoPath)
+	if err := os.RemoveAll(repoPath); err != nil {
+		return false, fmt.Errorf("failed to remove interrupted clone: %w", err)
+	}
+	_ = os.Remove(repoPath + cloneMarkerSuffix)
+	return true, nil
+}
+
+// markClone creates the clone marker of repoPath. The returned function
+// removes it again once the clone succeeded, or failed without leaving
+// anything behind.
+func markClone(repoPath string) (func(success bool), error) {
+	marker := repoPath + cloneMarkerSuffix
+	if err := os.MkdirAll(filepath.Dir(marker), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		return nil, fmt.Errorf("failed to create clone marker: %w", err)
+	}
+	return func(success bool) {
+		if success || !downloader.Exists(repoPath) {
+			_ = os.Remove(marker)
+		}
+	}, nil
+}
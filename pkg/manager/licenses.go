@@ -0,0 +1,228 @@
+package manager
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/downloader"
+)
+
+// LicenseFile is a license file found in a repository.
+type LicenseFile struct {
+	Path string // Relative to the repository, with forward slashes
+	SPDX string // SPDX license identifier or expression, "" if unrecognized
+}
+
+// RepoLicenses is the license information of a repository.
+type RepoLicenses struct {
+	Name   string
+	URL    string
+	Path   string
+	Exists bool
+	Files  []LicenseFile
+	Error  error
+}
+
+// Licenses returns the distinct SPDX identifiers of the repository's
+// license files, sorted. Unrecognized files are left out.
+func (r RepoLicenses) Licenses() []string {
+	var ids []string
+	for _, f := range r.Files {
+		if f.SPDX != "" && !slices.Contains(ids, f.SPDX) {
+			ids = append(ids, f.SPDX)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Licenses finds the license files of the selected repositories: LICENSE,
+// LICENCE, COPYING, UNLICENSE, and their variants (LICENSE.md,
+// LICENSE-MIT, COPYING.LESSER, ...) at the top of each repository, and
+// the files of a REUSE LICENSES directory. Each file is identified by its
+// SPDX-License-Identifier line, by its name under LICENSES, or by the
+// wording of common licenses. Missing repositories are listed without
+// files.
+func (m *RepositoryManager) Licenses(ctx context.Context, filter Filter) ([]RepoLicenses, error) {
+	repos, err := m.getRepositories(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	sem := newSemaphore(m.concurrent)
+	var wg sync.WaitGroup
+	results := make([]RepoLicenses, len(repos))
+
+	for i, repo := range repos {
+		results[i] = RepoLicenses{Name: repo.Name, URL: repo.URL, Path: m.getRepoPath(&repo)}
+		if !downloader.Exists(results[i].Path) {
+			continue
+		}
+		results[i].Exists = true
+		if repo.Type == config.RepoTypeHTTP {
+			// Single downloaded files have no license files of their own
+			continue
+		}
+
+		wg.Add(1)
+		go func(r *RepoLicenses) {
+			defer wg.Done()
+			if err := sem.acquire(ctx); err != nil {
+				r.Error = err
+				return
+			}
+			defer sem.release()
+
+			r.Files, r.Error = findLicenseFiles(r.Path)
+		}(&results[i])
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// licenseNamePattern matches the names of license files at the top of a
+// repository.
+var licenseNamePattern = regexp.MustCompile(`(?i)^(licen[cs]e|copying|unlicense)([.\-_].*)?$`)
+
+// reuseLicensesDir is the directory REUSE-compliant repositories keep
+// their licenses in, one file per license named after its identifier.
+const reuseLicensesDir = "LICENSES"
+
+// findLicenseFiles returns the license files of the repository at dir.
+func findLicenseFiles(dir string) ([]LicenseFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []LicenseFile
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !licenseNamePattern.MatchString(e.Name()) {
+			continue
+		}
+		id, err := identifyLicense(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, LicenseFile{Path: e.Name(), SPDX: id})
+	}
+
+	entries, err = os.ReadDir(filepath.Join(dir, reuseLicensesDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		files = append(files, LicenseFile{
+			Path: path.Join(reuseLicensesDir, e.Name()),
+			SPDX: strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())),
+		})
+	}
+	return files, nil
+}
+
+// licenseSniffLen is how much of a license file is read to identify it.
+const licenseSniffLen = 64 << 10
+
+// spdxIdentifierPattern matches an SPDX-License-Identifier line, up to
+// the end of a comment around it.
+var spdxIdentifierPattern = regexp.MustCompile(`SPDX-License-Identifier:[ \t]*([^\r\n]*?)[ \t]*(\*/|-->)?[ \t]*(\r?\n|$)`)
+
+// identifyLicense returns the SPDX identifier of a license file, or "" if
+// it is not recognized.
+func identifyLicense(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	data, err := io.ReadAll(io.LimitReader(f, licenseSniffLen))
+	if err != nil {
+		return "", err
+	}
+
+	if m := spdxIdentifierPattern.FindSubmatch(data); m != nil && len(m[1]) > 0 {
+		return string(m[1]), nil
+	}
+	return classifyLicense(normalizeLicenseText(data)), nil
+}
+
+// normalizeLicenseText lowercases text and collapses its whitespace, so
+// that wrapped lines match the phrases of licenseRules.
+func normalizeLicenseText(data []byte) string {
+	var b strings.Builder
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Split(bufio.ScanWords)
+	for scanner.Scan() {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(strings.ToLower(scanner.Text()))
+	}
+	return b.String()
+}
+
+// licenseRules identify common licenses by phrases of their text, all of
+// which must appear. More specific rules come first: the LGPL quotes the
+// GPL, and BSD-3-Clause extends BSD-2-Clause.
+var licenseRules = []struct {
+	spdx    string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"gnu affero general public license", "version 3"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license", "version 3"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license", "version 2.1"}},
+	{"LGPL-2.0", []string{"gnu library general public license", "version 2"}},
+	{"GPL-3.0", []string{"gnu general public license", "version 3"}},
+	{"GPL-2.0", []string{"gnu general public license", "version 2"}},
+	{"Apache-2.0", []string{"apache license", "version 2.0"}},
+	{"MPL-2.0", []string{"mozilla public license", "2.0"}},
+	{"EPL-2.0", []string{"eclipse public license", "2.0"}},
+	{"EPL-1.0", []string{"eclipse public license", "1.0"}},
+	{"BSL-1.0", []string{"boost software license", "version 1.0"}},
+	{"CC0-1.0", []string{"cc0 1.0 universal"}},
+	{"Unlicense", []string{"this is free and unencumbered software released into the public domain"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name of"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "may not be used to endorse or promote"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+	{"MIT", []string{"permission is hereby granted, free of charge", "the above copyright notice and this permission notice shall be included"}},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software for any purpose with or without fee is hereby granted"}},
+	{"ISC", []string{"permission to use, copy, modify, and distribute this software for any purpose with or without fee is hereby granted"}},
+	{"Zlib", []string{"this software is provided 'as-is', without any express or implied warranty", "altered source versions must be plainly marked"}},
+}
+
+// classifyLicense returns the SPDX identifier of the first rule matching
+// normalized license text, or "".
+func classifyLicense(text string) string {
+	for _, rule := range licenseRules {
+		matched := true
+		for _, p := range rule.phrases {
+			if !strings.Contains(text, p) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return rule.spdx
+		}
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+package manager
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/downloader"
+)
+
+// LogOptions selects the commits returned by Log.
+type LogOptions struct {
+	// Since is any date git log --since accepts; empty for no bound
+	Since string
+
+	// SinceLastSync bounds each repository by when it was last synced
+	// according to the lock file instead of by Since
+	SinceLastSync bool
+
+	// Fetch logs each repository's configured ref, fetched from the
+	// remote, instead of the checked-out HEAD
+	Fetch bool
+
+	// Limit is the maximum number of commits returned; 0 for no limit
+	Limit int
+}
+
+// LogEntry is a commit in a repository.
+type LogEntry struct {
+	Repo string
+	downloader.Commit
+}
+
+// LogError records a repository whose commits could not be listed.
+type LogError struct {
+	Repo  string
+	Error error
+}
+
+// Log lists the commits of the selected git repositories in one view,
+// newest first. Repositories that are missing or not git checkouts are
+// skipped; those that fail are returned as errors alongside the commits.
+func (m *RepositoryManager) Log(ctx context.Context, filter Filter, opts LogOptions) ([]LogEntry, []LogError, error) {
+	repos, err := m.getRepositories(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sem := newSemaphore(m.concurrent)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var entries []LogEntry
+	var failures []LogError
+
+	for _, repo := range repos {
+		repoPath := m.getRepoPath(&repo)
+		if repo.Type != config.RepoTypeGit || !downloader.IsGitRepository(repoPath) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(r config.Repository, repoPath string) {
+			defer wg.Done()
+			if err := sem.acquire(ctx); err != nil {
+				return
+			}
+			defer sem.release()
+
+			commits, err := m.logRepository(ctx, &r, repoPath, opts)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failures = append(failures, LogError{Repo: r.Name, Error: err})
+				return
+			}
+			for _, c := range commits {
+				entries = append(entries, LogEntry{Repo: r.Name, Commit: c})
+			}
+		}(repo, repoPath)
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.After(entries[j].Time)
+	})
+	if opts.Limit > 0 && len(entries) > opts.Limit {
+		entries = entries[:opts.Limit]
+	}
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Repo < failures[j].Repo })
+	return entries, failures, nil
+}
+
+func (m *RepositoryManager) logRepository(ctx context.Context, repo *config.Repository, repoPath string, opts LogOptions) ([]downloader.Commit, error) {
+	dl := m.gitDownloader(ctx, repo)
+
+	rev := "HEAD"
+	if opts.Fetch && repo.Commit == "" {
+		ref := m.remoteRef(repo)
+		sha, err := dl.LsRemote(repo.URL, ref)
+		if err != nil {
+			return nil, err
+		}
+		if err := dl.EnsureCommit(repoPath, ref, sha); err != nil {
+			return nil, err
+		}
+		rev = sha
+	}
+
+	since := opts.Since
+	if opts.SinceLastSync {
+		since = ""
+		if m.lockFile != nil {
+			if entry, ok := m.lockFile.Get(repo.Name); ok && !entry.LastSyncedAt.IsZero() {
+				since = entry.LastSyncedAt.Format(time.RFC3339)
+			}
+		}
+	}
+
+	// Each repository contributes at most the overall limit
+	return dl.LogSince(repoPath, rev, since, opts.Limit)
+}
@@ -0,0 +1,52 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/config"
+)
+
+// LogDirName is the directory, relative to the workspace root, that holds
+// per-repository sync logs.
+var LogDirName = filepath.Join(config.StateDirName, "logs")
+
+// logTimeFormat is used in log file names so that they sort by time.
+const logTimeFormat = "20060102-150405"
+
+// defaultLogDir returns the log directory of the workspace described by
+// cfg, or "" if the config was not loaded from a file.
+func defaultLogDir(cfg *config.Config) string {
+	if cfg.Path() == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(cfg.Path()), LogDirName)
+}
+
+// openRepoLog creates the log file for one sync of a repository. Logging
+// is best effort: if the file cannot be created the sync runs without it.
+func (m *RepositoryManager) openRepoLog(name string) *os.File {
+	if m.logDir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(m.logDir, 0755); err != nil {
+		m.logger.Warn("failed to create log directory", "path", m.logDir, "error", err)
+		return nil
+	}
+
+	now := time.Now()
+	fileName := fmt.Sprintf("%s-%s.log", strings.ReplaceAll(name, "/", "_"), now.Format(logTimeFormat))
+	path := filepath.Join(m.logDir, fileName)
+	f, err := os.Create(path)
+	if err != nil {
+		m.logger.Warn("failed to create repository log", "path", path, "error", err)
+		return nil
+	}
+
+	fmt.Fprintf(f, "repository: %s\nstarted: %s\n\n", name, now.Format(time.RFC3339))
+	return f
+}
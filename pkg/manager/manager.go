@@ -1,29 +1,79 @@
+// Package manager implements repository synchronization and is the entry
+// point for embedding Harbormaster in other tools.
+//
+// Load a workspace with config.Load and lockfile.Load, create a
+// RepositoryManager with NewRepositoryManager, and call its methods. The
+// manager never prints; use WithProgress to observe progress, and the
+// *Context variants of long-running methods to support cancellation.
 package manager
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/tierone/harbormaster/pkg/config"
 	"github.com/tierone/harbormaster/pkg/downloader"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/keychain"
 	"github.com/tierone/harbormaster/pkg/lockfile"
+	"github.com/tierone/harbormaster/pkg/logging"
+	"github.com/tierone/harbormaster/pkg/provider"
+	"github.com/tierone/harbormaster/pkg/quarantine"
 	"github.com/tierone/harbormaster/pkg/types"
 	"github.com/tierone/harbormaster/pkg/ui"
 )
 
-// RepositoryManager coordinates all repository operations.
+// RepositoryManager coordinates all repository operations. It never
+// writes to the terminal itself; progress is delivered to the reporter
+// configured with WithUI or WithProgress.
 type RepositoryManager struct {
 	config      *config.Config
 	lockFile    *lockfile.LockFile
-	ui          *ui.ProgressManager
+	ui          ProgressReporter
+	logger      *slog.Logger
+	verbose     io.Writer
+	logDir      string // Directory for per-repository sync logs; empty disables
 	workDir     string
 	concurrent  int
-	locked      bool // If true, only sync to locked SHAs
-	interactive bool
+	locked      bool     // If true, only sync to locked SHAs
+	fsck        bool     // If true, check existing checkouts with git fsck and repair them before syncing
+	force       bool     // If true, sync repositories synced within their min_sync_interval too
+	namePrefix  string   // Prefix for repository names in nested workspaces
+	ancestors   []string // URLs of repositories enclosing a nested workspace
+	quarantine  *quarantine.Store
+	providers   providerSource     // Hosting service APIs, for latest-release refs
+	hostKeys    *hostKeyPins       // SSH host keys pinned in the managed known_hosts file
+	credentials *keychain.Cache    // HTTPS tokens of hosts, from the keychain
+	session     *credentialSession // HTTPS credentials entered while the process runs
+	prompt      CredentialPrompt   // Asks for credentials when a host refuses them; nil never asks
+	mirrors     map[string]*mirror // Shared clones of the remotes of the current sync
+	bundles     map[string]string  // Git bundles fetched from instead of the remote, by repository
 }
 
+// ProgressReporter receives progress updates during sync operations.
+// ui.ProgressManager implements it for terminal output.
+type ProgressReporter interface {
+	SetTotal(n int)
+	SendProgress(msg types.ProgressMsg)
+	Complete(duration time.Duration)
+}
+
+// progressFunc adapts a function to ProgressReporter.
+type progressFunc func(types.ProgressMsg)
+
+func (f progressFunc) SetTotal(int) {}
+
+func (f progressFunc) SendProgress(msg types.ProgressMsg) { f(msg) }
+
+func (f progressFunc) Complete(time.Duration) {}
+
 // ManagerOption configures the manager.
 type ManagerOption func(*RepositoryManager)
 
@@ -34,7 +84,12 @@ func WithLockFile(lf *lockfile.LockFile) ManagerOption {
 	}
 }
 
-// WithConcurrency sets max concurrent operations.
+// DefaultConcurrency is the number of concurrent operations when neither
+// the config nor WithConcurrency sets one.
+const DefaultConcurrency = 4
+
+// WithConcurrency sets max concurrent operations, overriding
+// general.concurrency. Values below 1 are ignored.
 func WithConcurrency(n int) ManagerOption {
 	return func(m *RepositoryManager) {
 		if n > 0 {
@@ -43,10 +98,70 @@ func WithConcurrency(n int) ManagerOption {
 	}
 }
 
+// WithQuarantine tracks consecutive sync failures in q. Sync skips
+// quarantined repositories and quarantines those that reach
+// general.quarantine_after failures, saving q after every sync.
+func WithQuarantine(q *quarantine.Store) ManagerOption {
+	return func(m *RepositoryManager) {
+		m.quarantine = q
+	}
+}
+
 // WithUI enables the progress UI.
 func WithUI(ui *ui.ProgressManager) ManagerOption {
 	return func(m *RepositoryManager) {
-		m.ui = ui
+		if ui != nil {
+			m.ui = ui
+		}
+	}
+}
+
+// WithProgress delivers progress updates to fn. It is called from the
+// goroutines performing operations, so it must be safe for concurrent use.
+func WithProgress(fn func(types.ProgressMsg)) ManagerOption {
+	return func(m *RepositoryManager) {
+		if fn != nil {
+			m.ui = progressFunc(fn)
+		}
+	}
+}
+
+// WithLogger sets the diagnostic logger. By default nothing is logged.
+func WithLogger(l *slog.Logger) ManagerOption {
+	return func(m *RepositoryManager) {
+		if l != nil {
+			m.logger = l
+		}
+	}
+}
+
+// WithVerbose writes every git command and HTTP request to w as it runs,
+// with its directory, exit status, and duration. Writes are serialized.
+func WithVerbose(w io.Writer) ManagerOption {
+	return func(m *RepositoryManager) {
+		if w != nil {
+			m.verbose = &syncWriter{w: w}
+		}
+	}
+}
+
+// syncWriter serializes writes from concurrent operations.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// WithLogDir sets the directory that receives a log of each repository's
+// git and HTTP output during sync. An empty dir disables the logs.
+func WithLogDir(dir string) ManagerOption {
+	return func(m *RepositoryManager) {
+		m.logDir = dir
 	}
 }
 
@@ -57,26 +172,87 @@ func WithLocked(locked bool) ManagerOption {
 	}
 }
 
-// WithInteractive enables interactive UI mode.
-func WithInteractive(interactive bool) ManagerOption {
+// WithFsck checks existing git checkouts with git fsck before syncing
+// them, repairing corrupt ones as Fsck does.
+func WithFsck(fsck bool) ManagerOption {
 	return func(m *RepositoryManager) {
-		m.interactive = interactive
+		m.fsck = fsck
 	}
 }
 
-// NewRepositoryManager creates a new manager.
+// WithForce syncs repositories even if they were synced within their
+// min_sync_interval.
+func WithForce(force bool) ManagerOption {
+	return func(m *RepositoryManager) {
+		m.force = force
+	}
+}
+
+// WithCredentials sets the store HTTPS tokens of hosts are looked up in,
+// for git, HTTP downloads, and hosting service APIs. By default it is
+// the operating system's keychain.
+func WithCredentials(store keychain.Store) ManagerOption {
+	return func(m *RepositoryManager) {
+		if store != nil {
+			m.credentials = keychain.NewCache(store)
+		}
+	}
+}
+
+// WithCredentialPrompt makes a sync ask for the credential of each HTTPS
+// host that refused to authenticate repositories, once all have been
+// tried, and sync those repositories again with it.
+func WithCredentialPrompt(prompt CredentialPrompt) ManagerOption {
+	return func(m *RepositoryManager) {
+		m.prompt = prompt
+	}
+}
+
+// WithProviders sets the hosting service API clients used to look up
+// releases. By default clients are created from the config's hosts.
+func WithProviders(clients *provider.Clients) ManagerOption {
+	return func(m *RepositoryManager) {
+		if clients != nil {
+			m.providers = clients
+		}
+	}
+}
+
+// WithInteractive has no effect.
+//
+// Deprecated: the manager no longer creates a UI; pass one with WithUI.
+func WithInteractive(interactive bool) ManagerOption {
+	return func(m *RepositoryManager) {}
+}
+
+// NewRepositoryManager creates a new manager. Concurrency defaults to
+// general.concurrency in cfg, or DefaultConcurrency if it is unset.
 func NewRepositoryManager(cfg *config.Config, opts ...ManagerOption) *RepositoryManager {
 	m := &RepositoryManager{
 		config:      cfg,
 		workDir:     cfg.General.WorkDir,
-		concurrent:  4,
-		interactive: true,
+		logDir:      defaultLogDir(cfg),
+		logger:      logging.Discard(),
+		concurrent:  DefaultConcurrency,
+		hostKeys:    &hostKeyPins{path: defaultKnownHosts(cfg), errs: make(map[string]error)},
+		credentials: keychain.NewCache(keychain.System()),
+		session:     newCredentialSession(),
+	}
+	if cfg.General.Concurrency > 0 {
+		m.concurrent = cfg.General.Concurrency
 	}
 
 	for _, opt := range opts {
 		opt(m)
 	}
 
+	if m.providers == nil {
+		popts := provider.OptionsFromConfig(cfg)
+		popts.Logger = m.logger
+		popts.Keychain = m.credentials
+		m.providers = provider.NewClients(cfg, popts)
+	}
+
 	return m
 }
 
@@ -88,6 +264,24 @@ type Filter struct {
 	All      bool     // All repositories
 }
 
+// String describes the filter, e.g. "all" or "project: web".
+func (f Filter) String() string {
+	if f.All || (len(f.Names) == 0 && len(f.Projects) == 0 && len(f.Tags) == 0) {
+		return "all"
+	}
+	var parts []string
+	if len(f.Names) > 0 {
+		parts = append(parts, strings.Join(f.Names, ", "))
+	}
+	if len(f.Projects) > 0 {
+		parts = append(parts, "project: "+strings.Join(f.Projects, ", "))
+	}
+	if len(f.Tags) > 0 {
+		parts = append(parts, "tag: "+strings.Join(f.Tags, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
 // RepoStatus represents the status of a repository.
 type RepoStatus struct {
 	Name         string
@@ -100,6 +294,18 @@ type RepoStatus struct {
 	IsDirty      bool
 	NeedsUpdate  bool
 	Error        error
+
+	// Set by StatusUpstream for repositories compared with their remote
+	RemoteChecked bool
+	RemoteSHA     string
+	Ahead         int // Commits in the checkout missing from the remote ref
+	Behind        int // Commits on the remote ref missing from the checkout
+	RemoteError   error
+}
+
+// Repositories returns the configured repositories matching the filter.
+func (m *RepositoryManager) Repositories(filter Filter) ([]config.Repository, error) {
+	return m.getRepositories(filter)
 }
 
 // getRepositories returns the repositories matching the filter.
@@ -114,7 +320,7 @@ func (m *RepositoryManager) getRepositories(filter Filter) ([]config.Repository,
 	for _, name := range filter.Names {
 		repo, ok := m.config.GetRepository(name)
 		if !ok {
-			return nil, fmt.Errorf("repository not found: %s", name)
+			return nil, errcode.Wrap(errcode.UnknownRepository, fmt.Errorf("repository not found: %s", name))
 		}
 		repoSet[name] = *repo
 	}
@@ -151,22 +357,86 @@ func (m *RepositoryManager) getRepoPath(repo *config.Repository) string {
 	return filepath.Join(m.workDir, repo.GetEffectivePath())
 }
 
+// gitRepositories returns the selected git repositories that have been
+// cloned.
+func (m *RepositoryManager) gitRepositories(filter Filter) ([]config.Repository, error) {
+	repos, err := m.getRepositories(filter)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []config.Repository
+	for _, repo := range repos {
+		if repo.Type == config.RepoTypeGit && downloader.IsGitRepository(m.getRepoPath(&repo)) {
+			candidates = append(candidates, repo)
+		}
+	}
+	return candidates, nil
+}
+
+// gitDownloader returns a git downloader for operating on the checkout
+// of repo.
+func (m *RepositoryManager) gitDownloader(ctx context.Context, repo *config.Repository) *downloader.GitDownloader {
+	opts := downloader.OptionsFromRepository(repo, m.config)
+	opts.Context = ctx
+	opts.Logger = m.logger.With("repo", repo.Name)
+	opts.Verbose = m.verbose
+	// On failure git refuses the host, reporting the failure itself
+	knownHosts, err := m.knownHosts(ctx, repo)
+	if err != nil {
+		m.logger.Warn("failed to pin SSH host keys", "repo", repo.Name, "error", err)
+	}
+	opts.KnownHostsFile = knownHosts
+	m.withCredentials(&opts, repo)
+	return downloader.NewGitDownloader(opts)
+}
+
 // syncRepository syncs a single repository.
-func (m *RepositoryManager) syncRepository(repo *config.Repository) types.OperationResult {
+func (m *RepositoryManager) syncRepository(ctx context.Context, repo *config.Repository) types.OperationResult {
 	startTime := time.Now()
 	repoPath := m.getRepoPath(repo)
 
+	displayName := m.namePrefix + repo.Name
+
 	result := types.OperationResult{
 		RepoName: repo.Name,
 		RepoURL:  repo.URL,
 		Branch:   repo.Branch,
 		Tag:      repo.Tag,
 	}
+	if m.lockFile != nil {
+		result.PreviousSHA, _ = m.lockFile.GetResolvedSHA(repo.Name)
+	}
+
+	logFile := m.openRepoLog(displayName)
+	if logFile != nil {
+		defer func() { _ = logFile.Close() }()
+		result.LogPath = logFile.Name()
+	}
+
+	// fail records err as the result, pointing at the log for details.
+	// Errors without a more specific code are coded as failed syncs.
+	fail := func(err error) types.OperationResult {
+		if errcode.Of(err) == "" {
+			err = errcode.Wrap(errcode.SyncFailed, err)
+		}
+		if logFile != nil {
+			fmt.Fprintf(logFile, "error: %v\n", err)
+			err = fmt.Errorf("%w (log: %s)", err, logFile.Name())
+		}
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		if m.ui != nil {
+			msg := ui.CreateErrorMsg(displayName, repo.URL, err)
+			msg.LogPath = result.LogPath
+			m.ui.SendProgress(msg)
+		}
+		return result
+	}
 
 	// Send initial progress
 	if m.ui != nil {
 		m.ui.SendProgress(ui.CreateProgressMsg(
-			repo.Name, repo.URL,
+			displayName, repo.URL,
 			types.PhaseInit, "Starting...",
 		))
 	}
@@ -176,28 +446,189 @@ func (m *RepositoryManager) syncRepository(repo *config.Repository) types.Operat
 	if m.locked && m.lockFile != nil {
 		sha, ok := m.lockFile.GetResolvedSHA(repo.Name)
 		if !ok {
-			result.Error = fmt.Errorf("no lock entry for repository (run sync without --locked first)")
-			result.Duration = time.Since(startTime)
-			if m.ui != nil {
-				m.ui.SendProgress(ui.CreateErrorMsg(repo.Name, repo.URL, result.Error))
-			}
-			return result
+			return fail(errcode.Wrap(errcode.LockMissing, fmt.Errorf("no lock entry for repository (run sync without --locked first)")))
 		}
 		targetSHA = sha
 	}
 
+	// A version constraint or the latest release resolves to a tag, and a
+	// branch pattern to a branch, checked out like a configured one.
+	// Locked mode reuses the ref that was locked.
+	var resolvedRef string
+	if repo.ResolvesRef() {
+		if m.locked {
+			if resolvedRef = m.lockedRef(repo); resolvedRef == "" {
+				ref := repo.GetEffectiveRef(m.config.General.DefaultBranch)
+				return fail(errcode.Wrap(errcode.LockMissing, fmt.Errorf("lock entry has no resolved ref for %s (run sync without --locked first)", ref)))
+			}
+		} else {
+			ref, _, err := m.resolveRef(ctx, repo)
+			if err != nil {
+				return fail(err)
+			}
+			resolvedRef = ref
+		}
+		if repo.BranchPattern() {
+			result.Branch = resolvedRef
+		} else {
+			result.Tag = resolvedRef
+		}
+	}
+
 	// Create downloader
-	dl, err := downloader.NewFromRepository(repo, m.config)
+	opts := downloader.OptionsFromRepository(repo, m.config)
+	if repo.BranchPattern() {
+		opts.Branch = resolvedRef
+	} else if resolvedRef != "" {
+		opts.Tag = resolvedRef
+	}
+	if bundle, ok := m.bundles[repo.Name]; ok {
+		// Fetch from the bundle alone, bypassing any mirror
+		opts.GitConfig = []string{"url." + bundle + ".insteadOf=" + repo.URL}
+	} else {
+		knownHosts, err := m.knownHosts(ctx, repo)
+		if err != nil {
+			return fail(err)
+		}
+		opts.KnownHostsFile = knownHosts
+		m.withCredentials(&opts, repo)
+		shared, err := m.sharedClone(ctx, repo)
+		if err != nil {
+			return fail(err)
+		}
+		if shared != "" {
+			opts.GitConfig = append(opts.GitConfig, fetchFrom(shared, repo.URL))
+			opts.Reference = shared
+		}
+	}
+	opts.Context = ctx
+	opts.Logger = m.logger.With("repo", displayName)
+	opts.Verbose = m.verbose
+	if logFile != nil {
+		opts.Output = logFile
+	}
+	dl, err := downloader.New(repo.Type, opts)
 	if err != nil {
-		result.Error = fmt.Errorf("failed to create downloader: %w", err)
-		result.Duration = time.Since(startTime)
-		if m.ui != nil {
-			m.ui.SendProgress(ui.CreateErrorMsg(repo.Name, repo.URL, result.Error))
+		return fail(fmt.Errorf("failed to create downloader: %w", err))
+	}
+
+	// Vendored repositories have no VCS metadata to update from, so they
+	// are always cloned fresh into a staging directory and swapped in.
+	vendored := m.config.IsVendored(repo)
+
+	// A clone interrupted by an earlier sync is removed and cloned again
+	if repo.Type == config.RepoTypeGit && !vendored {
+		if result.Recovered, err = m.recoverInterruptedClone(repo, repoPath); err != nil {
+			return fail(err)
+		}
+		if result.Recovered && m.ui != nil {
+			m.ui.SendProgress(ui.CreateProgressMsg(
+				displayName, repo.URL,
+				types.PhaseInit, "Removed interrupted clone",
+			))
+		}
+	}
+
+	// Corrupt checkouts are repaired, or moved aside to be cloned again
+	// and put back if that fails
+	if m.fsck && repo.Type == config.RepoTypeGit && !vendored && downloader.IsGitRepository(repoPath) {
+		gd := m.gitDownloader(ctx, repo)
+		problems, err := gd.Fsck(repoPath)
+		if err != nil {
+			return fail(err)
+		}
+		if len(problems) > 0 {
+			m.logger.Warn("git fsck found problems", "repo", displayName, "problems", len(problems), "first", problems[0])
+			if result.Repaired, err = m.repairCorruption(repo, gd); err != nil {
+				return fail(err)
+			}
+		}
+		if result.Repaired == RepairRecloned {
+			aside := repoPath + corruptSuffix
+			defer func() {
+				var err error
+				if result.Success {
+					err = os.RemoveAll(aside)
+				} else {
+					err = restoreCheckout(repoPath, aside)
+				}
+				if err != nil {
+					m.logger.Warn("failed to clean up corrupt checkout", "repo", displayName, "path", aside, "error", err)
+				}
+			}()
 		}
-		return result
 	}
 
 	exists := downloader.Exists(repoPath)
+	clonePath := repoPath
+	if vendored {
+		if err := m.checkVendorTarget(ctx, repo, repoPath); err != nil {
+			return fail(err)
+		}
+		// The staging clone is gone once moved into place; whatever is
+		// left of it after a failure is removed
+		clonePath = repoPath + vendorStagingSuffix
+		_ = os.RemoveAll(clonePath)
+		defer func() { _ = os.RemoveAll(clonePath) }()
+		exists = false
+	}
+
+	// Path repositories are copied or linked again on every sync, from
+	// a source resolved against the config directory.
+	source := repo.URL
+	if repo.Type == config.RepoTypeHTTP {
+		source = m.config.MirrorURL(source)
+	}
+	if repo.Type == config.RepoTypePath {
+		if source, err = m.config.LocalSource(repo); err != nil {
+			return fail(fmt.Errorf("failed to resolve source directory: %w", err))
+		}
+		exists = false
+	}
+
+	// A changed URL, such as a switch between HTTPS and SSH, moves origin
+	if gd, ok := dl.(*downloader.GitDownloader); ok && exists {
+		if err := gd.SetRemoteURL(repoPath, repo.URL); err != nil {
+			return fail(err)
+		}
+	}
+
+	action := "clone"
+	if exists {
+		action = "update"
+	}
+	m.logger.Debug("syncing repository",
+		"repo", displayName,
+		"action", action,
+		"ref", repo.GetEffectiveRef(m.config.General.DefaultBranch),
+		"locked_sha", targetSHA,
+		"resolved_ref", resolvedRef,
+		"vendored", vendored,
+		"path", repoPath,
+	)
+
+	// A checkout rejected by the commit policy or signature check is put
+	// back where it was, so that the rejected commit is not left behind
+	var headBranch, headSHA string
+	if gd, ok := dl.(*downloader.GitDownloader); ok && exists {
+		headBranch, _ = gd.HeadBranch(repoPath)
+		headSHA, _ = gd.GetCurrentRef(repoPath)
+	}
+	reject := func(err error) types.OperationResult {
+		switch {
+		case vendored || repo.Type != config.RepoTypeGit:
+			// The staging clone is removed anyway
+		case !exists:
+			if rmErr := os.RemoveAll(repoPath); rmErr != nil {
+				m.logger.Warn("failed to remove rejected clone", "repo", displayName, "path", repoPath, "error", rmErr)
+			}
+		case headSHA != "":
+			if rsErr := m.gitDownloader(ctx, repo).Restore(repoPath, headBranch, headSHA); rsErr != nil {
+				m.logger.Warn("failed to restore checkout", "repo", displayName, "sha", headSHA, "error", rsErr)
+			}
+		}
+		return fail(err)
+	}
 
 	var sha string
 	var progressCh <-chan types.ProgressUpdate
@@ -206,36 +637,42 @@ func (m *RepositoryManager) syncRepository(repo *config.Repository) types.Operat
 		// Update existing repository
 		sha, progressCh, err = dl.UpdateWithProgress(repoPath)
 	} else {
+		if repo.Type == config.RepoTypeGit && !vendored {
+			unmark, err := markClone(repoPath)
+			if err != nil {
+				return fail(err)
+			}
+			defer func() { unmark(result.Success) }()
+		}
 		// Clone new repository
-		sha, progressCh, err = dl.DownloadWithProgress(repo.URL, repoPath)
+		sha, progressCh, err = dl.DownloadWithProgress(source, clonePath)
 	}
 
 	if err != nil {
-		result.Error = err
-		result.Duration = time.Since(startTime)
-		if m.ui != nil {
-			m.ui.SendProgress(ui.CreateErrorMsg(repo.Name, repo.URL, err))
-		}
-		return result
+		return fail(err)
 	}
 
 	// Process progress updates
 	for update := range progressCh {
 		if m.ui != nil {
-			percent := 0.0
+			percent := update.Percent
 			if update.BytesTotal > 0 {
 				percent = float64(update.BytesDone) / float64(update.BytesTotal) * 100
 			}
-			m.ui.SendProgress(ui.CreateProgressMsgWithPercent(
-				repo.Name, repo.URL,
+			msg := ui.CreateProgressMsgWithPercent(
+				displayName, repo.URL,
 				update.Phase, percent, update.Message,
-			))
+			)
+			msg.BytesDone = update.BytesDone
+			msg.BytesTotal = update.BytesTotal
+			msg.Stage = update.Stage
+			msg.ObjectsDone = update.ObjectsDone
+			msg.ObjectsTotal = update.ObjectsTotal
+			m.ui.SendProgress(msg)
 		}
 
 		if update.Error != nil {
-			result.Error = update.Error
-			result.Duration = time.Since(startTime)
-			return result
+			return fail(update.Error)
 		}
 
 		if update.Phase == types.PhaseComplete {
@@ -245,25 +682,48 @@ func (m *RepositoryManager) syncRepository(repo *config.Repository) types.Operat
 
 	// Get final SHA if not set
 	if sha == "" {
-		sha, err = dl.GetCurrentRef(repoPath)
+		sha, err = dl.GetCurrentRef(clonePath)
 		if err != nil {
-			result.Error = fmt.Errorf("failed to get current ref: %w", err)
-			result.Duration = time.Since(startTime)
-			if m.ui != nil {
-				m.ui.SendProgress(ui.CreateErrorMsg(repo.Name, repo.URL, result.Error))
-			}
-			return result
+			return fail(fmt.Errorf("failed to get current ref: %w", err))
 		}
 	}
 
 	// Verify locked SHA if in locked mode
 	if m.locked && targetSHA != "" && sha != targetSHA {
-		result.Error = fmt.Errorf("SHA mismatch: expected %s, got %s", targetSHA[:8], sha[:8])
-		result.Duration = time.Since(startTime)
-		if m.ui != nil {
-			m.ui.SendProgress(ui.CreateErrorMsg(repo.Name, repo.URL, result.Error))
+		return fail(errcode.Wrap(errcode.LockDrift, fmt.Errorf("SHA mismatch: expected %s, got %s", targetSHA[:8], sha[:8])))
+	}
+
+	// A force-push upstream drops the previously locked commit from the
+	// branch's history
+	if !m.locked && m.historyRewritten(ctx, repo, clonePath, result.Branch, result.PreviousSHA, sha) {
+		result.Rewritten = true
+		m.logger.Warn("branch history was rewritten upstream", "repo", displayName, "branch", result.Branch, "previous_sha", result.PreviousSHA, "sha", sha)
+	}
+
+	if err := m.checkCommitPolicy(ctx, repo, clonePath, result.PreviousSHA, sha); err != nil {
+		return reject(err)
+	}
+
+	// Projects can require every commit they check out to be signed
+	if m.config.RequiresSigned(repo) {
+		if err := m.gitDownloader(ctx, repo).VerifySignature(clonePath, sha, opts.Tag); err != nil {
+			return reject(err)
 		}
-		return result
+	}
+
+	// Clones of Gerrit projects need its hook to add Change-Ids
+	if repo.Type == config.RepoTypeGit && !vendored {
+		if err := m.installCommitMsgHook(ctx, repo, clonePath); err != nil {
+			m.logger.Warn("failed to install commit-msg hook", "repo", displayName, "error", err)
+		}
+	}
+
+	if vendored {
+		treeHash, err := m.finishVendoring(clonePath, repoPath, repo.Subdir)
+		if err != nil {
+			return fail(fmt.Errorf("failed to vendor: %w", err))
+		}
+		result.TreeHash = treeHash
 	}
 
 	result.Success = true
@@ -272,10 +732,12 @@ func (m *RepositoryManager) syncRepository(repo *config.Repository) types.Operat
 
 	// Send completion progress
 	if m.ui != nil {
-		m.ui.SendProgress(ui.CreateCompletedMsg(
-			repo.Name, repo.URL,
+		msg := ui.CreateCompletedMsg(
+			displayName, repo.URL,
 			fmt.Sprintf("Synced at %s", sha[:8]),
-		))
+		)
+		msg.LogPath = result.LogPath
+		m.ui.SendProgress(msg)
 	}
 
 	return result
@@ -304,7 +766,22 @@ func (m *RepositoryManager) updateLockFile(results []types.OperationResult) {
 			requestedRef,
 			result.CommitSHA,
 		)
+		if repo.BranchPattern() {
+			entry.ResolvedRef = result.Branch
+		} else if repo.ResolvesTag() {
+			entry.ResolvedRef = result.Tag
+		}
+		if result.TreeHash != "" {
+			entry.Vendored = true
+			entry.TreeHash = result.TreeHash
+		}
+		if result.Rewritten {
+			entry.RewrittenFrom = result.PreviousSHA
+		}
 		m.lockFile.Update(result.RepoName, entry)
+		if result.PreviousSHA != result.CommitSHA {
+			m.logger.Debug("lock entry updated", "repo", result.RepoName, "previous_sha", result.PreviousSHA, "sha", result.CommitSHA)
+		}
 	}
 }
 
@@ -320,8 +797,16 @@ func newSemaphore(n int) semaphore {
 	return make(chan struct{}, n)
 }
 
-func (s semaphore) acquire() {
-	s <- struct{}{}
+func (s semaphore) acquire(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (s semaphore) release() {
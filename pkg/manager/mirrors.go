@@ -0,0 +1,121 @@
+package manager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/config"
+)
+
+// CacheDirName is the cache directory used when cache_dir is not set,
+// relative to the workspace root.
+var CacheDirName = filepath.Join(config.StateDirName, "cache")
+
+// mirror is the shared clone of a remote that more than one repository
+// of the workspace checks out, updated at most once per sync so that the
+// remote is fetched from the network once. Checkouts borrow its objects
+// rather than copying them.
+type mirror struct {
+	path string
+	once sync.Once
+	err  error
+}
+
+// defaultCacheDir returns the cache directory of the workspace described
+// by cfg: cache_dir if set, or else next to the config file, or in the
+// work directory if the config was not loaded from a file.
+func defaultCacheDir(cfg *config.Config) string {
+	switch {
+	case cfg.General.CacheDir != "":
+		return cfg.General.CacheDir
+	case cfg.Path() == "":
+		return filepath.Join(cfg.General.WorkDir, CacheDirName)
+	default:
+		return filepath.Join(filepath.Dir(cfg.Path()), CacheDirName)
+	}
+}
+
+// mirrorPath returns where the shared clone of a remote URL is kept in
+// the cache directory dir.
+func mirrorPath(dir, url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(dir, "git", hex.EncodeToString(sum[:8])+".git")
+}
+
+// sharedRemotes returns the shared clones in the cache directory dir of
+// the git repositories in repos whose URL another one also uses, by URL.
+// Repositories fetching refs beyond branches and tags, which a shared
+// clone does not hold, are left out. Pass every repository of the
+// workspace, not just those being synced, so that a checkout keeps using
+// the shared clone it borrows objects from.
+func sharedRemotes(dir string, repos []config.Repository) map[string]*mirror {
+	count := make(map[string]int)
+	for _, repo := range repos {
+		if repo.Type == config.RepoTypeGit && !repo.CustomRef() && len(repo.Refspecs) == 0 {
+			count[repo.URL]++
+		}
+	}
+	mirrors := make(map[string]*mirror)
+	for url, n := range count {
+		if n > 1 {
+			mirrors[url] = &mirror{path: mirrorPath(dir, url)}
+		}
+	}
+	return mirrors
+}
+
+// sharedClone updates the shared clone of repo's remote, once per sync,
+// and returns its path, or "" if repo's remote is not shared.
+func (m *RepositoryManager) sharedClone(ctx context.Context, repo *config.Repository) (string, error) {
+	if repo.Type != config.RepoTypeGit || repo.CustomRef() || len(repo.Refspecs) > 0 {
+		return "", nil
+	}
+	mr, ok := m.mirrors[repo.URL]
+	if !ok {
+		return "", nil
+	}
+
+	mr.once.Do(func() {
+		mr.err = m.updateSharedClone(ctx, repo, mr.path)
+	})
+	if mr.err != nil {
+		return "", mr.err
+	}
+	return mr.path, nil
+}
+
+// fetchFrom returns the git setting that makes git fetch url from the
+// shared clone at path. The remote URL recorded in checkouts stays url.
+func fetchFrom(path, url string) string {
+	// insteadOf rewrites every URL starting with url, so a submodule
+	// whose URL merely extends it would be rewritten too; such URLs are
+	// rare enough not to be worth a more precise mechanism.
+	abs := filepath.ToSlash(path)
+	if !strings.HasPrefix(abs, "/") {
+		abs = "/" + abs
+	}
+	return "url.file://" + abs + ".insteadOf=" + url
+}
+
+// updateSharedClone clones or fetches the remote of repo into the shared
+// clone at path, with any extra refs, and marks it as used.
+func (m *RepositoryManager) updateSharedClone(ctx context.Context, repo *config.Repository, path string, refs ...string) error {
+	m.logger.Debug("updating shared clone", "url", repo.URL, "path", path)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := m.gitDownloader(ctx, repo).UpdateMirror(repo.URL, path, refs...); err != nil {
+		return err
+	}
+	// Record the use for hm cache clean --older-than
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return nil
+}
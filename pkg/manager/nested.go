@@ -0,0 +1,156 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/downloader"
+	"github.com/tierone/harbormaster/pkg/lockfile"
+	"github.com/tierone/harbormaster/pkg/provider"
+	"github.com/tierone/harbormaster/pkg/types"
+)
+
+// syncNestedWorkspaces syncs the workspaces defined by config files inside
+// successfully synced repositories. Nested results are named
+// "<parent>/<repo>" so they can be reported alongside top-level results.
+func (m *RepositoryManager) syncNestedWorkspaces(ctx context.Context, results []types.OperationResult) []types.OperationResult {
+	var nested []types.OperationResult
+	for _, result := range results {
+		if !result.Success {
+			continue
+		}
+		repo, ok := m.config.GetRepository(result.RepoName)
+		if !ok {
+			continue
+		}
+		cfgPath := filepath.Join(m.getRepoPath(repo), config.ConfigFileName)
+		if !downloader.Exists(cfgPath) {
+			continue
+		}
+		nested = append(nested, m.syncNestedWorkspace(ctx, repo, cfgPath)...)
+	}
+	return nested
+}
+
+// syncNestedWorkspace syncs a single nested workspace using its own config
+// and lock file, then recurses into its repositories if that config sets
+// recurse_workspaces. The workspace and its repositories must lie inside
+// the parent repository.
+func (m *RepositoryManager) syncNestedWorkspace(ctx context.Context, parent *config.Repository, cfgPath string) []types.OperationResult {
+	prefix := parent.Name + "/"
+	failed := func(err error) []types.OperationResult {
+		return prefixResults(prefix, []types.OperationResult{{
+			RepoName: config.ConfigFileName,
+			RepoURL:  parent.URL,
+			Error:    err,
+		}})
+	}
+
+	nestedCfg, err := config.Load(cfgPath)
+	if err != nil {
+		return failed(fmt.Errorf("failed to load nested workspace: %w", err))
+	}
+
+	// The nested workspace must stay inside the repository that holds it
+	parentPath := m.getRepoPath(parent)
+	if inside, err := config.PathWithin(nestedCfg.General.WorkDir, parentPath); err != nil || !inside {
+		return failed(fmt.Errorf("nested work_dir %s is outside the repository %s", nestedCfg.General.WorkDir, parentPath))
+	}
+
+	lockPath := filepath.Join(filepath.Dir(cfgPath), lockfile.LockFileName)
+	nestedLock, err := lockfile.Load(lockPath)
+	if err != nil {
+		return failed(fmt.Errorf("failed to load nested lock file: %w", err))
+	}
+
+	m.logger.Debug("syncing nested workspace", "repo", m.namePrefix+parent.Name, "config", cfgPath)
+
+	child := &RepositoryManager{
+		config:      nestedCfg,
+		lockFile:    nestedLock,
+		ui:          m.ui,
+		logger:      m.logger,
+		verbose:     m.verbose,
+		logDir:      m.logDir,
+		workDir:     nestedCfg.General.WorkDir,
+		concurrent:  m.concurrent,
+		locked:      m.locked,
+		namePrefix:  m.namePrefix + prefix,
+		ancestors:   append(append([]string{}, m.ancestors...), parent.URL),
+		hostKeys:    &hostKeyPins{path: defaultKnownHosts(nestedCfg), errs: make(map[string]error)},
+		credentials: m.credentials,
+		session:     m.session,
+		prompt:      m.prompt,
+	}
+	popts := provider.OptionsFromConfig(nestedCfg)
+	popts.Logger = m.logger
+	popts.Keychain = m.credentials
+	child.providers = provider.NewClients(nestedCfg, popts)
+
+	if err := child.ensureWorkDir(); err != nil {
+		return failed(fmt.Errorf("failed to create nested work directory: %w", err))
+	}
+
+	// Skip repositories that would re-enter an enclosing workspace
+	var results []types.OperationResult
+	var repos []config.Repository
+	for _, repo := range nestedCfg.Repositories {
+		if child.isAncestor(repo.URL) {
+			results = append(results, types.OperationResult{
+				RepoName: repo.Name,
+				RepoURL:  repo.URL,
+				Error:    fmt.Errorf("workspace cycle detected: %s encloses this workspace", repo.URL),
+			})
+			continue
+		}
+		if inside, err := config.PathWithin(child.getRepoPath(&repo), parentPath); err != nil || !inside {
+			results = append(results, types.OperationResult{
+				RepoName: repo.Name,
+				RepoURL:  repo.URL,
+				Error:    fmt.Errorf("path %s is outside the repository %s", child.getRepoPath(&repo), parentPath),
+			})
+			continue
+		}
+		repos = append(repos, repo)
+	}
+
+	synced := child.syncRepositories(ctx, repos)
+	child.retryUnauthorized(ctx, repos, synced)
+	child.updateLockFile(synced)
+	if !m.locked {
+		if err := nestedLock.Save(lockPath); err != nil {
+			results = append(results, types.OperationResult{
+				RepoName: config.ConfigFileName,
+				RepoURL:  parent.URL,
+				Error:    fmt.Errorf("failed to save nested lock file: %w", err),
+			})
+		}
+	}
+
+	results = append(results, synced...)
+	if nestedCfg.General.RecurseWorkspaces {
+		results = append(results, child.syncNestedWorkspaces(ctx, synced)...)
+	}
+
+	return prefixResults(prefix, results)
+}
+
+// prefixResults prepends prefix to each result's repository name.
+func prefixResults(prefix string, results []types.OperationResult) []types.OperationResult {
+	for i := range results {
+		results[i].RepoName = prefix + results[i].RepoName
+	}
+	return results
+}
+
+// isAncestor returns true if url belongs to a repository enclosing this workspace.
+func (m *RepositoryManager) isAncestor(url string) bool {
+	for _, a := range m.ancestors {
+		if a == url {
+			return true
+		}
+	}
+	return false
+}
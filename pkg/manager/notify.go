@@ -0,0 +1,119 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/notify"
+	"github.com/tierone/harbormaster/pkg/types"
+)
+
+// NotifySync sends a summary of a sync result to the configured
+// notification targets. Every target is attempted; failures are joined.
+func (m *RepositoryManager) NotifySync(ctx context.Context, result *types.SyncResult) error {
+	notifiers, err := m.notifiers()
+	if err != nil {
+		return err
+	}
+	if len(notifiers) == 0 {
+		return nil
+	}
+
+	return send(ctx, notifiers, notify.NewSyncSummary(m.WorkspaceName(), result))
+}
+
+// NotifyVerify sends a summary of a signature check to the configured
+// notification targets, like NotifySync.
+func (m *RepositoryManager) NotifyVerify(ctx context.Context, results []RepoVerification, duration time.Duration) error {
+	notifiers, err := m.notifiers()
+	if err != nil {
+		return err
+	}
+	if len(notifiers) == 0 {
+		return nil
+	}
+
+	entries := make([]notify.Result, 0, len(results))
+	for _, r := range results {
+		res := notify.Result{Name: r.Name, Success: r.Error == nil, SHA: r.SHA}
+		if repo, ok := m.config.GetRepository(r.Name); ok {
+			res.URL = repo.URL
+		}
+		if r.Error != nil {
+			res.Error = r.Error.Error()
+			res.Code = string(errcode.Of(r.Error))
+		}
+		entries = append(entries, res)
+	}
+
+	return send(ctx, notifiers, notify.NewVerifySummary(m.WorkspaceName(), entries, duration))
+}
+
+// send delivers a summary to every notifier and joins the failures.
+func send(ctx context.Context, notifiers []notify.Notifier, summary *notify.Summary) error {
+	var errs []error
+	for _, n := range notifiers {
+		if err := n.Notify(ctx, summary); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// notifiers builds the notification targets from the config.
+func (m *RepositoryManager) notifiers() ([]notify.Notifier, error) {
+	var notifiers []notify.Notifier
+
+	hook := m.config.Notify.Webhook
+	client := &http.Client{Timeout: hook.Timeout}
+	for _, url := range hook.URLs {
+		notifiers = append(notifiers, &notify.Webhook{
+			URL:           url,
+			Secret:        hook.Secret,
+			RetryAttempts: hook.RetryAttempts,
+			RetryDelay:    hook.RetryDelay,
+			Client:        client,
+		})
+	}
+
+	chats := []struct {
+		kind notify.ChatKind
+		cfg  config.ChatConfig
+	}{
+		{notify.ChatSlack, m.config.Notify.Slack},
+		{notify.ChatTeams, m.config.Notify.Teams},
+	}
+	for _, c := range chats {
+		if !c.cfg.Enabled() {
+			continue
+		}
+		chat, err := notify.NewChat(c.kind, c.cfg.URL, c.cfg.Template, notify.Trigger(c.cfg.On))
+		if err != nil {
+			return nil, err
+		}
+		chat.RetryAttempts = config.DefaultRetryAttempts
+		chat.RetryDelay = config.DefaultRetryDelay
+		chat.Client = &http.Client{Timeout: config.DefaultNotifyTimeout}
+		notifiers = append(notifiers, chat)
+	}
+
+	return notifiers, nil
+}
+
+// WorkspaceName returns the name of the workspace directory.
+func (m *RepositoryManager) WorkspaceName() string {
+	path := m.config.Path()
+	if path == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(abs)
+}
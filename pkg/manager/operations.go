@@ -1,19 +1,30 @@
 package manager
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/tierone/harbormaster/pkg/config"
 	"github.com/tierone/harbormaster/pkg/downloader"
+	"github.com/tierone/harbormaster/pkg/errcode"
 	"github.com/tierone/harbormaster/pkg/lockfile"
 	"github.com/tierone/harbormaster/pkg/types"
-	"github.com/tierone/harbormaster/pkg/ui"
 )
 
 // Sync synchronizes all or selected repositories.
 func (m *RepositoryManager) Sync(filter Filter) (*types.SyncResult, error) {
+	return m.SyncContext(context.Background(), filter)
+}
+
+// SyncContext is like Sync but stops starting new operations and cancels
+// running ones when ctx is done. Repositories that were not synced are
+// reported as failed with the context's error.
+func (m *RepositoryManager) SyncContext(ctx context.Context, filter Filter) (*types.SyncResult, error) {
 	startTime := time.Now()
 
 	// Ensure work directory exists
@@ -27,49 +38,162 @@ func (m *RepositoryManager) Sync(filter Filter) (*types.SyncResult, error) {
 		return nil, err
 	}
 
+	repos, skipped := m.skipQuarantined(repos)
+	repos, fresh := m.skipFresh(repos, filter)
 	if len(repos) == 0 {
-		return &types.SyncResult{}, nil
+		return &types.SyncResult{Quarantined: skipped, Fresh: fresh}, nil
 	}
 
-	// Create UI if not provided
-	if m.ui == nil {
-		m.ui = ui.NewProgressManager(m.interactive)
-		if err := m.ui.Start(); err != nil {
-			return nil, fmt.Errorf("failed to start UI: %w", err)
-		}
+	m.logger.Info("sync started", "repositories", len(repos), "concurrency", m.concurrent, "locked", m.locked)
+	if m.ui != nil {
+		m.ui.SetTotal(len(repos))
 	}
 
+	results := m.syncRepositories(ctx, repos)
+	m.retryUnauthorized(ctx, repos, results)
+	m.recordFailures(results)
+
+	// Update lock file
+	m.updateLockFile(results)
+
+	// Sync nested workspaces found in the synced repositories
+	if m.config.General.RecurseWorkspaces {
+		results = append(results, m.syncNestedWorkspaces(ctx, results)...)
+	}
+
+	duration := time.Since(startTime)
+	if m.ui != nil {
+		m.ui.Complete(duration)
+	}
+
+	result := types.NewSyncResult(results, duration)
+	result.Quarantined = skipped
+	result.Fresh = fresh
+	m.logger.Info("sync finished", "succeeded", result.SuccessCount, "failed", result.FailureCount, "duration", duration)
+	return result, nil
+}
+
+// syncRepositories syncs repositories concurrently within the
+// concurrency limit, returning results in the same order. Repositories
+// start in order of priority, highest first, and otherwise in the order
+// given. Remotes shared by several repositories are fetched once, into
+// a shared clone the checkouts fetch from and borrow objects from.
+func (m *RepositoryManager) syncRepositories(ctx context.Context, repos []config.Repository) []types.OperationResult {
+	m.mirrors = sharedRemotes(defaultCacheDir(m.config), m.config.Repositories)
+	defer func() { m.mirrors = nil }()
+
 	// Create semaphore for concurrency control
 	sem := newSemaphore(m.concurrent)
 	var wg sync.WaitGroup
 	results := make([]types.OperationResult, len(repos))
 
-	// Sync repositories concurrently
-	for i, repo := range repos {
+	// Sync repositories concurrently, acquiring each slot before starting
+	// the operation so that they start in schedule order
+	for _, i := range schedule(repos) {
+		r := repos[i]
+		if err := sem.acquire(ctx); err != nil {
+			results[i] = types.OperationResult{RepoName: r.Name, RepoURL: r.URL, Error: err}
+			continue
+		}
 		wg.Add(1)
 		go func(idx int, r config.Repository) {
 			defer wg.Done()
-			sem.acquire()
 			defer sem.release()
 
-			results[idx] = m.syncRepository(&r)
-		}(i, repo)
+			results[idx] = m.syncRepository(ctx, &r)
+			m.logResult(results[idx])
+		}(i, r)
 	}
 
 	// Wait for all operations to complete
 	wg.Wait()
 
-	// Update lock file
-	m.updateLockFile(results)
+	return results
+}
 
-	duration := time.Since(startTime)
-	m.ui.Complete(duration)
+// schedule returns the indexes of repos in the order they are synced:
+// by descending priority, keeping the configured order among equals.
+func schedule(repos []config.Repository) []int {
+	order := make([]int, len(repos))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return repos[order[a]].Priority > repos[order[b]].Priority
+	})
+	return order
+}
+
+// skipFresh separates the repositories synced within their
+// min_sync_interval from those to sync. Repositories named in filter,
+// missing ones, and those whose URL or ref changed since their last sync
+// are always synced, as is everything with WithForce or WithLocked.
+func (m *RepositoryManager) skipFresh(repos []config.Repository, filter Filter) (active []config.Repository, fresh []string) {
+	if m.force || m.locked || m.lockFile == nil {
+		return repos, nil
+	}
+	for _, repo := range repos {
+		interval := repo.GetMinSyncInterval(m.config.General.MinSyncInterval)
+		entry, ok := m.lockFile.Get(repo.Name)
+		if interval <= 0 || !ok || slices.Contains(filter.Names, repo.Name) ||
+			entry.URL != repo.URL || entry.RequestedRef != repo.GetEffectiveRef(m.config.General.DefaultBranch) ||
+			time.Since(entry.LastSyncedAt) >= interval || !downloader.Exists(m.getRepoPath(&repo)) {
+			active = append(active, repo)
+			continue
+		}
+		m.logger.Debug("skipping recently synced repository", "repo", m.namePrefix+repo.Name, "last_synced", entry.LastSyncedAt, "min_sync_interval", interval)
+		fresh = append(fresh, repo.Name)
+	}
+	return active, fresh
+}
+
+// skipQuarantined separates quarantined repositories from those to sync.
+func (m *RepositoryManager) skipQuarantined(repos []config.Repository) (active []config.Repository, skipped []string) {
+	if m.quarantine == nil {
+		return repos, nil
+	}
+	for _, repo := range repos {
+		if m.quarantine.IsQuarantined(repo.Name) {
+			m.logger.Warn("skipping quarantined repository", "repo", m.namePrefix+repo.Name)
+			skipped = append(skipped, repo.Name)
+			continue
+		}
+		active = append(active, repo)
+	}
+	return active, skipped
+}
+
+// recordFailures counts the outcome of a sync in the quarantine, if any.
+func (m *RepositoryManager) recordFailures(results []types.OperationResult) {
+	if m.quarantine == nil {
+		return
+	}
+	for _, name := range m.quarantine.Record(results, m.config.General.QuarantineAfter) {
+		m.logger.Warn("repository quarantined", "repo", m.namePrefix+name, "failures", m.config.General.QuarantineAfter)
+	}
+	if err := m.quarantine.Save(); err != nil {
+		m.logger.Warn("failed to save quarantine", "error", err)
+	}
+}
 
-	return types.NewSyncResult(results, duration), nil
+// logResult records the outcome of a repository operation.
+func (m *RepositoryManager) logResult(r types.OperationResult) {
+	name := m.namePrefix + r.RepoName
+	if !r.Success {
+		m.logger.Warn("repository sync failed", "repo", name, "duration", r.Duration, "error", r.Error)
+		return
+	}
+	m.logger.Info("repository synced", "repo", name, "sha", r.CommitSHA, "duration", r.Duration)
 }
 
 // Status returns the status of all or selected repositories.
 func (m *RepositoryManager) Status(filter Filter) ([]RepoStatus, error) {
+	return m.StatusContext(context.Background(), filter)
+}
+
+// StatusContext is like Status but returns the context's error if ctx is
+// done before all repositories have been inspected.
+func (m *RepositoryManager) StatusContext(ctx context.Context, filter Filter) ([]RepoStatus, error) {
 	repos, err := m.getRepositories(filter)
 	if err != nil {
 		return nil, err
@@ -78,6 +202,9 @@ func (m *RepositoryManager) Status(filter Filter) ([]RepoStatus, error) {
 	statuses := make([]RepoStatus, 0, len(repos))
 
 	for _, repo := range repos {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		status := m.getRepoStatus(&repo)
 		statuses = append(statuses, status)
 	}
@@ -85,6 +212,70 @@ func (m *RepositoryManager) Status(filter Filter) ([]RepoStatus, error) {
 	return statuses, nil
 }
 
+// StatusUpstream is like StatusContext but also resolves each git
+// repository's requested ref on its remote and counts the commits the
+// checkout is ahead and behind, fetching the ref if it is not present
+// locally. A repository then needs updating when it is behind its remote
+// rather than when it differs from the lock file. Missing, vendored, and
+// pinned repositories are not compared.
+func (m *RepositoryManager) StatusUpstream(ctx context.Context, filter Filter) ([]RepoStatus, error) {
+	statuses, err := m.StatusContext(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	sem := newSemaphore(m.concurrent)
+	var wg sync.WaitGroup
+
+	for i := range statuses {
+		repo, ok := m.config.GetRepository(statuses[i].Name)
+		if !ok || !statuses[i].Exists || repo.Type != config.RepoTypeGit || repo.Commit != "" || repo.AsOf != "" || m.config.IsVendored(repo) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(status *RepoStatus, r config.Repository) {
+			defer wg.Done()
+			if err := sem.acquire(ctx); err != nil {
+				status.RemoteError = err
+				return
+			}
+			defer sem.release()
+
+			dl := m.gitDownloader(ctx, &r)
+
+			ref := status.RequestedRef
+			if r.ResolvesRef() {
+				ref, status.RemoteSHA, status.RemoteError = m.resolveRef(ctx, &r)
+			} else {
+				status.RemoteSHA, status.RemoteError = dl.LsRemote(r.URL, ref)
+			}
+			if status.RemoteError == nil {
+				status.Ahead, status.Behind, status.RemoteError = dl.AheadBehind(status.Path, ref, status.RemoteSHA)
+			}
+			if status.RemoteError == nil {
+				status.RemoteChecked = true
+				status.NeedsUpdate = status.Behind > 0
+			}
+			m.logger.Debug("upstream compared",
+				"repo", r.Name,
+				"ref", status.RequestedRef,
+				"remote_sha", status.RemoteSHA,
+				"ahead", status.Ahead,
+				"behind", status.Behind,
+				"error", status.RemoteError,
+			)
+		}(&statuses[i], *repo)
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
 func (m *RepositoryManager) getRepoStatus(repo *config.Repository) RepoStatus {
 	repoPath := m.getRepoPath(repo)
 	requestedRef := repo.GetEffectiveRef(m.config.General.DefaultBranch)
@@ -104,8 +295,10 @@ func (m *RepositoryManager) getRepoStatus(repo *config.Repository) RepoStatus {
 	status.Exists = true
 
 	// Get detailed status based on repository type
-	switch repo.Type {
-	case config.RepoTypeGit:
+	switch {
+	case m.config.IsVendored(repo):
+		m.fillVendoredStatus(repo.Name, repoPath, &status)
+	case repo.Type == config.RepoTypeGit:
 		if sha, err := downloader.GetRemoteURL(repoPath); err == nil {
 			_ = sha // URL check passed
 		}
@@ -127,7 +320,13 @@ func (m *RepositoryManager) getRepoStatus(repo *config.Repository) RepoStatus {
 		if dirty, err := downloader.IsDirty(repoPath); err == nil {
 			status.IsDirty = dirty
 		}
-	case config.RepoTypeHTTP:
+	case repo.Type == config.RepoTypePath:
+		if hash, err := downloader.TreeHash(repoPath); err == nil {
+			status.CurrentSHA = hash
+		} else {
+			status.Error = err
+		}
+	case repo.Type == config.RepoTypeHTTP:
 		// For HTTP, get content hash
 		dl := downloader.NewHTTPDownloader(downloader.Options{})
 		if hash, err := dl.GetCurrentRef(repoPath); err == nil {
@@ -176,6 +375,30 @@ func (m *RepositoryManager) Add(repo config.Repository) error {
 	return nil
 }
 
+// AddAll adds repositories to the configuration as one transaction: if
+// any of them can't be added, or the resulting configuration is invalid,
+// none is added and the error lists every problem.
+func (m *RepositoryManager) AddAll(repos []config.Repository) error {
+	saved := slices.Clone(m.config.Repositories)
+
+	var errs []error
+	for _, repo := range repos {
+		if err := m.Add(repo); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", repo.Name, err))
+		}
+	}
+	if len(errs) == 0 {
+		if err := config.ValidateConfig(m.config); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		m.config.Repositories = saved
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
 // Remove removes a repository from the configuration.
 func (m *RepositoryManager) Remove(name string) error {
 	// Remove from config
@@ -201,7 +424,7 @@ func (m *RepositoryManager) AddProject(proj config.Project) error {
 	// Validate that all referenced repos exist
 	for _, repoName := range proj.Repositories {
 		if _, ok := m.config.GetRepository(repoName); !ok {
-			return fmt.Errorf("repository not found: %s", repoName)
+			return errcode.Wrap(errcode.UnknownRepository, fmt.Errorf("repository not found: %s", repoName))
 		}
 	}
 
@@ -225,9 +448,14 @@ func (m *RepositoryManager) RemoveRepoFromProject(projectName, repoName string)
 
 // SyncOne syncs a single repository by name.
 func (m *RepositoryManager) SyncOne(name string) (*types.OperationResult, error) {
+	return m.SyncOneContext(context.Background(), name)
+}
+
+// SyncOneContext is like SyncOne but cancels the operation when ctx is done.
+func (m *RepositoryManager) SyncOneContext(ctx context.Context, name string) (*types.OperationResult, error) {
 	repo, ok := m.config.GetRepository(name)
 	if !ok {
-		return nil, fmt.Errorf("repository not found: %s", name)
+		return nil, errcode.Wrap(errcode.UnknownRepository, fmt.Errorf("repository not found: %s", name))
 	}
 
 	// Ensure work directory exists
@@ -235,7 +463,7 @@ func (m *RepositoryManager) SyncOne(name string) (*types.OperationResult, error)
 		return nil, fmt.Errorf("failed to create work directory: %w", err)
 	}
 
-	result := m.syncRepository(repo)
+	result := m.syncRepository(ctx, repo)
 
 	// Update lock file
 	if result.Success && m.lockFile != nil && !m.locked {
@@ -0,0 +1,31 @@
+package manager
+
+import (
+	"fmt"
+
+	"github.com/tierone/harbormaster/pkg/downloader"
+	"github.com/tierone/harbormaster/pkg/overlay"
+)
+
+// BuildOverlay regenerates the symlink overlay from the synced repositories.
+// Repositories that have not been synced yet are skipped.
+func (m *RepositoryManager) BuildOverlay() (*overlay.Result, error) {
+	if !m.config.Overlay.Enabled() {
+		return nil, fmt.Errorf("no overlay target configured")
+	}
+
+	var sources []overlay.Source
+	for i := range m.config.Repositories {
+		repo := &m.config.Repositories[i]
+		if !m.config.Overlay.Includes(repo.Name) {
+			continue
+		}
+		repoPath := m.getRepoPath(repo)
+		if !downloader.Exists(repoPath) {
+			continue
+		}
+		sources = append(sources, overlay.Source{Name: repo.Name, Path: repoPath})
+	}
+
+	return overlay.Build(m.config.Overlay.Target, sources)
+}
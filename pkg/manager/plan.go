@@ -0,0 +1,190 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/errcode"
+)
+
+// SyncAction is what a sync would do to a repository.
+type SyncAction string
+
+const (
+	ActionNone   SyncAction = "none"   // Already at the target
+	ActionClone  SyncAction = "clone"  // Not present locally
+	ActionUpdate SyncAction = "update" // Checkout would move
+	ActionRelock SyncAction = "relock" // Only the lock file would change
+)
+
+// PlannedSync describes what a sync would do to one repository.
+type PlannedSync struct {
+	Name       string
+	Ref        string
+	Action     SyncAction
+	Reason     string
+	CurrentSHA string
+	TargetSHA  string // Commit the sync would check out, if known
+	Error      error  // Set if the target could not be resolved
+}
+
+// Changes reports whether the sync would change anything.
+func (p PlannedSync) Changes() bool {
+	return p.Action != ActionNone && p.Action != ""
+}
+
+// Plan resolves what a sync with the manager's settings would do to each
+// repository without changing any working tree. Git refs are resolved with
+// git ls-remote and nothing is fetched. HTTP sources cannot be compared
+// without downloading them and are checked against the lock file only.
+func (m *RepositoryManager) Plan(ctx context.Context, filter Filter) ([]PlannedSync, error) {
+	statuses, err := m.StatusContext(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	sem := newSemaphore(m.concurrent)
+	var wg sync.WaitGroup
+	plans := make([]PlannedSync, len(statuses))
+
+	for i, status := range statuses {
+		repo, ok := m.config.GetRepository(status.Name)
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(idx int, s RepoStatus, r config.Repository) {
+			defer wg.Done()
+			if err := sem.acquire(ctx); err != nil {
+				plans[idx] = PlannedSync{Name: s.Name, Ref: s.RequestedRef, Error: err}
+				return
+			}
+			defer sem.release()
+
+			plans[idx] = m.planRepository(ctx, s, &r)
+			m.logger.Debug("sync planned",
+				"repo", r.Name,
+				"action", plans[idx].Action,
+				"reason", plans[idx].Reason,
+				"target_sha", plans[idx].TargetSHA,
+				"error", plans[idx].Error,
+			)
+		}(i, status, *repo)
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
+// planRepository decides what syncing one repository would do.
+func (m *RepositoryManager) planRepository(ctx context.Context, s RepoStatus, repo *config.Repository) PlannedSync {
+	plan := PlannedSync{
+		Name:       s.Name,
+		Ref:        s.RequestedRef,
+		CurrentSHA: s.CurrentSHA,
+	}
+	if s.Error != nil {
+		plan.Error = s.Error
+		return plan
+	}
+
+	locked := ""
+	if m.lockFile != nil {
+		locked, _ = m.lockFile.GetResolvedSHA(repo.Name)
+	}
+	if m.locked && locked == "" {
+		plan.Error = errcode.Wrap(errcode.LockMissing, fmt.Errorf("no lock entry for repository (run sync without --locked first)"))
+		return plan
+	}
+
+	// Resolve the commit the sync would end up at
+	switch {
+	case m.locked:
+		plan.TargetSHA = locked
+	case repo.Type != config.RepoTypeGit || repo.AsOf != "":
+		// Finding the commit before a date needs the history
+		plan.TargetSHA = locked
+	case repo.Commit != "":
+		plan.TargetSHA = repo.Commit
+	case repo.ResolvesRef():
+		_, plan.TargetSHA, plan.Error = m.resolveRef(ctx, repo)
+		if plan.Error != nil {
+			return plan
+		}
+	default:
+		plan.TargetSHA, plan.Error = m.gitDownloader(ctx, repo).LsRemote(repo.URL, m.remoteRef(repo))
+		if plan.Error != nil {
+			return plan
+		}
+	}
+
+	switch {
+	case !s.Exists:
+		plan.Action = ActionClone
+		plan.Reason = "not present"
+	case m.config.IsVendored(repo) && s.IsDirty:
+		plan.Action = ActionUpdate
+		plan.Reason = "vendored content differs from the lock file"
+	case plan.TargetSHA == "":
+		plan.Action = ActionUpdate
+		plan.Reason = "no lock entry"
+	case !sameCommit(s.CurrentSHA, plan.TargetSHA):
+		plan.Action = ActionUpdate
+		plan.Reason = fmt.Sprintf("at %s, %s is at %s", shortSHA(s.CurrentSHA), m.targetName(repo), shortSHA(plan.TargetSHA))
+	case !m.locked && !sameCommit(locked, plan.TargetSHA):
+		plan.Action = ActionRelock
+		if locked == "" {
+			plan.Reason = "no lock entry"
+		} else {
+			plan.Reason = fmt.Sprintf("lock file records %s", shortSHA(locked))
+		}
+	default:
+		plan.Action = ActionNone
+		plan.Reason = "up to date"
+	}
+	return plan
+}
+
+// targetName describes where the target commit of a repository comes
+// from.
+func (m *RepositoryManager) targetName(repo *config.Repository) string {
+	switch {
+	case m.locked || repo.Type != config.RepoTypeGit || repo.AsOf != "":
+		return "lock file"
+	case repo.Commit != "":
+		return "pinned commit"
+	case repo.Version != "":
+		return "version " + repo.Version
+	case repo.Ref != "":
+		return repo.Ref
+	case repo.BranchPattern():
+		return "newest branch matching " + repo.Branch
+	default:
+		return "remote " + m.remoteRef(repo)
+	}
+}
+
+// sameCommit reports whether two SHAs name the same commit, allowing
+// either to be abbreviated.
+func sameCommit(a, b string) bool {
+	if a == "" || b == "" {
+		return a == b
+	}
+	return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
+}
+
+// shortSHA abbreviates a SHA for display.
+func shortSHA(sha string) string {
+	if sha == "" {
+		return "-"
+	}
+	return sha[:min(8, len(sha))]
+}
@@ -0,0 +1,62 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/errcode"
+)
+
+// checkCommitPolicy enforces the blocked_commits and allowed_committers
+// of repo on commit sha checked out at repoPath. previous is the commit
+// locked before, if any: commits since it are checked against
+// allowed_committers, or only sha if it is not available locally. A
+// blocked commit counts when it is sha or in its history as far as the
+// checkout has it.
+func (m *RepositoryManager) checkCommitPolicy(ctx context.Context, repo *config.Repository, repoPath, previous, sha string) error {
+	if len(repo.BlockedCommits) == 0 && len(repo.AllowedCommitters) == 0 {
+		return nil
+	}
+	dl := m.gitDownloader(ctx, repo)
+
+	for _, blocked := range repo.BlockedCommits {
+		if strings.HasPrefix(sha, strings.ToLower(blocked)) {
+			return errcode.Wrap(errcode.PolicyViolated, fmt.Errorf("commit %s is blocked", shortSHA(sha)))
+		}
+		if full, err := dl.ResolveLocal(repoPath, blocked); err == nil && dl.IsAncestor(repoPath, full, sha) {
+			return errcode.Wrap(errcode.PolicyViolated, fmt.Errorf("blocked commit %s is in the history of %s", shortSHA(full), shortSHA(sha)))
+		}
+	}
+
+	if len(repo.AllowedCommitters) == 0 {
+		return nil
+	}
+	if previous != "" && !dl.HasCommit(repoPath, previous) {
+		previous = ""
+	}
+	committers, err := dl.Committers(repoPath, previous, sha)
+	if err != nil {
+		return err
+	}
+	for _, c := range committers {
+		if !committerAllowed(repo.AllowedCommitters, c.Email) {
+			return errcode.Wrap(errcode.PolicyViolated, fmt.Errorf("commit %s was committed by %s <%s>, who is not in allowed_committers", shortSHA(c.SHA), c.Name, c.Email))
+		}
+	}
+	return nil
+}
+
+// committerAllowed reports whether email matches one of patterns, which
+// are emails or glob patterns such as *@example.com, ignoring case.
+func committerAllowed(patterns []string, email string) bool {
+	email = strings.ToLower(email)
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToLower(p), email); ok {
+			return true
+		}
+	}
+	return false
+}
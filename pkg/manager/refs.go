@@ -0,0 +1,198 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/downloader"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/provider"
+	"github.com/tierone/harbormaster/pkg/semver"
+)
+
+// providerSource finds the hosting service API serving a repository URL
+// or host. *provider.Clients implements it.
+type providerSource interface {
+	ForURL(repoURL string) (provider.API, string, error)
+	ForHost(host string) (provider.API, error)
+}
+
+// resolveRef returns the tag or branch that a repository whose ref is
+// resolved at sync time (see config.Repository.ResolvesRef) checks out,
+// with the commit it points to.
+func (m *RepositoryManager) resolveRef(ctx context.Context, repo *config.Repository) (ref, sha string, err error) {
+	dl := m.gitDownloader(ctx, repo)
+	switch {
+	case repo.Version != "":
+		return resolveVersion(dl, repo)
+	case repo.BranchPattern():
+		return resolveBranchPattern(dl, repo)
+	default:
+		return m.resolveLatestRelease(ctx, dl, repo)
+	}
+}
+
+// resolveVersion lists the tags on the remote of repo and returns the
+// highest one satisfying its version constraint, with the commit it
+// points to.
+func resolveVersion(dl *downloader.GitDownloader, repo *config.Repository) (tag, sha string, err error) {
+	constraint, err := semver.ParseConstraint(repo.Version)
+	if err != nil {
+		return "", "", errcode.Wrap(errcode.ConfigInvalid, err)
+	}
+
+	tags, err := dl.RemoteTags(repo.URL)
+	if err != nil {
+		return "", "", err
+	}
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+
+	tag, ok := constraint.Highest(names)
+	if !ok {
+		return "", "", errcode.Wrap(errcode.RefNotFound, fmt.Errorf("no tag on the remote satisfies version %s", repo.Version))
+	}
+	return tag, tags[tag], nil
+}
+
+// resolveLatestRelease asks the hosting service of repo for its latest
+// release and looks up the commit of the release's tag on the remote.
+func (m *RepositoryManager) resolveLatestRelease(ctx context.Context, dl *downloader.GitDownloader, repo *config.Repository) (tag, sha string, err error) {
+	api, path, err := m.providers.ForURL(repo.URL)
+	if err != nil {
+		return "", "", errcode.Wrap(errcode.ConfigInvalid, fmt.Errorf("cannot look up releases: %w", err))
+	}
+	release, err := api.LatestRelease(ctx, path, repo.Prereleases)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get latest release: %w", err)
+	}
+
+	sha, err = dl.LsRemote(repo.URL, release.Tag)
+	if err != nil {
+		return "", "", err
+	}
+	return release.Tag, sha, nil
+}
+
+// resolveBranchPattern lists the branches on the remote of repo and
+// returns the newest one matching its branch pattern, with the commit it
+// points to. Branches are compared by the version numbers in their names,
+// or with branch_sort = "date" by the committer date of their tips.
+func resolveBranchPattern(dl *downloader.GitDownloader, repo *config.Repository) (branch, sha string, err error) {
+	branches, err := dl.RemoteBranches(repo.URL)
+	if err != nil {
+		return "", "", err
+	}
+	var matches []string
+	for name := range branches {
+		if ok, _ := path.Match(repo.Branch, name); ok {
+			matches = append(matches, name)
+		}
+	}
+	if len(matches) == 0 {
+		return "", "", errcode.Wrap(errcode.RefNotFound, fmt.Errorf("no branch on the remote matches %s", repo.Branch))
+	}
+
+	newer := func(a, b string) bool { return compareVersionNames(a, b) > 0 }
+	if repo.BranchSort == config.BranchSortDate {
+		dates, err := dl.BranchDates(repo.URL, matches)
+		if err != nil {
+			return "", "", err
+		}
+		newer = func(a, b string) bool {
+			if !dates[a].Equal(dates[b]) {
+				return dates[a].After(dates[b])
+			}
+			return compareVersionNames(a, b) > 0
+		}
+	}
+
+	branch = matches[0]
+	for _, name := range matches[1:] {
+		if newer(name, branch) {
+			branch = name
+		}
+	}
+	return branch, branches[branch], nil
+}
+
+// compareVersionNames compares two names the way version sort does: runs
+// of digits compare numerically, everything else byte by byte, so that
+// "release/2024.10" sorts after "release/2024.9".
+func compareVersionNames(a, b string) int {
+	for a != "" && b != "" {
+		ra, restA := nextRun(a)
+		rb, restB := nextRun(b)
+		na, errA := strconv.ParseUint(ra, 10, 64)
+		nb, errB := strconv.ParseUint(rb, 10, 64)
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+		default:
+			if c := strings.Compare(ra, rb); c != 0 {
+				return c
+			}
+		}
+		a, b = restA, restB
+	}
+	return strings.Compare(a, b)
+}
+
+// nextRun splits s after its leading run of digits or non-digits.
+func nextRun(s string) (run, rest string) {
+	digit := isDigit(s[0])
+	i := 1
+	for i < len(s) && isDigit(s[i]) == digit {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// lockedRef returns the tag or branch the lock file records for a
+// repository whose ref is resolved at sync time, or "" if there is none.
+func (m *RepositoryManager) lockedRef(repo *config.Repository) string {
+	if m.lockFile == nil {
+		return ""
+	}
+	entry, ok := m.lockFile.Get(repo.Name)
+	if !ok || entry.RequestedRef != repo.GetEffectiveRef(m.config.General.DefaultBranch) {
+		return ""
+	}
+	return entry.ResolvedRef
+}
+
+// remoteRef returns the ref a sync checks out from the remote. Without a
+// branch or tag a clone follows the remote's HEAD. A ref resolved at sync
+// time is the one last locked, if any.
+func (m *RepositoryManager) remoteRef(repo *config.Repository) string {
+	if repo.ResolvesRef() {
+		if ref := m.lockedRef(repo); ref != "" {
+			return ref
+		}
+	}
+	switch {
+	case repo.CustomRef():
+		return repo.Ref
+	case repo.Tag != "":
+		return repo.Tag
+	case repo.Branch != "" && !repo.BranchPattern():
+		return repo.Branch
+	default:
+		return "HEAD"
+	}
+}
@@ -0,0 +1,115 @@
+package manager
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/downloader"
+)
+
+// ResolvedRef is what the configured ref of a repository currently
+// resolves to.
+type ResolvedRef struct {
+	Name       string
+	Ref        string // Branch, tag, or HEAD on the remote; the commit if pinned; as configured if resolved at sync time
+	Target     string // Tag or branch chosen for a version constraint, latest-release, or branch pattern
+	SHA        string // Commit the ref resolves to
+	Pinned     bool   // A commit is configured and was not looked up
+	CurrentSHA string // Commit checked out, empty if not cloned
+	LockedSHA  string // Commit in the lock file, empty if not locked
+	Error      error
+}
+
+// Changed reports whether a sync would move the checkout to a different
+// commit.
+func (r ResolvedRef) Changed() bool {
+	return r.Error == nil && !sameCommit(r.CurrentSHA, r.SHA)
+}
+
+// Resolve looks up the commit each selected git repository's branch or
+// tag points at on its remote with git ls-remote, without cloning,
+// fetching, or touching any working tree. Repositories pinned to a commit
+// resolve to that commit; those with a version constraint resolve to the
+// highest satisfying tag, latest-release refs to the tag of the latest
+// release, and branch patterns to the newest matching branch. Finding the
+// commit before an as_of date needs the history, so those repositories
+// resolve to their locked commit. Other repository types are skipped.
+func (m *RepositoryManager) Resolve(ctx context.Context, filter Filter) ([]ResolvedRef, error) {
+	repos, err := m.getRepositories(filter)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []config.Repository
+	for _, repo := range repos {
+		if repo.Type == config.RepoTypeGit {
+			candidates = append(candidates, repo)
+		}
+	}
+
+	sem := newSemaphore(m.concurrent)
+	var wg sync.WaitGroup
+	results := make([]ResolvedRef, len(candidates))
+
+	for i, repo := range candidates {
+		wg.Add(1)
+		go func(idx int, r config.Repository) {
+			defer wg.Done()
+			results[idx] = m.newResolvedRef(&r)
+			if results[idx].Pinned {
+				return
+			}
+			if r.AsOf != "" {
+				results[idx].SHA = results[idx].LockedSHA
+				return
+			}
+			if err := sem.acquire(ctx); err != nil {
+				results[idx].Error = err
+				return
+			}
+			defer sem.release()
+
+			if r.ResolvesRef() {
+				results[idx].Target, results[idx].SHA, results[idx].Error = m.resolveRef(ctx, &r)
+			} else {
+				results[idx].SHA, results[idx].Error = m.gitDownloader(ctx, &r).LsRemote(r.URL, results[idx].Ref)
+			}
+			m.logger.Debug("ref resolved",
+				"repo", r.Name,
+				"ref", results[idx].Ref,
+				"target", results[idx].Target,
+				"sha", results[idx].SHA,
+				"error", results[idx].Error,
+			)
+		}(i, repo)
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// newResolvedRef fills in what is known about a repository locally.
+func (m *RepositoryManager) newResolvedRef(repo *config.Repository) ResolvedRef {
+	r := ResolvedRef{Name: repo.Name, Ref: m.remoteRef(repo)}
+	if repo.ResolvesRef() {
+		r.Ref = repo.GetEffectiveRef(m.config.General.DefaultBranch)
+	}
+	if repo.Commit != "" {
+		r.Ref = repo.Commit
+		r.SHA = repo.Commit
+		r.Pinned = true
+	}
+
+	repoPath := m.getRepoPath(repo)
+	if downloader.IsGitRepository(repoPath) {
+		r.CurrentSHA, _ = downloader.NewGitDownloader(downloader.Options{}).GetCurrentRef(repoPath)
+	}
+	if m.lockFile != nil {
+		r.LockedSHA, _ = m.lockFile.GetResolvedSHA(repo.Name)
+	}
+	return r
+}
@@ -0,0 +1,70 @@
+package manager
+
+import (
+	"context"
+	"sort"
+
+	"github.com/tierone/harbormaster/pkg/config"
+)
+
+// Rewrite is a branch found rewritten upstream by the sync that wrote a
+// repository's lock entry.
+type Rewrite struct {
+	Name   string
+	Branch string
+	From   string // Previously locked commit, no longer in the branch's history
+	To     string // Commit locked instead
+}
+
+// historyRewritten reports whether the branch repo tracks was rewritten
+// upstream, such as by a force-push, since the previous sync: previous,
+// the commit locked then, is not in the history of sha, the commit just
+// synced into the checkout at repoPath. Branch is the branch a pattern
+// resolved to. Repositories on tags or commits, and syncs after the
+// requested ref changed, are never rewrites; neither are shallow
+// checkouts missing previous, whose history is unknown.
+func (m *RepositoryManager) historyRewritten(ctx context.Context, repo *config.Repository, repoPath, branch, previous, sha string) bool {
+	if previous == "" || previous == sha || m.lockFile == nil || !repo.TracksBranch() {
+		return false
+	}
+	entry, ok := m.lockFile.Get(repo.Name)
+	if !ok || entry.RequestedRef != repo.GetEffectiveRef(m.config.General.DefaultBranch) {
+		return false
+	}
+	if repo.BranchPattern() && entry.ResolvedRef != branch {
+		return false
+	}
+
+	dl := m.gitDownloader(ctx, repo)
+	if dl.HasCommit(repoPath, previous) {
+		return !dl.IsAncestor(repoPath, previous, sha)
+	}
+	// Fetches keep the objects of rewritten commits, so a full clone
+	// missing previous was cloned again after the rewrite
+	shallow, err := dl.IsShallow(repoPath)
+	return err == nil && !shallow
+}
+
+// Rewrites returns the selected repositories whose lock entry records
+// that their branch was rewritten upstream, sorted by name.
+func (m *RepositoryManager) Rewrites(filter Filter) ([]Rewrite, error) {
+	repos, err := m.getRepositories(filter)
+	if err != nil || m.lockFile == nil {
+		return nil, err
+	}
+
+	var rewrites []Rewrite
+	for _, repo := range repos {
+		entry, ok := m.lockFile.Get(repo.Name)
+		if !ok || entry.RewrittenFrom == "" {
+			continue
+		}
+		branch := entry.RequestedRef
+		if entry.ResolvedRef != "" {
+			branch = entry.ResolvedRef
+		}
+		rewrites = append(rewrites, Rewrite{Name: repo.Name, Branch: branch, From: entry.RewrittenFrom, To: entry.ResolvedSHA})
+	}
+	sort.Slice(rewrites, func(i, j int) bool { return rewrites[i].Name < rewrites[j].Name })
+	return rewrites, nil
+}
@@ -0,0 +1,202 @@
+package manager
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/downloader"
+	"github.com/tierone/harbormaster/pkg/lockfile"
+)
+
+// Checkout is an existing git clone to adopt as a repository.
+type Checkout struct {
+	Path   string // Relative to the work directory
+	URL    string // Configured origin URL
+	Branch string // Checked out branch; empty if HEAD is detached
+	Tag    string // Tag at HEAD when it is detached, if any
+	SHA    string // Checked out commit
+}
+
+// Repository returns the repository definition tracking the checkout
+// as it is: its branch, the tag it is detached at, or else its commit.
+func (c Checkout) Repository(name string) config.Repository {
+	repo := config.Repository{
+		Name:   name,
+		URL:    c.URL,
+		Type:   config.RepoTypeGit,
+		Path:   c.Path,
+		Branch: c.Branch,
+	}
+	if c.Branch == "" {
+		if c.Tag != "" {
+			repo.Tag = c.Tag
+		} else {
+			repo.Commit = c.SHA
+		}
+	}
+	return repo
+}
+
+// InspectCheckout reads the origin, checked out ref, and commit of the
+// git clone at path, relative to the work directory or absolute.
+func (m *RepositoryManager) InspectCheckout(path string) (Checkout, error) {
+	workDir, err := filepath.Abs(m.workDir)
+	if err != nil {
+		return Checkout{}, err
+	}
+	abs := path
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(workDir, path)
+	}
+	rel, err := filepath.Rel(workDir, abs)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return Checkout{}, fmt.Errorf("%s is not inside the work directory %s", path, workDir)
+	}
+	if !downloader.IsGitRepository(abs) {
+		return Checkout{}, fmt.Errorf("%s is not a git clone", path)
+	}
+
+	c := Checkout{Path: filepath.ToSlash(rel)}
+	if c.URL, err = downloader.ConfiguredRemoteURL(abs); err != nil {
+		return Checkout{}, fmt.Errorf("%s has no origin remote", path)
+	}
+	dl := downloader.NewGitDownloader(downloader.Options{})
+	if c.SHA, err = dl.GetCurrentRef(abs); err != nil {
+		return Checkout{}, fmt.Errorf("%s has no commit checked out: %w", path, err)
+	}
+	if c.Branch, err = downloader.GetCurrentBranch(abs); err != nil {
+		return Checkout{}, err
+	}
+	if c.Branch == "" {
+		tags, err := dl.TagsAt(abs)
+		if err != nil {
+			return Checkout{}, err
+		}
+		if len(tags) > 0 {
+			c.Tag = tags[0]
+		}
+	}
+	return c, nil
+}
+
+// InspectUnconfigured inspects the git clone at path like InspectCheckout,
+// and checks that no repository is configured at it and, if url isn't
+// empty, that its origin is url.
+func (m *RepositoryManager) InspectUnconfigured(path, url string) (Checkout, error) {
+	c, err := m.InspectCheckout(path)
+	if err != nil {
+		return Checkout{}, err
+	}
+	if url != "" && !sameRemote(url, c.URL) {
+		return Checkout{}, fmt.Errorf("origin of %s is %s, not %s", path, c.URL, url)
+	}
+	abs := filepath.Join(m.workDir, filepath.FromSlash(c.Path))
+	for _, repo := range m.config.Repositories {
+		if filepath.Clean(m.getRepoPath(&repo)) == filepath.Clean(abs) {
+			return Checkout{}, fmt.Errorf("%s is already the checkout of %s", path, repo.Name)
+		}
+	}
+	return c, nil
+}
+
+// sameRemote reports whether two remote URLs name the same repository,
+// ignoring a trailing slash or ".git".
+func sameRemote(a, b string) bool {
+	trim := func(url string) string {
+		return strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+	}
+	return trim(a) == trim(b)
+}
+
+// ScanCheckouts walks dir, inside the work directory, for git clones
+// that no repository is configured at. It doesn't descend into clones or
+// hidden directories. Clones that can't be adopted, such as those
+// without an origin, are returned as skipped with the reason.
+func (m *RepositoryManager) ScanCheckouts(dir string) (found []Checkout, skipped []string, err error) {
+	configured := make(map[string]bool)
+	for _, repo := range m.config.Repositories {
+		if abs, err := filepath.Abs(m.getRepoPath(&repo)); err == nil {
+			configured[abs] = true
+		}
+	}
+	if dir, err = filepath.Abs(dir); err != nil {
+		return nil, nil, err
+	}
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(path, ".git")); err != nil {
+			return nil
+		}
+		if configured[filepath.Clean(path)] {
+			return filepath.SkipDir
+		}
+		c, err := m.InspectCheckout(path)
+		if err != nil {
+			skipped = append(skipped, err.Error())
+		} else {
+			found = append(found, c)
+		}
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+	return found, skipped, nil
+}
+
+// CheckoutNames returns a repository name for each checkout: the base
+// name of its path, or the whole path joined with dashes when that is
+// taken, by a configured repository or another checkout.
+func (m *RepositoryManager) CheckoutNames(checkouts []Checkout) []string {
+	taken := make(map[string]bool)
+	for _, repo := range m.config.Repositories {
+		taken[repo.Name] = true
+	}
+	names := make([]string, len(checkouts))
+	for i, c := range checkouts {
+		name := filepath.Base(filepath.FromSlash(c.Path))
+		if taken[name] {
+			name = strings.ReplaceAll(c.Path, "/", "-")
+		}
+		taken[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+// Adopt adds the checkouts as repositories with the given names, as one
+// transaction, and records their checked out commits in the lock file,
+// so that they are synced in place instead of being cloned again.
+func (m *RepositoryManager) Adopt(checkouts []Checkout, names []string, tags []string) ([]config.Repository, error) {
+	repos := make([]config.Repository, len(checkouts))
+	for i, c := range checkouts {
+		repos[i] = c.Repository(names[i])
+		repos[i].Tags = tags
+	}
+	if err := m.AddAll(repos); err != nil {
+		return nil, err
+	}
+
+	for i, c := range checkouts {
+		repo, _ := m.config.GetRepository(names[i])
+		repos[i] = *repo
+		if m.lockFile != nil {
+			ref := repo.GetEffectiveRef(m.config.General.DefaultBranch)
+			m.lockFile.Update(repo.Name, lockfile.NewEntry(repo.URL, string(repo.Type), ref, c.SHA))
+		}
+	}
+	return repos, nil
+}
@@ -0,0 +1,145 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/provider"
+)
+
+// SourceRefresh is the outcome of refreshing the listing of a source.
+type SourceRefresh struct {
+	Source  string
+	Added   []string // Repositories the source now provides
+	Removed []string // Repositories the source no longer provides
+	Error   error
+}
+
+// RefreshSources lists the repositories of the named sources, or of all
+// sources without names, on their hosts, replaces their cached listings,
+// and merges the repositories into the config. Unless force is set, only
+// sources whose listing is older than their refresh interval are
+// refreshed. A source that fails keeps its previous listing.
+func (m *RepositoryManager) RefreshSources(ctx context.Context, names []string, force bool) ([]SourceRefresh, error) {
+	for _, name := range names {
+		if _, ok := m.config.GetSource(name); !ok {
+			return nil, fmt.Errorf("source not found: %s", name)
+		}
+	}
+	path := m.config.SourcesPath()
+	listings, err := config.LoadSourceListings(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var refreshed []SourceRefresh
+	for _, src := range m.config.Sources {
+		if len(names) > 0 && !slices.Contains(names, src.Name) {
+			continue
+		}
+		if !force && !sourceStale(&src, listings[src.Name]) {
+			continue
+		}
+		m.logger.Debug("refreshing source", "source", src.Name, "org", src.Org, "host", src.Host)
+		listing, err := m.listSource(ctx, &src)
+		if err != nil {
+			m.logger.Warn("failed to refresh source", "source", src.Name, "error", err)
+			refreshed = append(refreshed, SourceRefresh{Source: src.Name, Error: err})
+			continue
+		}
+		listings[src.Name] = listing
+		refreshed = append(refreshed, SourceRefresh{Source: src.Name})
+	}
+	if len(refreshed) == 0 {
+		return nil, nil
+	}
+
+	// Drop the listings of sources no longer configured
+	for name := range listings {
+		if _, ok := m.config.GetSource(name); !ok {
+			delete(listings, name)
+		}
+	}
+	if err := config.SaveSourceListings(path, listings); err != nil {
+		return nil, err
+	}
+
+	before := m.sourceRepositories()
+	if err := m.config.ReloadSources(); err != nil {
+		return nil, err
+	}
+	after := m.sourceRepositories()
+	for i := range refreshed {
+		r := &refreshed[i]
+		for _, name := range after[r.Source] {
+			if !slices.Contains(before[r.Source], name) {
+				r.Added = append(r.Added, name)
+			}
+		}
+		for _, name := range before[r.Source] {
+			if !slices.Contains(after[r.Source], name) {
+				r.Removed = append(r.Removed, name)
+			}
+		}
+	}
+	return refreshed, nil
+}
+
+// sourceStale returns true if the listing of src is older than its
+// refresh interval, or missing.
+func sourceStale(src *config.SourceConfig, listing config.SourceListing) bool {
+	if listing.Refreshed.IsZero() {
+		return true
+	}
+	interval := src.RefreshInterval()
+	return interval > 0 && time.Since(listing.Refreshed) >= interval
+}
+
+// listSource lists the repositories of src on its host, with the clone
+// URLs for the host's protocol.
+func (m *RepositoryManager) listSource(ctx context.Context, src *config.SourceConfig) (config.SourceListing, error) {
+	api, err := m.providers.ForHost(src.Host)
+	if err != nil {
+		return config.SourceListing{}, err
+	}
+	if api.Provider() != src.Provider() {
+		return config.SourceListing{}, fmt.Errorf("%s source needs a %s host, but %s is %s", src.Type, src.Provider(), src.Host, api.Provider())
+	}
+	lister, ok := api.(provider.RepositoryLister)
+	if !ok {
+		return config.SourceListing{}, fmt.Errorf("%s does not support listing repositories", src.Host)
+	}
+	remote, err := lister.ListRepositories(ctx, src.Org)
+	if err != nil {
+		return config.SourceListing{}, fmt.Errorf("failed to list repositories of %s: %w", src.Org, err)
+	}
+
+	ssh := false
+	if hc, ok := m.config.Host(src.Host); ok {
+		ssh = hc.Protocol == config.ProtocolSSH
+	}
+	listing := config.SourceListing{Source: src.Name, Refreshed: time.Now().UTC()}
+	for _, r := range remote {
+		url := r.CloneURL
+		if ssh && r.SSHURL != "" {
+			url = r.SSHURL
+		}
+		listing.Repositories = append(listing.Repositories, config.SourceRepository{Name: r.Name, URL: url, Archived: r.Archived})
+	}
+	return listing, nil
+}
+
+// sourceRepositories returns the names of the repositories merged from
+// each source.
+func (m *RepositoryManager) sourceRepositories() map[string][]string {
+	repos := make(map[string][]string)
+	for _, repo := range m.config.Repositories {
+		if src := m.config.SourceOf(repo.Name); src != "" {
+			repos[src] = append(repos[src], repo.Name)
+		}
+	}
+	return repos
+}
@@ -0,0 +1,200 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/downloader"
+)
+
+// stashPrefix marks the stash entries that belong to a stash set; the set
+// name follows it in the stash message.
+const stashPrefix = "harbormaster:"
+
+// StashSet is a named group of stash entries pushed together across
+// repositories.
+type StashSet struct {
+	Name    string
+	Created time.Time // When the newest entry was stashed
+	Repos   []string
+}
+
+// StashResult is the outcome of pushing or popping a stash set in one
+// repository.
+type StashResult struct {
+	Name  string
+	Set   string
+	Error error
+}
+
+// StashPush stashes the uncommitted changes, including untracked files,
+// of every selected git repository that has any, as one set named set.
+// Clean, missing, and non-git repositories are skipped. It fails if a set
+// of that name already exists in any selected repository.
+func (m *RepositoryManager) StashPush(ctx context.Context, filter Filter, set string) ([]StashResult, error) {
+	if set == "" || strings.ContainsAny(set, "\n\r") {
+		return nil, fmt.Errorf("invalid stash set name: %q", set)
+	}
+
+	repos, err := m.gitRepositories(filter)
+	if err != nil {
+		return nil, err
+	}
+	sets, err := m.StashSets(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sets {
+		if s.Name == set {
+			return nil, fmt.Errorf("stash set already exists: %s", set)
+		}
+	}
+
+	var dirty []config.Repository
+	for _, repo := range repos {
+		isDirty, err := downloader.IsDirty(m.getRepoPath(&repo))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", repo.Name, err)
+		}
+		if isDirty {
+			dirty = append(dirty, repo)
+		}
+	}
+
+	return m.forEachStash(ctx, dirty, set, func(dl *downloader.GitDownloader, repoPath string) error {
+		return dl.StashPush(repoPath, stashPrefix+set)
+	}), nil
+}
+
+// StashPop restores the stash set named set in the selected repositories
+// that have it and drops it. An empty name selects the most recent set.
+// If restoring conflicts in a repository, its entry is kept.
+func (m *RepositoryManager) StashPop(ctx context.Context, filter Filter, set string) ([]StashResult, error) {
+	sets, err := m.StashSets(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(sets) == 0 {
+		return nil, fmt.Errorf("no stash sets")
+	}
+
+	var found *StashSet
+	if set == "" {
+		found = &sets[0]
+	}
+	for i := range sets {
+		if sets[i].Name == set {
+			found = &sets[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("stash set not found: %s", set)
+	}
+
+	candidates, err := m.gitRepositories(filter)
+	if err != nil {
+		return nil, err
+	}
+	var repos []config.Repository
+	for _, repo := range candidates {
+		if slices.Contains(found.Repos, repo.Name) {
+			repos = append(repos, repo)
+		}
+	}
+
+	return m.forEachStash(ctx, repos, found.Name, func(dl *downloader.GitDownloader, repoPath string) error {
+		stashes, err := dl.Stashes(repoPath)
+		if err != nil {
+			return err
+		}
+		for _, s := range stashes {
+			if s.Message == stashPrefix+found.Name {
+				return dl.StashPop(repoPath, s.Ref)
+			}
+		}
+		return fmt.Errorf("stash set not found: %s", found.Name)
+	}), nil
+}
+
+// StashSets lists the stash sets in the selected repositories, most
+// recent first.
+func (m *RepositoryManager) StashSets(ctx context.Context, filter Filter) ([]StashSet, error) {
+	repos, err := m.gitRepositories(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*StashSet)
+	var sets []*StashSet
+	for _, repo := range repos {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		repoPath := m.getRepoPath(&repo)
+		stashes, err := m.gitDownloader(ctx, &repo).Stashes(repoPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", repo.Name, err)
+		}
+		for _, s := range stashes {
+			name, ok := strings.CutPrefix(s.Message, stashPrefix)
+			if !ok {
+				continue
+			}
+			set := byName[name]
+			if set == nil {
+				set = &StashSet{Name: name}
+				byName[name] = set
+				sets = append(sets, set)
+			}
+			if !slices.Contains(set.Repos, repo.Name) {
+				set.Repos = append(set.Repos, repo.Name)
+			}
+			if s.Time.After(set.Created) {
+				set.Created = s.Time
+			}
+		}
+	}
+
+	result := make([]StashSet, len(sets))
+	for i, s := range sets {
+		result[i] = *s
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Created.After(result[j].Created)
+	})
+	return result, nil
+}
+
+// forEachStash runs fn concurrently in each repository.
+func (m *RepositoryManager) forEachStash(ctx context.Context, repos []config.Repository, set string, fn func(dl *downloader.GitDownloader, repoPath string) error) []StashResult {
+	sem := newSemaphore(m.concurrent)
+	var wg sync.WaitGroup
+	results := make([]StashResult, len(repos))
+
+	for i, repo := range repos {
+		wg.Add(1)
+		go func(idx int, r config.Repository) {
+			defer wg.Done()
+			results[idx] = StashResult{Name: r.Name, Set: set}
+			if err := sem.acquire(ctx); err != nil {
+				results[idx].Error = err
+				return
+			}
+			defer sem.release()
+
+			results[idx].Error = fn(m.gitDownloader(ctx, &r), m.getRepoPath(&r))
+			if results[idx].Error == nil {
+				m.logger.Debug("stash updated", "repo", r.Name, "set", set)
+			}
+		}(i, repo)
+	}
+
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,155 @@
+package manager
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/config"
+)
+
+// RepoStats summarizes the activity in a repository.
+type RepoStats struct {
+	Name         string
+	Commits      int      // Commits on HEAD within the window
+	Contributors []string // Authors of those commits, sorted
+	Lines        int      // Lines in the tracked text files at HEAD
+	LastActivity time.Time
+	Error        error
+}
+
+// Stats summarizes the selected git repositories: commits and
+// contributors on HEAD since the given date (any date git log --since
+// accepts; empty for all history), lines of tracked text files, and the
+// date of the latest commit. Missing and non-git repositories are skipped.
+func (m *RepositoryManager) Stats(ctx context.Context, filter Filter, since string) ([]RepoStats, error) {
+	candidates, err := m.gitRepositories(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	sem := newSemaphore(m.concurrent)
+	var wg sync.WaitGroup
+	stats := make([]RepoStats, len(candidates))
+
+	for i, repo := range candidates {
+		wg.Add(1)
+		go func(idx int, r config.Repository) {
+			defer wg.Done()
+			stats[idx] = RepoStats{Name: r.Name}
+			if err := sem.acquire(ctx); err != nil {
+				stats[idx].Error = err
+				return
+			}
+			defer sem.release()
+
+			stats[idx].Error = m.repoStats(ctx, &r, since, &stats[idx])
+		}(i, repo)
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (m *RepositoryManager) repoStats(ctx context.Context, repo *config.Repository, since string, s *RepoStats) error {
+	repoPath := m.getRepoPath(repo)
+	dl := m.gitDownloader(ctx, repo)
+
+	latest, err := dl.LogSince(repoPath, "HEAD", "", 1)
+	if err != nil {
+		return err
+	}
+	if len(latest) > 0 {
+		s.LastActivity = latest[0].Time
+	}
+
+	commits, err := dl.LogSince(repoPath, "HEAD", since, 0)
+	if err != nil {
+		return err
+	}
+	s.Commits = len(commits)
+	authors := make(map[string]bool)
+	for _, c := range commits {
+		if !authors[c.Author] {
+			authors[c.Author] = true
+			s.Contributors = append(s.Contributors, c.Author)
+		}
+	}
+	sort.Strings(s.Contributors)
+
+	files, err := dl.TrackedFiles(repoPath)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		n, err := countLines(filepath.Join(repoPath, filepath.FromSlash(f)))
+		if err != nil {
+			// Tracked files may be deleted in the working tree
+			continue
+		}
+		s.Lines += n
+	}
+	return nil
+}
+
+// binarySniffLen is how much of a file is checked for NUL bytes to tell
+// binary files from text, as git does.
+const binarySniffLen = 8000
+
+// countLines returns the number of lines in a text file, or 0 for binary
+// files and anything that is not a regular file.
+func countLines(path string) (int, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return 0, err
+	}
+	if !info.Mode().IsRegular() {
+		return 0, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = f.Close() }()
+
+	r := bufio.NewReaderSize(f, binarySniffLen)
+	head, err := r.Peek(binarySniffLen)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return 0, nil
+	}
+
+	lines, last := 0, byte('\n')
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			lines += bytes.Count(buf[:n], []byte{'\n'})
+			last = buf[n-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if last != '\n' {
+		// A final line without a newline still counts
+		lines++
+	}
+	return lines, nil
+}
@@ -0,0 +1,90 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/downloader"
+)
+
+// UpstreamChanges lists repositories that changed in the upstream config.
+type UpstreamChanges struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// Empty returns true if nothing changed.
+func (c *UpstreamChanges) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Changed) == 0
+}
+
+// PullUpstream downloads the upstream config and replaces the cached copy
+// once it has been validated. Local definitions are never modified.
+func (m *RepositoryManager) PullUpstream() (*UpstreamChanges, error) {
+	if !m.config.Upstream.Enabled() {
+		return nil, fmt.Errorf("no upstream config configured")
+	}
+
+	cachePath := m.config.UpstreamPath()
+	tmpPath := cachePath + ".tmp"
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	opts := downloader.DefaultOptions()
+	opts.UserAgent = m.config.HTTP.UserAgent
+	opts.RetryAttempts = m.config.HTTP.RetryAttempts
+	opts.RetryDelay = m.config.HTTP.RetryDelay
+	opts.Timeout = m.config.General.Timeout
+	if _, err := downloader.NewHTTPDownloader(opts).Download(m.config.Upstream.URL, tmpPath); err != nil {
+		return nil, fmt.Errorf("failed to download upstream config: %w", err)
+	}
+
+	newRepos, _, err := config.LoadUpstream(tmpPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var oldRepos []config.Repository
+	if _, err := os.Stat(cachePath); err == nil {
+		// An unreadable cache is replaced; treat everything as added
+		oldRepos, _, _ = config.LoadUpstream(cachePath)
+	}
+
+	if err := os.Rename(tmpPath, cachePath); err != nil {
+		return nil, fmt.Errorf("failed to save upstream config: %w", err)
+	}
+
+	return diffRepositories(oldRepos, newRepos), nil
+}
+
+// diffRepositories compares two repository lists by name.
+func diffRepositories(old, new []config.Repository) *UpstreamChanges {
+	changes := &UpstreamChanges{}
+
+	oldByName := make(map[string]config.Repository)
+	for _, repo := range old {
+		oldByName[repo.Name] = repo
+	}
+
+	seen := make(map[string]bool)
+	for _, repo := range new {
+		seen[repo.Name] = true
+		prev, ok := oldByName[repo.Name]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, repo.Name)
+		case !reflect.DeepEqual(prev, repo):
+			changes.Changed = append(changes.Changed, repo.Name)
+		}
+	}
+
+	for _, repo := range old {
+		if !seen[repo.Name] {
+			changes.Removed = append(changes.Removed, repo.Name)
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,93 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/downloader"
+)
+
+// vendorStagingSuffix is appended to a repository path while a vendored
+// copy is being prepared.
+const vendorStagingSuffix = ".hm-vendor"
+
+// checkVendorTarget refuses to vendor a repository over a git checkout at
+// repoPath that has uncommitted changes or branches with commits origin
+// lacks, which replacing it with the vendored copy would lose.
+func (m *RepositoryManager) checkVendorTarget(ctx context.Context, repo *config.Repository, repoPath string) error {
+	if !downloader.Exists(filepath.Join(repoPath, ".git")) {
+		return nil
+	}
+
+	dirty, err := downloader.IsDirty(repoPath)
+	if err != nil {
+		return fmt.Errorf("cannot vendor over the git checkout at %s: could not check for uncommitted changes: %w", repoPath, err)
+	}
+	if dirty {
+		return fmt.Errorf("cannot vendor over the git checkout at %s: it has uncommitted changes; save them and remove the checkout first", repoPath)
+	}
+	branches, err := m.gitDownloader(ctx, repo).LocalOnlyBranches(repoPath)
+	if err != nil {
+		return fmt.Errorf("cannot vendor over the git checkout at %s: could not check for unpushed commits: %w", repoPath, err)
+	}
+	if len(branches) > 0 {
+		return fmt.Errorf("cannot vendor over the git checkout at %s: branches %s have commits that are not on origin; push them and remove the checkout first", repoPath, strings.Join(branches, ", "))
+	}
+	return nil
+}
+
+// finishVendoring strips VCS metadata from a fresh clone at stagingPath,
+// records its tree hash, and moves it into place at repoPath. With a
+// subdir, only that directory of the clone is kept. The caller removes
+// what is left at stagingPath.
+func (m *RepositoryManager) finishVendoring(stagingPath, repoPath, subdir string) (string, error) {
+	if err := downloader.StripGitMetadata(stagingPath); err != nil {
+		return "", err
+	}
+
+	content := stagingPath
+	if subdir != "" {
+		content = filepath.Join(stagingPath, filepath.FromSlash(subdir))
+		if info, err := os.Stat(content); err != nil || !info.IsDir() {
+			return "", fmt.Errorf("subdir %s not found in the checked out commit", subdir)
+		}
+	}
+
+	treeHash, err := downloader.TreeHash(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute tree hash: %w", err)
+	}
+
+	if err := os.RemoveAll(repoPath); err != nil {
+		return "", fmt.Errorf("failed to remove previous content: %w", err)
+	}
+	if err := os.Rename(content, repoPath); err != nil {
+		return "", fmt.Errorf("failed to move vendored content into place: %w", err)
+	}
+	return treeHash, nil
+}
+
+// fillVendoredStatus reports a vendored repository as being at its locked
+// commit when its content still matches the locked tree hash.
+func (m *RepositoryManager) fillVendoredStatus(name, repoPath string, status *RepoStatus) {
+	if m.lockFile == nil {
+		return
+	}
+	entry, ok := m.lockFile.Get(name)
+	if !ok || !entry.Vendored {
+		return
+	}
+
+	treeHash, err := downloader.TreeHash(repoPath)
+	if err != nil {
+		status.Error = err
+		return
+	}
+
+	status.CurrentSHA = entry.ResolvedSHA
+	status.IsDirty = treeHash != entry.TreeHash
+}
@@ -0,0 +1,85 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/errcode"
+)
+
+// RepoVerification is the result of verifying the signature of one
+// repository's locked commit.
+type RepoVerification struct {
+	Name  string
+	SHA   string // Locked commit
+	Error error  // errcode.Unsigned if the commit has no valid signature
+}
+
+// Verify checks that the locked commit of each selected repository in a
+// project with require_signed has a valid signature. A locked tag may
+// carry the signature instead. Commits missing from a checkout are
+// fetched; missing, vendored, and non-git repositories are skipped.
+func (m *RepositoryManager) Verify(ctx context.Context, filter Filter) ([]RepoVerification, error) {
+	candidates, err := m.gitRepositories(filter)
+	if err != nil {
+		return nil, err
+	}
+	var repos []config.Repository
+	for _, repo := range candidates {
+		if m.config.RequiresSigned(&repo) {
+			repos = append(repos, repo)
+		}
+	}
+
+	sem := newSemaphore(m.concurrent)
+	var wg sync.WaitGroup
+	results := make([]RepoVerification, len(repos))
+
+	for i, repo := range repos {
+		wg.Add(1)
+		go func(idx int, r config.Repository) {
+			defer wg.Done()
+			results[idx] = RepoVerification{Name: r.Name}
+			if err := sem.acquire(ctx); err != nil {
+				results[idx].Error = err
+				return
+			}
+			defer sem.release()
+
+			results[idx].SHA, results[idx].Error = m.verifyRepository(ctx, &r)
+		}(i, repo)
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// verifyRepository verifies the signature of the locked commit of repo and
+// returns the commit.
+func (m *RepositoryManager) verifyRepository(ctx context.Context, repo *config.Repository) (string, error) {
+	var sha string
+	if m.lockFile != nil {
+		sha, _ = m.lockFile.GetResolvedSHA(repo.Name)
+	}
+	if sha == "" {
+		return "", errcode.Wrap(errcode.LockMissing, fmt.Errorf("no lock entry for repository (run sync first)"))
+	}
+
+	tag := repo.Tag
+	if repo.ResolvesTag() {
+		tag = m.lockedRef(repo)
+	}
+
+	repoPath := m.getRepoPath(repo)
+	dl := m.gitDownloader(ctx, repo)
+	if err := dl.EnsureCommit(repoPath, m.remoteRef(repo), sha); err != nil {
+		return sha, err
+	}
+	return sha, dl.VerifySignature(repoPath, sha, tag)
+}
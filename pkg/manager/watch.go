@@ -0,0 +1,88 @@
+package manager
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/config"
+)
+
+// RemoteCheck compares a repository's locked SHA with its remote ref.
+type RemoteCheck struct {
+	Name      string
+	Ref       string
+	LockedSHA string
+	RemoteSHA string
+	Drifted   bool
+	Error     error
+}
+
+// CheckRemotes resolves each repository's requested ref on its remote with
+// git ls-remote and reports whether it has moved from the locked SHA.
+// Nothing is fetched. Repositories pinned to a commit or date and non-git
+// repositories cannot drift and are skipped.
+func (m *RepositoryManager) CheckRemotes(ctx context.Context, filter Filter) ([]RemoteCheck, error) {
+	repos, err := m.getRepositories(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []config.Repository
+	for _, repo := range repos {
+		if repo.Type == config.RepoTypeGit && repo.Commit == "" && repo.AsOf == "" {
+			candidates = append(candidates, repo)
+		}
+	}
+
+	sem := newSemaphore(m.concurrent)
+	var wg sync.WaitGroup
+	checks := make([]RemoteCheck, len(candidates))
+
+	for i, repo := range candidates {
+		wg.Add(1)
+		go func(idx int, r config.Repository) {
+			defer wg.Done()
+
+			check := RemoteCheck{
+				Name: r.Name,
+				Ref:  r.GetEffectiveRef(m.config.General.DefaultBranch),
+			}
+			if m.lockFile != nil {
+				check.LockedSHA, _ = m.lockFile.GetResolvedSHA(r.Name)
+			}
+
+			if err := sem.acquire(ctx); err != nil {
+				check.Error = err
+				checks[idx] = check
+				return
+			}
+			defer sem.release()
+
+			start := time.Now()
+			if r.ResolvesRef() {
+				_, check.RemoteSHA, check.Error = m.resolveRef(ctx, &r)
+			} else {
+				check.RemoteSHA, check.Error = m.gitDownloader(ctx, &r).LsRemote(r.URL, check.Ref)
+			}
+			check.Drifted = check.Error == nil && check.RemoteSHA != check.LockedSHA
+			m.logger.Debug("remote checked",
+				"repo", r.Name,
+				"ref", check.Ref,
+				"remote_sha", check.RemoteSHA,
+				"locked_sha", check.LockedSHA,
+				"drifted", check.Drifted,
+				"duration", time.Since(start),
+				"error", check.Error,
+			)
+			checks[idx] = check
+		}(i, repo)
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return checks, nil
+}
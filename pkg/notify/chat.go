@@ -0,0 +1,136 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ChatKind identifies a chat service's incoming webhook format.
+type ChatKind string
+
+const (
+	ChatSlack ChatKind = "slack"
+	ChatTeams ChatKind = "teams"
+)
+
+// Trigger controls which outcomes produce a chat message.
+type Trigger string
+
+const (
+	// TriggerAlways posts after every operation.
+	TriggerAlways Trigger = "always"
+
+	// TriggerFailure posts only when a repository failed.
+	TriggerFailure Trigger = "failure"
+
+	// TriggerChange posts when a repository failed or its lock moved.
+	TriggerChange Trigger = "change"
+)
+
+// DefaultChatTemplate is the message used when no template is configured.
+const DefaultChatTemplate = `Harbormaster {{.Event}} in {{.Workspace}}: {{.Succeeded}}/{{.Total}} succeeded{{if .Failed}}, {{.Failed}} failed{{end}}
+{{- range .FailedResults}}
+- {{.Name}} failed: {{.Error}}
+{{- end}}
+{{- range .UpdatedResults}}
+- {{.Name}} updated{{if .PrevSHA}} {{short .PrevSHA}} ->{{end}} {{short .SHA}}
+{{- end}}`
+
+// Chat posts a rendered message to a Slack or Microsoft Teams incoming
+// webhook.
+type Chat struct {
+	Kind          ChatKind
+	URL           string
+	On            Trigger
+	RetryAttempts int
+	RetryDelay    time.Duration
+	Client        *http.Client
+
+	tmpl *template.Template
+}
+
+// NewChat creates a chat notifier. An empty text uses DefaultChatTemplate
+// and an empty trigger defaults to TriggerChange.
+func NewChat(kind ChatKind, url, text string, on Trigger) (*Chat, error) {
+	if text == "" {
+		text = DefaultChatTemplate
+	}
+	if on == "" {
+		on = TriggerChange
+	}
+
+	tmpl, err := template.New(string(kind)).Funcs(template.FuncMap{
+		"short": short,
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s template: %w", kind, err)
+	}
+
+	return &Chat{Kind: kind, URL: url, On: on, tmpl: tmpl}, nil
+}
+
+// Notify renders and posts the message if the trigger matches.
+func (c *Chat) Notify(ctx context.Context, summary *Summary) error {
+	if !c.triggered(summary) {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := c.tmpl.Execute(&buf, summary); err != nil {
+		return fmt.Errorf("failed to render %s message: %w", c.Kind, err)
+	}
+
+	body, err := json.Marshal(c.payload(strings.TrimSpace(buf.String()), summary))
+	if err != nil {
+		return fmt.Errorf("failed to encode %s message: %w", c.Kind, err)
+	}
+
+	if err := deliver(ctx, c.Client, c.URL, body, nil, c.RetryAttempts, c.RetryDelay); err != nil {
+		return fmt.Errorf("%s notification failed: %w", c.Kind, err)
+	}
+	return nil
+}
+
+// triggered reports whether the summary matches the notifier's trigger.
+func (c *Chat) triggered(s *Summary) bool {
+	switch c.On {
+	case TriggerAlways:
+		return true
+	case TriggerFailure:
+		return s.Failed > 0
+	default:
+		return s.Failed > 0 || s.Updated > 0
+	}
+}
+
+// payload wraps the message in the service's webhook format.
+func (c *Chat) payload(text string, s *Summary) any {
+	if c.Kind == ChatTeams {
+		color := "2EB886"
+		if s.Failed > 0 {
+			color = "D00000"
+		}
+		return map[string]string{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"summary":    fmt.Sprintf("Harbormaster %s in %s", s.Event, s.Workspace),
+			"themeColor": color,
+			"text":       strings.ReplaceAll(text, "\n", "\n\n"),
+		}
+	}
+	return map[string]string{"text": text}
+}
+
+// short abbreviates a SHA for display.
+func short(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
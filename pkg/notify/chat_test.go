@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChat_Notify(t *testing.T) {
+	tests := []struct {
+		kind     ChatKind
+		wantType string
+	}{
+		{ChatSlack, ""},
+		{ChatTeams, "MessageCard"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.kind), func(t *testing.T) {
+			var payload map[string]string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewDecoder(r.Body).Decode(&payload)
+			}))
+			defer srv.Close()
+
+			chat, err := NewChat(tt.kind, srv.URL, "", "")
+			if err != nil {
+				t.Fatalf("NewChat failed: %v", err)
+			}
+			if err := chat.Notify(context.Background(), testSummary()); err != nil {
+				t.Fatalf("Notify failed: %v", err)
+			}
+
+			text := payload["text"]
+			if !strings.Contains(text, "1/2 succeeded, 1 failed") {
+				t.Errorf("expected totals in message, got %q", text)
+			}
+			if !strings.Contains(text, "bad failed: clone failed") {
+				t.Errorf("expected failure in message, got %q", text)
+			}
+			if !strings.Contains(text, "ok updated abc123") {
+				t.Errorf("expected lock update in message, got %q", text)
+			}
+			if payload["@type"] != tt.wantType {
+				t.Errorf("expected @type %q, got %q", tt.wantType, payload["@type"])
+			}
+		})
+	}
+}
+
+func TestChat_Trigger(t *testing.T) {
+	failed := testSummary()
+	clean := &Summary{Event: EventSync, Total: 1, Succeeded: 1, Results: []Result{
+		{Name: "ok", Success: true, SHA: "abc", PrevSHA: "abc"},
+	}}
+	updated := &Summary{Event: EventSync, Total: 1, Succeeded: 1, Updated: 1, Results: []Result{
+		{Name: "ok", Success: true, SHA: "def", PrevSHA: "abc"},
+	}}
+
+	tests := []struct {
+		on      Trigger
+		summary *Summary
+		want    bool
+	}{
+		{TriggerAlways, clean, true},
+		{TriggerFailure, clean, false},
+		{TriggerFailure, updated, false},
+		{TriggerFailure, failed, true},
+		{TriggerChange, clean, false},
+		{TriggerChange, updated, true},
+		{TriggerChange, failed, true},
+	}
+
+	for _, tt := range tests {
+		posted := false
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			posted = true
+		}))
+
+		chat, err := NewChat(ChatSlack, srv.URL, "{{.Total}}", tt.on)
+		if err != nil {
+			t.Fatalf("NewChat failed: %v", err)
+		}
+		if err := chat.Notify(context.Background(), tt.summary); err != nil {
+			t.Errorf("Notify failed: %v", err)
+		}
+		if posted != tt.want {
+			t.Errorf("on=%s updated=%d failed=%d: expected posted=%v", tt.on, tt.summary.Updated, tt.summary.Failed, tt.want)
+		}
+		srv.Close()
+	}
+}
+
+func TestNewChat_InvalidTemplate(t *testing.T) {
+	if _, err := NewChat(ChatSlack, "https://hooks.slack.com/x", "{{.Missing", ""); err == nil {
+		t.Error("expected error for invalid template")
+	}
+}
@@ -0,0 +1,134 @@
+// Package notify delivers workspace operation summaries to external
+// services such as dashboards and chat-ops bots.
+package notify
+
+import (
+	"context"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/types"
+)
+
+const (
+	// EventSync is the event name for a completed sync.
+	EventSync = "sync"
+
+	// EventVerify is the event name for a completed signature check.
+	EventVerify = "verify"
+)
+
+// Notifier delivers a summary to a single destination.
+type Notifier interface {
+	Notify(ctx context.Context, summary *Summary) error
+}
+
+// Summary is the JSON payload describing a completed operation.
+type Summary struct {
+	Event      string    `json:"event"`
+	Workspace  string    `json:"workspace"`
+	Timestamp  time.Time `json:"timestamp"`
+	DurationMs int64     `json:"duration_ms"`
+	Total      int       `json:"total"`
+	Succeeded  int       `json:"succeeded"`
+	Failed     int       `json:"failed"`
+	Updated    int       `json:"updated"`
+	Results    []Result  `json:"results"`
+}
+
+// Result describes the outcome for a single repository.
+type Result struct {
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	Success    bool   `json:"success"`
+	SHA        string `json:"sha,omitempty"`
+	PrevSHA    string `json:"previous_sha,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+	Code       string `json:"code,omitempty"` // Error code, e.g. HM104
+}
+
+// NewSyncSummary builds a summary from a sync result.
+func NewSyncSummary(workspace string, result *types.SyncResult) *Summary {
+	s := &Summary{
+		Event:      EventSync,
+		Workspace:  workspace,
+		Timestamp:  time.Now().UTC(),
+		DurationMs: result.Duration.Milliseconds(),
+		Total:      result.TotalRepos,
+		Succeeded:  result.SuccessCount,
+		Failed:     result.FailureCount,
+		Results:    make([]Result, 0, len(result.Results)),
+	}
+
+	for _, r := range result.Results {
+		res := Result{
+			Name:       r.RepoName,
+			URL:        r.RepoURL,
+			Success:    r.Success,
+			SHA:        r.CommitSHA,
+			PrevSHA:    r.PreviousSHA,
+			DurationMs: r.Duration.Milliseconds(),
+		}
+		if r.Error != nil {
+			res.Error = r.Error.Error()
+			res.Code = string(errcode.Of(r.Error))
+		}
+		if res.Updated() {
+			s.Updated++
+		}
+		s.Results = append(s.Results, res)
+	}
+
+	return s
+}
+
+// NewVerifySummary builds a summary from the per-repository outcomes of
+// a signature check. A result succeeds if its locked commit is signed.
+// A check never moves a lock, so each result's PrevSHA is set to its SHA.
+func NewVerifySummary(workspace string, results []Result, duration time.Duration) *Summary {
+	s := &Summary{
+		Event:      EventVerify,
+		Workspace:  workspace,
+		Timestamp:  time.Now().UTC(),
+		DurationMs: duration.Milliseconds(),
+		Total:      len(results),
+		Results:    results,
+	}
+	for i, r := range results {
+		results[i].PrevSHA = r.SHA
+		if r.Success {
+			s.Succeeded++
+		} else {
+			s.Failed++
+		}
+	}
+	return s
+}
+
+// Updated returns true if the operation moved the locked SHA.
+func (r Result) Updated() bool {
+	return r.Success && r.SHA != "" && r.SHA != r.PrevSHA
+}
+
+// FailedResults returns the results that failed.
+func (s *Summary) FailedResults() []Result {
+	var failed []Result
+	for _, r := range s.Results {
+		if !r.Success {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// UpdatedResults returns the results that moved the locked SHA.
+func (s *Summary) UpdatedResults() []Result {
+	var updated []Result
+	for _, r := range s.Results {
+		if r.Updated() {
+			updated = append(updated, r)
+		}
+	}
+	return updated
+}
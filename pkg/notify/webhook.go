@@ -0,0 +1,114 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// EventHeader carries the event name of a webhook delivery.
+	EventHeader = "X-Harbormaster-Event"
+
+	// SignatureHeader carries the HMAC-SHA256 signature of the request body.
+	SignatureHeader = "X-Harbormaster-Signature"
+)
+
+// Webhook posts the JSON summary to a URL.
+type Webhook struct {
+	URL           string
+	Secret        string // Signs the body when set
+	RetryAttempts int
+	RetryDelay    time.Duration
+	Client        *http.Client
+}
+
+// Sign returns the signature header value for body, in the form
+// "sha256=<hex>".
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Notify posts the summary, retrying on network errors, rate limiting,
+// and server errors.
+func (w *Webhook) Notify(ctx context.Context, summary *Summary) error {
+	body, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to encode summary: %w", err)
+	}
+
+	headers := map[string]string{EventHeader: summary.Event}
+	if w.Secret != "" {
+		headers[SignatureHeader] = Sign(w.Secret, body)
+	}
+
+	if err := deliver(ctx, w.Client, w.URL, body, headers, w.RetryAttempts, w.RetryDelay); err != nil {
+		return fmt.Errorf("webhook %s failed: %w", w.URL, err)
+	}
+	return nil
+}
+
+// deliver posts a JSON body to url. The delay doubles after each attempt.
+func deliver(ctx context.Context, client *http.Client, url string, body []byte, headers map[string]string, attempts int, delay time.Duration) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			delay *= 2
+		}
+
+		retry, err := post(ctx, client, url, body, headers)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+
+	return lastErr
+}
+
+// post sends a single request and reports whether a failure is retryable.
+func post(ctx context.Context, client *http.Client, url string, body []byte, headers map[string]string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected status: %s", resp.Status)
+}
@@ -0,0 +1,138 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/types"
+)
+
+func testSummary() *Summary {
+	return NewSyncSummary("ws", types.NewSyncResult([]types.OperationResult{
+		{RepoName: "ok", RepoURL: "https://example.com/ok.git", Success: true, CommitSHA: "abc123", Duration: 2 * time.Second},
+		{RepoName: "bad", RepoURL: "https://example.com/bad.git", Error: errors.New("clone failed")},
+	}, 3*time.Second))
+}
+
+func TestNewSyncSummary(t *testing.T) {
+	s := testSummary()
+
+	if s.Event != EventSync || s.Workspace != "ws" {
+		t.Errorf("unexpected event/workspace: %s/%s", s.Event, s.Workspace)
+	}
+	if s.Total != 2 || s.Succeeded != 1 || s.Failed != 1 {
+		t.Errorf("unexpected counts: %d/%d/%d", s.Total, s.Succeeded, s.Failed)
+	}
+	if s.DurationMs != 3000 {
+		t.Errorf("expected duration 3000ms, got %d", s.DurationMs)
+	}
+	if s.Results[0].SHA != "abc123" || s.Results[0].DurationMs != 2000 {
+		t.Errorf("unexpected first result: %+v", s.Results[0])
+	}
+	if s.Results[1].Error != "clone failed" {
+		t.Errorf("expected error message, got '%s'", s.Results[1].Error)
+	}
+}
+
+func TestNewVerifySummary(t *testing.T) {
+	s := NewVerifySummary("ws", []Result{
+		{Name: "signed", Success: true, SHA: "abc123"},
+		{Name: "unsigned", SHA: "def456", Error: "no valid signature", Code: "HM109"},
+	}, time.Second)
+
+	if s.Event != EventVerify || s.Total != 2 || s.Succeeded != 1 || s.Failed != 1 {
+		t.Errorf("unexpected summary: %+v", s)
+	}
+	if s.Updated != 0 || len(s.UpdatedResults()) != 0 {
+		t.Errorf("expected no updates in a verify summary, got %d", s.Updated)
+	}
+}
+
+func TestWebhook_Notify(t *testing.T) {
+	var body []byte
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header
+	}))
+	defer srv.Close()
+
+	hook := &Webhook{URL: srv.URL, Secret: "s3cret"}
+	if err := hook.Notify(context.Background(), testSummary()); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	if header.Get(EventHeader) != EventSync {
+		t.Errorf("expected event header %q, got %q", EventSync, header.Get(EventHeader))
+	}
+	if got, want := header.Get(SignatureHeader), Sign("s3cret", body); got != want {
+		t.Errorf("expected signature %q, got %q", want, got)
+	}
+
+	var decoded Summary
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("invalid JSON body: %v", err)
+	}
+	if decoded.Failed != 1 || len(decoded.Results) != 2 {
+		t.Errorf("unexpected decoded summary: %+v", decoded)
+	}
+}
+
+func TestWebhook_Retry(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int32
+		wantErr  bool
+	}{
+		{"server error retried", http.StatusBadGateway, 3, true},
+		{"rate limit retried", http.StatusTooManyRequests, 3, true},
+		{"client error not retried", http.StatusBadRequest, 1, true},
+		{"success", http.StatusNoContent, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			hook := &Webhook{URL: srv.URL, RetryAttempts: 2, RetryDelay: time.Millisecond}
+			err := hook.Notify(context.Background(), testSummary())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if calls.Load() != tt.attempts {
+				t.Errorf("expected %d attempts, got %d", tt.attempts, calls.Load())
+			}
+		})
+	}
+}
+
+func TestWebhook_RecoversAfterRetry(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	hook := &Webhook{URL: srv.URL, RetryAttempts: 2, RetryDelay: time.Millisecond}
+	if err := hook.Notify(context.Background(), testSummary()); err != nil {
+		t.Fatalf("expected success after retry, got %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expected 2 attempts, got %d", calls.Load())
+	}
+}
@@ -0,0 +1,198 @@
+package overlay
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MarkerFileName marks a directory as a generated overlay so it can be
+// safely cleared and rebuilt.
+const MarkerFileName = ".harbormaster-overlay"
+
+// Source is a directory (or file) contributing entries to the overlay.
+type Source struct {
+	Name string // Repository name
+	Path string // Absolute path to the checkout
+}
+
+// Conflict describes a path provided by more than one source.
+type Conflict struct {
+	Path    string
+	Sources []string
+}
+
+// ConflictError is returned when sources overlap and the overlay cannot
+// be built unambiguously.
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d overlay conflicts", len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		fmt.Fprintf(&b, "\n  %s: %s", c.Path, strings.Join(c.Sources, ", "))
+	}
+	return b.String()
+}
+
+// Result summarizes a built overlay.
+type Result struct {
+	Target string
+	Links  int
+}
+
+// entry is a planned overlay path.
+type entry struct {
+	source string // Source name
+	target string // Absolute path the link points to
+	isDir  bool
+}
+
+// Build creates a merged tree of symlinks from all sources in target.
+// Directories are merged; a file provided by more than one source (or a
+// file in one source shadowing a directory in another) is a conflict, and
+// no changes are made to target in that case.
+func Build(target string, sources []Source) (*Result, error) {
+	plan, err := plan(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := prepareTarget(target); err != nil {
+		return nil, err
+	}
+
+	// Create directories before links so parents always exist
+	paths := make([]string, 0, len(plan))
+	for p := range plan {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	result := &Result{Target: target}
+	for _, p := range paths {
+		e := plan[p]
+		dest := filepath.Join(target, p)
+		if e.isDir {
+			if err := os.MkdirAll(dest, 0755); err != nil {
+				return nil, fmt.Errorf("failed to create directory: %w", err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.Symlink(e.target, dest); err != nil {
+			return nil, fmt.Errorf("failed to link %s: %w", p, err)
+		}
+		result.Links++
+	}
+
+	return result, nil
+}
+
+// plan walks all sources and returns the overlay layout keyed by
+// relative path, or a ConflictError if sources overlap.
+func plan(sources []Source) (map[string]entry, error) {
+	entries := make(map[string]entry)
+	conflicts := make(map[string][]string)
+
+	add := func(rel string, e entry) {
+		existing, ok := entries[rel]
+		if !ok {
+			entries[rel] = e
+			return
+		}
+		if existing.isDir && e.isDir {
+			return
+		}
+		if _, seen := conflicts[rel]; !seen {
+			conflicts[rel] = []string{existing.source}
+		}
+		conflicts[rel] = append(conflicts[rel], e.source)
+	}
+
+	for _, src := range sources {
+		info, err := os.Stat(src.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", src.Name, err)
+		}
+
+		// Single-file sources (HTTP downloads) are linked by base name
+		if !info.IsDir() {
+			add(filepath.Base(src.Path), entry{source: src.Name, target: src.Path})
+			continue
+		}
+
+		err = filepath.WalkDir(src.Path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(src.Path, path)
+			if err != nil {
+				return err
+			}
+			if rel == "." {
+				return nil
+			}
+			if d.Name() == ".git" {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			add(rel, entry{source: src.Name, target: path, isDir: d.IsDir()})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk %s: %w", src.Name, err)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		ce := &ConflictError{}
+		for p, names := range conflicts {
+			ce.Conflicts = append(ce.Conflicts, Conflict{Path: p, Sources: names})
+		}
+		sort.Slice(ce.Conflicts, func(i, j int) bool {
+			return ce.Conflicts[i].Path < ce.Conflicts[j].Path
+		})
+		return nil, ce
+	}
+
+	return entries, nil
+}
+
+// prepareTarget clears a previously generated overlay, refusing to touch
+// a non-empty directory that was not created by Build.
+func prepareTarget(target string) error {
+	dirEntries, err := os.ReadDir(target)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read overlay target: %w", err)
+	}
+
+	if len(dirEntries) > 0 {
+		if _, err := os.Stat(filepath.Join(target, MarkerFileName)); err != nil {
+			return fmt.Errorf("overlay target %s is not empty and was not generated by harbormaster", target)
+		}
+		if err := os.RemoveAll(target); err != nil {
+			return fmt.Errorf("failed to clear overlay target: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return fmt.Errorf("failed to create overlay target: %w", err)
+	}
+
+	marker := filepath.Join(target, MarkerFileName)
+	if err := os.WriteFile(marker, []byte("# Generated by Harbormaster - DO NOT EDIT\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write overlay marker: %w", err)
+	}
+
+	return nil
+}
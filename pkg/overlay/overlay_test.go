@@ -0,0 +1,117 @@
+package overlay
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+}
+
+func TestBuild_MergesSources(t *testing.T) {
+	tmpDir := t.TempDir()
+	a := filepath.Join(tmpDir, "a")
+	b := filepath.Join(tmpDir, "b")
+	writeFile(t, filepath.Join(a, "src", "a.c"), "a")
+	writeFile(t, filepath.Join(a, ".git", "HEAD"), "ref")
+	writeFile(t, filepath.Join(b, "src", "b.c"), "b")
+	writeFile(t, filepath.Join(b, "README.md"), "b")
+
+	target := filepath.Join(tmpDir, "overlay")
+	result, err := Build(target, []Source{{Name: "a", Path: a}, {Name: "b", Path: b}})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	if result.Links != 3 {
+		t.Errorf("expected 3 links, got %d", result.Links)
+	}
+
+	link, err := os.Readlink(filepath.Join(target, "src", "a.c"))
+	if err != nil {
+		t.Fatalf("expected symlink for src/a.c: %v", err)
+	}
+	if link != filepath.Join(a, "src", "a.c") {
+		t.Errorf("unexpected link target: %s", link)
+	}
+
+	if _, err := os.Lstat(filepath.Join(target, ".git")); err == nil {
+		t.Error("expected .git to be excluded from overlay")
+	}
+	if _, err := os.Stat(filepath.Join(target, MarkerFileName)); err != nil {
+		t.Error("expected overlay marker file")
+	}
+}
+
+func TestBuild_Conflict(t *testing.T) {
+	tmpDir := t.TempDir()
+	a := filepath.Join(tmpDir, "a")
+	b := filepath.Join(tmpDir, "b")
+	writeFile(t, filepath.Join(a, "Makefile"), "a")
+	writeFile(t, filepath.Join(b, "Makefile"), "b")
+
+	target := filepath.Join(tmpDir, "overlay")
+	_, err := Build(target, []Source{{Name: "a", Path: a}, {Name: "b", Path: b}})
+
+	var ce *ConflictError
+	if !errors.As(err, &ce) {
+		t.Fatalf("expected ConflictError, got %v", err)
+	}
+	if len(ce.Conflicts) != 1 || ce.Conflicts[0].Path != "Makefile" {
+		t.Errorf("unexpected conflicts: %+v", ce.Conflicts)
+	}
+	if _, err := os.Stat(target); err == nil {
+		t.Error("expected target not to be created on conflict")
+	}
+}
+
+func TestBuild_Rebuild(t *testing.T) {
+	tmpDir := t.TempDir()
+	a := filepath.Join(tmpDir, "a")
+	writeFile(t, filepath.Join(a, "one.txt"), "1")
+
+	target := filepath.Join(tmpDir, "overlay")
+	if _, err := Build(target, []Source{{Name: "a", Path: a}}); err != nil {
+		t.Fatalf("first Build failed: %v", err)
+	}
+
+	if err := os.Remove(filepath.Join(a, "one.txt")); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	writeFile(t, filepath.Join(a, "two.txt"), "2")
+
+	if _, err := Build(target, []Source{{Name: "a", Path: a}}); err != nil {
+		t.Fatalf("second Build failed: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(target, "one.txt")); err == nil {
+		t.Error("expected stale link to be removed")
+	}
+	if _, err := os.Lstat(filepath.Join(target, "two.txt")); err != nil {
+		t.Error("expected new link to be created")
+	}
+}
+
+func TestBuild_RefusesForeignTarget(t *testing.T) {
+	tmpDir := t.TempDir()
+	a := filepath.Join(tmpDir, "a")
+	writeFile(t, filepath.Join(a, "one.txt"), "1")
+
+	target := filepath.Join(tmpDir, "overlay")
+	writeFile(t, filepath.Join(target, "precious.txt"), "keep")
+
+	if _, err := Build(target, []Source{{Name: "a", Path: a}}); err == nil {
+		t.Fatal("expected error for non-overlay target")
+	}
+	if _, err := os.Stat(filepath.Join(target, "precious.txt")); err != nil {
+		t.Error("expected existing file to be preserved")
+	}
+}
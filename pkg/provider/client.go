@@ -0,0 +1,343 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/logging"
+)
+
+// maxRetryDelay caps the backoff between retries of server errors.
+const maxRetryDelay = time.Minute
+
+// RateLimitError is returned when a host's rate limit is exhausted for
+// longer than the client is willing to wait.
+type RateLimitError struct {
+	Host  string
+	Reset time.Time // When the limit resets
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("API rate limit of %s exhausted until %s", e.Host, e.Reset.Local().Format(time.Kitchen))
+}
+
+// APIError is an unsuccessful response from an API.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("API request failed: %s", http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("API request failed: %s: %s", http.StatusText(e.StatusCode), e.Message)
+}
+
+// Replaced in tests
+var (
+	now   = time.Now
+	sleep = func(ctx context.Context, d time.Duration) error {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		}
+	}
+)
+
+// client sends requests to the API of one host. It tracks the rate limit
+// the host reports in its response headers: once the limit is exhausted,
+// requests wait for it to reset instead of failing, and responses that
+// report being rate limited are retried after the wait the host asks for.
+type client struct {
+	host    string
+	baseURL string
+	header  http.Header // Sent with every request, including credentials
+	prefix  []byte      // Stripped from responses before decoding
+	options Options
+	http    *http.Client
+	logger  *slog.Logger
+
+	mu        sync.Mutex
+	remaining int       // Requests left in the current window; -1 if unknown
+	reset     time.Time // When the current window ends
+}
+
+func newClient(host, baseURL string, header http.Header, opts Options) *client {
+	if opts.MaxWait <= 0 {
+		opts.MaxWait = DefaultMaxWait
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.UserAgent != "" {
+		header.Set("User-Agent", opts.UserAgent)
+	}
+	return &client{
+		host:      host,
+		baseURL:   baseURL,
+		header:    header,
+		options:   opts,
+		http:      &http.Client{Timeout: opts.Timeout},
+		logger:    logging.OrDiscard(opts.Logger).With("host", host),
+		remaining: -1,
+	}
+}
+
+// do sends a request with body encoded as JSON, if not nil, and decodes
+// the JSON response into out, if not nil. A *[]byte out receives the
+// response as is. Rate limited requests are always retried, as the host
+// did not act on them; network and server errors only for idempotent
+// methods, since a POST that failed may still have created something.
+func (c *client) do(ctx context.Context, method, path string, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.options.RetryAttempts; attempt++ {
+		if err := c.waitForLimit(ctx); err != nil {
+			return err
+		}
+
+		resp, err := c.send(ctx, method, path, payload)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			lastErr = errcode.Wrap(errcode.NetworkError, err)
+			if !idempotent(method) {
+				return lastErr
+			}
+			if err := c.backoff(ctx, attempt, lastErr); err != nil {
+				return err
+			}
+			continue
+		}
+
+		limited := c.observe(resp)
+		data, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			lastErr = errcode.Wrap(errcode.NetworkError, fmt.Errorf("failed to read response: %w", err))
+			if !idempotent(method) {
+				return lastErr
+			}
+			if err := c.backoff(ctx, attempt, lastErr); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			if out == nil {
+				return nil
+			}
+			if raw, ok := out.(*[]byte); ok {
+				*raw = data
+				return nil
+			}
+			if err := json.Unmarshal(bytes.TrimPrefix(data, c.prefix), out); err != nil {
+				return fmt.Errorf("failed to decode response: %w", err)
+			}
+			return nil
+		case limited:
+			// Retried once the limit resets
+			lastErr = &RateLimitError{Host: c.host, Reset: c.resetTime()}
+		case resp.StatusCode >= 500:
+			lastErr = responseError(resp.StatusCode, data)
+			if !idempotent(method) {
+				return lastErr
+			}
+			if err := c.backoff(ctx, attempt, lastErr); err != nil {
+				return err
+			}
+		default:
+			return responseError(resp.StatusCode, data)
+		}
+	}
+	return lastErr
+}
+
+// idempotent reports whether sending a request with method again has the
+// same effect as sending it once.
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func (c *client) send(ctx context.Context, method, path string, payload []byte) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = c.header.Clone()
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	start := now()
+	resp, err := c.http.Do(req)
+	if err != nil {
+		c.logger.Debug("api request failed", "method", method, "path", path, "error", err)
+		return nil, err
+	}
+	c.logger.Debug("api request",
+		"method", method,
+		"path", path,
+		"status", resp.StatusCode,
+		"duration", now().Sub(start),
+	)
+	return resp, nil
+}
+
+// observe records the rate limit reported by a response and reports
+// whether the response says the request was rate limited.
+func (c *client) observe(resp *http.Response) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// GitHub uses X-RateLimit-*, GitLab RateLimit-*
+	remaining, okRemaining := intHeader(resp.Header, "X-RateLimit-Remaining", "RateLimit-Remaining")
+	reset, okReset := intHeader(resp.Header, "X-RateLimit-Reset", "RateLimit-Reset")
+	if okRemaining {
+		c.remaining = remaining
+	}
+	if okReset {
+		c.reset = time.Unix(int64(reset), 0)
+	}
+
+	limited := resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden && ((okRemaining && remaining == 0) || resp.Header.Get("Retry-After") != ""))
+	if !limited {
+		return false
+	}
+
+	// Retry-After takes precedence, as secondary limits only report it
+	if seconds, ok := intHeader(resp.Header, "Retry-After"); ok {
+		c.remaining = 0
+		c.reset = now().Add(time.Duration(seconds) * time.Second)
+	} else if c.remaining != 0 || !c.reset.After(now()) {
+		// No hint when to retry: back off for a minute
+		c.remaining = 0
+		c.reset = now().Add(time.Minute)
+	}
+	return true
+}
+
+// waitForLimit blocks until the rate limit resets if it is exhausted.
+func (c *client) waitForLimit(ctx context.Context) error {
+	c.mu.Lock()
+	var wait time.Duration
+	if c.remaining == 0 {
+		wait = c.reset.Sub(now())
+	}
+	reset := c.reset
+	c.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	if wait > c.options.MaxWait {
+		return &RateLimitError{Host: c.host, Reset: reset}
+	}
+
+	c.logger.Info("waiting for API rate limit to reset", "wait", wait.Round(time.Second))
+	if err := sleep(ctx, wait); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if c.reset.Equal(reset) {
+		c.remaining = -1
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *client) resetTime() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reset
+}
+
+// backoff waits before retrying a failed request, unless it was the
+// last attempt.
+func (c *client) backoff(ctx context.Context, attempt int, lastErr error) error {
+	if attempt >= c.options.RetryAttempts {
+		return nil
+	}
+	delay := min(c.options.RetryDelay<<attempt, maxRetryDelay)
+	if delay > 0 {
+		// Jitter into [delay/2, delay] so that parallel callers spread out
+		delay = delay/2 + rand.N(delay/2+1)
+	}
+	c.logger.Debug("retrying api request", "attempt", attempt+1, "delay", delay, "error", lastErr)
+	return sleep(ctx, delay)
+}
+
+// responseError describes an unsuccessful response, coding those that
+// say the token or repository is wrong.
+func responseError(status int, body []byte) error {
+	// Both GitHub and GitLab describe errors in a "message" field
+	var msg struct {
+		Message any `json:"message"`
+	}
+	err := &APIError{StatusCode: status}
+	if json.Unmarshal(body, &msg) == nil && msg.Message != nil {
+		err.Message = fmt.Sprint(msg.Message)
+	}
+
+	switch status {
+	case http.StatusUnauthorized:
+		return errcode.Wrap(errcode.AuthFailed, err)
+	case http.StatusNotFound:
+		return errcode.Wrap(errcode.RemoteNotFound, err)
+	default:
+		return err
+	}
+}
+
+// intHeader returns the integer value of the first of names present in h.
+func intHeader(h http.Header, names ...string) (int, bool) {
+	for _, name := range names {
+		if v := h.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
+
+// IsRateLimited reports whether err is a RateLimitError.
+func IsRateLimited(err error) bool {
+	var rle *RateLimitError
+	return errors.As(err, &rle)
+}
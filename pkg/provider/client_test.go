@@ -0,0 +1,188 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/errcode"
+)
+
+// fakeClock replaces now and sleep: sleeping advances the clock.
+func fakeClock(t *testing.T) *[]time.Duration {
+	t.Helper()
+	clock := time.Unix(1_800_000_000, 0)
+	var slept []time.Duration
+
+	savedNow, savedSleep := now, sleep
+	now = func() time.Time { return clock }
+	sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		clock = clock.Add(d)
+		return nil
+	}
+	t.Cleanup(func() { now, sleep = savedNow, savedSleep })
+	return &slept
+}
+
+func testClient(srv *httptest.Server, opts Options) *client {
+	return newClient("example.com", srv.URL, make(http.Header), opts)
+}
+
+func TestClient_WaitsForExhaustedLimit(t *testing.T) {
+	slept := fakeClock(t)
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		remaining := 2 - n // The second request uses up the limit
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(int(max(remaining, 0))))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(now().Add(30*time.Second).Unix(), 10))
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	c := testClient(srv, Options{})
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := c.do(ctx, http.MethodGet, "/", nil, nil); err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+	}
+	if len(*slept) != 0 {
+		t.Fatalf("expected no wait while requests remain, slept %v", *slept)
+	}
+
+	if err := c.do(ctx, http.MethodGet, "/", nil, nil); err != nil {
+		t.Fatalf("request after reset failed: %v", err)
+	}
+	if len(*slept) != 1 || (*slept)[0] != 30*time.Second {
+		t.Errorf("expected to wait 30s for the reset, slept %v", *slept)
+	}
+}
+
+func TestClient_RetriesRateLimitedResponses(t *testing.T) {
+	slept := fakeClock(t)
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			// Secondary rate limit
+			w.Header().Set("Retry-After", "20")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message": "You have exceeded a secondary rate limit"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"sha": "abc"}`))
+	}))
+	defer srv.Close()
+
+	var out struct{ SHA string }
+	if err := testClient(srv, Options{RetryAttempts: 2}).do(context.Background(), http.MethodGet, "/", nil, &out); err != nil {
+		t.Fatalf("expected retry to succeed: %v", err)
+	}
+	if out.SHA != "abc" {
+		t.Errorf("unexpected response %+v", out)
+	}
+	if len(*slept) != 1 || (*slept)[0] != 20*time.Second {
+		t.Errorf("expected to wait 20s as asked, slept %v", *slept)
+	}
+}
+
+func TestClient_GivesUpOnLongWaits(t *testing.T) {
+	fakeClock(t)
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("RateLimit-Remaining", "0")
+		w.Header().Set("RateLimit-Reset", strconv.FormatInt(now().Add(time.Hour).Unix(), 10))
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	err := testClient(srv, Options{RetryAttempts: 3}).do(context.Background(), http.MethodGet, "/", nil, nil)
+	var rle *RateLimitError
+	if !errors.As(err, &rle) || !IsRateLimited(err) {
+		t.Fatalf("expected RateLimitError, got %v", err)
+	}
+	if !rle.Reset.Equal(now().Add(time.Hour)) {
+		t.Errorf("expected reset in an hour, got %v", rle.Reset)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected no retry past the max wait, got %d requests", n)
+	}
+}
+
+func TestClient_RetriesServerErrors(t *testing.T) {
+	fakeClock(t)
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	if err := testClient(srv, Options{RetryAttempts: 2, RetryDelay: time.Second}).do(context.Background(), http.MethodGet, "/", nil, nil); err != nil {
+		t.Fatalf("expected retries to succeed: %v", err)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestClient_DoesNotRetryPosts(t *testing.T) {
+	fakeClock(t)
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	err := testClient(srv, Options{RetryAttempts: 2, RetryDelay: time.Second}).do(context.Background(), http.MethodPost, "/", map[string]string{"title": "PR"}, nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected the server error, got %v", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected a POST to be sent once, got %d requests", n)
+	}
+}
+
+func TestClient_ErrorCodes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/private":
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message": "Bad credentials"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "Not Found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	c := testClient(srv, Options{RetryAttempts: 3})
+	err := c.do(context.Background(), http.MethodGet, "/private", nil, nil)
+	if errcode.Of(err) != errcode.AuthFailed {
+		t.Errorf("expected %s, got %v", errcode.AuthFailed, err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "Bad credentials" {
+		t.Errorf("expected the API's message, got %v", err)
+	}
+	if err := c.do(context.Background(), http.MethodGet, "/missing", nil, nil); errcode.Of(err) != errcode.RemoteNotFound {
+		t.Errorf("expected %s, got %v", errcode.RemoteNotFound, err)
+	}
+}
@@ -0,0 +1,176 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/errcode"
+)
+
+// gerritXSSIPrefix precedes every JSON response of Gerrit's REST API.
+const gerritXSSIPrefix = ")]}'"
+
+// Change is a change under review on a code review host.
+type Change struct {
+	Number   int
+	Project  string // Project (repository) path on the host
+	Branch   string // Branch the change is for
+	Subject  string
+	Status   string // NEW, MERGED, or ABANDONED
+	Revision string // Commit of the current patch set
+	Patchset int    // Number of the current patch set
+	Ref      string // Ref of the current patch set, refs/changes/...
+}
+
+// Reviewer is implemented by the APIs of code review hosts (Gerrit),
+// where changes are reviewed before they land on a branch.
+type Reviewer interface {
+	// Change looks up a change by its number or Change-Id.
+	Change(ctx context.Context, id string) (*Change, error)
+
+	// CommitMsgHook returns the commit-msg hook the host expects in
+	// clones, which adds a Change-Id to every commit message.
+	CommitMsgHook(ctx context.Context) ([]byte, error)
+}
+
+// gerritAPI is the REST API of a Gerrit server. With a token of the form
+// "user:password" (the HTTP password from the user's settings), requests
+// are authenticated under /a/; otherwise they are anonymous.
+type gerritAPI struct {
+	c    *client
+	auth string // "/a" if authenticated
+
+	mu   sync.Mutex
+	hook []byte
+}
+
+func newGerrit(host config.HostConfig, opts Options) *gerritAPI {
+	baseURL := host.APIURL
+	if baseURL == "" {
+		baseURL = "https://" + host.Name
+	}
+
+	header := make(http.Header)
+	header.Set("Accept", "application/json")
+	g := &gerritAPI{}
+	if user, password, ok := strings.Cut(host.Token, ":"); ok {
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user+":"+password)))
+		g.auth = "/a"
+	}
+	g.c = newClient(host.Name, baseURL, header, opts)
+	g.c.prefix = []byte(gerritXSSIPrefix)
+	return g
+}
+
+// Provider returns "gerrit".
+func (g *gerritAPI) Provider() string {
+	return config.ProviderGerrit
+}
+
+// project returns the API path of a project. Authenticated clone URLs
+// carry an /a/ prefix that is not part of the project's name.
+func (g *gerritAPI) project(repo string) string {
+	return g.auth + "/projects/" + url.PathEscape(strings.TrimPrefix(repo, "a/"))
+}
+
+// LatestRelease fails: Gerrit has no releases.
+func (g *gerritAPI) LatestRelease(ctx context.Context, repo string, prereleases bool) (*Release, error) {
+	return nil, errcode.Wrap(errcode.RefNotFound, fmt.Errorf("%s is on Gerrit, which has no releases (use a version constraint instead)", repo))
+}
+
+// RefSHA returns the commit a branch or tag points at.
+func (g *gerritAPI) RefSHA(ctx context.Context, repo, ref string) (string, error) {
+	var branch struct {
+		Revision string `json:"revision"`
+	}
+	err := g.c.do(ctx, http.MethodGet, g.project(repo)+"/branches/"+url.PathEscape(strings.TrimPrefix(ref, "refs/heads/")), nil, &branch)
+	if err == nil {
+		return branch.Revision, nil
+	}
+	if errcode.Of(err) != errcode.RemoteNotFound {
+		return "", err
+	}
+
+	var tag struct {
+		Revision string `json:"revision"`
+		Object   string `json:"object"` // Commit of an annotated tag
+	}
+	if err := g.c.do(ctx, http.MethodGet, g.project(repo)+"/tags/"+url.PathEscape(strings.TrimPrefix(ref, "refs/tags/")), nil, &tag); err != nil {
+		if errcode.Of(err) == errcode.RemoteNotFound {
+			return "", errcode.Wrap(errcode.RefNotFound, fmt.Errorf("%s has no branch or tag %s", repo, ref))
+		}
+		return "", err
+	}
+	if tag.Object != "" {
+		return tag.Object, nil
+	}
+	return tag.Revision, nil
+}
+
+// CreatePullRequest fails: Gerrit reviews commits pushed to
+// refs/for/<branch> rather than pull requests.
+func (g *gerritAPI) CreatePullRequest(ctx context.Context, repo string, pr PullRequest) (string, error) {
+	return "", fmt.Errorf("%s is on Gerrit: push %s to refs/for/%s for review instead of opening a pull request", repo, pr.Head, pr.Base)
+}
+
+// Change looks up a change by its number, Change-Id, or project~number,
+// with its current patch set.
+func (g *gerritAPI) Change(ctx context.Context, id string) (*Change, error) {
+	var resp struct {
+		Project         string `json:"project"`
+		Branch          string `json:"branch"`
+		Subject         string `json:"subject"`
+		Status          string `json:"status"`
+		Number          int    `json:"_number"`
+		CurrentRevision string `json:"current_revision"`
+		Revisions       map[string]struct {
+			Number int    `json:"_number"`
+			Ref    string `json:"ref"`
+		} `json:"revisions"`
+	}
+	path := g.auth + "/changes/" + url.PathEscape(id) + "?o=CURRENT_REVISION"
+	if err := g.c.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		if errcode.Of(err) == errcode.RemoteNotFound {
+			return nil, errcode.Wrap(errcode.RefNotFound, fmt.Errorf("change %s not found", id))
+		}
+		return nil, err
+	}
+	rev, ok := resp.Revisions[resp.CurrentRevision]
+	if !ok {
+		return nil, fmt.Errorf("change %s has no current patch set", id)
+	}
+	return &Change{
+		Number:   resp.Number,
+		Project:  resp.Project,
+		Branch:   resp.Branch,
+		Subject:  resp.Subject,
+		Status:   resp.Status,
+		Revision: resp.CurrentRevision,
+		Patchset: rev.Number,
+		Ref:      rev.Ref,
+	}, nil
+}
+
+// CommitMsgHook returns the server's commit-msg hook, fetched once.
+func (g *gerritAPI) CommitMsgHook(ctx context.Context) ([]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.hook != nil {
+		return g.hook, nil
+	}
+	var hook []byte
+	if err := g.c.do(ctx, http.MethodGet, "/tools/hooks/commit-msg", nil, &hook); err != nil {
+		return nil, fmt.Errorf("failed to download commit-msg hook: %w", err)
+	}
+	if !strings.HasPrefix(string(hook), "#!") {
+		return nil, fmt.Errorf("failed to download commit-msg hook: not a script")
+	}
+	g.hook = hook
+	return hook, nil
+}
@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/errcode"
+)
+
+// giteaAPI is the API of Gitea and of Forgejo, its fork, which serve
+// the same API.
+type giteaAPI struct {
+	c *client
+}
+
+func newGitea(host config.HostConfig, opts Options) *giteaAPI {
+	baseURL := host.APIURL
+	if baseURL == "" {
+		baseURL = "https://" + host.Name + "/api/v1"
+	}
+
+	header := make(http.Header)
+	header.Set("Accept", "application/json")
+	if host.Token != "" {
+		header.Set("Authorization", "token "+host.Token)
+	}
+	return &giteaAPI{c: newClient(host.Name, baseURL, header, opts)}
+}
+
+// Provider returns "gitea", for Forgejo hosts too.
+func (g *giteaAPI) Provider() string {
+	return config.ProviderGitea
+}
+
+// LatestRelease returns the latest published release. As on GitHub,
+// the latest release is never a prerelease, so with prereleases the
+// newest published release is used instead. Releases have the same
+// fields as GitHub's.
+func (g *giteaAPI) LatestRelease(ctx context.Context, repo string, prereleases bool) (*Release, error) {
+	if !prereleases {
+		var resp githubRelease
+		if err := g.c.do(ctx, http.MethodGet, "/repos/"+repo+"/releases/latest", nil, &resp); err != nil {
+			if errcode.Of(err) == errcode.RemoteNotFound {
+				return nil, errcode.Wrap(errcode.RefNotFound, fmt.Errorf("%s has no releases", repo))
+			}
+			return nil, err
+		}
+		return resp.release(), nil
+	}
+
+	var resp []githubRelease
+	if err := g.c.do(ctx, http.MethodGet, "/repos/"+repo+"/releases?draft=false&limit=20", nil, &resp); err != nil {
+		return nil, err
+	}
+	for _, r := range resp {
+		if !r.Draft {
+			return r.release(), nil
+		}
+	}
+	return nil, errcode.Wrap(errcode.RefNotFound, fmt.Errorf("%s has no releases", repo))
+}
+
+// RefSHA returns the commit a branch or tag points at, as the first
+// commit of its history.
+func (g *giteaAPI) RefSHA(ctx context.Context, repo, ref string) (string, error) {
+	var resp []struct {
+		SHA string `json:"sha"`
+	}
+	path := "/repos/" + repo + "/commits?limit=1&stat=false&verification=false&files=false&sha=" + url.QueryEscape(ref)
+	if err := g.c.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return "", err
+	}
+	if len(resp) == 0 {
+		return "", errcode.Wrap(errcode.RefNotFound, fmt.Errorf("%s has no ref %s", repo, ref))
+	}
+	return resp[0].SHA, nil
+}
+
+// CreatePullRequest opens a pull request and returns its web URL.
+func (g *giteaAPI) CreatePullRequest(ctx context.Context, repo string, pr PullRequest) (string, error) {
+	req := struct {
+		Title string `json:"title"`
+		Body  string `json:"body,omitempty"`
+		Head  string `json:"head"`
+		Base  string `json:"base"`
+	}{pr.Title, pr.Body, pr.Head, pr.Base}
+	var resp struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := g.c.do(ctx, http.MethodPost, "/repos/"+repo+"/pulls", req, &resp); err != nil {
+		return "", err
+	}
+	return resp.HTMLURL, nil
+}
+
+// giteaPageSize is the number of items requested per page of a listing,
+// Gitea's default maximum. Servers may be configured to return fewer.
+const giteaPageSize = 50
+
+// ListRepositories returns every repository of an organization, or of a
+// user if there is no organization of that name.
+func (g *giteaAPI) ListRepositories(ctx context.Context, org string) ([]RemoteRepository, error) {
+	repos, err := g.listRepositories(ctx, "/orgs/"+url.PathEscape(org)+"/repos")
+	if errcode.Of(err) == errcode.RemoteNotFound {
+		repos, err = g.listRepositories(ctx, "/users/"+url.PathEscape(org)+"/repos")
+	}
+	return repos, err
+}
+
+// listRepositories follows the pages of a repository listing until an
+// empty one, as a page may be shorter than requested.
+func (g *giteaAPI) listRepositories(ctx context.Context, path string) ([]RemoteRepository, error) {
+	var repos []RemoteRepository
+	for page := 1; ; page++ {
+		var resp []struct {
+			Name     string `json:"name"`
+			CloneURL string `json:"clone_url"`
+			SSHURL   string `json:"ssh_url"`
+			Archived bool   `json:"archived"`
+		}
+		if err := g.c.do(ctx, http.MethodGet, fmt.Sprintf("%s?limit=%d&page=%d", path, giteaPageSize, page), nil, &resp); err != nil {
+			return nil, err
+		}
+		for _, r := range resp {
+			repos = append(repos, RemoteRepository{Name: r.Name, CloneURL: r.CloneURL, SSHURL: r.SSHURL, Archived: r.Archived})
+		}
+		if len(resp) == 0 {
+			return repos, nil
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/errcode"
+)
+
+// githubAPI is the API of github.com and GitHub Enterprise Server.
+type githubAPI struct {
+	c *client
+}
+
+func newGitHub(host config.HostConfig, opts Options) *githubAPI {
+	baseURL := host.APIURL
+	if baseURL == "" {
+		if host.Name == "github.com" {
+			baseURL = "https://api.github.com"
+		} else {
+			baseURL = "https://" + host.Name + "/api/v3"
+		}
+	}
+
+	header := make(http.Header)
+	header.Set("Accept", "application/vnd.github+json")
+	header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if host.Token != "" {
+		header.Set("Authorization", "Bearer "+host.Token)
+	}
+	return &githubAPI{c: newClient(host.Name, baseURL, header, opts)}
+}
+
+// Provider returns "github".
+func (g *githubAPI) Provider() string {
+	return config.ProviderGitHub
+}
+
+// githubRelease is a release as returned by the API.
+type githubRelease struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	PublishedAt time.Time `json:"published_at"`
+	HTMLURL     string    `json:"html_url"`
+	Draft       bool      `json:"draft"`
+	Prerelease  bool      `json:"prerelease"`
+}
+
+func (r githubRelease) release() *Release {
+	return &Release{Tag: r.TagName, Name: r.Name, PublishedAt: r.PublishedAt, URL: r.HTMLURL, Prerelease: r.Prerelease}
+}
+
+// LatestRelease returns the latest published release. GitHub's latest
+// release is never a prerelease, so with prereleases the newest published
+// release is used instead.
+func (g *githubAPI) LatestRelease(ctx context.Context, repo string, prereleases bool) (*Release, error) {
+	if !prereleases {
+		var resp githubRelease
+		if err := g.c.do(ctx, http.MethodGet, "/repos/"+repo+"/releases/latest", nil, &resp); err != nil {
+			return nil, err
+		}
+		return resp.release(), nil
+	}
+
+	// Releases are listed newest first; drafts are only visible with
+	// push access and have no tag yet
+	var resp []githubRelease
+	if err := g.c.do(ctx, http.MethodGet, "/repos/"+repo+"/releases?per_page=20", nil, &resp); err != nil {
+		return nil, err
+	}
+	for _, r := range resp {
+		if !r.Draft {
+			return r.release(), nil
+		}
+	}
+	return nil, errcode.Wrap(errcode.RefNotFound, fmt.Errorf("%s has no releases", repo))
+}
+
+// RefSHA returns the commit a branch or tag points at.
+func (g *githubAPI) RefSHA(ctx context.Context, repo, ref string) (string, error) {
+	var resp struct {
+		SHA string `json:"sha"`
+	}
+	if err := g.c.do(ctx, http.MethodGet, "/repos/"+repo+"/commits/"+url.PathEscape(ref), nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.SHA, nil
+}
+
+// CreatePullRequest opens a pull request and returns its web URL.
+func (g *githubAPI) CreatePullRequest(ctx context.Context, repo string, pr PullRequest) (string, error) {
+	req := struct {
+		Title string `json:"title"`
+		Body  string `json:"body,omitempty"`
+		Head  string `json:"head"`
+		Base  string `json:"base"`
+	}{pr.Title, pr.Body, pr.Head, pr.Base}
+	var resp struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := g.c.do(ctx, http.MethodPost, "/repos/"+repo+"/pulls", req, &resp); err != nil {
+		return "", err
+	}
+	return resp.HTMLURL, nil
+}
+
+// githubPageSize is the number of items requested per page of a listing.
+const githubPageSize = 100
+
+// ListRepositories returns every repository of an organization, or of a
+// user if there is no organization of that name.
+func (g *githubAPI) ListRepositories(ctx context.Context, org string) ([]RemoteRepository, error) {
+	repos, err := g.listRepositories(ctx, "/orgs/"+url.PathEscape(org)+"/repos")
+	if errcode.Of(err) == errcode.RemoteNotFound {
+		repos, err = g.listRepositories(ctx, "/users/"+url.PathEscape(org)+"/repos")
+	}
+	return repos, err
+}
+
+// listRepositories follows the pages of a repository listing.
+func (g *githubAPI) listRepositories(ctx context.Context, path string) ([]RemoteRepository, error) {
+	var repos []RemoteRepository
+	for page := 1; ; page++ {
+		var resp []struct {
+			Name     string `json:"name"`
+			CloneURL string `json:"clone_url"`
+			SSHURL   string `json:"ssh_url"`
+			Archived bool   `json:"archived"`
+		}
+		if err := g.c.do(ctx, http.MethodGet, fmt.Sprintf("%s?per_page=%d&page=%d", path, githubPageSize, page), nil, &resp); err != nil {
+			return nil, err
+		}
+		for _, r := range resp {
+			repos = append(repos, RemoteRepository{Name: r.Name, CloneURL: r.CloneURL, SSHURL: r.SSHURL, Archived: r.Archived})
+		}
+		if len(resp) < githubPageSize {
+			return repos, nil
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/semver"
+)
+
+// gitlabAPI is the API of gitlab.com and self-managed GitLab.
+type gitlabAPI struct {
+	c *client
+}
+
+func newGitLab(host config.HostConfig, opts Options) *gitlabAPI {
+	baseURL := host.APIURL
+	if baseURL == "" {
+		baseURL = "https://" + host.Name + "/api/v4"
+	}
+
+	header := make(http.Header)
+	header.Set("Accept", "application/json")
+	if host.Token != "" {
+		header.Set("PRIVATE-TOKEN", host.Token)
+	}
+	return &gitlabAPI{c: newClient(host.Name, baseURL, header, opts)}
+}
+
+// Provider returns "gitlab".
+func (g *gitlabAPI) Provider() string {
+	return config.ProviderGitLab
+}
+
+// project returns the API path of a project, which is addressed by its
+// URL-encoded path.
+func (g *gitlabAPI) project(repo string) string {
+	return "/projects/" + url.PathEscape(repo)
+}
+
+// LatestRelease returns the most recently released release. GitLab has
+// no prerelease flag, so upcoming releases and those whose tag is a
+// semver prerelease (v2.0.0-rc.1) count as prereleases.
+func (g *gitlabAPI) LatestRelease(ctx context.Context, repo string, prereleases bool) (*Release, error) {
+	var resp []struct {
+		TagName         string    `json:"tag_name"`
+		Name            string    `json:"name"`
+		ReleasedAt      time.Time `json:"released_at"`
+		UpcomingRelease bool      `json:"upcoming_release"`
+		Links           struct {
+			Self string `json:"self"`
+		} `json:"_links"`
+	}
+	path := g.project(repo) + "/releases?order_by=released_at&sort=desc&per_page=20"
+	if err := g.c.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	for _, r := range resp {
+		v, err := semver.Parse(r.TagName)
+		pre := r.UpcomingRelease || (err == nil && v.Prerelease != "")
+		if pre && !prereleases {
+			continue
+		}
+		return &Release{Tag: r.TagName, Name: r.Name, PublishedAt: r.ReleasedAt, URL: r.Links.Self, Prerelease: pre}, nil
+	}
+	return nil, errcode.Wrap(errcode.RefNotFound, fmt.Errorf("%s has no releases", repo))
+}
+
+// RefSHA returns the commit a branch or tag points at.
+func (g *gitlabAPI) RefSHA(ctx context.Context, repo, ref string) (string, error) {
+	var resp struct {
+		ID string `json:"id"`
+	}
+	if err := g.c.do(ctx, http.MethodGet, g.project(repo)+"/repository/commits/"+url.PathEscape(ref), nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// CreatePullRequest opens a merge request and returns its web URL.
+func (g *gitlabAPI) CreatePullRequest(ctx context.Context, repo string, pr PullRequest) (string, error) {
+	req := struct {
+		SourceBranch string `json:"source_branch"`
+		TargetBranch string `json:"target_branch"`
+		Title        string `json:"title"`
+		Description  string `json:"description,omitempty"`
+	}{pr.Head, pr.Base, pr.Title, pr.Body}
+	var resp struct {
+		WebURL string `json:"web_url"`
+	}
+	if err := g.c.do(ctx, http.MethodPost, g.project(repo)+"/merge_requests", req, &resp); err != nil {
+		return "", err
+	}
+	return resp.WebURL, nil
+}
+
+// gitlabPageSize is the number of items requested per page of a listing.
+const gitlabPageSize = 100
+
+// ListRepositories returns every project of a group and its subgroups,
+// or of a user if there is no group of that path. Projects are named by
+// their path below the group, such as "backend/api".
+func (g *gitlabAPI) ListRepositories(ctx context.Context, group string) ([]RemoteRepository, error) {
+	repos, err := g.listRepositories(ctx, "/groups/"+url.PathEscape(group)+"/projects", group, url.Values{"include_subgroups": {"true"}})
+	if errcode.Of(err) == errcode.RemoteNotFound && !strings.Contains(group, "/") {
+		repos, err = g.listRepositories(ctx, "/users/"+url.PathEscape(group)+"/projects", group, url.Values{})
+	}
+	return repos, err
+}
+
+// listRepositories follows the pages of a project listing, naming the
+// projects relative to group.
+func (g *gitlabAPI) listRepositories(ctx context.Context, path, group string, query url.Values) ([]RemoteRepository, error) {
+	query.Set("per_page", strconv.Itoa(gitlabPageSize))
+	var repos []RemoteRepository
+	for page := 1; ; page++ {
+		var resp []struct {
+			PathWithNamespace string `json:"path_with_namespace"`
+			HTTPURL           string `json:"http_url_to_repo"`
+			SSHURL            string `json:"ssh_url_to_repo"`
+			Archived          bool   `json:"archived"`
+		}
+		query.Set("page", strconv.Itoa(page))
+		if err := g.c.do(ctx, http.MethodGet, path+"?"+query.Encode(), nil, &resp); err != nil {
+			return nil, err
+		}
+		for _, r := range resp {
+			name := r.PathWithNamespace
+			if len(name) > len(group)+1 && strings.EqualFold(name[:len(group)+1], group+"/") {
+				name = name[len(group)+1:]
+			}
+			repos = append(repos, RemoteRepository{Name: name, CloneURL: r.HTTPURL, SSHURL: r.SSHURL, Archived: r.Archived})
+		}
+		if len(resp) < gitlabPageSize {
+			return repos, nil
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Package provider talks to the APIs of git hosting services such as
+// GitHub, GitLab, Gitea (and Forgejo), and Gerrit, for lookups that git itself cannot do:
+// releases, the commit a ref points at without a clone, opening pull
+// requests, and finding changes under review.
+//
+// All calls go through a client per host that shares the host's token,
+// from the config or the keychain, and honors its rate limits.
+package provider
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/keychain"
+)
+
+// DefaultTimeout is the default timeout of an API request.
+const DefaultTimeout = 30 * time.Second
+
+// DefaultMaxWait is the longest a request waits by default for a rate
+// limit to reset.
+const DefaultMaxWait = 5 * time.Minute
+
+// Release is a published release of a repository.
+type Release struct {
+	Tag         string
+	Name        string
+	PublishedAt time.Time
+	URL         string // Web page of the release
+	Prerelease  bool
+}
+
+// PullRequest describes a pull request (merge request on GitLab) to
+// open.
+type PullRequest struct {
+	Title string
+	Body  string
+	Head  string // Branch with the changes
+	Base  string // Branch to merge into
+}
+
+// API is the interface to a hosting service. Repositories are named by
+// their path on the host, such as "owner/repo".
+type API interface {
+	// LatestRelease returns the most recent release of the repository,
+	// skipping prereleases unless prereleases is true.
+	LatestRelease(ctx context.Context, repo string, prereleases bool) (*Release, error)
+
+	// RefSHA returns the commit a branch or tag points at.
+	RefSHA(ctx context.Context, repo, ref string) (string, error)
+
+	// CreatePullRequest opens a pull request and returns its web URL.
+	CreatePullRequest(ctx context.Context, repo string, pr PullRequest) (string, error)
+
+	// Provider returns the provider kind (github, gitlab, gitea, gerrit).
+	Provider() string
+}
+
+// RemoteRepository is a repository as listed by a hosting service.
+type RemoteRepository struct {
+	Name     string
+	CloneURL string // HTTPS clone URL
+	SSHURL   string // SSH clone URL
+	Archived bool
+}
+
+// RepositoryLister is implemented by APIs that can list the repositories
+// of an organization or group.
+type RepositoryLister interface {
+	// ListRepositories returns every repository of the organization,
+	// or GitLab group with its subgroups, visible with the client's
+	// token.
+	ListRepositories(ctx context.Context, org string) ([]RemoteRepository, error)
+}
+
+// Options configures API clients.
+type Options struct {
+	UserAgent     string
+	RetryAttempts int           // Retries of server errors and rate-limited requests
+	RetryDelay    time.Duration // Delay before the first retry of a server error; doubles on each one
+	MaxWait       time.Duration // Longest wait for a rate limit to reset; 0 uses DefaultMaxWait
+	Timeout       time.Duration // Timeout of each request; 0 uses DefaultTimeout
+	Logger        *slog.Logger  // Diagnostic log; nil discards
+
+	// Keychain supplies the tokens of hosts that have none in the config;
+	// nil uses none.
+	Keychain *keychain.Cache
+}
+
+// OptionsFromConfig returns the client options for a workspace.
+func OptionsFromConfig(cfg *config.Config) Options {
+	return Options{
+		UserAgent:     cfg.HTTP.UserAgent,
+		RetryAttempts: cfg.HTTP.RetryAttempts,
+		RetryDelay:    cfg.HTTP.RetryDelay,
+	}
+}
+
+// New creates the API client of a host.
+func New(host config.HostConfig, opts Options) (API, error) {
+	switch host.Provider {
+	case config.ProviderGitHub:
+		return newGitHub(host, opts), nil
+	case config.ProviderGitLab:
+		return newGitLab(host, opts), nil
+	case config.ProviderGerrit:
+		return newGerrit(host, opts), nil
+	case config.ProviderGitea, config.ProviderForgejo:
+		return newGitea(host, opts), nil
+	default:
+		return nil, fmt.Errorf("unsupported provider for host %s: %q", host.Name, host.Provider)
+	}
+}
+
+// Clients hands out one API client per host, so that all repositories
+// on a host share its token and rate limit.
+type Clients struct {
+	cfg  *config.Config
+	opts Options
+
+	mu      sync.Mutex
+	clients map[string]API
+}
+
+// NewClients creates the clients of the hosts configured in cfg.
+func NewClients(cfg *config.Config, opts Options) *Clients {
+	return &Clients{cfg: cfg, opts: opts, clients: make(map[string]API)}
+}
+
+// ForURL returns the client of the host serving a repository URL, and the
+// repository's path on that host.
+func (c *Clients) ForURL(repoURL string) (API, string, error) {
+	hostName, repo, err := ParseRepoURL(repoURL)
+	if err != nil {
+		return nil, "", err
+	}
+	api, err := c.ForHost(hostName)
+	if err != nil {
+		return nil, "", err
+	}
+	return api, repo, nil
+}
+
+// ForHost returns the client of the named host.
+func (c *Clients) ForHost(hostName string) (API, error) {
+	hostName = strings.ToLower(hostName)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if api, ok := c.clients[hostName]; ok {
+		return api, nil
+	}
+	host, ok := c.cfg.Host(hostName)
+	if !ok {
+		return nil, fmt.Errorf("no provider configured for host %s", hostName)
+	}
+	if host.Token == "" && c.opts.Keychain != nil {
+		host.Token = c.opts.Keychain.Token(hostName)
+	}
+	api, err := New(host, c.opts)
+	if err != nil {
+		return nil, err
+	}
+	c.clients[hostName] = api
+	return api, nil
+}
+
+// ParseRepoURL splits a repository URL into the host and the path of the
+// repository on it, without a .git suffix. HTTP(S), ssh:// and scp-like
+// (git@host:owner/repo) URLs are understood.
+func ParseRepoURL(repoURL string) (host, repo string, err error) {
+	if !strings.Contains(repoURL, "://") {
+		// scp-like syntax: [user@]host:path
+		if at := strings.Index(repoURL, "@"); at >= 0 {
+			repoURL = repoURL[at+1:]
+		}
+		var ok bool
+		host, repo, ok = strings.Cut(repoURL, ":")
+		if !ok {
+			return "", "", fmt.Errorf("not a remote repository URL: %s", repoURL)
+		}
+	} else {
+		u, err := url.Parse(repoURL)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid repository URL: %w", err)
+		}
+		host, repo = u.Hostname(), u.Path
+	}
+
+	repo = strings.TrimSuffix(strings.Trim(repo, "/"), ".git")
+	if host == "" || repo == "" {
+		return "", "", fmt.Errorf("not a remote repository URL: %s", repoURL)
+	}
+	return strings.ToLower(host), repo, nil
+}
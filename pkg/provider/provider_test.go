@@ -0,0 +1,371 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/errcode"
+)
+
+func TestParseRepoURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		host string
+		repo string
+	}{
+		{"https://github.com/owner/repo.git", "github.com", "owner/repo"},
+		{"https://GitHub.com/owner/repo/", "github.com", "owner/repo"},
+		{"git@github.com:owner/repo.git", "github.com", "owner/repo"},
+		{"ssh://git@gitlab.example.com:2222/group/sub/repo.git", "gitlab.example.com", "group/sub/repo"},
+		{"gitlab.com:group/repo", "gitlab.com", "group/repo"},
+	}
+	for _, tt := range tests {
+		host, repo, err := ParseRepoURL(tt.url)
+		if err != nil {
+			t.Errorf("ParseRepoURL(%q) failed: %v", tt.url, err)
+			continue
+		}
+		if host != tt.host || repo != tt.repo {
+			t.Errorf("ParseRepoURL(%q) = %q, %q, want %q, %q", tt.url, host, repo, tt.host, tt.repo)
+		}
+	}
+
+	for _, bad := range []string{"/srv/git/repo", "file:///srv/git/repo", "https://github.com/"} {
+		if _, _, err := ParseRepoURL(bad); err == nil {
+			t.Errorf("ParseRepoURL(%q): expected error", bad)
+		}
+	}
+}
+
+func TestClients_ForURL(t *testing.T) {
+	cfg := &config.Config{
+		Hosts: []config.HostConfig{
+			{Name: "git.example.com", Provider: config.ProviderGitLab, Token: "secret"},
+		},
+	}
+	clients := NewClients(cfg, Options{})
+
+	api, repo, err := clients.ForURL("https://git.example.com/group/repo.git")
+	if err != nil {
+		t.Fatalf("ForURL failed: %v", err)
+	}
+	if api.Provider() != config.ProviderGitLab || repo != "group/repo" {
+		t.Errorf("unexpected client %s for %s", api.Provider(), repo)
+	}
+	if got := api.(*gitlabAPI).c.header.Get("PRIVATE-TOKEN"); got != "secret" {
+		t.Errorf("expected the host's token to be sent, got %q", got)
+	}
+
+	// Repositories on the same host share the client
+	other, _, _ := clients.ForURL("git@git.example.com:group/other.git")
+	if other != api {
+		t.Error("expected one client per host")
+	}
+
+	// Well-known hosts work without configuration
+	if api, _, err := clients.ForURL("https://github.com/owner/repo"); err != nil || api.Provider() != config.ProviderGitHub {
+		t.Errorf("expected a GitHub client, got %v, %v", api, err)
+	}
+	if _, _, err := clients.ForURL("https://unknown.example.com/owner/repo"); err == nil {
+		t.Error("expected error for a host without a provider")
+	}
+}
+
+func TestGitHub(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.EscapedPath() {
+		case "GET /repos/owner/repo/releases/latest":
+			_, _ = w.Write([]byte(`{"tag_name": "v1.2.0", "name": "1.2", "published_at": "2026-01-02T03:04:05Z"}`))
+		case "GET /repos/owner/repo/releases":
+			_, _ = w.Write([]byte(`[{"tag_name": "v1.4.0", "draft": true}, {"tag_name": "v1.3.0-rc.1", "prerelease": true}, {"tag_name": "v1.2.0"}]`))
+		case "GET /repos/owner/repo/commits/release%2F1.x":
+			_, _ = w.Write([]byte(`{"sha": "abc123"}`))
+		case "POST /repos/owner/repo/pulls":
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["head"] != "update" || body["base"] != "main" {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				return
+			}
+			_, _ = w.Write([]byte(`{"html_url": "https://github.com/owner/repo/pull/7"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	api, err := New(config.HostConfig{Name: "github.com", Provider: config.ProviderGitHub, APIURL: srv.URL, Token: "token"}, Options{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ctx := context.Background()
+
+	release, err := api.LatestRelease(ctx, "owner/repo", false)
+	if err != nil || release.Tag != "v1.2.0" || release.PublishedAt.Year() != 2026 {
+		t.Errorf("unexpected release %+v, %v", release, err)
+	}
+	release, err = api.LatestRelease(ctx, "owner/repo", true)
+	if err != nil || release.Tag != "v1.3.0-rc.1" || !release.Prerelease {
+		t.Errorf("expected the prerelease, skipping the draft, got %+v, %v", release, err)
+	}
+	if sha, err := api.RefSHA(ctx, "owner/repo", "release/1.x"); err != nil || sha != "abc123" {
+		t.Errorf("expected abc123, got %q, %v", sha, err)
+	}
+	url, err := api.CreatePullRequest(ctx, "owner/repo", PullRequest{Title: "Update", Head: "update", Base: "main"})
+	if err != nil || url != "https://github.com/owner/repo/pull/7" {
+		t.Errorf("unexpected pull request %q, %v", url, err)
+	}
+}
+
+func TestGitHub_ListRepositories(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/someone/repos" {
+			// Not an organization: the listing falls back to the user
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var page []map[string]any
+		switch r.URL.Query().Get("page") {
+		case "1":
+			for i := 0; i < githubPageSize; i++ {
+				page = append(page, map[string]any{"name": "repo", "clone_url": "https://github.com/someone/repo.git"})
+			}
+		case "2":
+			page = append(page, map[string]any{"name": "last", "clone_url": "https://github.com/someone/last.git", "ssh_url": "git@github.com:someone/last.git", "archived": true})
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+	defer srv.Close()
+
+	api, err := New(config.HostConfig{Name: "github.com", Provider: config.ProviderGitHub, APIURL: srv.URL}, Options{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	repos, err := api.(RepositoryLister).ListRepositories(context.Background(), "someone")
+	if err != nil {
+		t.Fatalf("ListRepositories failed: %v", err)
+	}
+	if len(repos) != githubPageSize+1 {
+		t.Fatalf("expected both pages, got %d repositories", len(repos))
+	}
+	last := repos[len(repos)-1]
+	if last.Name != "last" || last.SSHURL != "git@github.com:someone/last.git" || !last.Archived {
+		t.Errorf("unexpected repository %+v", last)
+	}
+}
+
+func TestGitLab(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.EscapedPath() {
+		case "GET /projects/group%2Frepo/releases":
+			_, _ = w.Write([]byte(`[{"tag_name": "v2.1.0", "upcoming_release": true}, {"tag_name": "v2.1.0-rc.1"}, {"tag_name": "v2.0.0", "released_at": "2026-03-01T00:00:00Z"}]`))
+		case "GET /projects/group%2Fempty/releases":
+			_, _ = w.Write([]byte(`[]`))
+		case "GET /projects/group%2Frepo/repository/commits/main":
+			_, _ = w.Write([]byte(`{"id": "def456"}`))
+		case "POST /projects/group%2Frepo/merge_requests":
+			_, _ = w.Write([]byte(`{"web_url": "https://gitlab.com/group/repo/-/merge_requests/3"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	api, _ := New(config.HostConfig{Name: "gitlab.com", Provider: config.ProviderGitLab, APIURL: srv.URL}, Options{})
+	ctx := context.Background()
+
+	if release, err := api.LatestRelease(ctx, "group/repo", false); err != nil || release.Tag != "v2.0.0" {
+		t.Errorf("unexpected release %+v, %v", release, err)
+	}
+	if release, err := api.LatestRelease(ctx, "group/repo", true); err != nil || release.Tag != "v2.1.0" || !release.Prerelease {
+		t.Errorf("expected the upcoming release, got %+v, %v", release, err)
+	}
+	if _, err := api.LatestRelease(ctx, "group/empty", false); err == nil {
+		t.Error("expected error for a project without releases")
+	}
+	if sha, err := api.RefSHA(ctx, "group/repo", "main"); err != nil || sha != "def456" {
+		t.Errorf("expected def456, got %q, %v", sha, err)
+	}
+	if url, err := api.CreatePullRequest(ctx, "group/repo", PullRequest{Title: "Update", Head: "update", Base: "main"}); err != nil || url == "" {
+		t.Errorf("unexpected merge request %q, %v", url, err)
+	}
+}
+
+func TestGitLab_ListRepositories(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/groups/acme%2Fplatform/projects" || r.URL.Query().Get("include_subgroups") != "true" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var page []map[string]any
+		switch r.URL.Query().Get("page") {
+		case "1":
+			for i := 0; i < gitlabPageSize; i++ {
+				page = append(page, map[string]any{"path_with_namespace": "acme/platform/api", "http_url_to_repo": "https://gitlab.com/acme/platform/api.git"})
+			}
+		case "2":
+			page = append(page, map[string]any{
+				"path_with_namespace": "Acme/Platform/backend/db",
+				"http_url_to_repo":    "https://gitlab.com/acme/platform/backend/db.git",
+				"ssh_url_to_repo":     "git@gitlab.com:acme/platform/backend/db.git",
+				"archived":            true,
+			})
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+	defer srv.Close()
+
+	api, err := New(config.HostConfig{Name: "gitlab.com", Provider: config.ProviderGitLab, APIURL: srv.URL}, Options{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	repos, err := api.(RepositoryLister).ListRepositories(context.Background(), "acme/platform")
+	if err != nil {
+		t.Fatalf("ListRepositories failed: %v", err)
+	}
+	if len(repos) != gitlabPageSize+1 {
+		t.Fatalf("expected both pages, got %d repositories", len(repos))
+	}
+	if repos[0].Name != "api" {
+		t.Errorf("expected names relative to the group, got %q", repos[0].Name)
+	}
+	last := repos[len(repos)-1]
+	if last.Name != "backend/db" || last.SSHURL != "git@gitlab.com:acme/platform/backend/db.git" || !last.Archived {
+		t.Errorf("unexpected repository %+v", last)
+	}
+
+	// Subgroup paths don't fall back to users
+	if _, err := api.(RepositoryLister).ListRepositories(context.Background(), "acme/missing"); err == nil {
+		t.Error("expected error for a missing group")
+	}
+}
+
+func TestGerrit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, _ := r.BasicAuth(); user != "jane" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.EscapedPath() {
+		case "/a/changes/1234":
+			_, _ = w.Write([]byte(`)]}'
+{"project": "team/app", "branch": "main", "subject": "Fix", "status": "NEW", "_number": 1234, "current_revision": "abc",
+ "revisions": {"abc": {"_number": 3, "ref": "refs/changes/34/1234/3"}}}`))
+		case "/a/projects/team%2Fapp/branches/main":
+			_, _ = w.Write([]byte(")]}'\n{\"revision\": \"def\"}"))
+		case "/a/projects/team%2Fapp/tags/v1.0":
+			_, _ = w.Write([]byte(")]}'\n{\"revision\": \"tagobject\", \"object\": \"commit\"}"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	api, err := New(config.HostConfig{Name: "review.example.com", Provider: config.ProviderGerrit, APIURL: srv.URL, Token: "jane:secret"}, Options{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ctx := context.Background()
+
+	change, err := api.(Reviewer).Change(ctx, "1234")
+	if err != nil || change.Project != "team/app" || change.Patchset != 3 || change.Ref != "refs/changes/34/1234/3" {
+		t.Errorf("unexpected change %+v, %v", change, err)
+	}
+	if _, err := api.(Reviewer).Change(ctx, "999"); errcode.Of(err) != errcode.RefNotFound {
+		t.Errorf("expected a missing change to be RefNotFound, got %v", err)
+	}
+
+	// Authenticated clone URLs name the project under /a/
+	if sha, err := api.RefSHA(ctx, "a/team/app", "main"); err != nil || sha != "def" {
+		t.Errorf("expected def, got %q, %v", sha, err)
+	}
+	if sha, err := api.RefSHA(ctx, "team/app", "v1.0"); err != nil || sha != "commit" {
+		t.Errorf("expected the tagged commit, got %q, %v", sha, err)
+	}
+	if _, err := api.RefSHA(ctx, "team/app", "missing"); errcode.Of(err) != errcode.RefNotFound {
+		t.Errorf("expected a missing ref to be RefNotFound, got %v", err)
+	}
+	if _, err := api.CreatePullRequest(ctx, "team/app", PullRequest{Head: "update", Base: "main"}); err == nil {
+		t.Error("expected pull requests to be refused")
+	}
+}
+
+func TestGitea(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/owner/repo/releases/latest":
+			_, _ = w.Write([]byte(`{"tag_name": "v1.2.0", "name": "1.2", "published_at": "2026-01-02T03:04:05Z"}`))
+		case "GET /repos/owner/empty/releases/latest":
+			w.WriteHeader(http.StatusNotFound)
+		case "GET /repos/owner/repo/releases":
+			_, _ = w.Write([]byte(`[{"tag_name": "v1.3.0-rc.1", "prerelease": true}, {"tag_name": "v1.2.0"}]`))
+		case "GET /repos/owner/repo/commits":
+			if r.URL.Query().Get("sha") != "release/1.x" {
+				_, _ = w.Write([]byte(`[]`))
+				return
+			}
+			_, _ = w.Write([]byte(`[{"sha": "abc123"}]`))
+		case "POST /repos/owner/repo/pulls":
+			_, _ = w.Write([]byte(`{"html_url": "https://forge.example.com/owner/repo/pulls/7"}`))
+		case "GET /users/someone/repos":
+			// Pages may be shorter than requested; the listing ends at an
+			// empty one
+			if r.URL.Query().Get("page") == "1" {
+				_, _ = w.Write([]byte(`[{"name": "app", "clone_url": "https://forge.example.com/someone/app.git", "ssh_url": "git@forge.example.com:someone/app.git"}]`))
+				return
+			}
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	api, err := New(config.HostConfig{Name: "forge.example.com", Provider: config.ProviderForgejo, APIURL: srv.URL, Token: "secret"}, Options{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if api.Provider() != config.ProviderGitea {
+		t.Errorf("expected Forgejo to use the Gitea API, got %s", api.Provider())
+	}
+	ctx := context.Background()
+
+	release, err := api.LatestRelease(ctx, "owner/repo", false)
+	if err != nil || release.Tag != "v1.2.0" {
+		t.Errorf("unexpected release %+v, %v", release, err)
+	}
+	if _, err := api.LatestRelease(ctx, "owner/empty", false); errcode.Of(err) != errcode.RefNotFound {
+		t.Errorf("expected a repository without releases to be RefNotFound, got %v", err)
+	}
+	release, err = api.LatestRelease(ctx, "owner/repo", true)
+	if err != nil || release.Tag != "v1.3.0-rc.1" || !release.Prerelease {
+		t.Errorf("expected the prerelease, got %+v, %v", release, err)
+	}
+	if sha, err := api.RefSHA(ctx, "owner/repo", "release/1.x"); err != nil || sha != "abc123" {
+		t.Errorf("expected abc123, got %q, %v", sha, err)
+	}
+	if _, err := api.RefSHA(ctx, "owner/repo", "missing"); errcode.Of(err) != errcode.RefNotFound {
+		t.Errorf("expected a missing ref to be RefNotFound, got %v", err)
+	}
+	url, err := api.CreatePullRequest(ctx, "owner/repo", PullRequest{Title: "Update", Head: "update", Base: "main"})
+	if err != nil || url != "https://forge.example.com/owner/repo/pulls/7" {
+		t.Errorf("unexpected pull request %q, %v", url, err)
+	}
+
+	repos, err := api.(RepositoryLister).ListRepositories(ctx, "someone")
+	if err != nil || len(repos) != 1 || repos[0].SSHURL != "git@forge.example.com:someone/app.git" {
+		t.Errorf("unexpected repositories %+v, %v", repos, err)
+	}
+}
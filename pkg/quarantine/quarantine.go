@@ -0,0 +1,179 @@
+// Package quarantine tracks consecutive sync failures per repository and
+// sets aside repositories that keep failing, so that one broken remote
+// does not slow down every sync.
+package quarantine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/types"
+)
+
+// FileName is the name of the quarantine file inside the state directory.
+const FileName = "quarantine.json"
+
+// Entry records the consecutive failures of one repository.
+type Entry struct {
+	Failures    int       `json:"failures"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastFailure time.Time `json:"last_failure"`
+	Since       time.Time `json:"quarantined_since,omitempty"` // Zero unless quarantined
+}
+
+// Quarantined reports whether the repository is quarantined.
+func (e *Entry) Quarantined() bool {
+	return !e.Since.IsZero()
+}
+
+// Store holds the entries of a workspace. Repositories without failures
+// have no entry.
+type Store struct {
+	path  string
+	Repos map[string]*Entry
+}
+
+// DefaultPath returns the quarantine file of the workspace whose config is
+// in dir.
+func DefaultPath(dir string) string {
+	return filepath.Join(dir, config.StateDirName, FileName)
+}
+
+// Load reads the store at path. A missing file yields an empty store.
+func Load(path string) (*Store, error) {
+	s := &Store{path: path, Repos: make(map[string]*Entry)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quarantine: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.Repos); err != nil {
+		return nil, fmt.Errorf("failed to parse quarantine: %w", err)
+	}
+	if s.Repos == nil {
+		s.Repos = make(map[string]*Entry)
+	}
+	return s, nil
+}
+
+// Path returns the file backing the store.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Save writes the store, replacing the file atomically. An empty store
+// removes the file.
+func (s *Store) Save() error {
+	if len(s.Repos) == 0 {
+		if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to write quarantine: %w", err)
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.Repos, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode quarantine: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), FileName+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write quarantine: %w", err)
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write quarantine: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write quarantine: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write quarantine: %w", err)
+	}
+	return nil
+}
+
+// Record counts the outcome of synced repositories: a success clears the
+// repository's entry and a failure adds to its consecutive failures.
+// Repositories reaching threshold failures are quarantined and returned.
+// A threshold below 1 counts failures without quarantining. Operations
+// that were canceled are not counted.
+func (s *Store) Record(results []types.OperationResult, threshold int) []string {
+	var quarantined []string
+	now := time.Now()
+	for _, r := range results {
+		if r.Success {
+			delete(s.Repos, r.RepoName)
+			continue
+		}
+		if errors.Is(r.Error, context.Canceled) {
+			continue
+		}
+
+		e, ok := s.Repos[r.RepoName]
+		if !ok {
+			e = &Entry{}
+			s.Repos[r.RepoName] = e
+		}
+		e.Failures++
+		e.LastFailure = now
+		if r.Error != nil {
+			e.LastError = r.Error.Error()
+		}
+		if threshold > 0 && e.Failures >= threshold && !e.Quarantined() {
+			e.Since = now
+			quarantined = append(quarantined, r.RepoName)
+		}
+	}
+	return quarantined
+}
+
+// Get returns the entry of a repository.
+func (s *Store) Get(name string) (Entry, bool) {
+	e, ok := s.Repos[name]
+	if !ok {
+		return Entry{}, false
+	}
+	return *e, true
+}
+
+// IsQuarantined reports whether a repository is quarantined.
+func (s *Store) IsQuarantined(name string) bool {
+	e, ok := s.Repos[name]
+	return ok && e.Quarantined()
+}
+
+// Quarantined returns the names of quarantined repositories, sorted.
+func (s *Store) Quarantined() []string {
+	var names []string
+	for name, e := range s.Repos {
+		if e.Quarantined() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Release clears the entry of a repository so that it is synced again,
+// reporting whether it was quarantined.
+func (s *Store) Release(name string) bool {
+	quarantined := s.IsQuarantined(name)
+	delete(s.Repos, name)
+	return quarantined
+}
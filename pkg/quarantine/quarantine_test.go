@@ -0,0 +1,96 @@
+package quarantine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tierone/harbormaster/pkg/types"
+)
+
+func failed(name string) types.OperationResult {
+	return types.OperationResult{RepoName: name, Error: errors.New("connection refused")}
+}
+
+func TestStore_Record(t *testing.T) {
+	s, err := Load(filepath.Join(t.TempDir(), FileName))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if got := s.Record([]types.OperationResult{failed("api"), failed("web")}, 3); len(got) != 0 {
+			t.Fatalf("round %d: expected nothing quarantined, got %v", i, got)
+		}
+	}
+	got := s.Record([]types.OperationResult{failed("api"), {RepoName: "web", Success: true}}, 3)
+	if len(got) != 1 || got[0] != "api" {
+		t.Fatalf("expected api to be quarantined, got %v", got)
+	}
+	if !s.IsQuarantined("api") {
+		t.Error("expected api to be quarantined")
+	}
+	if _, ok := s.Get("web"); ok {
+		t.Error("expected success to clear web's failures")
+	}
+
+	e, _ := s.Get("api")
+	if e.Failures != 3 || e.LastError != "connection refused" {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+
+	// Further failures don't report it again
+	if got := s.Record([]types.OperationResult{failed("api")}, 3); len(got) != 0 {
+		t.Errorf("expected no newly quarantined repositories, got %v", got)
+	}
+}
+
+func TestStore_RecordIgnoresCanceled(t *testing.T) {
+	s, _ := Load(filepath.Join(t.TempDir(), FileName))
+	s.Record([]types.OperationResult{{RepoName: "api", Error: context.Canceled}}, 1)
+	if _, ok := s.Get("api"); ok {
+		t.Error("expected canceled operations not to be counted")
+	}
+}
+
+func TestStore_RecordWithoutThreshold(t *testing.T) {
+	s, _ := Load(filepath.Join(t.TempDir(), FileName))
+	for i := 0; i < 10; i++ {
+		s.Record([]types.OperationResult{failed("api")}, 0)
+	}
+	if s.IsQuarantined("api") {
+		t.Error("expected no quarantine with a threshold of 0")
+	}
+}
+
+func TestStore_SaveLoadRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".harbormaster", FileName)
+	s, _ := Load(path)
+	s.Record([]types.OperationResult{failed("api"), failed("web")}, 1)
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got := loaded.Quarantined(); len(got) != 2 || got[0] != "api" || got[1] != "web" {
+		t.Fatalf("expected [api web], got %v", got)
+	}
+
+	if !loaded.Release("api") || !loaded.Release("web") {
+		t.Error("expected Release to report quarantined repositories")
+	}
+	if loaded.Release("api") {
+		t.Error("expected second Release to report false")
+	}
+	if err := loaded.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected empty store to remove the file, got %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+// Package registry keeps the user's list of known workspaces, so that
+// commands can be run against any of them by name from anywhere.
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// FileName is the name of the registry file in the user's config
+// directory.
+const FileName = "workspaces.toml"
+
+// PathEnv names the environment variable that overrides the registry
+// file.
+const PathEnv = "HARBORMASTER_WORKSPACES"
+
+// Workspace is a registered workspace.
+type Workspace struct {
+	Name string `toml:"name"`
+	Path string `toml:"path"` // Workspace root, the directory of its config file
+}
+
+// Registry is the set of registered workspaces and the current one, used
+// by commands run outside any workspace.
+type Registry struct {
+	path       string
+	Current    string      `toml:"current,omitempty"`
+	Workspaces []Workspace `toml:"workspace"`
+}
+
+// namePattern matches valid workspace names.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// DefaultPath returns the registry file: the file named by PathEnv, or
+// harbormaster/workspaces.toml in the user's config directory.
+func DefaultPath() (string, error) {
+	if path := os.Getenv(PathEnv); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find user config directory: %w", err)
+	}
+	return filepath.Join(dir, "harbormaster", FileName), nil
+}
+
+// Load reads the registry at path. A missing file yields an empty
+// registry.
+func Load(path string) (*Registry, error) {
+	r := &Registry{path: path}
+	if _, err := toml.DecodeFile(path, r); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to parse workspace registry: %w", err)
+	}
+	return r, nil
+}
+
+// Path returns the file backing the registry.
+func (r *Registry) Path() string {
+	return r.path
+}
+
+// Save writes the registry, replacing the file atomically.
+func (r *Registry) Save() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("failed to create registry directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), FileName+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write workspace registry: %w", err)
+	}
+	if err := toml.NewEncoder(tmp).Encode(r); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to encode workspace registry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write workspace registry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write workspace registry: %w", err)
+	}
+	return nil
+}
+
+// Add registers the workspace rooted at dir under name. The first
+// workspace added becomes the current one.
+func (r *Registry) Add(name, dir string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid workspace name %q: use letters, digits, '.', '_', and '-'", name)
+	}
+	if _, ok := r.Get(name); ok {
+		return fmt.Errorf("workspace %s already exists", name)
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	for _, w := range r.Workspaces {
+		if w.Path == abs {
+			return fmt.Errorf("%s is already registered as workspace %s", abs, w.Name)
+		}
+	}
+
+	r.Workspaces = append(r.Workspaces, Workspace{Name: name, Path: abs})
+	sort.Slice(r.Workspaces, func(i, j int) bool { return r.Workspaces[i].Name < r.Workspaces[j].Name })
+	if r.Current == "" {
+		r.Current = name
+	}
+	return nil
+}
+
+// Remove unregisters a workspace, reporting whether it was registered.
+// Removing the current workspace leaves none current.
+func (r *Registry) Remove(name string) bool {
+	for i, w := range r.Workspaces {
+		if w.Name == name {
+			r.Workspaces = append(r.Workspaces[:i], r.Workspaces[i+1:]...)
+			if r.Current == name {
+				r.Current = ""
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns a registered workspace by name.
+func (r *Registry) Get(name string) (Workspace, bool) {
+	for _, w := range r.Workspaces {
+		if w.Name == name {
+			return w, true
+		}
+	}
+	return Workspace{}, false
+}
+
+// Switch makes a registered workspace the current one.
+func (r *Registry) Switch(name string) error {
+	if _, ok := r.Get(name); !ok {
+		return r.notFound(name)
+	}
+	r.Current = name
+	return nil
+}
+
+// Resolve returns the workspace to use by name, or the current one if
+// name is empty. ok is false if name is empty and none is current.
+func (r *Registry) Resolve(name string) (w Workspace, ok bool, err error) {
+	if name == "" {
+		if r.Current == "" {
+			return Workspace{}, false, nil
+		}
+		name = r.Current
+	}
+	w, ok = r.Get(name)
+	if !ok {
+		return Workspace{}, false, r.notFound(name)
+	}
+	return w, true, nil
+}
+
+// Names returns the names of the registered workspaces, sorted.
+func (r *Registry) Names() []string {
+	names := make([]string, len(r.Workspaces))
+	for i, w := range r.Workspaces {
+		names[i] = w.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *Registry) notFound(name string) error {
+	if len(r.Workspaces) == 0 {
+		return fmt.Errorf("unknown workspace %s: no workspaces registered", name)
+	}
+	return fmt.Errorf("unknown workspace %s (known: %s)", name, strings.Join(r.Names(), ", "))
+}
@@ -0,0 +1,88 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_AddSwitchRemove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "harbormaster", FileName)
+	r, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(r.Workspaces) != 0 || r.Current != "" {
+		t.Fatalf("expected an empty registry, got %+v", r)
+	}
+
+	if err := r.Add("frontend", "/work/frontend"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := r.Add("backend", "/work/backend"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if r.Current != "frontend" {
+		t.Errorf("expected the first workspace to be current, got %q", r.Current)
+	}
+	if err := r.Add("backend", "/work/other"); err == nil {
+		t.Error("expected a duplicate name to be rejected")
+	}
+	if err := r.Add("again", "/work/backend"); err == nil {
+		t.Error("expected a duplicate path to be rejected")
+	}
+	if err := r.Add("../up", "/work/up"); err == nil {
+		t.Error("expected an invalid name to be rejected")
+	}
+
+	if err := r.Switch("backend"); err != nil {
+		t.Fatalf("Switch failed: %v", err)
+	}
+	if err := r.Switch("mobile"); err == nil || !strings.Contains(err.Error(), "known: backend, frontend") {
+		t.Errorf("expected unknown workspace error listing names, got %v", err)
+	}
+	if err := r.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	r, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got := r.Names(); !slices.Equal(got, []string{"backend", "frontend"}) {
+		t.Errorf("Names() = %v", got)
+	}
+	w, ok, err := r.Resolve("")
+	if err != nil || !ok || w.Name != "backend" || w.Path != "/work/backend" {
+		t.Errorf("Resolve(\"\") = %+v, %v, %v; want current backend", w, ok, err)
+	}
+	if w, ok, _ := r.Resolve("frontend"); !ok || w.Path != "/work/frontend" {
+		t.Errorf("Resolve(frontend) = %+v, %v", w, ok)
+	}
+
+	if !r.Remove("backend") || r.Remove("backend") {
+		t.Error("expected backend to be removed once")
+	}
+	if _, ok, err := r.Resolve(""); ok || err != nil {
+		t.Errorf("expected no current workspace after removing it, got %v, %v", ok, err)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte("current = ["), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("expected an invalid registry to fail to load")
+	}
+}
+
+func TestDefaultPath_Env(t *testing.T) {
+	t.Setenv(PathEnv, "/tmp/registry.toml")
+	if path, err := DefaultPath(); err != nil || path != "/tmp/registry.toml" {
+		t.Errorf("DefaultPath() = %q, %v", path, err)
+	}
+}
@@ -0,0 +1,226 @@
+package semver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// comparison is one bound of a constraint, such as ">= 1.4.0".
+type comparison struct {
+	op string // "=", "!=", ">", ">=", "<", or "<="
+	v  Version
+}
+
+func (c comparison) check(v Version) bool {
+	d := v.Compare(c.v)
+	switch c.op {
+	case "=":
+		return d == 0
+	case "!=":
+		return d != 0
+	case ">":
+		return d > 0
+	case ">=":
+		return d >= 0
+	case "<":
+		return d < 0
+	default: // "<="
+		return d <= 0
+	}
+}
+
+// Constraint is a set of version ranges. Terms separated by commas or
+// spaces must all hold; alternatives are separated by "||".
+//
+// Besides the comparison operators (=, !=, >, >=, <, <=), a term can be
+//
+//	~> 1.4     pessimistic: >= 1.4.0, < 2.0.0 (~> 1.4.2 is < 1.5.0)
+//	^1.4.2     compatible: >= 1.4.2, < 2.0.0 (^0.4.2 is < 0.5.0)
+//	~1.4.2     patch-level: >= 1.4.2, < 1.5.0
+//	1.4, 1.4.x any 1.4 version
+//	*          any version
+//
+// Prereleases only satisfy a constraint that mentions a prerelease.
+type Constraint struct {
+	raw          string
+	alternatives [][]comparison
+	prerelease   bool
+}
+
+// ParseConstraint parses a version constraint.
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{raw: s}
+	for _, alt := range strings.Split(s, "||") {
+		terms, err := splitTerms(alt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid constraint %q: %w", s, err)
+		}
+		if len(terms) == 0 {
+			return nil, fmt.Errorf("invalid constraint %q: empty", s)
+		}
+
+		var cmps []comparison
+		for _, term := range terms {
+			tc, pre, err := parseTerm(term)
+			if err != nil {
+				return nil, fmt.Errorf("invalid constraint %q: %w", s, err)
+			}
+			cmps = append(cmps, tc...)
+			c.prerelease = c.prerelease || pre
+		}
+		c.alternatives = append(c.alternatives, cmps)
+	}
+	return c, nil
+}
+
+// String returns the constraint as written.
+func (c *Constraint) String() string {
+	return c.raw
+}
+
+// Check reports whether v satisfies the constraint.
+func (c *Constraint) Check(v Version) bool {
+	if v.Prerelease != "" && !c.prerelease {
+		return false
+	}
+	for _, cmps := range c.alternatives {
+		ok := true
+		for _, cmp := range cmps {
+			if !cmp.check(v) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Highest returns the name whose version is the highest satisfying the
+// constraint. Names that are not versions are ignored.
+func (c *Constraint) Highest(names []string) (string, bool) {
+	var best string
+	var bestVersion Version
+	for _, name := range names {
+		v, err := Parse(name)
+		if err != nil || !c.Check(v) {
+			continue
+		}
+		if best == "" || v.Compare(bestVersion) > 0 {
+			best, bestVersion = name, v
+		}
+	}
+	return best, best != ""
+}
+
+// operators in the order they must be matched, longest first.
+var operators = []string{"~>", ">=", "<=", "!=", ">", "<", "=", "^", "~"}
+
+// splitTerms splits the terms of one alternative, joining operators
+// written apart from their version.
+func splitTerms(s string) ([]string, error) {
+	var terms []string
+	pending := ""
+	for _, field := range strings.Fields(strings.ReplaceAll(s, ",", " ")) {
+		field = pending + field
+		pending = ""
+		if isOperator(field) {
+			pending = field
+			continue
+		}
+		terms = append(terms, field)
+	}
+	if pending != "" {
+		return nil, fmt.Errorf("operator %s without a version", pending)
+	}
+	return terms, nil
+}
+
+func isOperator(s string) bool {
+	for _, op := range operators {
+		if s == op {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTerm converts a term to comparisons, reporting whether it mentions
+// a prerelease.
+func parseTerm(term string) ([]comparison, bool, error) {
+	op := ""
+	for _, o := range operators {
+		if strings.HasPrefix(term, o) {
+			op = o
+			break
+		}
+	}
+	rest := strings.TrimSpace(term[len(op):])
+
+	if rest == "*" || rest == "x" || rest == "X" {
+		if op != "" && op != "=" {
+			return nil, false, fmt.Errorf("operator %s with a wildcard", op)
+		}
+		return nil, false, nil
+	}
+
+	// Wildcards stand for the parts they replace being unspecified
+	wildcard := false
+	if parts := strings.Split(rest, "."); len(parts) > 1 {
+		last := parts[len(parts)-1]
+		if last == "x" || last == "X" || last == "*" {
+			rest = strings.Join(parts[:len(parts)-1], ".")
+			wildcard = true
+		}
+	}
+	if wildcard && op != "" && op != "=" {
+		return nil, false, fmt.Errorf("operator %s with a wildcard", op)
+	}
+
+	v, n, err := parse(rest)
+	if err != nil {
+		return nil, false, err
+	}
+	pre := v.Prerelease != ""
+
+	lower := comparison{">=", v}
+	switch op {
+	case "", "=":
+		if n == 3 {
+			return []comparison{{"=", v}}, pre, nil
+		}
+		return []comparison{lower, {"<", bump(v, n-1)}}, pre, nil
+	case "~>":
+		// The last given part may increase
+		return []comparison{lower, {"<", bump(v, max(n-2, 0))}}, pre, nil
+	case "~":
+		return []comparison{lower, {"<", bump(v, min(n-1, 1))}}, pre, nil
+	case "^":
+		// The first non-zero given part may not change
+		switch {
+		case v.Major > 0 || n == 1:
+			return []comparison{lower, {"<", bump(v, 0)}}, pre, nil
+		case v.Minor > 0 || n == 2:
+			return []comparison{lower, {"<", bump(v, 1)}}, pre, nil
+		default:
+			return []comparison{lower, {"<", bump(v, 2)}}, pre, nil
+		}
+	default:
+		return []comparison{{op, v}}, pre, nil
+	}
+}
+
+// bump returns the lowest release above all versions sharing v's parts up
+// to and including index part (0 major, 1 minor, 2 patch).
+func bump(v Version, part int) Version {
+	switch part {
+	case 0:
+		return Version{Major: v.Major + 1}
+	case 1:
+		return Version{Major: v.Major, Minor: v.Minor + 1}
+	default:
+		return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+	}
+}
@@ -0,0 +1,108 @@
+package semver
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"1.4.2", "1.4.2"},
+		{"v1.4", "1.4.0"},
+		{"2", "2.0.0"},
+		{"v2.0.0-rc.1+build.5", "2.0.0-rc.1"},
+	}
+	for _, tt := range tests {
+		v, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if v.String() != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.in, v, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "latest", "1.2.3.4", "v1.x", "1.-2", "1.2.3-"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q): expected error", bad)
+		}
+	}
+}
+
+func TestVersion_Compare(t *testing.T) {
+	ordered := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.2.0", "2.0.0"}
+	for i := 0; i < len(ordered)-1; i++ {
+		a, _ := Parse(ordered[i])
+		b, _ := Parse(ordered[i+1])
+		if a.Compare(b) != -1 || b.Compare(a) != 1 {
+			t.Errorf("expected %s < %s", a, b)
+		}
+	}
+	a, _ := Parse("v1.2")
+	b, _ := Parse("1.2.0")
+	if a.Compare(b) != 0 {
+		t.Errorf("expected %s = %s", a, b)
+	}
+}
+
+func TestConstraint_Check(t *testing.T) {
+	tests := []struct {
+		constraint string
+		match      []string
+		noMatch    []string
+	}{
+		{"~>1.4", []string{"1.4.0", "1.9.3"}, []string{"1.3.9", "2.0.0"}},
+		{"~> 1.4.2", []string{"1.4.2", "1.4.9"}, []string{"1.4.1", "1.5.0"}},
+		{"^1.4.2", []string{"1.4.2", "1.9.0"}, []string{"1.4.1", "2.0.0"}},
+		{"^0.4.2", []string{"0.4.2", "0.4.9"}, []string{"0.5.0"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"~1.4", []string{"1.4.0", "1.4.7"}, []string{"1.5.0"}},
+		{"1.4", []string{"1.4.0", "1.4.7"}, []string{"1.5.0", "1.3.0"}},
+		{"1.4.x", []string{"1.4.3"}, []string{"1.5.0"}},
+		{"=1.4.2", []string{"1.4.2"}, []string{"1.4.3"}},
+		{">= 1.2, < 2", []string{"1.2.0", "1.99.0"}, []string{"1.1.9", "2.0.0"}},
+		{">1.2 !=1.3.0", []string{"1.2.1", "1.3.1"}, []string{"1.2.0", "1.3.0"}},
+		{"<1 || >=3", []string{"0.9.0", "3.1.0"}, []string{"1.0.0", "2.5.0"}},
+		{"*", []string{"0.0.1", "9.9.9"}, []string{"2.0.0-rc.1"}},
+		{">=2.0.0-rc.1", []string{"2.0.0-rc.2", "2.0.0"}, []string{"2.0.0-beta.1"}},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Errorf("ParseConstraint(%q) failed: %v", tt.constraint, err)
+			continue
+		}
+		for _, s := range tt.match {
+			if v, _ := Parse(s); !c.Check(v) {
+				t.Errorf("%q: expected %s to match", tt.constraint, s)
+			}
+		}
+		for _, s := range tt.noMatch {
+			if v, _ := Parse(s); c.Check(v) {
+				t.Errorf("%q: expected %s not to match", tt.constraint, s)
+			}
+		}
+	}
+}
+
+func TestParseConstraint_Invalid(t *testing.T) {
+	for _, bad := range []string{"", "~>", ">= 1.2 ||", "~>1.x", "latest", "1.2.3.4"} {
+		if _, err := ParseConstraint(bad); err == nil {
+			t.Errorf("ParseConstraint(%q): expected error", bad)
+		}
+	}
+}
+
+func TestConstraint_Highest(t *testing.T) {
+	c, _ := ParseConstraint("~>1.4")
+	tags := []string{"v1.3.0", "v1.4.0", "v1.10.1", "v1.9.0", "v2.0.0", "v1.11.0-rc.1", "nightly"}
+	if got, ok := c.Highest(tags); !ok || got != "v1.10.1" {
+		t.Errorf("expected v1.10.1, got %q", got)
+	}
+
+	c, _ = ParseConstraint(">=3")
+	if got, ok := c.Highest(tags); ok {
+		t.Errorf("expected no match, got %q", got)
+	}
+}
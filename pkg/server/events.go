@@ -0,0 +1,146 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/types"
+)
+
+// Event names sent on the event stream.
+const (
+	EventProgress = "progress"
+	EventComplete = "complete"
+	EventError    = "error"
+)
+
+// subscriberBuffer is the number of events buffered per subscriber. Slow
+// subscribers miss events rather than stalling a sync.
+const subscriberBuffer = 256
+
+// event is a named, JSON-encoded server-sent event.
+type event struct {
+	name string
+	data []byte
+}
+
+// broker fans events out to event stream subscribers.
+type broker struct {
+	mu   sync.Mutex
+	subs map[chan event]struct{}
+}
+
+func newBroker() *broker {
+	return &broker{subs: make(map[chan event]struct{})}
+}
+
+func (b *broker) subscribe() chan event {
+	ch := make(chan event, subscriberBuffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch
+}
+
+func (b *broker) unsubscribe(ch chan event) {
+	b.mu.Lock()
+	delete(b.subs, ch)
+	b.mu.Unlock()
+}
+
+// publish encodes v and sends it to every subscriber without blocking.
+func (b *broker) publish(name string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- event{name: name, data: data}:
+		default:
+		}
+	}
+}
+
+// progressEvent is the JSON form of a progress message.
+type progressEvent struct {
+	Repository  string     `json:"repository"`
+	URL         string     `json:"url"`
+	Phase       string     `json:"phase"`
+	Percent     float64    `json:"percent"`
+	Stage       string     `json:"stage,omitempty"`
+	Done        int        `json:"done,omitempty"`
+	Total       int        `json:"total,omitempty"`
+	Unit        string     `json:"unit,omitempty"`
+	BytesDone   int64      `json:"bytes_done,omitempty"`
+	BytesTotal  int64      `json:"bytes_total,omitempty"`
+	Message     string     `json:"message,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	Code        string     `json:"code,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+func newProgressEvent(msg types.ProgressMsg) progressEvent {
+	ev := progressEvent{
+		Repository:  msg.RepoName,
+		URL:         msg.RepoURL,
+		Phase:       string(msg.Phase),
+		Percent:     msg.Percent,
+		Stage:       string(msg.Stage),
+		Done:        msg.ObjectsDone,
+		Total:       msg.ObjectsTotal,
+		BytesDone:   msg.BytesDone,
+		BytesTotal:  msg.BytesTotal,
+		Message:     msg.Message,
+		StartedAt:   msg.StartedAt,
+		CompletedAt: msg.CompletedAt,
+	}
+	if msg.ObjectsTotal > 0 {
+		ev.Unit = msg.Stage.Unit()
+	}
+	if msg.Error != nil {
+		ev.Error = msg.Error.Error()
+		ev.Code = string(errcode.Of(msg.Error))
+	}
+	return ev
+}
+
+// handleEvents streams events to the client as server-sent events until
+// the client disconnects or the server shuts down.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+		return
+	}
+
+	ch := s.events.subscribe()
+	defer s.events.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.ctx.Done():
+			return
+		case ev := <-ch:
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.name, ev.data)
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,429 @@
+// Package server exposes a workspace over a local JSON HTTP API so that
+// editors and dashboards can drive Harbormaster without running the CLI.
+//
+// Endpoints:
+//
+//	GET  /api/repositories  configured repositories
+//	GET  /api/projects      configured projects
+//	GET  /api/status        repository status (filter with name, project, tag)
+//	POST /api/sync          start a sync in the background
+//	GET  /api/events        progress and completion as server-sent events
+//
+// Requests must name the server by localhost or an IP address, must not
+// come from a page of another origin, and must post JSON.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/errcode"
+	"github.com/tierone/harbormaster/pkg/history"
+	"github.com/tierone/harbormaster/pkg/lockfile"
+	"github.com/tierone/harbormaster/pkg/logging"
+	"github.com/tierone/harbormaster/pkg/manager"
+	"github.com/tierone/harbormaster/pkg/notify"
+	"github.com/tierone/harbormaster/pkg/types"
+)
+
+// Server serves the workspace API.
+type Server struct {
+	config      *config.Config
+	concurrency int
+	logger      *slog.Logger
+	verbose     io.Writer
+	ctx         context.Context
+
+	// mu guards lockFile. Syncs update a copy that replaces it on
+	// completion, so status requests never see a partial update.
+	mu       sync.RWMutex
+	lockFile *lockfile.LockFile
+
+	syncing atomic.Bool
+	events  *broker
+}
+
+// Option configures the server.
+type Option func(*Server)
+
+// WithConcurrency sets the number of concurrent sync operations. By
+// default general.concurrency from the config is used.
+func WithConcurrency(n int) Option {
+	return func(s *Server) {
+		if n > 0 {
+			s.concurrency = n
+		}
+	}
+}
+
+// WithLogger sets the diagnostic logger used by the server and its syncs.
+func WithLogger(l *slog.Logger) Option {
+	return func(s *Server) {
+		if l != nil {
+			s.logger = l
+		}
+	}
+}
+
+// WithVerbose writes the git commands and HTTP requests of syncs to w.
+func WithVerbose(w io.Writer) Option {
+	return func(s *Server) {
+		s.verbose = w
+	}
+}
+
+// New creates a server for the workspace described by cfg and lf.
+func New(cfg *config.Config, lf *lockfile.LockFile, opts ...Option) *Server {
+	s := &Server{
+		config:   cfg,
+		logger:   logging.Discard(),
+		ctx:      context.Background(),
+		lockFile: lf,
+		events:   newBroker(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Handler returns the HTTP handler for the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/repositories", s.handleRepositories)
+	mux.HandleFunc("GET /api/projects", s.handleProjects)
+	mux.HandleFunc("GET /api/status", s.handleStatus)
+	mux.HandleFunc("POST /api/sync", s.handleSync)
+	mux.HandleFunc("GET /api/events", s.handleEvents)
+	return s.guard(mux)
+}
+
+// guard rejects requests a web page could make on the user's behalf. The
+// API has no authentication, so a page must not reach it by cross-site
+// request or by rebinding its own host name to the loopback address.
+func (s *Server) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowedHost(r) {
+			s.logger.Warn("rejected request", "host", r.Host, "path", r.URL.Path)
+			writeError(w, http.StatusForbidden, fmt.Errorf("host %s is not allowed", r.Host))
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+				s.logger.Warn("rejected request", "origin", origin, "path", r.URL.Path)
+				writeError(w, http.StatusForbidden, fmt.Errorf("origin %s is not allowed", origin))
+				return
+			}
+		}
+		// Pages can post forms cross-site without preflight, but not JSON
+		if r.Method == http.MethodPost {
+			if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, fmt.Errorf("content type must be application/json"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost reports whether the request names the server by localhost
+// or an IP address, which unlike other names cannot be rebound to it.
+// Requests over a unix socket are not reachable by pages and pass.
+func allowedHost(r *http.Request) bool {
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && addr.Network() == "unix" {
+		return true
+	}
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return strings.EqualFold(host, "localhost") || net.ParseIP(host) != nil
+}
+
+// Serve accepts connections on ln until ctx is canceled, then shuts down
+// gracefully. Background syncs are canceled with ctx.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	s.ctx = ctx
+
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+type repositoryView struct {
+	Name    string   `json:"name"`
+	URL     string   `json:"url"`
+	Type    string   `json:"type"`
+	Path    string   `json:"path"`
+	Branch  string   `json:"branch,omitempty"`
+	Tag     string   `json:"tag,omitempty"`
+	Commit  string   `json:"commit,omitempty"`
+	Version string   `json:"version,omitempty"`
+	Ref     string   `json:"ref,omitempty"`
+	AsOf    string   `json:"as_of,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+func (s *Server) handleRepositories(w http.ResponseWriter, r *http.Request) {
+	output := make([]repositoryView, len(s.config.Repositories))
+	for i, repo := range s.config.Repositories {
+		output[i] = repositoryView{
+			Name:    repo.Name,
+			URL:     repo.URL,
+			Type:    string(repo.Type),
+			Path:    repo.Path,
+			Branch:  repo.Branch,
+			Tag:     repo.Tag,
+			Commit:  repo.Commit,
+			Version: repo.Version,
+			Ref:     repo.Ref,
+			AsOf:    repo.AsOf,
+			Tags:    repo.Tags,
+		}
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+type projectView struct {
+	Name         string   `json:"name"`
+	Repositories []string `json:"repositories"`
+	Tags         []string `json:"tags,omitempty"`
+}
+
+func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
+	output := make([]projectView, len(s.config.Projects))
+	for i, proj := range s.config.Projects {
+		output[i] = projectView{
+			Name:         proj.Name,
+			Repositories: proj.Repositories,
+			Tags:         proj.Tags,
+		}
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+type statusView struct {
+	Name         string `json:"name"`
+	Path         string `json:"path"`
+	Exists       bool   `json:"exists"`
+	CurrentSHA   string `json:"current_sha,omitempty"`
+	LockedSHA    string `json:"locked_sha,omitempty"`
+	RequestedRef string `json:"requested_ref"`
+	Branch       string `json:"branch,omitempty"`
+	IsDirty      bool   `json:"is_dirty"`
+	NeedsUpdate  bool   `json:"needs_update"`
+	Error        string `json:"error,omitempty"`
+	Code         string `json:"code,omitempty"`
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := manager.Filter{
+		Names:    q["name"],
+		Projects: q["project"],
+		Tags:     q["tag"],
+	}
+
+	s.mu.RLock()
+	mgr := manager.NewRepositoryManager(s.config,
+		manager.WithLockFile(s.lockFile),
+		manager.WithConcurrency(s.concurrency),
+		manager.WithLogger(s.logger),
+	)
+	statuses, err := mgr.StatusContext(r.Context(), filter)
+	s.mu.RUnlock()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	output := make([]statusView, len(statuses))
+	for i, st := range statuses {
+		output[i] = statusView{
+			Name:         st.Name,
+			Path:         st.Path,
+			Exists:       st.Exists,
+			CurrentSHA:   st.CurrentSHA,
+			LockedSHA:    st.LockedSHA,
+			RequestedRef: st.RequestedRef,
+			Branch:       st.Branch,
+			IsDirty:      st.IsDirty,
+			NeedsUpdate:  st.NeedsUpdate,
+		}
+		if st.Error != nil {
+			output[i].Error = st.Error.Error()
+			output[i].Code = string(errcode.Of(st.Error))
+		}
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+// SyncRequest is the body of a sync request. An empty request syncs all
+// repositories.
+type SyncRequest struct {
+	Names    []string `json:"names,omitempty"`
+	Projects []string `json:"projects,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Locked   bool     `json:"locked,omitempty"`
+}
+
+func (s *Server) handleSync(w http.ResponseWriter, r *http.Request) {
+	var req SyncRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+	}
+
+	filter := manager.Filter{
+		Names:    req.Names,
+		Projects: req.Projects,
+		Tags:     req.Tags,
+	}
+
+	if !s.syncing.CompareAndSwap(false, true) {
+		writeError(w, http.StatusConflict, fmt.Errorf("a sync is already running"))
+		return
+	}
+
+	go s.runSync(filter, req.Locked)
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "started"})
+}
+
+// runSync syncs against a copy of the lock file and publishes progress and
+// the final summary on the event stream.
+func (s *Server) runSync(filter manager.Filter, locked bool) {
+	defer s.syncing.Store(false)
+
+	s.mu.RLock()
+	before := s.lockFile
+	lf := before.Clone()
+	s.mu.RUnlock()
+
+	mgr := manager.NewRepositoryManager(s.config,
+		manager.WithLockFile(lf),
+		manager.WithConcurrency(s.concurrency),
+		manager.WithLogger(s.logger),
+		manager.WithVerbose(s.verbose),
+		manager.WithLocked(locked),
+		manager.WithProgress(func(msg types.ProgressMsg) {
+			s.events.publish(EventProgress, newProgressEvent(msg))
+		}),
+	)
+
+	result, err := mgr.SyncContext(s.ctx, filter)
+	if err != nil {
+		s.publishError(err)
+		return
+	}
+
+	if !locked {
+		s.mu.Lock()
+		s.lockFile = lf
+		err := lf.Save(lf.Path())
+		s.mu.Unlock()
+		if err != nil {
+			s.publishError(fmt.Errorf("failed to save lock file: %w", err))
+		}
+	}
+
+	s.recordHistory(history.NewSyncEntry(filter.String(), result, lockfile.Diff(before, lf)))
+
+	if err := mgr.NotifySync(s.ctx, result); err != nil {
+		s.logger.Warn("failed to send notifications", "error", err)
+	}
+
+	if !result.HasFailures() {
+		if err := s.runPostSync(mgr); err != nil {
+			s.publishError(err)
+		}
+	}
+
+	s.events.publish(EventComplete, notify.NewSyncSummary(mgr.WorkspaceName(), result))
+}
+
+// runPostSync runs the workspace steps that follow a successful sync.
+func (s *Server) runPostSync(mgr *manager.RepositoryManager) error {
+	if s.config.Overlay.Enabled() {
+		if _, err := mgr.BuildOverlay(); err != nil {
+			return fmt.Errorf("failed to build overlay: %w", err)
+		}
+	}
+	if len(s.config.Generate) > 0 {
+		if _, err := mgr.GenerateFiles(); err != nil {
+			return fmt.Errorf("failed to generate files: %w", err)
+		}
+	}
+	return nil
+}
+
+// recordHistory appends e to the workspace history, if the workspace has
+// a config file.
+func (s *Server) recordHistory(e *history.Entry) {
+	if s.config.Path() == "" {
+		return
+	}
+	store := history.NewStore(history.DefaultPath(filepath.Dir(s.config.Path())))
+	if err := store.Append(e); err != nil {
+		s.logger.Warn("failed to record history", "error", err)
+	}
+}
+
+func (s *Server) publishError(err error) {
+	s.logger.Error("sync failed", "error", err)
+	s.events.publish(EventError, errorBody(err))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorBody(err))
+}
+
+// errorBody is the JSON form of an error, with its code if it has one.
+func errorBody(err error) map[string]string {
+	body := map[string]string{"error": err.Error()}
+	if code := errcode.Of(err); code != "" {
+		body["code"] = string(code)
+	}
+	return body
+}
@@ -0,0 +1,240 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/lockfile"
+	"github.com/tierone/harbormaster/pkg/notify"
+	"github.com/tierone/harbormaster/pkg/types"
+)
+
+// setupTestGitRepo creates a git repository with a single commit.
+func setupTestGitRepo(t *testing.T) string {
+	t.Helper()
+
+	repoDir := t.TempDir()
+	commands := [][]string{
+		{"git", "init"},
+		{"git", "config", "user.email", "test@test.com"},
+		{"git", "config", "user.name", "Test User"},
+		{"git", "commit", "--allow-empty", "-m", "Initial commit"},
+	}
+	for _, cmd := range commands {
+		c := exec.Command(cmd[0], cmd[1:]...)
+		c.Dir = repoDir
+		if out, err := c.CombinedOutput(); err != nil {
+			t.Fatalf("failed to run %v: %v\n%s", cmd, err, out)
+		}
+	}
+	return repoDir
+}
+
+func newTestServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+
+	workDir := t.TempDir()
+	cfg := &config.Config{
+		General: config.GeneralConfig{
+			WorkDir:       workDir,
+			DefaultBranch: "main",
+			Timeout:       config.DefaultTimeout,
+		},
+		Repositories: []config.Repository{
+			{Name: "test-repo", URL: setupTestGitRepo(t), Type: config.RepoTypeGit, Path: "test-repo", Tags: []string{"core"}},
+		},
+		Projects: []config.Project{
+			{Name: "app", Repositories: []string{"test-repo"}},
+		},
+	}
+
+	lockPath := filepath.Join(workDir, lockfile.LockFileName)
+	lf, err := lockfile.Load(lockPath)
+	if err != nil {
+		t.Fatalf("failed to load lock file: %v", err)
+	}
+
+	srv := httptest.NewServer(New(cfg, lf).Handler())
+	t.Cleanup(srv.Close)
+	return srv, lockPath
+}
+
+func getJSON(t *testing.T, url string, v any) int {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp.StatusCode
+}
+
+func TestServer_List(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	var repos []repositoryView
+	getJSON(t, srv.URL+"/api/repositories", &repos)
+	if len(repos) != 1 || repos[0].Name != "test-repo" || repos[0].Type != "git" {
+		t.Errorf("unexpected repositories: %+v", repos)
+	}
+
+	var projects []projectView
+	getJSON(t, srv.URL+"/api/projects", &projects)
+	if len(projects) != 1 || projects[0].Name != "app" {
+		t.Errorf("unexpected projects: %+v", projects)
+	}
+
+	var statuses []statusView
+	getJSON(t, srv.URL+"/api/status?tag=core", &statuses)
+	if len(statuses) != 1 || statuses[0].Exists {
+		t.Errorf("unexpected status: %+v", statuses)
+	}
+
+	var errResp map[string]string
+	if code := getJSON(t, srv.URL+"/api/status?name=missing", &errResp); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown repository, got %d", code)
+	}
+}
+
+func TestServer_Guard(t *testing.T) {
+	handler := New(&config.Config{}, nil).Handler()
+
+	tests := []struct {
+		name        string
+		method      string
+		host        string
+		origin      string
+		contentType string
+		want        int
+	}{
+		{"localhost", http.MethodGet, "localhost:7420", "", "", http.StatusOK},
+		{"loopback address", http.MethodGet, "127.0.0.1:7420", "", "", http.StatusOK},
+		{"same origin", http.MethodGet, "127.0.0.1:7420", "http://127.0.0.1:7420", "", http.StatusOK},
+		{"rebound host name", http.MethodGet, "attacker.example:7420", "", "", http.StatusForbidden},
+		{"foreign origin", http.MethodGet, "127.0.0.1:7420", "https://attacker.example", "", http.StatusForbidden},
+		{"form post", http.MethodPost, "127.0.0.1:7420", "", "text/plain", http.StatusUnsupportedMediaType},
+		{"post without content type", http.MethodPost, "127.0.0.1:7420", "", "", http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/api/projects"
+			if tt.method == http.MethodPost {
+				path = "/api/sync"
+			}
+			req := httptest.NewRequest(tt.method, path, nil)
+			req.Host = tt.host
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("expected %d, got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestServer_Sync(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	srv, lockPath := newTestServer(t)
+
+	// Subscribe before starting the sync so no events are missed
+	events, err := http.Get(srv.URL + "/api/events")
+	if err != nil {
+		t.Fatalf("failed to open event stream: %v", err)
+	}
+	defer events.Body.Close()
+	if ct := events.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type: %s", ct)
+	}
+
+	resp, err := http.Post(srv.URL+"/api/sync", "application/json", strings.NewReader(`{"projects": ["app"]}`))
+	if err != nil {
+		t.Fatalf("POST /api/sync failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", resp.StatusCode)
+	}
+
+	done := make(chan *notify.Summary, 1)
+	go func() {
+		var name string
+		scanner := bufio.NewScanner(events.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: ") && name == EventComplete:
+				var summary notify.Summary
+				_ = json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &summary)
+				done <- &summary
+				return
+			}
+		}
+	}()
+
+	select {
+	case summary := <-done:
+		if summary.Total != 1 || summary.Failed != 0 {
+			t.Errorf("unexpected summary: %+v", summary)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("timed out waiting for complete event")
+	}
+
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Errorf("expected lock file to be saved: %v", err)
+	}
+
+	var statuses []statusView
+	getJSON(t, srv.URL+"/api/status", &statuses)
+	if len(statuses) != 1 || !statuses[0].Exists || statuses[0].LockedSHA == "" {
+		t.Errorf("expected synced and locked repository, got %+v", statuses)
+	}
+}
+
+func TestNewProgressEvent(t *testing.T) {
+	ev := newProgressEvent(types.ProgressMsg{
+		RepoName:     "repo",
+		Phase:        types.PhaseFetching,
+		Percent:      45,
+		Stage:        types.StageResolving,
+		ObjectsDone:  45,
+		ObjectsTotal: 100,
+	})
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		t.Fatalf("failed to marshal event: %v", err)
+	}
+	for _, want := range []string{`"stage":"resolving"`, `"done":45`, `"total":100`, `"unit":"deltas"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected %s in %s", want, data)
+		}
+	}
+	if strings.Contains(string(data), "bytes_done") {
+		t.Errorf("expected unknown byte counts to be omitted, got %s", data)
+	}
+}
@@ -0,0 +1,165 @@
+// Package statuscache stores a summary of the last workspace status so
+// that shell prompts can show it without inspecting every repository.
+package statuscache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/config"
+	"github.com/tierone/harbormaster/pkg/manager"
+)
+
+// FileName is the name of the cache file inside the state directory.
+const FileName = "status.json"
+
+// States in the order they are summarized. A repository to which several
+// apply is counted in the most severe, the last in this list.
+var States = []string{"ok", "drift", "dirty", "missing", "error"}
+
+// Summary counts the repositories of a workspace by state.
+type Summary struct {
+	UpdatedAt time.Time      `json:"updated_at"`
+	Total     int            `json:"total"`
+	Counts    map[string]int `json:"counts"`
+}
+
+// New summarizes statuses. drift means the checkout differs from the
+// lock file.
+func New(statuses []manager.RepoStatus) *Summary {
+	s := &Summary{
+		UpdatedAt: time.Now(),
+		Total:     len(statuses),
+		Counts:    make(map[string]int),
+	}
+	for _, st := range statuses {
+		s.Counts[State(st)]++
+	}
+	return s
+}
+
+// State returns the state a repository is counted in.
+func State(s manager.RepoStatus) string {
+	switch {
+	case s.Error != nil:
+		return "error"
+	case !s.Exists:
+		return "missing"
+	case s.IsDirty:
+		return "dirty"
+	case s.LockedSHA != "" && s.CurrentSHA != s.LockedSHA:
+		return "drift"
+	default:
+		return "ok"
+	}
+}
+
+// String formats the summary for a prompt, e.g. "12 ok · 2 drift · 1 dirty".
+func (s *Summary) String() string {
+	var parts []string
+	for _, state := range States {
+		if n := s.Counts[state]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, state))
+		}
+	}
+	return strings.Join(parts, " · ")
+}
+
+// DefaultPath returns the cache file of the workspace whose config is in
+// dir.
+func DefaultPath(dir string) string {
+	return filepath.Join(dir, config.StateDirName, FileName)
+}
+
+// Load reads the summary at path. The error satisfies os.IsNotExist if
+// there is none.
+func Load(path string) (*Summary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Summary
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse status cache: %w", err)
+	}
+	return &s, nil
+}
+
+// Save writes s to path, replacing it atomically so that concurrent
+// readers never see a partial file.
+func Save(path string, s *Summary) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode status cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), FileName+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write status cache: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write status cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write status cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write status cache: %w", err)
+	}
+	return nil
+}
+
+// Stale reports whether s is older than maxAge or than any of the files
+// at paths, such as the config and lock file, that affect the status.
+func (s *Summary) Stale(maxAge time.Duration, paths ...string) bool {
+	if time.Since(s.UpdatedAt) > maxAge {
+		return true
+	}
+	for _, p := range paths {
+		if info, err := os.Stat(p); err == nil && info.ModTime().After(s.UpdatedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshTimeout is how long a claimed refresh may run before another
+// process may claim it again.
+const refreshTimeout = time.Minute
+
+// ClaimRefresh marks the cache at path as being refreshed and reports
+// whether the caller should do it, so that prompts drawn in quick
+// succession start only one refresh. The claim is released with
+// ReleaseRefresh or expires after a minute.
+func ClaimRefresh(path string) bool {
+	marker := path + ".refresh"
+	if info, err := os.Stat(marker); err == nil && time.Since(info.ModTime()) < refreshTimeout {
+		return false
+	}
+	_ = os.Remove(marker)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false
+	}
+	f, err := os.OpenFile(marker, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return false
+	}
+	_ = f.Close()
+	return true
+}
+
+// ReleaseRefresh removes the claim made by ClaimRefresh.
+func ReleaseRefresh(path string) {
+	_ = os.Remove(path + ".refresh")
+}
@@ -0,0 +1,105 @@
+package statuscache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/manager"
+)
+
+func TestNew(t *testing.T) {
+	statuses := []manager.RepoStatus{
+		{Name: "a", Exists: true, CurrentSHA: "abc", LockedSHA: "abc"},
+		{Name: "b", Exists: true, CurrentSHA: "abc"},
+		{Name: "c", Exists: true, CurrentSHA: "abc", LockedSHA: "def"},
+		{Name: "d", Exists: true, CurrentSHA: "abc", LockedSHA: "def", IsDirty: true},
+		{Name: "e", Exists: false},
+		{Name: "f", Exists: true, Error: errors.New("boom")},
+	}
+
+	s := New(statuses)
+	if s.Total != 6 {
+		t.Errorf("Total = %d, want 6", s.Total)
+	}
+	if got, want := s.String(), "2 ok · 1 drift · 1 dirty · 1 missing · 1 error"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if got := (&Summary{}).String(); got != "" {
+		t.Errorf("empty summary = %q, want empty", got)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := DefaultPath(t.TempDir())
+
+	if _, err := Load(path); !os.IsNotExist(err) {
+		t.Fatalf("expected not-exist error, got %v", err)
+	}
+
+	want := &Summary{UpdatedAt: time.Now().Round(0), Total: 3, Counts: map[string]int{"ok": 2, "dirty": 1}}
+	if err := Save(path, want); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !got.UpdatedAt.Equal(want.UpdatedAt) || got.String() != want.String() {
+		t.Errorf("Load = %+v, want %+v", got, want)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("expected only the cache file, got %v", entries)
+	}
+}
+
+func TestStale(t *testing.T) {
+	dir := t.TempDir()
+	lock := filepath.Join(dir, "lock")
+	if err := os.WriteFile(lock, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(lock, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	fresh := &Summary{UpdatedAt: time.Now()}
+	if fresh.Stale(time.Minute, lock) {
+		t.Error("expected a fresh summary not to be stale")
+	}
+	if !(&Summary{UpdatedAt: time.Now().Add(-2 * time.Minute)}).Stale(time.Minute, lock) {
+		t.Error("expected a summary older than max age to be stale")
+	}
+	if !(&Summary{UpdatedAt: old.Add(-time.Second)}).Stale(24*time.Hour, lock) {
+		t.Error("expected a summary older than the lock file to be stale")
+	}
+}
+
+func TestClaimRefresh(t *testing.T) {
+	path := DefaultPath(t.TempDir())
+
+	if !ClaimRefresh(path) {
+		t.Fatal("expected the first claim to succeed")
+	}
+	if ClaimRefresh(path) {
+		t.Error("expected a second claim to fail while the first is held")
+	}
+	ReleaseRefresh(path)
+	if !ClaimRefresh(path) {
+		t.Error("expected a claim to succeed after release")
+	}
+
+	// An abandoned claim expires
+	old := time.Now().Add(-2 * refreshTimeout)
+	if err := os.Chtimes(path+".refresh", old, old); err != nil {
+		t.Fatal(err)
+	}
+	if !ClaimRefresh(path) {
+		t.Error("expected an expired claim to be taken over")
+	}
+}
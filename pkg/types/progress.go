@@ -14,12 +14,39 @@ const (
 	PhaseFailed     ProgressPhase = "failed"
 )
 
+// GitStage is a stage of a git transfer, as reported in its progress
+// output.
+type GitStage string
+
+const (
+	StageEnumerating GitStage = "enumerating"
+	StageCounting    GitStage = "counting"
+	StageCompressing GitStage = "compressing"
+	StageReceiving   GitStage = "receiving"
+	StageResolving   GitStage = "resolving"
+	StageUpdating    GitStage = "updating"
+)
+
+// Unit returns what the stage counts.
+func (s GitStage) Unit() string {
+	switch s {
+	case StageResolving:
+		return "deltas"
+	case StageUpdating:
+		return "files"
+	default:
+		return "objects"
+	}
+}
+
 // ProgressUpdate is the internal progress message from downloaders.
 type ProgressUpdate struct {
 	Phase        ProgressPhase
+	Stage        GitStage // Stage of a git transfer, if any
+	Percent      float64  // Completion of the phase when bytes are not counted
 	BytesTotal   int64
 	BytesDone    int64
-	ObjectsTotal int
+	ObjectsTotal int // Items counted by the stage: objects, deltas, or files
 	ObjectsDone  int
 	Message      string
 	Error        error
@@ -27,14 +54,20 @@ type ProgressUpdate struct {
 
 // ProgressMsg is the rich progress message for UI display.
 type ProgressMsg struct {
-	RepoName    string
-	RepoURL     string
-	Phase       ProgressPhase
-	Percent     float64
-	Message     string
-	Error       error
-	StartedAt   time.Time
-	CompletedAt *time.Time
+	RepoName     string
+	RepoURL      string
+	Phase        ProgressPhase
+	Percent      float64
+	BytesDone    int64 // Bytes transferred so far; 0 if unknown
+	BytesTotal   int64 // Bytes to transfer; 0 if unknown
+	Stage        GitStage
+	ObjectsDone  int // Items counted by Stage
+	ObjectsTotal int
+	Message      string
+	Error        error
+	StartedAt    time.Time
+	CompletedAt  *time.Time
+	LogPath      string // Log of the repository's sync, once complete
 }
 
 // IsComplete returns true if the progress indicates completion.
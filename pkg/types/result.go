@@ -4,14 +4,20 @@ import "time"
 
 // OperationResult represents the outcome of a single repository operation.
 type OperationResult struct {
-	RepoName  string
-	RepoURL   string
-	Success   bool
-	Error     error
-	Duration  time.Duration
-	CommitSHA string
-	Branch    string
-	Tag       string
+	RepoName    string
+	RepoURL     string
+	Success     bool
+	Error       error
+	Duration    time.Duration
+	CommitSHA   string
+	Branch      string
+	Tag         string
+	TreeHash    string // Content tree hash, set for vendored repositories
+	PreviousSHA string // Locked SHA before the operation, if any
+	LogPath     string // Log of the operation's command output, if any
+	Recovered   bool   // An interrupted clone was removed and cloned again
+	Repaired    string // How a corrupt checkout was repaired before syncing, if it was
+	Rewritten   bool   // The branch was rewritten upstream: PreviousSHA is not in the history of CommitSHA
 }
 
 // SyncResult aggregates results from a sync operation.
@@ -21,6 +27,8 @@ type SyncResult struct {
 	FailureCount int
 	Results      []OperationResult
 	Duration     time.Duration
+	Quarantined  []string // Repositories skipped because they are quarantined
+	Fresh        []string // Repositories skipped because they were synced within min_sync_interval
 }
 
 // NewSyncResult creates a new SyncResult from a slice of operation results.
@@ -55,3 +63,27 @@ func (sr *SyncResult) FailedResults() []OperationResult {
 	}
 	return failed
 }
+
+// RewrittenResults returns the results of repositories whose branch was
+// rewritten upstream since the previous sync.
+func (sr *SyncResult) RewrittenResults() []OperationResult {
+	var rewritten []OperationResult
+	for _, r := range sr.Results {
+		if r.Rewritten {
+			rewritten = append(rewritten, r)
+		}
+	}
+	return rewritten
+}
+
+// RecoveredResults returns the results of repositories whose interrupted
+// clone was removed and cloned again.
+func (sr *SyncResult) RecoveredResults() []OperationResult {
+	var recovered []OperationResult
+	for _, r := range sr.Results {
+		if r.Recovered {
+			recovered = append(recovered, r)
+		}
+	}
+	return recovered
+}
@@ -0,0 +1,418 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// StatusRow is one repository in the status dashboard.
+type StatusRow struct {
+	Name    string
+	Project string
+	Status  string // ok, dirty, outdated, missing, or error
+	Branch  string
+	Commit  string
+	Lock    string // locked, drift, or empty
+	Error   string
+}
+
+// dashboardColumn is a sortable column of the dashboard.
+type dashboardColumn struct {
+	title string
+	value func(StatusRow) string
+}
+
+var dashboardColumns = []dashboardColumn{
+	{"REPOSITORY", func(r StatusRow) string { return r.Name }},
+	{"PROJECT", func(r StatusRow) string { return r.Project }},
+	{"STATUS", func(r StatusRow) string { return r.Status }},
+	{"BRANCH", func(r StatusRow) string { return r.Branch }},
+	{"COMMIT", func(r StatusRow) string { return r.Commit }},
+	{"LOCK", func(r StatusRow) string { return r.Lock }},
+}
+
+// Lines used by the dashboard around its table.
+const dashboardChrome = 6
+
+type dashboardLoadedMsg struct {
+	rows []StatusRow
+	err  error
+}
+
+type dashboardSyncedMsg struct {
+	names []string
+	err   error
+}
+
+// dashboardModel is the Bubbletea model for the status dashboard.
+type dashboardModel struct {
+	load func() ([]StatusRow, error)
+	sync func(names []string) error
+
+	rows      []StatusRow
+	marked    map[string]bool
+	sortCol   int
+	sortDesc  bool
+	filter    string
+	filtering bool // Whether keys edit the filter
+	cursor    int
+	offset    int
+	height    int
+	busy      string // What is running, if anything
+	notice    string // Outcome of the last sync
+	err       error
+	updated   time.Time
+}
+
+func newDashboardModel(load func() ([]StatusRow, error), sync func([]string) error) dashboardModel {
+	return dashboardModel{
+		load:   load,
+		sync:   sync,
+		marked: make(map[string]bool),
+		busy:   "Loading",
+	}
+}
+
+func (m dashboardModel) loadCmd() tea.Cmd {
+	load := m.load
+	return func() tea.Msg {
+		rows, err := load()
+		return dashboardLoadedMsg{rows: rows, err: err}
+	}
+}
+
+func (m dashboardModel) syncCmd(names []string) tea.Cmd {
+	sync := m.sync
+	return func() tea.Msg {
+		return dashboardSyncedMsg{names: names, err: sync(names)}
+	}
+}
+
+// Init starts loading the status.
+func (m dashboardModel) Init() tea.Cmd {
+	return m.loadCmd()
+}
+
+// Update handles messages.
+func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.Type == tea.KeyCtrlC {
+			return m, tea.Quit
+		}
+		if m.filtering {
+			m.editFilter(msg)
+			return m, nil
+		}
+		return m.handleKey(msg)
+
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+		m.move(0)
+
+	case dashboardLoadedMsg:
+		// A sync error stays shown until the next refresh or sync
+		m.busy = ""
+		if msg.err != nil {
+			m.err = msg.err
+		} else {
+			m.rows = msg.rows
+			m.updated = time.Now()
+		}
+		m.move(0)
+
+	case dashboardSyncedMsg:
+		m.err = msg.err
+		if msg.err == nil {
+			m.notice = fmt.Sprintf("Synced %d repositories", len(msg.names))
+			m.marked = make(map[string]bool)
+		}
+		m.busy = "Loading"
+		return m, m.loadCmd()
+	}
+
+	return m, nil
+}
+
+func (m dashboardModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q":
+		return m, tea.Quit
+	case "up", "k":
+		m.move(-1)
+	case "down", "j":
+		m.move(1)
+	case "pgup", "b":
+		m.move(-m.listHeight())
+	case "pgdown", "f":
+		m.move(m.listHeight())
+	case "home", "g":
+		m.move(-len(m.rows))
+	case "end", "G":
+		m.move(len(m.rows))
+	case " ", "x":
+		if rows := m.visible(); m.cursor < len(rows) {
+			name := rows[m.cursor].Name
+			m.marked[name] = !m.marked[name]
+		}
+	case "a":
+		rows := m.visible()
+		all := true
+		for _, r := range rows {
+			all = all && m.marked[r.Name]
+		}
+		for _, r := range rows {
+			m.marked[r.Name] = !all
+		}
+	case "/":
+		m.filtering = true
+	case "esc":
+		m.filter = ""
+		m.move(0)
+	case "o":
+		m.sortCol = (m.sortCol + 1) % len(dashboardColumns)
+	case "O":
+		m.sortDesc = !m.sortDesc
+	case "r":
+		if m.busy == "" {
+			m.busy = "Loading"
+			m.notice = ""
+			m.err = nil
+			return m, m.loadCmd()
+		}
+	case "s":
+		if m.busy != "" {
+			break
+		}
+		names := m.selected()
+		if len(names) == 0 {
+			break
+		}
+		m.busy = fmt.Sprintf("Syncing %d repositories", len(names))
+		m.notice = ""
+		m.err = nil
+		return m, m.syncCmd(names)
+	}
+	return m, nil
+}
+
+// editFilter applies a key to the filter being typed.
+func (m *dashboardModel) editFilter(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.filtering = false
+	case tea.KeyEsc:
+		m.filter = ""
+		m.filtering = false
+	case tea.KeyBackspace:
+		if r := []rune(m.filter); len(r) > 0 {
+			m.filter = string(r[:len(r)-1])
+		}
+	case tea.KeySpace:
+		m.filter += " "
+	case tea.KeyRunes:
+		m.filter += string(msg.Runes)
+	}
+	m.move(0)
+}
+
+// visible returns the rows matching the filter in display order.
+func (m *dashboardModel) visible() []StatusRow {
+	filter := strings.ToLower(m.filter)
+	rows := make([]StatusRow, 0, len(m.rows))
+	for _, r := range m.rows {
+		if filter == "" ||
+			strings.Contains(strings.ToLower(r.Name), filter) ||
+			strings.Contains(strings.ToLower(r.Project), filter) {
+			rows = append(rows, r)
+		}
+	}
+
+	value := dashboardColumns[m.sortCol].value
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := value(rows[i]), value(rows[j])
+		if a == b {
+			return rows[i].Name < rows[j].Name
+		}
+		if m.sortDesc {
+			return a > b
+		}
+		return a < b
+	})
+	return rows
+}
+
+// selected returns the marked repositories in display order, or the one
+// under the cursor if none are marked.
+func (m *dashboardModel) selected() []string {
+	rows := m.visible()
+	var names []string
+	for _, r := range rows {
+		if m.marked[r.Name] {
+			names = append(names, r.Name)
+		}
+	}
+	if len(names) == 0 && m.cursor < len(rows) {
+		names = []string{rows[m.cursor].Name}
+	}
+	return names
+}
+
+// listHeight returns the number of table rows that fit on screen, or 0 if
+// the terminal height is unknown.
+func (m *dashboardModel) listHeight() int {
+	if m.height == 0 {
+		return 0
+	}
+	return max(m.height-dashboardChrome, 1)
+}
+
+// move moves the cursor by delta rows and scrolls to keep it visible.
+func (m *dashboardModel) move(delta int) {
+	rows := len(m.visible())
+	m.cursor = max(min(m.cursor+delta, rows-1), 0)
+
+	height := m.listHeight()
+	if height == 0 {
+		m.offset = 0
+		return
+	}
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+height {
+		m.offset = m.cursor - height + 1
+	}
+	m.offset = max(min(m.offset, rows-height), 0)
+}
+
+// View renders the dashboard.
+func (m dashboardModel) View() string {
+	var b strings.Builder
+	rows := m.visible()
+
+	b.WriteString(TitleStyle.Render("Harbormaster Status"))
+	summary := fmt.Sprintf("  %d repositories", len(m.rows))
+	if len(rows) != len(m.rows) {
+		summary += fmt.Sprintf(", %d shown", len(rows))
+	}
+	marked := 0
+	for _, v := range m.marked {
+		if v {
+			marked++
+		}
+	}
+	if marked > 0 {
+		summary += fmt.Sprintf(", %d marked", marked)
+	}
+	if !m.updated.IsZero() {
+		summary += "  updated " + m.updated.Format("15:04:05")
+	}
+	b.WriteString(MutedStyle.Render(summary))
+	b.WriteString("\n\n")
+
+	// Column widths fit the longest value
+	widths := make([]int, len(dashboardColumns))
+	for i, col := range dashboardColumns {
+		widths[i] = len(col.title) + 2
+		for _, r := range m.rows {
+			widths[i] = max(widths[i], len(col.value(r)))
+		}
+	}
+
+	b.WriteString("      ")
+	for i, col := range dashboardColumns {
+		title := col.title
+		if i == m.sortCol {
+			if m.sortDesc {
+				title += " ▼"
+			} else {
+				title += " ▲"
+			}
+		}
+		b.WriteString(MutedStyle.Render(fmt.Sprintf("%-*s", widths[i], title)))
+		b.WriteString("  ")
+	}
+	b.WriteString("\n")
+
+	end := len(rows)
+	if height := m.listHeight(); height > 0 && m.offset+height < end {
+		end = m.offset + height
+	}
+	for i := m.offset; i < end; i++ {
+		r := rows[i]
+		if i == m.cursor {
+			b.WriteString(HighlightStyle.Render("›") + " ")
+		} else {
+			b.WriteString("  ")
+		}
+		if m.marked[r.Name] {
+			b.WriteString(SuccessStyle.Render("[x]") + " ")
+		} else {
+			b.WriteString(MutedStyle.Render("[ ]") + " ")
+		}
+		for c, col := range dashboardColumns {
+			cell := fmt.Sprintf("%-*s", widths[c], col.value(r))
+			switch col.title {
+			case "STATUS":
+				cell = statusStyle(r.Status).Render(cell)
+			case "LOCK":
+				if r.Lock == "drift" {
+					cell = WarningStyle.Render(cell)
+				} else if r.Lock != "" {
+					cell = SuccessStyle.Render(cell)
+				}
+			}
+			b.WriteString(cell)
+			b.WriteString("  ")
+		}
+		b.WriteString("\n")
+	}
+	if len(rows) == 0 && m.busy == "" {
+		b.WriteString(MutedStyle.Render("  No matching repositories"))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	switch {
+	case m.filtering:
+		b.WriteString("/" + m.filter + "█")
+	case m.busy != "":
+		b.WriteString(MutedStyle.Render(m.busy + "..."))
+	case m.err != nil:
+		b.WriteString(ErrorStyle.Render(m.err.Error()))
+	case m.notice != "":
+		b.WriteString(SuccessStyle.Render(m.notice))
+	case m.cursor < len(rows) && rows[m.cursor].Error != "":
+		b.WriteString(ErrorStyle.Render(rows[m.cursor].Name + ": " + rows[m.cursor].Error))
+	case m.filter != "":
+		b.WriteString(MutedStyle.Render("filter: " + m.filter))
+	}
+	b.WriteString("\n")
+	b.WriteString(MutedStyle.Render("space mark  s sync  / filter  o sort  O reverse  r refresh  q quit"))
+	return b.String()
+}
+
+// statusStyle returns the style for a repository status.
+func statusStyle(status string) lipgloss.Style {
+	switch status {
+	case "ok":
+		return SuccessStyle
+	case "error":
+		return ErrorStyle
+	default:
+		return WarningStyle
+	}
+}
+
+// RunDashboard runs the interactive status dashboard. load returns the
+// current status of the repositories and is called again on refresh;
+// sync syncs the named repositories.
+func RunDashboard(load func() ([]StatusRow, error), sync func(names []string) error) error {
+	_, err := tea.NewProgram(newDashboardModel(load, sync), tea.WithAltScreen()).Run()
+	return err
+}
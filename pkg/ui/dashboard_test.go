@@ -0,0 +1,171 @@
+package ui
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func dashboardKey(m dashboardModel, keys ...string) (dashboardModel, tea.Cmd) {
+	var cmd tea.Cmd
+	for _, key := range keys {
+		var msg tea.KeyMsg
+		switch key {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		case "backspace":
+			msg = tea.KeyMsg{Type: tea.KeyBackspace}
+		case "down":
+			msg = tea.KeyMsg{Type: tea.KeyDown}
+		case " ":
+			msg = tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+		}
+		var next tea.Model
+		next, cmd = m.Update(msg)
+		m = next.(dashboardModel)
+	}
+	return m, cmd
+}
+
+func visibleNames(m dashboardModel) []string {
+	var names []string
+	for _, r := range m.visible() {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+func newTestDashboard(t *testing.T) (dashboardModel, *[]string) {
+	t.Helper()
+	rows := []StatusRow{
+		{Name: "web", Project: "frontend", Status: "ok"},
+		{Name: "api", Project: "backend", Status: "outdated"},
+		{Name: "db", Project: "backend", Status: "dirty"},
+	}
+	var synced []string
+	m := newDashboardModel(
+		func() ([]StatusRow, error) { return rows, nil },
+		func(names []string) error {
+			synced = names
+			return nil
+		},
+	)
+	next, _ := m.Update(m.Init()())
+	return next.(dashboardModel), &synced
+}
+
+func TestDashboard_Sort(t *testing.T) {
+	m, _ := newTestDashboard(t)
+
+	if got := visibleNames(m); !reflect.DeepEqual(got, []string{"api", "db", "web"}) {
+		t.Errorf("sorted by name = %v", got)
+	}
+
+	// Project, then status
+	m, _ = dashboardKey(m, "o")
+	if got := visibleNames(m); !reflect.DeepEqual(got, []string{"api", "db", "web"}) {
+		t.Errorf("sorted by project = %v", got)
+	}
+	m, _ = dashboardKey(m, "o")
+	if got := visibleNames(m); !reflect.DeepEqual(got, []string{"db", "web", "api"}) {
+		t.Errorf("sorted by status = %v", got)
+	}
+	m, _ = dashboardKey(m, "O")
+	if got := visibleNames(m); !reflect.DeepEqual(got, []string{"api", "web", "db"}) {
+		t.Errorf("sorted by status descending = %v", got)
+	}
+	if view := m.View(); !strings.Contains(view, "STATUS ▼") {
+		t.Errorf("view does not mark the sort column:\n%s", view)
+	}
+}
+
+func TestDashboard_Filter(t *testing.T) {
+	m, _ := newTestDashboard(t)
+
+	m, _ = dashboardKey(m, "/", "b", "a", "c", "k")
+	if !m.filtering {
+		t.Fatal("expected filter mode")
+	}
+	if got := visibleNames(m); !reflect.DeepEqual(got, []string{"api", "db"}) {
+		t.Errorf("filtered by project = %v", got)
+	}
+
+	// Keys are typed into the filter rather than acting
+	m, _ = dashboardKey(m, "backspace", "backspace", "backspace", "w", "q")
+	if m.filter != "bwq" {
+		t.Errorf("filter = %q, want %q", m.filter, "bwq")
+	}
+	if got := visibleNames(m); got != nil {
+		t.Errorf("expected no matches, got %v", got)
+	}
+
+	m, _ = dashboardKey(m, "esc")
+	if m.filtering || m.filter != "" {
+		t.Errorf("esc left filtering=%v filter=%q", m.filtering, m.filter)
+	}
+	if got := len(m.visible()); got != 3 {
+		t.Errorf("expected all rows after clearing, got %d", got)
+	}
+}
+
+func TestDashboard_SyncSelected(t *testing.T) {
+	m, synced := newTestDashboard(t)
+
+	// Without marks the row under the cursor is synced
+	m, cmd := dashboardKey(m, "down", "s")
+	if cmd == nil {
+		t.Fatal("expected a sync command")
+	}
+	next, _ := m.Update(cmd())
+	m = next.(dashboardModel)
+	if !reflect.DeepEqual(*synced, []string{"db"}) {
+		t.Errorf("synced %v, want [db]", *synced)
+	}
+	if m.notice != "Synced 1 repositories" {
+		t.Errorf("notice = %q", m.notice)
+	}
+
+	// Marked rows are synced in display order
+	m.busy = ""
+	m, cmd = dashboardKey(m, " ", "down", " ", "s")
+	m.Update(cmd())
+	if !reflect.DeepEqual(*synced, []string{"db", "web"}) {
+		t.Errorf("synced %v, want [db web]", *synced)
+	}
+}
+
+func TestDashboard_SyncError(t *testing.T) {
+	m := newDashboardModel(
+		func() ([]StatusRow, error) { return []StatusRow{{Name: "api", Status: "ok"}}, nil },
+		func([]string) error { return errors.New("1 of 1 repositories failed to sync") },
+	)
+	next, _ := m.Update(m.Init()())
+	m = next.(dashboardModel)
+
+	m, cmd := dashboardKey(m, "s")
+	next, cmd = m.Update(cmd())
+	m = next.(dashboardModel)
+	if cmd == nil {
+		t.Fatal("expected a reload after sync")
+	}
+
+	// The error stays shown after the reload
+	next, _ = m.Update(cmd())
+	m = next.(dashboardModel)
+	if m.err == nil || !strings.Contains(m.View(), "failed to sync") {
+		t.Errorf("expected sync error in view:\n%s", m.View())
+	}
+
+	// Refreshing clears it
+	m, _ = dashboardKey(m, "r")
+	if m.err != nil {
+		t.Errorf("expected refresh to clear the error, got %v", m.err)
+	}
+}
@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// FoldStyle selects the markers that wrap each repository's output in the
+// non-interactive display, so CI services show it as a collapsible
+// section of the job log.
+type FoldStyle string
+
+// Fold styles. FoldAuto resolves to the style of the CI service detected
+// from the environment, or FoldOff.
+const (
+	FoldOff    FoldStyle = "off"
+	FoldAuto   FoldStyle = "auto"
+	FoldGitHub FoldStyle = "github"
+	FoldGitLab FoldStyle = "gitlab"
+)
+
+// FoldStyles are the accepted fold style names.
+var FoldStyles = []string{string(FoldAuto), string(FoldGitHub), string(FoldGitLab), string(FoldOff)}
+
+// ParseFoldStyle parses a fold style name, resolving FoldAuto with
+// getenv.
+func ParseFoldStyle(name string, getenv func(string) string) (FoldStyle, error) {
+	switch style := FoldStyle(strings.ToLower(name)); style {
+	case FoldOff, FoldGitHub, FoldGitLab:
+		return style, nil
+	case FoldAuto:
+		return DetectFoldStyle(getenv), nil
+	}
+	return "", fmt.Errorf("unsupported log group style: %s (must be %s)", name, strings.Join(FoldStyles, ", "))
+}
+
+// DetectFoldStyle returns the fold style of the CI service the process
+// runs in, judged by the variables it sets, or FoldOff.
+func DetectFoldStyle(getenv func(string) string) FoldStyle {
+	switch {
+	case getenv("GITHUB_ACTIONS") == "true":
+		return FoldGitHub
+	case getenv("GITLAB_CI") == "true":
+		return FoldGitLab
+	}
+	return FoldOff
+}
+
+// gitlabSectionInvalid matches characters not allowed in GitLab section
+// names.
+var gitlabSectionInvalid = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// writeFold writes title and lines to w as one section of style, started
+// at start. Failed sections are left expanded where the CI service allows
+// it.
+func writeFold(w io.Writer, style FoldStyle, name, title string, lines []string, start time.Time, failed bool) {
+	section := "hm_" + gitlabSectionInvalid.ReplaceAllString(name, "_")
+	switch style {
+	case FoldGitHub:
+		fmt.Fprintf(w, "::group::%s\n", title)
+	case FoldGitLab:
+		options := "[collapsed=true]"
+		if failed {
+			options = ""
+		}
+		fmt.Fprintf(w, "\x1b[0Ksection_start:%d:%s%s\r\x1b[0K%s\n", start.Unix(), section, options, title)
+	default:
+		fmt.Fprintln(w, title)
+	}
+
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+
+	switch style {
+	case FoldGitHub:
+		fmt.Fprintln(w, "::endgroup::")
+	case FoldGitLab:
+		fmt.Fprintf(w, "\x1b[0Ksection_end:%d:%s\r\x1b[0K\n", time.Now().Unix(), section)
+	}
+}
+
+// readLogLines returns the lines of a sync log, or nil if it cannot be
+// read. Of progress redrawn with carriage returns, only the final state
+// of each line is kept.
+func readLogLines(path string) []string {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	text := strings.TrimRight(strings.ReplaceAll(string(data), "\r\n", "\n"), "\r\n")
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if j := strings.LastIndexByte(line, '\r'); j >= 0 {
+			line = line[j+1:]
+		}
+		lines[i] = line
+	}
+	return lines
+}
@@ -0,0 +1,100 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tierone/harbormaster/pkg/types"
+)
+
+func TestParseFoldStyle(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    FoldStyle
+		wantErr bool
+	}{
+		{name: "off", want: FoldOff},
+		{name: "GitHub", want: FoldGitHub},
+		{name: "gitlab", env: map[string]string{"GITHUB_ACTIONS": "true"}, want: FoldGitLab},
+		{name: "auto", env: map[string]string{"GITHUB_ACTIONS": "true"}, want: FoldGitHub},
+		{name: "auto", env: map[string]string{"GITLAB_CI": "true"}, want: FoldGitLab},
+		{name: "auto", want: FoldOff},
+		{name: "jenkins", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFoldStyle(tt.name, env(tt.env))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFoldStyle(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseFoldStyle(%q) with %v = %q, want %q", tt.name, tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestSimpleOutput_FoldGitHub(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "a.log")
+	if err := os.WriteFile(logPath, []byte("Cloning into 'a'...\nReceiving objects:  50%\rReceiving objects: 100%, done.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	s := NewSimpleOutput()
+	s.out = &out
+	s.fold = FoldGitHub
+
+	s.Update(CreateProgressMsg("a", "", types.PhaseConnecting, "connecting"))
+	s.Update(CreateProgressMsg("b", "", types.PhaseFetching, "fetching"))
+	s.Update(types.ProgressMsg{RepoName: "b", Phase: types.PhaseFailed, Error: errors.New("boom")})
+	done := CreateCompletedMsg("a", "", "Synced at 0123abcd")
+	done.LogPath = logPath
+	s.Update(done)
+
+	want := []string{
+		"::group::✗ b: failed boom",
+		"● b: fetching fetching",
+		"::endgroup::",
+		"::group::✓ a: complete Synced at 0123abcd",
+		"● a: connecting connecting",
+		"Cloning into 'a'...",
+		"Receiving objects: 100%, done.",
+		"::endgroup::",
+	}
+	if got := out.String(); got != strings.Join(want, "\n")+"\n" {
+		t.Errorf("output:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestSimpleOutput_FoldGitLab(t *testing.T) {
+	var out bytes.Buffer
+	s := NewSimpleOutput()
+	s.out = &out
+	s.fold = FoldGitLab
+
+	s.Update(CreateProgressMsg("team/a b", "", types.PhaseConnecting, "connecting"))
+	s.Update(CreateCompletedMsg("team/a b", "", "done"))
+	s.Update(types.ProgressMsg{RepoName: "c", Phase: types.PhaseFailed, Error: errors.New("boom")})
+
+	got := out.String()
+	for _, want := range []string{
+		"section_start:",
+		":hm_team_a_b[collapsed=true]\r\x1b[0K✓ team/a b: complete done\n● team/a b: connecting connecting\n\x1b[0Ksection_end:",
+		":hm_team_a_b\r\x1b[0K\n",
+		":hm_c\r\x1b[0K✗ c: failed boom\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%q", want, got)
+		}
+	}
+}
@@ -12,11 +12,12 @@ import (
 type ProgressManager struct {
 	program     *tea.Program
 	model       Model
-	msgChan     chan types.ProgressMsg
-	resultChan  chan types.OperationResult
+	queue       *progressQueue
 	results     []types.OperationResult
 	resultMu    sync.Mutex
 	done        chan struct{}
+	quit        chan struct{} // Closed by Stop
+	stopped     chan struct{} // Closed once queued messages are handled
 	started     bool
 	interactive bool
 	simple      *SimpleOutput
@@ -26,10 +27,11 @@ type ProgressManager struct {
 func NewProgressManager(interactive bool) *ProgressManager {
 	pm := &ProgressManager{
 		model:       NewModel(),
-		msgChan:     make(chan types.ProgressMsg, 100),
-		resultChan:  make(chan types.OperationResult, 100),
+		queue:       newProgressQueue(),
 		results:     []types.OperationResult{},
 		done:        make(chan struct{}),
+		quit:        make(chan struct{}),
+		stopped:     make(chan struct{}),
 		interactive: interactive,
 	}
 
@@ -40,6 +42,21 @@ func NewProgressManager(interactive bool) *ProgressManager {
 	return pm
 }
 
+// SetGroups groups the interactive display by project. groups maps each
+// repository name to its project. It must be called before Start.
+func (pm *ProgressManager) SetGroups(groups map[string]string) {
+	pm.model.groups = groups
+}
+
+// SetFolding wraps each repository's output in the non-interactive display
+// in markers of style, printing it in one piece once the repository is
+// done. It must be called before Start.
+func (pm *ProgressManager) SetFolding(style FoldStyle) {
+	if pm.simple != nil {
+		pm.simple.fold = style
+	}
+}
+
 // Start initializes the UI manager.
 func (pm *ProgressManager) Start() error {
 	if pm.started {
@@ -51,85 +68,73 @@ func (pm *ProgressManager) Start() error {
 		pm.program = tea.NewProgram(pm.model)
 
 		// Start the message processor
-		go pm.processMessages()
+		go pm.processMessages(func(msg types.ProgressMsg) {
+			pm.program.Send(ProgressMsg(msg))
+		})
 
 		// Run the program in background
 		go func() {
 			_, _ = pm.program.Run()
 		}()
 	} else {
-		go pm.processMessagesSimple()
+		go pm.processMessages(pm.simple.Update)
 	}
 
 	return nil
 }
 
-func (pm *ProgressManager) processMessages() {
+// processMessages hands the queued progress of each repository to handle
+// until Complete, then handles whatever is still queued. Transfer updates
+// that are superseded while handle is busy are skipped, so a slow display
+// never blocks operations or misses their final state or log lines.
+func (pm *ProgressManager) processMessages(handle func(types.ProgressMsg)) {
+	defer close(pm.stopped)
 	for {
 		select {
-		case msg, ok := <-pm.msgChan:
-			if !ok {
-				return
+		case <-pm.queue.ready:
+			for _, msg := range pm.queue.take() {
+				handle(msg)
 			}
-			if pm.program != nil {
-				pm.program.Send(ProgressMsg(msg))
-			}
-		case result, ok := <-pm.resultChan:
-			if !ok {
-				return
-			}
-			pm.resultMu.Lock()
-			pm.results = append(pm.results, result)
-			pm.resultMu.Unlock()
 		case <-pm.done:
+			for _, msg := range pm.queue.take() {
+				handle(msg)
+			}
+			return
+		case <-pm.quit:
 			return
 		}
 	}
 }
 
-func (pm *ProgressManager) processMessagesSimple() {
-	for {
-		select {
-		case msg, ok := <-pm.msgChan:
-			if !ok {
-				return
-			}
-			if pm.simple != nil {
-				pm.simple.Update(msg)
-			}
-		case result, ok := <-pm.resultChan:
-			if !ok {
-				return
-			}
-			pm.resultMu.Lock()
-			pm.results = append(pm.results, result)
-			pm.resultMu.Unlock()
-		case <-pm.done:
-			return
-		}
+func (pm *ProgressManager) addResult(result types.OperationResult) {
+	pm.resultMu.Lock()
+	pm.results = append(pm.results, result)
+	pm.resultMu.Unlock()
+}
+
+// SetTotal announces the number of repositories about to be synced, for
+// the overall progress bar.
+func (pm *ProgressManager) SetTotal(n int) {
+	if pm.program != nil {
+		pm.program.Send(TotalMsg(n))
 	}
 }
 
-// SendProgress sends a progress update to the UI.
+// SendProgress sends a progress update to the UI without blocking. If the
+// UI has not yet shown an earlier transfer update for the same
+// repository, msg replaces it.
 func (pm *ProgressManager) SendProgress(msg types.ProgressMsg) {
-	select {
-	case pm.msgChan <- msg:
-	default:
-		// Channel full, drop message
-	}
+	pm.queue.put(msg)
 }
 
-// SendResult sends an operation result.
+// SendResult records an operation result.
 func (pm *ProgressManager) SendResult(result types.OperationResult) {
-	select {
-	case pm.resultChan <- result:
-	default:
-	}
+	pm.addResult(result)
 }
 
 // Wait blocks until Complete is called and returns the sync result.
 func (pm *ProgressManager) Wait() *types.SyncResult {
-	<-pm.done
+	<-pm.stopped
 
 	pm.resultMu.Lock()
 	defer pm.resultMu.Unlock()
@@ -137,8 +142,16 @@ func (pm *ProgressManager) Wait() *types.SyncResult {
 	return types.NewSyncResult(pm.results, 0)
 }
 
-// Complete signals that all operations are complete.
+// Complete signals that all operations are complete. Progress sent before
+// it is shown before the summary.
 func (pm *ProgressManager) Complete(duration time.Duration) {
+	close(pm.done)
+	if pm.started {
+		<-pm.stopped
+	} else {
+		close(pm.stopped)
+	}
+
 	if pm.interactive && pm.program != nil {
 		pm.program.Send(CompleteMsg{})
 		// Give the UI time to render the final state
@@ -147,14 +160,27 @@ func (pm *ProgressManager) Complete(duration time.Duration) {
 	} else if pm.simple != nil {
 		pm.simple.Complete()
 	}
+}
 
-	close(pm.done)
+// Suspend hands the terminal back, for asking the user something while
+// operations are paused. The interactive display stops drawing until
+// Resume.
+func (pm *ProgressManager) Suspend() {
+	if pm.program != nil {
+		_ = pm.program.ReleaseTerminal()
+	}
+}
+
+// Resume takes the terminal again after Suspend.
+func (pm *ProgressManager) Resume() {
+	if pm.program != nil {
+		_ = pm.program.RestoreTerminal()
+	}
 }
 
 // Stop gracefully shuts down the UI.
 func (pm *ProgressManager) Stop() {
-	close(pm.msgChan)
-	close(pm.resultChan)
+	close(pm.quit)
 
 	if pm.program != nil {
 		pm.program.Quit()
@@ -0,0 +1,108 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tierone/harbormaster/pkg/types"
+)
+
+func TestProgressManager_CompleteHandlesQueued(t *testing.T) {
+	pm := NewProgressManager(false)
+	if err := pm.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		pm.SendProgress(CreateCompletedMsg(fmt.Sprintf("repo-%02d", i), "", "done"))
+	}
+	pm.Complete(time.Second)
+
+	if got := len(pm.simple.operations); got != 20 {
+		t.Errorf("expected 20 operations before the summary, got %d", got)
+	}
+	for name, op := range pm.simple.operations {
+		if op.phase != types.PhaseComplete {
+			t.Errorf("%s: expected complete, got %s", name, op.phase)
+		}
+	}
+}
+
+func TestProgressManager_CoalescesWithoutLosingCompletion(t *testing.T) {
+	pm := NewProgressManager(false)
+	if err := pm.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	// Far more updates than any buffer, each repository ending complete
+	const repos = 300
+	for step := 0; step < 10; step++ {
+		for i := 0; i < repos; i++ {
+			name := fmt.Sprintf("repo-%03d", i)
+			pm.SendProgress(CreateProgressMsgWithPercent(name, "", types.PhaseFetching, float64(step*10), "fetching"))
+		}
+	}
+	for i := 0; i < repos; i++ {
+		pm.SendProgress(CreateCompletedMsg(fmt.Sprintf("repo-%03d", i), "", "done"))
+	}
+	pm.Complete(time.Second)
+
+	if got := len(pm.simple.operations); got != repos {
+		t.Fatalf("expected %d operations, got %d", repos, got)
+	}
+	for name, op := range pm.simple.operations {
+		if op.phase != types.PhaseComplete {
+			t.Errorf("%s: expected complete, got %s", name, op.phase)
+		}
+	}
+}
+
+func TestProgressQueue_LatestWins(t *testing.T) {
+	q := newProgressQueue()
+	q.put(types.ProgressMsg{RepoName: "b", Stage: types.StageReceiving, Message: "1"})
+	q.put(types.ProgressMsg{RepoName: "a", Message: "1"})
+	q.put(types.ProgressMsg{RepoName: "b", Stage: types.StageReceiving, Message: "2"})
+
+	msgs := q.take()
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(msgs))
+	}
+	if msgs[0].RepoName != "b" || msgs[0].Message != "2" {
+		t.Errorf("expected latest message of b first, got %+v", msgs[0])
+	}
+	if msgs[1].RepoName != "a" {
+		t.Errorf("expected a second, got %+v", msgs[1])
+	}
+	if len(q.take()) != 0 {
+		t.Error("expected take to empty the queue")
+	}
+}
+
+func TestProgressQueue_KeepsLogLines(t *testing.T) {
+	q := newProgressQueue()
+	q.put(types.ProgressMsg{RepoName: "a", Message: "Retrying in 1s"})
+	q.put(types.ProgressMsg{RepoName: "a", Stage: types.StageReceiving, Message: "Receiving objects: 10%"})
+	q.put(types.ProgressMsg{RepoName: "a", Message: "remote: Mirror is read-only"})
+	q.put(types.ProgressMsg{RepoName: "a", Stage: types.StageReceiving, Message: "Receiving objects: 50%"})
+	q.put(types.ProgressMsg{RepoName: "a", Stage: types.StageReceiving, Message: "Receiving objects: 90%"})
+
+	var got []string
+	for _, msg := range q.take() {
+		got = append(got, msg.Message)
+	}
+	want := []string{"Retrying in 1s", "remote: Mirror is read-only", "Receiving objects: 90%"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	// Lines are bounded per repository, keeping the latest
+	for i := 0; i < 3*maxQueuedLines; i++ {
+		q.put(types.ProgressMsg{RepoName: "a", Message: fmt.Sprintf("line %d", i)})
+	}
+	msgs := q.take()
+	if len(msgs) != maxQueuedLines+1 || msgs[len(msgs)-1].Message != fmt.Sprintf("line %d", 3*maxQueuedLines-1) {
+		t.Errorf("expected %d messages ending with the latest, got %d", maxQueuedLines+1, len(msgs))
+	}
+}
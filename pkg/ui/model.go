@@ -2,6 +2,9 @@ package ui
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"regexp"
 	"strings"
 	"time"
 
@@ -12,17 +15,114 @@ import (
 	"github.com/tierone/harbormaster/pkg/types"
 )
 
+// maxLogLines bounds the output kept per operation for the detail pane.
+const maxLogLines = 1000
+
 // operationState tracks the state of a single operation.
 type operationState struct {
 	repoName  string
+	repoURL   string
 	phase     types.ProgressPhase
 	percent   float64
 	message   string
+	speed     string // Transfer rate from git's progress output, if any
+	bytes     int64  // Bytes received so far, if known
+	total     int64  // Bytes to receive, if known
+	stage     types.GitStage
+	counted   int // Items done and in total in the git stage, if counted
+	countable int
+	rate      float64 // Smoothed transfer rate in bytes per second
+	sampledAt time.Time
+	stageAt   time.Time // When the percentage last started over
+	log       []string  // Distinct progress messages, oldest first
 	err       error
 	startedAt time.Time
 	endedAt   *time.Time
 }
 
+// rateSmoothing is the weight of the latest sample in the transfer rate.
+const rateSmoothing = 0.3
+
+// track updates the transfer rate and stage timing from a progress
+// message. Progress messages are stamped with the time they were created,
+// so their StartedAt serves as the sample time.
+func (o *operationState) track(msg ProgressMsg) {
+	at := msg.StartedAt
+	if o.stageAt.IsZero() || msg.Percent < o.percent {
+		o.stageAt = at
+	}
+
+	if msg.BytesDone > 0 && msg.BytesDone != o.bytes {
+		if msg.BytesDone > o.bytes && !o.sampledAt.IsZero() {
+			if dt := at.Sub(o.sampledAt).Seconds(); dt > 0 {
+				rate := float64(msg.BytesDone-o.bytes) / dt
+				if o.rate == 0 {
+					o.rate = rate
+				} else {
+					o.rate = rateSmoothing*rate + (1-rateSmoothing)*o.rate
+				}
+			}
+		}
+		o.bytes = msg.BytesDone
+		o.sampledAt = at
+	}
+	if msg.BytesTotal > 0 {
+		o.total = msg.BytesTotal
+	}
+}
+
+// stageText describes the git stage with its counters, e.g. "receiving
+// 450/1000 objects", or returns "" if they are unknown.
+func (o *operationState) stageText() string {
+	if o.countable == 0 {
+		return string(o.stage)
+	}
+	text := fmt.Sprintf("%d/%d %s", o.counted, o.countable, o.stage.Unit())
+	if o.stage != "" {
+		text = string(o.stage) + " " + text
+	}
+	return text
+}
+
+// speedText returns the transfer rate, or "" if it is unknown.
+func (o *operationState) speedText() string {
+	if o.rate > 0 {
+		return FormatBytes(int64(o.rate)) + "/s"
+	}
+	return o.speed
+}
+
+// eta estimates the time left in the current transfer: from the byte
+// counters when the size is known, otherwise from how fast the
+// percentage of the current stage has grown.
+func (o *operationState) eta(now time.Time) (time.Duration, bool) {
+	switch {
+	case o.isComplete():
+		return 0, false
+	case o.total > 0 && o.rate > 0:
+		return time.Duration(float64(o.total-o.bytes) / o.rate * float64(time.Second)), true
+	case o.percent > 0 && o.percent < 100 && !o.stageAt.IsZero():
+		elapsed := now.Sub(o.stageAt)
+		return time.Duration(float64(elapsed) * (100 - o.percent) / o.percent), true
+	}
+	return 0, false
+}
+
+// speedRegex matches the transfer rate in git's progress output, e.g.
+// "Receiving objects:  45% (450/1000), 1.20 MiB | 3.40 MiB/s".
+var speedRegex = regexp.MustCompile(`(\d+(?:\.\d+)? [KMGT]?i?B/s)`)
+
+// record appends a message to the operation's log.
+func (o *operationState) record(line string) {
+	if line == "" || (len(o.log) > 0 && o.log[len(o.log)-1] == line) {
+		return
+	}
+	o.log = append(o.log, line)
+	if len(o.log) > maxLogLines {
+		o.log = o.log[len(o.log)-maxLogLines:]
+	}
+}
+
 func (o *operationState) isComplete() bool {
 	return o.phase == types.PhaseComplete || o.phase == types.PhaseFailed
 }
@@ -34,13 +134,36 @@ func (o *operationState) duration() time.Duration {
 	return time.Since(o.startedAt)
 }
 
+// collapseMode controls whether successfully completed operations are
+// folded into a single line.
+type collapseMode int
+
+const (
+	collapseAuto collapseMode = iota // Collapse when the list does not fit
+	collapseOn
+	collapseOff
+)
+
+// Lines used by the header, overall progress, and footer around the
+// operation list.
+const chromeLines = 5
+
 // Model is the Bubbletea model for the progress UI.
 type Model struct {
 	operations map[string]*operationState
 	order      []string // Maintains insertion order
+	total      int      // Repositories in the sync, if announced
+	startedAt  time.Time
+	groups     map[string]string // Project of each repository; nil for a flat list
 	spinner    spinner.Model
 	progress   progress.Model
 	width      int
+	height     int    // Terminal height; 0 until known, which disables scrolling
+	offset     int    // First visible row of the operation list
+	selected   int    // Row under the cursor
+	cursor     bool   // Whether the cursor is shown; set by the first navigation key
+	detail     string // Repository shown in the detail pane, if any
+	collapse   collapseMode
 	quitting   bool
 	done       bool
 }
@@ -51,11 +174,11 @@ func NewModel() Model {
 	s.Spinner = spinner.Dot
 	s.Style = SpinnerStyle
 
-	p := progress.New(
-		progress.WithDefaultGradient(),
+	p := progress.New(append([]progress.Option{
 		progress.WithWidth(40),
 		progress.WithoutPercentage(),
-	)
+		progress.WithColorProfile(lipgloss.ColorProfile()),
+	}, progressTheme()...)...)
 
 	return Model{
 		operations: make(map[string]*operationState),
@@ -69,6 +192,9 @@ func NewModel() Model {
 // ProgressMsg is sent to update operation progress.
 type ProgressMsg types.ProgressMsg
 
+// TotalMsg announces the number of repositories in the sync.
+type TotalMsg int
+
 // CompleteMsg signals that all operations are complete.
 type CompleteMsg struct{}
 
@@ -86,10 +212,46 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.quitting = true
 			return m, tea.Quit
 		}
+		if m.detail != "" {
+			switch msg.String() {
+			case "esc", "enter", "backspace", "left", "h":
+				m.detail = ""
+			}
+			return m, nil
+		}
+		switch msg.String() {
+		case "up", "k":
+			m.move(-1)
+		case "down", "j":
+			m.move(1)
+		case "pgup", "b":
+			m.move(-m.listHeight())
+		case "pgdown", "f", " ":
+			m.move(m.listHeight())
+		case "home", "g":
+			m.move(-len(m.rows()))
+		case "end", "G":
+			m.move(len(m.rows()))
+		case "enter", "right", "l":
+			rows := m.rows()
+			if m.selected < len(rows) && rows[m.selected].name != "" {
+				m.detail = rows[m.selected].name
+			}
+		case "c":
+			if m.collapsed() {
+				m.collapse = collapseOff
+			} else {
+				m.collapse = collapseOn
+			}
+			m.scroll(0)
+		}
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
-		m.progress.Width = msg.Width - 50
+		m.height = msg.Height
+		m.scroll(0)
+		// Leave room for the name, percentage, speed, and ETA
+		m.progress.Width = msg.Width - 65
 		if m.progress.Width < 20 {
 			m.progress.Width = 20
 		}
@@ -101,6 +263,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case ProgressMsg:
 		m.updateOperation(msg)
+		m.scroll(0)
+		return m, nil
+
+	case TotalMsg:
+		m.total = int(msg)
 		return m, nil
 
 	case CompleteMsg:
@@ -120,12 +287,29 @@ func (m *Model) updateOperation(msg ProgressMsg) {
 		}
 		m.operations[msg.RepoName] = op
 		m.order = append(m.order, msg.RepoName)
+		if m.startedAt.IsZero() {
+			m.startedAt = msg.StartedAt
+		}
 	}
 
+	op.track(msg)
 	op.phase = msg.Phase
 	op.percent = msg.Percent
+	op.stage = msg.Stage
+	op.counted = msg.ObjectsDone
+	op.countable = msg.ObjectsTotal
 	op.message = msg.Message
 	op.err = msg.Error
+	if msg.RepoURL != "" {
+		op.repoURL = msg.RepoURL
+	}
+	if speed := speedRegex.FindString(msg.Message); speed != "" {
+		op.speed = speed
+	}
+	op.record(msg.Message)
+	if msg.Error != nil {
+		op.record(msg.Error.Error())
+	}
 
 	if msg.CompletedAt != nil {
 		op.endedAt = msg.CompletedAt
@@ -137,17 +321,36 @@ func (m Model) View() string {
 	if m.quitting {
 		return ""
 	}
+	if op, ok := m.operations[m.detail]; ok && !m.done {
+		return m.renderDetail(op)
+	}
 
 	var b strings.Builder
 
 	// Header
-	b.WriteString(HeaderStyle.Render("Harbormaster Sync"))
+	b.WriteString(TitleStyle.Render("Harbormaster Sync"))
+	b.WriteString("  ")
+	b.WriteString(m.renderCounts())
+	b.WriteString("\n")
+	b.WriteString(m.renderOverall())
 	b.WriteString("\n\n")
 
-	// Operations
-	for _, name := range m.order {
-		op := m.operations[name]
-		b.WriteString(m.renderOperation(op))
+	// Operations, limited to the rows that fit
+	rows := m.rows()
+	height := m.listHeight()
+	end := len(rows)
+	if height > 0 && m.offset+height < end {
+		end = m.offset + height
+	}
+	for i := min(m.offset, len(rows)); i < end; i++ {
+		if m.cursor && !m.done {
+			if i == m.selected {
+				b.WriteString(HighlightStyle.Render("›") + " ")
+			} else {
+				b.WriteString("  ")
+			}
+		}
+		b.WriteString(rows[i].text)
 		b.WriteString("\n")
 	}
 
@@ -158,12 +361,351 @@ func (m Model) View() string {
 	} else {
 		// Help text
 		b.WriteString("\n")
-		b.WriteString(MutedStyle.Render("Press q to quit"))
+		help := "↑/↓ select  enter details  q quit"
+		if height > 0 && len(rows) > height {
+			help = fmt.Sprintf("rows %d-%d of %d  %s", m.offset+1, end, len(rows), help)
+		}
+		if m.collapsed() {
+			help += "  c show completed"
+		} else {
+			help += "  c hide completed"
+		}
+		b.WriteString(MutedStyle.Render(help))
 	}
 
 	return b.String()
 }
 
+// counts returns the number of running, completed, and failed operations.
+func (m *Model) counts() (running, done, failed int) {
+	for _, op := range m.operations {
+		switch {
+		case !op.isComplete():
+			running++
+		case op.err != nil:
+			failed++
+		default:
+			done++
+		}
+	}
+	return running, done, failed
+}
+
+func (m *Model) renderCounts() string {
+	running, done, failed := m.counts()
+	parts := []string{
+		fmt.Sprintf("%d running", running),
+		SuccessStyle.Render(fmt.Sprintf("%d done", done)),
+	}
+	failedText := fmt.Sprintf("%d failed", failed)
+	if failed > 0 {
+		failedText = ErrorStyle.Render(failedText)
+	}
+	parts = append(parts, failedText)
+	return strings.Join(parts, MutedStyle.Render(" / "))
+}
+
+// fraction returns how much of the sync is done, counting finished
+// repositories and the progress of running ones.
+func (m *Model) fraction(total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	var done float64
+	for _, op := range m.operations {
+		if op.isComplete() {
+			done++
+		} else {
+			done += op.percent / 100
+		}
+	}
+	return min(done/float64(total), 1)
+}
+
+// renderOverall renders the progress of the whole sync: finished
+// repositories out of the total, bytes received where known, and the
+// elapsed and estimated remaining time.
+func (m *Model) renderOverall() string {
+	total := max(m.total, len(m.operations))
+	if total == 0 {
+		return MutedStyle.Render("Starting...")
+	}
+
+	_, done, failed := m.counts()
+	fraction := m.fraction(total)
+	parts := []string{fmt.Sprintf("%d/%d repositories", done+failed, total)}
+
+	var bytes int64
+	for _, op := range m.operations {
+		bytes += op.bytes
+	}
+	if bytes > 0 {
+		parts = append(parts, FormatBytes(bytes))
+	}
+
+	elapsed := time.Since(m.startedAt)
+	parts = append(parts, "elapsed "+elapsed.Round(time.Second).String())
+	if !m.done && fraction > 0 && fraction < 1 {
+		remaining := time.Duration(float64(elapsed) * (1 - fraction) / fraction)
+		parts = append(parts, "ETA "+remaining.Round(time.Second).String())
+	}
+
+	return m.progress.ViewAs(fraction) + "  " + MutedStyle.Render(strings.Join(parts, "  "))
+}
+
+// FormatBytes formats a byte count with a binary unit, as git does.
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, exp := float64(n)/unit, 0
+	for value >= unit && exp < 3 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGT"[exp])
+}
+
+// row is one line of the operation list. Its name is empty for the line
+// that stands in for collapsed operations.
+type row struct {
+	name string
+	text string
+}
+
+// rows renders the operation list. When collapsed, successfully completed
+// operations are replaced by a single line counting them.
+//
+// When the operations span more than one project they are listed under
+// project headings instead, and collapsed operations are only counted in
+// their heading.
+func (m *Model) rows() []row {
+	collapsed := m.collapsed()
+	hide := func(op *operationState) bool {
+		return collapsed && op.isComplete() && op.err == nil
+	}
+
+	groups := m.groupOrder()
+	if len(groups) > 1 {
+		rows := make([]row, 0, len(m.order)+len(groups))
+		for _, group := range groups {
+			names := m.groupMembers(group)
+			rows = append(rows, row{text: m.renderGroupHeader(group, names)})
+			for _, name := range names {
+				if op := m.operations[name]; !hide(op) {
+					rows = append(rows, row{name: name, text: "  " + m.renderOperation(op)})
+				}
+			}
+		}
+		return rows
+	}
+
+	rows := make([]row, 0, len(m.order))
+	hidden := 0
+	for _, name := range m.order {
+		op := m.operations[name]
+		if hide(op) {
+			hidden++
+			continue
+		}
+		rows = append(rows, row{name: name, text: m.renderOperation(op)})
+	}
+	if hidden > 0 {
+		summary := row{text: fmt.Sprintf("%s %s", SymbolSuccess, MutedStyle.Render(fmt.Sprintf("%d completed", hidden)))}
+		rows = append([]row{summary}, rows...)
+	}
+	return rows
+}
+
+// groupOrder returns the projects of the current operations in the order
+// they first started, with repositories outside any project last.
+func (m *Model) groupOrder() []string {
+	if m.groups == nil {
+		return nil
+	}
+	var groups []string
+	seen := make(map[string]bool)
+	ungrouped := false
+	for _, name := range m.order {
+		group := m.groups[name]
+		if group == "" {
+			ungrouped = true
+			continue
+		}
+		if !seen[group] {
+			seen[group] = true
+			groups = append(groups, group)
+		}
+	}
+	if ungrouped {
+		groups = append(groups, "")
+	}
+	return groups
+}
+
+// groupMembers returns the operations in a project in insertion order.
+func (m *Model) groupMembers(group string) []string {
+	var names []string
+	for _, name := range m.order {
+		if m.groups[name] == group {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// renderGroupHeader renders a project heading with its completion counts.
+func (m *Model) renderGroupHeader(group string, names []string) string {
+	var done, failed int
+	for _, name := range names {
+		op := m.operations[name]
+		switch {
+		case !op.isComplete():
+		case op.err != nil:
+			failed++
+		default:
+			done++
+		}
+	}
+
+	if group == "" {
+		group = "Other"
+	}
+	text := TitleStyle.Render(group) + MutedStyle.Render(fmt.Sprintf(" %d/%d done", done, len(names)))
+	if failed > 0 {
+		text += MutedStyle.Render(", ") + ErrorStyle.Render(fmt.Sprintf("%d failed", failed))
+	}
+	return text
+}
+
+// move moves the cursor by delta rows and scrolls to keep it visible.
+func (m *Model) move(delta int) {
+	m.cursor = true
+	rows := len(m.rows())
+	m.selected = max(min(m.selected+delta, rows-1), 0)
+
+	height := m.listHeight()
+	if height > 0 {
+		if m.selected < m.offset {
+			m.offset = m.selected
+		} else if m.selected >= m.offset+height {
+			m.offset = m.selected - height + 1
+		}
+	}
+	m.scroll(0)
+}
+
+// collapsed reports whether completed operations are folded away.
+func (m *Model) collapsed() bool {
+	switch m.collapse {
+	case collapseOn:
+		return true
+	case collapseOff:
+		return false
+	default:
+		height := m.listHeight()
+		if height == 0 {
+			return false
+		}
+		lines := len(m.order)
+		if groups := m.groupOrder(); len(groups) > 1 {
+			lines += len(groups)
+		}
+		return lines > height
+	}
+}
+
+// listHeight returns the number of operation rows that fit on screen, or
+// 0 if the terminal height is unknown.
+func (m *Model) listHeight() int {
+	if m.height == 0 {
+		return 0
+	}
+	return max(m.height-chromeLines, 1)
+}
+
+// scroll moves the list by delta rows, keeping it within bounds.
+func (m *Model) scroll(delta int) {
+	m.offset += delta
+	height := m.listHeight()
+	if height == 0 {
+		m.offset = 0
+		return
+	}
+	rows := len(m.rows())
+	m.offset = min(m.offset, max(rows-height, 0))
+	m.offset = max(m.offset, 0)
+	m.selected = max(min(m.selected, rows-1), 0)
+}
+
+// renderDetail renders the detail pane for one operation: its state,
+// error, and as much of its output as fits.
+func (m *Model) renderDetail(op *operationState) string {
+	var b strings.Builder
+
+	b.WriteString(TitleStyle.Render("Harbormaster Sync"))
+	b.WriteString("  ")
+	b.WriteString(m.renderCounts())
+	b.WriteString("\n\n")
+
+	b.WriteString(m.getSymbol(op) + " " + RepoNameStyle.UnsetWidth().Render(op.repoName))
+	b.WriteString("\n")
+
+	field := func(label, value string) {
+		b.WriteString(MutedStyle.Render(fmt.Sprintf("  %-9s", label)))
+		b.WriteString(value)
+		b.WriteString("\n")
+	}
+	if op.repoURL != "" {
+		field("URL", op.repoURL)
+	}
+	phase := PhaseColor(string(op.phase)).Render(string(op.phase))
+	if op.percent > 0 && !op.isComplete() {
+		phase += fmt.Sprintf(" (%.0f%%)", op.percent)
+	}
+	field("Phase", phase)
+	if stage := op.stageText(); stage != "" && !op.isComplete() {
+		field("Stage", stage)
+	}
+	field("Elapsed", op.duration().Round(time.Millisecond).String())
+	if speed := op.speedText(); speed != "" {
+		field("Speed", speed)
+	}
+	if op.bytes > 0 {
+		received := FormatBytes(op.bytes)
+		if op.total > 0 {
+			received += " of " + FormatBytes(op.total)
+		}
+		field("Received", received)
+	}
+	if eta, ok := op.eta(time.Now()); ok {
+		field("ETA", eta.Round(time.Second).String())
+	}
+	if op.err != nil {
+		field("Error", ErrorStyle.Render(op.err.Error()))
+	}
+
+	b.WriteString("\n")
+	b.WriteString(MutedStyle.Render("  Output"))
+	b.WriteString("\n")
+
+	// Header, fields, and footer take about a dozen lines
+	lines := op.log
+	if m.height > 0 {
+		lines = lines[max(len(lines)-max(m.height-12, 3), 0):]
+	}
+	for _, line := range lines {
+		b.WriteString("  ")
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(MutedStyle.Render("esc back  q quit"))
+	return b.String()
+}
+
 func (m *Model) renderOperation(op *operationState) string {
 	var b strings.Builder
 
@@ -191,6 +733,21 @@ func (m *Model) renderOperation(op *operationState) string {
 		// Show progress bar or phase
 		if op.percent > 0 {
 			b.WriteString(m.progress.ViewAs(op.percent / 100))
+			b.WriteString(fmt.Sprintf(" %3.0f%%", op.percent))
+			var details []string
+			if stage := op.stageText(); stage != "" {
+				details = append(details, stage)
+			}
+			if speed := op.speedText(); speed != "" {
+				details = append(details, speed)
+			}
+			if eta, ok := op.eta(time.Now()); ok {
+				details = append(details, "ETA "+eta.Round(time.Second).String())
+			}
+			if len(details) > 0 {
+				b.WriteString("  ")
+				b.WriteString(MutedStyle.Render(strings.Join(details, "  ")))
+			}
 		} else {
 			phase := PhaseColor(string(op.phase)).Render(string(op.phase))
 			b.WriteString(phase)
@@ -274,12 +831,18 @@ func Run(m Model) (Model, error) {
 // Simple progress output for non-interactive mode.
 type SimpleOutput struct {
 	operations map[string]*operationState
+	out        io.Writer
+	fold       FoldStyle
+	held       map[string][]string // Lines of unfinished operations, when folding
 }
 
 // NewSimpleOutput creates a simple non-interactive output.
 func NewSimpleOutput() *SimpleOutput {
 	return &SimpleOutput{
 		operations: make(map[string]*operationState),
+		out:        os.Stdout,
+		fold:       FoldOff,
+		held:       make(map[string][]string),
 	}
 }
 
@@ -300,12 +863,24 @@ func (s *SimpleOutput) Update(msg types.ProgressMsg) {
 	op.err = msg.Error
 
 	// Print on phase change or completion
-	if prevPhase != op.phase || op.isComplete() {
-		s.print(op)
+	if prevPhase == op.phase && !op.isComplete() {
+		return
+	}
+	line := s.line(op)
+	switch {
+	case s.fold == FoldOff:
+		fmt.Fprintln(s.out, line)
+	case !op.isComplete():
+		s.held[op.repoName] = append(s.held[op.repoName], line)
+	default:
+		// The final line heads the section, so it shows when collapsed
+		lines := append(s.held[op.repoName], readLogLines(msg.LogPath)...)
+		delete(s.held, op.repoName)
+		writeFold(s.out, s.fold, op.repoName, line, lines, op.startedAt, op.err != nil)
 	}
 }
 
-func (s *SimpleOutput) print(op *operationState) {
+func (s *SimpleOutput) line(op *operationState) string {
 	symbol := "●"
 	style := lipgloss.NewStyle()
 
@@ -323,7 +898,7 @@ func (s *SimpleOutput) print(op *operationState) {
 		msg = op.err.Error()
 	}
 
-	fmt.Printf("%s %s: %s %s\n",
+	return fmt.Sprintf("%s %s: %s %s",
 		style.Render(symbol),
 		op.repoName,
 		string(op.phase),
@@ -342,10 +917,10 @@ func (s *SimpleOutput) Complete() {
 		}
 	}
 
-	fmt.Println()
+	fmt.Fprintln(s.out)
 	if failed == 0 {
-		fmt.Printf("✓ All %d repositories synced successfully\n", success)
+		fmt.Fprintf(s.out, "✓ All %d repositories synced successfully\n", success)
 	} else {
-		fmt.Printf("✓ %d synced, ✗ %d failed\n", success, failed)
+		fmt.Fprintf(s.out, "✓ %d synced, ✗ %d failed\n", success, failed)
 	}
 }
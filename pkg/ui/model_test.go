@@ -0,0 +1,341 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/tierone/harbormaster/pkg/types"
+)
+
+// newTestModel returns a model with n operations of which the first done
+// completed and the next failed failed.
+func newTestModel(n, done, failed int) Model {
+	m := NewModel()
+	now := time.Now()
+	for i := 0; i < n; i++ {
+		msg := types.ProgressMsg{
+			RepoName:  fmt.Sprintf("repo-%02d", i),
+			Phase:     types.PhaseFetching,
+			StartedAt: now,
+		}
+		switch {
+		case i < done:
+			msg.Phase = types.PhaseComplete
+			msg.CompletedAt = &now
+		case i < done+failed:
+			msg.Phase = types.PhaseFailed
+			msg.Error = errors.New("clone failed")
+			msg.CompletedAt = &now
+		}
+		m.updateOperation(ProgressMsg(msg))
+	}
+	return m
+}
+
+func update(m Model, msg tea.Msg) Model {
+	next, _ := m.Update(msg)
+	return next.(Model)
+}
+
+func TestModel_Counts(t *testing.T) {
+	m := newTestModel(10, 6, 1)
+
+	running, done, failed := m.counts()
+	if running != 3 || done != 6 || failed != 1 {
+		t.Errorf("expected 3/6/1, got %d/%d/%d", running, done, failed)
+	}
+	if view := m.View(); !strings.Contains(view, "3 running") || !strings.Contains(view, "6 done") || !strings.Contains(view, "1 failed") {
+		t.Errorf("expected counts in header, got:\n%s", view)
+	}
+}
+
+func TestModel_FitsWithoutScrolling(t *testing.T) {
+	m := update(newTestModel(5, 2, 0), tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	if m.collapsed() {
+		t.Error("expected small lists not to collapse")
+	}
+	view := m.View()
+	for i := 0; i < 5; i++ {
+		if !strings.Contains(view, fmt.Sprintf("repo-%02d", i)) {
+			t.Errorf("expected repo-%02d in view", i)
+		}
+	}
+}
+
+func TestModel_CollapsesCompleted(t *testing.T) {
+	m := update(newTestModel(80, 70, 2), tea.WindowSizeMsg{Width: 100, Height: 30})
+
+	if !m.collapsed() {
+		t.Fatal("expected large list to collapse")
+	}
+	// One summary line, two failures, eight running
+	if rows := m.rows(); len(rows) != 11 {
+		t.Errorf("expected 11 rows, got %d", len(rows))
+	}
+	view := m.View()
+	if !strings.Contains(view, "70 completed") {
+		t.Errorf("expected collapsed summary, got:\n%s", view)
+	}
+	if strings.Contains(view, "repo-00") {
+		t.Error("expected completed repository to be hidden")
+	}
+
+	// c expands the completed items again
+	m = update(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+	if m.collapsed() || len(m.rows()) != 80 {
+		t.Errorf("expected all 80 rows after toggle, got %d", len(m.rows()))
+	}
+}
+
+func TestModel_Scroll(t *testing.T) {
+	m := update(newTestModel(50, 0, 0), tea.WindowSizeMsg{Width: 100, Height: 24})
+	height := m.listHeight()
+
+	if lines := strings.Count(m.View(), "\n"); lines > 24 {
+		t.Errorf("expected view to fit in 24 lines, got %d", lines)
+	}
+	if !strings.Contains(m.View(), fmt.Sprintf("rows 1-%d of 50", height)) {
+		t.Errorf("expected scroll position in footer, got:\n%s", m.View())
+	}
+
+	// The list scrolls once the cursor leaves the visible rows
+	for i := 0; i < height; i++ {
+		m = update(m, tea.KeyMsg{Type: tea.KeyDown})
+	}
+	if m.selected != height || m.offset != 1 {
+		t.Errorf("expected selection %d at offset 1, got %d at %d", height, m.selected, m.offset)
+	}
+	if strings.Contains(m.View(), "repo-00") {
+		t.Error("expected first row scrolled out of view")
+	}
+
+	m = update(m, tea.KeyMsg{Type: tea.KeyEnd})
+	if m.selected != 49 || m.offset != 50-height {
+		t.Errorf("expected last row selected at offset %d, got %d at %d", 50-height, m.selected, m.offset)
+	}
+	m = update(m, tea.KeyMsg{Type: tea.KeyDown})
+	if m.selected != 49 {
+		t.Errorf("expected selection to stay on the last row, got %d", m.selected)
+	}
+
+	m = update(m, tea.KeyMsg{Type: tea.KeyHome})
+	m = update(m, tea.KeyMsg{Type: tea.KeyUp})
+	if m.selected != 0 || m.offset != 0 {
+		t.Errorf("expected first row at offset 0, got %d at %d", m.selected, m.offset)
+	}
+}
+
+func TestModel_Groups(t *testing.T) {
+	m := newTestModel(5, 2, 1)
+	m.groups = map[string]string{
+		"repo-00": "core",
+		"repo-01": "web",
+		"repo-02": "core",
+		"repo-03": "web",
+	}
+
+	// core, its two repositories, web, its two, then Other with repo-04
+	rows := m.rows()
+	var names []string
+	for _, r := range rows {
+		names = append(names, r.name)
+	}
+	want := []string{"", "repo-00", "repo-02", "", "repo-01", "repo-03", "", "repo-04"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected rows %v, got %v", want, names)
+	}
+
+	view := m.View()
+	for _, s := range []string{"core 1/2 done, 1 failed", "web 1/2 done", "Other 0/1 done"} {
+		if !strings.Contains(view, s) {
+			t.Errorf("expected %q in view, got:\n%s", s, view)
+		}
+	}
+
+	// Collapsing hides completed repositories but keeps their headings
+	m.collapse = collapseOn
+	if got := len(m.rows()); got != 6 {
+		t.Errorf("expected 6 collapsed rows, got %d", got)
+	}
+
+	// A single project is listed flat
+	m.groups = map[string]string{"repo-00": "core", "repo-01": "core", "repo-02": "core", "repo-03": "core", "repo-04": "core"}
+	m.collapse = collapseOff
+	if got := len(m.rows()); got != 5 {
+		t.Errorf("expected 5 flat rows, got %d", got)
+	}
+}
+
+func TestModel_Overall(t *testing.T) {
+	m := newTestModel(4, 1, 1)
+	m = update(m, TotalMsg(10))
+	m.startedAt = time.Now().Add(-30 * time.Second)
+	m.operations["repo-02"].percent = 50
+	m.operations["repo-02"].bytes = 1 << 20
+	m.operations["repo-03"].bytes = 512 << 10
+
+	// Two finished and one half done out of ten
+	if got := m.fraction(10); got != 0.25 {
+		t.Errorf("expected fraction 0.25, got %v", got)
+	}
+
+	view := m.View()
+	for _, want := range []string{"2/10 repositories", "1.5 MiB", "elapsed 30s", "ETA 1m30s"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in view, got:\n%s", want, view)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1024:            "1.0 KiB",
+		1536 << 10:      "1.5 MiB",
+		3 << 30:         "3.0 GiB",
+		5 << 40:         "5.0 TiB",
+		(5 << 40) * 100: "500.0 TiB",
+	}
+	for n, want := range tests {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestModel_Detail(t *testing.T) {
+	m := update(newTestModel(3, 0, 1), tea.WindowSizeMsg{Width: 100, Height: 40})
+	for _, line := range []string{
+		"Cloning repository...",
+		"Receiving objects:  45% (450/1000), 1.20 MiB | 3.40 MiB/s",
+	} {
+		m = update(m, ProgressMsg{RepoName: "repo-01", RepoURL: "https://example.com/r.git", Phase: types.PhaseFetching, Message: line})
+	}
+
+	// Select the second repository and open it
+	m = update(m, tea.KeyMsg{Type: tea.KeyDown})
+	m = update(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.detail != "repo-01" {
+		t.Fatalf("expected detail pane for repo-01, got %q", m.detail)
+	}
+
+	view := m.View()
+	for _, want := range []string{"https://example.com/r.git", "fetching", "3.40 MiB/s", "Cloning repository...", "Receiving objects"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in detail pane, got:\n%s", want, view)
+		}
+	}
+
+	m = update(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.detail != "" {
+		t.Error("expected esc to close the detail pane")
+	}
+
+	// The failed repository shows its full error
+	m = update(m, tea.KeyMsg{Type: tea.KeyUp})
+	m = update(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if view := m.View(); !strings.Contains(view, "Error") || !strings.Contains(view, "clone failed") {
+		t.Errorf("expected error in detail pane, got:\n%s", view)
+	}
+}
+
+func TestOperationState_Record(t *testing.T) {
+	op := &operationState{}
+	op.record("a")
+	op.record("a")
+	op.record("")
+	op.record("b")
+	if len(op.log) != 2 {
+		t.Errorf("expected repeated and empty messages to be skipped, got %v", op.log)
+	}
+
+	for i := 0; i < maxLogLines+10; i++ {
+		op.record(fmt.Sprint(i))
+	}
+	if len(op.log) != maxLogLines || op.log[len(op.log)-1] != fmt.Sprint(maxLogLines+9) {
+		t.Errorf("expected the latest %d lines, got %d ending %q", maxLogLines, len(op.log), op.log[len(op.log)-1])
+	}
+}
+
+func TestOperationState_Track(t *testing.T) {
+	start := time.Now()
+	at := func(seconds float64) time.Time {
+		return start.Add(time.Duration(seconds * float64(time.Second)))
+	}
+	const mib = 1 << 20
+
+	op := &operationState{}
+	op.track(ProgressMsg{BytesDone: mib, BytesTotal: 10 * mib, StartedAt: at(0)})
+	if op.rate != 0 {
+		t.Fatalf("expected no rate from one sample, got %v", op.rate)
+	}
+
+	op.track(ProgressMsg{BytesDone: 3 * mib, StartedAt: at(1)})
+	if op.rate != 2*mib {
+		t.Errorf("expected 2 MiB/s, got %v", op.rate)
+	}
+	if got := op.speedText(); got != "2.0 MiB/s" {
+		t.Errorf("expected speed 2.0 MiB/s, got %q", got)
+	}
+	if eta, ok := op.eta(at(1)); !ok || eta != 3500*time.Millisecond {
+		t.Errorf("expected ETA 3.5s, got %v (%v)", eta, ok)
+	}
+
+	// The rate is smoothed
+	op.track(ProgressMsg{BytesDone: 4 * mib, StartedAt: at(2)})
+	if want := 0.3*mib + 0.7*2*mib; op.rate != want {
+		t.Errorf("expected smoothed rate %v, got %v", want, op.rate)
+	}
+}
+
+func TestOperationState_PercentETA(t *testing.T) {
+	start := time.Now()
+	m := NewModel()
+	send := func(seconds int, percent float64) {
+		m.updateOperation(ProgressMsg{
+			RepoName:  "repo",
+			Phase:     types.PhaseFetching,
+			Percent:   percent,
+			StartedAt: start.Add(time.Duration(seconds) * time.Second),
+		})
+	}
+
+	send(0, 10)
+	send(10, 25)
+	op := m.operations["repo"]
+	if eta, ok := op.eta(start.Add(10 * time.Second)); !ok || eta != 30*time.Second {
+		t.Errorf("expected ETA 30s, got %v (%v)", eta, ok)
+	}
+
+	// A new stage starts the estimate over
+	send(20, 5)
+	if eta, ok := op.eta(start.Add(21 * time.Second)); !ok || eta != 19*time.Second {
+		t.Errorf("expected ETA 19s after a new stage, got %v (%v)", eta, ok)
+	}
+	if view := m.View(); !strings.Contains(view, "  5%") {
+		t.Errorf("expected percentage in view, got:\n%s", view)
+	}
+}
+
+func TestOperationState_StageText(t *testing.T) {
+	tests := []struct {
+		op   operationState
+		want string
+	}{
+		{operationState{}, ""},
+		{operationState{stage: types.StageReceiving, counted: 450, countable: 1000}, "receiving 450/1000 objects"},
+		{operationState{stage: types.StageResolving, counted: 3, countable: 9}, "resolving 3/9 deltas"},
+		{operationState{counted: 1, countable: 2}, "1/2 objects"},
+	}
+	for _, tt := range tests {
+		if got := tt.op.stageText(); got != tt.want {
+			t.Errorf("stageText() = %q, want %q", got, tt.want)
+		}
+	}
+}
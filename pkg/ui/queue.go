@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"sync"
+
+	"github.com/tierone/harbormaster/pkg/types"
+)
+
+// maxQueuedLines bounds the superseded messages kept per repository for
+// the lines they add to its log.
+const maxQueuedLines = 20
+
+// progressQueue holds the undelivered progress messages of each
+// repository. Sending never blocks and never loses a repository's latest
+// state: a transfer progress update still waiting when a newer message
+// for the same repository arrives is replaced by it. Messages with a line
+// of their own for the log, such as retry notices, remote messages, and
+// errors, are kept, up to maxQueuedLines per repository, so the queue is
+// bounded by the number of repositories.
+type progressQueue struct {
+	mu      sync.Mutex
+	pending map[string][]types.ProgressMsg // Oldest first; the last is the latest state
+	order   []string                       // Repositories with pending messages, oldest first
+	ready   chan struct{}                  // Holds a value while messages are pending
+}
+
+func newProgressQueue() *progressQueue {
+	return &progressQueue{
+		pending: make(map[string][]types.ProgressMsg),
+		ready:   make(chan struct{}, 1),
+	}
+}
+
+// put queues msg, replacing the latest pending message of the same
+// repository unless that adds a line to the log.
+func (q *progressQueue) put(msg types.ProgressMsg) {
+	q.mu.Lock()
+	msgs, ok := q.pending[msg.RepoName]
+	if !ok {
+		q.order = append(q.order, msg.RepoName)
+	}
+	if n := len(msgs); n > 0 && !logsLine(msgs[n-1], msg) {
+		msgs = msgs[:n-1]
+	}
+	if len(msgs) > maxQueuedLines {
+		msgs = append(msgs[:0], msgs[len(msgs)-maxQueuedLines:]...)
+	}
+	q.pending[msg.RepoName] = append(msgs, msg)
+	q.mu.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+		// Already signaled
+	}
+}
+
+// logsLine reports whether msg, superseded by next, adds a line to the
+// log that next does not repeat. Transfer progress updates only change
+// the state, which next replaces.
+func logsLine(msg, next types.ProgressMsg) bool {
+	if msg.Error != nil {
+		return true
+	}
+	return msg.Message != "" && msg.Stage == "" && msg.Message != next.Message
+}
+
+// take removes and returns the pending messages, grouped by repository
+// in the order the repositories were first queued.
+func (q *progressQueue) take() []types.ProgressMsg {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var msgs []types.ProgressMsg
+	for _, name := range q.order {
+		msgs = append(msgs, q.pending[name]...)
+		delete(q.pending, name)
+	}
+	q.order = q.order[:0]
+	return msgs
+}
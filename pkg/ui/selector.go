@@ -0,0 +1,257 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ErrSelectionCanceled is returned by RunSelector when the user cancels.
+var ErrSelectionCanceled = errors.New("selection canceled")
+
+// SelectItem is an entry offered by the selector.
+type SelectItem struct {
+	Name     string
+	Group    string // Heading the item is listed under; empty for none
+	Selected bool
+}
+
+// selectorLine is one line of the selector: a group heading (item < 0) or
+// an item.
+type selectorLine struct {
+	group string
+	item  int
+}
+
+// selectorModel is the Bubbletea model for choosing items with checkboxes.
+type selectorModel struct {
+	title     string
+	items     []SelectItem
+	lines     []selectorLine
+	cursor    int
+	offset    int
+	height    int
+	confirmed bool
+}
+
+func newSelectorModel(title string, items []SelectItem) selectorModel {
+	m := selectorModel{
+		title: title,
+		items: append([]SelectItem(nil), items...),
+	}
+
+	// Group items under their headings in order of first appearance.
+	// Headings are only shown if some item has a group.
+	var groups []string
+	byGroup := make(map[string][]int)
+	grouped := false
+	for i, item := range m.items {
+		if _, ok := byGroup[item.Group]; !ok {
+			groups = append(groups, item.Group)
+		}
+		byGroup[item.Group] = append(byGroup[item.Group], i)
+		grouped = grouped || item.Group != ""
+	}
+	for _, g := range groups {
+		if grouped {
+			m.lines = append(m.lines, selectorLine{group: g, item: -1})
+		}
+		for _, i := range byGroup[g] {
+			m.lines = append(m.lines, selectorLine{group: g, item: i})
+		}
+	}
+
+	return m
+}
+
+// Init initializes the model.
+func (m selectorModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages.
+func (m selectorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "esc", "q":
+			return m, tea.Quit
+		case "enter":
+			m.confirmed = true
+			return m, tea.Quit
+		case "up", "k":
+			m.move(-1)
+		case "down", "j":
+			m.move(1)
+		case "pgup", "b":
+			m.move(-m.listHeight())
+		case "pgdown", "f":
+			m.move(m.listHeight())
+		case "home", "g":
+			m.move(-len(m.lines))
+		case "end", "G":
+			m.move(len(m.lines))
+		case " ", "x":
+			m.toggle()
+		case "a":
+			m.setAll(m.selectedCount() < len(m.items))
+		}
+
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+		m.move(0)
+	}
+
+	return m, nil
+}
+
+// toggle flips the item under the cursor. On a heading it selects the
+// whole group, or clears it if it is already fully selected.
+func (m *selectorModel) toggle() {
+	if len(m.lines) == 0 {
+		return
+	}
+	line := m.lines[m.cursor]
+	if line.item >= 0 {
+		m.items[line.item].Selected = !m.items[line.item].Selected
+		return
+	}
+
+	selected, total := m.groupCounts(line.group)
+	for i := range m.items {
+		if m.items[i].Group == line.group {
+			m.items[i].Selected = selected < total
+		}
+	}
+}
+
+func (m *selectorModel) setAll(selected bool) {
+	for i := range m.items {
+		m.items[i].Selected = selected
+	}
+}
+
+func (m *selectorModel) selectedCount() int {
+	n := 0
+	for _, item := range m.items {
+		if item.Selected {
+			n++
+		}
+	}
+	return n
+}
+
+func (m *selectorModel) groupCounts(group string) (selected, total int) {
+	for _, item := range m.items {
+		if item.Group == group {
+			total++
+			if item.Selected {
+				selected++
+			}
+		}
+	}
+	return selected, total
+}
+
+// listHeight returns the number of lines that fit between the header and
+// footer, or 0 if the terminal height is unknown.
+func (m *selectorModel) listHeight() int {
+	if m.height == 0 {
+		return 0
+	}
+	return max(m.height-chromeLines, 1)
+}
+
+// move moves the cursor by delta lines and scrolls to keep it visible.
+func (m *selectorModel) move(delta int) {
+	m.cursor = max(min(m.cursor+delta, len(m.lines)-1), 0)
+
+	height := m.listHeight()
+	if height == 0 {
+		m.offset = 0
+		return
+	}
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+height {
+		m.offset = m.cursor - height + 1
+	}
+}
+
+// View renders the selector.
+func (m selectorModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(TitleStyle.Render(m.title))
+	b.WriteString("  ")
+	b.WriteString(MutedStyle.Render(fmt.Sprintf("%d of %d selected", m.selectedCount(), len(m.items))))
+	b.WriteString("\n\n")
+
+	end := len(m.lines)
+	if height := m.listHeight(); height > 0 && m.offset+height < end {
+		end = m.offset + height
+	}
+	for i := m.offset; i < end; i++ {
+		if i == m.cursor {
+			b.WriteString(HighlightStyle.Render("›") + " ")
+		} else {
+			b.WriteString("  ")
+		}
+
+		line := m.lines[i]
+		if line.item < 0 {
+			name := line.group
+			if name == "" {
+				name = "Other"
+			}
+			selected, total := m.groupCounts(line.group)
+			b.WriteString(TitleStyle.Render(name))
+			b.WriteString(MutedStyle.Render(fmt.Sprintf(" (%d/%d)", selected, total)))
+		} else {
+			item := m.items[line.item]
+			box := MutedStyle.Render("[ ]")
+			if item.Selected {
+				box = SuccessStyle.Render("[x]")
+			}
+			if line.group != "" || m.lines[0].item < 0 {
+				b.WriteString("  ")
+			}
+			b.WriteString(box + " " + item.Name)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(MutedStyle.Render("space toggle  a all  enter confirm  esc cancel"))
+	return b.String()
+}
+
+// selected returns the names of the selected items in their original
+// order.
+func (m selectorModel) selected() []string {
+	var names []string
+	for _, item := range m.items {
+		if item.Selected {
+			names = append(names, item.Name)
+		}
+	}
+	return names
+}
+
+// RunSelector shows a checkbox list of items and returns the names the
+// user confirmed. Items are listed under their group headings; selecting
+// a heading toggles its group. It returns ErrSelectionCanceled if the
+// user quits without confirming.
+func RunSelector(title string, items []SelectItem) ([]string, error) {
+	final, err := tea.NewProgram(newSelectorModel(title, items)).Run()
+	if err != nil {
+		return nil, err
+	}
+	m := final.(selectorModel)
+	if !m.confirmed {
+		return nil, ErrSelectionCanceled
+	}
+	return m.selected(), nil
+}
@@ -0,0 +1,114 @@
+package ui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func selectorKey(m selectorModel, key string) selectorModel {
+	var msg tea.KeyMsg
+	switch key {
+	case "down":
+		msg = tea.KeyMsg{Type: tea.KeyDown}
+	case "enter":
+		msg = tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		msg = tea.KeyMsg{Type: tea.KeyEsc}
+	case " ":
+		msg = tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+	default:
+		msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+	}
+	next, _ := m.Update(msg)
+	return next.(selectorModel)
+}
+
+func TestSelector_Toggle(t *testing.T) {
+	m := newSelectorModel("Select", []SelectItem{
+		{Name: "api", Group: "backend", Selected: true},
+		{Name: "db", Group: "backend"},
+		{Name: "web", Group: "frontend"},
+		{Name: "tools"},
+	})
+
+	// Headings are listed before each group
+	if len(m.lines) != 7 {
+		t.Fatalf("lines = %d, want 7", len(m.lines))
+	}
+
+	// Toggling a partly selected heading selects the whole group
+	m = selectorKey(m, " ")
+	if got := m.selected(); !reflect.DeepEqual(got, []string{"api", "db"}) {
+		t.Errorf("after group toggle selected = %v", got)
+	}
+
+	// Toggling it again clears the group
+	m = selectorKey(m, " ")
+	if got := m.selected(); got != nil {
+		t.Errorf("after second group toggle selected = %v", got)
+	}
+
+	// Toggle a single item: backend, api, db, frontend, web
+	for i := 0; i < 4; i++ {
+		m = selectorKey(m, "down")
+	}
+	m = selectorKey(m, "x")
+	if got := m.selected(); !reflect.DeepEqual(got, []string{"web"}) {
+		t.Errorf("after item toggle selected = %v", got)
+	}
+
+	m = selectorKey(m, "a")
+	if got := m.selectedCount(); got != 4 {
+		t.Errorf("after select all selected = %d, want 4", got)
+	}
+	m = selectorKey(m, "a")
+	if got := m.selectedCount(); got != 0 {
+		t.Errorf("after clear all selected = %d, want 0", got)
+	}
+}
+
+func TestSelector_Ungrouped(t *testing.T) {
+	m := newSelectorModel("Select", []SelectItem{{Name: "a"}, {Name: "b"}})
+	if len(m.lines) != 2 {
+		t.Fatalf("lines = %d, want 2 without headings", len(m.lines))
+	}
+
+	m = selectorKey(m, " ")
+	if got := m.selected(); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("selected = %v", got)
+	}
+	if view := m.View(); !strings.Contains(view, "1 of 2 selected") {
+		t.Errorf("view missing count:\n%s", view)
+	}
+}
+
+func TestSelector_Confirm(t *testing.T) {
+	m := newSelectorModel("Select", []SelectItem{{Name: "a", Selected: true}})
+	if got := selectorKey(m, "esc"); got.confirmed {
+		t.Error("esc confirmed the selection")
+	}
+	if got := selectorKey(m, "enter"); !got.confirmed {
+		t.Error("enter did not confirm the selection")
+	}
+}
+
+func TestSelector_Scroll(t *testing.T) {
+	items := make([]SelectItem, 20)
+	for i := range items {
+		items[i] = SelectItem{Name: string(rune('a' + i))}
+	}
+	m := newSelectorModel("Select", items)
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 10})
+	m = next.(selectorModel)
+
+	m = selectorKey(m, "G")
+	if m.cursor != 19 {
+		t.Fatalf("cursor = %d, want 19", m.cursor)
+	}
+	if want := 20 - m.listHeight(); m.offset != want {
+		t.Errorf("offset = %d, want %d", m.offset, want)
+	}
+}
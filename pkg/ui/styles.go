@@ -1,86 +1,209 @@
 package ui
 
 import (
+	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 )
 
-var (
-	// Colors
-	colorPrimary   = lipgloss.Color("39")  // Blue
-	colorSuccess   = lipgloss.Color("82")  // Green
-	colorWarning   = lipgloss.Color("214") // Orange
-	colorError     = lipgloss.Color("196") // Red
-	colorMuted     = lipgloss.Color("241") // Gray
-	colorHighlight = lipgloss.Color("213") // Pink
+// Palette is the set of colors styled output is drawn in.
+type Palette struct {
+	Primary   lipgloss.TerminalColor
+	Success   lipgloss.TerminalColor
+	Warning   lipgloss.TerminalColor
+	Error     lipgloss.TerminalColor
+	Muted     lipgloss.TerminalColor
+	Highlight lipgloss.TerminalColor
+}
+
+// defaultTheme is the name of the theme used when none is chosen.
+const defaultTheme = "default"
+
+// themePalettes are the palettes of the built-in themes, by the names
+// accepted for ui.theme.
+var themePalettes = map[string]Palette{
+	defaultTheme: {
+		Primary:   lipgloss.Color("39"),  // Blue
+		Success:   lipgloss.Color("82"),  // Green
+		Warning:   lipgloss.Color("214"), // Orange
+		Error:     lipgloss.Color("196"), // Red
+		Muted:     lipgloss.Color("241"), // Gray
+		Highlight: lipgloss.Color("213"), // Pink
+	},
+	// After the Okabe-Ito palette: success and errors are blue and
+	// vermillion, which stay apart under common color vision deficiencies
+	"colorblind-safe": {
+		Primary:   lipgloss.Color("74"),  // Sky blue
+		Success:   lipgloss.Color("33"),  // Blue
+		Warning:   lipgloss.Color("220"), // Yellow
+		Error:     lipgloss.Color("166"), // Vermillion
+		Muted:     lipgloss.Color("245"), // Gray
+		Highlight: lipgloss.Color("175"), // Reddish purple
+	},
+	// Bold text and symbols still tell states apart
+	"monochrome": {
+		Primary:   lipgloss.NoColor{},
+		Success:   lipgloss.NoColor{},
+		Warning:   lipgloss.NoColor{},
+		Error:     lipgloss.NoColor{},
+		Muted:     lipgloss.NoColor{},
+		Highlight: lipgloss.NoColor{},
+	},
+}
 
+var (
 	// Text styles
+	TitleStyle     lipgloss.Style
+	SubtitleStyle  lipgloss.Style
+	SuccessStyle   lipgloss.Style
+	WarningStyle   lipgloss.Style
+	ErrorStyle     lipgloss.Style
+	MutedStyle     lipgloss.Style
+	HighlightStyle lipgloss.Style
+
+	// Status indicators
+	SpinnerStyle  lipgloss.Style
+	ProgressStyle lipgloss.Style
+
+	// Repository name style
+	RepoNameStyle lipgloss.Style
+
+	// Phase styles
+	PhaseStyle lipgloss.Style
+
+	// Box styles
+	BoxStyle lipgloss.Style
+
+	// Header style
+	HeaderStyle lipgloss.Style
+
+	// Summary styles
+	SummarySuccessStyle lipgloss.Style
+	SummaryErrorStyle   lipgloss.Style
+
+	// progressFill is the color of the progress bar, or nil for the
+	// default gradient.
+	progressFill lipgloss.TerminalColor
+)
+
+// setStyles draws the styles in the colors of p.
+func setStyles(p Palette) {
 	TitleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(colorPrimary)
+		Bold(true).
+		Foreground(p.Primary)
 
 	SubtitleStyle = lipgloss.NewStyle().
-			Foreground(colorMuted)
+		Foreground(p.Muted)
 
 	SuccessStyle = lipgloss.NewStyle().
-			Foreground(colorSuccess)
+		Foreground(p.Success)
 
 	WarningStyle = lipgloss.NewStyle().
-			Foreground(colorWarning)
+		Foreground(p.Warning)
 
 	ErrorStyle = lipgloss.NewStyle().
-			Foreground(colorError).
-			Bold(true)
+		Foreground(p.Error).
+		Bold(true)
 
 	MutedStyle = lipgloss.NewStyle().
-			Foreground(colorMuted)
+		Foreground(p.Muted)
 
 	HighlightStyle = lipgloss.NewStyle().
-			Foreground(colorHighlight)
+		Foreground(p.Highlight)
 
-	// Status indicators
 	SpinnerStyle = lipgloss.NewStyle().
-			Foreground(colorPrimary)
+		Foreground(p.Primary)
 
 	ProgressStyle = lipgloss.NewStyle().
-			Foreground(colorPrimary)
+		Foreground(p.Primary)
 
-	// Repository name style
 	RepoNameStyle = lipgloss.NewStyle().
-			Bold(true).
-			Width(30)
+		Bold(true).
+		Width(30)
 
-	// Phase styles
 	PhaseStyle = lipgloss.NewStyle().
-			Width(15)
-
-	// Symbols
-	SymbolSuccess = SuccessStyle.Render("✓")
-	SymbolError   = ErrorStyle.Render("✗")
-	SymbolPending = MutedStyle.Render("○")
-	SymbolRunning = SpinnerStyle.Render("●")
+		Width(15)
 
-	// Box styles
 	BoxStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(colorMuted).
-			Padding(0, 1)
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(p.Muted).
+		Padding(0, 1)
 
-	// Header style
 	HeaderStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(colorPrimary).
-			MarginBottom(1)
+		Bold(true).
+		Foreground(p.Primary).
+		MarginBottom(1)
 
-	// Summary styles
 	SummarySuccessStyle = lipgloss.NewStyle().
-				Foreground(colorSuccess).
-				Bold(true)
+		Foreground(p.Success).
+		Bold(true)
 
 	SummaryErrorStyle = lipgloss.NewStyle().
-				Foreground(colorError).
-				Bold(true)
+		Foreground(p.Error).
+		Bold(true)
+}
+
+// ThemePalette returns the palette of the built-in theme with the given
+// name. An empty or unknown name returns the default theme's palette.
+func ThemePalette(name string) Palette {
+	if p, ok := themePalettes[name]; ok {
+		return p
+	}
+	return themePalettes[defaultTheme]
+}
+
+// SetPalette draws styled output in the colors of p. The progress bar
+// keeps its default gradient unless p changes the default primary color.
+// It must be called before any UI is started.
+func SetPalette(p Palette) {
+	progressFill = nil
+	if p.Primary != themePalettes[defaultTheme].Primary {
+		progressFill = p.Primary
+	}
+	setStyles(p)
+	renderSymbols()
+}
+
+// progressTheme returns the progress bar options drawing it in the
+// current theme.
+func progressTheme() []progress.Option {
+	switch fill := progressFill.(type) {
+	case nil:
+		return []progress.Option{progress.WithDefaultGradient()}
+	case lipgloss.Color:
+		return []progress.Option{progress.WithSolidFill(string(fill))}
+	default:
+		return []progress.Option{progress.WithSolidFill(""), progress.WithColorProfile(termenv.Ascii)}
+	}
+}
+
+// Symbols, rendered by renderSymbols
+var (
+	SymbolSuccess string
+	SymbolError   string
+	SymbolPending string
+	SymbolRunning string
 )
 
+func init() {
+	setStyles(themePalettes[defaultTheme])
+	renderSymbols()
+}
+
+func renderSymbols() {
+	SymbolSuccess = SuccessStyle.Render("✓")
+	SymbolError = ErrorStyle.Render("✗")
+	SymbolPending = MutedStyle.Render("○")
+	SymbolRunning = SpinnerStyle.Render("●")
+}
+
+// DisableColor turns off colors and text attributes in all styled output.
+// It must be called before any UI is started.
+func DisableColor() {
+	lipgloss.SetColorProfile(termenv.Ascii)
+	renderSymbols()
+}
+
 // StatusSymbol returns the appropriate symbol for a status.
 func StatusSymbol(success bool, running bool, pending bool) string {
 	if running {
@@ -0,0 +1,76 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+func TestDisableColor(t *testing.T) {
+	previous := lipgloss.ColorProfile()
+	t.Cleanup(func() {
+		lipgloss.SetColorProfile(previous)
+		renderSymbols()
+	})
+
+	lipgloss.SetColorProfile(termenv.TrueColor)
+	renderSymbols()
+	if !strings.Contains(SymbolSuccess, "\x1b[") {
+		t.Fatalf("expected a colored symbol, got %q", SymbolSuccess)
+	}
+
+	DisableColor()
+	if SymbolSuccess != "✓" || SymbolError != "✗" {
+		t.Errorf("expected plain symbols, got %q and %q", SymbolSuccess, SymbolError)
+	}
+	if got := ErrorStyle.Render("failed"); got != "failed" {
+		t.Errorf("expected plain text, got %q", got)
+	}
+	if view := NewModel().progress.ViewAs(0.5); strings.Contains(view, "\x1b[") {
+		t.Errorf("expected a plain progress bar, got %q", view)
+	}
+}
+
+func TestSetPalette(t *testing.T) {
+	previous := lipgloss.ColorProfile()
+	t.Cleanup(func() {
+		lipgloss.SetColorProfile(previous)
+		SetPalette(ThemePalette(""))
+	})
+	lipgloss.SetColorProfile(termenv.ANSI256)
+
+	defaultError := ErrorStyle.Render("failed")
+	SetPalette(ThemePalette("colorblind-safe"))
+	if got := SuccessStyle.Render("ok"); !strings.Contains(got, "38;5;33m") {
+		t.Errorf("expected colorblind-safe blue for success, got %q", got)
+	}
+	if got := ErrorStyle.Render("failed"); got == defaultError || !strings.Contains(got, "38;5;166m") {
+		t.Errorf("expected vermillion for errors, got %q", got)
+	}
+
+	p := ThemePalette("monochrome")
+	p.Warning = lipgloss.Color("214")
+	SetPalette(p)
+	if got := SuccessStyle.Render("ok"); got != "ok" {
+		t.Errorf("expected plain success text in monochrome, got %q", got)
+	}
+	if got := ErrorStyle.Render("failed"); strings.Contains(got, "38;5;") || !strings.Contains(got, "\x1b[1m") {
+		t.Errorf("expected bold errors without color in monochrome, got %q", got)
+	}
+	if got := WarningStyle.Render("dirty"); !strings.Contains(got, "38;5;214m") {
+		t.Errorf("expected the overridden warning color, got %q", got)
+	}
+	if SymbolSuccess != "✓" {
+		t.Errorf("expected symbols redrawn in monochrome, got %q", SymbolSuccess)
+	}
+	if view := NewModel().progress.ViewAs(0.5); strings.Contains(view, "\x1b[") {
+		t.Errorf("expected a plain progress bar in monochrome, got %q", view)
+	}
+
+	SetPalette(ThemePalette(""))
+	if got := ErrorStyle.Render("failed"); got != defaultError {
+		t.Errorf("expected the default theme back, got %q, want %q", got, defaultError)
+	}
+}